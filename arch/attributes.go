@@ -0,0 +1,214 @@
+package arch
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// SHTAttributes is the section type of build attributes on ARM
+// (SHT_ARM_ATTRIBUTES) and RISC-V (SHT_RISCV_ATTRIBUTES).
+const SHTAttributes = elf.SectionType(0x70000003)
+
+// A Scope is what a group of attributes applies to.
+type Scope uint64
+
+// Scopes of attribute groups.
+const (
+	ScopeFile    Scope = 1
+	ScopeSection Scope = 2
+	ScopeSymbol  Scope = 3
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeFile:
+		return "File"
+	case ScopeSection:
+		return "Section"
+	case ScopeSymbol:
+		return "Symbol"
+	}
+	return fmt.Sprintf("Scope(%d)", uint64(s))
+}
+
+// An Attribute is a tag and its value, which is an integer or a
+// string depending on the tag.
+type Attribute struct {
+	Tag      uint64
+	IsString bool
+	Int      uint64
+	Str      string
+	vendor   string
+}
+
+// Name returns the name of the tag of a, such as "Tag_CPU_arch".
+func (a Attribute) Name() string {
+	if t, ok := vendorTags[a.vendor][a.Tag]; ok {
+		return t.name
+	}
+	return fmt.Sprintf("Tag_unknown_%d", a.Tag)
+}
+
+// Value returns the value of a, spelled out for the tags whose values
+// are enumerations.
+func (a Attribute) Value() string {
+	if a.IsString {
+		if a.Tag == armTagCompatibility && a.vendor == "aeabi" {
+			return fmt.Sprintf("flag = %d, vendor = %s", a.Int, a.Str)
+		}
+		return a.Str
+	}
+	t, ok := vendorTags[a.vendor][a.Tag]
+	switch {
+	case ok && t.format != "":
+		return fmt.Sprintf(t.format, a.Int)
+	case ok && a.Int < uint64(len(t.values)) && t.values[a.Int] != "":
+		return t.values[a.Int]
+	case ok && t.named != nil && t.named[a.Int] != "":
+		return t.named[a.Int]
+	}
+	return fmt.Sprintf("%d", a.Int)
+}
+
+// A Group is the attributes of one scope; Indices lists the sections
+// or symbols of ScopeSection and ScopeSymbol groups.
+type Group struct {
+	Scope   Scope
+	Indices []uint64
+	Attrs   []Attribute
+}
+
+// A Subsection is the attributes a vendor defines, such as "aeabi"
+// for ARM and "riscv" for RISC-V.
+type Subsection struct {
+	Vendor string
+	Groups []Group
+}
+
+// ErrBadAttributes is returned for attribute sections of an unknown
+// format version.
+var ErrBadAttributes = errors.New("attribute section is not format version 'A'")
+
+// ParseAttributes decodes a build attribute section, such as
+// .ARM.attributes or .riscv.attributes.
+func ParseAttributes(b []byte, order binary.ByteOrder) ([]Subsection, error) {
+	if len(b) == 0 || b[0] != 'A' {
+		return nil, ErrBadAttributes
+	}
+	var subs []Subsection
+	for b = b[1:]; len(b) > 0; {
+		if len(b) < 4 {
+			return subs, errors.New("truncated subsection length")
+		}
+		n := order.Uint32(b)
+		if n < 4 || uint64(n) > uint64(len(b)) {
+			return subs, fmt.Errorf("subsection length %d past the end of the section", n)
+		}
+		d := b[4:n]
+		b = b[n:]
+		i := bytes.IndexByte(d, 0)
+		if i < 0 {
+			return subs, errors.New("unterminated vendor name")
+		}
+		sub := Subsection{Vendor: string(d[:i])}
+		for d = d[i+1:]; len(d) > 0; {
+			scope, m := uleb(d)
+			if m == 0 || len(d) < m+4 {
+				return subs, fmt.Errorf("%s: truncated attribute group", sub.Vendor)
+			}
+			size := order.Uint32(d[m:])
+			if uint64(size) < uint64(m+4) || uint64(size) > uint64(len(d)) {
+				return subs, fmt.Errorf("%s: attribute group length %d past the end of the subsection", sub.Vendor, size)
+			}
+			g, err := parseGroup(Scope(scope), d[m+4:size], sub.Vendor)
+			sub.Groups = append(sub.Groups, g)
+			if err != nil {
+				subs = append(subs, sub)
+				return subs, fmt.Errorf("%s: %v", sub.Vendor, err)
+			}
+			d = d[size:]
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// parseGroup decodes the attributes of a group after its scope and
+// length.
+func parseGroup(scope Scope, d []byte, vendor string) (Group, error) {
+	g := Group{Scope: scope}
+	if scope == ScopeSection || scope == ScopeSymbol {
+		for {
+			v, m := uleb(d)
+			if m == 0 {
+				return g, errors.New("truncated index list")
+			}
+			d = d[m:]
+			if v == 0 {
+				break
+			}
+			g.Indices = append(g.Indices, v)
+		}
+	}
+	for len(d) > 0 {
+		tag, m := uleb(d)
+		if m == 0 {
+			return g, errors.New("truncated tag")
+		}
+		d = d[m:]
+		a := Attribute{Tag: tag, vendor: vendor}
+		if tag == armTagCompatibility && vendor == "aeabi" {
+			// a flag, then the vendor it is compatible with
+			if a.Int, m = uleb(d); m == 0 {
+				return g, errors.New("truncated Tag_compatibility")
+			}
+			d = d[m:]
+			a.IsString = true
+		} else {
+			a.IsString = isString(vendor, tag)
+		}
+		if a.IsString {
+			i := bytes.IndexByte(d, 0)
+			if i < 0 {
+				return g, fmt.Errorf("unterminated string of tag %d", tag)
+			}
+			a.Str, d = string(d[:i]), d[i+1:]
+		} else {
+			if a.Int, m = uleb(d); m == 0 {
+				return g, fmt.Errorf("truncated value of tag %d", tag)
+			}
+			d = d[m:]
+		}
+		g.Attrs = append(g.Attrs, a)
+	}
+	return g, nil
+}
+
+// isString tells whether tag takes a string: the known tags say, and
+// the others follow the generic rule that odd tags take strings.
+func isString(vendor string, tag uint64) bool {
+	if t, ok := vendorTags[vendor][tag]; ok {
+		return t.str
+	}
+	return tag%2 == 1
+}
+
+// uleb decodes an unsigned LEB128 number, returning 0 bytes read if b
+// ends within it.
+func uleb(b []byte) (uint64, int) {
+	var v uint64
+	var shift uint
+	for i, c := range b {
+		if shift < 64 {
+			v |= uint64(c&0x7f) << shift
+		}
+		shift += 7
+		if c&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
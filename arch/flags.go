@@ -0,0 +1,256 @@
+// Package arch decodes the machine-specific parts of ELF files: the
+// e_flags of the file header, the build attribute sections of ARM and
+// RISC-V and the MIPS ABI flags.
+package arch
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ReadFlags reads e_flags from the file header in r, which the
+// debug/elf FileHeader leaves out.
+func ReadFlags(r io.ReaderAt, class elf.Class, order binary.ByteOrder) (uint32, error) {
+	off := int64(0x24)
+	if class == elf.ELFCLASS64 {
+		off = 0x30
+	}
+	var b [4]byte
+	if _, err := r.ReadAt(b[:], off); err != nil {
+		return 0, err
+	}
+	return order.Uint32(b[:]), nil
+}
+
+// A flagName names a bit of e_flags.
+type flagName struct {
+	bit  uint32
+	name string
+}
+
+// names appends the names of the bits of flags set in table.
+func names(out []string, flags uint32, table []flagName) []string {
+	for _, f := range table {
+		if flags&f.bit != 0 {
+			out = append(out, f.name)
+		}
+	}
+	return out
+}
+
+// DecodeFlags spells e_flags of a file for machine and class the way
+// readelf does, one string per property.
+func DecodeFlags(machine elf.Machine, class elf.Class, flags uint32) []string {
+	switch machine {
+	case elf.EM_ARM:
+		return armFlags(flags)
+	case elf.EM_RISCV:
+		return riscvFlags(flags)
+	case elf.EM_MIPS, elf.EM_MIPS_RS3_LE:
+		return mipsFlags(flags)
+	case elf.EM_PPC64:
+		return ppc64Flags(flags)
+	case elf.EM_PPC:
+		return names(nil, flags, ppcFlagNames)
+	case elf.EM_LOONGARCH:
+		return loongarchFlags(class, flags)
+	}
+	return nil
+}
+
+// ARM e_flags.
+const (
+	efARMEABIMask  = 0xff000000
+	efARMBE8       = 0x00800000
+	efARMLE8       = 0x00400000
+	efARMSoftFloat = 0x00000200
+	efARMHardFloat = 0x00000400
+)
+
+// armGNUFlags are the flags of objects without an EABI version.
+var armGNUFlags = []flagName{
+	{0x04, "interworking enabled"},
+	{0x08, "uses APCS/26"},
+	{0x10, "uses APCS/float"},
+	{0x20, "position independent"},
+	{0x40, "8 bit structure alignment"},
+	{0x80, "uses new ABI"},
+	{0x100, "uses old ABI"},
+	{0x200, "software FP"},
+	{0x400, "VFP"},
+	{0x800, "Maverick FP"},
+}
+
+func armFlags(flags uint32) []string {
+	v := flags & efARMEABIMask >> 24
+	if v == 0 {
+		return names([]string{"GNU EABI"}, flags, armGNUFlags)
+	}
+	out := []string{fmt.Sprintf("Version%d EABI", v)}
+	if v == 5 {
+		if flags&efARMSoftFloat != 0 {
+			out = append(out, "soft-float ABI")
+		}
+		if flags&efARMHardFloat != 0 {
+			out = append(out, "hard-float ABI")
+		}
+	}
+	if v >= 4 {
+		if flags&efARMBE8 != 0 {
+			out = append(out, "BE8")
+		}
+		if flags&efARMLE8 != 0 {
+			out = append(out, "LE8")
+		}
+	}
+	return out
+}
+
+// RISC-V e_flags.
+const (
+	efRISCVRVC          = 0x1
+	efRISCVFloatABIMask = 0x6
+	efRISCVRVE          = 0x8
+	efRISCVTSO          = 0x10
+)
+
+var riscvFloatABIs = []string{"soft-float ABI", "single-float ABI", "double-float ABI", "quad-float ABI"}
+
+func riscvFlags(flags uint32) []string {
+	var out []string
+	if flags&efRISCVRVC != 0 {
+		out = append(out, "RVC")
+	}
+	out = append(out, riscvFloatABIs[flags&efRISCVFloatABIMask>>1])
+	if flags&efRISCVRVE != 0 {
+		out = append(out, "RVE")
+	}
+	if flags&efRISCVTSO != 0 {
+		out = append(out, "TSO")
+	}
+	return out
+}
+
+// MIPS e_flags.
+const (
+	efMIPSABIMask  = 0x0000f000
+	efMIPSMachMask = 0x00ff0000
+	efMIPSASEMask  = 0x0f000000
+	efMIPSArchMask = 0xf0000000
+)
+
+var mipsFlagNames = []flagName{
+	{0x1, "noreorder"},
+	{0x2, "pic"},
+	{0x4, "cpic"},
+	{0x8, "xgot"},
+	{0x10, "ugen_reserved"},
+	{0x20, "abi2"},
+	{0x80, "odk first"},
+	{0x100, "32bitmode"},
+	{0x200, "fp64"},
+	{0x400, "nan2008"},
+}
+
+var mipsABIs = map[uint32]string{
+	0x1000: "o32",
+	0x2000: "o64",
+	0x3000: "eabi32",
+	0x4000: "eabi64",
+}
+
+var mipsMachs = map[uint32]string{
+	0x00810000: "3900",
+	0x00820000: "4010",
+	0x00830000: "4100",
+	0x00850000: "4650",
+	0x00870000: "4120",
+	0x00880000: "4111",
+	0x008a0000: "sb1",
+	0x008b0000: "octeon",
+	0x008c0000: "xlr",
+	0x008d0000: "octeon2",
+	0x008e0000: "octeon3",
+	0x00910000: "5400",
+	0x00920000: "5900",
+	0x00930000: "interaptiv-mr2",
+	0x00980000: "5500",
+	0x00990000: "9000",
+	0x00a00000: "loongson-2e",
+	0x00a10000: "loongson-2f",
+	0x00a20000: "gs464",
+	0x00a30000: "gs464e",
+	0x00a40000: "gs264e",
+}
+
+var mipsASEs = []flagName{
+	{0x08000000, "mdmx"},
+	{0x04000000, "mips16"},
+	{0x02000000, "micromips"},
+}
+
+var mipsArchs = []string{
+	"mips1", "mips2", "mips3", "mips4", "mips5", "mips32", "mips64",
+	"mips32r2", "mips64r2", "mips32r6", "mips64r6",
+}
+
+func mipsFlags(flags uint32) []string {
+	out := names(nil, flags, mipsFlagNames)
+	if m := flags & efMIPSMachMask; m != 0 {
+		if s, ok := mipsMachs[m]; ok {
+			out = append(out, s)
+		} else {
+			out = append(out, fmt.Sprintf("unknown CPU 0x%x", m>>16))
+		}
+	}
+	switch a := flags & efMIPSABIMask; {
+	case a != 0 && mipsABIs[a] != "":
+		out = append(out, mipsABIs[a])
+	case a != 0:
+		out = append(out, fmt.Sprintf("unknown ABI 0x%x", a>>12))
+	case flags&0x20 != 0:
+		// EF_MIPS_ABI2 without an ABI is n32
+		out = append(out, "n32")
+	}
+	out = names(out, flags&efMIPSASEMask, mipsASEs)
+	if a := flags & efMIPSArchMask >> 28; int(a) < len(mipsArchs) {
+		out = append(out, mipsArchs[a])
+	} else {
+		out = append(out, fmt.Sprintf("unknown ISA %d", a))
+	}
+	return out
+}
+
+// PowerPC e_flags.
+var ppcFlagNames = []flagName{
+	{0x80000000, "emb"},
+	{0x00010000, "relocatable"},
+	{0x00008000, "relocatable-lib"},
+}
+
+func ppc64Flags(flags uint32) []string {
+	if v := flags & 3; v != 0 {
+		return []string{fmt.Sprintf("abiv%d", v)}
+	}
+	return []string{"abiv1 or unspecified"}
+}
+
+// LoongArch e_flags: the low three bits modify the base ABI, which
+// the class gives, and the next two are the object file ABI version.
+var loongarchFloatABIs = []string{"", "SOFT-FLOAT", "SINGLE-FLOAT", "DOUBLE-FLOAT"}
+
+func loongarchFlags(class elf.Class, flags uint32) []string {
+	base := "ILP32"
+	if class == elf.ELFCLASS64 {
+		base = "LP64"
+	}
+	var out []string
+	if m := flags & 7; m > 0 && int(m) < len(loongarchFloatABIs) {
+		out = append(out, fmt.Sprintf("%s (%s%s)", loongarchFloatABIs[m], base, "SFD"[m-1:m]))
+	} else {
+		out = append(out, fmt.Sprintf("unknown ABI modifier %d", m))
+	}
+	return append(out, fmt.Sprintf("OBJ-v%d", flags>>6&3))
+}
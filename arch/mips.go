@@ -0,0 +1,150 @@
+package arch
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// PT_MIPS_ABIFLAGS is the segment, and SHT_MIPS_ABIFLAGS the section
+// type, of the MIPS ABI flags.
+const (
+	PT_MIPS_ABIFLAGS  = elf.ProgType(0x70000003)
+	SHT_MIPS_ABIFLAGS = elf.SectionType(0x7000002a)
+)
+
+// MIPSABIFlags is the Elf_MIPS_ABIFlags_v0 structure of
+// .MIPS.abiflags, telling the ISA, the register sizes and the FP ABI
+// the file needs.
+type MIPSABIFlags struct {
+	Version  uint16
+	ISALevel uint8
+	ISARev   uint8
+	GPRSize  uint8
+	CPR1Size uint8
+	CPR2Size uint8
+	FPABI    uint8
+	ISAExt   uint32
+	ASEs     uint32
+	Flags1   uint32
+	Flags2   uint32
+}
+
+// ParseMIPSABIFlags decodes the contents of .MIPS.abiflags.
+func ParseMIPSABIFlags(b []byte, order binary.ByteOrder) (*MIPSABIFlags, error) {
+	if len(b) < 24 {
+		return nil, errors.New("MIPS ABI flags shorter than 24 bytes")
+	}
+	return &MIPSABIFlags{
+		Version:  order.Uint16(b),
+		ISALevel: b[2],
+		ISARev:   b[3],
+		GPRSize:  b[4],
+		CPR1Size: b[5],
+		CPR2Size: b[6],
+		FPABI:    b[7],
+		ISAExt:   order.Uint32(b[8:]),
+		ASEs:     order.Uint32(b[12:]),
+		Flags1:   order.Uint32(b[16:]),
+		Flags2:   order.Uint32(b[20:]),
+	}, nil
+}
+
+// ISA spells the ISA level and revision, such as "MIPS32r2".
+func (m *MIPSABIFlags) ISA() string {
+	if m.ISARev > 1 {
+		return fmt.Sprintf("MIPS%dr%d", m.ISALevel, m.ISARev)
+	}
+	return fmt.Sprintf("MIPS%d", m.ISALevel)
+}
+
+// RegSize spells an AFL_REG size code in bits.
+func RegSize(code uint8) string {
+	switch code {
+	case 0:
+		return "0"
+	case 1:
+		return "32"
+	case 2:
+		return "64"
+	case 3:
+		return "128"
+	}
+	return fmt.Sprintf("unknown (%d)", code)
+}
+
+var mipsFPABIs = []string{
+	"Hard or soft float",
+	"Hard float (double precision)",
+	"Hard float (single precision)",
+	"Soft float",
+	"Hard float (MIPS32r2 64-bit FPU 12 callee-saved)",
+	"Hard float (32-bit CPU, Any FPU)",
+	"Hard float (32-bit CPU, 64-bit FPU)",
+	"Hard float compat (32-bit CPU, 64-bit FPU)",
+}
+
+// FPABIName spells the floating point ABI.
+func (m *MIPSABIFlags) FPABIName() string {
+	if int(m.FPABI) < len(mipsFPABIs) {
+		return mipsFPABIs[m.FPABI]
+	}
+	return fmt.Sprintf("Unknown (%d)", m.FPABI)
+}
+
+var mipsISAExts = []string{
+	"None", "RMI XLR", "Cavium Networks Octeon2", "Cavium Networks OcteonP",
+	"Loongson 3A", "Cavium Networks Octeon", "Toshiba R5900", "MIPS R4650",
+	"LSI R4010", "NEC VR4100", "Toshiba R3900", "MIPS R10000",
+	"Broadcom SB-1", "NEC VR4111/VR4181", "NEC VR4120", "NEC VR5400",
+	"NEC VR5500", "ST Microelectronics Loongson 2E",
+	"ST Microelectronics Loongson 2F", "Cavium Networks Octeon3",
+	"Imagination interAptiv MR2",
+}
+
+// ISAExtName spells the processor-specific ISA extension.
+func (m *MIPSABIFlags) ISAExtName() string {
+	if int(m.ISAExt) < len(mipsISAExts) {
+		return mipsISAExts[m.ISAExt]
+	}
+	return fmt.Sprintf("Unknown (%d)", m.ISAExt)
+}
+
+var mipsASENames = []flagName{
+	{0x1, "DSP ASE"},
+	{0x2, "DSP R2 ASE"},
+	{0x4, "Enhanced VA Scheme"},
+	{0x8, "MCU (MicroController) ASE"},
+	{0x10, "MDMX ASE"},
+	{0x20, "MIPS-3D ASE"},
+	{0x40, "MT ASE"},
+	{0x80, "SmartMIPS ASE"},
+	{0x100, "VZ ASE"},
+	{0x200, "MSA ASE"},
+	{0x400, "MIPS16 ASE"},
+	{0x800, "microMIPS ASE"},
+	{0x1000, "XPA ASE"},
+	{0x2000, "DSP R3 ASE"},
+	{0x4000, "MIPS16e2 ASE"},
+	{0x8000, "CRC ASE"},
+	{0x20000, "GINV ASE"},
+	{0x40000, "Loongson MMI ASE"},
+	{0x80000, "Loongson CAM ASE"},
+	{0x100000, "Loongson EXT ASE"},
+	{0x200000, "Loongson EXT2 ASE"},
+}
+
+// ASENames lists the application-specific extensions the file uses.
+func (m *MIPSABIFlags) ASENames() []string {
+	return names(nil, m.ASEs, mipsASENames)
+}
+
+// Flags1Names lists the flags of Flags1.
+func (m *MIPSABIFlags) Flags1Names() []string {
+	var out []string
+	if m.Flags1&1 != 0 {
+		out = append(out, "ODDSPREG")
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package arch
+
+// A tag describes an attribute tag: its name, whether it takes a
+// string, and how to spell its integer values, either by index into
+// values, by lookup in named or with format.
+type tag struct {
+	name   string
+	str    bool
+	values []string
+	named  map[uint64]string
+	format string
+}
+
+const armTagCompatibility = 32
+
+var noYes = []string{"No", "Yes"}
+
+// armTags are the tags of the "aeabi" subsection, from the ARM
+// Addenda to the AAPCS.
+var armTags = map[uint64]tag{
+	4: {name: "Tag_CPU_raw_name", str: true},
+	5: {name: "Tag_CPU_name", str: true},
+	6: {name: "Tag_CPU_arch", values: []string{
+		"Pre-v4", "v4", "v4T", "v5T", "v5TE", "v5TEJ", "v6", "v6KZ", "v6T2",
+		"v6K", "v7", "v6-M", "v6S-M", "v7E-M", "v8", "v8-R", "v8-M.baseline",
+		"v8-M.mainline", "v8.1-A", "v8.2-A", "v8.3-A", "v8.1-M.mainline", "v9",
+	}},
+	7: {name: "Tag_CPU_arch_profile", named: map[uint64]string{
+		0: "None", 'A': "Application", 'R': "Realtime", 'M': "Microcontroller",
+		'S': "Application or Realtime",
+	}},
+	8:  {name: "Tag_ARM_ISA_use", values: noYes},
+	9:  {name: "Tag_THUMB_ISA_use", values: []string{"No", "Thumb-1", "Thumb-2", "Yes"}},
+	10: {name: "Tag_FP_arch", values: []string{"No", "VFPv1", "VFPv2", "VFPv3", "VFPv3-D16", "VFPv4", "VFPv4-D16", "FP for ARMv8", "FPv5/FP-D16 for ARMv8"}},
+	11: {name: "Tag_WMMX_arch", values: []string{"No", "WMMXv1", "WMMXv2"}},
+	12: {name: "Tag_Advanced_SIMD_arch", values: []string{"No", "NEONv1", "NEONv1 with Fused-MAC", "NEON for ARMv8", "NEON for ARMv8.1"}},
+	13: {name: "Tag_PCS_config", values: []string{"None", "Bare platform", "Linux application", "Linux DSO", "PalmOS 2004", "PalmOS (reserved)", "SymbianOS 2004", "SymbianOS (reserved)"}},
+	14: {name: "Tag_ABI_PCS_R9_use", values: []string{"V6", "SB", "TLS", "Unused"}},
+	15: {name: "Tag_ABI_PCS_RW_data", values: []string{"Absolute", "PC-relative", "SB-relative", "None"}},
+	16: {name: "Tag_ABI_PCS_RO_data", values: []string{"Absolute", "PC-relative", "None"}},
+	17: {name: "Tag_ABI_PCS_GOT_use", values: []string{"None", "direct", "GOT-indirect"}},
+	18: {name: "Tag_ABI_PCS_wchar_t", named: map[uint64]string{0: "None", 2: "2", 4: "4"}},
+	19: {name: "Tag_ABI_FP_rounding", values: []string{"Unused", "Needed"}},
+	20: {name: "Tag_ABI_FP_denormal", values: []string{"Unused", "Needed", "Sign only"}},
+	21: {name: "Tag_ABI_FP_exceptions", values: []string{"Unused", "Needed"}},
+	22: {name: "Tag_ABI_FP_user_exceptions", values: []string{"Unused", "Needed"}},
+	23: {name: "Tag_ABI_FP_number_model", values: []string{"Unused", "Finite", "RTABI", "IEEE 754"}},
+	24: {name: "Tag_ABI_align_needed", values: []string{"None", "8-byte", "4-byte"}},
+	25: {name: "Tag_ABI_align_preserved", values: []string{"None", "8-byte, except leaf SP", "8-byte"}},
+	26: {name: "Tag_ABI_enum_size", values: []string{"Unused", "small", "int", "forced to int"}},
+	27: {name: "Tag_ABI_HardFP_use", values: []string{"As Tag_FP_arch", "SP only", "DP only", "SP and DP"}},
+	28: {name: "Tag_ABI_VFP_args", values: []string{"AAPCS", "VFP registers", "custom", "compatible"}},
+	29: {name: "Tag_ABI_WMMX_args", values: []string{"AAPCS", "WMMX registers", "custom"}},
+	30: {name: "Tag_ABI_optimization_goals", values: []string{"None", "Prefer Speed", "Aggressive Speed", "Prefer Size", "Aggressive Size", "Prefer Debug", "Aggressive Debug"}},
+	31: {name: "Tag_ABI_FP_optimization_goals", values: []string{"None", "Prefer Speed", "Aggressive Speed", "Prefer Size", "Aggressive Size", "Prefer Accuracy", "Aggressive Accuracy"}},
+	32: {name: "Tag_compatibility", str: true},
+	34: {name: "Tag_CPU_unaligned_access", values: []string{"None", "v6"}},
+	36: {name: "Tag_FP_HP_extension", values: []string{"Not Allowed", "Allowed"}},
+	38: {name: "Tag_ABI_FP_16bit_format", values: []string{"None", "IEEE 754", "Alternative Format"}},
+	42: {name: "Tag_MPextension_use", values: []string{"Not Allowed", "Allowed"}},
+	44: {name: "Tag_DIV_use", values: []string{"Allowed in Thumb-ISA, v7-R or v7-M", "Not allowed", "Allowed in v7-A with integer division extension"}},
+	46: {name: "Tag_DSP_extension", values: []string{"Follow architecture", "Allowed"}},
+	48: {name: "Tag_MVE_arch", values: []string{"No MVE", "MVE Integer only", "MVE Integer and FP"}},
+	50: {name: "Tag_PAC_extension", values: []string{"No PAC/AUT instructions", "PAC/AUT instructions permitted in the NOP space", "PAC/AUT instructions permitted in the NOP and in the non-NOP space"}},
+	52: {name: "Tag_BTI_extension", values: []string{"BTI instructions not permitted", "BTI instructions permitted in the NOP space", "BTI instructions permitted in the NOP and in the non-NOP space"}},
+	64: {name: "Tag_nodefaults"},
+	65: {name: "Tag_also_compatible_with", str: true},
+	66: {name: "Tag_T2EE_use", values: []string{"Not Allowed", "Allowed"}},
+	67: {name: "Tag_conformance", str: true},
+	68: {name: "Tag_Virtualization_use", values: []string{"Not Allowed", "TrustZone", "Virtualization Extensions", "TrustZone and Virtualization Extensions"}},
+	70: {name: "Tag_MPextension_use", values: []string{"Not Allowed", "Allowed"}},
+	74: {name: "Tag_FramePointer_use", values: []string{"No frame records", "Frame records permitted", "Frame records required"}},
+	76: {name: "Tag_BTI_use", values: []string{"Not compiled with branch target enforcement", "Compiled with branch target enforcement"}},
+	78: {name: "Tag_PACRET_use", values: []string{"Not compiled with return address signing and authentication", "Compiled with return address signing and authentication"}},
+}
+
+// riscvTags are the tags of the "riscv" subsection, from the RISC-V
+// ELF psABI.
+var riscvTags = map[uint64]tag{
+	4:  {name: "Tag_RISCV_stack_align", format: "%d-bytes"},
+	5:  {name: "Tag_RISCV_arch", str: true},
+	6:  {name: "Tag_RISCV_unaligned_access", values: []string{"No unaligned access", "Unaligned access"}},
+	8:  {name: "Tag_RISCV_priv_spec"},
+	10: {name: "Tag_RISCV_priv_spec_minor"},
+	12: {name: "Tag_RISCV_priv_spec_revision"},
+	14: {name: "Tag_RISCV_atomic_abi", values: []string{"UNKNOWN", "A6C", "A6S", "A7"}},
+	16: {name: "Tag_RISCV_x3_reg_usage", values: []string{"UNKNOWN", "GP", "SCS", "TMP"}},
+}
+
+// vendorTags maps the vendor of a subsection to its tags.
+var vendorTags = map[string]map[uint64]tag{
+	"aeabi": armTags,
+	"riscv": riscvTags,
+}
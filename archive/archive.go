@@ -0,0 +1,336 @@
+// Package archive reads ar archives, the static libraries of Unix
+// linkers: the GNU and BSD schemes for long member names, the symbol
+// indexes "/", "/SYM64/" and "__.SYMDEF", and GNU thin archives, whose
+// members are files next to the archive rather than copies in it.
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	magic     = "!<arch>\n"
+	thinMagic = "!<thin>\n"
+	headerLen = 60
+)
+
+// ErrNotArchive is returned for files without the ar magic.
+var ErrNotArchive = errors.New("not an ar archive")
+
+// A Member is a file of an archive.
+type Member struct {
+	Name string
+	Date int64
+	UID  int
+	GID  int
+	Mode uint32
+	Size int64
+	// Offset is the offset of the member header in the archive
+	Offset int64
+	// Path is the file of a member of a thin archive
+	Path string
+
+	data io.ReaderAt
+}
+
+// A ReadAtCloser is the contents of an opened member.
+type ReadAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+type nopCloser struct{ io.ReaderAt }
+
+func (nopCloser) Close() error { return nil }
+
+// Open returns the contents of m, opening the file of a thin archive
+// member.
+func (m *Member) Open() (ReadAtCloser, error) {
+	if m.data != nil {
+		return nopCloser{m.data}, nil
+	}
+	return os.Open(m.Path)
+}
+
+// A Symbol is an entry of the symbol index of an archive.
+type Symbol struct {
+	Name string
+	// Member defines the symbol; nil if the index points elsewhere
+	Member *Member
+	// Offset is the member header offset the index records
+	Offset int64
+}
+
+// An Archive is an opened ar archive.
+type Archive struct {
+	Thin    bool
+	Members []*Member
+	// Index is the name of the symbol index member, such as "/" or
+	// "__.SYMDEF", and IndexSize its size; "" if there is none
+	Index     string
+	IndexSize int64
+	Symbols   []Symbol
+
+	closer io.Closer
+}
+
+// Open opens the archive name.
+func Open(name string) (*Archive, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a, err := New(f, st.Size(), filepath.Dir(name))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a.closer = f
+	return a, nil
+}
+
+// Close closes the archive.
+func (a *Archive) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// New reads the archive of size bytes in r. The members of a thin
+// archive are looked up relative to dir.
+func New(r io.ReaderAt, size int64, dir string) (*Archive, error) {
+	var m [8]byte
+	if _, err := r.ReadAt(m[:], 0); err != nil {
+		return nil, ErrNotArchive
+	}
+	a := &Archive{}
+	switch string(m[:]) {
+	case magic:
+	case thinMagic:
+		a.Thin = true
+	default:
+		return nil, ErrNotArchive
+	}
+
+	var longNames []byte
+	var index []byte
+	for off := int64(len(magic)); off+headerLen <= size; {
+		var h [headerLen]byte
+		if _, err := r.ReadAt(h[:], off); err != nil {
+			return nil, err
+		}
+		if h[58] != '`' || h[59] != '\n' {
+			return nil, fmt.Errorf("bad member header at 0x%x", off)
+		}
+		mem := &Member{
+			Name:   strings.TrimRight(string(h[0:16]), " "),
+			Date:   field(h[16:28], 10),
+			UID:    int(field(h[28:34], 10)),
+			GID:    int(field(h[34:40], 10)),
+			Mode:   uint32(field(h[40:48], 8)),
+			Size:   field(h[48:58], 10),
+			Offset: off,
+		}
+		if mem.Size < 0 {
+			return nil, fmt.Errorf("bad member size at 0x%x", off)
+		}
+		data := off + headerLen
+		// the contents of thin archive members are elsewhere, except
+		// for the symbol index and the long names
+		stored := mem.Size
+		special := mem.Name == "/" || mem.Name == "/SYM64/" || mem.Name == "//"
+		if a.Thin && !special {
+			stored = 0
+		}
+		if data+stored > size {
+			return nil, fmt.Errorf("member at 0x%x: size %d past the end of the archive", off, mem.Size)
+		}
+		next := data + stored + stored%2
+
+		switch {
+		case mem.Name == "//":
+			longNames = make([]byte, mem.Size)
+			if _, err := r.ReadAt(longNames, data); err != nil {
+				return nil, err
+			}
+		case mem.Name == "/" || mem.Name == "/SYM64/":
+			if a.Index == "" {
+				index = make([]byte, mem.Size)
+				if _, err := r.ReadAt(index, data); err != nil {
+					return nil, err
+				}
+				a.Index, a.IndexSize = mem.Name, mem.Size
+			}
+		case strings.HasPrefix(mem.Name, "#1/"):
+			// BSD: the name follows the header and counts in the size
+			n, err := strconv.Atoi(mem.Name[3:])
+			if err != nil || int64(n) > mem.Size {
+				return nil, fmt.Errorf("member at 0x%x: bad name %q", off, mem.Name)
+			}
+			name := make([]byte, n)
+			if _, err := r.ReadAt(name, data); err != nil {
+				return nil, err
+			}
+			mem.Name = string(bytes.TrimRight(name, "\x00"))
+			data += int64(n)
+			mem.Size -= int64(n)
+		case strings.HasPrefix(mem.Name, "/") && len(mem.Name) > 1:
+			// GNU: an offset into the long names
+			n, err := strconv.ParseInt(mem.Name[1:], 10, 64)
+			if err != nil || n < 0 || n >= int64(len(longNames)) {
+				return nil, fmt.Errorf("member at 0x%x: bad long name %q", off, mem.Name)
+			}
+			name := longNames[n:]
+			if i := bytes.IndexByte(name, '\n'); i >= 0 {
+				name = name[:i]
+			}
+			mem.Name = strings.TrimSuffix(string(name), "/")
+		default:
+			mem.Name = strings.TrimSuffix(mem.Name, "/")
+		}
+
+		switch {
+		case special:
+		case strings.HasPrefix(mem.Name, "__.SYMDEF"):
+			if a.Index == "" {
+				index = make([]byte, mem.Size)
+				if _, err := r.ReadAt(index, data); err != nil {
+					return nil, err
+				}
+				a.Index, a.IndexSize = mem.Name, mem.Size
+			}
+		case a.Thin:
+			mem.Path = mem.Name
+			if !filepath.IsAbs(mem.Path) {
+				mem.Path = filepath.Join(dir, mem.Path)
+			}
+			a.Members = append(a.Members, mem)
+		default:
+			mem.data = io.NewSectionReader(r, data, mem.Size)
+			a.Members = append(a.Members, mem)
+		}
+		off = next
+	}
+
+	var err error
+	switch {
+	case a.Index == "/":
+		a.Symbols, err = gnuIndex(index, 4)
+	case a.Index == "/SYM64/":
+		a.Symbols, err = gnuIndex(index, 8)
+	case strings.HasPrefix(a.Index, "__.SYMDEF_64"):
+		a.Symbols, err = bsdIndex(index, 8)
+	case a.Index != "":
+		a.Symbols, err = bsdIndex(index, 4)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("symbol index %s: %v", a.Index, err)
+	}
+	byOffset := make(map[int64]*Member, len(a.Members))
+	for _, mem := range a.Members {
+		byOffset[mem.Offset] = mem
+	}
+	for i := range a.Symbols {
+		a.Symbols[i].Member = byOffset[a.Symbols[i].Offset]
+	}
+	return a, nil
+}
+
+// field parses a space padded number of a member header.
+func field(b []byte, base int) int64 {
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// gnuIndex decodes the big-endian symbol index of GNU and System V
+// archives: a count, the member offsets and the NUL-terminated names.
+func gnuIndex(b []byte, w int) ([]Symbol, error) {
+	word := func(b []byte) uint64 {
+		if w == 8 {
+			return binary.BigEndian.Uint64(b)
+		}
+		return uint64(binary.BigEndian.Uint32(b))
+	}
+	if len(b) < w {
+		return nil, errors.New("truncated")
+	}
+	n := word(b)
+	if n > uint64(len(b)/w) {
+		return nil, fmt.Errorf("%d symbols do not fit in %d bytes", n, len(b))
+	}
+	names := b[w+int(n)*w:]
+	syms := make([]Symbol, n)
+	for i := range syms {
+		end := bytes.IndexByte(names, 0)
+		if end < 0 {
+			return nil, errors.New("truncated names")
+		}
+		syms[i] = Symbol{Name: string(names[:end]), Offset: int64(word(b[w+i*w:]))}
+		names = names[end+1:]
+	}
+	return syms, nil
+}
+
+// bsdIndex decodes the ranlib structures of a BSD symbol index: the
+// size of an array of (name offset, member offset) pairs, the array,
+// the size of the names and the names. It is in the byte order of the
+// machine that wrote it, told apart by the sizes fitting.
+func bsdIndex(b []byte, w int) ([]Symbol, error) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		word := func(b []byte) uint64 {
+			if w == 8 {
+				return order.Uint64(b)
+			}
+			return uint64(order.Uint32(b))
+		}
+		if len(b) < w {
+			return nil, errors.New("truncated")
+		}
+		n := word(b)
+		if n%uint64(2*w) != 0 || n > uint64(len(b)-2*w) {
+			continue
+		}
+		strSize := word(b[w+int(n):])
+		strs := b[2*w+int(n):]
+		if strSize > uint64(len(strs)) {
+			continue
+		}
+		strs = strs[:strSize]
+		syms := make([]Symbol, n/uint64(2*w))
+		for i := range syms {
+			e := b[w+i*2*w:]
+			strx := word(e)
+			if strx >= uint64(len(strs)) {
+				return nil, fmt.Errorf("name offset 0x%x out of range", strx)
+			}
+			name := strs[strx:]
+			if end := bytes.IndexByte(name, 0); end >= 0 {
+				name = name[:end]
+			}
+			syms[i] = Symbol{Name: string(name), Offset: int64(word(e[w:]))}
+		}
+		return syms, nil
+	}
+	return nil, errors.New("bad ranlib sizes")
+}
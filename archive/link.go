@@ -0,0 +1,197 @@
+package archive
+
+import (
+	"debug/elf"
+	"elfreader/file"
+	"fmt"
+)
+
+// A Selection is an archive member the linker pulls in.
+type Selection struct {
+	Archive string
+	Member  *Member
+	// Symbol is the undefined symbol the member was pulled in for,
+	// and Ref the input that referenced it first
+	Symbol string
+	Ref    string
+}
+
+// An Undefined is a symbol no input defines.
+type Undefined struct {
+	Name string
+	// Ref is the input that referenced it first
+	Ref string
+}
+
+// A Linker simulates the archive scan of a traditional Unix linker.
+// Inputs are added in link order. Object files contribute all their
+// global symbols; an archive contributes only the members defining a
+// symbol undefined at the time it is scanned, found through its index,
+// and is rescanned until it adds no more members. Archives scanned
+// earlier are not revisited unless they are added as a group.
+type Linker struct {
+	Selected []Selection
+
+	defined map[string]string
+	// undefined holds the strong undefined symbols and the input
+	// referencing them first, in order
+	undefined map[string]string
+	order     []string
+	loaded    map[*Member]bool
+}
+
+// NewLinker returns a linker with no inputs.
+func NewLinker() *Linker {
+	return &Linker{
+		defined:   make(map[string]string),
+		undefined: make(map[string]string),
+		loaded:    make(map[*Member]bool),
+	}
+}
+
+// AddObject adds the symbols of the object file f, named name. The
+// undefined symbols of executables and shared objects count the same.
+func (l *Linker) AddObject(name string, f *file.File) error {
+	syms, err := f.Symbols()
+	if err != nil || len(syms) == 0 {
+		syms, err = f.DynamicSymbols()
+	}
+	if err != nil && err != file.ErrNoSymbols {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	for _, s := range syms {
+		bind := elf.ST_BIND(s.Info)
+		if s.Name == "" || bind != elf.STB_GLOBAL && bind != elf.STB_WEAK {
+			continue
+		}
+		switch {
+		case s.Section != elf.SHN_UNDEF:
+			if _, ok := l.defined[s.Name]; !ok {
+				l.defined[s.Name] = name
+			}
+			delete(l.undefined, s.Name)
+		case bind == elf.STB_WEAK:
+			// weak references do not pull in archive members
+		case l.defined[s.Name] == "":
+			if _, ok := l.undefined[s.Name]; !ok {
+				l.undefined[s.Name] = name
+				l.order = append(l.order, s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// AddArchive scans the archive a, named name.
+func (l *Linker) AddArchive(name string, a *Archive) error {
+	return l.AddGroup([]string{name}, []*Archive{a})
+}
+
+// AddGroup scans the archives as, named names, as a group like
+// --start-group and --end-group: all of them are rescanned until none
+// adds a member.
+func (l *Linker) AddGroup(names []string, as []*Archive) error {
+	index := make([][]Symbol, len(as))
+	for i, a := range as {
+		index[i] = a.index()
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, a := range as {
+			for {
+				n, err := l.scan(names[i], a, index[i])
+				if err != nil {
+					return err
+				}
+				if n == 0 {
+					break
+				}
+				changed = true
+			}
+		}
+	}
+	return nil
+}
+
+// scan pulls in the members of a defining a symbol of index that is
+// undefined, returning how many it added.
+func (l *Linker) scan(name string, a *Archive, index []Symbol) (int, error) {
+	n := 0
+	for _, s := range index {
+		ref, ok := l.undefined[s.Name]
+		if !ok || s.Member == nil || l.loaded[s.Member] {
+			continue
+		}
+		l.loaded[s.Member] = true
+		l.Selected = append(l.Selected, Selection{Archive: name, Member: s.Member, Symbol: s.Name, Ref: ref})
+		n++
+		f, err := openMember(s.Member)
+		if err != nil {
+			return n, fmt.Errorf("%s(%s): %v", name, s.Member.Name, err)
+		}
+		err = l.AddObject(fmt.Sprintf("%s(%s)", name, s.Member.Name), f.File)
+		f.Close()
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Unresolved returns the strong undefined symbols no input defines, in
+// the order they were first referenced.
+func (l *Linker) Unresolved() []Undefined {
+	var u []Undefined
+	for _, name := range l.order {
+		if ref, ok := l.undefined[name]; ok {
+			u = append(u, Undefined{name, ref})
+		}
+	}
+	return u
+}
+
+// index returns the symbol index of a or, for archives without one,
+// which linkers reject, the global symbols defined by its members.
+func (a *Archive) index() []Symbol {
+	if a.Index != "" {
+		return a.Symbols
+	}
+	var index []Symbol
+	for _, m := range a.Members {
+		f, err := openMember(m)
+		if err != nil {
+			// like ar, skip members that are not ELF files
+			continue
+		}
+		syms, _ := f.Symbols()
+		f.Close()
+		for _, s := range syms {
+			bind := elf.ST_BIND(s.Info)
+			if s.Name != "" && s.Section != elf.SHN_UNDEF && (bind == elf.STB_GLOBAL || bind == elf.STB_WEAK) {
+				index = append(index, Symbol{Name: s.Name, Member: m, Offset: m.Offset})
+			}
+		}
+	}
+	return index
+}
+
+// memberFile is an ELF member that closes its contents with it.
+type memberFile struct {
+	*file.File
+	r ReadAtCloser
+}
+
+func (f memberFile) Close() error { return f.r.Close() }
+
+func openMember(m *Member) (memberFile, error) {
+	r, err := m.Open()
+	if err != nil {
+		return memberFile{}, err
+	}
+	f := file.NewFile(r)
+	if f == nil {
+		r.Close()
+		return memberFile{}, fmt.Errorf("not a valid ELF file")
+	}
+	return memberFile{f, r}, nil
+}
@@ -0,0 +1,419 @@
+// Package bpf decodes eBPF objects: the BPF Type Format of .BTF and
+// .BTF.ext, the programs named by their libbpf section names, the map
+// definitions of .maps and maps, the license and the BPF relocations.
+// The BTF of a vmlinux image or a raw BTF blob decodes the same way.
+package bpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// btfMagic starts .BTF and .BTF.ext, in the byte order of the data.
+const btfMagic = 0xeb9f
+
+// ErrNotBTF is returned for data without the BTF magic.
+var ErrNotBTF = errors.New("not BTF data")
+
+// A Kind is the kind of a BTF type.
+type Kind int
+
+// BTF kinds.
+const (
+	KindUnknown Kind = iota
+	KindInt
+	KindPtr
+	KindArray
+	KindStruct
+	KindUnion
+	KindEnum
+	KindFwd
+	KindTypedef
+	KindVolatile
+	KindConst
+	KindRestrict
+	KindFunc
+	KindFuncProto
+	KindVar
+	KindDatasec
+	KindFloat
+	KindDeclTag
+	KindTypeTag
+	KindEnum64
+)
+
+var kindNames = []string{
+	"UNKNOWN", "INT", "PTR", "ARRAY", "STRUCT", "UNION", "ENUM", "FWD",
+	"TYPEDEF", "VOLATILE", "CONST", "RESTRICT", "FUNC", "FUNC_PROTO",
+	"VAR", "DATASEC", "FLOAT", "DECL_TAG", "TYPE_TAG", "ENUM64",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("KIND_%d", int(k))
+}
+
+// Encoding bits of BTF integers.
+const (
+	IntSigned = 1 << iota
+	IntChar
+	IntBool
+)
+
+// A Member is a member of a struct or union, or a parameter of a
+// function prototype.
+type Member struct {
+	Name string
+	Type uint32
+	// BitOffset and BitSize are zero for parameters; BitSize is zero
+	// for members that are not bitfields
+	BitOffset uint32
+	BitSize   uint32
+}
+
+// An Enumerator is a value of an enum.
+type Enumerator struct {
+	Name  string
+	Value int64
+}
+
+// A VarSecinfo places a variable of a DATASEC.
+type VarSecinfo struct {
+	Type   uint32
+	Offset uint32
+	Size   uint32
+}
+
+// A Type is a BTF type. Which fields are set depends on the kind.
+type Type struct {
+	ID       uint32
+	Kind     Kind
+	Name     string
+	KindFlag bool
+	// Size is the size in bytes of ints, floats, structs, unions,
+	// enums and datasecs
+	Size uint32
+	// Type is the type referred to by pointers, typedefs, qualifiers,
+	// functions, variables and tags, and the return type of prototypes
+	Type uint32
+
+	// ints
+	Encoding  uint8
+	BitOffset uint8
+	Bits      uint8
+
+	// arrays
+	Elem, Index, Elems uint32
+
+	// Members of structs and unions, or the parameters of prototypes
+	Members     []Member
+	Enumerators []Enumerator
+	// Linkage of functions and variables
+	Linkage uint32
+	Vars    []VarSecinfo
+	// Component of a decl tag; -1 tags the type itself
+	Component int32
+}
+
+// A Spec is a decoded .BTF.
+type Spec struct {
+	Order   binary.ByteOrder
+	Version uint8
+	Flags   uint8
+	// Types are indexed by type ID; Types[0] is void
+	Types   []*Type
+	strings []byte
+}
+
+// String returns the string at off of the string section.
+func (s *Spec) String(off uint32) string {
+	if int(off) >= len(s.strings) {
+		return ""
+	}
+	b := s.strings[off:]
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// Type returns the type id, or nil.
+func (s *Spec) Type(id uint32) *Type {
+	if int(id) >= len(s.Types) {
+		return nil
+	}
+	return s.Types[id]
+}
+
+// header returns the byte order of the BTF data b, told by its magic.
+func header(b []byte) (binary.ByteOrder, error) {
+	if len(b) < 8 {
+		return nil, ErrNotBTF
+	}
+	switch {
+	case binary.LittleEndian.Uint16(b) == btfMagic:
+		return binary.LittleEndian, nil
+	case binary.BigEndian.Uint16(b) == btfMagic:
+		return binary.BigEndian, nil
+	}
+	return nil, ErrNotBTF
+}
+
+// ParseBTF decodes the contents of a .BTF section or raw BTF file.
+func ParseBTF(b []byte) (*Spec, error) {
+	order, err := header(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 24 {
+		return nil, errors.New("truncated BTF header")
+	}
+	s := &Spec{Order: order, Version: b[2], Flags: b[3]}
+	hdrLen := order.Uint32(b[4:])
+	typeOff, typeLen := order.Uint32(b[8:]), order.Uint32(b[12:])
+	strOff, strLen := order.Uint32(b[16:]), order.Uint32(b[20:])
+	section := func(off, n uint32, what string) ([]byte, error) {
+		start, end := uint64(hdrLen)+uint64(off), uint64(hdrLen)+uint64(off)+uint64(n)
+		if end > uint64(len(b)) {
+			return nil, fmt.Errorf("BTF %s section past the end of the data", what)
+		}
+		return b[start:end], nil
+	}
+	types, err := section(typeOff, typeLen, "type")
+	if err != nil {
+		return nil, err
+	}
+	if s.strings, err = section(strOff, strLen, "string"); err != nil {
+		return nil, err
+	}
+	s.Types = []*Type{{Name: "void"}}
+	for len(types) > 0 {
+		t, n, err := s.parseType(types, uint32(len(s.Types)))
+		if err != nil {
+			return nil, err
+		}
+		s.Types = append(s.Types, t)
+		types = types[n:]
+	}
+	return s, nil
+}
+
+// parseType decodes the type at the start of b, returning it and its
+// size.
+func (s *Spec) parseType(b []byte, id uint32) (*Type, int, error) {
+	if len(b) < 12 {
+		return nil, 0, fmt.Errorf("type %d: truncated", id)
+	}
+	o := s.Order
+	info := o.Uint32(b[4:])
+	t := &Type{
+		ID:       id,
+		Name:     s.String(o.Uint32(b)),
+		Kind:     Kind(info >> 24 & 0x1f),
+		KindFlag: info>>31 != 0,
+	}
+	vlen := int(info & 0xffff)
+	sizeType := o.Uint32(b[8:])
+	n := 12
+	need := func(k int) error {
+		if len(b) < n+k {
+			return fmt.Errorf("type %d (%v): truncated", id, t.Kind)
+		}
+		return nil
+	}
+	switch t.Kind {
+	case KindInt:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		v := o.Uint32(b[n:])
+		t.Size, t.Encoding, t.BitOffset, t.Bits = sizeType, uint8(v>>24&0xf), uint8(v>>16), uint8(v)
+		n += 4
+	case KindPtr, KindTypedef, KindVolatile, KindConst, KindRestrict, KindTypeTag:
+		t.Type = sizeType
+	case KindFwd:
+	case KindFunc:
+		t.Type, t.Linkage = sizeType, uint32(vlen)
+	case KindFloat:
+		t.Size = sizeType
+	case KindArray:
+		if err := need(12); err != nil {
+			return nil, 0, err
+		}
+		t.Elem, t.Index, t.Elems = o.Uint32(b[n:]), o.Uint32(b[n+4:]), o.Uint32(b[n+8:])
+		n += 12
+	case KindStruct, KindUnion:
+		t.Size = sizeType
+		if err := need(12 * vlen); err != nil {
+			return nil, 0, err
+		}
+		for i := 0; i < vlen; i++ {
+			m := Member{Name: s.String(o.Uint32(b[n:])), Type: o.Uint32(b[n+4:])}
+			off := o.Uint32(b[n+8:])
+			if t.KindFlag {
+				m.BitOffset, m.BitSize = off&0xffffff, off>>24
+			} else {
+				m.BitOffset = off
+			}
+			t.Members = append(t.Members, m)
+			n += 12
+		}
+	case KindEnum:
+		t.Size = sizeType
+		if err := need(8 * vlen); err != nil {
+			return nil, 0, err
+		}
+		for i := 0; i < vlen; i++ {
+			// the kind flag marks signed enums
+			v := int64(o.Uint32(b[n+4:]))
+			if t.KindFlag {
+				v = int64(int32(v))
+			}
+			t.Enumerators = append(t.Enumerators, Enumerator{s.String(o.Uint32(b[n:])), v})
+			n += 8
+		}
+	case KindEnum64:
+		t.Size = sizeType
+		if err := need(12 * vlen); err != nil {
+			return nil, 0, err
+		}
+		for i := 0; i < vlen; i++ {
+			v := uint64(o.Uint32(b[n+8:]))<<32 | uint64(o.Uint32(b[n+4:]))
+			t.Enumerators = append(t.Enumerators, Enumerator{s.String(o.Uint32(b[n:])), int64(v)})
+			n += 12
+		}
+	case KindFuncProto:
+		t.Type = sizeType
+		if err := need(8 * vlen); err != nil {
+			return nil, 0, err
+		}
+		for i := 0; i < vlen; i++ {
+			t.Members = append(t.Members, Member{Name: s.String(o.Uint32(b[n:])), Type: o.Uint32(b[n+4:])})
+			n += 8
+		}
+	case KindVar:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		t.Type, t.Linkage = sizeType, o.Uint32(b[n:])
+		n += 4
+	case KindDatasec:
+		t.Size = sizeType
+		if err := need(12 * vlen); err != nil {
+			return nil, 0, err
+		}
+		for i := 0; i < vlen; i++ {
+			t.Vars = append(t.Vars, VarSecinfo{o.Uint32(b[n:]), o.Uint32(b[n+4:]), o.Uint32(b[n+8:])})
+			n += 12
+		}
+	case KindDeclTag:
+		if err := need(4); err != nil {
+			return nil, 0, err
+		}
+		t.Type, t.Component = sizeType, int32(o.Uint32(b[n:]))
+		n += 4
+	default:
+		return nil, 0, fmt.Errorf("type %d: unknown kind %d", id, int(t.Kind))
+	}
+	return t, n, nil
+}
+
+// Resolve follows typedefs, qualifiers and type tags from id to the
+// type they stand for.
+func (s *Spec) Resolve(id uint32) *Type {
+	for i := 0; i < 64; i++ {
+		t := s.Type(id)
+		if t == nil {
+			return nil
+		}
+		switch t.Kind {
+		case KindTypedef, KindVolatile, KindConst, KindRestrict, KindTypeTag:
+			id = t.Type
+		default:
+			return t
+		}
+	}
+	return nil
+}
+
+// TypeName returns the C spelling of the type id, such as
+// "const char *" or "struct task_struct".
+func (s *Spec) TypeName(id uint32) string {
+	return s.declare(id, "", 0)
+}
+
+// declare spells a declaration of name with the type id, the way C
+// wraps declarators around the name.
+func (s *Spec) declare(id uint32, name string, depth int) string {
+	t := s.Type(id)
+	if t == nil || depth > 32 {
+		return strings.TrimSpace(fmt.Sprintf("<type %d> %s", id, name))
+	}
+	join := func(base string) string {
+		if name == "" {
+			return base
+		}
+		return base + " " + name
+	}
+	tag := func(prefix string) string {
+		if t.Name == "" {
+			return prefix + " (anon)"
+		}
+		return prefix + " " + t.Name
+	}
+	switch t.Kind {
+	case KindUnknown:
+		return join("void")
+	case KindInt, KindFloat, KindTypedef:
+		return join(t.Name)
+	case KindStruct:
+		return join(tag("struct"))
+	case KindUnion:
+		return join(tag("union"))
+	case KindEnum, KindEnum64:
+		return join(tag("enum"))
+	case KindFwd:
+		if t.KindFlag {
+			return join(tag("union"))
+		}
+		return join(tag("struct"))
+	case KindPtr:
+		inner := "*" + name
+		if p := s.Type(t.Type); p != nil && (p.Kind == KindArray || p.Kind == KindFuncProto) {
+			inner = "(" + inner + ")"
+		}
+		return s.declare(t.Type, inner, depth+1)
+	case KindConst, KindVolatile, KindRestrict:
+		q := strings.ToLower(t.Kind.String())
+		if p := s.Type(t.Type); p != nil && p.Kind == KindPtr {
+			return s.declare(t.Type, strings.TrimSpace(q+" "+name), depth+1)
+		}
+		return q + " " + s.declare(t.Type, name, depth+1)
+	case KindTypeTag:
+		return s.declare(t.Type, name, depth+1)
+	case KindArray:
+		return s.declare(t.Elem, fmt.Sprintf("%s[%d]", name, t.Elems), depth+1)
+	case KindFuncProto:
+		var params []string
+		for _, p := range t.Members {
+			if p.Type == 0 {
+				params = append(params, "...")
+				continue
+			}
+			params = append(params, s.declare(p.Type, p.Name, depth+1))
+		}
+		if len(params) == 0 {
+			params = []string{"void"}
+		}
+		return s.declare(t.Type, name+"("+strings.Join(params, ", ")+")", depth+1)
+	case KindFunc, KindVar:
+		return s.declare(t.Type, name, depth+1)
+	}
+	return join(t.Name)
+}
@@ -0,0 +1,144 @@
+package bpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// A FuncInfo maps the first instruction of a function to its BTF FUNC.
+type FuncInfo struct {
+	Section string
+	// InsnOff is the byte offset of the instruction in the section
+	InsnOff uint32
+	TypeID  uint32
+}
+
+// A LineInfo is the source line of an instruction.
+type LineInfo struct {
+	Section string
+	InsnOff uint32
+	File    string
+	// Text is the source line itself, as recorded by the compiler
+	Text   string
+	Line   uint32
+	Column uint32
+}
+
+// A CORERelo is a CO-RE relocation: an instruction to adjust to the
+// BTF of the running kernel.
+type CORERelo struct {
+	Section string
+	InsnOff uint32
+	TypeID  uint32
+	// Access is the accessor string, such as "0:1:2", listing the
+	// member or element indices from TypeID
+	Access string
+	Kind   uint32
+}
+
+var coreReloKinds = []string{
+	"field_byte_offset", "field_byte_size", "field_exists", "field_signed",
+	"field_lshift_u64", "field_rshift_u64", "type_id_local", "type_id_target",
+	"type_exists", "type_size", "enumval_exists", "enumval_value",
+	"type_matches",
+}
+
+// CORERelocKindName returns the libbpf name of the CO-RE relocation
+// kind k.
+func CORERelocKindName(k uint32) string {
+	if int(k) < len(coreReloKinds) {
+		return coreReloKinds[k]
+	}
+	return fmt.Sprintf("%d", k)
+}
+
+// An Ext is a decoded .BTF.ext, whose names are in the strings of the
+// .BTF it goes with.
+type Ext struct {
+	Funcs []FuncInfo
+	Lines []LineInfo
+	CORE  []CORERelo
+}
+
+// ParseExt decodes the .BTF.ext b, taking the names from spec.
+func ParseExt(b []byte, spec *Spec) (*Ext, error) {
+	order, err := header(b)
+	if err != nil {
+		return nil, err
+	}
+	hdrLen := order.Uint32(b[4:])
+	if hdrLen < 24 || int(hdrLen) > len(b) {
+		return nil, errors.New("bad BTF.ext header length")
+	}
+	e := &Ext{}
+	err = records(b, order, hdrLen, 8, 8, func(sec string, r []byte) {
+		e.Funcs = append(e.Funcs, FuncInfo{sec, order.Uint32(r), order.Uint32(r[4:])})
+	}, spec)
+	if err != nil {
+		return nil, fmt.Errorf("func info: %v", err)
+	}
+	err = records(b, order, hdrLen, 16, 16, func(sec string, r []byte) {
+		lc := order.Uint32(r[12:])
+		e.Lines = append(e.Lines, LineInfo{
+			Section: sec,
+			InsnOff: order.Uint32(r),
+			File:    spec.String(order.Uint32(r[4:])),
+			Text:    spec.String(order.Uint32(r[8:])),
+			Line:    lc >> 10,
+			Column:  lc & 0x3ff,
+		})
+	}, spec)
+	if err != nil {
+		return nil, fmt.Errorf("line info: %v", err)
+	}
+	if hdrLen >= 32 {
+		err = records(b, order, hdrLen, 24, 16, func(sec string, r []byte) {
+			e.CORE = append(e.CORE, CORERelo{
+				Section: sec,
+				InsnOff: order.Uint32(r),
+				TypeID:  order.Uint32(r[4:]),
+				Access:  spec.String(order.Uint32(r[8:])),
+				Kind:    order.Uint32(r[12:]),
+			})
+		}, spec)
+		if err != nil {
+			return nil, fmt.Errorf("CO-RE relocations: %v", err)
+		}
+	}
+	return e, nil
+}
+
+// records walks the subsection whose offset and length are at hdrOff
+// of the header: a record size, then per program section its name, a
+// count and the records, of at least min bytes each.
+func records(b []byte, order binary.ByteOrder, hdrLen uint32, hdrOff, min int, fn func(string, []byte), spec *Spec) error {
+	off, n := order.Uint32(b[hdrOff:]), order.Uint32(b[hdrOff+4:])
+	if n == 0 {
+		return nil
+	}
+	start := uint64(hdrLen) + uint64(off)
+	if start+uint64(n) > uint64(len(b)) || n < 4 {
+		return errors.New("past the end of the data")
+	}
+	d := b[start : start+uint64(n)]
+	size := int(order.Uint32(d))
+	if size < min {
+		return fmt.Errorf("record size %d below %d", size, min)
+	}
+	for d = d[4:]; len(d) > 0; {
+		if len(d) < 8 {
+			return errors.New("truncated section header")
+		}
+		sec, count := spec.String(order.Uint32(d)), int(order.Uint32(d[4:]))
+		d = d[8:]
+		if count*size > len(d) || count < 0 {
+			return fmt.Errorf("%s: %d records past the end of the data", sec, count)
+		}
+		for i := 0; i < count; i++ {
+			fn(sec, d[i*size:])
+		}
+		d = d[count*size:]
+	}
+	return nil
+}
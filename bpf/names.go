@@ -0,0 +1,184 @@
+package bpf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A secDef maps a libbpf section name prefix to a program type and
+// how the rest of the name is read.
+type secDef struct {
+	prefix string
+	// exact is set for names that take no attach target
+	exact    bool
+	progType string
+	attach   string
+}
+
+// secDefs follow the section_defs table of libbpf; longer prefixes come
+// before the shorter ones they extend.
+var secDefs = []secDef{
+	{"socket", true, "socket_filter", ""},
+	{"sk_reuseport/migrate", true, "sk_reuseport", "sk_reuseport_select_or_migrate"},
+	{"sk_reuseport", true, "sk_reuseport", "sk_reuseport_select"},
+	{"kprobe.multi/", false, "kprobe", "kprobe_multi"},
+	{"kretprobe.multi/", false, "kprobe", "kprobe_multi (return)"},
+	{"kprobe.session/", false, "kprobe", "kprobe_session"},
+	{"uprobe.multi.s/", false, "kprobe", "uprobe_multi (sleepable)"},
+	{"uprobe.multi/", false, "kprobe", "uprobe_multi"},
+	{"uretprobe.multi.s/", false, "kprobe", "uprobe_multi (return, sleepable)"},
+	{"uretprobe.multi/", false, "kprobe", "uprobe_multi (return)"},
+	{"kprobe/", false, "kprobe", "kprobe"},
+	{"kretprobe/", false, "kprobe", "kretprobe"},
+	{"ksyscall/", false, "kprobe", "ksyscall"},
+	{"kretsyscall/", false, "kprobe", "kretsyscall"},
+	{"uprobe.s", false, "kprobe", "uprobe (sleepable)"},
+	{"uprobe", false, "kprobe", "uprobe"},
+	{"uretprobe.s", false, "kprobe", "uretprobe (sleepable)"},
+	{"uretprobe", false, "kprobe", "uretprobe"},
+	{"usdt.s", false, "kprobe", "usdt (sleepable)"},
+	{"usdt", false, "kprobe", "usdt"},
+	{"tc/ingress", true, "sched_cls", "tcx_ingress"},
+	{"tc/egress", true, "sched_cls", "tcx_egress"},
+	{"tcx/ingress", true, "sched_cls", "tcx_ingress"},
+	{"tcx/egress", true, "sched_cls", "tcx_egress"},
+	{"netkit/primary", true, "sched_cls", "netkit_primary"},
+	{"netkit/peer", true, "sched_cls", "netkit_peer"},
+	{"tc", true, "sched_cls", ""},
+	{"classifier", true, "sched_cls", ""},
+	{"action", true, "sched_act", ""},
+	{"tracepoint/", false, "tracepoint", "tracepoint"},
+	{"tp/", false, "tracepoint", "tracepoint"},
+	{"raw_tracepoint.w/", false, "raw_tracepoint_writable", "raw_tracepoint"},
+	{"raw_tp.w/", false, "raw_tracepoint_writable", "raw_tracepoint"},
+	{"raw_tracepoint/", false, "raw_tracepoint", "raw_tracepoint"},
+	{"raw_tp/", false, "raw_tracepoint", "raw_tracepoint"},
+	{"tp_btf/", false, "tracing", "trace_raw_tp"},
+	{"fentry.s/", false, "tracing", "trace_fentry (sleepable)"},
+	{"fentry/", false, "tracing", "trace_fentry"},
+	{"fmod_ret.s/", false, "tracing", "modify_return (sleepable)"},
+	{"fmod_ret/", false, "tracing", "modify_return"},
+	{"fexit.s/", false, "tracing", "trace_fexit (sleepable)"},
+	{"fexit/", false, "tracing", "trace_fexit"},
+	{"freplace/", false, "ext", "freplace"},
+	{"lsm.s/", false, "lsm", "lsm_mac (sleepable)"},
+	{"lsm_cgroup/", false, "lsm", "lsm_cgroup"},
+	{"lsm/", false, "lsm", "lsm_mac"},
+	{"iter.s/", false, "tracing", "trace_iter (sleepable)"},
+	{"iter/", false, "tracing", "trace_iter"},
+	{"syscall", true, "syscall", ""},
+	{"xdp.frags/devmap", true, "xdp", "xdp_devmap (frags)"},
+	{"xdp/devmap", true, "xdp", "xdp_devmap"},
+	{"xdp.frags/cpumap", true, "xdp", "xdp_cpumap (frags)"},
+	{"xdp/cpumap", true, "xdp", "xdp_cpumap"},
+	{"xdp.frags", true, "xdp", "xdp (frags)"},
+	{"xdp", true, "xdp", ""},
+	{"perf_event", true, "perf_event", ""},
+	{"lwt_in", true, "lwt_in", ""},
+	{"lwt_out", true, "lwt_out", ""},
+	{"lwt_xmit", true, "lwt_xmit", ""},
+	{"lwt_seg6local", true, "lwt_seg6local", ""},
+	{"sockops", true, "sock_ops", "cgroup_sock_ops"},
+	{"sk_skb/stream_parser", true, "sk_skb", "sk_skb_stream_parser"},
+	{"sk_skb/stream_verdict", true, "sk_skb", "sk_skb_stream_verdict"},
+	{"sk_skb/verdict", true, "sk_skb", "sk_skb_verdict"},
+	{"sk_skb", true, "sk_skb", ""},
+	{"sk_msg", true, "sk_msg", "sk_msg_verdict"},
+	{"lirc_mode2", true, "lirc_mode2", "lirc_mode2"},
+	{"flow_dissector", true, "flow_dissector", "flow_dissector"},
+	{"cgroup_skb/ingress", true, "cgroup_skb", "cgroup_inet_ingress"},
+	{"cgroup_skb/egress", true, "cgroup_skb", "cgroup_inet_egress"},
+	{"cgroup/skb", true, "cgroup_skb", ""},
+	{"cgroup/sock_create", true, "cgroup_sock", "cgroup_inet_sock_create"},
+	{"cgroup/sock_release", true, "cgroup_sock", "cgroup_inet_sock_release"},
+	{"cgroup/sock", true, "cgroup_sock", "cgroup_inet_sock_create"},
+	{"cgroup/post_bind4", true, "cgroup_sock", "cgroup_inet4_post_bind"},
+	{"cgroup/post_bind6", true, "cgroup_sock", "cgroup_inet6_post_bind"},
+	{"cgroup/dev", true, "cgroup_device", "cgroup_device"},
+	{"cgroup/bind4", true, "cgroup_sock_addr", "cgroup_inet4_bind"},
+	{"cgroup/bind6", true, "cgroup_sock_addr", "cgroup_inet6_bind"},
+	{"cgroup/connect4", true, "cgroup_sock_addr", "cgroup_inet4_connect"},
+	{"cgroup/connect6", true, "cgroup_sock_addr", "cgroup_inet6_connect"},
+	{"cgroup/connect_unix", true, "cgroup_sock_addr", "cgroup_unix_connect"},
+	{"cgroup/sendmsg4", true, "cgroup_sock_addr", "cgroup_udp4_sendmsg"},
+	{"cgroup/sendmsg6", true, "cgroup_sock_addr", "cgroup_udp6_sendmsg"},
+	{"cgroup/sendmsg_unix", true, "cgroup_sock_addr", "cgroup_unix_sendmsg"},
+	{"cgroup/recvmsg4", true, "cgroup_sock_addr", "cgroup_udp4_recvmsg"},
+	{"cgroup/recvmsg6", true, "cgroup_sock_addr", "cgroup_udp6_recvmsg"},
+	{"cgroup/recvmsg_unix", true, "cgroup_sock_addr", "cgroup_unix_recvmsg"},
+	{"cgroup/getpeername4", true, "cgroup_sock_addr", "cgroup_inet4_getpeername"},
+	{"cgroup/getpeername6", true, "cgroup_sock_addr", "cgroup_inet6_getpeername"},
+	{"cgroup/getsockname4", true, "cgroup_sock_addr", "cgroup_inet4_getsockname"},
+	{"cgroup/getsockname6", true, "cgroup_sock_addr", "cgroup_inet6_getsockname"},
+	{"cgroup/sysctl", true, "cgroup_sysctl", "cgroup_sysctl"},
+	{"cgroup/getsockopt", true, "cgroup_sockopt", "cgroup_getsockopt"},
+	{"cgroup/setsockopt", true, "cgroup_sockopt", "cgroup_setsockopt"},
+	{"struct_ops.s/", false, "struct_ops", "struct_ops (sleepable)"},
+	{"struct_ops/", false, "struct_ops", "struct_ops"},
+	{"struct_ops.s", true, "struct_ops", "struct_ops (sleepable)"},
+	{"struct_ops", true, "struct_ops", "struct_ops"},
+	{"sk_lookup", true, "sk_lookup", "sk_lookup"},
+	{"netfilter", true, "netfilter", "netfilter"},
+}
+
+// ProgramType returns the program type, the attach type and the
+// attach target libbpf reads from the program section name sec, or
+// ok false if it does not know the name.
+func ProgramType(sec string) (progType, attach, target string, ok bool) {
+	for _, d := range secDefs {
+		switch {
+		case d.exact && (sec == d.prefix || strings.HasPrefix(sec, d.prefix+"/")):
+			return d.progType, d.attach, strings.TrimPrefix(strings.TrimPrefix(sec, d.prefix), "/"), true
+		case !d.exact && strings.HasPrefix(sec, d.prefix):
+			return d.progType, d.attach, strings.TrimPrefix(strings.TrimPrefix(sec, d.prefix), "/"), true
+		}
+	}
+	return "", "", "", false
+}
+
+var mapTypes = []string{
+	"unspec", "hash", "array", "prog_array", "perf_event_array",
+	"percpu_hash", "percpu_array", "stack_trace", "cgroup_array",
+	"lru_hash", "lru_percpu_hash", "lpm_trie", "array_of_maps",
+	"hash_of_maps", "devmap", "sockmap", "cpumap", "xskmap", "sockhash",
+	"cgroup_storage", "reuseport_sockarray", "percpu_cgroup_storage",
+	"queue", "stack", "sk_storage", "devmap_hash", "struct_ops", "ringbuf",
+	"inode_storage", "task_storage", "bloom_filter", "user_ringbuf",
+	"cgrp_storage", "arena",
+}
+
+// MapTypeName returns the name of the BPF map type t, as bpftool shows
+// it.
+func MapTypeName(t uint32) string {
+	if int(t) < len(mapTypes) {
+		return mapTypes[t]
+	}
+	return fmt.Sprintf("type %d", t)
+}
+
+// BPF relocation types.
+const (
+	rBPFNone       = 0
+	rBPF64_64      = 1
+	rBPF64Abs64    = 2
+	rBPF64Abs32    = 3
+	rBPF64NoDyld32 = 4
+	rBPF64_32      = 10
+)
+
+var relocNames = map[uint32]string{
+	rBPFNone:       "R_BPF_NONE",
+	rBPF64_64:      "R_BPF_64_64",
+	rBPF64Abs64:    "R_BPF_64_ABS64",
+	rBPF64Abs32:    "R_BPF_64_ABS32",
+	rBPF64NoDyld32: "R_BPF_64_NODYLD32",
+	rBPF64_32:      "R_BPF_64_32",
+}
+
+// RelocName returns the name of the BPF relocation type t.
+func RelocName(t uint32) string {
+	if s, ok := relocNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("R_BPF_%d", t)
+}
@@ -0,0 +1,330 @@
+package bpf
+
+import (
+	"debug/elf"
+	"elfreader/file"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoBTF is returned by LoadBTF for files without a .BTF section.
+var ErrNoBTF = errors.New("no .BTF section")
+
+// A Program is a BPF program, or a subprogram of .text that programs
+// call.
+type Program struct {
+	Name    string
+	Section string
+	// Offset and Size locate the program in its section, in bytes
+	Offset, Size uint64
+	// ProgType, Attach and Target are what libbpf reads from the
+	// section name; ProgType is "" for subprograms and unknown names
+	ProgType, Attach, Target string
+}
+
+// Insns returns the number of instruction slots of p; a 64-bit
+// immediate load takes two.
+func (p Program) Insns() uint64 { return p.Size / 8 }
+
+// A Map is a map definition.
+type Map struct {
+	Name string
+	// Section is ".maps" for BTF-defined maps and "maps" for legacy
+	// struct bpf_map_def ones
+	Section    string
+	Type       uint32
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	Flags      uint32
+	Pinning    uint32
+	// Key and Value are the BTF types of key and value, if given
+	Key, Value string
+}
+
+// A Reloc is a relocation of a program section.
+type Reloc struct {
+	Section string
+	// Offset is the byte offset of the instruction in Section
+	Offset uint64
+	Type   uint32
+	Symbol string
+	// Target names what the symbol is: "map", "global data", "call",
+	// "func pointer" or "extern"
+	Target string
+}
+
+// An Object is a decoded BPF ELF object.
+type Object struct {
+	License string
+	// Version is the kernel version of the version section, which old
+	// kernels checked for kprobes; HasVersion is false without it
+	Version    uint32
+	HasVersion bool
+	Programs   []Program
+	Maps       []Map
+	Relocs     []Reloc
+	// BTF and Ext are nil without .BTF and .BTF.ext
+	BTF *Spec
+	Ext *Ext
+}
+
+// LoadBTF decodes the .BTF section of f, such as that of a vmlinux
+// image.
+func LoadBTF(f *file.File) (*Spec, error) {
+	s := f.Section(".BTF")
+	if s == nil || s.Type == elf.SHT_NOBITS {
+		return nil, ErrNoBTF
+	}
+	return ParseBTF(s.Data())
+}
+
+// Open decodes the BPF object f.
+func Open(f *file.File) (*Object, error) {
+	if f.Machine != elf.EM_BPF {
+		return nil, fmt.Errorf("machine %v is not EM_BPF", f.Machine)
+	}
+	o := &Object{}
+	var err error
+	if o.BTF, err = LoadBTF(f); err != nil && err != ErrNoBTF {
+		return nil, fmt.Errorf(".BTF: %v", err)
+	}
+	if s := f.Section(".BTF.ext"); s != nil && o.BTF != nil {
+		if o.Ext, err = ParseExt(s.Data(), o.BTF); err != nil {
+			return nil, fmt.Errorf(".BTF.ext: %v", err)
+		}
+	}
+	if s := f.Section("license"); s != nil {
+		b := s.Data()
+		if i := strings.IndexByte(string(b), 0); i >= 0 {
+			b = b[:i]
+		}
+		o.License = string(b)
+	}
+	if s := f.Section("version"); s != nil {
+		if b := s.Data(); len(b) >= 4 {
+			o.Version, o.HasVersion = f.ByteOrder.Uint32(b), true
+		}
+	}
+
+	syms, err := f.Symbols()
+	if err != nil && err != file.ErrNoSymbols {
+		return nil, err
+	}
+	o.programs(f, syms)
+	o.legacyMaps(f, syms)
+	o.btfMaps()
+	if err := o.relocs(f, syms); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// programs collects the functions of the executable sections.
+func (o *Object) programs(f *file.File, syms []file.Symbol) {
+	for i, s := range f.Sections {
+		if s.Flags&elf.SHF_EXECINSTR == 0 || s.Size == 0 {
+			continue
+		}
+		var progs []Program
+		for _, sym := range syms {
+			if int(sym.Section) != i || elf.ST_TYPE(sym.Info) != elf.STT_FUNC {
+				continue
+			}
+			progs = append(progs, Program{Name: sym.Name, Section: s.Name, Offset: sym.Value, Size: sym.Size})
+		}
+		if len(progs) == 0 {
+			progs = []Program{{Name: s.Name, Section: s.Name, Size: s.Size}}
+		}
+		sort.Slice(progs, func(i, j int) bool { return progs[i].Offset < progs[j].Offset })
+		if s.Name != ".text" {
+			for j := range progs {
+				progs[j].ProgType, progs[j].Attach, progs[j].Target, _ = ProgramType(s.Name)
+			}
+		}
+		o.Programs = append(o.Programs, progs...)
+	}
+}
+
+// legacyMaps decodes the struct bpf_map_def of the maps section.
+func (o *Object) legacyMaps(f *file.File, syms []file.Symbol) {
+	var sec *file.Section
+	idx := -1
+	for i, s := range f.Sections {
+		if s.Name == "maps" {
+			sec, idx = s, i
+		}
+	}
+	if sec == nil || sec.Type == elf.SHT_NOBITS {
+		return
+	}
+	b := sec.Data()
+	for _, sym := range syms {
+		if int(sym.Section) != idx || sym.Name == "" || elf.ST_TYPE(sym.Info) == elf.STT_SECTION {
+			continue
+		}
+		if sym.Value+20 > uint64(len(b)) {
+			continue
+		}
+		d := b[sym.Value:]
+		u := func(i int) uint32 { return f.ByteOrder.Uint32(d[4*i:]) }
+		o.Maps = append(o.Maps, Map{
+			Name: sym.Name, Section: "maps",
+			Type: u(0), KeySize: u(1), ValueSize: u(2), MaxEntries: u(3), Flags: u(4),
+		})
+	}
+}
+
+// btfMaps decodes the maps of the .maps DATASEC: each variable is a
+// struct whose members encode integers as pointers to arrays of that
+// many elements, and types as pointers to them.
+func (o *Object) btfMaps() {
+	s := o.BTF
+	if s == nil {
+		return
+	}
+	for _, t := range s.Types {
+		if t.Kind != KindDatasec || t.Name != ".maps" {
+			continue
+		}
+		for _, v := range t.Vars {
+			vt := s.Type(v.Type)
+			if vt == nil || vt.Kind != KindVar {
+				continue
+			}
+			m := Map{Name: vt.Name, Section: ".maps"}
+			def := s.Resolve(vt.Type)
+			if def == nil || def.Kind != KindStruct && def.Kind != KindUnion {
+				continue
+			}
+			for _, mem := range def.Members {
+				switch mem.Name {
+				case "type":
+					m.Type = s.ptrInt(mem.Type)
+				case "max_entries":
+					m.MaxEntries = s.ptrInt(mem.Type)
+				case "map_flags":
+					m.Flags = s.ptrInt(mem.Type)
+				case "key_size":
+					m.KeySize = s.ptrInt(mem.Type)
+				case "value_size":
+					m.ValueSize = s.ptrInt(mem.Type)
+				case "pinning":
+					m.Pinning = s.ptrInt(mem.Type)
+				case "key", "value":
+					p := s.Resolve(mem.Type)
+					if p == nil || p.Kind != KindPtr {
+						continue
+					}
+					size, _ := s.Size(p.Type)
+					if mem.Name == "key" {
+						m.Key, m.KeySize = s.TypeName(p.Type), uint32(size)
+					} else {
+						m.Value, m.ValueSize = s.TypeName(p.Type), uint32(size)
+					}
+				}
+			}
+			o.Maps = append(o.Maps, m)
+		}
+	}
+}
+
+// ptrInt decodes the integer of a __uint(name, val) map member: a
+// pointer to an array of val elements.
+func (s *Spec) ptrInt(id uint32) uint32 {
+	p := s.Resolve(id)
+	if p == nil || p.Kind != KindPtr {
+		return 0
+	}
+	a := s.Resolve(p.Type)
+	if a == nil || a.Kind != KindArray {
+		return 0
+	}
+	return a.Elems
+}
+
+// Size returns the size in bytes of the type id.
+func (s *Spec) Size(id uint32) (uint64, error) {
+	for i := 0; i < 64; i++ {
+		t := s.Type(id)
+		if t == nil {
+			return 0, fmt.Errorf("type %d does not exist", id)
+		}
+		switch t.Kind {
+		case KindInt, KindFloat, KindStruct, KindUnion, KindEnum, KindEnum64, KindDatasec:
+			return uint64(t.Size), nil
+		case KindPtr:
+			return 8, nil
+		case KindArray:
+			n, err := s.Size(t.Elem)
+			return n * uint64(t.Elems), err
+		case KindTypedef, KindVolatile, KindConst, KindRestrict, KindTypeTag, KindVar:
+			id = t.Type
+		default:
+			return 0, fmt.Errorf("type %d (%v) has no size", id, t.Kind)
+		}
+	}
+	return 0, fmt.Errorf("type %d: reference loop", id)
+}
+
+// relocs decodes the relocations of the program and map sections.
+func (o *Object) relocs(f *file.File, syms []file.Symbol) error {
+	for _, rs := range f.Sections {
+		if rs.Type != elf.SHT_REL || int(rs.Info) >= len(f.Sections) {
+			continue
+		}
+		target := f.Sections[rs.Info]
+		if target.Flags&elf.SHF_EXECINSTR == 0 && target.Name != ".maps" {
+			// .BTF, .BTF.ext and DWARF
+			continue
+		}
+		b := rs.Data()
+		size := 16
+		if f.Class == elf.ELFCLASS32 {
+			size = 8
+		}
+		for ; len(b) >= size; b = b[size:] {
+			var off uint64
+			var sym, typ uint32
+			if size == 16 {
+				info := f.ByteOrder.Uint64(b[8:])
+				off, sym, typ = f.ByteOrder.Uint64(b), uint32(info>>32), uint32(info)
+			} else {
+				info := f.ByteOrder.Uint32(b[4:])
+				off, sym, typ = uint64(f.ByteOrder.Uint32(b)), info>>8, info&0xff
+			}
+			r := Reloc{Section: target.Name, Offset: off, Type: typ}
+			if sym > 0 && int(sym) <= len(syms) {
+				s := syms[sym-1]
+				r.Symbol, r.Target = s.Name, relocTarget(f, s, typ)
+				if r.Symbol == "" && int(s.Section) < len(f.Sections) {
+					r.Symbol = f.Sections[s.Section].Name
+				}
+			}
+			o.Relocs = append(o.Relocs, r)
+		}
+	}
+	return nil
+}
+
+// relocTarget tells what the symbol s of a relocation of type typ is.
+func relocTarget(f *file.File, s file.Symbol, typ uint32) string {
+	if s.Section == elf.SHN_UNDEF {
+		return "extern"
+	}
+	if int(s.Section) >= len(f.Sections) {
+		return ""
+	}
+	sec := f.Sections[s.Section]
+	switch {
+	case sec.Name == ".maps" || sec.Name == "maps":
+		return "map"
+	case sec.Flags&elf.SHF_EXECINSTR != 0 && typ == rBPF64_32:
+		return "call"
+	case sec.Flags&elf.SHF_EXECINSTR != 0:
+		return "func pointer"
+	}
+	return "global data"
+}
@@ -0,0 +1,243 @@
+// Package cfi decodes DWARF call frame information: the common
+// information entries (CIEs) and frame description entries (FDEs) of
+// .debug_frame and .eh_frame, the binary search table of
+// .eh_frame_hdr and the DW_CFA instructions describing how to unwind
+// each function.
+package cfi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// A CIE is a common information entry, holding what the FDEs of a
+// group of functions share.
+type CIE struct {
+	Offset       uint64
+	Length       uint64
+	Dwarf64      bool
+	Version      int
+	Augmentation string
+	AddrSize     int
+	SegSize      int
+	CodeAlign    uint64
+	DataAlign    int64
+	RAReg        uint64
+
+	// The fields below are set from the augmentation of .eh_frame CIEs.
+	// AugmentationData holds the raw data of a "z" augmentation
+	AugmentationData []byte
+	// FDEEncoding is the pointer encoding of the addresses of the FDEs
+	FDEEncoding uint8
+	// LSDAEncoding is the pointer encoding of the LSDA pointers of the
+	// FDEs, EncOmit if they have none
+	LSDAEncoding uint8
+	// Personality is the address of the personality routine, or of the
+	// pointer to it when PersonalityEncoding is indirect; valid if
+	// HasPersonality
+	Personality         uint64
+	PersonalityEncoding uint8
+	HasPersonality      bool
+	// SignalFrame marks the frames of signal handlers
+	SignalFrame bool
+
+	// Instructions set up the initial rules of every FDE of the CIE
+	Instructions []byte
+
+	order binary.ByteOrder
+	eh    bool
+}
+
+// An FDE is a frame description entry, describing the unwinding of
+// the address range [Begin, Begin+Range).
+type FDE struct {
+	Offset uint64
+	Length uint64
+	CIE    *CIE
+	Begin  uint64
+	Range  uint64
+	// AugmentationData holds the raw data of a "z" augmentation
+	AugmentationData []byte
+	// LSDA is the address of the language specific data area of the
+	// function, valid if HasLSDA
+	LSDA         uint64
+	HasLSDA      bool
+	Instructions []byte
+}
+
+// A Section holds the decoded entries of a call frame section.
+type Section struct {
+	// Addr is the address of the section, that pc-relative pointers of
+	// .eh_frame are relative to
+	Addr uint64
+	CIEs []*CIE
+	FDEs []*FDE
+	// Zero holds the offsets of zero terminators
+	Zero []uint64
+}
+
+// FDE returns the FDE covering the address pc, or nil.
+func (s *Section) FDE(pc uint64) *FDE {
+	for _, f := range s.FDEs {
+		if pc >= f.Begin && pc-f.Begin < f.Range {
+			return f
+		}
+	}
+	return nil
+}
+
+// ParseDebugFrame decodes the contents of .debug_frame. addrSize is
+// used for CIEs before version 4, which do not record it.
+func ParseDebugFrame(data []byte, order binary.ByteOrder, addrSize int) (*Section, error) {
+	return parse(data, order, addrSize, false, 0)
+}
+
+// ParseEHFrame decodes the contents of .eh_frame, loaded at addr.
+func ParseEHFrame(data []byte, order binary.ByteOrder, addrSize int, addr uint64) (*Section, error) {
+	return parse(data, order, addrSize, true, addr)
+}
+
+func parse(data []byte, order binary.ByteOrder, addrSize int, eh bool, addr uint64) (*Section, error) {
+	sec := &Section{Addr: addr}
+	cies := make(map[uint64]*CIE)
+	r := &reader{data: data, order: order, addrSize: addrSize}
+	for r.left() > 0 {
+		off := uint64(r.off)
+		length := r.unitLength()
+		if r.err != nil {
+			return sec, r.err
+		}
+		if length == 0 {
+			sec.Zero = append(sec.Zero, off)
+			continue
+		}
+		if length > uint64(r.left()) {
+			return sec, fmt.Errorf("entry at 0x%x: length 0x%x past end of section", off, length)
+		}
+		base := uint64(r.off)
+		e := &reader{base: base, data: r.bytes(int(length)), order: order, dwarf64: r.dwarf64, addrSize: addrSize}
+		var id, cieOff uint64
+		var isCIE bool
+		if eh {
+			// the CIE pointer of .eh_frame is always four bytes and
+			// counts back from its own position
+			id = uint64(e.u32())
+			isCIE = id == 0
+			cieOff = e.base - id
+		} else {
+			id = e.offset()
+			isCIE = !r.dwarf64 && id == 0xffffffff || r.dwarf64 && id == ^uint64(0)
+			cieOff = id
+		}
+		if isCIE {
+			c := &CIE{Offset: off, Length: length, Dwarf64: r.dwarf64, LSDAEncoding: EncOmit, order: order, eh: eh}
+			if err := c.parse(e, addrSize, addr); err != nil {
+				return sec, fmt.Errorf("CIE at 0x%x: %v", off, err)
+			}
+			cies[off] = c
+			sec.CIEs = append(sec.CIEs, c)
+			continue
+		}
+		c, ok := cies[cieOff]
+		if !ok {
+			return sec, fmt.Errorf("FDE at 0x%x: no CIE at 0x%x", off, cieOff)
+		}
+		f := &FDE{Offset: off, Length: length, CIE: c}
+		if err := f.parse(e, addr); err != nil {
+			return sec, fmt.Errorf("FDE at 0x%x: %v", off, err)
+		}
+		sec.FDEs = append(sec.FDEs, f)
+	}
+	return sec, nil
+}
+
+func (c *CIE) parse(e *reader, addrSize int, secAddr uint64) error {
+	c.Version = int(e.u8())
+	if c.Version != 1 && c.Version != 3 && c.Version != 4 {
+		return fmt.Errorf("unsupported version %d", c.Version)
+	}
+	c.Augmentation = e.cstring()
+	c.AddrSize = addrSize
+	if c.Version >= 4 {
+		c.AddrSize = int(e.u8())
+		c.SegSize = int(e.u8())
+	}
+	if strings.HasPrefix(c.Augmentation, "eh") {
+		// the old GCC "eh" augmentation stores a pointer here
+		e.addr()
+	}
+	c.CodeAlign = e.uleb()
+	c.DataAlign = e.sleb()
+	if c.Version == 1 {
+		c.RAReg = uint64(e.u8())
+	} else {
+		c.RAReg = e.uleb()
+	}
+	switch {
+	case c.Augmentation == "" || c.eh && c.Augmentation == "eh":
+	case c.eh && strings.HasPrefix(c.Augmentation, "z"):
+		n := e.uleb()
+		aug := e.sub(n)
+		c.AugmentationData = aug.data
+		for _, ch := range c.Augmentation[1:] {
+			switch ch {
+			case 'R':
+				c.FDEEncoding = aug.u8()
+			case 'L':
+				c.LSDAEncoding = aug.u8()
+			case 'P':
+				c.PersonalityEncoding = aug.u8()
+				c.Personality, _ = aug.pointer(c.PersonalityEncoding, secAddr)
+				c.HasPersonality = true
+			case 'S':
+				c.SignalFrame = true
+			case 'B', 'G':
+				// AArch64 branch target and memory tagged frames
+			default:
+				return fmt.Errorf("unknown augmentation %q", c.Augmentation)
+			}
+		}
+		if aug.err != nil {
+			return aug.err
+		}
+	default:
+		// other augmentations carry data that cannot be skipped
+		// without knowing them
+		return fmt.Errorf("unsupported augmentation %q", c.Augmentation)
+	}
+	c.Instructions = e.data[e.off:]
+	return e.err
+}
+
+func (f *FDE) parse(e *reader, secAddr uint64) error {
+	c := f.CIE
+	e.addrSize = c.AddrSize
+	if !c.eh {
+		if c.SegSize > 0 {
+			e.sized(c.SegSize)
+		}
+		f.Begin = e.addr()
+		f.Range = e.addr()
+		f.Instructions = e.data[e.off:]
+		return e.err
+	}
+	f.Begin, _ = e.pointer(c.FDEEncoding, secAddr)
+	// the range is a length: only the value format of the encoding
+	// applies to it
+	f.Range, _ = e.pointer(c.FDEEncoding&0x0f, secAddr)
+	if strings.HasPrefix(c.Augmentation, "z") {
+		aug := e.sub(e.uleb())
+		f.AugmentationData = aug.data
+		if c.LSDAEncoding != EncOmit && len(aug.data) > 0 {
+			var null bool
+			f.LSDA, null = aug.pointer(c.LSDAEncoding, secAddr)
+			f.HasLSDA = !null
+		}
+		if aug.err != nil {
+			return aug.err
+		}
+	}
+	f.Instructions = e.data[e.off:]
+	return e.err
+}
@@ -0,0 +1,119 @@
+package cfi
+
+import "strings"
+
+// DW_EH_PE pointer encodings of .eh_frame and .eh_frame_hdr. The low
+// four bits give the format of the value, the next three how it is
+// applied and the top bit whether it points to the real value.
+const (
+	EncAbsPtr  = 0x00
+	EncULEB128 = 0x01
+	EncUData2  = 0x02
+	EncUData4  = 0x03
+	EncUData8  = 0x04
+	EncSLEB128 = 0x09
+	EncSData2  = 0x0a
+	EncSData4  = 0x0b
+	EncSData8  = 0x0c
+
+	EncPCRel   = 0x10
+	EncTextRel = 0x20
+	EncDataRel = 0x30
+	EncFuncRel = 0x40
+	EncAligned = 0x50
+
+	EncIndirect = 0x80
+	EncOmit     = 0xff
+)
+
+var encFormats = map[uint8]string{
+	EncAbsPtr:  "absptr",
+	EncULEB128: "uleb128",
+	EncUData2:  "udata2",
+	EncUData4:  "udata4",
+	EncUData8:  "udata8",
+	EncSLEB128: "sleb128",
+	EncSData2:  "sdata2",
+	EncSData4:  "sdata4",
+	EncSData8:  "sdata8",
+}
+
+var encApps = map[uint8]string{
+	EncPCRel:   "pcrel",
+	EncTextRel: "textrel",
+	EncDataRel: "datarel",
+	EncFuncRel: "funcrel",
+	EncAligned: "aligned",
+}
+
+// EncodingName describes the pointer encoding enc, as in
+// "pcrel sdata4".
+func EncodingName(enc uint8) string {
+	if enc == EncOmit {
+		return "omit"
+	}
+	var parts []string
+	if enc&EncIndirect != 0 {
+		parts = append(parts, "indirect")
+	}
+	if a, ok := encApps[enc&0x70]; ok {
+		parts = append(parts, a)
+	}
+	if f, ok := encFormats[enc&0x0f]; ok {
+		parts = append(parts, f)
+	} else {
+		parts = append(parts, "unknown")
+	}
+	return strings.Join(parts, " ")
+}
+
+// pointer reads a pointer in encoding enc. pc-relative pointers are
+// relative to their own address in a section loaded at secAddr. null
+// reports a raw value of zero, which marks a missing pointer.
+func (r *reader) pointer(enc uint8, secAddr uint64) (v uint64, null bool) {
+	if enc == EncOmit {
+		return 0, true
+	}
+	pos := secAddr + r.base + uint64(r.off)
+	if enc&0x70 == EncAligned {
+		if a := uint64(r.addrSize); a > 0 && pos%a != 0 {
+			r.bytes(int(a - pos%a))
+		}
+	}
+	switch enc & 0x0f {
+	case EncAbsPtr:
+		v = r.addr()
+	case EncULEB128:
+		v = r.uleb()
+	case EncUData2:
+		v = uint64(r.u16())
+	case EncUData4:
+		v = uint64(r.u32())
+	case EncUData8:
+		v = r.u64()
+	case EncSLEB128:
+		v = uint64(r.sleb())
+	case EncSData2:
+		v = uint64(int16(r.u16()))
+	case EncSData4:
+		v = uint64(int32(r.u32()))
+	case EncSData8:
+		v = r.u64()
+	default:
+		r.fail(errBadEncoding)
+		return 0, true
+	}
+	if v == 0 {
+		return 0, true
+	}
+	switch enc & 0x70 {
+	case EncPCRel:
+		v += pos
+	case EncDataRel:
+		v += r.dataRel
+	}
+	if r.addrSize == 4 {
+		v &= 0xffffffff
+	}
+	return v, false
+}
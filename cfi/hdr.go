@@ -0,0 +1,101 @@
+package cfi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// A HdrEntry is an entry of the binary search table of .eh_frame_hdr.
+type HdrEntry struct {
+	Loc uint64
+	FDE uint64
+}
+
+// A Hdr is the contents of .eh_frame_hdr.
+type Hdr struct {
+	Version       int
+	EHFramePtrEnc uint8
+	FDECountEnc   uint8
+	TableEnc      uint8
+	EHFramePtr    uint64
+	Count         uint64
+	Table         []HdrEntry
+}
+
+// ParseEHFrameHdr decodes the contents of .eh_frame_hdr, loaded at
+// addr.
+func ParseEHFrameHdr(data []byte, order binary.ByteOrder, addrSize int, addr uint64) (*Hdr, error) {
+	// datarel pointers of the header are relative to its start
+	r := &reader{data: data, order: order, addrSize: addrSize, dataRel: addr}
+	h := &Hdr{}
+	h.Version = int(r.u8())
+	if h.Version != 1 {
+		return nil, fmt.Errorf("unsupported .eh_frame_hdr version %d", h.Version)
+	}
+	h.EHFramePtrEnc = r.u8()
+	h.FDECountEnc = r.u8()
+	h.TableEnc = r.u8()
+	h.EHFramePtr, _ = r.pointer(h.EHFramePtrEnc, addr)
+	if h.FDECountEnc != EncOmit {
+		h.Count, _ = r.pointer(h.FDECountEnc, addr)
+	}
+	if h.TableEnc != EncOmit {
+		for i := uint64(0); i < h.Count && r.err == nil; i++ {
+			var e HdrEntry
+			e.Loc, _ = r.pointer(h.TableEnc, addr)
+			e.FDE, _ = r.pointer(h.TableEnc, addr)
+			h.Table = append(h.Table, e)
+		}
+	}
+	if r.err != nil {
+		return h, r.err
+	}
+	return h, nil
+}
+
+// Check validates h against the .eh_frame section eh: the table must
+// be sorted by location, every entry must point to an FDE starting at
+// its location and every FDE must have an entry. It returns a
+// description of each problem found.
+func (h *Hdr) Check(eh *Section) []string {
+	var problems []string
+	if h.EHFramePtr != eh.Addr {
+		problems = append(problems, fmt.Sprintf("eh_frame_ptr 0x%x is not the address of .eh_frame 0x%x", h.EHFramePtr, eh.Addr))
+	}
+	if h.TableEnc == EncOmit {
+		return append(problems, "no binary search table")
+	}
+	if !sort.SliceIsSorted(h.Table, func(i, j int) bool { return h.Table[i].Loc < h.Table[j].Loc }) {
+		for i := 1; i < len(h.Table); i++ {
+			if h.Table[i].Loc < h.Table[i-1].Loc {
+				problems = append(problems, fmt.Sprintf("entry %d: location 0x%x is below the previous one 0x%x", i, h.Table[i].Loc, h.Table[i-1].Loc))
+			}
+		}
+	}
+	fdes := make(map[uint64]*FDE, len(eh.FDEs))
+	for _, f := range eh.FDEs {
+		fdes[eh.Addr+f.Offset] = f
+	}
+	covered := make(map[*FDE]bool)
+	for i, e := range h.Table {
+		f, ok := fdes[e.FDE]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("entry %d: 0x%x is not the address of an FDE", i, e.FDE))
+		case f.Begin != e.Loc:
+			problems = append(problems, fmt.Sprintf("entry %d: location 0x%x, but the FDE at 0x%x starts at 0x%x", i, e.Loc, e.FDE, f.Begin))
+		default:
+			covered[f] = true
+		}
+	}
+	if uint64(len(eh.FDEs)) != h.Count {
+		problems = append(problems, fmt.Sprintf("table has %d entries for %d FDEs", h.Count, len(eh.FDEs)))
+	}
+	for _, f := range eh.FDEs {
+		if !covered[f] && f.Range != 0 {
+			problems = append(problems, fmt.Sprintf("FDE at 0x%x for 0x%x has no table entry", eh.Addr+f.Offset, f.Begin))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,239 @@
+package cfi
+
+import (
+	"elfreader/debuginfo"
+	"fmt"
+)
+
+// DW_CFA opcodes. The first three are primary opcodes, which keep an
+// operand in the low six bits.
+const (
+	cfaAdvanceLoc        = 0x40
+	cfaOffset            = 0x80
+	cfaRestore           = 0xc0
+	cfaNop               = 0x00
+	cfaSetLoc            = 0x01
+	cfaAdvanceLoc1       = 0x02
+	cfaAdvanceLoc2       = 0x03
+	cfaAdvanceLoc4       = 0x04
+	cfaOffsetExtended    = 0x05
+	cfaRestoreExtended   = 0x06
+	cfaUndefined         = 0x07
+	cfaSameValue         = 0x08
+	cfaRegister          = 0x09
+	cfaRememberState     = 0x0a
+	cfaRestoreState      = 0x0b
+	cfaDefCFA            = 0x0c
+	cfaDefCFARegister    = 0x0d
+	cfaDefCFAOffset      = 0x0e
+	cfaDefCFAExpression  = 0x0f
+	cfaExpression        = 0x10
+	cfaOffsetExtendedSF  = 0x11
+	cfaDefCFASF          = 0x12
+	cfaDefCFAOffsetSF    = 0x13
+	cfaValOffset         = 0x14
+	cfaValOffsetSF       = 0x15
+	cfaValExpression     = 0x16
+	cfaMIPSAdvanceLoc8   = 0x1d
+	cfaGNUWindowSave     = 0x2d
+	cfaGNUArgsSize       = 0x2e
+	cfaGNUNegOffsetExtnd = 0x2f
+)
+
+var cfaNames = map[uint8]string{
+	cfaAdvanceLoc:        "DW_CFA_advance_loc",
+	cfaOffset:            "DW_CFA_offset",
+	cfaRestore:           "DW_CFA_restore",
+	cfaNop:               "DW_CFA_nop",
+	cfaSetLoc:            "DW_CFA_set_loc",
+	cfaAdvanceLoc1:       "DW_CFA_advance_loc1",
+	cfaAdvanceLoc2:       "DW_CFA_advance_loc2",
+	cfaAdvanceLoc4:       "DW_CFA_advance_loc4",
+	cfaOffsetExtended:    "DW_CFA_offset_extended",
+	cfaRestoreExtended:   "DW_CFA_restore_extended",
+	cfaUndefined:         "DW_CFA_undefined",
+	cfaSameValue:         "DW_CFA_same_value",
+	cfaRegister:          "DW_CFA_register",
+	cfaRememberState:     "DW_CFA_remember_state",
+	cfaRestoreState:      "DW_CFA_restore_state",
+	cfaDefCFA:            "DW_CFA_def_cfa",
+	cfaDefCFARegister:    "DW_CFA_def_cfa_register",
+	cfaDefCFAOffset:      "DW_CFA_def_cfa_offset",
+	cfaDefCFAExpression:  "DW_CFA_def_cfa_expression",
+	cfaExpression:        "DW_CFA_expression",
+	cfaOffsetExtendedSF:  "DW_CFA_offset_extended_sf",
+	cfaDefCFASF:          "DW_CFA_def_cfa_sf",
+	cfaDefCFAOffsetSF:    "DW_CFA_def_cfa_offset_sf",
+	cfaValOffset:         "DW_CFA_val_offset",
+	cfaValOffsetSF:       "DW_CFA_val_offset_sf",
+	cfaValExpression:     "DW_CFA_val_expression",
+	cfaMIPSAdvanceLoc8:   "DW_CFA_MIPS_advance_loc8",
+	cfaGNUWindowSave:     "DW_CFA_GNU_window_save",
+	cfaGNUArgsSize:       "DW_CFA_GNU_args_size",
+	cfaGNUNegOffsetExtnd: "DW_CFA_GNU_negative_offset_extended",
+}
+
+// An Inst is a decoded DW_CFA instruction. Args holds the operands in
+// encoding order, unfactored; signed operands are stored in two's
+// complement. Expr holds the expression of the expression opcodes.
+type Inst struct {
+	Op   uint8
+	Args []uint64
+	Expr []byte
+}
+
+// operand kinds
+const (
+	argULEB = iota
+	argSLEB
+	argAddr
+	argU8
+	argU16
+	argU32
+	argU64
+)
+
+var cfaArgs = map[uint8][]int{
+	cfaSetLoc:            {argAddr},
+	cfaAdvanceLoc1:       {argU8},
+	cfaAdvanceLoc2:       {argU16},
+	cfaAdvanceLoc4:       {argU32},
+	cfaOffsetExtended:    {argULEB, argULEB},
+	cfaRestoreExtended:   {argULEB},
+	cfaUndefined:         {argULEB},
+	cfaSameValue:         {argULEB},
+	cfaRegister:          {argULEB, argULEB},
+	cfaDefCFA:            {argULEB, argULEB},
+	cfaDefCFARegister:    {argULEB},
+	cfaDefCFAOffset:      {argULEB},
+	cfaExpression:        {argULEB},
+	cfaOffsetExtendedSF:  {argULEB, argSLEB},
+	cfaDefCFASF:          {argULEB, argSLEB},
+	cfaDefCFAOffsetSF:    {argSLEB},
+	cfaValOffset:         {argULEB, argULEB},
+	cfaValOffsetSF:       {argULEB, argSLEB},
+	cfaValExpression:     {argULEB},
+	cfaMIPSAdvanceLoc8:   {argU64},
+	cfaGNUArgsSize:       {argULEB},
+	cfaGNUNegOffsetExtnd: {argULEB, argULEB},
+}
+
+// Decode decodes the instruction stream b of c or one of its FDEs.
+func (c *CIE) Decode(b []byte) ([]Inst, error) {
+	r := &reader{data: b, order: c.order, dwarf64: c.Dwarf64, addrSize: c.AddrSize}
+	var insts []Inst
+	for r.left() > 0 {
+		op := r.u8()
+		var in Inst
+		if hi := op & 0xc0; hi != 0 {
+			in = Inst{Op: hi, Args: []uint64{uint64(op & 0x3f)}}
+			if hi == cfaOffset {
+				in.Args = append(in.Args, r.uleb())
+			}
+		} else {
+			if _, ok := cfaNames[op]; !ok {
+				return insts, fmt.Errorf("unknown call frame instruction 0x%x", op)
+			}
+			in.Op = op
+			for _, a := range cfaArgs[op] {
+				var v uint64
+				switch a {
+				case argULEB:
+					v = r.uleb()
+				case argSLEB:
+					v = uint64(r.sleb())
+				case argAddr:
+					if c.eh {
+						// DW_CFA_set_loc uses the FDE encoding;
+						// the position of the operand is unknown
+						// here, so relative encodings are read as
+						// plain values
+						v, _ = r.pointer(c.FDEEncoding&0x0f, 0)
+					} else {
+						v = r.addr()
+					}
+				case argU8:
+					v = uint64(r.u8())
+				case argU16:
+					v = uint64(r.u16())
+				case argU32:
+					v = uint64(r.u32())
+				case argU64:
+					v = r.u64()
+				}
+				in.Args = append(in.Args, v)
+			}
+			switch op {
+			case cfaDefCFAExpression, cfaExpression, cfaValExpression:
+				in.Expr = r.bytes(int(r.uleb()))
+			}
+		}
+		if r.err != nil {
+			return insts, r.err
+		}
+		insts = append(insts, in)
+	}
+	return insts, nil
+}
+
+// Format renders insts in the style of readelf --debug-dump=frames.
+// loc is the start address of the FDE, used to show the locations
+// that advance instructions move to; regName names DWARF registers.
+func (c *CIE) Format(insts []Inst, loc uint64, regName func(uint64) string) []string {
+	reg := func(r uint64) string {
+		if n := regName(r); n != "" {
+			return fmt.Sprintf("r%d (%s)", r, n)
+		}
+		return fmt.Sprintf("r%d", r)
+	}
+	expr := func(b []byte) string {
+		return debuginfo.FormatExpr(b, c.order, c.AddrSize, c.Dwarf64)
+	}
+	da := c.DataAlign
+	lines := make([]string, 0, len(insts))
+	for _, in := range insts {
+		name := cfaNames[in.Op]
+		var text string
+		a := in.Args
+		switch in.Op {
+		case cfaAdvanceLoc, cfaAdvanceLoc1, cfaAdvanceLoc2, cfaAdvanceLoc4, cfaMIPSAdvanceLoc8:
+			d := a[0] * c.CodeAlign
+			loc += d
+			text = fmt.Sprintf("%d to %016x", d, loc)
+		case cfaSetLoc:
+			loc = a[0]
+			text = fmt.Sprintf("%016x", loc)
+		case cfaOffset, cfaOffsetExtended:
+			text = fmt.Sprintf("%s at cfa%+d", reg(a[0]), int64(a[1])*da)
+		case cfaOffsetExtendedSF:
+			text = fmt.Sprintf("%s at cfa%+d", reg(a[0]), int64(a[1])*da)
+		case cfaGNUNegOffsetExtnd:
+			text = fmt.Sprintf("%s at cfa%+d", reg(a[0]), -int64(a[1])*da)
+		case cfaValOffset, cfaValOffsetSF:
+			text = fmt.Sprintf("%s is cfa%+d", reg(a[0]), int64(a[1])*da)
+		case cfaRestore, cfaRestoreExtended, cfaUndefined, cfaSameValue, cfaDefCFARegister:
+			text = reg(a[0])
+		case cfaRegister:
+			text = fmt.Sprintf("%s in %s", reg(a[0]), reg(a[1]))
+		case cfaDefCFA:
+			text = fmt.Sprintf("%s ofs %d", reg(a[0]), a[1])
+		case cfaDefCFASF:
+			text = fmt.Sprintf("%s ofs %d", reg(a[0]), int64(a[1])*da)
+		case cfaDefCFAOffset:
+			text = fmt.Sprint(a[0])
+		case cfaDefCFAOffsetSF:
+			text = fmt.Sprint(int64(a[0]) * da)
+		case cfaDefCFAExpression:
+			text = "(" + expr(in.Expr) + ")"
+		case cfaExpression, cfaValExpression:
+			text = fmt.Sprintf("%s (%s)", reg(a[0]), expr(in.Expr))
+		case cfaGNUArgsSize:
+			text = fmt.Sprint(a[0])
+		}
+		if text != "" {
+			name += ": " + text
+		}
+		lines = append(lines, name)
+	}
+	return lines
+}
@@ -0,0 +1,223 @@
+package cfi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// A CallSite is an entry of the call-site table of an LSDA: a range of
+// call instructions and where to continue if they throw.
+type CallSite struct {
+	// Start and Len give the address range of the calls
+	Start uint64
+	Len   uint64
+	// LandingPad is the address of the landing pad, 0 if exceptions
+	// are not caught or cleaned up
+	LandingPad uint64
+	// Action is 0 for cleanups only, or one plus the offset of the
+	// first action in the action table
+	Action uint64
+}
+
+// An Action is a record of the action table of an LSDA.
+type Action struct {
+	// Offset is the offset of the record in the action table
+	Offset uint64
+	// Filter is positive for catch clauses, giving the index of the
+	// caught type in the type table, negative for exception
+	// specifications and 0 for cleanups
+	Filter int64
+	// Next is the offset of the next action of the chain; valid if
+	// HasNext
+	Next    uint64
+	HasNext bool
+}
+
+// An LSDA is the language specific data area of a function, as laid
+// out by GCC in .gcc_except_table.
+type LSDA struct {
+	Addr       uint64
+	LPStartEnc uint8
+	// LPStart is the base of the landing pad addresses, the start of
+	// the function unless LPStartEnc says otherwise
+	LPStart     uint64
+	TTypeEnc    uint8
+	CallSiteEnc uint8
+	// TTBase is the address the type table counts back from; valid if
+	// TTypeEnc is not EncOmit
+	TTBase    uint64
+	CallSites []CallSite
+	// Actions holds the records reachable from the call sites, by
+	// offset
+	Actions []Action
+	// Types holds the type table entries referenced by the actions,
+	// Types[i-1] being the entry of filter i. An entry is the address
+	// of a typeinfo object, or of a pointer to it when TTypeEnc is
+	// indirect; 0 stands for catch (...)
+	Types []uint64
+	// Specs holds the type indices of the exception specifications
+	// referenced by the actions, keyed by filter
+	Specs map[int64][]uint64
+
+	actionBase uint64
+}
+
+// ParseLSDA decodes the LSDA at address lsda of the function starting
+// at funcStart. data holds the contents of .gcc_except_table, loaded at
+// addr.
+func ParseLSDA(data []byte, order binary.ByteOrder, addrSize int, addr, lsda, funcStart uint64) (*LSDA, error) {
+	if lsda < addr || lsda-addr >= uint64(len(data)) {
+		return nil, fmt.Errorf("LSDA 0x%x is outside .gcc_except_table", lsda)
+	}
+	off := lsda - addr
+	r := &reader{base: off, data: data[off:], order: order, addrSize: addrSize}
+	l := &LSDA{Addr: lsda, LPStart: funcStart, Specs: make(map[int64][]uint64)}
+	l.LPStartEnc = r.u8()
+	if l.LPStartEnc != EncOmit {
+		l.LPStart, _ = r.pointer(l.LPStartEnc, addr)
+	}
+	l.TTypeEnc = r.u8()
+	if l.TTypeEnc != EncOmit {
+		n := r.uleb()
+		l.TTBase = addr + r.base + uint64(r.off) + n
+	}
+	l.CallSiteEnc = r.u8()
+	cs := r.sub(r.uleb())
+	for cs.left() > 0 {
+		// call-site fields are offsets, so only their value format
+		// applies
+		var c CallSite
+		start, _ := cs.pointer(l.CallSiteEnc&0x0f, addr)
+		c.Len, _ = cs.pointer(l.CallSiteEnc&0x0f, addr)
+		lp, _ := cs.pointer(l.CallSiteEnc&0x0f, addr)
+		c.Action = cs.uleb()
+		if cs.err != nil {
+			return l, cs.err
+		}
+		c.Start = funcStart + start
+		if lp != 0 {
+			c.LandingPad = l.LPStart + lp
+		}
+		l.CallSites = append(l.CallSites, c)
+	}
+	if r.err != nil {
+		return l, r.err
+	}
+	l.actionBase = r.base + uint64(r.off)
+
+	// the action table has no length: walk the chains the call sites
+	// start
+	seen := make(map[uint64]bool)
+	var maxFilter int64
+	for _, c := range l.CallSites {
+		next, ok := c.Action-1, c.Action != 0
+		for ok && !seen[next] {
+			seen[next] = true
+			a, err := l.action(data, order, next)
+			if err != nil {
+				return l, err
+			}
+			l.Actions = append(l.Actions, a)
+			if a.Filter > maxFilter {
+				maxFilter = a.Filter
+			}
+			if a.Filter < 0 {
+				spec, err := l.spec(data, addr, a.Filter)
+				if err != nil {
+					return l, err
+				}
+				l.Specs[a.Filter] = spec
+				for _, t := range spec {
+					if int64(t) > maxFilter {
+						maxFilter = int64(t)
+					}
+				}
+			}
+			next, ok = a.Next, a.HasNext
+		}
+	}
+	sort.Slice(l.Actions, func(i, j int) bool { return l.Actions[i].Offset < l.Actions[j].Offset })
+
+	if maxFilter > 0 {
+		size := encSize(l.TTypeEnc, addrSize)
+		if l.TTypeEnc == EncOmit || size == 0 {
+			return l, fmt.Errorf("actions use a type table of encoding %s", EncodingName(l.TTypeEnc))
+		}
+		for i := int64(1); i <= maxFilter; i++ {
+			pos := l.TTBase - uint64(i)*uint64(size)
+			if pos < addr || pos-addr+uint64(size) > uint64(len(data)) {
+				return l, fmt.Errorf("type %d is outside .gcc_except_table", i)
+			}
+			t := &reader{base: pos - addr, data: data[pos-addr:], order: order, addrSize: addrSize}
+			v, _ := t.pointer(l.TTypeEnc&^EncIndirect, addr)
+			if t.err != nil {
+				return l, t.err
+			}
+			l.Types = append(l.Types, v)
+		}
+	}
+	return l, nil
+}
+
+// action decodes the action record at offset off of the action table.
+func (l *LSDA) action(data []byte, order binary.ByteOrder, off uint64) (Action, error) {
+	pos := l.actionBase + off
+	if pos >= uint64(len(data)) {
+		return Action{}, fmt.Errorf("action 0x%x is outside .gcc_except_table", off)
+	}
+	r := &reader{data: data[pos:], order: order}
+	a := Action{Offset: off, Filter: r.sleb()}
+	// the displacement counts from its own position
+	at := off + uint64(r.off)
+	if d := r.sleb(); d != 0 {
+		a.Next, a.HasNext = at+uint64(d), true
+	}
+	return a, r.err
+}
+
+// spec decodes the list of type indices of the exception specification
+// of filter, stored after the type table base.
+func (l *LSDA) spec(data []byte, addr uint64, filter int64) ([]uint64, error) {
+	pos := l.TTBase + uint64(-filter-1)
+	if l.TTypeEnc == EncOmit || pos < addr || pos-addr >= uint64(len(data)) {
+		return nil, fmt.Errorf("exception specification %d is outside .gcc_except_table", filter)
+	}
+	r := &reader{data: data[pos-addr:]}
+	var types []uint64
+	for {
+		t := r.uleb()
+		if r.err != nil {
+			return types, r.err
+		}
+		if t == 0 {
+			return types, nil
+		}
+		types = append(types, t)
+	}
+}
+
+// Type returns the type table entry of filter; ok is false when it is
+// not in l.Types.
+func (l *LSDA) Type(filter int64) (v uint64, ok bool) {
+	if filter < 1 || filter > int64(len(l.Types)) {
+		return 0, false
+	}
+	return l.Types[filter-1], true
+}
+
+// encSize returns the size of a fixed-size pointer encoding, or 0 for
+// the variable-length ones.
+func encSize(enc uint8, addrSize int) int {
+	switch enc & 0x0f {
+	case EncAbsPtr:
+		return addrSize
+	case EncUData2, EncSData2:
+		return 2
+	case EncUData4, EncSData4:
+		return 4
+	case EncUData8, EncSData8:
+		return 8
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package cfi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	errShort       = errors.New("unexpected end of call frame data")
+	errBadEncoding = errors.New("unknown pointer encoding")
+)
+
+// reader decodes the primitive encodings of call frame sections. The
+// first error is sticky.
+type reader struct {
+	// base is the section offset of data
+	base     uint64
+	data     []byte
+	off      int
+	order    binary.ByteOrder
+	dwarf64  bool
+	addrSize int
+	// dataRel is the base of DW_EH_PE_datarel pointers
+	dataRel uint64
+	err     error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.off = len(r.data)
+}
+
+func (r *reader) left() int { return len(r.data) - r.off }
+
+func (r *reader) bytes(n int) []byte {
+	if n < 0 || r.left() < n {
+		r.fail(errShort)
+		return nil
+	}
+	s := r.data[r.off : r.off+n]
+	r.off += n
+	return s
+}
+
+func (r *reader) u8() uint8 {
+	if s := r.bytes(1); s != nil {
+		return s[0]
+	}
+	return 0
+}
+
+func (r *reader) u16() uint16 {
+	if s := r.bytes(2); s != nil {
+		return r.order.Uint16(s)
+	}
+	return 0
+}
+
+func (r *reader) u32() uint32 {
+	if s := r.bytes(4); s != nil {
+		return r.order.Uint32(s)
+	}
+	return 0
+}
+
+func (r *reader) u64() uint64 {
+	if s := r.bytes(8); s != nil {
+		return r.order.Uint64(s)
+	}
+	return 0
+}
+
+func (r *reader) sized(n int) uint64 {
+	switch n {
+	case 1:
+		return uint64(r.u8())
+	case 2:
+		return uint64(r.u16())
+	case 4:
+		return uint64(r.u32())
+	case 8:
+		return r.u64()
+	}
+	r.fail(fmt.Errorf("unsupported value size %d", n))
+	return 0
+}
+
+func (r *reader) addr() uint64 { return r.sized(r.addrSize) }
+
+func (r *reader) uleb() uint64 {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		c := r.u8()
+		if r.err != nil {
+			return 0
+		}
+		if shift < 64 {
+			v |= uint64(c&0x7f) << shift
+		}
+		if c&0x80 == 0 {
+			return v
+		}
+	}
+}
+
+func (r *reader) sleb() int64 {
+	var v int64
+	var shift uint
+	for {
+		c := r.u8()
+		if r.err != nil {
+			return 0
+		}
+		if shift < 64 {
+			v |= int64(c&0x7f) << shift
+		}
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v
+		}
+	}
+}
+
+func (r *reader) cstring() string {
+	for i := r.off; i < len(r.data); i++ {
+		if r.data[i] == 0 {
+			s := string(r.data[r.off:i])
+			r.off = i + 1
+			return s
+		}
+	}
+	r.fail(errShort)
+	return ""
+}
+
+// unitLength reads an initial length field, switching r to the 64-bit
+// format if it announces one.
+func (r *reader) unitLength() uint64 {
+	n := uint64(r.u32())
+	if n == 0xffffffff {
+		r.dwarf64 = true
+		return r.u64()
+	}
+	r.dwarf64 = false
+	return n
+}
+
+func (r *reader) offset() uint64 {
+	if r.dwarf64 {
+		return r.u64()
+	}
+	return uint64(r.u32())
+}
+
+// sub returns a reader over the next n bytes and skips them in r.
+func (r *reader) sub(n uint64) *reader {
+	s := &reader{base: r.base + uint64(r.off), order: r.order, dwarf64: r.dwarf64, addrSize: r.addrSize, dataRel: r.dataRel}
+	if n > uint64(r.left()) {
+		r.fail(errShort)
+		s.err = errShort
+		return s
+	}
+	s.data = r.bytes(int(n))
+	return s
+}
@@ -0,0 +1,63 @@
+package cfi
+
+import (
+	"debug/elf"
+	"fmt"
+)
+
+var x86_64Regs = []string{
+	"rax", "rdx", "rcx", "rbx", "rsi", "rdi", "rbp", "rsp",
+	"r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15", "rip",
+}
+
+var i386Regs = []string{
+	"eax", "ecx", "edx", "ebx", "esp", "ebp", "esi", "edi", "eip",
+}
+
+// RegNames returns a function naming the DWARF registers of machine m.
+// It returns "" for registers it does not know.
+func RegNames(m elf.Machine) func(uint64) string {
+	return func(r uint64) string {
+		switch m {
+		case elf.EM_X86_64:
+			if r < uint64(len(x86_64Regs)) {
+				return x86_64Regs[r]
+			}
+			if r >= 17 && r <= 32 {
+				return fmt.Sprintf("xmm%d", r-17)
+			}
+		case elf.EM_386:
+			if r < uint64(len(i386Regs)) {
+				return i386Regs[r]
+			}
+		case elf.EM_AARCH64:
+			switch {
+			case r <= 30:
+				return fmt.Sprintf("x%d", r)
+			case r == 31:
+				return "sp"
+			case r >= 64 && r <= 95:
+				return fmt.Sprintf("v%d", r-64)
+			}
+		case elf.EM_ARM:
+			switch {
+			case r <= 12:
+				return fmt.Sprintf("r%d", r)
+			case r == 13:
+				return "sp"
+			case r == 14:
+				return "lr"
+			case r == 15:
+				return "pc"
+			}
+		case elf.EM_RISCV:
+			if r <= 31 {
+				return fmt.Sprintf("x%d", r)
+			}
+			if r <= 63 {
+				return fmt.Sprintf("f%d", r-32)
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,196 @@
+package cfi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// RuleKind tells how a register is recovered in the caller's frame.
+type RuleKind int
+
+const (
+	// RuleUndefined registers cannot be recovered; it is the initial
+	// rule of registers not mentioned by the CIE, and the one
+	// DW_CFA_undefined sets explicitly
+	RuleUndefined RuleKind = iota
+	// RuleSameValue registers are not modified by the function
+	RuleSameValue
+	// RuleOffset registers are saved at CFA+Offset
+	RuleOffset
+	// RuleValOffset registers hold the value CFA+Offset
+	RuleValOffset
+	// RuleRegister registers are saved in register Reg
+	RuleRegister
+	// RuleExpression registers are saved at the address computed by
+	// Expr
+	RuleExpression
+	// RuleValExpression registers hold the value computed by Expr
+	RuleValExpression
+	// RuleCFA is the rule of the CFA itself, Reg+Offset, or Expr if it
+	// is set
+	RuleCFA
+)
+
+// A Rule is the recovery rule of a register or of the CFA.
+type Rule struct {
+	Kind   RuleKind
+	Reg    uint64
+	Offset int64
+	Expr   []byte
+}
+
+// A Row is the state of the unwind table from Loc up to the Loc of
+// the next row.
+type Row struct {
+	Loc uint64
+	CFA Rule
+	// Regs holds the rules of the registers the instructions
+	// mention, including those made undefined by DW_CFA_undefined;
+	// registers missing from it were never mentioned
+	Regs map[uint64]Rule
+}
+
+func (r Row) clone() Row {
+	c := Row{Loc: r.Loc, CFA: r.CFA, Regs: make(map[uint64]Rule, len(r.Regs))}
+	for k, v := range r.Regs {
+		c.Regs[k] = v
+	}
+	return c
+}
+
+// Table runs the instructions of the CIE of f and then those of f,
+// returning one row for each location the rules change at.
+func Table(f *FDE) ([]Row, error) {
+	c := f.CIE
+	cieInsts, err := c.Decode(c.Instructions)
+	if err != nil {
+		return nil, fmt.Errorf("CIE at 0x%x: %v", c.Offset, err)
+	}
+	insts, err := c.Decode(f.Instructions)
+	if err != nil {
+		return nil, fmt.Errorf("FDE at 0x%x: %v", f.Offset, err)
+	}
+
+	row := Row{Loc: f.Begin, Regs: make(map[uint64]Rule)}
+	m := &machine{c: c, row: &row}
+	if err := m.run(cieInsts, nil); err != nil {
+		return nil, err
+	}
+	initial := row.clone()
+	var rows []Row
+	emit := func() {
+		if n := len(rows); n > 0 && rows[n-1].Loc == row.Loc {
+			rows[n-1] = row.clone()
+			return
+		}
+		rows = append(rows, row.clone())
+	}
+	if err := m.run(insts, emit, initial); err != nil {
+		return rows, err
+	}
+	// rules changed after the last advance still apply from it, but a
+	// trailing advance with no change adds no row
+	if n := len(rows); n == 0 || rows[n-1].Loc == row.Loc || !sameRules(rows[n-1], row) {
+		emit()
+	}
+	return rows, nil
+}
+
+func sameRules(a, b Row) bool {
+	return reflect.DeepEqual(a.CFA, b.CFA) && reflect.DeepEqual(a.Regs, b.Regs)
+}
+
+// Find returns the row of rows covering pc.
+func Find(rows []Row, pc uint64) (Row, bool) {
+	i := sort.Search(len(rows), func(i int) bool { return rows[i].Loc > pc }) - 1
+	if i < 0 {
+		return Row{}, false
+	}
+	return rows[i], true
+}
+
+// machine executes DW_CFA instructions on a row.
+type machine struct {
+	c     *CIE
+	row   *Row
+	stack []Row
+}
+
+// run executes insts. emit is called with the row before each advance
+// of the location; initial holds the rules DW_CFA_restore returns to.
+func (m *machine) run(insts []Inst, emit func(), initial ...Row) error {
+	da := m.c.DataAlign
+	row := m.row
+	set := func(reg uint64, r Rule) { row.Regs[reg] = r }
+	restore := func(reg uint64) {
+		if len(initial) == 0 {
+			delete(row.Regs, reg)
+			return
+		}
+		if r, ok := initial[0].Regs[reg]; ok {
+			row.Regs[reg] = r
+		} else {
+			delete(row.Regs, reg)
+		}
+	}
+	advance := func(loc uint64) {
+		if emit != nil {
+			emit()
+		}
+		row.Loc = loc
+	}
+	for _, in := range insts {
+		a := in.Args
+		switch in.Op {
+		case cfaAdvanceLoc, cfaAdvanceLoc1, cfaAdvanceLoc2, cfaAdvanceLoc4, cfaMIPSAdvanceLoc8:
+			advance(row.Loc + a[0]*m.c.CodeAlign)
+		case cfaSetLoc:
+			advance(a[0])
+		case cfaOffset, cfaOffsetExtended:
+			set(a[0], Rule{Kind: RuleOffset, Offset: int64(a[1]) * da})
+		case cfaOffsetExtendedSF:
+			set(a[0], Rule{Kind: RuleOffset, Offset: int64(a[1]) * da})
+		case cfaGNUNegOffsetExtnd:
+			set(a[0], Rule{Kind: RuleOffset, Offset: -int64(a[1]) * da})
+		case cfaValOffset, cfaValOffsetSF:
+			set(a[0], Rule{Kind: RuleValOffset, Offset: int64(a[1]) * da})
+		case cfaRestore, cfaRestoreExtended:
+			restore(a[0])
+		case cfaUndefined:
+			set(a[0], Rule{Kind: RuleUndefined})
+		case cfaSameValue:
+			set(a[0], Rule{Kind: RuleSameValue})
+		case cfaRegister:
+			set(a[0], Rule{Kind: RuleRegister, Reg: a[1]})
+		case cfaExpression:
+			set(a[0], Rule{Kind: RuleExpression, Expr: in.Expr})
+		case cfaValExpression:
+			set(a[0], Rule{Kind: RuleValExpression, Expr: in.Expr})
+		case cfaRememberState:
+			m.stack = append(m.stack, row.clone())
+		case cfaRestoreState:
+			if len(m.stack) == 0 {
+				return fmt.Errorf("DW_CFA_restore_state without remembered state")
+			}
+			// the location is not part of the remembered state
+			loc := row.Loc
+			*row = m.stack[len(m.stack)-1]
+			row.Loc = loc
+			m.stack = m.stack[:len(m.stack)-1]
+		case cfaDefCFA:
+			row.CFA = Rule{Kind: RuleCFA, Reg: a[0], Offset: int64(a[1])}
+		case cfaDefCFASF:
+			row.CFA = Rule{Kind: RuleCFA, Reg: a[0], Offset: int64(a[1]) * da}
+		case cfaDefCFARegister:
+			row.CFA.Kind, row.CFA.Reg, row.CFA.Expr = RuleCFA, a[0], nil
+		case cfaDefCFAOffset:
+			row.CFA.Kind, row.CFA.Offset, row.CFA.Expr = RuleCFA, int64(a[0]), nil
+		case cfaDefCFAOffsetSF:
+			row.CFA.Kind, row.CFA.Offset, row.CFA.Expr = RuleCFA, int64(a[0])*da, nil
+		case cfaDefCFAExpression:
+			row.CFA = Rule{Kind: RuleCFA, Expr: in.Expr}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+// Package core decodes Linux core dumps: the threads and their
+// registers of NT_PRSTATUS, the process of NT_PRPSINFO, the signal of
+// NT_SIGINFO, the files mapped in NT_FILE and the auxiliary vector,
+// and reads the memory of the process from the PT_LOAD segments.
+package core
+
+import (
+	"bytes"
+	"debug/elf"
+	"elfreader/file"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNotCore is returned for files that are not core dumps.
+var ErrNotCore = errors.New("not a core file")
+
+// A Note is a raw note of the core file.
+type Note struct {
+	Name string
+	Type uint32
+	Desc []byte
+}
+
+// A Siginfo is the decoded siginfo_t of the signal that caused the
+// dump.
+type Siginfo struct {
+	Signo int
+	Errno int32
+	Code  int32
+	// Addr is the faulting address of SIGSEGV, SIGBUS, SIGILL,
+	// SIGFPE and SIGTRAP
+	Addr    uint64
+	HasAddr bool
+	// PID and UID are the sender of signals sent by a process
+	PID, UID  uint32
+	HasSender bool
+}
+
+// A Thread is a thread of the dumped process.
+type Thread struct {
+	PID, PPID, PGRP, SID int
+	// Signal is the signal that stopped the thread
+	Signal        int
+	Pending, Held uint64
+	// UTime and STime are the user and system times, in microseconds
+	UTime, STime uint64
+	Regs         []Reg
+	// FPRegs is the NT_PRFPREG note of the thread, or nil
+	FPRegs []byte
+	// Extra are the other register set notes of the thread, such as
+	// NT_X86_XSTATE
+	Extra []Note
+	// Siginfo is the NT_SIGINFO of the thread, or nil
+	Siginfo *Siginfo
+}
+
+// Reg returns the value of the register named name.
+func (t *Thread) Reg(name string) (uint64, bool) {
+	for _, r := range t.Regs {
+		if r.Name == name {
+			return r.Value, true
+		}
+	}
+	return 0, false
+}
+
+// A Process is the decoded NT_PRPSINFO.
+type Process struct {
+	State byte
+	// SName is the state letter, as in ps
+	SName     byte
+	Zombie    bool
+	Nice      int8
+	Flags     uint64
+	UID, GID  uint32
+	PID, PPID int
+	PGRP, SID int
+	// Name is the executable name, truncated to 15 bytes
+	Name string
+	// Args is the start of the command line
+	Args string
+}
+
+// A MappedFile is a file range of NT_FILE.
+type MappedFile struct {
+	Start, End uint64
+	// Offset is the file offset of Start, in bytes
+	Offset uint64
+	Path   string
+}
+
+// An AuxEntry is an entry of the auxiliary vector.
+type AuxEntry struct {
+	Tag, Val uint64
+}
+
+// A Core is a decoded core dump.
+type Core struct {
+	*file.File
+	Threads []*Thread
+	// Process is nil if the dump has no NT_PRPSINFO
+	Process *Process
+	// Files are the file mappings, in address order
+	Files    []MappedFile
+	PageSize uint64
+	Auxv     []AuxEntry
+	Notes    []Note
+	// WordSize is the size of the longs of the dumped process
+	WordSize int
+}
+
+// New decodes the notes of the core dump f.
+func New(f *file.File) (*Core, error) {
+	if f.Type != elf.ET_CORE {
+		return nil, ErrNotCore
+	}
+	c := &Core{File: f, WordSize: 8}
+	if f.Class == elf.ELFCLASS32 {
+		c.WordSize = 4
+	}
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil {
+			return nil, err
+		}
+		c.Notes = append(c.Notes, c.parseNotes(data)...)
+	}
+
+	var cur *Thread
+	for _, n := range c.Notes {
+		var err error
+		switch {
+		case n.Type == ntPRStatus && n.Name == "CORE":
+			cur, err = c.prstatus(n.Desc)
+			if err == nil {
+				c.Threads = append(c.Threads, cur)
+			}
+		case n.Type == ntPRPSInfo && n.Name == "CORE":
+			c.Process, err = c.prpsinfo(n.Desc)
+		case n.Type == ntFile && n.Name == "CORE":
+			err = c.files(n.Desc)
+		case n.Type == ntAuxv && n.Name == "CORE":
+			for b := n.Desc; len(b) >= 2*c.WordSize; b = b[2*c.WordSize:] {
+				e := AuxEntry{c.word(b), c.word(b[c.WordSize:])}
+				if e.Tag == 0 {
+					break
+				}
+				c.Auxv = append(c.Auxv, e)
+			}
+		case n.Type == ntSigInfo && cur != nil:
+			cur.Siginfo = c.siginfo(n.Desc)
+		case n.Type == ntPRFPReg && cur != nil:
+			cur.FPRegs = n.Desc
+		case cur != nil && n.Name == "LINUX":
+			cur.Extra = append(cur.Extra, n)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", NoteName(n.Type), err)
+		}
+	}
+	return c, nil
+}
+
+func (c *Core) parseNotes(d []byte) []Note {
+	order := c.ByteOrder
+	var notes []Note
+	for len(d) >= 12 {
+		namesz := uint64(order.Uint32(d[0:4]))
+		descsz := uint64(order.Uint32(d[4:8]))
+		typ := order.Uint32(d[8:12])
+		d = d[12:]
+		nlen, dlen := (namesz+3)&^3, (descsz+3)&^3
+		if nlen+descsz > uint64(len(d)) {
+			break
+		}
+		name := string(bytes.TrimRight(d[:namesz], "\x00"))
+		notes = append(notes, Note{name, typ, d[nlen : nlen+descsz]})
+		if nlen+dlen > uint64(len(d)) {
+			break
+		}
+		d = d[nlen+dlen:]
+	}
+	return notes
+}
+
+func (c *Core) word(b []byte) uint64 {
+	if c.WordSize == 4 {
+		return uint64(c.ByteOrder.Uint32(b))
+	}
+	return c.ByteOrder.Uint64(b)
+}
+
+// prstatus decodes an elf_prstatus: the siginfo head and cursig, the
+// signal masks, four pids, four timevals and the registers, followed
+// by pr_fpvalid.
+func (c *Core) prstatus(b []byte) (*Thread, error) {
+	w := c.WordSize
+	regOff := 32 + 10*w
+	fpvalid := 4
+	if w == 8 {
+		fpvalid = 8
+	}
+	if len(b) < regOff+fpvalid {
+		return nil, errors.New("short note")
+	}
+	u32 := func(off int) int { return int(int32(c.ByteOrder.Uint32(b[off:]))) }
+	t := &Thread{
+		Signal:  int(c.ByteOrder.Uint16(b[12:])),
+		Pending: c.word(b[16:]),
+		Held:    c.word(b[16+w:]),
+		PID:     u32(16 + 2*w),
+		PPID:    u32(20 + 2*w),
+		PGRP:    u32(24 + 2*w),
+		SID:     u32(28 + 2*w),
+	}
+	tv := func(off int) uint64 { return c.word(b[off:])*1000000 + c.word(b[off+w:]) }
+	t.UTime, t.STime = tv(32+2*w), tv(32+4*w)
+	t.Regs = decodeRegs(b[regOff:len(b)-fpvalid], c.Machine, w, c.ByteOrder)
+	return t, nil
+}
+
+// prpsinfo decodes an elf_prpsinfo, whose uid_t is 16 bits on i386
+// and 32-bit ARM.
+func (c *Core) prpsinfo(b []byte) (*Process, error) {
+	w := c.WordSize
+	if len(b) < 8+w+96 {
+		return nil, errors.New("short note")
+	}
+	p := &Process{State: b[0], SName: b[1], Zombie: b[2] != 0, Nice: int8(b[3])}
+	off := 4
+	if w == 8 {
+		off = 8
+	}
+	p.Flags = c.word(b[off:])
+	off += w
+	if len(b) == off+4+16+96 {
+		p.UID, p.GID = uint32(c.ByteOrder.Uint16(b[off:])), uint32(c.ByteOrder.Uint16(b[off+2:]))
+		off += 4
+	} else {
+		p.UID, p.GID = c.ByteOrder.Uint32(b[off:]), c.ByteOrder.Uint32(b[off+4:])
+		off += 8
+	}
+	if off+16+96 > len(b) {
+		return nil, errors.New("short note")
+	}
+	p.PID = int(int32(c.ByteOrder.Uint32(b[off:])))
+	p.PPID = int(int32(c.ByteOrder.Uint32(b[off+4:])))
+	p.PGRP = int(int32(c.ByteOrder.Uint32(b[off+8:])))
+	p.SID = int(int32(c.ByteOrder.Uint32(b[off+12:])))
+	off += 16
+	p.Name = cstring(b[off : off+16])
+	p.Args = cstring(b[off+16 : off+96])
+	return p, nil
+}
+
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// files decodes NT_FILE: the count and page size, the start, end and
+// page offset of each mapping, then their NUL-terminated paths.
+func (c *Core) files(b []byte) error {
+	w := c.WordSize
+	if len(b) < 2*w {
+		return errors.New("short note")
+	}
+	n, page := c.word(b), c.word(b[w:])
+	b = b[2*w:]
+	if n > uint64(len(b))/uint64(3*w) {
+		return errors.New("bad mapping count")
+	}
+	names := bytes.Split(b[n*uint64(3*w):], []byte{0})
+	if uint64(len(names)) < n {
+		return errors.New("missing file names")
+	}
+	c.PageSize = page
+	for i := uint64(0); i < n; i++ {
+		e := b[i*uint64(3*w):]
+		c.Files = append(c.Files, MappedFile{
+			Start:  c.word(e),
+			End:    c.word(e[w:]),
+			Offset: c.word(e[2*w:]) * page,
+			Path:   string(names[i]),
+		})
+	}
+	sort.Slice(c.Files, func(i, j int) bool { return c.Files[i].Start < c.Files[j].Start })
+	return nil
+}
+
+// siginfo decodes the head of a siginfo_t, whose union starts after
+// the signal number, errno and code, aligned to a word.
+func (c *Core) siginfo(b []byte) *Siginfo {
+	if len(b) < 12 {
+		return nil
+	}
+	s := &Siginfo{
+		Signo: int(int32(c.ByteOrder.Uint32(b))),
+		Errno: int32(c.ByteOrder.Uint32(b[4:])),
+		Code:  int32(c.ByteOrder.Uint32(b[8:])),
+	}
+	u := 12
+	if c.WordSize == 8 {
+		u = 16
+	}
+	if len(b) < u+c.WordSize {
+		return s
+	}
+	switch {
+	case s.Code > 0 && s.Code < 0x80 && isFault(s.Signo):
+		s.Addr, s.HasAddr = c.word(b[u:]), true
+	case s.Code <= 0:
+		s.PID, s.UID = c.ByteOrder.Uint32(b[u:]), c.ByteOrder.Uint32(b[u+4:])
+		s.HasSender = true
+	}
+	return s
+}
+
+// AuxVal returns the value of the auxiliary vector tag.
+func (c *Core) AuxVal(tag uint64) (uint64, bool) {
+	for _, e := range c.Auxv {
+		if e.Tag == tag {
+			return e.Val, true
+		}
+	}
+	return 0, false
+}
+
+// ReadMemory reads the memory of the process at addr into b. Memory
+// not dumped, such as unmodified file mappings, reads as an error.
+func (c *Core) ReadMemory(b []byte, addr uint64) error {
+	for len(b) > 0 {
+		p := c.segment(addr)
+		if p == nil {
+			return fmt.Errorf("address 0x%x is not in the core", addr)
+		}
+		off := addr - p.Vaddr
+		if off >= p.Filesz {
+			return fmt.Errorf("address 0x%x was not dumped", addr)
+		}
+		n := uint64(len(b))
+		if n > p.Filesz-off {
+			n = p.Filesz - off
+		}
+		if _, err := p.ReadAt(b[:n], int64(off)); err != nil {
+			return err
+		}
+		b, addr = b[n:], addr+n
+	}
+	return nil
+}
+
+func (c *Core) segment(addr uint64) *file.Prog {
+	for _, p := range c.Progs {
+		if p.Type == elf.PT_LOAD && addr >= p.Vaddr && addr-p.Vaddr < p.Memsz {
+			return p
+		}
+	}
+	return nil
+}
+
+// ReadString reads the NUL-terminated string at addr, up to max bytes.
+func (c *Core) ReadString(addr uint64, max int) (string, error) {
+	var s []byte
+	for len(s) < max {
+		// a chunk at a time, up to the end of the segment
+		p := c.segment(addr)
+		if p == nil || addr-p.Vaddr >= p.Filesz {
+			return "", fmt.Errorf("address 0x%x was not dumped", addr)
+		}
+		n := p.Filesz - (addr - p.Vaddr)
+		if n > 64 {
+			n = 64
+		}
+		buf := make([]byte, n)
+		if err := c.ReadMemory(buf, addr); err != nil {
+			return "", err
+		}
+		if i := bytes.IndexByte(buf, 0); i >= 0 {
+			return string(append(s, buf[:i]...)), nil
+		}
+		s = append(s, buf...)
+		addr += n
+	}
+	return string(s[:max]), nil
+}
+
+// A Region is an entry of the memory map of the process.
+type Region struct {
+	Start, End uint64
+	// Perm is the permissions, as in /proc/<pid>/maps
+	Perm string
+	// Dumped is the size of the region in the core
+	Dumped uint64
+	// Path and Offset are the file mapped, if any
+	Path   string
+	Offset uint64
+}
+
+// vsyscall is the address of the x86-64 vsyscall page.
+const vsyscall = 0xffffffffff600000
+
+// MemoryMap reconstructs the memory map of the process from the
+// PT_LOAD segments, named after the files of NT_FILE, the vDSO and the
+// stacks of the threads.
+func (c *Core) MemoryMap() []Region {
+	var regions []Region
+	vdso, hasVDSO := c.AuxVal(atSysinfoEHdr)
+	for _, p := range c.Progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		r := Region{Start: p.Vaddr, End: p.Vaddr + p.Memsz, Perm: perm(p.Flags), Dumped: p.Filesz}
+		for _, m := range c.Files {
+			if r.Start >= m.Start && r.Start < m.End {
+				r.Path, r.Offset = m.Path, m.Offset+(r.Start-m.Start)
+				break
+			}
+		}
+		if r.Path == "" && hasVDSO && r.Start == vdso {
+			r.Path = "[vdso]"
+		}
+		if r.Path == "" && c.Machine == elf.EM_X86_64 && r.Start == vsyscall {
+			r.Path = "[vsyscall]"
+		}
+		for _, t := range c.Threads {
+			if _, sp, ok := c.PCSP(t); r.Path == "" && ok && sp >= r.Start && sp < r.End {
+				if c.Process != nil && t.PID == c.Process.PID {
+					r.Path = "[stack]"
+				} else {
+					r.Path = fmt.Sprintf("[stack:%d]", t.PID)
+				}
+			}
+		}
+		regions = append(regions, r)
+	}
+	// file mappings the kernel left out of the dump entirely
+	for _, m := range c.Files {
+		covered := false
+		for _, r := range regions {
+			if m.Start < r.End && r.Start < m.End {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			regions = append(regions, Region{Start: m.Start, End: m.End, Perm: "?", Path: m.Path, Offset: m.Offset})
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Start < regions[j].Start })
+	return regions
+}
+
+func perm(f elf.ProgFlag) string {
+	b := []byte("---p")
+	if f&elf.PF_R != 0 {
+		b[0] = 'r'
+	}
+	if f&elf.PF_W != 0 {
+		b[1] = 'w'
+	}
+	if f&elf.PF_X != 0 {
+		b[2] = 'x'
+	}
+	return string(b)
+}
+
+// PCSP returns the program counter and stack pointer of t, if the
+// machine is known.
+func (c *Core) PCSP(t *Thread) (pc, sp uint64, ok bool) {
+	names, known := pcSP[c.Machine]
+	if !known {
+		return 0, 0, false
+	}
+	pc, ok1 := t.Reg(names[0])
+	sp, ok2 := t.Reg(names[1])
+	return pc, sp, ok1 && ok2
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"debug/elf"
+	"elfreader/cfi"
+	"elfreader/file"
+	"fmt"
+	"path/filepath"
+)
+
+// A Module is an ELF file mapped into the process: a file of NT_FILE
+// opened from the local filesystem, or the vDSO read from the dump.
+type Module struct {
+	Path       string
+	Start, End uint64
+	// Bias is the difference between the run-time and link-time
+	// addresses of File
+	Bias uint64
+	// File is nil if the file could not be opened, for the reason Err
+	File *file.File
+	Err  error
+
+	// ranges are the mappings of the module
+	ranges [][2]uint64
+	// frames are .eh_frame and .debug_frame, parsed on first use
+	frames []*cfi.Section
+	parsed bool
+	rows   map[*cfi.FDE][]cfi.Row
+}
+
+// Contains tells whether the run-time address pc is mapped from m.
+func (m *Module) Contains(pc uint64) bool {
+	for _, r := range m.ranges {
+		if pc >= r[0] && pc < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes the file of m.
+func (m *Module) Close() error {
+	if m.File == nil {
+		return nil
+	}
+	return m.File.Close()
+}
+
+// Modules opens the files mapped into the process, with their paths
+// taken relative to root, and the vDSO. The files are not checked to be
+// the ones the process ran; a binary rebuilt since gives wrong frames.
+func (c *Core) Modules(root string) []*Module {
+	var mods []*Module
+	byPath := make(map[string]*Module)
+	for _, f := range c.Files {
+		m := byPath[f.Path]
+		if m == nil {
+			m = &Module{Path: f.Path, Start: f.Start, End: f.End}
+			m.File, m.Err = file.Open(filepath.Join(root, f.Path))
+			if m.File != nil {
+				if m.Bias, m.Err = bias(m.File, f); m.Err != nil {
+					m.File.Close()
+					m.File = nil
+				}
+			}
+			byPath[f.Path] = m
+			mods = append(mods, m)
+		}
+		if f.Start < m.Start {
+			m.Start = f.Start
+		}
+		if f.End > m.End {
+			m.End = f.End
+		}
+		m.ranges = append(m.ranges, [2]uint64{f.Start, f.End})
+	}
+	if m := c.vdso(); m != nil {
+		mods = append(mods, m)
+	}
+	return mods
+}
+
+// bias returns the load bias of f, mapped by the NT_FILE entry m.
+func bias(f *file.File, m MappedFile) (uint64, error) {
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_LOAD || p.Align == 0 {
+			continue
+		}
+		start := p.Off &^ (p.Align - 1)
+		if m.Offset >= start && m.Offset < p.Off+p.Filesz {
+			// addresses and offsets of a segment are congruent
+			// modulo the page size, so the difference carries over
+			return m.Start - m.Offset - (p.Vaddr - p.Off), nil
+		}
+	}
+	return 0, fmt.Errorf("no segment maps offset 0x%x", m.Offset)
+}
+
+// vdso returns the vDSO as a module, read from the dump, or nil if it
+// was not dumped.
+func (c *Core) vdso() *Module {
+	start, ok := c.AuxVal(atSysinfoEHdr)
+	if !ok {
+		return nil
+	}
+	p := c.segment(start)
+	if p == nil || start != p.Vaddr || p.Filesz == 0 {
+		return nil
+	}
+	f := file.NewFile(p)
+	if f == nil {
+		return nil
+	}
+	m := &Module{Path: "[vdso]", Start: start, End: start + p.Memsz, File: f}
+	m.ranges = [][2]uint64{{m.Start, m.End}}
+	m.Bias, m.Err = bias(f, MappedFile{Start: start, End: m.End})
+	if m.Err != nil {
+		m.File = nil
+	}
+	return m
+}
+
+// ModuleOf returns the module of mods mapping pc, or nil.
+func ModuleOf(mods []*Module, pc uint64) *Module {
+	for _, m := range mods {
+		if m.Contains(pc) {
+			return m
+		}
+	}
+	return nil
+}
+
+// rowFor returns the unwind row of the link-time address pc and the
+// CIE of its FDE.
+func (m *Module) rowFor(pc uint64) (cfi.Row, *cfi.CIE, bool) {
+	if m.File == nil {
+		return cfi.Row{}, nil, false
+	}
+	if !m.parsed {
+		m.parsed = true
+		m.rows = make(map[*cfi.FDE][]cfi.Row)
+		addrSize := 8
+		if m.File.Class == elf.ELFCLASS32 {
+			addrSize = 4
+		}
+		if s := m.File.Section(".eh_frame"); s != nil && s.Type != elf.SHT_NOBITS {
+			if sec, err := cfi.ParseEHFrame(s.Data(), m.File.ByteOrder, addrSize, s.Addr); err == nil {
+				m.frames = append(m.frames, sec)
+			}
+		}
+		if s := m.File.Section(".debug_frame"); s != nil && s.Type != elf.SHT_NOBITS {
+			if sec, err := cfi.ParseDebugFrame(s.Data(), m.File.ByteOrder, addrSize); err == nil {
+				m.frames = append(m.frames, sec)
+			}
+		}
+	}
+	for _, sec := range m.frames {
+		fde := sec.FDE(pc)
+		if fde == nil {
+			continue
+		}
+		rows, ok := m.rows[fde]
+		if !ok {
+			// a table that fails part way still covers its start
+			rows, _ = cfi.Table(fde)
+			m.rows[fde] = rows
+		}
+		if row, ok := cfi.Find(rows, pc); ok {
+			return row, fde.CIE, true
+		}
+	}
+	return cfi.Row{}, nil, false
+}
@@ -0,0 +1,152 @@
+package core
+
+import "fmt"
+
+// Note types of core files.
+const (
+	ntPRStatus  = 1
+	ntPRFPReg   = 2
+	ntPRPSInfo  = 3
+	ntAuxv      = 6
+	ntSigInfo   = 0x53494749
+	ntFile      = 0x46494c45
+	ntPRXFPReg  = 0x46e62b7f
+	ntX86XState = 0x202
+	ntARMVFP    = 0x400
+)
+
+var noteNames = map[uint32]string{
+	ntPRStatus:  "NT_PRSTATUS",
+	ntPRFPReg:   "NT_PRFPREG",
+	ntPRPSInfo:  "NT_PRPSINFO",
+	ntAuxv:      "NT_AUXV",
+	ntSigInfo:   "NT_SIGINFO",
+	ntFile:      "NT_FILE",
+	ntPRXFPReg:  "NT_PRXFPREG",
+	ntX86XState: "NT_X86_XSTATE",
+	ntARMVFP:    "NT_ARM_VFP",
+	0x401:       "NT_ARM_TLS",
+	0x402:       "NT_ARM_HW_BREAK",
+	0x403:       "NT_ARM_HW_WATCH",
+	0x404:       "NT_ARM_SYSTEM_CALL",
+	0x405:       "NT_ARM_SVE",
+	0x406:       "NT_ARM_PAC_MASK",
+	0x409:       "NT_ARM_TAGGED_ADDR_CTRL",
+	0x200:       "NT_386_TLS",
+	0x201:       "NT_386_IOPERM",
+	0x204:       "NT_X86_SHSTK",
+	0x100:       "NT_PPC_VMX",
+	0x102:       "NT_PPC_VSX",
+	0x900:       "NT_RISCV_CSR",
+}
+
+// NoteName returns the name of the core note type t.
+func NoteName(t uint32) string {
+	if s, ok := noteNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("0x%x", t)
+}
+
+var auxvNames = map[uint64]string{
+	0:  "AT_NULL",
+	1:  "AT_IGNORE",
+	2:  "AT_EXECFD",
+	3:  "AT_PHDR",
+	4:  "AT_PHENT",
+	5:  "AT_PHNUM",
+	6:  "AT_PAGESZ",
+	7:  "AT_BASE",
+	8:  "AT_FLAGS",
+	9:  "AT_ENTRY",
+	10: "AT_NOTELF",
+	11: "AT_UID",
+	12: "AT_EUID",
+	13: "AT_GID",
+	14: "AT_EGID",
+	15: "AT_PLATFORM",
+	16: "AT_HWCAP",
+	17: "AT_CLKTCK",
+	23: "AT_SECURE",
+	24: "AT_BASE_PLATFORM",
+	25: "AT_RANDOM",
+	26: "AT_HWCAP2",
+	27: "AT_RSEQ_FEATURE_SIZE",
+	28: "AT_RSEQ_ALIGN",
+	29: "AT_HWCAP3",
+	30: "AT_HWCAP4",
+	31: "AT_EXECFN",
+	32: "AT_SYSINFO",
+	33: "AT_SYSINFO_EHDR",
+	51: "AT_MINSIGSTKSZ",
+}
+
+// Auxiliary vector tags the decoder resolves.
+const (
+	atPlatform     = 15
+	atBasePlatform = 24
+	atExecFn       = 31
+	atSysinfoEHdr  = 33
+	atEntry        = 9
+	atPHdr         = 3
+)
+
+// AuxvName returns the name of the auxiliary vector tag t.
+func AuxvName(t uint64) string {
+	if s, ok := auxvNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("AT_0x%x", t)
+}
+
+var signalNames = []string{
+	1: "SIGHUP", 2: "SIGINT", 3: "SIGQUIT", 4: "SIGILL", 5: "SIGTRAP",
+	6: "SIGABRT", 7: "SIGBUS", 8: "SIGFPE", 9: "SIGKILL", 10: "SIGUSR1",
+	11: "SIGSEGV", 12: "SIGUSR2", 13: "SIGPIPE", 14: "SIGALRM", 15: "SIGTERM",
+	16: "SIGSTKFLT", 17: "SIGCHLD", 18: "SIGCONT", 19: "SIGSTOP", 20: "SIGTSTP",
+	21: "SIGTTIN", 22: "SIGTTOU", 23: "SIGURG", 24: "SIGXCPU", 25: "SIGXFSZ",
+	26: "SIGVTALRM", 27: "SIGPROF", 28: "SIGWINCH", 29: "SIGIO", 30: "SIGPWR",
+	31: "SIGSYS",
+}
+
+// SignalName returns the name of the Linux signal number s.
+func SignalName(s int) string {
+	if s > 0 && s < len(signalNames) {
+		return signalNames[s]
+	}
+	if s >= 32 && s <= 64 {
+		return fmt.Sprintf("SIGRT%d", s-32)
+	}
+	return fmt.Sprintf("signal %d", s)
+}
+
+var (
+	siCodes = map[int32]string{
+		0: "SI_USER", 0x80: "SI_KERNEL", -1: "SI_QUEUE", -2: "SI_TIMER",
+		-3: "SI_MESGQ", -4: "SI_ASYNCIO", -5: "SI_SIGIO", -6: "SI_TKILL",
+	}
+	faultCodes = map[int][]string{
+		4:  {1: "ILL_ILLOPC", 2: "ILL_ILLOPN", 3: "ILL_ILLADR", 4: "ILL_ILLTRP", 5: "ILL_PRVOPC", 6: "ILL_PRVREG", 7: "ILL_COPROC", 8: "ILL_BADSTK"},
+		5:  {1: "TRAP_BRKPT", 2: "TRAP_TRACE", 3: "TRAP_BRANCH", 4: "TRAP_HWBKPT"},
+		7:  {1: "BUS_ADRALN", 2: "BUS_ADRERR", 3: "BUS_OBJERR", 4: "BUS_MCEERR_AR", 5: "BUS_MCEERR_AO"},
+		8:  {1: "FPE_INTDIV", 2: "FPE_INTOVF", 3: "FPE_FLTDIV", 4: "FPE_FLTOVF", 5: "FPE_FLTUND", 6: "FPE_FLTRES", 7: "FPE_FLTINV", 8: "FPE_FLTSUB"},
+		11: {1: "SEGV_MAPERR", 2: "SEGV_ACCERR", 3: "SEGV_BNDERR", 4: "SEGV_PKUERR"},
+	}
+)
+
+// isFault tells whether signal s carries a fault address.
+func isFault(s int) bool {
+	return s == 4 || s == 5 || s == 7 || s == 8 || s == 11
+}
+
+// SigCodeName returns the name of the si_code c of signal s.
+func SigCodeName(s int, c int32) string {
+	if c > 0 && c < 0x80 {
+		if names := faultCodes[s]; int(c) < len(names) && names[c] != "" {
+			return names[c]
+		}
+	} else if name, ok := siCodes[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", c)
+}
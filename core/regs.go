@@ -0,0 +1,141 @@
+package core
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+// regNames are the general registers of NT_PRSTATUS, the elf_gregset_t
+// of the Linux ABI of each machine, in their order in the note.
+var regNames = map[elf.Machine][]string{
+	elf.EM_X86_64: {
+		"r15", "r14", "r13", "r12", "rbp", "rbx", "r11", "r10",
+		"r9", "r8", "rax", "rcx", "rdx", "rsi", "rdi", "orig_rax",
+		"rip", "cs", "eflags", "rsp", "ss", "fs_base", "gs_base",
+		"ds", "es", "fs", "gs",
+	},
+	elf.EM_386: {
+		"ebx", "ecx", "edx", "esi", "edi", "ebp", "eax", "ds",
+		"es", "fs", "gs", "orig_eax", "eip", "cs", "eflags", "esp", "ss",
+	},
+	elf.EM_AARCH64: {
+		"x0", "x1", "x2", "x3", "x4", "x5", "x6", "x7",
+		"x8", "x9", "x10", "x11", "x12", "x13", "x14", "x15",
+		"x16", "x17", "x18", "x19", "x20", "x21", "x22", "x23",
+		"x24", "x25", "x26", "x27", "x28", "x29", "x30", "sp",
+		"pc", "pstate",
+	},
+	elf.EM_ARM: {
+		"r0", "r1", "r2", "r3", "r4", "r5", "r6", "r7",
+		"r8", "r9", "r10", "fp", "ip", "sp", "lr", "pc",
+		"cpsr", "orig_r0",
+	},
+	elf.EM_RISCV: {
+		"pc", "ra", "sp", "gp", "tp", "t0", "t1", "t2",
+		"s0", "s1", "a0", "a1", "a2", "a3", "a4", "a5",
+		"a6", "a7", "s2", "s3", "s4", "s5", "s6", "s7",
+		"s8", "s9", "s10", "s11", "t3", "t4", "t5", "t6",
+	},
+	elf.EM_PPC64: {
+		"r0", "r1", "r2", "r3", "r4", "r5", "r6", "r7",
+		"r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15",
+		"r16", "r17", "r18", "r19", "r20", "r21", "r22", "r23",
+		"r24", "r25", "r26", "r27", "r28", "r29", "r30", "r31",
+		"nip", "msr", "orig_r3", "ctr", "lr", "xer", "ccr", "softe",
+		"trap", "dar", "dsisr", "result",
+	},
+}
+
+// pcSP names the program counter and stack pointer of each machine.
+var pcSP = map[elf.Machine][2]string{
+	elf.EM_X86_64:  {"rip", "rsp"},
+	elf.EM_386:     {"eip", "esp"},
+	elf.EM_AARCH64: {"pc", "sp"},
+	elf.EM_ARM:     {"pc", "sp"},
+	elf.EM_RISCV:   {"pc", "sp"},
+	elf.EM_PPC64:   {"nip", "r1"},
+}
+
+// A Reg is a named register value.
+type Reg struct {
+	Name  string
+	Value uint64
+}
+
+// decodeRegs splits the register set b of machine m into words of
+// size w, named after the Linux layout or r0, r1... for machines it
+// does not know.
+func decodeRegs(b []byte, m elf.Machine, w int, order binary.ByteOrder) []Reg {
+	names := regNames[m]
+	n := len(b) / w
+	if names != nil && len(names) < n {
+		n = len(names)
+	}
+	regs := make([]Reg, n)
+	for i := range regs {
+		name := fmt.Sprintf("r%d", i)
+		if names != nil {
+			name = names[i]
+		}
+		var v uint64
+		if w == 8 {
+			v = order.Uint64(b[i*8:])
+		} else {
+			v = uint64(order.Uint32(b[i*4:]))
+		}
+		regs[i] = Reg{name, v}
+	}
+	return regs
+}
+
+// A VecReg is a floating point or vector register.
+type VecReg struct {
+	Name string
+	// Bytes is the register in memory order
+	Bytes []byte
+}
+
+// FPState is the decoded NT_PRFPREG of a thread.
+type FPState struct {
+	// Control are the control and status registers
+	Control []Reg
+	Vectors []VecReg
+}
+
+// FP decodes the floating point registers of t, the fxsave area on
+// x86-64 and user_fpsimd_state on AArch64. It returns nil for other
+// machines or threads without them.
+func (c *Core) FP(t *Thread) *FPState {
+	b, order := t.FPRegs, c.ByteOrder
+	switch {
+	case c.Machine == elf.EM_X86_64 && len(b) >= 416:
+		s := &FPState{Control: []Reg{
+			{"fcw", uint64(order.Uint16(b[0:]))},
+			{"fsw", uint64(order.Uint16(b[2:]))},
+			{"ftw", uint64(b[4])},
+			{"fop", uint64(order.Uint16(b[6:]))},
+			{"fip", order.Uint64(b[8:])},
+			{"fdp", order.Uint64(b[16:])},
+			{"mxcsr", uint64(order.Uint32(b[24:]))},
+		}}
+		for i := 0; i < 8; i++ {
+			// 80-bit x87 registers in 16-byte slots
+			s.Vectors = append(s.Vectors, VecReg{fmt.Sprintf("st%d", i), b[32+16*i : 32+16*i+10]})
+		}
+		for i := 0; i < 16; i++ {
+			s.Vectors = append(s.Vectors, VecReg{fmt.Sprintf("xmm%d", i), b[160+16*i : 176+16*i]})
+		}
+		return s
+	case c.Machine == elf.EM_AARCH64 && len(b) >= 520:
+		s := &FPState{Control: []Reg{
+			{"fpsr", uint64(order.Uint32(b[512:]))},
+			{"fpcr", uint64(order.Uint32(b[516:]))},
+		}}
+		for i := 0; i < 32; i++ {
+			s.Vectors = append(s.Vectors, VecReg{fmt.Sprintf("v%d", i), b[16*i : 16*i+16]})
+		}
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package core
+
+import (
+	"debug/elf"
+	"elfreader/cfi"
+	"encoding/binary"
+	"fmt"
+)
+
+// How the frames of a backtrace were found.
+const (
+	// ViaRegisters is the innermost frame, from the registers of the
+	// thread
+	ViaRegisters = "registers"
+	ViaCFI       = "cfi"
+	// ViaFramePointer follows the saved frame pointer, for code
+	// without call frame information
+	ViaFramePointer = "frame pointer"
+	// ViaReturn recovers from a call to an unmapped address, taking
+	// the return address from the stack or the link register
+	ViaReturn = "return address"
+)
+
+// maxFrames bounds a backtrace, against loops in corrupt stacks.
+const maxFrames = 256
+
+// A Frame is a frame of a backtrace.
+type Frame struct {
+	PC, SP uint64
+	// Module maps PC; nil if no file does
+	Module *Module
+	// Via is how the frame was unwound from the one below it
+	Via string
+	// Caller is set when PC is a return address, which is looked up
+	// as PC-1 to land in the call instruction
+	Caller bool
+}
+
+// LookupPC returns the address to symbolize the frame at.
+func (f Frame) LookupPC() uint64 {
+	if f.Caller {
+		return f.PC - 1
+	}
+	return f.PC
+}
+
+// unwindArch holds the DWARF registers the unwinder uses.
+type unwindArch struct {
+	sp, fp, ra uint64
+	// link is set for machines returning through a link register,
+	// which keeps its value in functions without a rule for it
+	link bool
+	// addrMask strips pointer authentication codes from return
+	// addresses
+	addrMask uint64
+}
+
+var unwindArchs = map[elf.Machine]unwindArch{
+	elf.EM_X86_64:  {sp: 7, fp: 6, ra: 16, addrMask: ^uint64(0)},
+	elf.EM_AARCH64: {sp: 31, fp: 29, ra: 30, link: true, addrMask: 1<<48 - 1},
+}
+
+// regState maps DWARF register numbers to their values in a frame.
+// Registers not in it are unknown.
+type regState map[uint64]uint64
+
+// Backtrace unwinds the stack of t, finding the code of each frame in
+// mods. Frames are unwound with the CFI of .eh_frame or .debug_frame,
+// falling back to the frame pointer chain. Only x86-64 and AArch64 are
+// supported; other machines yield the innermost frame alone.
+func (c *Core) Backtrace(t *Thread, mods []*Module) []Frame {
+	pc, sp, ok := c.PCSP(t)
+	if !ok {
+		return nil
+	}
+	frames := []Frame{{PC: pc, SP: sp, Module: ModuleOf(mods, pc), Via: ViaRegisters}}
+	a, ok := unwindArchs[c.Machine]
+	if !ok {
+		return frames
+	}
+	regs := make(regState)
+	name := cfi.RegNames(c.Machine)
+	for r := uint64(0); r < 128; r++ {
+		if n := name(r); n == "" {
+			continue
+		} else if v, ok := t.Reg(n); ok {
+			regs[r] = v
+		}
+	}
+
+	for len(frames) < maxFrames {
+		cur := frames[len(frames)-1]
+		next, via, signal := c.unwind(a, cur, regs, len(frames) == 1)
+		if next == nil {
+			break
+		}
+		npc, ok := next[a.ra]
+		if !ok || npc == 0 {
+			break
+		}
+		npc &= a.addrMask
+		nsp := next[a.sp]
+		// the stack grows down, except into the interrupted code of a
+		// signal handler, which may run on an alternate stack
+		if nsp < cur.SP && !signal || nsp == cur.SP && npc == cur.PC {
+			break
+		}
+		frames = append(frames, Frame{PC: npc, SP: nsp, Module: ModuleOf(mods, npc), Via: via, Caller: !signal})
+		regs = next
+	}
+	return frames
+}
+
+// unwind returns the registers of the caller of frame f, how they were
+// found and whether f is a signal frame, whose caller was interrupted
+// rather than making a call. It returns nil registers at the end of the
+// stack or when f cannot be unwound.
+func (c *Core) unwind(a unwindArch, f Frame, regs regState, innermost bool) (regState, string, bool) {
+	if f.Module == nil {
+		if !innermost {
+			return nil, "", false
+		}
+		return c.unwindReturn(a, regs), ViaReturn, false
+	}
+	if row, cie, ok := f.Module.rowFor(f.LookupPC() - f.Module.Bias); ok {
+		if next, err := c.unwindCFI(a, row, cie, regs); err == nil {
+			return next, ViaCFI, cie.SignalFrame
+		}
+	}
+	return c.unwindFP(a, regs), ViaFramePointer, false
+}
+
+// unwindCFI applies the rules of row to regs.
+func (c *Core) unwindCFI(a unwindArch, row cfi.Row, cie *cfi.CIE, regs regState) (regState, error) {
+	var cfa uint64
+	switch r := row.CFA; {
+	case r.Kind != cfi.RuleCFA:
+		return nil, fmt.Errorf("no CFA rule")
+	case r.Expr != nil:
+		v, err := c.eval(r.Expr, regs)
+		if err != nil {
+			return nil, err
+		}
+		cfa = v
+	default:
+		v, ok := regs[r.Reg]
+		if !ok {
+			return nil, fmt.Errorf("CFA register %d unknown", r.Reg)
+		}
+		cfa = v + uint64(r.Offset)
+	}
+
+	// registers without a rule keep their values, as the callee-saved
+	// ones do; the return address column does only for a link register
+	next := make(regState, len(regs))
+	for r, v := range regs {
+		next[r] = v
+	}
+	if !a.link {
+		delete(next, cie.RAReg)
+	}
+	next[a.sp] = cfa
+	for r, rule := range row.Regs {
+		var v uint64
+		var err error
+		switch rule.Kind {
+		case cfi.RuleSameValue:
+			continue
+		case cfi.RuleOffset:
+			v, err = c.readWord(cfa + uint64(rule.Offset))
+		case cfi.RuleValOffset:
+			v = cfa + uint64(rule.Offset)
+		case cfi.RuleRegister:
+			var ok bool
+			if v, ok = regs[rule.Reg]; !ok {
+				err = fmt.Errorf("register %d unknown", rule.Reg)
+			}
+		case cfi.RuleExpression:
+			if v, err = c.eval(rule.Expr, regs, cfa); err == nil {
+				v, err = c.readWord(v)
+			}
+		case cfi.RuleValExpression:
+			v, err = c.eval(rule.Expr, regs, cfa)
+		default:
+			delete(next, r)
+			continue
+		}
+		if err != nil {
+			if r == cie.RAReg {
+				return nil, err
+			}
+			delete(next, r)
+			continue
+		}
+		next[r] = v
+	}
+	if cie.RAReg != a.ra {
+		if v, ok := next[cie.RAReg]; ok {
+			next[a.ra] = v
+		}
+	}
+	return next, nil
+}
+
+// unwindFP follows the frame record the frame pointer points to, which
+// holds the caller's frame pointer and the return address on both
+// x86-64 and AArch64.
+func (c *Core) unwindFP(a unwindArch, regs regState) regState {
+	fp, ok := regs[a.fp]
+	if !ok || fp == 0 || fp < regs[a.sp] {
+		return nil
+	}
+	w := uint64(c.WordSize)
+	caller, err1 := c.readWord(fp)
+	ra, err2 := c.readWord(fp + w)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	return regState{a.fp: caller, a.ra: ra, a.sp: fp + 2*w}
+}
+
+// unwindReturn returns the caller of a call to an unmapped address,
+// before the callee has pushed anything: the return address is at the
+// top of the stack or in the link register.
+func (c *Core) unwindReturn(a unwindArch, regs regState) regState {
+	next := make(regState, len(regs))
+	for r, v := range regs {
+		next[r] = v
+	}
+	if a.link {
+		return next
+	}
+	sp := regs[a.sp]
+	ra, err := c.readWord(sp)
+	if err != nil {
+		return nil
+	}
+	next[a.ra], next[a.sp] = ra, sp+uint64(c.WordSize)
+	return next
+}
+
+func (c *Core) readWord(addr uint64) (uint64, error) {
+	b := make([]byte, c.WordSize)
+	if err := c.ReadMemory(b, addr); err != nil {
+		return 0, err
+	}
+	return c.word(b), nil
+}
+
+// eval evaluates the DWARF expression expr on a stack holding push,
+// supporting the operations CFI expressions use: constants, register
+// values, dereferences and arithmetic.
+func (c *Core) eval(expr []byte, regs regState, push ...uint64) (uint64, error) {
+	stack := append([]uint64(nil), push...)
+	pop := func() uint64 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	order := c.ByteOrder
+	for i := 0; i < len(expr); {
+		op := expr[i]
+		i++
+		// operands must be complete, and binary operations need two
+		// values on the stack
+		need := 0
+		switch {
+		case op >= 0x70 && op <= 0x8f, op == 0x10, op == 0x11, op == 0x23:
+		case op == 0x06, op == 0x12, op == 0x13:
+			need = 1
+		case op == 0x1a, op == 0x1c, op == 0x1e, op == 0x21, op == 0x22,
+			op == 0x24, op == 0x25, op == 0x26, op == 0x27, op >= 0x29 && op <= 0x2e:
+			need = 2
+		}
+		if len(stack) < need {
+			return 0, fmt.Errorf("DWARF expression stack underflow")
+		}
+		switch {
+		case op >= 0x30 && op <= 0x4f: // DW_OP_lit<n>
+			stack = append(stack, uint64(op-0x30))
+		case op >= 0x70 && op <= 0x8f: // DW_OP_breg<n>
+			off, n := sleb(expr[i:])
+			i += n
+			v, ok := regs[uint64(op-0x70)]
+			if !ok {
+				return 0, fmt.Errorf("register %d unknown", op-0x70)
+			}
+			stack = append(stack, v+uint64(off))
+		case op >= 0x08 && op <= 0x0f: // DW_OP_const<size><u|s>
+			size := 1 << ((op - 0x08) / 2)
+			if i+size > len(expr) {
+				return 0, fmt.Errorf("truncated DWARF expression")
+			}
+			v := constant(expr[i:i+size], order, op%2 == 1)
+			i += size
+			stack = append(stack, v)
+		case op == 0x10: // DW_OP_constu
+			v, n := uleb(expr[i:])
+			i += n
+			stack = append(stack, v)
+		case op == 0x11: // DW_OP_consts
+			v, n := sleb(expr[i:])
+			i += n
+			stack = append(stack, uint64(v))
+		case op == 0x06: // DW_OP_deref
+			v, err := c.readWord(pop())
+			if err != nil {
+				return 0, err
+			}
+			stack = append(stack, v)
+		case op == 0x12: // DW_OP_dup
+			stack = append(stack, stack[len(stack)-1])
+		case op == 0x13: // DW_OP_drop
+			pop()
+		case op == 0x23: // DW_OP_plus_uconst
+			v, n := uleb(expr[i:])
+			i += n
+			if len(stack) == 0 {
+				return 0, fmt.Errorf("DWARF expression stack underflow")
+			}
+			stack[len(stack)-1] += v
+		case need == 2:
+			b, a := pop(), pop()
+			stack = append(stack, binaryOp(op, a, b))
+		default:
+			return 0, fmt.Errorf("unsupported DWARF operation 0x%x", op)
+		}
+	}
+	if len(stack) == 0 {
+		return 0, fmt.Errorf("empty DWARF expression")
+	}
+	return stack[len(stack)-1], nil
+}
+
+// binaryOp applies the DWARF arithmetic or comparison op to a and b.
+func binaryOp(op byte, a, b uint64) uint64 {
+	t := func(c bool) uint64 {
+		if c {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case 0x1a:
+		return a & b
+	case 0x1c:
+		return a - b
+	case 0x1e:
+		return a * b
+	case 0x21:
+		return a | b
+	case 0x22:
+		return a + b
+	case 0x24:
+		return a << b
+	case 0x25:
+		return a >> b
+	case 0x26:
+		return uint64(int64(a) >> b)
+	case 0x27:
+		return a ^ b
+	case 0x29:
+		return t(a == b)
+	case 0x2a:
+		return t(int64(a) >= int64(b))
+	case 0x2b:
+		return t(int64(a) > int64(b))
+	case 0x2c:
+		return t(int64(a) <= int64(b))
+	case 0x2d:
+		return t(int64(a) < int64(b))
+	default:
+		return t(a != b)
+	}
+}
+
+// constant decodes the operand b of a DW_OP_const operation.
+func constant(b []byte, order binary.ByteOrder, signed bool) uint64 {
+	switch len(b) {
+	case 1:
+		if signed {
+			return uint64(int8(b[0]))
+		}
+		return uint64(b[0])
+	case 2:
+		if signed {
+			return uint64(int16(order.Uint16(b)))
+		}
+		return uint64(order.Uint16(b))
+	case 4:
+		if signed {
+			return uint64(int32(order.Uint32(b)))
+		}
+		return uint64(order.Uint32(b))
+	}
+	return order.Uint64(b)
+}
+
+// uleb decodes an unsigned LEB128 number, returning it and its length.
+func uleb(b []byte) (uint64, int) {
+	var v uint64
+	var shift uint
+	for i, c := range b {
+		v |= uint64(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+// sleb decodes a signed LEB128 number, returning it and its length.
+func sleb(b []byte) (int64, int) {
+	var v int64
+	var shift uint
+	for i, c := range b {
+		v |= int64(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
@@ -0,0 +1,296 @@
+package debuginfo
+
+import (
+	"debug/dwarf"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A Frame is one source location of an address. An address inside
+// inlined code has one frame per inlining level.
+type Frame struct {
+	// Function is the linkage name of the function, or its plain
+	// name if it has none
+	Function string
+	File     string
+	Line     int
+	Column   int
+}
+
+// unitRange is an address range of a compile unit.
+type unitRange struct {
+	low, high uint64
+	unit      *dwarf.Entry
+}
+
+// A Symbolizer maps addresses to source locations and back.
+type Symbolizer struct {
+	d      *dwarf.Data
+	ranges []unitRange
+	units  []*dwarf.Entry
+}
+
+// NewSymbolizer indexes the compile units of d by address.
+func NewSymbolizer(d *dwarf.Data) (*Symbolizer, error) {
+	s := &Symbolizer{d: d}
+	r := d.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == dwarf.TagCompileUnit || e.Tag == dwarf.TagPartialUnit {
+			s.units = append(s.units, e)
+			ranges, err := d.Ranges(e)
+			if err != nil {
+				return nil, err
+			}
+			for _, rg := range ranges {
+				s.ranges = append(s.ranges, unitRange{rg[0], rg[1], e})
+			}
+		}
+		r.SkipChildren()
+	}
+	sort.Slice(s.ranges, func(i, j int) bool { return s.ranges[i].low < s.ranges[j].low })
+	return s, nil
+}
+
+// ErrUnknownPC is returned by Frames for addresses not described by
+// the debugging information.
+var ErrUnknownPC = errors.New("address not covered by debugging information")
+
+func (s *Symbolizer) unit(pc uint64) *dwarf.Entry {
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].low > pc }) - 1
+	for ; i >= 0; i-- {
+		if pc < s.ranges[i].high {
+			return s.ranges[i].unit
+		}
+	}
+	return nil
+}
+
+// Frames returns the source locations of pc, innermost inlined
+// function first and the function containing the code last.
+func (s *Symbolizer) Frames(pc uint64) ([]Frame, error) {
+	cu := s.unit(pc)
+	if cu == nil {
+		return nil, ErrUnknownPC
+	}
+
+	lr, err := s.d.LineReader(cu)
+	if err != nil {
+		return nil, err
+	}
+	var line dwarf.LineEntry
+	haveLine := lr != nil && lineAt(lr, pc, &line)
+
+	scopes, err := s.scopes(cu, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	// the innermost frame is located by the line table, each outer
+	// one by the call site attributes of the frame inside it
+	frame := Frame{}
+	if haveLine && line.File != nil {
+		frame.File, frame.Line, frame.Column = line.File.Name, line.Line, line.Column
+	}
+	var files []*dwarf.LineFile
+	if lr != nil {
+		files = lr.Files()
+	}
+	var frames []Frame
+	for i := len(scopes) - 1; i >= 0; i-- {
+		e := scopes[i]
+		frame.Function = s.functionName(e)
+		frames = append(frames, frame)
+
+		frame = Frame{}
+		if idx, ok := e.Val(dwarf.AttrCallFile).(int64); ok && idx >= 0 && int(idx) < len(files) && files[idx] != nil {
+			frame.File = files[idx].Name
+		}
+		if l, ok := e.Val(dwarf.AttrCallLine).(int64); ok {
+			frame.Line = int(l)
+		}
+		if c, ok := e.Val(dwarf.AttrCallColumn).(int64); ok {
+			frame.Column = int(c)
+		}
+	}
+	if len(frames) == 0 {
+		// code without a subprogram DIE, such as assembly
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// lineAt sets *entry to the row of the line table of lr covering pc.
+// Unlike LineReader.SeekPC it scans every sequence: compilers need not
+// emit them in address order, and GCC puts the sequence of a function
+// in .text.startup after those of the .text functions it precedes.
+func lineAt(lr *dwarf.LineReader, pc uint64, entry *dwarf.LineEntry) bool {
+	var e, prev dwarf.LineEntry
+	inSeq := false
+	for lr.Next(&e) == nil {
+		if inSeq && prev.Address <= pc && pc < e.Address {
+			*entry = prev
+			return true
+		}
+		inSeq = !e.EndSequence
+		prev = e
+	}
+	return false
+}
+
+// scopes returns the subprogram and the inlined subroutines containing
+// pc inside cu, outermost first.
+func (s *Symbolizer) scopes(cu *dwarf.Entry, pc uint64) ([]*dwarf.Entry, error) {
+	r := s.d.Reader()
+	r.Seek(cu.Offset)
+	if _, err := r.Next(); err != nil {
+		return nil, err
+	}
+	if !cu.Children {
+		return nil, nil
+	}
+
+	var chain []*dwarf.Entry
+	var walk func() error
+	walk = func() error {
+		for {
+			e, err := r.Next()
+			if err != nil {
+				return err
+			}
+			if e == nil || e.Tag == 0 {
+				return nil
+			}
+			switch e.Tag {
+			case dwarf.TagSubprogram, dwarf.TagInlinedSubroutine, dwarf.TagLexDwarfBlock:
+				if !s.contains(e, pc) {
+					break
+				}
+				if e.Tag != dwarf.TagLexDwarfBlock {
+					chain = append(chain, e)
+				}
+				if e.Children {
+					if err := walk(); err != nil {
+						return err
+					}
+				}
+				// the scope containing pc is found, nothing
+				// after it in this unit matters
+				return errFound
+			case dwarf.TagNamespace, dwarf.TagModule:
+				if e.Children {
+					if err := walk(); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if e.Children {
+				r.SkipChildren()
+			}
+		}
+	}
+	if err := walk(); err != nil && err != errFound {
+		return nil, err
+	}
+	return chain, nil
+}
+
+var errFound = errors.New("found")
+
+func (s *Symbolizer) contains(e *dwarf.Entry, pc uint64) bool {
+	ranges, err := s.d.Ranges(e)
+	if err != nil {
+		return false
+	}
+	for _, rg := range ranges {
+		if pc >= rg[0] && pc < rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// attrMIPSLinkageName is the pre-DWARF 4 vendor attribute for linkage
+// names, still emitted by some compilers.
+const attrMIPSLinkageName dwarf.Attr = 0x2007
+
+// functionName returns the name of the function of a subprogram or
+// inlined subroutine entry, following abstract origins and
+// specifications.
+func (s *Symbolizer) functionName(e *dwarf.Entry) string {
+	for i := 0; i < 8 && e != nil; i++ {
+		for _, attr := range []dwarf.Attr{dwarf.AttrLinkageName, attrMIPSLinkageName} {
+			if name, ok := e.Val(attr).(string); ok {
+				return name
+			}
+		}
+		if name, ok := e.Val(dwarf.AttrName).(string); ok {
+			return name
+		}
+		off, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+		if !ok {
+			off, ok = e.Val(dwarf.AttrSpecification).(dwarf.Offset)
+		}
+		if !ok {
+			break
+		}
+		r := s.d.Reader()
+		r.Seek(off)
+		e, _ = r.Next()
+	}
+	return ""
+}
+
+// Addrs returns the addresses of the statements on line of the source
+// file name. name matches a file whose path equals it or ends with it
+// after a "/".
+func (s *Symbolizer) Addrs(name string, line int) ([]uint64, error) {
+	var addrs []uint64
+	seen := make(map[uint64]bool)
+	for _, cu := range s.units {
+		lr, err := s.d.LineReader(cu)
+		if err != nil {
+			return nil, err
+		}
+		if lr == nil {
+			continue
+		}
+		var e dwarf.LineEntry
+		// only the first row of each run of statement rows for the
+		// line counts; rows that are not statements neither start nor
+		// end a run, as they often precede the statement row of the
+		// same line, such as at the start of a .cold part
+		prevMatch := false
+		for lr.Next(&e) == nil {
+			if e.EndSequence {
+				prevMatch = false
+				continue
+			}
+			if !e.IsStmt {
+				continue
+			}
+			match := e.Line == line && e.File != nil && matchPath(e.File.Name, name)
+			if match && !prevMatch && !seen[e.Address] {
+				seen[e.Address] = true
+				addrs = append(addrs, e.Address)
+			}
+			prevMatch = match
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	return addrs, nil
+}
+
+func matchPath(full, name string) bool {
+	full, name = path.Clean(full), path.Clean(name)
+	return full == name || strings.HasSuffix(full, "/"+name)
+}
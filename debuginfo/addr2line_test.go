@@ -0,0 +1,159 @@
+package debuginfo
+
+import (
+	"debug/elf"
+	"elfreader/file"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const coldSource = `#include <cstdio>
+#include <stdexcept>
+
+int main(int argc, char **argv)
+{
+	int sum = 0;
+	for (int i = 0; i < argc; i++) { if (argv[i][0] == '-') throw std::runtime_error(argv[i]); sum += argv[i][0]; }
+	std::printf("%d\n", sum);
+	return 0;
+}
+`
+
+const startupSource = `int helper(int x) __attribute__((noinline));
+int helper(int x) { return x * 3 + 1; }
+
+int main(int argc, char **argv)
+{
+	return helper(argc);
+}
+`
+
+// TestFramesStartup symbolizes main of an -O2 build, which GCC places
+// in .text.startup so that its line table sequence follows the one of
+// the .text functions at lower addresses.
+func TestFramesStartup(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not found")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "t.c")
+	if err := os.WriteFile(src, []byte(startupSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"-gdwarf-4", "-gdwarf-5"} {
+		t.Run(version, func(t *testing.T) {
+			bin := filepath.Join(dir, "t"+version)
+			out, err := exec.Command("gcc", "-O2", version, "-o", bin, src).CombinedOutput()
+			if err != nil {
+				t.Skipf("gcc %s: %v\n%s", version, err, out)
+			}
+			f, err := file.Open(bin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			var main uint64
+			syms, err := f.Symbols()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range syms {
+				if s.Name == "main" && elf.ST_TYPE(s.Info) == elf.STT_FUNC {
+					main = s.Value
+				}
+			}
+			if main == 0 {
+				t.Fatal("no main symbol")
+			}
+
+			d, err := Load(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewSymbolizer(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frames, err := s.Frames(main)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := frames[len(frames)-1]
+			if last.Function != "main" || filepath.Base(last.File) != "t.c" || last.Line < 4 || last.Line > 7 {
+				t.Errorf("Frames(%#x) = %+v, want main in t.c:4-7", main, frames)
+			}
+		})
+	}
+}
+
+// build compiles source, named name, with the compiler cc and flags,
+// skipping the test if it cannot, and opens the result.
+func build(t *testing.T, cc, name, source string, flags ...string) *file.File {
+	t.Helper()
+	if _, err := exec.LookPath(cc); err != nil {
+		t.Skipf("%s not found", cc)
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, name)
+	if err := os.WriteFile(src, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "a.out")
+	args := append(append([]string{}, flags...), "-o", bin, src)
+	if out, err := exec.Command(cc, args...).CombinedOutput(); err != nil {
+		t.Skipf("%s: %v\n%s", cc, err, out)
+	}
+	f, err := file.Open(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// TestAddrsCold looks up a line that GCC splits between main and the
+// unlikely code it moves to main.cold, whose line table sequence starts
+// with rows that are not statements for that same line.
+func TestAddrsCold(t *testing.T) {
+	f := build(t, "g++", "a.cpp", coldSource, "-O2", "-gdwarf-5")
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs := make(map[string]file.Symbol)
+	for _, s := range syms {
+		funcs[s.Name] = s
+	}
+	hot, ok := funcs["main"]
+	cold, okCold := funcs["main.cold"]
+	if !ok || !okCold {
+		t.Skip("compiler did not split main into a .cold part")
+	}
+
+	d, err := Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSymbolizer(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := s.Addrs("a.cpp", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := func(sym file.Symbol) bool {
+		for _, a := range addrs {
+			if a >= sym.Value && a < sym.Value+sym.Size {
+				return true
+			}
+		}
+		return false
+	}
+	if !in(hot) || !in(cold) {
+		t.Errorf("Addrs(a.cpp, 7) = %#x, want addresses in both main and main.cold", addrs)
+	}
+}
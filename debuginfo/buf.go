@@ -0,0 +1,204 @@
+package debuginfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// errShort is recorded by buf when a read runs past the end of the data.
+var errShort = errors.New("unexpected end of section data")
+
+// buf decodes the primitive encodings of DWARF sections. The first
+// error is sticky: later reads return zero values.
+type buf struct {
+	data  []byte
+	off   int
+	order binary.ByteOrder
+	// dwarf64 selects 8-byte section offsets
+	dwarf64  bool
+	addrSize int
+	err      error
+}
+
+func newBuf(data []byte, order binary.ByteOrder) *buf {
+	return &buf{data: data, order: order, addrSize: 8}
+}
+
+func (b *buf) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+	b.off = len(b.data)
+}
+
+func (b *buf) left() int { return len(b.data) - b.off }
+
+func (b *buf) bytes(n int) []byte {
+	if n < 0 || b.left() < n {
+		b.fail(errShort)
+		return nil
+	}
+	s := b.data[b.off : b.off+n]
+	b.off += n
+	return s
+}
+
+func (b *buf) u8() uint8 {
+	s := b.bytes(1)
+	if s == nil {
+		return 0
+	}
+	return s[0]
+}
+
+func (b *buf) u16() uint16 {
+	s := b.bytes(2)
+	if s == nil {
+		return 0
+	}
+	return b.order.Uint16(s)
+}
+
+func (b *buf) u24() uint32 {
+	s := b.bytes(3)
+	if s == nil {
+		return 0
+	}
+	if b.order == binary.BigEndian {
+		return uint32(s[0])<<16 | uint32(s[1])<<8 | uint32(s[2])
+	}
+	return uint32(s[2])<<16 | uint32(s[1])<<8 | uint32(s[0])
+}
+
+func (b *buf) u32() uint32 {
+	s := b.bytes(4)
+	if s == nil {
+		return 0
+	}
+	return b.order.Uint32(s)
+}
+
+func (b *buf) u64() uint64 {
+	s := b.bytes(8)
+	if s == nil {
+		return 0
+	}
+	return b.order.Uint64(s)
+}
+
+// sized reads an unsigned value of n bytes.
+func (b *buf) sized(n int) uint64 {
+	switch n {
+	case 1:
+		return uint64(b.u8())
+	case 2:
+		return uint64(b.u16())
+	case 4:
+		return uint64(b.u32())
+	case 8:
+		return b.u64()
+	}
+	b.fail(fmt.Errorf("unsupported value size %d", n))
+	return 0
+}
+
+func (b *buf) uleb() uint64 {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		c := b.u8()
+		if b.err != nil {
+			return 0
+		}
+		if shift < 64 {
+			v |= uint64(c&0x7f) << shift
+		}
+		if c&0x80 == 0 {
+			return v
+		}
+	}
+}
+
+func (b *buf) sleb() int64 {
+	var v int64
+	var shift uint
+	for {
+		c := b.u8()
+		if b.err != nil {
+			return 0
+		}
+		if shift < 64 {
+			v |= int64(c&0x7f) << shift
+		}
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v
+		}
+	}
+}
+
+func (b *buf) addr() uint64 { return b.sized(b.addrSize) }
+
+// offset reads a section offset, 4 or 8 bytes depending on the
+// DWARF format.
+func (b *buf) offset() uint64 {
+	if b.dwarf64 {
+		return b.u64()
+	}
+	return uint64(b.u32())
+}
+
+// unitLength reads an initial length field, switching b to the 64-bit
+// format if it announces one.
+func (b *buf) unitLength() uint64 {
+	n := uint64(b.u32())
+	if n == 0xffffffff {
+		b.dwarf64 = true
+		return b.u64()
+	}
+	b.dwarf64 = false
+	if n >= 0xfffffff0 {
+		b.fail(fmt.Errorf("reserved unit length 0x%x", n))
+		return 0
+	}
+	return n
+}
+
+func (b *buf) cstring() string {
+	for i := b.off; i < len(b.data); i++ {
+		if b.data[i] == 0 {
+			s := string(b.data[b.off:i])
+			b.off = i + 1
+			return s
+		}
+	}
+	b.fail(errShort)
+	return ""
+}
+
+// sub returns a buf over the next n bytes and skips them in b.
+func (b *buf) sub(n uint64) *buf {
+	if n > uint64(b.left()) {
+		b.fail(errShort)
+		return &buf{order: b.order, dwarf64: b.dwarf64, addrSize: b.addrSize}
+	}
+	s := &buf{data: b.data[b.off : b.off+int(n)], order: b.order, dwarf64: b.dwarf64, addrSize: b.addrSize}
+	b.off += int(n)
+	return s
+}
+
+// cstringAt returns the NUL-terminated string at off in data.
+func cstringAt(data []byte, off uint64) (string, bool) {
+	if off >= uint64(len(data)) {
+		return "", false
+	}
+	for i := off; i < uint64(len(data)); i++ {
+		if data[i] == 0 {
+			return string(data[off:i]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,76 @@
+// Package debuginfo reads the DWARF debugging information of ELF files
+// opened with package file and answers source-level queries on it.
+package debuginfo
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"elfreader/file"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoDWARF is returned by Load for files without a .debug_info section.
+var ErrNoDWARF = errors.New("no DWARF debugging information")
+
+// SectionData returns the contents of s, decompressing it through
+// s.Open if it is stored compressed.
+func SectionData(s *file.Section) ([]byte, error) {
+	r := s.Open()
+	if r == nil {
+		return nil, fmt.Errorf("section %s: unsupported compression", s.Name)
+	}
+	return io.ReadAll(r)
+}
+
+// dwarf5Sections are handed to dwarf.Data.AddSection when present.
+var dwarf5Sections = []string{
+	".debug_addr",
+	".debug_line_str",
+	".debug_loclists",
+	".debug_rnglists",
+	".debug_str_offsets",
+}
+
+// Load parses the DWARF sections of f. Relocations are not applied, so
+// relocatable objects are only supported if their debug sections need
+// none.
+func Load(f *file.File) (*dwarf.Data, error) {
+	if f.Section(".debug_info") == nil {
+		return nil, ErrNoDWARF
+	}
+	get := func(name string) ([]byte, error) {
+		s := f.Section(name)
+		if s == nil || s.Type == elf.SHT_NOBITS {
+			return nil, nil
+		}
+		return SectionData(s)
+	}
+
+	var dat [8][]byte
+	for i, name := range []string{".debug_abbrev", ".debug_aranges", ".debug_frame", ".debug_info",
+		".debug_line", ".debug_pubnames", ".debug_ranges", ".debug_str"} {
+		b, err := get(name)
+		if err != nil {
+			return nil, err
+		}
+		dat[i] = b
+	}
+	d, err := dwarf.New(dat[0], dat[1], dat[2], dat[3], dat[4], dat[5], dat[6], dat[7])
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range dwarf5Sections {
+		b, err := get(name)
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			if err := d.AddSection(name, b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,200 @@
+package debuginfo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// operand kinds of DWARF expression operations
+const (
+	opNone = iota
+	opAddr
+	opU8
+	opS8
+	opU16
+	opS16
+	opU32
+	opS32
+	opU64
+	opS64
+	opULEB
+	opSLEB
+	opULEBSLEB // register or type, then offset
+	opULEBULEB // two unsigned operands
+	opOffset   // section offset
+	opOffsetSLEB
+	opBlock     // ULEB length, then raw bytes
+	opExprBlock // ULEB length, then a nested expression
+	opTypedConst
+	opU8ULEB
+)
+
+type opInfo struct {
+	name    string
+	operand int
+}
+
+var ops = map[byte]opInfo{
+	0x03: {"DW_OP_addr", opAddr},
+	0x06: {"DW_OP_deref", opNone},
+	0x08: {"DW_OP_const1u", opU8},
+	0x09: {"DW_OP_const1s", opS8},
+	0x0a: {"DW_OP_const2u", opU16},
+	0x0b: {"DW_OP_const2s", opS16},
+	0x0c: {"DW_OP_const4u", opU32},
+	0x0d: {"DW_OP_const4s", opS32},
+	0x0e: {"DW_OP_const8u", opU64},
+	0x0f: {"DW_OP_const8s", opS64},
+	0x10: {"DW_OP_constu", opULEB},
+	0x11: {"DW_OP_consts", opSLEB},
+	0x12: {"DW_OP_dup", opNone},
+	0x13: {"DW_OP_drop", opNone},
+	0x14: {"DW_OP_over", opNone},
+	0x15: {"DW_OP_pick", opU8},
+	0x16: {"DW_OP_swap", opNone},
+	0x17: {"DW_OP_rot", opNone},
+	0x18: {"DW_OP_xderef", opNone},
+	0x19: {"DW_OP_abs", opNone},
+	0x1a: {"DW_OP_and", opNone},
+	0x1b: {"DW_OP_div", opNone},
+	0x1c: {"DW_OP_minus", opNone},
+	0x1d: {"DW_OP_mod", opNone},
+	0x1e: {"DW_OP_mul", opNone},
+	0x1f: {"DW_OP_neg", opNone},
+	0x20: {"DW_OP_not", opNone},
+	0x21: {"DW_OP_or", opNone},
+	0x22: {"DW_OP_plus", opNone},
+	0x23: {"DW_OP_plus_uconst", opULEB},
+	0x24: {"DW_OP_shl", opNone},
+	0x25: {"DW_OP_shr", opNone},
+	0x26: {"DW_OP_shra", opNone},
+	0x27: {"DW_OP_xor", opNone},
+	0x28: {"DW_OP_bra", opS16},
+	0x29: {"DW_OP_eq", opNone},
+	0x2a: {"DW_OP_ge", opNone},
+	0x2b: {"DW_OP_gt", opNone},
+	0x2c: {"DW_OP_le", opNone},
+	0x2d: {"DW_OP_lt", opNone},
+	0x2e: {"DW_OP_ne", opNone},
+	0x2f: {"DW_OP_skip", opS16},
+	0x90: {"DW_OP_regx", opULEB},
+	0x91: {"DW_OP_fbreg", opSLEB},
+	0x92: {"DW_OP_bregx", opULEBSLEB},
+	0x93: {"DW_OP_piece", opULEB},
+	0x94: {"DW_OP_deref_size", opU8},
+	0x95: {"DW_OP_xderef_size", opU8},
+	0x96: {"DW_OP_nop", opNone},
+	0x97: {"DW_OP_push_object_address", opNone},
+	0x98: {"DW_OP_call2", opU16},
+	0x99: {"DW_OP_call4", opU32},
+	0x9a: {"DW_OP_call_ref", opOffset},
+	0x9b: {"DW_OP_form_tls_address", opNone},
+	0x9c: {"DW_OP_call_frame_cfa", opNone},
+	0x9d: {"DW_OP_bit_piece", opULEBULEB},
+	0x9e: {"DW_OP_implicit_value", opBlock},
+	0x9f: {"DW_OP_stack_value", opNone},
+	0xa0: {"DW_OP_implicit_pointer", opOffsetSLEB},
+	0xa1: {"DW_OP_addrx", opULEB},
+	0xa2: {"DW_OP_constx", opULEB},
+	0xa3: {"DW_OP_entry_value", opExprBlock},
+	0xa4: {"DW_OP_const_type", opTypedConst},
+	0xa5: {"DW_OP_regval_type", opULEBULEB},
+	0xa6: {"DW_OP_deref_type", opU8ULEB},
+	0xa7: {"DW_OP_xderef_type", opU8ULEB},
+	0xa8: {"DW_OP_convert", opULEB},
+	0xa9: {"DW_OP_reinterpret", opULEB},
+	0xe0: {"DW_OP_GNU_push_tls_address", opNone},
+	0xf0: {"DW_OP_GNU_uninit", opNone},
+	0xf2: {"DW_OP_GNU_implicit_pointer", opOffsetSLEB},
+	0xf3: {"DW_OP_GNU_entry_value", opExprBlock},
+	0xf4: {"DW_OP_GNU_const_type", opTypedConst},
+	0xf5: {"DW_OP_GNU_regval_type", opULEBULEB},
+	0xf6: {"DW_OP_GNU_deref_type", opU8ULEB},
+	0xf7: {"DW_OP_GNU_convert", opULEB},
+	0xf9: {"DW_OP_GNU_reinterpret", opULEB},
+	0xfa: {"DW_OP_GNU_parameter_ref", opU32},
+	0xfb: {"DW_OP_GNU_addr_index", opULEB},
+	0xfc: {"DW_OP_GNU_const_index", opULEB},
+	0xfd: {"DW_OP_GNU_variable_value", opOffset},
+}
+
+func init() {
+	for i := 0; i < 32; i++ {
+		ops[byte(0x30+i)] = opInfo{fmt.Sprintf("DW_OP_lit%d", i), opNone}
+		ops[byte(0x50+i)] = opInfo{fmt.Sprintf("DW_OP_reg%d", i), opNone}
+		ops[byte(0x70+i)] = opInfo{fmt.Sprintf("DW_OP_breg%d", i), opSLEB}
+	}
+}
+
+// FormatExpr renders the DWARF expression b as a semicolon-separated
+// list of operations with their operands.
+func FormatExpr(b []byte, order binary.ByteOrder, addrSize int, dwarf64 bool) string {
+	r := newBuf(b, order)
+	r.addrSize, r.dwarf64 = addrSize, dwarf64
+	return formatExpr(r)
+}
+
+func formatExpr(r *buf) string {
+	var parts []string
+	for r.left() > 0 && r.err == nil {
+		code := r.u8()
+		op, ok := ops[code]
+		if !ok {
+			parts = append(parts, fmt.Sprintf("DW_OP_0x%x", code))
+			continue
+		}
+		var arg string
+		switch op.operand {
+		case opAddr:
+			arg = fmt.Sprintf("0x%x", r.addr())
+		case opU8:
+			arg = fmt.Sprint(r.u8())
+		case opS8:
+			arg = fmt.Sprint(int8(r.u8()))
+		case opU16:
+			arg = fmt.Sprint(r.u16())
+		case opS16:
+			arg = fmt.Sprint(int16(r.u16()))
+		case opU32:
+			arg = fmt.Sprint(r.u32())
+		case opS32:
+			arg = fmt.Sprint(int32(r.u32()))
+		case opU64:
+			arg = fmt.Sprint(r.u64())
+		case opS64:
+			arg = fmt.Sprint(int64(r.u64()))
+		case opULEB:
+			arg = fmt.Sprint(r.uleb())
+		case opSLEB:
+			arg = fmt.Sprint(r.sleb())
+		case opULEBSLEB:
+			arg = fmt.Sprintf("%d %d", r.uleb(), r.sleb())
+		case opULEBULEB:
+			arg = fmt.Sprintf("%d %d", r.uleb(), r.uleb())
+		case opOffset:
+			arg = fmt.Sprintf("<0x%x>", r.offset())
+		case opOffsetSLEB:
+			arg = fmt.Sprintf("<0x%x> %d", r.offset(), r.sleb())
+		case opBlock:
+			arg = fmt.Sprintf("% x", r.bytes(int(r.uleb())))
+		case opExprBlock:
+			sub := r.sub(r.uleb())
+			arg = "(" + formatExpr(sub) + ")"
+		case opTypedConst:
+			typ := r.uleb()
+			arg = fmt.Sprintf("<0x%x> % x", typ, r.bytes(int(r.u8())))
+		case opU8ULEB:
+			arg = fmt.Sprintf("%d <0x%x>", r.u8(), r.uleb())
+		}
+		if arg != "" {
+			parts = append(parts, op.name+": "+arg)
+		} else {
+			parts = append(parts, op.name)
+		}
+	}
+	if r.err != nil {
+		parts = append(parts, "<truncated>")
+	}
+	return strings.Join(parts, "; ")
+}
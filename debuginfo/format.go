@@ -0,0 +1,146 @@
+package debuginfo
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"strconv"
+)
+
+// Bases holds the table offsets that indexed attribute values of a
+// unit are relative to, taken from its unit DIE.
+type Bases struct {
+	StrOffsets uint64
+	Addr       uint64
+	RngLists   uint64
+	LocLists   uint64
+}
+
+// attrGNUAddrBase is the pre-DWARF 5 split DWARF address table base.
+const attrGNUAddrBase dwarf.Attr = 0x2133
+
+// UnitBases returns the bases declared by the unit DIE cu.
+func UnitBases(cu *DIE) Bases {
+	var b Bases
+	get := func(a dwarf.Attr) uint64 {
+		v, _ := cu.Val(a).(uint64)
+		return v
+	}
+	b.StrOffsets = get(dwarf.AttrStrOffsetsBase)
+	b.Addr = get(dwarf.AttrAddrBase)
+	if b.Addr == 0 {
+		b.Addr = get(attrGNUAddrBase)
+	}
+	b.RngLists = get(dwarf.AttrRnglistsBase)
+	b.LocLists = get(dwarf.AttrLoclistsBase)
+	return b
+}
+
+// exprAttrs are the attributes whose blocks hold DWARF expressions.
+var exprAttrs = map[dwarf.Attr]bool{
+	dwarf.AttrLocation:      true,
+	dwarf.AttrFrameBase:     true,
+	dwarf.AttrDataMemberLoc: true,
+	dwarf.AttrUseLocation:   true,
+	dwarf.AttrVtableElemLoc: true,
+	dwarf.AttrStringLength:  true,
+	dwarf.AttrReturnAddr:    true,
+	dwarf.AttrStaticLink:    true,
+	dwarf.AttrSegment:       true,
+	dwarf.AttrLowerBound:    true,
+	dwarf.AttrUpperBound:    true,
+	dwarf.AttrCount:         true,
+	dwarf.AttrByteSize:      true,
+	dwarf.AttrBitSize:       true,
+	dwarf.AttrDataLocation:  true,
+	dwarf.AttrAllocated:     true,
+	dwarf.AttrAssociated:    true,
+	0x7e:                    true, // DW_AT_call_value
+	0x7f:                    true, // DW_AT_call_origin
+	0x80:                    true, // DW_AT_call_parameter
+	0x81:                    true, // DW_AT_call_pc
+	0x83:                    true, // DW_AT_call_target
+	0x84:                    true, // DW_AT_call_target_clobbered
+	0x85:                    true, // DW_AT_call_data_location
+	0x86:                    true, // DW_AT_call_data_value
+	0x2111:                  true, // DW_AT_GNU_call_site_value
+	0x2113:                  true, // DW_AT_GNU_call_site_target
+	0x2114:                  true, // DW_AT_GNU_call_site_target_clobbered
+}
+
+// FormatValue renders the value of f, an attribute of a DIE in unit u,
+// resolving strings, indexed addresses and list offsets and decoding
+// expressions and enumerated constants.
+func (s *Sections) FormatValue(u *Unit, b Bases, f Field) string {
+	switch v := f.Val.(type) {
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case string:
+		return strconv.Quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		if f.Form == formExprloc || exprAttrs[f.Attr] && f.Form != formData16 {
+			return "(" + FormatExpr(v, s.Order, u.AddrSize, u.Dwarf64) + ")"
+		}
+		return fmt.Sprintf("%d byte block: % x", len(v), v)
+	case uint64:
+		switch f.Form {
+		case formAddr:
+			return fmt.Sprintf("0x%x", v)
+		case formAddrx, formAddrx1, formAddrx2, formAddrx3, formAddrx4, formGNUAddrIndex:
+			if a, ok := s.AddrIndex(u, b.Addr, v); ok {
+				return fmt.Sprintf("0x%x (index %d)", a, v)
+			}
+			return fmt.Sprintf("<bad address index %d>", v)
+		case formStrp, formGNUStrpAlt:
+			if str, ok := s.Str(v); ok {
+				return fmt.Sprintf("%q (.debug_str+0x%x)", str, v)
+			}
+			return fmt.Sprintf("<bad .debug_str offset 0x%x>", v)
+		case formLineStrp:
+			if str, ok := s.LineStr(v); ok {
+				return fmt.Sprintf("%q (.debug_line_str+0x%x)", str, v)
+			}
+			return fmt.Sprintf("<bad .debug_line_str offset 0x%x>", v)
+		case formStrx, formStrx1, formStrx2, formStrx3, formStrx4, formGNUStrIndex:
+			if str, ok := s.StrIndex(u, b.StrOffsets, v); ok {
+				return fmt.Sprintf("%q (index %d)", str, v)
+			}
+			return fmt.Sprintf("<bad string index %d>", v)
+		case formRef1, formRef2, formRef4, formRef8, formRefUdata, formRefAddr:
+			return fmt.Sprintf("<0x%x>", v)
+		case formGNURefAlt, formRefSup4, formRefSup8:
+			return fmt.Sprintf("<alt 0x%x>", v)
+		case formRefSig8:
+			return fmt.Sprintf("signature 0x%016x", v)
+		case formSecOffset:
+			return fmt.Sprintf("0x%x", v)
+		case formLoclistx, formRnglistx:
+			base, name := b.RngLists, ".debug_rnglists"
+			if f.Form == formLoclistx {
+				base, name = b.LocLists, ".debug_loclists"
+			}
+			if off, ok := s.listOffset(u, name, base, v); ok {
+				return fmt.Sprintf("index %d (0x%x)", v, off)
+			}
+			return fmt.Sprintf("<bad list index %d>", v)
+		}
+		switch f.Attr {
+		case dwarf.AttrLanguage:
+			return fmt.Sprintf("%d (%s)", v, LangName(v))
+		case dwarf.AttrEncoding:
+			return fmt.Sprintf("%d (%s)", v, EncodingName(v))
+		case dwarf.AttrHighpc:
+			return fmt.Sprintf("0x%x (length)", v)
+		case dwarf.AttrStmtList, dwarf.AttrRanges, dwarf.AttrLocation, dwarf.AttrMacros, dwarf.AttrMacroInfo:
+			// section offsets in DWARF 2 and 3, where data4 and data8
+			// served as offsets
+			return fmt.Sprintf("0x%x", v)
+		}
+		return strconv.FormatUint(v, 10)
+	}
+	return fmt.Sprint(f.Val)
+}
@@ -0,0 +1,330 @@
+package debuginfo
+
+import (
+	"debug/dwarf"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Member is a data member or base class of a struct, class or union.
+type Member struct {
+	Name string
+	// Type is the C-like spelling of the member type
+	Type string
+	// Base tells whether the member is a base class subobject
+	Base bool
+	// Offset and Size are in bytes; for bitfields they describe the
+	// storage unit containing the field
+	Offset int64
+	Size   int64
+	// BitOffset and BitSize are set for bitfields; BitOffset counts
+	// from the least significant bit of the storage unit on little
+	// endian targets and from the most significant one on big endian
+	// targets, as the compiler lays them out
+	BitOffset int64
+	BitSize   int64
+}
+
+// A Hole is unused space before a member, or at the end of the type
+// when Member is -1.
+type Hole struct {
+	// Member is the index of the member following the hole
+	Member int
+	// Bits is the size of the hole in bits
+	Bits int64
+}
+
+// A Layout is the memory layout of a struct, class or union type.
+type Layout struct {
+	// Kind is "struct", "class" or "union"
+	Kind string
+	// Name is qualified with its enclosing namespaces and classes
+	Name    string
+	Size    int64
+	Offset  dwarf.Offset
+	Members []Member
+	Holes   []Hole
+}
+
+// HoleBytes returns the number of bytes lost to holes between members,
+// rounded down.
+func (l *Layout) HoleBytes() int64 {
+	var bits int64
+	for _, h := range l.Holes {
+		if h.Member >= 0 {
+			bits += h.Bits
+		}
+	}
+	return bits / 8
+}
+
+// Padding returns the number of bytes of trailing padding.
+func (l *Layout) Padding() int64 {
+	for _, h := range l.Holes {
+		if h.Member < 0 {
+			return h.Bits / 8
+		}
+	}
+	return 0
+}
+
+// Layouts returns the layouts of all named struct, class and union
+// definitions in d. Types defined identically in several compile
+// units are returned once.
+func Layouts(d *dwarf.Data, order binary.ByteOrder) ([]*Layout, error) {
+	var layouts []*Layout
+	seen := make(map[string]bool)
+	r := d.Reader()
+	// scope holds the names of the enclosing namespaces and types,
+	// with "" for other entries that have children
+	var scope []string
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == 0 {
+			if len(scope) > 0 {
+				scope = scope[:len(scope)-1]
+			}
+			continue
+		}
+		name, _ := e.Val(dwarf.AttrName).(string)
+		switch e.Tag {
+		case dwarf.TagCompileUnit, dwarf.TagPartialUnit:
+			scope = scope[:0]
+		case dwarf.TagStructType, dwarf.TagClassType, dwarf.TagUnionType:
+			decl, _ := e.Val(dwarf.AttrDeclaration).(bool)
+			if name != "" && !decl {
+				l, err := layout(d, order, e, qualify(scope, name))
+				if err != nil {
+					return nil, err
+				}
+				key := l.Name + "\x00" + strings.Join(memberKey(l), "\x00")
+				if !seen[key] {
+					seen[key] = true
+					layouts = append(layouts, l)
+				}
+			}
+		}
+		if e.Children {
+			switch e.Tag {
+			case dwarf.TagNamespace, dwarf.TagStructType, dwarf.TagClassType, dwarf.TagUnionType:
+				if name == "" {
+					name = "(anonymous namespace)"
+				}
+				scope = append(scope, name)
+			case dwarf.TagSubprogram, dwarf.TagLexDwarfBlock, dwarf.TagInlinedSubroutine:
+				// types local to functions are not looked up by name
+				r.SkipChildren()
+			default:
+				scope = append(scope, "")
+			}
+		}
+	}
+	return layouts, nil
+}
+
+func qualify(scope []string, name string) string {
+	var parts []string
+	for _, s := range scope {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(append(parts, name), "::")
+}
+
+func memberKey(l *Layout) []string {
+	key := []string{l.Kind, strconv.FormatInt(l.Size, 10)}
+	for _, m := range l.Members {
+		key = append(key, m.Name, m.Type)
+	}
+	return key
+}
+
+var layoutKinds = map[dwarf.Tag]string{
+	dwarf.TagStructType: "struct",
+	dwarf.TagClassType:  "class",
+	dwarf.TagUnionType:  "union",
+}
+
+// layout reads the members of the type entry e.
+func layout(d *dwarf.Data, order binary.ByteOrder, e *dwarf.Entry, name string) (*Layout, error) {
+	l := &Layout{Kind: layoutKinds[e.Tag], Name: name, Offset: e.Offset}
+	l.Size, _ = e.Val(dwarf.AttrByteSize).(int64)
+	if !e.Children {
+		l.computeHoles()
+		return l, nil
+	}
+	// read the children with a reader of their own, so the caller's
+	// walk descends into nested types too
+	r := d.Reader()
+	r.Seek(e.Offset)
+	if _, err := r.Next(); err != nil {
+		return nil, err
+	}
+	for {
+		c, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if c == nil || c.Tag == 0 {
+			break
+		}
+		if c.Children {
+			r.SkipChildren()
+		}
+		if c.Tag != dwarf.TagMember && c.Tag != dwarf.TagInheritance {
+			continue
+		}
+		// static members are declarations without a location
+		if decl, _ := c.Val(dwarf.AttrDeclaration).(bool); decl {
+			continue
+		}
+		if ext, _ := c.Val(dwarf.AttrExternal).(bool); ext {
+			continue
+		}
+		m := Member{Base: c.Tag == dwarf.TagInheritance}
+		m.Name, _ = c.Val(dwarf.AttrName).(string)
+		if toff, ok := c.Val(dwarf.AttrType).(dwarf.Offset); ok {
+			if t, err := d.Type(toff); err == nil {
+				m.Type = typeName(t)
+				m.Size = t.Size()
+				if m.Size < 0 {
+					// flexible array members take no space
+					m.Size = 0
+				}
+			}
+		}
+		if m.Base && m.Name == "" {
+			m.Name = "<base>"
+		}
+		m.Offset = memberOffset(c.Val(dwarf.AttrDataMemberLoc))
+		m.BitSize, _ = c.Val(dwarf.AttrBitSize).(int64)
+		if m.BitSize > 0 {
+			if bs, ok := c.Val(dwarf.AttrByteSize).(int64); ok {
+				m.Size = bs
+			}
+			if dbo, ok := c.Val(dwarf.AttrDataBitOffset).(int64); ok {
+				// DWARF 4 bit offsets count from the start of the
+				// structure in allocation order; place the field in a
+				// storage unit of its type's size
+				unit := m.Size * 8
+				if unit == 0 {
+					unit = 8
+				}
+				m.Offset = dbo / unit * m.Size
+				m.BitOffset = dbo - m.Offset*8
+			} else if bo, ok := c.Val(dwarf.AttrBitOffset).(int64); ok {
+				// DWARF 2 and 3 bit offsets count from the most
+				// significant bit of the storage unit
+				m.BitOffset = bo
+				if order != binary.BigEndian {
+					m.BitOffset = m.Size*8 - bo - m.BitSize
+				}
+			}
+		}
+		l.Members = append(l.Members, m)
+	}
+	if l.Kind != "union" {
+		sort.SliceStable(l.Members, func(i, j int) bool {
+			return l.Members[i].bitStart() < l.Members[j].bitStart()
+		})
+	}
+	l.computeHoles()
+	return l, nil
+}
+
+// typeName spells t like debug/dwarf does, but with array dimensions
+// after the element type as in C.
+func typeName(t dwarf.Type) string {
+	var dims string
+	for {
+		a, ok := t.(*dwarf.ArrayType)
+		if !ok {
+			break
+		}
+		if a.Count < 0 {
+			dims += "[]"
+		} else {
+			dims += "[" + strconv.FormatInt(a.Count, 10) + "]"
+		}
+		t = a.Type
+	}
+	return t.String() + dims
+}
+
+// memberOffset decodes DW_AT_data_member_location, a constant or, in
+// DWARF 2, a DW_OP_plus_uconst expression.
+func memberOffset(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case []byte:
+		if len(v) > 1 && v[0] == 0x23 {
+			b := newBuf(v[1:], binary.LittleEndian)
+			return int64(b.uleb())
+		}
+	}
+	return 0
+}
+
+// bitStart returns the offset of the first bit of m from the start of
+// the type, numbering bits in the order the target allocates them.
+func (m Member) bitStart() int64 {
+	return m.Offset*8 + m.BitOffset
+}
+
+func (m Member) bitLen() int64 {
+	if m.BitSize > 0 {
+		return m.BitSize
+	}
+	return m.Size * 8
+}
+
+func (l *Layout) computeHoles() {
+	l.Holes = nil
+	if l.Kind == "union" {
+		var max int64
+		for _, m := range l.Members {
+			if m.Size > max {
+				max = m.Size
+			}
+		}
+		if l.Size > max {
+			l.Holes = append(l.Holes, Hole{-1, (l.Size - max) * 8})
+		}
+		return
+	}
+	var end int64
+	for i, m := range l.Members {
+		start := m.bitStart()
+		if start > end {
+			l.Holes = append(l.Holes, Hole{i, start - end})
+		}
+		if e := start + m.bitLen(); e > end {
+			end = e
+		}
+	}
+	if l.Size*8 > end {
+		l.Holes = append(l.Holes, Hole{-1, l.Size*8 - end})
+	}
+}
+
+// FindLayouts returns the layouts named name, matching either the
+// qualified name or the unqualified one.
+func FindLayouts(layouts []*Layout, name string) []*Layout {
+	var found []*Layout
+	for _, l := range layouts {
+		if l.Name == name || strings.HasSuffix(l.Name, "::"+name) {
+			found = append(found, l)
+		}
+	}
+	return found
+}
@@ -0,0 +1,334 @@
+package debuginfo
+
+import (
+	"fmt"
+)
+
+// DW_LNCT content types of DWARF 5 directory and file entries
+const (
+	lnctPath           = 0x1
+	lnctDirectoryIndex = 0x2
+	lnctTimestamp      = 0x3
+	lnctSize           = 0x4
+	lnctMD5            = 0x5
+)
+
+// A LineFile is a directory or file entry of a line program header.
+type LineFile struct {
+	Name  string
+	Dir   uint64
+	MTime uint64
+	Size  uint64
+	MD5   []byte
+}
+
+// A LineProgram is a line number program of .debug_line.
+type LineProgram struct {
+	Offset        uint64
+	Length        uint64
+	Dwarf64       bool
+	Version       int
+	AddrSize      int
+	SegSelSize    int
+	HeaderLength  uint64
+	MinInstLength int
+	MaxOpsPerInst int
+	DefaultIsStmt bool
+	LineBase      int8
+	LineRange     uint8
+	OpcodeBase    uint8
+	// OpcodeLengths holds the operand counts of the standard opcodes
+	OpcodeLengths []uint8
+	Dirs          []LineFile
+	Files         []LineFile
+
+	s       *Sections
+	program []byte
+	// progOff is the section offset of program
+	progOff uint64
+}
+
+// LinePrograms decodes the headers of all line programs in .debug_line.
+// addrSize is used by versions before 5, which do not record it.
+func (s *Sections) LinePrograms(addrSize int) ([]*LineProgram, error) {
+	b := s.buf(".debug_line")
+	var progs []*LineProgram
+	for b.left() > 0 {
+		p := &LineProgram{s: s, Offset: uint64(b.off), AddrSize: addrSize}
+		p.Length = b.unitLength()
+		p.Dwarf64 = b.dwarf64
+		end := uint64(b.off) + p.Length
+		if b.err != nil || end > uint64(len(b.data)) {
+			return progs, fmt.Errorf("line program at 0x%x: bad length", p.Offset)
+		}
+		r := b.sub(p.Length)
+		if err := p.readHeader(r); err != nil {
+			return progs, fmt.Errorf("line program at 0x%x: %v", p.Offset, err)
+		}
+		progs = append(progs, p)
+	}
+	return progs, nil
+}
+
+func (p *LineProgram) readHeader(r *buf) error {
+	p.Version = int(r.u16())
+	if p.Version < 2 || p.Version > 5 {
+		return fmt.Errorf("unsupported version %d", p.Version)
+	}
+	if p.Version >= 5 {
+		p.AddrSize = int(r.u8())
+		p.SegSelSize = int(r.u8())
+	}
+	r.addrSize = p.AddrSize
+	p.HeaderLength = r.offset()
+	hdrStart := r.off
+	p.MinInstLength = int(r.u8())
+	p.MaxOpsPerInst = 1
+	if p.Version >= 4 {
+		p.MaxOpsPerInst = int(r.u8())
+	}
+	p.DefaultIsStmt = r.u8() != 0
+	p.LineBase = int8(r.u8())
+	p.LineRange = r.u8()
+	p.OpcodeBase = r.u8()
+	if p.OpcodeBase > 0 {
+		p.OpcodeLengths = r.bytes(int(p.OpcodeBase) - 1)
+	}
+	if p.Version >= 5 {
+		var err error
+		if p.Dirs, err = p.readEntries(r); err != nil {
+			return err
+		}
+		if p.Files, err = p.readEntries(r); err != nil {
+			return err
+		}
+	} else {
+		// the compilation directory is the implicit entry 0
+		p.Dirs = append(p.Dirs, LineFile{})
+		for {
+			name := r.cstring()
+			if name == "" || r.err != nil {
+				break
+			}
+			p.Dirs = append(p.Dirs, LineFile{Name: name})
+		}
+		p.Files = append(p.Files, LineFile{})
+		for {
+			name := r.cstring()
+			if name == "" || r.err != nil {
+				break
+			}
+			p.Files = append(p.Files, LineFile{Name: name, Dir: r.uleb(), MTime: r.uleb(), Size: r.uleb()})
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	progStart := hdrStart + int(p.HeaderLength)
+	if progStart > len(r.data) {
+		return fmt.Errorf("header length 0x%x past end of unit", p.HeaderLength)
+	}
+	p.program = r.data[progStart:]
+	p.progOff = p.Offset + 4 + uint64(progStart)
+	if p.Dwarf64 {
+		p.progOff += 8
+	}
+	return nil
+}
+
+// readEntries reads a DWARF 5 entry format description and the
+// directory or file entries following it.
+func (p *LineProgram) readEntries(r *buf) ([]LineFile, error) {
+	type format struct {
+		content uint64
+		form    Form
+	}
+	formats := make([]format, r.u8())
+	for i := range formats {
+		formats[i] = format{r.uleb(), Form(r.uleb())}
+	}
+	n := r.uleb()
+	if r.err != nil {
+		return nil, r.err
+	}
+	u := &Unit{Version: p.Version, Dwarf64: p.Dwarf64, AddrSize: p.AddrSize}
+	var entries []LineFile
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		var e LineFile
+		for _, ft := range formats {
+			v, err := readForm(r, u, ft.form, 0)
+			if err != nil {
+				return nil, err
+			}
+			switch ft.content {
+			case lnctPath:
+				switch x := v.(type) {
+				case string:
+					e.Name = x
+				case uint64:
+					if ft.form == formLineStrp {
+						e.Name, _ = p.s.LineStr(x)
+					} else {
+						e.Name, _ = p.s.Str(x)
+					}
+				}
+			case lnctDirectoryIndex:
+				e.Dir, _ = v.(uint64)
+			case lnctTimestamp:
+				e.MTime, _ = v.(uint64)
+			case lnctSize:
+				e.Size, _ = v.(uint64)
+			case lnctMD5:
+				e.MD5, _ = v.([]byte)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, r.err
+}
+
+// A LineOp is one decoded opcode of a line program, described with the
+// state machine registers it changes.
+type LineOp struct {
+	Offset uint64
+	Text   string
+}
+
+// standard opcodes
+const (
+	lnsCopy             = 1
+	lnsAdvancePC        = 2
+	lnsAdvanceLine      = 3
+	lnsSetFile          = 4
+	lnsSetColumn        = 5
+	lnsNegateStmt       = 6
+	lnsSetBasicBlock    = 7
+	lnsConstAddPC       = 8
+	lnsFixedAdvancePC   = 9
+	lnsSetPrologueEnd   = 10
+	lnsSetEpilogueBegin = 11
+	lnsSetISA           = 12
+)
+
+// extended opcodes
+const (
+	lneEndSequence      = 1
+	lneSetAddress       = 2
+	lneDefineFile       = 3
+	lneSetDiscriminator = 4
+)
+
+// Ops decodes the opcodes of the program, running the state machine
+// to report the address and line each opcode moves to. Decoding stops
+// at the first malformed opcode.
+func (p *LineProgram) Ops() []LineOp {
+	r := newBuf(p.program, p.s.Order)
+	r.addrSize, r.dwarf64 = p.AddrSize, p.Dwarf64
+	var ops []LineOp
+	var addr uint64
+	line := 1
+	isStmt := p.DefaultIsStmt
+	reset := func() { addr, line, isStmt = 0, 1, p.DefaultIsStmt }
+	advance := func(n uint64) uint64 {
+		addr += n * uint64(p.MinInstLength)
+		return n * uint64(p.MinInstLength)
+	}
+	for r.left() > 0 && r.err == nil {
+		off := p.progOff + uint64(r.off)
+		op := r.u8()
+		var text string
+		switch {
+		case op >= p.OpcodeBase:
+			adj := int(op - p.OpcodeBase)
+			if p.LineRange == 0 {
+				text = fmt.Sprintf("Special opcode %d: line range is zero", adj)
+				break
+			}
+			da := advance(uint64(adj / int(p.LineRange)))
+			dl := int(p.LineBase) + adj%int(p.LineRange)
+			line += dl
+			text = fmt.Sprintf("Special opcode %d: advance Address by %d to 0x%x and Line by %d to %d", adj, da, addr, dl, line)
+		case op == 0:
+			n := r.uleb()
+			ext := r.sub(n)
+			if n == 0 {
+				text = "Badly formed extended line op"
+				break
+			}
+			switch sub := ext.u8(); sub {
+			case lneEndSequence:
+				text = "Extended opcode 1: End of Sequence"
+				reset()
+			case lneSetAddress:
+				ext.addrSize = int(n - 1)
+				addr = ext.addr()
+				text = fmt.Sprintf("Extended opcode 2: set Address to 0x%x", addr)
+			case lneDefineFile:
+				name := ext.cstring()
+				dir, mtime, size := ext.uleb(), ext.uleb(), ext.uleb()
+				text = fmt.Sprintf("Extended opcode 3: define new File Table entry %s (dir %d, time %d, size %d)", name, dir, mtime, size)
+			case lneSetDiscriminator:
+				text = fmt.Sprintf("Extended opcode 4: set Discriminator to %d", ext.uleb())
+			default:
+				text = fmt.Sprintf("Extended opcode %d: % x", sub, ext.data[ext.off:])
+			}
+		case op == lnsCopy:
+			text = "Copy"
+		case op == lnsAdvancePC:
+			da := advance(r.uleb())
+			text = fmt.Sprintf("Advance PC by %d to 0x%x", da, addr)
+		case op == lnsAdvanceLine:
+			dl := r.sleb()
+			line += int(dl)
+			text = fmt.Sprintf("Advance Line by %d to %d", dl, line)
+		case op == lnsSetFile:
+			text = fmt.Sprintf("Set File Name to entry %d in the File Name Table", r.uleb())
+		case op == lnsSetColumn:
+			text = fmt.Sprintf("Set column to %d", r.uleb())
+		case op == lnsNegateStmt:
+			isStmt = !isStmt
+			text = fmt.Sprintf("Set is_stmt to %d", b2i(isStmt))
+		case op == lnsSetBasicBlock:
+			text = "Set basic block"
+		case op == lnsConstAddPC:
+			if p.LineRange == 0 {
+				text = "Advance PC by constant: line range is zero"
+				break
+			}
+			da := advance(uint64((255 - int(p.OpcodeBase)) / int(p.LineRange)))
+			text = fmt.Sprintf("Advance PC by constant %d to 0x%x", da, addr)
+		case op == lnsFixedAdvancePC:
+			da := uint64(r.u16())
+			addr += da
+			text = fmt.Sprintf("Advance PC by fixed size amount %d to 0x%x", da, addr)
+		case op == lnsSetPrologueEnd:
+			text = "Set prologue_end to true"
+		case op == lnsSetEpilogueBegin:
+			text = "Set epilogue_begin to true"
+		case op == lnsSetISA:
+			text = fmt.Sprintf("Set ISA to %d", r.uleb())
+		default:
+			// an unknown standard opcode; its operand count is in the
+			// header
+			var args []uint64
+			if int(op) <= len(p.OpcodeLengths) {
+				for i := 0; i < int(p.OpcodeLengths[op-1]); i++ {
+					args = append(args, r.uleb())
+				}
+			}
+			text = fmt.Sprintf("Unknown opcode %d with operands %v", op, args)
+		}
+		if r.err != nil {
+			text = "<truncated opcode>"
+		}
+		ops = append(ops, LineOp{off, text})
+	}
+	return ops
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,319 @@
+package debuginfo
+
+import (
+	"debug/dwarf"
+	"fmt"
+)
+
+// An ARangeSet is a set of address ranges of .debug_aranges belonging
+// to one compile unit.
+type ARangeSet struct {
+	Offset     uint64
+	Length     uint64
+	Dwarf64    bool
+	Version    int
+	InfoOffset uint64
+	AddrSize   int
+	SegSize    int
+	// Ranges holds address and length pairs
+	Ranges [][2]uint64
+}
+
+// ARanges decodes .debug_aranges.
+func (s *Sections) ARanges() ([]ARangeSet, error) {
+	b := s.buf(".debug_aranges")
+	var sets []ARangeSet
+	for b.left() > 0 {
+		set := ARangeSet{Offset: uint64(b.off)}
+		set.Length = b.unitLength()
+		set.Dwarf64 = b.dwarf64
+		r := b.sub(set.Length)
+		if b.err != nil {
+			return sets, fmt.Errorf("address ranges at 0x%x: %v", set.Offset, b.err)
+		}
+		set.Version = int(r.u16())
+		set.InfoOffset = r.offset()
+		set.AddrSize = int(r.u8())
+		set.SegSize = int(r.u8())
+		r.addrSize = set.AddrSize
+		// the tuples are aligned to twice the address size from the
+		// start of the set
+		hdr := uint64(r.off) + 4
+		if set.Dwarf64 {
+			hdr += 8
+		}
+		if tuple := uint64(2 * set.AddrSize); tuple > 0 && hdr%tuple != 0 {
+			r.bytes(int(tuple - hdr%tuple))
+		}
+		for r.left() > 0 && r.err == nil {
+			if set.SegSize > 0 {
+				r.sized(set.SegSize)
+			}
+			addr, n := r.addr(), r.addr()
+			if addr == 0 && n == 0 {
+				break
+			}
+			set.Ranges = append(set.Ranges, [2]uint64{addr, n})
+		}
+		if r.err != nil {
+			return sets, fmt.Errorf("address ranges at 0x%x: %v", set.Offset, r.err)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// A RangeEntry is an entry of a DWARF 4 .debug_ranges list, or the
+// end of one when both addresses are zero. A Begin of all ones marks
+// a base address selection with the base in End.
+type RangeEntry struct {
+	Offset     uint64
+	Begin, End uint64
+}
+
+// Ranges decodes .debug_ranges as a sequence of entries.
+func (s *Sections) Ranges(addrSize int) ([]RangeEntry, error) {
+	b := s.buf(".debug_ranges")
+	b.addrSize = addrSize
+	var entries []RangeEntry
+	for b.left() > 0 {
+		e := RangeEntry{Offset: uint64(b.off)}
+		e.Begin, e.End = b.addr(), b.addr()
+		if b.err != nil {
+			return entries, b.err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// A LocEntry is an entry of a DWARF 4 .debug_loc list. Begin and End
+// are both zero at the end of a list; a Begin of all ones marks a
+// base address selection. For GCC location view pairs View is set and
+// Begin and End are the view numbers.
+type LocEntry struct {
+	Offset     uint64
+	Begin, End uint64
+	Expr       []byte
+	View       bool
+}
+
+// Locs decodes .debug_loc as a sequence of entries. views is as for
+// LocLists.
+func (s *Sections) Locs(addrSize int, views map[uint64]uint64) ([]LocEntry, error) {
+	b := s.buf(".debug_loc")
+	b.addrSize = addrSize
+	max := ^uint64(0) >> (64 - 8*uint(addrSize))
+	var entries []LocEntry
+	for b.left() > 0 {
+		e := LocEntry{Offset: uint64(b.off)}
+		if end, ok := views[e.Offset]; ok && end > e.Offset {
+			for uint64(b.off) < end && b.err == nil {
+				e := LocEntry{Offset: uint64(b.off), View: true}
+				e.Begin, e.End = b.uleb(), b.uleb()
+				entries = append(entries, e)
+			}
+			continue
+		}
+		e.Begin, e.End = b.addr(), b.addr()
+		if !(e.Begin == 0 && e.End == 0) && e.Begin != max {
+			e.Expr = b.bytes(int(b.u16()))
+		}
+		if b.err != nil {
+			return entries, b.err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// A ListTable is a DWARF 5 range or location list table of
+// .debug_rnglists or .debug_loclists.
+type ListTable struct {
+	Offset   uint64
+	Length   uint64
+	Dwarf64  bool
+	Version  int
+	AddrSize int
+	SegSize  int
+	// Offsets is the offset array, relative to the end of the header
+	Offsets []uint64
+	// Base is the section offset of the end of the header, that the
+	// DW_AT_rnglists_base and DW_AT_loclists_base attributes point to
+	Base    uint64
+	Entries []ListEntry
+}
+
+// A ListEntry is a range or location list entry.
+type ListEntry struct {
+	Offset uint64
+	Kind   uint8
+	// Name is the DW_RLE_* or DW_LLE_* name of Kind
+	Name string
+	Args []uint64
+	// Expr is the location description of location list entries
+	Expr []byte
+}
+
+// DW_RLE_* and DW_LLE_* operands
+const (
+	argULEB = iota
+	argAddr
+)
+
+type listKind struct {
+	name string
+	args []int
+	// expr tells whether a location description follows
+	expr bool
+}
+
+var rleKinds = map[uint8]listKind{
+	0: {"DW_RLE_end_of_list", nil, false},
+	1: {"DW_RLE_base_addressx", []int{argULEB}, false},
+	2: {"DW_RLE_startx_endx", []int{argULEB, argULEB}, false},
+	3: {"DW_RLE_startx_length", []int{argULEB, argULEB}, false},
+	4: {"DW_RLE_offset_pair", []int{argULEB, argULEB}, false},
+	5: {"DW_RLE_base_address", []int{argAddr}, false},
+	6: {"DW_RLE_start_end", []int{argAddr, argAddr}, false},
+	7: {"DW_RLE_start_length", []int{argAddr, argULEB}, false},
+}
+
+var lleKinds = map[uint8]listKind{
+	0: {"DW_LLE_end_of_list", nil, false},
+	1: {"DW_LLE_base_addressx", []int{argULEB}, false},
+	2: {"DW_LLE_startx_endx", []int{argULEB, argULEB}, true},
+	3: {"DW_LLE_startx_length", []int{argULEB, argULEB}, true},
+	4: {"DW_LLE_offset_pair", []int{argULEB, argULEB}, true},
+	5: {"DW_LLE_default_location", nil, true},
+	6: {"DW_LLE_base_address", []int{argAddr}, false},
+	7: {"DW_LLE_start_end", []int{argAddr, argAddr}, true},
+	8: {"DW_LLE_start_length", []int{argAddr, argULEB}, true},
+}
+
+// RngLists decodes the tables of .debug_rnglists.
+func (s *Sections) RngLists() ([]ListTable, error) {
+	return s.listTables(".debug_rnglists", rleKinds, nil)
+}
+
+// LocLists decodes the tables of .debug_loclists. views maps the
+// offsets of GCC location view lists to the offsets of the location
+// lists they belong to, as returned by LocViews.
+func (s *Sections) LocLists(views map[uint64]uint64) ([]ListTable, error) {
+	return s.listTables(".debug_loclists", lleKinds, views)
+}
+
+func (s *Sections) listTables(name string, kinds map[uint8]listKind, views map[uint64]uint64) ([]ListTable, error) {
+	b := s.buf(name)
+	var tables []ListTable
+	for b.left() > 0 {
+		t := ListTable{Offset: uint64(b.off)}
+		t.Length = b.unitLength()
+		t.Dwarf64 = b.dwarf64
+		start := uint64(b.off)
+		r := b.sub(t.Length)
+		if b.err != nil {
+			return tables, fmt.Errorf("%s table at 0x%x: %v", name, t.Offset, b.err)
+		}
+		t.Version = int(r.u16())
+		t.AddrSize = int(r.u8())
+		t.SegSize = int(r.u8())
+		r.addrSize = t.AddrSize
+		count := r.u32()
+		t.Base = start + uint64(r.off)
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			t.Offsets = append(t.Offsets, r.offset())
+		}
+		for r.left() > 0 && r.err == nil {
+			off := start + uint64(r.off)
+			if end, ok := views[off]; ok && end > off {
+				// GCC location views: pairs of view numbers for the
+				// entries of the list that follows them
+				for start+uint64(r.off) < end && r.err == nil {
+					e := ListEntry{Offset: start + uint64(r.off), Name: "view pair"}
+					e.Args = []uint64{r.uleb(), r.uleb()}
+					t.Entries = append(t.Entries, e)
+				}
+				continue
+			}
+			e := ListEntry{Offset: off, Kind: r.u8()}
+			k, ok := kinds[e.Kind]
+			if !ok {
+				return tables, fmt.Errorf("%s entry at 0x%x: unknown kind 0x%x", name, e.Offset, e.Kind)
+			}
+			e.Name = k.name
+			for _, a := range k.args {
+				if a == argAddr {
+					e.Args = append(e.Args, r.addr())
+				} else {
+					e.Args = append(e.Args, r.uleb())
+				}
+			}
+			if k.expr {
+				e.Expr = r.bytes(int(r.uleb()))
+			}
+			t.Entries = append(t.Entries, e)
+		}
+		if r.err != nil {
+			return tables, fmt.Errorf("%s table at 0x%x: %v", name, t.Offset, r.err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// attrGNULocviews points to the GCC location view list of a variable.
+const attrGNULocviews dwarf.Attr = 0x2137
+
+// LocViews maps the offsets of the GCC location view lists referred to
+// by DIEs to the offsets of the location lists of the same DIEs.
+func (s *Sections) LocViews() map[uint64]uint64 {
+	views := make(map[uint64]uint64)
+	units, _ := s.Units()
+	for _, u := range units {
+		dies, _ := s.DIEs(u)
+		var bases Bases
+		if len(dies) > 0 {
+			bases = UnitBases(&dies[0])
+		}
+		for i := range dies {
+			d := &dies[i]
+			view, ok := d.Val(attrGNULocviews).(uint64)
+			if !ok {
+				continue
+			}
+			for _, f := range d.Fields {
+				if f.Attr != dwarf.AttrLocation {
+					continue
+				}
+				switch v := f.Val.(type) {
+				case uint64:
+					if f.Form == formLoclistx {
+						if off, ok := s.listOffset(u, ".debug_loclists", bases.LocLists, v); ok {
+							views[view] = off
+						}
+					} else {
+						views[view] = v
+					}
+				}
+			}
+		}
+	}
+	return views
+}
+
+// listOffset resolves index idx of the offset array of the list table
+// whose header ends at base.
+func (s *Sections) listOffset(u *Unit, name string, base, idx uint64) (uint64, bool) {
+	size := uint64(4)
+	if u.Dwarf64 {
+		size = 8
+	}
+	r := s.buf(name)
+	r.dwarf64 = u.Dwarf64
+	if base+(idx+1)*size > uint64(len(r.data)) {
+		return 0, false
+	}
+	r.off = int(base + idx*size)
+	return base + r.offset(), true
+}
@@ -0,0 +1,349 @@
+package debuginfo
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"elfreader/file"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Sections holds the raw DWARF sections of a file, for decoding below
+// the level of debug/dwarf.
+type Sections struct {
+	Order binary.ByteOrder
+	data  map[string][]byte
+}
+
+// LoadSections reads every .debug_* section of f.
+func LoadSections(f *file.File) (*Sections, error) {
+	s := &Sections{Order: f.ByteOrder, data: make(map[string][]byte)}
+	for _, sec := range f.Sections {
+		if !strings.HasPrefix(sec.Name, ".debug_") || sec.Type == elf.SHT_NOBITS {
+			continue
+		}
+		b, err := SectionData(sec)
+		if err != nil {
+			return nil, err
+		}
+		s.data[sec.Name] = b
+	}
+	return s, nil
+}
+
+// Data returns the contents of the named section, or nil.
+func (s *Sections) Data(name string) []byte { return s.data[name] }
+
+func (s *Sections) buf(name string) *buf { return newBuf(s.data[name], s.Order) }
+
+// DWARF 5 unit types
+const (
+	UTCompile      = 0x01
+	UTType         = 0x02
+	UTPartial      = 0x03
+	UTSkeleton     = 0x04
+	UTSplitCompile = 0x05
+	UTSplitType    = 0x06
+)
+
+// A Unit is a unit header in .debug_info.
+type Unit struct {
+	Offset       uint64
+	Length       uint64
+	Dwarf64      bool
+	Version      int
+	Type         int
+	AbbrevOffset uint64
+	AddrSize     int
+	// ID is the DWO id of skeleton and split units or the signature
+	// of type units
+	ID         uint64
+	TypeOffset uint64
+	// DataOffset is the section offset of the first entry
+	DataOffset uint64
+
+	end uint64
+}
+
+// Units returns the unit headers of .debug_info.
+func (s *Sections) Units() ([]*Unit, error) {
+	b := s.buf(".debug_info")
+	var units []*Unit
+	for b.left() > 0 {
+		u := &Unit{Offset: uint64(b.off)}
+		u.Length = b.unitLength()
+		u.Dwarf64 = b.dwarf64
+		start := uint64(b.off)
+		u.end = start + u.Length
+		u.Version = int(b.u16())
+		if u.Version < 2 || u.Version > 5 {
+			return units, fmt.Errorf("unit at 0x%x: unsupported DWARF version %d", u.Offset, u.Version)
+		}
+		if u.Version >= 5 {
+			u.Type = int(b.u8())
+			u.AddrSize = int(b.u8())
+			u.AbbrevOffset = b.offset()
+			switch u.Type {
+			case UTSkeleton, UTSplitCompile:
+				u.ID = b.u64()
+			case UTType, UTSplitType:
+				u.ID = b.u64()
+				u.TypeOffset = b.offset()
+			}
+		} else {
+			u.Type = UTCompile
+			u.AbbrevOffset = b.offset()
+			u.AddrSize = int(b.u8())
+		}
+		if b.err != nil {
+			return units, b.err
+		}
+		if u.end > uint64(len(b.data)) {
+			return units, fmt.Errorf("unit at 0x%x: length 0x%x past end of section", u.Offset, u.Length)
+		}
+		u.DataOffset = uint64(b.off)
+		units = append(units, u)
+		b.off = int(u.end)
+	}
+	return units, nil
+}
+
+// An AbbrevAttr is an attribute specification of an abbreviation.
+type AbbrevAttr struct {
+	Attr dwarf.Attr
+	Form Form
+	// Const is the value of a DW_FORM_implicit_const attribute
+	Const int64
+}
+
+// Implicit tells whether the value of the attribute is Const, stored
+// in the abbreviation rather than in each entry.
+func (a AbbrevAttr) Implicit() bool { return a.Form == formImplicitConst }
+
+// An Abbrev is an entry of .debug_abbrev.
+type Abbrev struct {
+	Code     uint64
+	Tag      dwarf.Tag
+	Children bool
+	Attrs    []AbbrevAttr
+}
+
+// Abbrevs returns the abbreviation table at off, in table order.
+func (s *Sections) Abbrevs(off uint64) ([]*Abbrev, error) {
+	b := s.buf(".debug_abbrev")
+	if off > uint64(len(b.data)) {
+		return nil, fmt.Errorf("abbreviation offset 0x%x past end of section", off)
+	}
+	b.off = int(off)
+	var table []*Abbrev
+	for {
+		code := b.uleb()
+		if code == 0 || b.err != nil {
+			break
+		}
+		a := &Abbrev{Code: code, Tag: dwarf.Tag(b.uleb()), Children: b.u8() != 0}
+		for {
+			attr, form := b.uleb(), b.uleb()
+			if attr == 0 && form == 0 || b.err != nil {
+				break
+			}
+			aa := AbbrevAttr{Attr: dwarf.Attr(attr), Form: Form(form)}
+			if aa.Form == formImplicitConst {
+				aa.Const = b.sleb()
+			}
+			a.Attrs = append(a.Attrs, aa)
+		}
+		table = append(table, a)
+	}
+	return table, b.err
+}
+
+// A Field is a decoded attribute of a DIE.
+type Field struct {
+	Attr dwarf.Attr
+	Form Form
+	// Val is a uint64 for addresses, unsigned constants, section
+	// offsets, indexes and references (made section-relative), an
+	// int64 for signed constants, a string for inline strings, a
+	// []byte for blocks, expressions and 16-byte data and a bool for
+	// flags.
+	Val interface{}
+}
+
+// A DIE is a debugging information entry. Null entries, which end a
+// list of children, have a nil Abbrev.
+type DIE struct {
+	Offset uint64
+	Depth  int
+	Abbrev *Abbrev
+	Fields []Field
+}
+
+// Val returns the value of attribute a, or nil.
+func (d *DIE) Val(a dwarf.Attr) interface{} {
+	for _, f := range d.Fields {
+		if f.Attr == a {
+			return f.Val
+		}
+	}
+	return nil
+}
+
+// DIEs decodes the entries of u in order.
+func (s *Sections) DIEs(u *Unit) ([]DIE, error) {
+	table, err := s.Abbrevs(u.AbbrevOffset)
+	if err != nil {
+		return nil, err
+	}
+	abbrevs := make(map[uint64]*Abbrev, len(table))
+	for _, a := range table {
+		abbrevs[a.Code] = a
+	}
+
+	b := s.buf(".debug_info")
+	b.data = b.data[:u.end]
+	b.off = int(u.DataOffset)
+	b.dwarf64, b.addrSize = u.Dwarf64, u.AddrSize
+
+	var dies []DIE
+	depth := 0
+	for b.left() > 0 {
+		d := DIE{Offset: uint64(b.off), Depth: depth}
+		code := b.uleb()
+		if code == 0 {
+			dies = append(dies, d)
+			if depth > 0 {
+				depth--
+			}
+			continue
+		}
+		a, ok := abbrevs[code]
+		if !ok {
+			return dies, fmt.Errorf("DIE at 0x%x: unknown abbreviation %d", d.Offset, code)
+		}
+		d.Abbrev = a
+		for _, spec := range a.Attrs {
+			val, err := readForm(b, u, spec.Form, spec.Const)
+			if err != nil {
+				return dies, fmt.Errorf("DIE at 0x%x: %v", d.Offset, err)
+			}
+			d.Fields = append(d.Fields, Field{spec.Attr, spec.Form, val})
+		}
+		if b.err != nil {
+			return dies, b.err
+		}
+		dies = append(dies, d)
+		if a.Children {
+			depth++
+		}
+	}
+	return dies, nil
+}
+
+func readForm(b *buf, u *Unit, form Form, implicit int64) (interface{}, error) {
+	switch form {
+	case formAddr:
+		return b.addr(), nil
+	case formBlock1:
+		return b.bytes(int(b.u8())), nil
+	case formBlock2:
+		return b.bytes(int(b.u16())), nil
+	case formBlock4:
+		return b.bytes(int(b.u32())), nil
+	case formBlock, formExprloc:
+		return b.bytes(int(b.uleb())), nil
+	case formData1, formRef1, formFlag, formStrx1, formAddrx1:
+		v := uint64(b.u8())
+		if form == formRef1 {
+			v += u.Offset
+		}
+		if form == formFlag {
+			return v != 0, nil
+		}
+		return v, nil
+	case formData2, formRef2, formStrx2, formAddrx2:
+		v := uint64(b.u16())
+		if form == formRef2 {
+			v += u.Offset
+		}
+		return v, nil
+	case formStrx3, formAddrx3:
+		return uint64(b.u24()), nil
+	case formData4, formRef4, formStrx4, formAddrx4, formRefSup4:
+		v := uint64(b.u32())
+		if form == formRef4 {
+			v += u.Offset
+		}
+		return v, nil
+	case formData8, formRef8, formRefSig8, formRefSup8:
+		v := b.u64()
+		if form == formRef8 {
+			v += u.Offset
+		}
+		return v, nil
+	case formData16:
+		return b.bytes(16), nil
+	case formSdata:
+		return b.sleb(), nil
+	case formUdata, formStrx, formAddrx, formLoclistx, formRnglistx, formGNUAddrIndex, formGNUStrIndex:
+		return b.uleb(), nil
+	case formRefUdata:
+		return b.uleb() + u.Offset, nil
+	case formString:
+		return b.cstring(), nil
+	case formStrp, formLineStrp, formSecOffset, formStrpSup, formGNURefAlt, formGNUStrpAlt:
+		return b.offset(), nil
+	case formRefAddr:
+		// DWARF 2 used the address size for DW_FORM_ref_addr
+		if u.Version == 2 {
+			return b.addr(), nil
+		}
+		return b.offset(), nil
+	case formFlagPresent:
+		return true, nil
+	case formImplicitConst:
+		return implicit, nil
+	case formIndirect:
+		return readForm(b, u, Form(b.uleb()), implicit)
+	}
+	return nil, fmt.Errorf("unknown form 0x%x", uint16(form))
+}
+
+// Str returns the string at off in .debug_str.
+func (s *Sections) Str(off uint64) (string, bool) {
+	return cstringAt(s.data[".debug_str"], off)
+}
+
+// LineStr returns the string at off in .debug_line_str.
+func (s *Sections) LineStr(off uint64) (string, bool) {
+	return cstringAt(s.data[".debug_line_str"], off)
+}
+
+// StrIndex returns string idx of the string offsets table at base.
+func (s *Sections) StrIndex(u *Unit, base, idx uint64) (string, bool) {
+	b := s.buf(".debug_str_offsets")
+	b.dwarf64 = u.Dwarf64
+	size := uint64(4)
+	if u.Dwarf64 {
+		size = 8
+	}
+	pos := base + idx*size
+	if pos+size > uint64(len(b.data)) {
+		return "", false
+	}
+	b.off = int(pos)
+	return s.Str(b.offset())
+}
+
+// AddrIndex returns address idx of the address table at base.
+func (s *Sections) AddrIndex(u *Unit, base, idx uint64) (uint64, bool) {
+	b := s.buf(".debug_addr")
+	b.addrSize = u.AddrSize
+	pos := base + idx*uint64(u.AddrSize)
+	if pos+uint64(u.AddrSize) > uint64(len(b.data)) {
+		return 0, false
+	}
+	b.off = int(pos)
+	return b.addr(), true
+}
@@ -0,0 +1,254 @@
+// Package debuginfod is a client of debuginfod servers, which serve the
+// debug files, executables and sources of build-ids over HTTP:
+//
+//	<url>/buildid/<id>/debuginfo
+//	<url>/buildid/<id>/executable
+//	<url>/buildid/<id>/source/<absolute path>
+//
+// Downloads are kept in a local cache laid out like the one of the
+// elfutils client, <cache>/<id>/<kind>, and reused until they expire.
+package debuginfod
+
+import (
+	"bytes"
+	"elfreader/debuglink"
+	"elfreader/file"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no server has the requested file.
+var ErrNotFound = errors.New("not found on any debuginfod server")
+
+// ErrNoServers is returned when the client has no server URL.
+var ErrNoServers = errors.New("no debuginfod servers, set DEBUGINFOD_URLS")
+
+// Defaults of the client, those of elfutils.
+const (
+	DefaultMaxAge  = 7 * 24 * time.Hour
+	DefaultMissAge = 10 * time.Minute
+	DefaultTimeout = 90 * time.Second
+)
+
+// A Client fetches files from debuginfod servers through a cache.
+type Client struct {
+	// URLs are the servers, queried in order
+	URLs []string
+	// CacheDir is the root of the cache
+	CacheDir string
+	// MaxAge is how long a download is used before it is fetched
+	// again; 0 keeps it forever
+	MaxAge time.Duration
+	// MissAge is how long a server answer of "not found" is
+	// remembered
+	MissAge time.Duration
+	// HTTP is the client used for the requests
+	HTTP *http.Client
+}
+
+// NewClient returns a client configured from the environment, like the
+// elfutils one: DEBUGINFOD_URLS holds the space-separated server URLs,
+// DEBUGINFOD_CACHE_PATH the cache directory and DEBUGINFOD_TIMEOUT the
+// timeout of a request in seconds.
+func NewClient() *Client {
+	c := &Client{
+		URLs:     strings.Fields(os.Getenv("DEBUGINFOD_URLS")),
+		CacheDir: os.Getenv("DEBUGINFOD_CACHE_PATH"),
+		MaxAge:   DefaultMaxAge,
+		MissAge:  DefaultMissAge,
+		HTTP:     &http.Client{Timeout: DefaultTimeout},
+	}
+	if c.CacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			c.CacheDir = filepath.Join(dir, "debuginfod_client")
+		}
+	}
+	if s, err := strconv.Atoi(os.Getenv("DEBUGINFOD_TIMEOUT")); err == nil && s > 0 {
+		c.HTTP.Timeout = time.Duration(s) * time.Second
+	}
+	return c
+}
+
+// Debuginfo returns the path of the cached debug file of build-id id.
+func (c *Client) Debuginfo(id []byte) (string, error) {
+	return c.fetch(id, "debuginfo", "debuginfo", true)
+}
+
+// Executable returns the path of the cached executable of build-id id.
+func (c *Client) Executable(id []byte) (string, error) {
+	return c.fetch(id, "executable", "executable", true)
+}
+
+// Source returns the path of the cached source file path, an absolute
+// path as recorded in the DWARF of build-id id.
+func (c *Client) Source(id []byte, path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("source path %q is not absolute", path)
+	}
+	var parts []string
+	for _, p := range strings.Split(path, "/") {
+		parts = append(parts, url.PathEscape(p))
+	}
+	// the cache flattens the path like elfutils does
+	return c.fetch(id, "source"+strings.Join(parts, "/"), "source-"+strings.ReplaceAll(path, "/", "#"), false)
+}
+
+// fetch returns the cached copy of <url>/buildid/<id>/<kind>, stored as
+// name, downloading it if needed. ELF files are checked to carry the
+// build-id if check is set.
+func (c *Client) fetch(id []byte, kind, name string, check bool) (string, error) {
+	if len(id) == 0 {
+		return "", errors.New("empty build-id")
+	}
+	if c.CacheDir == "" {
+		return "", errors.New("no debuginfod cache directory")
+	}
+	hexID := hex.EncodeToString(id)
+	dir := filepath.Join(c.CacheDir, hexID)
+	p := filepath.Join(dir, name)
+	miss := p + ".miss"
+
+	st, err := os.Stat(p)
+	cached := err == nil
+	if cached && (c.MaxAge <= 0 || time.Since(st.ModTime()) < c.MaxAge) {
+		return p, nil
+	}
+	if st, err := os.Stat(miss); err == nil && time.Since(st.ModTime()) < c.MissAge {
+		return "", ErrNotFound
+	}
+	if len(c.URLs) == 0 {
+		if cached {
+			return p, nil
+		}
+		return "", ErrNoServers
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	last := ErrNotFound
+	for _, u := range c.URLs {
+		err := c.download(strings.TrimRight(u, "/")+"/buildid/"+hexID+"/"+kind, p, id, check)
+		if err == nil {
+			os.Remove(miss)
+			return p, nil
+		}
+		if err != ErrNotFound {
+			last = err
+		}
+	}
+	if last == ErrNotFound {
+		// the servers do not have it: drop the expired copy and
+		// remember the miss
+		os.Remove(p)
+		if m, err := os.Create(miss); err == nil {
+			m.Close()
+		}
+		return "", ErrNotFound
+	}
+	if cached {
+		// a stale copy is better than none while the servers fail
+		return p, nil
+	}
+	return "", last
+}
+
+// download stores the body of u at p, through a temporary file so
+// that readers of the cache never see a partial file.
+func (c *Client) download(u, p string, id []byte, check bool) error {
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s: %s", u, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%s: %v", u, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if check {
+		f, err := file.Open(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("%s: %v", u, err)
+		}
+		got := debuglink.BuildID(f)
+		f.Close()
+		if !bytes.Equal(got, id) {
+			return fmt.Errorf("%s: file has build-id %x", u, got)
+		}
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Clean removes the cache entries older than MaxAge and the misses
+// older than MissAge.
+func (c *Client) Clean() error {
+	if c.CacheDir == "" || c.MaxAge <= 0 {
+		return nil
+	}
+	ids, err := os.ReadDir(c.CacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, d := range ids {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(c.CacheDir, d.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		left := len(entries)
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			age := c.MaxAge
+			if strings.HasSuffix(e.Name(), ".miss") {
+				age = c.MissAge
+			}
+			if time.Since(info.ModTime()) >= age {
+				if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+					return err
+				}
+				left--
+			}
+		}
+		if left == 0 {
+			os.Remove(dir)
+		}
+	}
+	return nil
+}
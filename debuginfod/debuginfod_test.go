@@ -0,0 +1,155 @@
+package debuginfod
+
+import (
+	"bytes"
+	"elfreader/debuglink"
+	"elfreader/file"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server is a stand-in debuginfod server serving one debug file.
+type server struct {
+	*httptest.Server
+	requests int32
+}
+
+// newServer serves data as the debug file of build-id id, or nothing if
+// data is nil.
+func newServer(t *testing.T, id []byte, data []byte) *server {
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		if data == nil || r.URL.Path != "/buildid/"+hex.EncodeToString(id)+"/debuginfo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) count() int { return int(atomic.LoadInt32(&s.requests)) }
+
+// sampleDebugFile returns the sample executable, which stands in for a
+// debug file, and its build-id.
+func sampleDebugFile(t *testing.T) ([]byte, []byte) {
+	f, err := file.Open("../sample/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := debuglink.BuildID(f)
+	f.Close()
+	if id == nil {
+		t.Fatal("sample has no build-id")
+	}
+	data, err := os.ReadFile("../sample/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, id
+}
+
+func newTestClient(t *testing.T, urls ...string) *Client {
+	return &Client{
+		URLs:     urls,
+		CacheDir: t.TempDir(),
+		MaxAge:   DefaultMaxAge,
+		MissAge:  DefaultMissAge,
+		HTTP:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func TestDebuginfoFetchAndCache(t *testing.T) {
+	data, id := sampleDebugFile(t)
+	s := newServer(t, id, data)
+	c := newTestClient(t, s.URL)
+
+	p, err := c.Debuginfo(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("cached file differs from the served one")
+	}
+	if s.count() != 1 {
+		t.Errorf("fetch made %d requests, want 1", s.count())
+	}
+
+	// the second lookup is answered by the cache
+	p2, err := c.Debuginfo(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p2 != p {
+		t.Errorf("cached path %q, want %q", p2, p)
+	}
+	if s.count() != 1 {
+		t.Errorf("cache hit made %d requests, want none", s.count()-1)
+	}
+}
+
+func TestDebuginfoMiss(t *testing.T) {
+	_, id := sampleDebugFile(t)
+	s := newServer(t, id, nil)
+	c := newTestClient(t, s.URL)
+
+	if _, err := c.Debuginfo(id); err != ErrNotFound {
+		t.Fatalf("Debuginfo = %v, want ErrNotFound", err)
+	}
+	// the miss is remembered for MissAge
+	if _, err := c.Debuginfo(id); err != ErrNotFound {
+		t.Fatalf("second Debuginfo = %v, want ErrNotFound", err)
+	}
+	if s.count() != 1 {
+		t.Errorf("made %d requests, want 1", s.count())
+	}
+}
+
+func TestDebuginfoFallback(t *testing.T) {
+	data, id := sampleDebugFile(t)
+	missing := newServer(t, id, nil)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	s := newServer(t, id, data)
+	c := newTestClient(t, missing.URL, failing.URL, s.URL)
+
+	p, err := c.Debuginfo(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(p); !bytes.Equal(got, data) {
+		t.Errorf("cached file differs from the served one")
+	}
+	if missing.count() != 1 || s.count() != 1 {
+		t.Errorf("requests: %d to the server without the file, %d to the one with it; want 1 and 1", missing.count(), s.count())
+	}
+}
+
+func TestDebuginfoWrongBuildID(t *testing.T) {
+	data, id := sampleDebugFile(t)
+	other := append([]byte(nil), id...)
+	other[0] ^= 0xff
+	// a server answering any build-id with the sample
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer s.Close()
+	c := newTestClient(t, s.URL)
+
+	if _, err := c.Debuginfo(other); err == nil || err == ErrNotFound {
+		t.Fatalf("Debuginfo of a mismatched file = %v, want a build-id error", err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package debuglink locates the separate debug file of a stripped ELF
+// file, through its GNU build-id note or its .gnu_debuglink section, in
+// the layouts used by GDB and the distributions:
+//
+//	<root>/.build-id/xx/yyyy.debug
+//	<dir>/<debuglink>
+//	<dir>/.debug/<debuglink>
+//	<root>/<dir>/<debuglink>
+//
+// where xx is the first byte of the build-id in hex, yyyy the rest and
+// dir the directory of the stripped file.
+package debuglink
+
+import (
+	"bytes"
+	"debug/elf"
+	"elfreader/file"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DefaultRoots are the directories searched when no others are given.
+var DefaultRoots = []string{"/usr/lib/debug"}
+
+// ErrNotFound is returned by Find when no candidate matches.
+var ErrNotFound = errors.New("no separate debug file found")
+
+const ntGNUBuildID = 3
+
+// BuildID returns the GNU build-id of f, or nil if it has none. The
+// note is looked for in the SHT_NOTE sections, then in the PT_NOTE
+// segments.
+func BuildID(f *file.File) []byte {
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NOTE {
+			if id := findBuildID(s.Data(), f.ByteOrder, s.Addralign); id != nil {
+				return id
+			}
+		}
+	}
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil {
+			continue
+		}
+		if id := findBuildID(data, f.ByteOrder, p.Align); id != nil {
+			return id
+		}
+	}
+	return nil
+}
+
+func findBuildID(data []byte, order binary.ByteOrder, align uint64) []byte {
+	if align != 8 {
+		align = 4
+	}
+	pad := func(n uint64) uint64 { return (n + align - 1) &^ (align - 1) }
+	for len(data) >= 12 {
+		namesz := uint64(order.Uint32(data[0:4]))
+		descsz := uint64(order.Uint32(data[4:8]))
+		typ := order.Uint32(data[8:12])
+		data = data[12:]
+		if pad(namesz) > uint64(len(data)) {
+			return nil
+		}
+		name := bytes.TrimRight(data[:namesz], "\x00")
+		data = data[pad(namesz):]
+		if descsz > uint64(len(data)) {
+			return nil
+		}
+		if string(name) == "GNU" && typ == ntGNUBuildID {
+			return data[:descsz]
+		}
+		if pad(descsz) > uint64(len(data)) {
+			return nil
+		}
+		data = data[pad(descsz):]
+	}
+	return nil
+}
+
+// Link returns the file name and CRC32 recorded in the .gnu_debuglink
+// section of f.
+func Link(f *file.File) (name string, crc uint32, ok bool) {
+	s := f.Section(".gnu_debuglink")
+	if s == nil || s.Type == elf.SHT_NOBITS {
+		return "", 0, false
+	}
+	data := s.Data()
+	i := bytes.IndexByte(data, 0)
+	if i <= 0 {
+		return "", 0, false
+	}
+	// the CRC follows the name, aligned to 4 bytes
+	off := (i + 4) &^ 3
+	if off+4 > len(data) {
+		return "", 0, false
+	}
+	return string(data[:i]), f.ByteOrder.Uint32(data[off:]), true
+}
+
+// CRC returns the CRC32 of the file at path, as .gnu_debuglink
+// records it.
+func CRC(path string) (uint32, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, r); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
+
+// BuildIDPath returns the path of the debug file of build-id id under
+// root.
+func BuildIDPath(root string, id []byte) string {
+	s := hex.EncodeToString(id)
+	return filepath.Join(root, ".build-id", s[:2], s[2:]+".debug")
+}
+
+// Find returns the path of the separate debug file of f, opened from
+// path, searching roots. Files found through the build-id must carry
+// the same build-id; files found through .gnu_debuglink must have the
+// recorded CRC32.
+func Find(f *file.File, path string, roots []string) (string, error) {
+	self, _ := os.Stat(path)
+	usable := func(p string) bool {
+		st, err := os.Stat(p)
+		return err == nil && st.Mode().IsRegular() && (self == nil || !os.SameFile(self, st))
+	}
+
+	if id := BuildID(f); len(id) >= 2 {
+		for _, root := range roots {
+			p := BuildIDPath(root, id)
+			if !usable(p) {
+				continue
+			}
+			d, err := file.Open(p)
+			if err != nil {
+				continue
+			}
+			ok := bytes.Equal(BuildID(d), id)
+			d.Close()
+			if ok {
+				return p, nil
+			}
+		}
+	}
+
+	name, crc, ok := Link(f)
+	if !ok {
+		return "", ErrNotFound
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	candidates := []string{filepath.Join(dir, name), filepath.Join(dir, ".debug", name)}
+	for _, root := range roots {
+		candidates = append(candidates, filepath.Join(root, dir, name))
+	}
+	for _, p := range candidates {
+		if !usable(p) {
+			continue
+		}
+		if c, err := CRC(p); err == nil && c == crc {
+			return p, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Open finds and opens the separate debug file of f, opened from path.
+// It returns the file and its path.
+func Open(f *file.File, path string, roots []string) (*file.File, string, error) {
+	p, err := Find(f, path, roots)
+	if err != nil {
+		return nil, "", err
+	}
+	d, err := file.Open(p)
+	if err != nil {
+		return nil, "", err
+	}
+	return d, p, nil
+}
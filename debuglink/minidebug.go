@@ -0,0 +1,61 @@
+package debuglink
+
+import (
+	"bytes"
+	"debug/elf"
+	"elfreader/file"
+	"elfreader/xz"
+	"errors"
+	"fmt"
+)
+
+// MiniDebugData returns the decompressed contents of the .gnu_debugdata
+// section of f, an ELF file holding the symbols stripped from f
+// ("MiniDebugInfo"), or nil if f has none.
+func MiniDebugData(f *file.File) ([]byte, error) {
+	s := f.Section(".gnu_debugdata")
+	if s == nil || s.Type == elf.SHT_NOBITS {
+		return nil, nil
+	}
+	data, err := xz.Decompress(s.Data())
+	if err != nil {
+		return nil, fmt.Errorf(".gnu_debugdata: %v", err)
+	}
+	return data, nil
+}
+
+// MiniDebugInfo returns the ELF file embedded in the .gnu_debugdata
+// section of f, or nil if f has none. Its section headers are those of
+// f, so the section indices of its symbols apply to f.
+func MiniDebugInfo(f *file.File) (*file.File, error) {
+	data, err := MiniDebugData(f)
+	if data == nil {
+		return nil, err
+	}
+	m := file.NewFile(bytes.NewReader(data))
+	if m == nil {
+		return nil, errors.New(".gnu_debugdata: not a valid ELF file")
+	}
+	return m, nil
+}
+
+// MiniDebugSymbols returns the function symbols of the MiniDebugInfo of
+// f, or nil if it has none. The embedded file holds only the symbols
+// missing from .dynsym, so they complete the dynamic ones.
+func MiniDebugSymbols(f *file.File) ([]file.Symbol, error) {
+	m, err := MiniDebugInfo(f)
+	if m == nil {
+		return nil, err
+	}
+	syms, err := m.Symbols()
+	if err != nil && err != file.ErrNoSymbols {
+		return nil, fmt.Errorf(".gnu_debugdata: %v", err)
+	}
+	var funcs []file.Symbol
+	for _, s := range syms {
+		if elf.ST_TYPE(s.Info) == elf.STT_FUNC {
+			funcs = append(funcs, s)
+		}
+	}
+	return funcs, nil
+}
@@ -0,0 +1,94 @@
+// Package demangle decodes mangled symbol names produced by C++
+// compilers following the Itanium C++ ABI and by the Rust compiler,
+// in both its legacy and v0 mangling schemes.
+package demangle
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotMangledName is returned by ToString when the argument is not
+// a mangled name of a recognized scheme.
+var ErrNotMangledName = errors.New("not a mangled name")
+
+// maxDepth bounds the recursion of the parsers so that hostile
+// input cannot exhaust the stack.
+const maxDepth = 256
+
+// ToString demangles name. A symbol version suffix such as
+// "@GLIBC_2.2.5" is kept as it is.
+func ToString(name string) (string, error) {
+	base, version := name, ""
+	if i := strings.IndexByte(name, '@'); i > 0 {
+		base, version = name[:i], name[i:]
+	}
+
+	var out string
+	var err error
+	switch {
+	case strings.HasPrefix(base, "_R"):
+		out, err = rustV0(base)
+	case strings.HasPrefix(base, "_ZN") && isRustLegacy(base):
+		out, err = rustLegacy(base)
+	case strings.HasPrefix(base, "_Z"):
+		out, err = itanium(base)
+	default:
+		return "", ErrNotMangledName
+	}
+	if err != nil {
+		return "", err
+	}
+	return out + version, nil
+}
+
+// Filter demangles name, returning it unchanged if it cannot be
+// demangled.
+func Filter(name string) string {
+	if out, err := ToString(name); err == nil {
+		return out
+	}
+	return name
+}
+
+// Scope returns the namespace or class enclosing the entity named by
+// a mangled name, for example "std::vector<int, std::allocator<int> >"
+// for the mangled form of std::vector<int>::push_back(int const&).
+// It returns "" for global entities and for names it cannot demangle.
+func Scope(name string) string {
+	if i := strings.IndexByte(name, '@'); i > 0 {
+		name = name[:i]
+	}
+	switch {
+	case strings.HasPrefix(name, "_R"):
+		return rustV0Scope(name)
+	case strings.HasPrefix(name, "_ZN") && isRustLegacy(name):
+		return rustLegacyScope(name)
+	case strings.HasPrefix(name, "_Z"):
+		return itaniumScope(name)
+	}
+	return ""
+}
+
+// parseError is raised with panic inside the parsers and turned into
+// an error by the entry points.
+type parseError struct {
+	msg string
+}
+
+func (e parseError) Error() string { return e.msg }
+
+func fail(msg string) {
+	panic(parseError{msg})
+}
+
+// catch converts a parseError panic into *err.
+func catch(err *error) {
+	if r := recover(); r != nil {
+		pe, ok := r.(parseError)
+		if !ok {
+			panic(r)
+		}
+		*err = pe
+	}
+}
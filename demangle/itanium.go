@@ -0,0 +1,1833 @@
+package demangle
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The Itanium C++ ABI demangler parses a mangled name into a tree of
+// nodes and prints the tree. Types print in two halves, so that the
+// declarator of a pointer to function or array ends up in the middle
+// of the type: "void (*)(int)".
+
+type node interface {
+	printLeft(p *printer)
+	printRight(p *printer)
+}
+
+type printer struct {
+	strings.Builder
+}
+
+func (p *printer) last() byte {
+	s := p.String()
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1]
+}
+
+func (p *printer) print(n node) {
+	n.printLeft(p)
+	n.printRight(p)
+}
+
+func nodeString(n node) string {
+	var p printer
+	p.print(n)
+	return p.String()
+}
+
+// hasRHS reports whether n prints anything in its right half.
+func hasRHS(n node) bool {
+	switch n := n.(type) {
+	case *functionType, *arrayType:
+		return true
+	case *pointerType:
+		return hasRHS(n.pointee)
+	case *referenceType:
+		pointee, _ := n.collapse()
+		return hasRHS(pointee)
+	case *ptrToMember:
+		return hasRHS(n.member)
+	case *qualType:
+		return hasRHS(n.child)
+	}
+	return false
+}
+
+func isFunction(n node) bool {
+	switch n := n.(type) {
+	case *functionType:
+		return true
+	case *qualType:
+		return isFunction(n.child)
+	}
+	return false
+}
+
+func isArray(n node) bool {
+	switch n := n.(type) {
+	case *arrayType:
+		return true
+	case *qualType:
+		return isArray(n.child)
+	}
+	return false
+}
+
+// nameNode is a plain identifier, builtin type or preformatted text.
+type nameNode struct {
+	s string
+}
+
+func (n *nameNode) printLeft(p *printer)  { p.WriteString(n.s) }
+func (n *nameNode) printRight(p *printer) {}
+
+// specialSub is one of the abbreviations Sa, Sb, Ss, Si, So, Sd. Like
+// c++filt, the abbreviated types are printed in full: Ss is
+// std::basic_string<char, std::char_traits<char>, std::allocator<char> >
+// rather than std::string.
+type specialSub struct {
+	name, base string
+}
+
+func (n *specialSub) printLeft(p *printer) {
+	p.WriteString(n.name)
+}
+func (n *specialSub) printRight(p *printer) {}
+
+type nestedName struct {
+	qual, name node
+}
+
+func (n *nestedName) printLeft(p *printer) {
+	p.print(n.qual)
+	p.WriteString("::")
+	p.print(n.name)
+}
+func (n *nestedName) printRight(p *printer) {}
+
+type localName struct {
+	encoding, entity node
+}
+
+func (n *localName) printLeft(p *printer) {
+	// the enclosing function is printed without its return type
+	if enc, ok := n.encoding.(*encoding); ok && enc.ret != nil {
+		noRet := *enc
+		noRet.ret = nil
+		p.print(&noRet)
+	} else {
+		p.print(n.encoding)
+	}
+	p.WriteString("::")
+	p.print(n.entity)
+}
+func (n *localName) printRight(p *printer) {}
+
+type templateArgs struct {
+	args []node
+}
+
+func (n *templateArgs) printLeft(p *printer) {
+	if p.last() == '<' {
+		p.WriteByte(' ')
+	}
+	p.WriteByte('<')
+	printList(p, n.args)
+	// like c++filt, a trailing empty pack suppresses the space
+	// between closing angle brackets
+	if p.last() == '>' && !endsWithEmptyPack(n.args) {
+		p.WriteByte(' ')
+	}
+	p.WriteByte('>')
+}
+func (n *templateArgs) printRight(p *printer) {}
+
+// printList prints l separated by commas. Like c++filt, trailing
+// elements that print as nothing, such as expansions of empty packs,
+// are left out together with their commas.
+func printList(p *printer, l []node) {
+	strs := make([]string, len(l))
+	for i, a := range l {
+		strs[i] = nodeString(a)
+	}
+	for len(strs) > 0 && strs[len(strs)-1] == "" {
+		strs = strs[:len(strs)-1]
+	}
+	p.WriteString(strings.Join(strs, ", "))
+}
+
+func endsWithEmptyPack(l []node) bool {
+	return len(l) >= 2 && nodeString(l[len(l)-1]) == ""
+}
+
+// argPack is a template argument pack, printed as a plain list.
+type argPack struct {
+	args []node
+}
+
+func (n *argPack) printLeft(p *printer)  { printList(p, n.args) }
+func (n *argPack) printRight(p *printer) {}
+
+type nameWithArgs struct {
+	name node
+	args *templateArgs
+}
+
+func (n *nameWithArgs) printLeft(p *printer) {
+	p.print(n.name)
+	p.print(n.args)
+}
+func (n *nameWithArgs) printRight(p *printer) {}
+
+type ctorDtorName struct {
+	base node
+	dtor bool
+}
+
+func (n *ctorDtorName) printLeft(p *printer) {
+	if n.dtor {
+		p.WriteByte('~')
+	}
+	p.WriteString(baseName(n.base))
+}
+func (n *ctorDtorName) printRight(p *printer) {}
+
+// baseName returns the unqualified name of a class without template
+// arguments, as used to name its constructors.
+func baseName(n node) string {
+	switch n := n.(type) {
+	case *nestedName:
+		return baseName(n.name)
+	case *nameWithArgs:
+		return baseName(n.name)
+	case *specialSub:
+		return n.base
+	case *abiTagged:
+		return baseName(n.base)
+	}
+	return nodeString(n)
+}
+
+type abiTagged struct {
+	base node
+	tag  string
+}
+
+func (n *abiTagged) printLeft(p *printer) {
+	p.print(n.base)
+	p.WriteString("[abi:" + n.tag + "]")
+}
+func (n *abiTagged) printRight(p *printer) {}
+
+type convOperator struct {
+	typ node
+}
+
+func (n *convOperator) printLeft(p *printer) {
+	p.WriteString("operator ")
+	p.print(n.typ)
+}
+func (n *convOperator) printRight(p *printer) {}
+
+type qualType struct {
+	child node
+	quals string
+}
+
+func (n *qualType) printLeft(p *printer) {
+	n.child.printLeft(p)
+	p.WriteString(n.quals)
+}
+func (n *qualType) printRight(p *printer) { n.child.printRight(p) }
+
+type vendorQual struct {
+	child node
+	qual  string
+}
+
+func (n *vendorQual) printLeft(p *printer) {
+	p.print(n.child)
+	p.WriteString(" " + n.qual)
+}
+func (n *vendorQual) printRight(p *printer) {}
+
+type pointerType struct {
+	pointee node
+}
+
+func (n *pointerType) printLeft(p *printer) {
+	n.pointee.printLeft(p)
+	if isArray(n.pointee) {
+		p.WriteByte(' ')
+	}
+	if isArray(n.pointee) || isFunction(n.pointee) {
+		p.WriteByte('(')
+	}
+	p.WriteByte('*')
+}
+
+func (n *pointerType) printRight(p *printer) {
+	if isArray(n.pointee) || isFunction(n.pointee) {
+		p.WriteByte(')')
+	}
+	n.pointee.printRight(p)
+}
+
+type referenceType struct {
+	pointee node
+	rvalue  bool
+}
+
+// collapse applies the reference collapsing rules: T& & and T&& &
+// become T&, T&& && becomes T&&.
+func (n *referenceType) collapse() (node, bool) {
+	pointee, rvalue := n.pointee, n.rvalue
+	for {
+		r, ok := pointee.(*referenceType)
+		if !ok {
+			return pointee, rvalue
+		}
+		pointee, rvalue = r.pointee, rvalue && r.rvalue
+	}
+}
+
+func (n *referenceType) printLeft(p *printer) {
+	pointee, rvalue := n.collapse()
+	pointee.printLeft(p)
+	if isArray(pointee) {
+		p.WriteByte(' ')
+	}
+	if isArray(pointee) || isFunction(pointee) {
+		p.WriteByte('(')
+	}
+	if rvalue {
+		p.WriteString("&&")
+	} else {
+		p.WriteByte('&')
+	}
+}
+
+func (n *referenceType) printRight(p *printer) {
+	pointee, _ := n.collapse()
+	if isArray(pointee) || isFunction(pointee) {
+		p.WriteByte(')')
+	}
+	pointee.printRight(p)
+}
+
+type ptrToMember struct {
+	class, member node
+}
+
+func (n *ptrToMember) printLeft(p *printer) {
+	n.member.printLeft(p)
+	if isArray(n.member) || isFunction(n.member) {
+		p.WriteByte('(')
+	} else {
+		p.WriteByte(' ')
+	}
+	p.print(n.class)
+	p.WriteString("::*")
+}
+
+func (n *ptrToMember) printRight(p *printer) {
+	if isArray(n.member) || isFunction(n.member) {
+		p.WriteByte(')')
+	}
+	n.member.printRight(p)
+}
+
+type functionType struct {
+	ret      node
+	params   []node
+	cv, ref  string
+	noexcept string
+}
+
+func (n *functionType) printLeft(p *printer) {
+	n.ret.printLeft(p)
+	if !hasRHS(n.ret) {
+		p.WriteByte(' ')
+	}
+}
+
+func (n *functionType) printRight(p *printer) {
+	p.WriteByte('(')
+	printList(p, n.params)
+	p.WriteByte(')')
+	n.ret.printRight(p)
+	p.WriteString(n.cv + n.ref + n.noexcept)
+}
+
+type arrayType struct {
+	base node
+	dim  string
+}
+
+func (n *arrayType) printLeft(p *printer) { n.base.printLeft(p) }
+
+func (n *arrayType) printRight(p *printer) {
+	if p.last() != ']' {
+		p.WriteByte(' ')
+	}
+	p.WriteString("[" + n.dim + "]")
+	n.base.printRight(p)
+}
+
+type vectorType struct {
+	base node
+	dim  string
+}
+
+func (n *vectorType) printLeft(p *printer) {
+	p.print(n.base)
+	p.WriteString(" __vector(" + n.dim + ")")
+}
+func (n *vectorType) printRight(p *printer) {}
+
+type packExpansion struct {
+	child node
+}
+
+// printLeft expands the pattern once for each element of the
+// parameter pack it refers to.
+func (n *packExpansion) printLeft(p *printer) {
+	pack := findPack(n.child)
+	if pack == nil {
+		p.print(n.child)
+		p.WriteString("...")
+		return
+	}
+	for i, elem := range pack.args {
+		if i > 0 {
+			p.WriteString(", ")
+		}
+		p.print(substitute(n.child, pack, elem))
+	}
+}
+func (n *packExpansion) printRight(p *printer) {}
+
+// paramPack is a template parameter bound to an argument pack.
+type paramPack struct {
+	pack *argPack
+}
+
+func (n *paramPack) printLeft(p *printer)  { p.print(n.pack) }
+func (n *paramPack) printRight(p *printer) {}
+
+// findPack returns the first argument pack referred to in n.
+func findPack(n node) *argPack {
+	switch n := n.(type) {
+	case *paramPack:
+		return n.pack
+	case *qualType:
+		return findPack(n.child)
+	case *vendorQual:
+		return findPack(n.child)
+	case *pointerType:
+		return findPack(n.pointee)
+	case *referenceType:
+		return findPack(n.pointee)
+	case *ptrToMember:
+		if p := findPack(n.class); p != nil {
+			return p
+		}
+		return findPack(n.member)
+	case *arrayType:
+		return findPack(n.base)
+	case *vectorType:
+		return findPack(n.base)
+	case *functionType:
+		if p := findPack(n.ret); p != nil {
+			return p
+		}
+		for _, a := range n.params {
+			if p := findPack(a); p != nil {
+				return p
+			}
+		}
+	case *nameWithArgs:
+		if p := findPack(n.name); p != nil {
+			return p
+		}
+		for _, a := range n.args.args {
+			if p := findPack(a); p != nil {
+				return p
+			}
+		}
+	case *nestedName:
+		if p := findPack(n.qual); p != nil {
+			return p
+		}
+		return findPack(n.name)
+	}
+	return nil
+}
+
+// substitute returns a copy of n in which references to pack are
+// replaced by elem.
+func substitute(n node, pack *argPack, elem node) node {
+	sub := func(c node) node { return substitute(c, pack, elem) }
+	switch n := n.(type) {
+	case *paramPack:
+		if n.pack == pack {
+			return elem
+		}
+	case *qualType:
+		return &qualType{sub(n.child), n.quals}
+	case *vendorQual:
+		return &vendorQual{sub(n.child), n.qual}
+	case *pointerType:
+		return &pointerType{sub(n.pointee)}
+	case *referenceType:
+		return &referenceType{sub(n.pointee), n.rvalue}
+	case *ptrToMember:
+		return &ptrToMember{sub(n.class), sub(n.member)}
+	case *arrayType:
+		return &arrayType{sub(n.base), n.dim}
+	case *vectorType:
+		return &vectorType{sub(n.base), n.dim}
+	case *functionType:
+		fn := *n
+		fn.ret = sub(n.ret)
+		fn.params = make([]node, len(n.params))
+		for i, a := range n.params {
+			fn.params[i] = sub(a)
+		}
+		return &fn
+	case *nameWithArgs:
+		args := &templateArgs{make([]node, len(n.args.args))}
+		for i, a := range n.args.args {
+			args.args[i] = sub(a)
+		}
+		return &nameWithArgs{sub(n.name), args}
+	case *nestedName:
+		return &nestedName{sub(n.qual), sub(n.name)}
+	}
+	return n
+}
+
+type encoding struct {
+	ret     node
+	name    node
+	params  []node
+	cv, ref string
+}
+
+func (n *encoding) printLeft(p *printer) {
+	if n.ret != nil {
+		n.ret.printLeft(p)
+		if !hasRHS(n.ret) {
+			p.WriteByte(' ')
+		}
+	}
+	p.print(n.name)
+}
+
+func (n *encoding) printRight(p *printer) {
+	p.WriteByte('(')
+	printList(p, n.params)
+	p.WriteByte(')')
+	if n.ret != nil {
+		n.ret.printRight(p)
+	}
+	p.WriteString(n.cv + n.ref)
+}
+
+type specialName struct {
+	prefix string
+	child  node
+}
+
+func (n *specialName) printLeft(p *printer) {
+	p.WriteString(n.prefix)
+	p.print(n.child)
+}
+func (n *specialName) printRight(p *printer) {}
+
+type cloneSuffix struct {
+	child  node
+	suffix string
+}
+
+func (n *cloneSuffix) printLeft(p *printer) {
+	p.print(n.child)
+	p.WriteString(" [clone " + n.suffix + "]")
+}
+func (n *cloneSuffix) printRight(p *printer) {}
+
+// itParser holds the state of an Itanium demangling.
+type itParser struct {
+	s     string
+	pos   int
+	depth int
+
+	subs []node
+	// template arguments referred to by T_ in the current encoding
+	tmplArgs []node
+	// template parameter numbers of the substitutions that are bare
+	// template parameters
+	subParams map[int]int
+	// qualifiers of the outermost nested name of the encoding
+	cv, ref string
+}
+
+func itanium(name string) (out string, err error) {
+	n, err := parseItanium(name)
+	if err != nil {
+		return "", err
+	}
+	return nodeString(n), nil
+}
+
+func parseItanium(name string) (n node, err error) {
+	defer catch(&err)
+	d := &itParser{s: name, pos: 2}
+	n = d.encoding(true)
+	n = d.cloneSuffixes(n)
+	if d.pos != len(d.s) {
+		fail("trailing characters")
+	}
+	return n, nil
+}
+
+// itaniumScope returns the qualifier of the name of the entity.
+func itaniumScope(name string) string {
+	n, err := parseItanium(name)
+	if err != nil {
+		return ""
+	}
+	for {
+		switch x := n.(type) {
+		case *cloneSuffix:
+			n = x.child
+			continue
+		case *encoding:
+			n = x.name
+			continue
+		case *nameWithArgs:
+			n = x.name
+			continue
+		case *abiTagged:
+			n = x.base
+			continue
+		case *nestedName:
+			return nodeString(x.qual)
+		case *localName:
+			return nodeString(x.encoding)
+		}
+		return ""
+	}
+}
+
+func (d *itParser) peek() byte {
+	if d.pos < len(d.s) {
+		return d.s[d.pos]
+	}
+	return 0
+}
+
+func (d *itParser) peekAt(i int) byte {
+	if d.pos+i < len(d.s) {
+		return d.s[d.pos+i]
+	}
+	return 0
+}
+
+func (d *itParser) next() byte {
+	c := d.peek()
+	if c == 0 {
+		fail("unexpected end")
+	}
+	d.pos++
+	return c
+}
+
+func (d *itParser) consume(prefix string) bool {
+	if strings.HasPrefix(d.s[d.pos:], prefix) {
+		d.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (d *itParser) expect(c byte) {
+	if d.next() != c {
+		fail("expected " + string(c))
+	}
+}
+
+func (d *itParser) enter() {
+	d.depth++
+	if d.depth > maxDepth {
+		fail("nesting too deep")
+	}
+}
+
+func (d *itParser) leave() { d.depth-- }
+
+func (d *itParser) number() int {
+	neg := d.consume("n")
+	if !isDigit(d.peek()) {
+		fail("expected number")
+	}
+	n := 0
+	for isDigit(d.peek()) {
+		n = n*10 + int(d.next()-'0')
+		if n > 1<<24 {
+			fail("number too large")
+		}
+	}
+	if neg {
+		return -n
+	}
+	return n
+}
+
+func (d *itParser) numberString() string {
+	start := d.pos
+	d.consume("n")
+	for isDigit(d.peek()) {
+		d.pos++
+	}
+	s := d.s[start:d.pos]
+	if strings.HasPrefix(s, "n") {
+		s = "-" + s[1:]
+	}
+	return s
+}
+
+// seqID parses a base-36 substitution or template parameter index
+// followed by '_'; a bare '_' is index 0.
+func (d *itParser) seqID() int {
+	if d.consume("_") {
+		return 0
+	}
+	n := 0
+	for {
+		c := d.next()
+		switch {
+		case c == '_':
+			return n + 1
+		case isDigit(c):
+			n = n*36 + int(c-'0')
+		case c >= 'A' && c <= 'Z':
+			n = n*36 + int(c-'A') + 10
+		default:
+			fail("bad sequence id")
+		}
+		if n > 1<<24 {
+			fail("sequence id too large")
+		}
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func (d *itParser) cloneSuffixes(n node) node {
+	for d.peek() == '.' {
+		c := d.peekAt(1)
+		if !(c >= 'a' && c <= 'z' || c == '_' || isDigit(c)) {
+			break
+		}
+		start := d.pos
+		d.pos++
+		for c := d.peek(); c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'; c = d.peek() {
+			d.pos++
+		}
+		for d.peek() == '.' && isDigit(d.peekAt(1)) {
+			d.pos++
+			for isDigit(d.peek()) {
+				d.pos++
+			}
+		}
+		n = &cloneSuffix{n, d.s[start:d.pos]}
+	}
+	return n
+}
+
+// encoding parses <encoding>. Function encodings are told apart from
+// data by the presence of parameter types.
+func (d *itParser) encoding(top bool) node {
+	d.enter()
+	defer d.leave()
+
+	if c := d.peek(); c == 'T' || c == 'G' {
+		return d.specialName()
+	}
+
+	savedArgs, savedCV, savedRef := d.tmplArgs, d.cv, d.ref
+	d.cv, d.ref = "", ""
+	defer func() {
+		if !top {
+			d.tmplArgs = savedArgs
+		}
+		d.cv, d.ref = savedCV, savedRef
+	}()
+
+	name := d.name(true)
+	if c := d.peek(); c == 0 || c == 'E' || c == '.' {
+		return name
+	}
+
+	enc := &encoding{name: name, cv: d.cv, ref: d.ref}
+	if hasReturnType(name) {
+		enc.ret = d.typ()
+	}
+	enc.params = d.bareFunctionType()
+	return enc
+}
+
+// hasReturnType reports whether the encoding of a function named n
+// mangles a return type: true for template functions other than
+// constructors, destructors and conversion operators.
+func hasReturnType(n node) bool {
+	switch x := n.(type) {
+	case *localName:
+		return hasReturnType(x.entity)
+	case *nestedName:
+		return hasReturnType(x.name)
+	case *abiTagged:
+		return hasReturnType(x.base)
+	case *nameWithArgs:
+		switch last(x.name).(type) {
+		case *ctorDtorName, *convOperator:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func last(n node) node {
+	switch x := n.(type) {
+	case *nestedName:
+		return last(x.name)
+	case *abiTagged:
+		return last(x.base)
+	}
+	return n
+}
+
+func (d *itParser) bareFunctionType() []node {
+	var params []node
+	for {
+		c := d.peek()
+		if c == 0 || c == 'E' || c == '.' || (c == 'R' || c == 'O') && d.peekAt(1) == 'E' {
+			break
+		}
+		params = append(params, d.typ())
+	}
+	if len(params) == 0 {
+		fail("missing parameters")
+	}
+	if len(params) == 1 {
+		if n, ok := params[0].(*nameNode); ok && n.s == "void" {
+			return nil
+		}
+	}
+	return params
+}
+
+func (d *itParser) specialName() node {
+	switch {
+	case d.consume("TV"):
+		return &specialName{"vtable for ", d.typ()}
+	case d.consume("TT"):
+		return &specialName{"VTT for ", d.typ()}
+	case d.consume("TI"):
+		return &specialName{"typeinfo for ", d.typ()}
+	case d.consume("TS"):
+		return &specialName{"typeinfo name for ", d.typ()}
+	case d.consume("TW"):
+		return &specialName{"TLS wrapper function for ", d.name(false)}
+	case d.consume("TH"):
+		return &specialName{"TLS init function for ", d.name(false)}
+	case d.consume("Th"):
+		d.callOffset('h')
+		return &specialName{"non-virtual thunk to ", d.encoding(false)}
+	case d.consume("Tv"):
+		d.callOffset('v')
+		return &specialName{"virtual thunk to ", d.encoding(false)}
+	case d.consume("Tc"):
+		d.callOffset(d.next())
+		d.callOffset(d.next())
+		return &specialName{"covariant return thunk to ", d.encoding(false)}
+	case d.consume("TC"):
+		derived := d.typ()
+		d.number()
+		d.expect('_')
+		base := d.typ()
+		return &nameNode{"construction vtable for " + nodeString(base) + "-in-" + nodeString(derived)}
+	case d.consume("GV"):
+		return &specialName{"guard variable for ", d.name(false)}
+	case d.consume("GR"):
+		n := d.name(false)
+		if d.peek() != '_' {
+			d.seqID()
+		} else {
+			d.pos++
+		}
+		return &specialName{"reference temporary for ", n}
+	case d.consume("GA"):
+		return &specialName{"hidden alias for ", d.encoding(false)}
+	case d.consume("GTt"):
+		return &specialName{"transaction clone for ", d.encoding(false)}
+	case d.consume("GTn"):
+		return &specialName{"non-transaction clone for ", d.encoding(false)}
+	}
+	fail("unknown special name")
+	return nil
+}
+
+// callOffset skips an <nv-offset> or <v-offset> of a thunk.
+func (d *itParser) callOffset(kind byte) {
+	switch kind {
+	case 'h':
+		d.number()
+		d.expect('_')
+	case 'v':
+		d.number()
+		d.expect('_')
+		d.number()
+		d.expect('_')
+	default:
+		fail("bad call offset")
+	}
+}
+
+// name parses <name>. top is set for the name of the outermost
+// encoding, whose template arguments are the referents of T_.
+func (d *itParser) name(top bool) node {
+	d.enter()
+	defer d.leave()
+
+	switch d.peek() {
+	case 'N':
+		return d.nestedName(top)
+	case 'Z':
+		return d.localName(top)
+	case 'S':
+		if d.peekAt(1) != 't' {
+			sub := d.substitution()
+			if d.peek() != 'I' {
+				fail("substitution used as name")
+			}
+			return &nameWithArgs{sub, d.templateArgs(top)}
+		}
+	}
+
+	n := d.unscopedName()
+	if d.peek() == 'I' {
+		d.subs = append(d.subs, n)
+		n = &nameWithArgs{n, d.templateArgs(top)}
+	}
+	return n
+}
+
+func (d *itParser) unscopedName() node {
+	if d.consume("St") {
+		return &nestedName{&nameNode{"std"}, d.unqualifiedName(nil)}
+	}
+	return d.unqualifiedName(nil)
+}
+
+func (d *itParser) nestedName(top bool) node {
+	d.expect('N')
+	var cv string
+	if d.consume("r") {
+		cv += " restrict"
+	}
+	if d.consume("V") {
+		cv = " volatile" + cv
+	}
+	if d.consume("K") {
+		cv = " const" + cv
+	}
+	ref := ""
+	if d.consume("R") {
+		ref = " &"
+	} else if d.consume("O") {
+		ref = " &&"
+	}
+	if top {
+		d.cv, d.ref = cv, ref
+	}
+
+	var soFar node
+	push := func(n node) {
+		if soFar == nil {
+			soFar = n
+		} else {
+			soFar = &nestedName{soFar, n}
+		}
+	}
+	for !d.consume("E") {
+		switch c := d.peek(); {
+		case c == 'L':
+			d.pos++
+			continue
+		case c == 'M':
+			// closure in the initializer of a data member
+			if soFar == nil {
+				fail("data member prefix without name")
+			}
+			d.pos++
+			continue
+		case c == 'T':
+			if soFar != nil {
+				fail("template parameter inside prefix")
+			}
+			soFar = d.templateParam()
+		case c == 'I':
+			if soFar == nil {
+				fail("template arguments without name")
+			}
+			soFar = &nameWithArgs{soFar, d.templateArgs(top)}
+		case c == 'D' && (d.peekAt(1) == 't' || d.peekAt(1) == 'T'):
+			if soFar != nil {
+				fail("decltype inside prefix")
+			}
+			soFar = d.decltype()
+		case c == 'S' && d.peekAt(1) == 't':
+			d.pos += 2
+			if soFar != nil {
+				fail("std:: inside prefix")
+			}
+			soFar = &nameNode{"std"}
+			continue
+		case c == 'S':
+			if soFar != nil {
+				fail("substitution inside prefix")
+			}
+			soFar = d.substitution()
+			continue
+		case c == 0:
+			fail("unterminated nested name")
+		default:
+			push(d.unqualifiedName(soFar))
+		}
+		if d.peek() != 'E' {
+			d.subs = append(d.subs, soFar)
+		}
+	}
+	if soFar == nil {
+		fail("empty nested name")
+	}
+	return soFar
+}
+
+func (d *itParser) ctorDtorName(class node) node {
+	var n node
+	switch {
+	case d.consume("CI1"), d.consume("CI2"):
+		d.typ()
+		n = &ctorDtorName{class, false}
+	case d.consume("C1"), d.consume("C2"), d.consume("C3"), d.consume("C4"), d.consume("C5"):
+		n = &ctorDtorName{class, false}
+	case d.consume("D0"), d.consume("D1"), d.consume("D2"), d.consume("D4"), d.consume("D5"):
+		n = &ctorDtorName{class, true}
+	default:
+		fail("bad constructor or destructor")
+	}
+	return d.abiTags(n)
+}
+
+func (d *itParser) localName(top bool) node {
+	d.expect('Z')
+	enc := d.encoding(false)
+	d.expect('E')
+	if d.consume("s") {
+		d.discriminator()
+		return &localName{enc, &nameNode{"string literal"}}
+	}
+	var arg node
+	if d.consume("d") {
+		n := 1
+		if isDigit(d.peek()) {
+			n = d.number() + 2
+		}
+		d.expect('_')
+		arg = &nameNode{"{default arg#" + strconv.Itoa(n) + "}"}
+	}
+	entity := d.name(top)
+	d.discriminator()
+	if arg != nil {
+		entity = &nestedName{arg, entity}
+	}
+	return &localName{enc, entity}
+}
+
+func (d *itParser) discriminator() {
+	if d.peek() != '_' {
+		return
+	}
+	if d.consume("__") {
+		d.number()
+		d.expect('_')
+		return
+	}
+	d.pos++
+	if isDigit(d.peek()) {
+		d.pos++
+	}
+}
+
+// unqualifiedName parses <unqualified-name>; scope is the enclosing
+// prefix, if any.
+func (d *itParser) unqualifiedName(scope node) node {
+	var n node
+	switch c := d.peek(); {
+	case isDigit(c):
+		n = &nameNode{d.sourceName()}
+	case c >= 'a' && c <= 'z':
+		n = d.operatorName()
+	case c == 'U':
+		n = d.unnamedTypeName()
+	case c == 'D' && d.peekAt(1) == 'C':
+		d.pos += 2
+		var names []string
+		for !d.consume("E") {
+			names = append(names, d.sourceName())
+		}
+		n = &nameNode{"[" + strings.Join(names, ", ") + "]"}
+	case c == 'C' || c == 'D':
+		if scope == nil {
+			fail("constructor without class")
+		}
+		return d.ctorDtorName(scope)
+	default:
+		fail("bad unqualified name")
+	}
+	return d.abiTags(n)
+}
+
+func (d *itParser) abiTags(n node) node {
+	for d.consume("B") {
+		n = &abiTagged{n, d.sourceName()}
+	}
+	return n
+}
+
+func (d *itParser) sourceName() string {
+	l := d.number()
+	if l <= 0 || d.pos+l > len(d.s) {
+		fail("bad source name length")
+	}
+	id := d.s[d.pos : d.pos+l]
+	d.pos += l
+	if strings.HasPrefix(id, "_GLOBAL_") && len(id) > 9 &&
+		strings.IndexByte("._$", id[8]) >= 0 && id[9] == 'N' {
+		return "(anonymous namespace)"
+	}
+	return id
+}
+
+func (d *itParser) unnamedTypeName() node {
+	switch {
+	case d.consume("Ut"):
+		n := 1
+		if d.peek() != '_' {
+			n = d.number() + 2
+		}
+		d.expect('_')
+		return &nameNode{"{unnamed type#" + strconv.Itoa(n) + "}"}
+	case d.consume("Ul"):
+		var params []node
+		for d.peek() != 'E' {
+			params = append(params, d.typ())
+		}
+		d.pos++
+		if len(params) == 1 {
+			if p, ok := params[0].(*nameNode); ok && p.s == "void" {
+				params = nil
+			}
+		}
+		n := 1
+		if d.peek() != '_' {
+			n = d.number() + 2
+		}
+		d.expect('_')
+		var p printer
+		p.WriteString("{lambda(")
+		printList(&p, params)
+		p.WriteString(")#" + strconv.Itoa(n) + "}")
+		return &nameNode{p.String()}
+	}
+	fail("bad unnamed type")
+	return nil
+}
+
+type operator struct {
+	name  string
+	arity int
+}
+
+var operators = map[string]operator{
+	"nw": {"new", 3}, "na": {"new[]", 3}, "dl": {"delete", 1}, "da": {"delete[]", 1},
+	"ps": {"+", 1}, "ng": {"-", 1}, "ad": {"&", 1}, "de": {"*", 1},
+	"co": {"~", 1}, "pl": {"+", 2}, "mi": {"-", 2}, "ml": {"*", 2},
+	"dv": {"/", 2}, "rm": {"%", 2}, "an": {"&", 2}, "or": {"|", 2},
+	"eo": {"^", 2}, "aS": {"=", 2}, "pL": {"+=", 2}, "mI": {"-=", 2},
+	"mL": {"*=", 2}, "dV": {"/=", 2}, "rM": {"%=", 2}, "aN": {"&=", 2},
+	"oR": {"|=", 2}, "eO": {"^=", 2}, "ls": {"<<", 2}, "rs": {">>", 2},
+	"lS": {"<<=", 2}, "rS": {">>=", 2}, "eq": {"==", 2}, "ne": {"!=", 2},
+	"lt": {"<", 2}, "gt": {">", 2}, "le": {"<=", 2}, "ge": {">=", 2},
+	"ss": {"<=>", 2}, "nt": {"!", 1}, "aa": {"&&", 2}, "oo": {"||", 2},
+	"pp": {"++", 1}, "mm": {"--", 1}, "cm": {",", 2}, "pm": {"->*", 2},
+	"pt": {"->", 2}, "cl": {"()", 2}, "ix": {"[]", 2}, "qu": {"?", 3},
+	"st": {"sizeof ", 1}, "sz": {"sizeof ", 1}, "at": {"alignof ", 1}, "az": {"alignof ", 1},
+	"aw": {"co_await", 1},
+}
+
+func (d *itParser) operatorName() node {
+	switch {
+	case d.consume("cv"):
+		return &convOperator{d.typ()}
+	case d.consume("li"):
+		return &nameNode{"operator\"\" " + d.sourceName()}
+	case d.peek() == 'v' && isDigit(d.peekAt(1)):
+		d.pos += 2
+		return &nameNode{"operator " + d.sourceName()}
+	}
+	if d.pos+2 > len(d.s) {
+		fail("bad operator")
+	}
+	op, ok := operators[d.s[d.pos:d.pos+2]]
+	if !ok {
+		fail("unknown operator")
+	}
+	d.pos += 2
+	name := strings.TrimSpace(op.name)
+	if c := name[0]; c >= 'a' && c <= 'z' {
+		return &nameNode{"operator " + name}
+	}
+	return &nameNode{"operator" + name}
+}
+
+func (d *itParser) substitution() node {
+	d.expect('S')
+	switch d.peek() {
+	case 'a':
+		d.pos++
+		return &specialSub{"std::allocator", "allocator"}
+	case 'b':
+		d.pos++
+		return &specialSub{"std::basic_string", "basic_string"}
+	case 's':
+		d.pos++
+		return &specialSub{"std::basic_string<char, std::char_traits<char>, std::allocator<char> >", "basic_string"}
+	case 'i':
+		d.pos++
+		return &specialSub{"std::basic_istream<char, std::char_traits<char> >", "basic_istream"}
+	case 'o':
+		d.pos++
+		return &specialSub{"std::basic_ostream<char, std::char_traits<char> >", "basic_ostream"}
+	case 'd':
+		d.pos++
+		return &specialSub{"std::basic_iostream<char, std::char_traits<char> >", "basic_iostream"}
+	}
+	i := d.seqID()
+	if i >= len(d.subs) {
+		fail("substitution out of range")
+	}
+	// a substituted template parameter names the argument of the
+	// template it is used in, which differs from the one it was
+	// recorded in when a local name's function is a template
+	if param, ok := d.subParams[i]; ok {
+		return d.resolveParam(param)
+	}
+	return d.subs[i]
+}
+
+func (d *itParser) templateParam() node {
+	d.expect('T')
+	return d.resolveParam(d.seqID())
+}
+
+// resolveParam returns template argument i of the current template.
+func (d *itParser) resolveParam(i int) node {
+	if i < len(d.tmplArgs) {
+		if pack, ok := d.tmplArgs[i].(*argPack); ok {
+			return &paramPack{pack}
+		}
+		return d.tmplArgs[i]
+	}
+	if i == 0 {
+		return &nameNode{"T_"}
+	}
+	return &nameNode{"T" + strconv.Itoa(i-1) + "_"}
+}
+
+func (d *itParser) templateArgs(top bool) *templateArgs {
+	d.enter()
+	defer d.leave()
+
+	d.expect('I')
+	args := &templateArgs{}
+	for !d.consume("E") {
+		args.args = append(args.args, d.templateArg())
+	}
+	if top {
+		d.tmplArgs = args.args
+	}
+	return args
+}
+
+func (d *itParser) templateArg() node {
+	switch d.peek() {
+	case 'X':
+		d.pos++
+		e := d.expression()
+		d.expect('E')
+		return e
+	case 'L':
+		return d.exprPrimary()
+	case 'J':
+		d.pos++
+		pack := &argPack{}
+		for !d.consume("E") {
+			pack.args = append(pack.args, d.templateArg())
+		}
+		return pack
+	}
+	return d.typ()
+}
+
+var builtinTypes = map[byte]string{
+	'v': "void", 'w': "wchar_t", 'b': "bool", 'c': "char", 'a': "signed char",
+	'h': "unsigned char", 's': "short", 't': "unsigned short", 'i': "int",
+	'j': "unsigned int", 'l': "long", 'm': "unsigned long", 'x': "long long",
+	'y': "unsigned long long", 'n': "__int128", 'o': "unsigned __int128",
+	'f': "float", 'd': "double", 'e': "long double", 'g': "__float128", 'z': "...",
+}
+
+var builtinDTypes = map[byte]string{
+	'd': "decimal64", 'e': "decimal128", 'f': "decimal32", 'h': "half",
+	'i': "char32_t", 's': "char16_t", 'u': "char8_t", 'a': "auto",
+	'c': "decltype(auto)", 'n': "decltype(nullptr)",
+}
+
+func (d *itParser) typ() node {
+	d.enter()
+	defer d.leave()
+
+	c := d.peek()
+	if s, ok := builtinTypes[c]; ok {
+		d.pos++
+		return &nameNode{s}
+	}
+
+	var n node
+	switch c {
+	case 'r', 'V', 'K':
+		var quals string
+		if d.consume("r") {
+			quals += " restrict"
+		}
+		if d.consume("V") {
+			quals = " volatile" + quals
+		}
+		if d.consume("K") {
+			quals = " const" + quals
+		}
+		// qualifiers of a function type apply to its this
+		// parameter, so the unqualified type is no substitution
+		var child node
+		if d.peek() == 'F' || d.peek() == 'D' && strings.IndexByte("oOwx", d.peekAt(1)) >= 0 {
+			child = d.functionType()
+		} else {
+			child = d.typ()
+		}
+		switch c := child.(type) {
+		case *functionType:
+			q := *c
+			q.cv = quals + q.cv
+			n = &q
+		case *qualType:
+			// a qualifier applied twice through a template
+			// parameter counts once
+			merged := c.quals
+			for _, q := range []string{" const", " volatile", " restrict"} {
+				if strings.Contains(quals, q) && !strings.Contains(merged, q) {
+					merged += q
+				}
+			}
+			n = &qualType{c.child, merged}
+		default:
+			n = &qualType{child, quals}
+		}
+	case 'U':
+		d.pos++
+		qual := d.sourceName()
+		if d.peek() == 'I' {
+			qual += nodeString(d.templateArgs(false))
+		}
+		n = &vendorQual{d.typ(), qual}
+	case 'u':
+		d.pos++
+		return &nameNode{d.sourceName()}
+	case 'D':
+		if s, ok := builtinDTypes[d.peekAt(1)]; ok {
+			d.pos += 2
+			return &nameNode{s}
+		}
+		switch d.peekAt(1) {
+		case 'F':
+			d.pos += 2
+			bits := d.numberString()
+			d.expect('_')
+			return &nameNode{"_Float" + bits}
+		case 't', 'T':
+			n = d.decltype()
+		case 'p':
+			d.pos += 2
+			n = &packExpansion{d.typ()}
+		case 'v':
+			d.pos += 2
+			var dim string
+			if isDigit(d.peek()) {
+				dim = d.numberString()
+			} else {
+				d.expect('_')
+				dim = nodeString(d.expression())
+			}
+			d.expect('_')
+			n = &vectorType{d.typ(), dim}
+		case 'o', 'O', 'w', 'x':
+			n = d.functionType()
+		default:
+			fail("unknown D type")
+		}
+	case 'F':
+		n = d.functionType()
+	case 'A':
+		d.pos++
+		var dim string
+		switch {
+		case isDigit(d.peek()):
+			dim = d.numberString()
+		case d.peek() != '_':
+			dim = nodeString(d.expression())
+		}
+		d.expect('_')
+		n = &arrayType{d.typ(), dim}
+	case 'M':
+		d.pos++
+		class := d.typ()
+		n = &ptrToMember{class, d.typ()}
+	case 'T':
+		d.pos++
+		param := d.seqID()
+		n = d.resolveParam(param)
+		if d.peek() != 'I' {
+			if d.subParams == nil {
+				d.subParams = make(map[int]int)
+			}
+			d.subParams[len(d.subs)] = param
+		} else {
+			d.subs = append(d.subs, n)
+			n = &nameWithArgs{n, d.templateArgs(false)}
+		}
+	case 'P':
+		d.pos++
+		n = &pointerType{d.typ()}
+	case 'R':
+		d.pos++
+		n = &referenceType{d.typ(), false}
+	case 'O':
+		d.pos++
+		n = &referenceType{d.typ(), true}
+	case 'C':
+		d.pos++
+		n = &vendorQual{d.typ(), "_Complex"}
+	case 'G':
+		d.pos++
+		n = &vendorQual{d.typ(), "_Imaginary"}
+	case 'S':
+		if d.peekAt(1) != 't' {
+			sub := d.substitution()
+			if d.peek() != 'I' {
+				return sub
+			}
+			n = &nameWithArgs{sub, d.templateArgs(false)}
+			break
+		}
+		n = d.name(false)
+	default:
+		n = d.name(false)
+	}
+	d.subs = append(d.subs, n)
+	return n
+}
+
+func (d *itParser) functionType() node {
+	var noexcept string
+	switch {
+	case d.consume("Do"):
+		noexcept = " noexcept"
+	case d.consume("DO"):
+		noexcept = " noexcept(" + nodeString(d.expression()) + ")"
+		d.expect('E')
+	case d.consume("Dw"):
+		var l []node
+		for !d.consume("E") {
+			l = append(l, d.typ())
+		}
+		var p printer
+		printList(&p, l)
+		noexcept = " throw(" + p.String() + ")"
+	}
+	d.consume("Dx")
+	d.expect('F')
+	d.consume("Y")
+	fn := &functionType{ret: d.typ(), noexcept: noexcept}
+	for {
+		if d.consume("E") {
+			break
+		}
+		if d.consume("RE") {
+			fn.ref = " &"
+			break
+		}
+		if d.consume("OE") {
+			fn.ref = " &&"
+			break
+		}
+		fn.params = append(fn.params, d.typ())
+	}
+	if len(fn.params) == 1 {
+		if n, ok := fn.params[0].(*nameNode); ok && n.s == "void" {
+			fn.params = nil
+		}
+	}
+	return fn
+}
+
+func (d *itParser) decltype() node {
+	d.expect('D')
+	if c := d.next(); c != 't' && c != 'T' {
+		fail("bad decltype")
+	}
+	e := d.expression()
+	d.expect('E')
+	return &nameNode{"decltype (" + nodeString(e) + ")"}
+}
+
+// exprPrimary parses a literal: L <type> <value> E or L _Z <encoding> E.
+func (d *itParser) exprPrimary() node {
+	d.expect('L')
+	if d.consume("_Z") {
+		n := d.encoding(false)
+		d.expect('E')
+		return n
+	}
+	if d.consume("Z") {
+		n := d.encoding(false)
+		d.expect('E')
+		return n
+	}
+	t := d.typ()
+	start := d.pos
+	for d.peek() != 'E' {
+		d.next()
+	}
+	value := d.s[start:d.pos]
+	d.pos++
+	if strings.HasPrefix(value, "n") {
+		value = "-" + value[1:]
+	}
+
+	name := ""
+	if n, ok := t.(*nameNode); ok {
+		name = n.s
+	}
+	switch name {
+	case "bool":
+		switch value {
+		case "0":
+			return &nameNode{"false"}
+		case "1":
+			return &nameNode{"true"}
+		}
+	case "int":
+		return &nameNode{value}
+	case "unsigned int":
+		return &nameNode{value + "u"}
+	case "long":
+		return &nameNode{value + "l"}
+	case "unsigned long":
+		return &nameNode{value + "ul"}
+	case "long long":
+		return &nameNode{value + "ll"}
+	case "unsigned long long":
+		return &nameNode{value + "ull"}
+	case "decltype(nullptr)":
+		return &nameNode{"nullptr"}
+	}
+	if value == "" {
+		return &nameNode{"(" + nodeString(t) + ")"}
+	}
+	if name == "float" || name == "double" || name == "long double" {
+		return &nameNode{"(" + name + ")[" + value + "]"}
+	}
+	return &nameNode{"(" + nodeString(t) + ")" + value}
+}
+
+// expression parses a template argument expression and renders it in
+// the fully parenthesized style of c++filt.
+func (d *itParser) expression() node {
+	d.enter()
+	defer d.leave()
+
+	switch c := d.peek(); {
+	case c == 'L':
+		return d.exprPrimary()
+	case c == 'T':
+		return d.templateParam()
+	case d.consume("fp"):
+		d.cvQuals()
+		n := 1
+		if d.peek() != '_' {
+			n = d.number() + 2
+		}
+		d.expect('_')
+		return &simpleExpr{"{parm#" + strconv.Itoa(n) + "}"}
+	case d.consume("fL"):
+		d.number()
+		d.expect('p')
+		d.cvQuals()
+		n := 1
+		if d.peek() != '_' {
+			n = d.number() + 2
+		}
+		d.expect('_')
+		return &simpleExpr{"{parm#" + strconv.Itoa(n) + "}"}
+	case d.consume("st"):
+		return &nameNode{"sizeof (" + nodeString(d.typ()) + ")"}
+	case d.consume("at"):
+		return &nameNode{"alignof (" + nodeString(d.typ()) + ")"}
+	case d.consume("sZ"):
+		var n node
+		if d.peek() == 'T' {
+			n = d.templateParam()
+		} else {
+			n = d.expression()
+		}
+		return &nameNode{"sizeof...(" + nodeString(n) + ")"}
+	case d.consume("sp"):
+		return &packExpansion{d.expression()}
+	case d.consume("tw"):
+		return &nameNode{"throw " + nodeString(d.expression())}
+	case d.consume("tr"):
+		return &nameNode{"throw"}
+	case d.consume("te"):
+		return &nameNode{"typeid (" + nodeString(d.expression()) + ")"}
+	case d.consume("ti"):
+		return &nameNode{"typeid (" + nodeString(d.typ()) + ")"}
+	case d.consume("nx"):
+		return &nameNode{"noexcept (" + nodeString(d.expression()) + ")"}
+	case d.consume("dc"), d.consume("sc"), d.consume("cc"), d.consume("rc"):
+		kind := map[string]string{"dc": "dynamic_cast", "sc": "static_cast",
+			"cc": "const_cast", "rc": "reinterpret_cast"}[d.s[d.pos-2:d.pos]]
+		t := d.typ()
+		return &nameNode{kind + "<" + nodeString(t) + ">(" + nodeString(d.expression()) + ")"}
+	case d.consume("cv"):
+		t := d.typ()
+		var args []node
+		if d.consume("_") {
+			for !d.consume("E") {
+				args = append(args, d.expression())
+			}
+		} else {
+			return &nameNode{"(" + nodeString(t) + ")" + subexpr(d.expression())}
+		}
+		var p printer
+		printList(&p, args)
+		return &nameNode{"(" + nodeString(t) + ")(" + p.String() + ")"}
+	case d.consume("tl"):
+		t := d.typ()
+		var args []node
+		for !d.consume("E") {
+			args = append(args, d.expression())
+		}
+		var p printer
+		printList(&p, args)
+		return &nameNode{nodeString(t) + "{" + p.String() + "}"}
+	case d.consume("il"):
+		var args []node
+		for !d.consume("E") {
+			args = append(args, d.expression())
+		}
+		var p printer
+		printList(&p, args)
+		return &simpleExpr{"{" + p.String() + "}"}
+	case d.consume("cl"):
+		fn := d.expression()
+		var args []node
+		for !d.consume("E") {
+			args = append(args, d.expression())
+		}
+		var p printer
+		printList(&p, args)
+		return &nameNode{subexpr(fn) + "(" + p.String() + ")"}
+	case d.consume("dt"), d.consume("pt"):
+		sep := "."
+		if d.s[d.pos-2:d.pos] == "pt" {
+			sep = "->"
+		}
+		obj := d.expression()
+		return &nameNode{subexpr(obj) + sep + subexpr(d.unresolvedName())}
+	case d.consume("gs"):
+		return &nameNode{"::" + nodeString(d.expression())}
+	case c == 's' && d.peekAt(1) == 'r', c == 'd' && d.peekAt(1) == 'n', c == 'o' && d.peekAt(1) == 'n':
+		return d.unresolvedName()
+	case isDigit(c):
+		return d.unresolvedName()
+	}
+
+	if d.pos+2 > len(d.s) {
+		fail("bad expression")
+	}
+	op, ok := operators[d.s[d.pos:d.pos+2]]
+	if !ok {
+		fail("unknown expression")
+	}
+	d.pos += 2
+	switch op.arity {
+	case 1:
+		if op.name == "++" || op.name == "--" {
+			if d.consume("_") {
+				return &nameNode{op.name + subexpr(d.expression())}
+			}
+			return &nameNode{subexpr(d.expression()) + op.name}
+		}
+		operand := d.expression()
+		// the address of a function is printed without its
+		// parameter list
+		if enc, ok := operand.(*encoding); ok && op.name == "&" && enc.ret == nil {
+			operand = &simpleExpr{nodeString(enc.name)}
+		}
+		return &nameNode{op.name + subexpr(operand)}
+	case 2:
+		a := d.expression()
+		b := d.expression()
+		s := subexpr(a) + op.name + subexpr(b)
+		if op.name == ">" {
+			s = "(" + s + ")"
+		}
+		return &nameNode{s}
+	case 3:
+		if op.name != "?" {
+			fail("unsupported new expression")
+		}
+		a := d.expression()
+		b := d.expression()
+		c := d.expression()
+		return &nameNode{subexpr(a) + "?" + subexpr(b) + " : " + subexpr(c)}
+	}
+	fail("bad expression")
+	return nil
+}
+
+// simpleExpr is an expression that c++filt prints without
+// parentheses when it is an operand: a name, a function parameter or
+// an initializer list.
+type simpleExpr struct {
+	s string
+}
+
+func (n *simpleExpr) printLeft(p *printer)  { p.WriteString(n.s) }
+func (n *simpleExpr) printRight(p *printer) {}
+
+// subexpr renders the operand n of an expression.
+func subexpr(n node) string {
+	if _, ok := n.(*simpleExpr); ok {
+		return nodeString(n)
+	}
+	return "(" + nodeString(n) + ")"
+}
+
+func (d *itParser) cvQuals() {
+	d.consume("r")
+	d.consume("V")
+	d.consume("K")
+}
+
+// unresolvedName parses the names used in dependent expressions.
+func (d *itParser) unresolvedName() node {
+	global := d.consume("gs")
+	var parts []string
+	if d.consume("sr") {
+		if d.consume("N") {
+			parts = append(parts, nodeString(d.unresolvedType()))
+			for !d.consume("E") {
+				parts = append(parts, nodeString(d.simpleID()))
+			}
+		} else if isDigit(d.peek()) {
+			for !d.consume("E") {
+				parts = append(parts, nodeString(d.simpleID()))
+			}
+		} else {
+			parts = append(parts, nodeString(d.unresolvedType()))
+		}
+	}
+	base := d.baseUnresolvedName()
+	parts = append(parts, nodeString(base))
+	s := strings.Join(parts, "::")
+	if global {
+		s = "::" + s
+	}
+	if _, ok := base.(*nameWithArgs); ok {
+		return &nameNode{s}
+	}
+	return &simpleExpr{s}
+}
+
+func (d *itParser) unresolvedType() node {
+	switch d.peek() {
+	case 'T':
+		n := d.templateParam()
+		if d.peek() == 'I' {
+			n = &nameWithArgs{n, d.templateArgs(false)}
+		}
+		d.subs = append(d.subs, n)
+		return n
+	case 'D':
+		n := d.decltype()
+		d.subs = append(d.subs, n)
+		return n
+	case 'S':
+		return d.typ()
+	}
+	return d.simpleID()
+}
+
+func (d *itParser) simpleID() node {
+	n := node(&nameNode{d.sourceName()})
+	if d.peek() == 'I' {
+		n = &nameWithArgs{n, d.templateArgs(false)}
+	}
+	return n
+}
+
+func (d *itParser) baseUnresolvedName() node {
+	switch {
+	case isDigit(d.peek()):
+		return d.simpleID()
+	case d.consume("dn"):
+		if isDigit(d.peek()) {
+			return &nameNode{"~" + nodeString(d.simpleID())}
+		}
+		return &nameNode{"~" + nodeString(d.unresolvedType())}
+	}
+	d.consume("on")
+	n := d.operatorName()
+	if d.peek() == 'I' {
+		n = &nameWithArgs{n, d.templateArgs(false)}
+	}
+	return n
+}
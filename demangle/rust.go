@@ -0,0 +1,715 @@
+package demangle
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rust hashes are left out of the demangled output: "h" followed by
+// 16 hex digits for legacy symbols and crate disambiguators for v0.
+
+// legacyComponents splits a legacy Rust symbol "_ZN...E" into its
+// path components, or returns nil if it is not well-formed.
+func legacyComponents(name string) []string {
+	if !strings.HasPrefix(name, "_ZN") {
+		return nil
+	}
+	s := name[3:]
+	// drop an LLVM suffix such as ".llvm.1234"
+	if i := strings.Index(s, "E."); i >= 0 {
+		s = s[:i+1]
+	}
+	var parts []string
+	for len(s) > 0 && s[0] != 'E' {
+		n, i := 0, 0
+		for i < len(s) && isDigit(s[i]) {
+			n = n*10 + int(s[i]-'0')
+			if n > len(s) {
+				return nil
+			}
+			i++
+		}
+		if i == 0 || n == 0 || i+n > len(s) {
+			return nil
+		}
+		parts = append(parts, s[i:i+n])
+		s = s[i+n:]
+	}
+	if s != "E" || len(parts) == 0 {
+		return nil
+	}
+	return parts
+}
+
+func isRustLegacy(name string) bool {
+	parts := legacyComponents(name)
+	if len(parts) < 2 || !isRustHash(parts[len(parts)-1]) {
+		return false
+	}
+	for _, p := range parts {
+		for i := 0; i < len(p); i++ {
+			c := p[i]
+			if !(c == '_' || c == '$' || c == '.' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isRustHash(s string) bool {
+	if len(s) != 17 || s[0] != 'h' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !isDigit(c) && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func rustLegacy(name string) (string, error) {
+	parts := legacyComponents(name)
+	parts = parts[:len(parts)-1]
+	for i, p := range parts {
+		s, ok := unescapeLegacy(p)
+		if !ok {
+			return "", ErrNotMangledName
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, "::"), nil
+}
+
+func rustLegacyScope(name string) string {
+	parts := legacyComponents(name)
+	if len(parts) < 3 {
+		return ""
+	}
+	var scope []string
+	for _, p := range parts[:len(parts)-2] {
+		s, ok := unescapeLegacy(p)
+		if !ok {
+			return ""
+		}
+		scope = append(scope, s)
+	}
+	return strings.Join(scope, "::")
+}
+
+var legacyEscapes = map[string]string{
+	"SP": "@", "BP": "*", "RF": "&", "LT": "<", "GT": ">",
+	"LP": "(", "RP": ")", "C": ",",
+}
+
+// unescapeLegacy decodes the $..$ escapes and ".." separators of a
+// legacy Rust path component.
+func unescapeLegacy(s string) (string, bool) {
+	if strings.HasPrefix(s, "_$") {
+		s = s[1:]
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		switch {
+		case s[0] == '$':
+			end := strings.IndexByte(s[1:], '$')
+			if end < 0 {
+				return "", false
+			}
+			esc := s[1 : end+1]
+			s = s[end+2:]
+			if r, ok := legacyEscapes[esc]; ok {
+				b.WriteString(r)
+				continue
+			}
+			if !strings.HasPrefix(esc, "u") {
+				return "", false
+			}
+			v, err := strconv.ParseUint(esc[1:], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(v)) {
+				return "", false
+			}
+			b.WriteRune(rune(v))
+		case strings.HasPrefix(s, ".."):
+			b.WriteString("::")
+			s = s[2:]
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	return b.String(), true
+}
+
+// v0Parser demangles symbols of the Rust v0 mangling scheme ("_R").
+type v0Parser struct {
+	s     string
+	pos   int
+	depth int
+	out   strings.Builder
+
+	// number of lifetimes bound by the enclosing binders
+	boundLifetimes int
+}
+
+func rustV0(name string) (out string, err error) {
+	defer catch(&err)
+	p := newV0Parser(name)
+	p.path(true)
+	p.finish()
+	return p.out.String(), nil
+}
+
+func rustV0Scope(name string) (scope string) {
+	defer func() {
+		if recover() != nil {
+			scope = ""
+		}
+	}()
+	p := newV0Parser(name)
+	// the scope is everything but the final "N" element
+	if p.peek() != 'N' {
+		return ""
+	}
+	p.pos++
+	p.pos++ // namespace
+	p.path(true)
+	return p.out.String()
+}
+
+func newV0Parser(name string) *v0Parser {
+	s := name[2:]
+	// drop a vendor suffix such as ".llvm.1234"
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	p := &v0Parser{s: s}
+	// optional encoding version
+	if isDigit(p.peek()) {
+		p.decimal()
+	}
+	return p
+}
+
+func (p *v0Parser) finish() {
+	// an optional instantiating crate follows the path
+	if p.pos < len(p.s) {
+		p.skipPath()
+	}
+	if p.pos != len(p.s) {
+		fail("trailing characters")
+	}
+}
+
+func (p *v0Parser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *v0Parser) next() byte {
+	c := p.peek()
+	if c == 0 {
+		fail("unexpected end")
+	}
+	p.pos++
+	return c
+}
+
+func (p *v0Parser) consume(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *v0Parser) enter() {
+	p.depth++
+	if p.depth > maxDepth {
+		fail("nesting too deep")
+	}
+}
+
+func (p *v0Parser) leave() { p.depth-- }
+
+func (p *v0Parser) decimal() int {
+	if !isDigit(p.peek()) {
+		fail("expected number")
+	}
+	if p.peek() == '0' {
+		p.pos++
+		return 0
+	}
+	n := 0
+	for isDigit(p.peek()) {
+		n = n*10 + int(p.next()-'0')
+		if n > 1<<24 {
+			fail("number too large")
+		}
+	}
+	return n
+}
+
+// base62 parses <base-62-number>: "_" is 0, otherwise the digits
+// encode the value minus one.
+func (p *v0Parser) base62() uint64 {
+	if p.consume('_') {
+		return 0
+	}
+	var n uint64
+	for {
+		c := p.next()
+		var v uint64
+		switch {
+		case c == '_':
+			return n + 1
+		case isDigit(c):
+			v = uint64(c - '0')
+		case c >= 'a' && c <= 'z':
+			v = uint64(c-'a') + 10
+		case c >= 'A' && c <= 'Z':
+			v = uint64(c-'A') + 36
+		default:
+			fail("bad base-62 number")
+		}
+		// disambiguators are hashes that may use all 64 bits,
+		// so the value is allowed to wrap
+		n = n*62 + v
+	}
+}
+
+func (p *v0Parser) disambiguator() uint64 {
+	if p.consume('s') {
+		return p.base62() + 1
+	}
+	return 0
+}
+
+func (p *v0Parser) ident() string {
+	puny := p.consume('u')
+	n := p.decimal()
+	p.consume('_')
+	if p.pos+n > len(p.s) {
+		fail("identifier too long")
+	}
+	id := p.s[p.pos : p.pos+n]
+	p.pos += n
+	if puny {
+		return punycode(id)
+	}
+	return id
+}
+
+// backref runs f with the parser moved to the target of a backref.
+func (p *v0Parser) backref(f func()) {
+	start := p.pos - 1
+	target := int(p.base62())
+	if target >= start {
+		fail("forward backref")
+	}
+	saved := p.pos
+	p.pos = target
+	f()
+	p.pos = saved
+}
+
+// path prints <path>. Generic arguments in value paths are written
+// with the turbofish "::<>".
+func (p *v0Parser) path(inValue bool) {
+	p.enter()
+	defer p.leave()
+
+	switch p.next() {
+	case 'C':
+		p.disambiguator()
+		p.out.WriteString(p.ident())
+	case 'M':
+		p.disambiguator()
+		p.skipPath()
+		p.out.WriteByte('<')
+		p.typ()
+		p.out.WriteByte('>')
+	case 'X':
+		p.disambiguator()
+		p.skipPath()
+		p.out.WriteByte('<')
+		p.typ()
+		p.out.WriteString(" as ")
+		p.path(false)
+		p.out.WriteByte('>')
+	case 'Y':
+		p.out.WriteByte('<')
+		p.typ()
+		p.out.WriteString(" as ")
+		p.path(false)
+		p.out.WriteByte('>')
+	case 'N':
+		ns := p.next()
+		p.path(inValue)
+		dis := p.disambiguator()
+		name := p.ident()
+		switch {
+		case ns >= 'a' && ns <= 'z':
+			p.out.WriteString("::" + name)
+		default:
+			p.out.WriteString("::{")
+			switch ns {
+			case 'C':
+				p.out.WriteString("closure")
+			case 'S':
+				p.out.WriteString("shim")
+			default:
+				p.out.WriteByte(ns)
+			}
+			if name != "" {
+				p.out.WriteString(":" + name)
+			}
+			p.out.WriteString("#" + strconv.FormatUint(dis, 10) + "}")
+		}
+	case 'I':
+		p.path(inValue)
+		if inValue {
+			p.out.WriteString("::")
+		}
+		p.out.WriteByte('<')
+		for i := 0; !p.consume('E'); i++ {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.genericArg()
+		}
+		p.out.WriteByte('>')
+	case 'B':
+		p.backref(func() { p.path(inValue) })
+	default:
+		fail("bad path")
+	}
+}
+
+// skipPath parses a path without printing it.
+func (p *v0Parser) skipPath() {
+	saved := p.out.String()
+	p.path(false)
+	p.out.Reset()
+	p.out.WriteString(saved)
+}
+
+func (p *v0Parser) genericArg() {
+	switch {
+	case p.consume('L'):
+		p.lifetime(p.base62())
+	case p.consume('K'):
+		p.constant()
+	default:
+		p.typ()
+	}
+}
+
+func (p *v0Parser) lifetime(i uint64) {
+	if i == 0 {
+		p.out.WriteString("'_")
+		return
+	}
+	depth := p.boundLifetimes - int(i)
+	if depth < 0 {
+		fail("unbound lifetime")
+	}
+	p.out.WriteString(lifetimeName(depth))
+}
+
+func lifetimeName(depth int) string {
+	if depth < 26 {
+		return "'" + string(rune('a'+depth))
+	}
+	return "'_" + strconv.Itoa(depth)
+}
+
+var v0Basic = map[byte]string{
+	'a': "i8", 'b': "bool", 'c': "char", 'd': "f64", 'e': "str", 'f': "f32",
+	'h': "u8", 'i': "isize", 'j': "usize", 'l': "i32", 'm': "u32", 'n': "i128",
+	'o': "u128", 's': "i16", 't': "u16", 'u': "()", 'v': "...", 'x': "i64",
+	'y': "u64", 'z': "!", 'p': "_",
+}
+
+func (p *v0Parser) typ() {
+	p.enter()
+	defer p.leave()
+
+	c := p.next()
+	if s, ok := v0Basic[c]; ok {
+		p.out.WriteString(s)
+		return
+	}
+	switch c {
+	case 'A':
+		p.out.WriteByte('[')
+		p.typ()
+		p.out.WriteString("; ")
+		p.constant()
+		p.out.WriteByte(']')
+	case 'S':
+		p.out.WriteByte('[')
+		p.typ()
+		p.out.WriteByte(']')
+	case 'T':
+		p.out.WriteByte('(')
+		n := 0
+		for ; !p.consume('E'); n++ {
+			if n > 0 {
+				p.out.WriteString(", ")
+			}
+			p.typ()
+		}
+		if n == 1 {
+			p.out.WriteByte(',')
+		}
+		p.out.WriteByte(')')
+	case 'R', 'Q':
+		p.out.WriteByte('&')
+		if p.consume('L') {
+			if i := p.base62(); i != 0 {
+				p.lifetime(i)
+				p.out.WriteByte(' ')
+			}
+		}
+		if c == 'Q' {
+			p.out.WriteString("mut ")
+		}
+		p.typ()
+	case 'P':
+		p.out.WriteString("*const ")
+		p.typ()
+	case 'O':
+		p.out.WriteString("*mut ")
+		p.typ()
+	case 'F':
+		p.fnSig()
+	case 'D':
+		p.dynBounds()
+		if !p.consume('L') {
+			fail("missing dyn lifetime")
+		}
+		if i := p.base62(); i != 0 {
+			p.out.WriteString(" + ")
+			p.lifetime(i)
+		}
+	case 'B':
+		p.backref(p.typ)
+	default:
+		p.pos--
+		p.path(false)
+	}
+}
+
+// binder parses an optional for<'a, ...> binder and returns the
+// number of lifetimes it introduced.
+func (p *v0Parser) binder() int {
+	if !p.consume('G') {
+		return 0
+	}
+	n := int(p.base62()) + 1
+	p.out.WriteString("for<")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.out.WriteString(lifetimeName(p.boundLifetimes + i))
+	}
+	p.out.WriteString("> ")
+	p.boundLifetimes += n
+	return n
+}
+
+func (p *v0Parser) fnSig() {
+	bound := p.binder()
+	defer func() { p.boundLifetimes -= bound }()
+
+	if p.consume('U') {
+		p.out.WriteString("unsafe ")
+	}
+	if p.consume('K') {
+		abi := "C"
+		if !p.consume('C') {
+			abi = strings.ReplaceAll(p.ident(), "_", "-")
+		}
+		p.out.WriteString("extern \"" + abi + "\" ")
+	}
+	p.out.WriteString("fn(")
+	for i := 0; !p.consume('E'); i++ {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.typ()
+	}
+	p.out.WriteByte(')')
+	if p.peek() == 'u' {
+		p.pos++
+		return
+	}
+	p.out.WriteString(" -> ")
+	p.typ()
+}
+
+func (p *v0Parser) dynBounds() {
+	bound := p.binder()
+	defer func() { p.boundLifetimes -= bound }()
+
+	p.out.WriteString("dyn ")
+	for i := 0; !p.consume('E'); i++ {
+		if i > 0 {
+			p.out.WriteString(" + ")
+		}
+		p.dynTrait()
+	}
+}
+
+func (p *v0Parser) dynTrait() {
+	// the trait path may carry generic arguments that are continued
+	// by associated type bindings
+	p.path(false)
+	open := false
+	for p.consume('p') {
+		if !open {
+			s := p.out.String()
+			if strings.HasSuffix(s, ">") {
+				p.out.Reset()
+				p.out.WriteString(s[:len(s)-1] + ", ")
+			} else {
+				p.out.WriteByte('<')
+			}
+			open = true
+		} else {
+			p.out.WriteString(", ")
+		}
+		p.out.WriteString(p.ident() + " = ")
+		p.typ()
+	}
+	if open {
+		p.out.WriteByte('>')
+	}
+}
+
+func (p *v0Parser) constant() {
+	p.enter()
+	defer p.leave()
+
+	if p.consume('p') {
+		p.out.WriteByte('_')
+		return
+	}
+	if p.consume('B') {
+		p.backref(p.constant)
+		return
+	}
+	ty := p.next()
+	neg := false
+	if ty != 'b' && ty != 'c' && p.consume('n') {
+		neg = true
+	}
+	start := p.pos
+	for p.peek() != '_' {
+		c := p.next()
+		if !isDigit(c) && !(c >= 'a' && c <= 'f') {
+			fail("bad constant")
+		}
+	}
+	hex := p.s[start:p.pos]
+	p.pos++
+	v, err := strconv.ParseUint("0"+hex, 16, 64)
+	if err != nil {
+		p.out.WriteString("0x" + hex)
+		return
+	}
+	switch ty {
+	case 'b':
+		p.out.WriteString(strconv.FormatBool(v != 0))
+	case 'c':
+		p.out.WriteString(strconv.QuoteRune(rune(v)))
+	default:
+		if neg {
+			p.out.WriteByte('-')
+		}
+		p.out.WriteString(strconv.FormatUint(v, 10))
+	}
+}
+
+// punycode decodes a Rust punycode identifier, in which the basic
+// code points and the encoded deltas are separated by the last '_'.
+func punycode(s string) string {
+	const (
+		base        = 36
+		tMin        = 1
+		tMax        = 26
+		skew        = 38
+		damp        = 700
+		initialBias = 72
+		initialN    = 128
+	)
+	var out []rune
+	if i := strings.LastIndexByte(s, '_'); i >= 0 {
+		out = []rune(s[:i])
+		s = s[i+1:]
+	}
+	n, bias, i := initialN, initialBias, 0
+	for len(s) > 0 {
+		oldi, w := i, 1
+		for k := base; ; k += base {
+			if len(s) == 0 {
+				fail("bad punycode")
+			}
+			c := s[0]
+			s = s[1:]
+			var digit int
+			switch {
+			case c >= 'a' && c <= 'z':
+				digit = int(c - 'a')
+			case isDigit(c):
+				digit = int(c-'0') + 26
+			default:
+				fail("bad punycode digit")
+			}
+			i += digit * w
+			t := k - bias
+			if t < tMin {
+				t = tMin
+			} else if t > tMax {
+				t = tMax
+			}
+			if digit < t {
+				break
+			}
+			w *= base - t
+			if i > 1<<30 || w > 1<<30 {
+				fail("punycode overflow")
+			}
+		}
+		// adapt the bias
+		delta := i - oldi
+		if oldi == 0 {
+			delta /= damp
+		} else {
+			delta /= 2
+		}
+		delta += delta / (len(out) + 1)
+		k := 0
+		for delta > ((base-tMin)*tMax)/2 {
+			delta /= base - tMin
+			k += base
+		}
+		bias = k + (base-tMin+1)*delta/(delta+skew)
+
+		n += i / (len(out) + 1)
+		i %= len(out) + 1
+		if !utf8.ValidRune(rune(n)) {
+			fail("bad punycode code point")
+		}
+		out = append(out[:i], append([]rune{rune(n)}, out[i:]...)...)
+		i++
+	}
+	return string(out)
+}
@@ -0,0 +1,1350 @@
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// arm64Dec decodes one AArch64 instruction word.
+type arm64Dec struct {
+	d  *Decoder
+	w  uint32
+	pc uint64
+
+	args      []string
+	target    uint64
+	hasTarget bool
+	ref       uint64
+	hasRef    bool
+	// page is set by adrp for the register it writes
+	page     uint64
+	pageReg  int
+	setsPage bool
+}
+
+// decodeARM64 decodes the instruction word at b.
+func (d *Decoder) decodeARM64(b []byte, pc uint64) Inst {
+	if len(b) < 4 {
+		return Inst{Len: len(b), Op: "(bad)"}
+	}
+	a := &arm64Dec{d: d, w: binary.LittleEndian.Uint32(b), pc: pc}
+	op := a.decode()
+	if op == "" {
+		op, a.args = ".inst", []string{fmt.Sprintf("0x%08x", a.w)}
+		a.hasTarget, a.hasRef, a.setsPage = false, false, false
+	}
+	// whatever the instruction writes no longer holds an adrp page; the
+	// first operand is the destination of nearly all of them
+	if len(a.args) > 0 {
+		if n, ok := arm64RegNum(a.args[0]); ok {
+			d.known &^= 1 << n
+		}
+	}
+	if a.setsPage {
+		d.pages[a.pageReg] = a.page
+		d.known |= 1 << a.pageReg
+	}
+	return Inst{
+		Len: 4, Op: op, Args: a.args,
+		Target: a.target, HasTarget: a.hasTarget,
+		Ref: a.ref, HasRef: a.hasRef,
+		armSep: true,
+	}
+}
+
+// arm64RegNum returns the number of general register xN or wN.
+func arm64RegNum(s string) (int, bool) {
+	if len(s) < 2 || s[0] != 'x' && s[0] != 'w' {
+		return 0, false
+	}
+	var n int
+	if _, err := fmt.Sscanf(s[1:], "%d", &n); err != nil || n > 30 {
+		return 0, false
+	}
+	return n, true
+}
+
+// bits returns the field of w from bit lo, n bits wide.
+func (a *arm64Dec) bits(lo, n uint) uint32 {
+	return a.w >> lo & (1<<n - 1)
+}
+
+func (a *arm64Dec) bit(n uint) bool {
+	return a.w>>n&1 != 0
+}
+
+// pageRef records the address an access at offset from register rn
+// reaches when an earlier adrp put a page in rn.
+func (a *arm64Dec) pageRef(rn int, off uint64) {
+	if rn < 31 && a.d.known&(1<<rn) != 0 {
+		a.ref, a.hasRef = a.d.pages[rn]+off, true
+	}
+}
+
+func signExtend32(v uint32, n uint) int64 {
+	return int64(int32(v<<(32-n)) >> (32 - n))
+}
+
+// reg names general register n as x or w by sf, with 31 as the zero
+// register or, if sp is set, the stack pointer.
+func reg(sf bool, n uint32, sp bool) string {
+	switch {
+	case n == 31 && sp && sf:
+		return "sp"
+	case n == 31 && sp:
+		return "wsp"
+	case n == 31 && sf:
+		return "xzr"
+	case n == 31:
+		return "wzr"
+	case sf:
+		return fmt.Sprintf("x%d", n)
+	}
+	return fmt.Sprintf("w%d", n)
+}
+
+func x(n uint32) string { return reg(true, n, false) }
+
+func imm(v uint64) string { return fmt.Sprintf("#0x%x", v) }
+
+func (a *arm64Dec) set(args ...string) { a.args = args }
+
+// branch sets the target of a PC-relative branch and formats it.
+func (a *arm64Dec) branch(off int64) string {
+	a.target, a.hasTarget = a.pc+uint64(off), true
+	return fmt.Sprintf("%x", a.target)
+}
+
+var arm64Conds = [16]string{"eq", "ne", "cs", "cc", "mi", "pl", "vs", "vc", "hi", "ls", "ge", "lt", "gt", "le", "al", "nv"}
+
+func (a *arm64Dec) decode() string {
+	switch op0 := a.bits(25, 4); {
+	case a.w&0xffff0000 == 0:
+		a.set(fmt.Sprintf("#%d", a.w))
+		return "udf"
+	case op0&0xe == 0x8:
+		return a.dataImm()
+	case op0&0xe == 0xa:
+		return a.branchSys()
+	case op0&0x5 == 0x4:
+		return a.loadStore()
+	case op0&0x7 == 0x5:
+		return a.dataReg()
+	case op0&0x7 == 0x7:
+		return a.simdFP()
+	}
+	return ""
+}
+
+// dataImm decodes the data processing instructions with immediates.
+func (a *arm64Dec) dataImm() string {
+	sf := a.bit(31)
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	switch a.bits(23, 3) {
+	case 0, 1:
+		lo, hi := a.bits(29, 2), a.bits(5, 19)
+		off := signExtend32(hi<<2|lo, 21)
+		if a.bit(31) {
+			page := a.pc&^0xfff + uint64(off<<12)
+			a.set(x(rd), a.branch(int64(page-a.pc)))
+			a.page, a.pageReg, a.setsPage = page, int(rd), rd < 31
+			return "adrp"
+		}
+		a.set(x(rd), a.branch(off))
+		return "adr"
+	case 2:
+		sub, s := a.bit(30), a.bit(29)
+		v := uint64(a.bits(10, 12))
+		shift := a.bits(22, 1) == 1
+		op := map[bool]string{false: "add", true: "sub"}[sub]
+		if s {
+			op += "s"
+		}
+		ival := imm(v)
+		amount := v
+		if shift {
+			amount <<= 12
+		}
+		if !sub && !s {
+			a.pageRef(int(rn), amount)
+		}
+		rest := []string{ival}
+		if shift {
+			rest = append(rest, "lsl #12")
+		}
+		switch {
+		case !s && !sub && v == 0 && !shift && (rd == 31 || rn == 31):
+			a.set(reg(sf, rd, true), reg(sf, rn, true))
+			return "mov"
+		case s && rd == 31:
+			a.set(append([]string{reg(sf, rn, true)}, rest...)...)
+			return map[bool]string{false: "cmn", true: "cmp"}[sub]
+		}
+		a.set(append([]string{reg(sf, rd, !s), reg(sf, rn, true)}, rest...)...)
+		return op
+	case 4:
+		opc := a.bits(29, 2)
+		v, ok := decodeBitMask(a.bits(22, 1), a.bits(16, 6), a.bits(10, 6), sf)
+		if !ok {
+			return ""
+		}
+		switch {
+		case opc == 1 && rn == 31 && !moveWidePreferred(v, sf):
+			a.set(reg(sf, rd, true), imm(v))
+			return "mov"
+		case opc == 3 && rd == 31:
+			a.set(reg(sf, rn, false), imm(v))
+			return "tst"
+		}
+		a.set(reg(sf, rd, opc != 3), reg(sf, rn, false), imm(v))
+		return [4]string{"and", "orr", "eor", "ands"}[opc]
+	case 5:
+		opc, hw := a.bits(29, 2), a.bits(21, 2)
+		v := uint64(a.bits(5, 16))
+		if !sf && hw > 1 || opc == 1 {
+			return ""
+		}
+		shift := 16 * uint(hw)
+		switch {
+		case opc == 2 && !(v == 0 && hw != 0):
+			a.set(reg(sf, rd, false), imm(v<<shift))
+			return "mov"
+		case opc == 0 && !(v == 0 && hw != 0):
+			r := ^(v << shift)
+			if !sf {
+				r &= 0xffffffff
+				if v == 0xffff {
+					break
+				}
+			}
+			a.set(reg(sf, rd, false), imm(r))
+			return "mov"
+		}
+		a.set(reg(sf, rd, false), imm(v))
+		if hw != 0 {
+			a.args = append(a.args, fmt.Sprintf("lsl #%d", shift))
+		}
+		return [4]string{"movn", "", "movz", "movk"}[opc]
+	case 6:
+		return a.bitfield(sf, rd, rn)
+	case 7:
+		if a.bits(29, 2) != 0 || a.bit(21) || a.bit(22) != sf || !sf && a.bit(15) {
+			return ""
+		}
+		rm, lsb := a.bits(16, 5), a.bits(10, 6)
+		if rn == rm {
+			a.set(reg(sf, rd, false), reg(sf, rn, false), fmt.Sprintf("#%d", lsb))
+			return "ror"
+		}
+		a.set(reg(sf, rd, false), reg(sf, rn, false), reg(sf, rm, false), fmt.Sprintf("#%d", lsb))
+		return "extr"
+	}
+	return ""
+}
+
+// bitfield decodes sbfm, bfm and ubfm with their aliases.
+func (a *arm64Dec) bitfield(sf bool, rd, rn uint32) string {
+	opc := a.bits(29, 2)
+	immr, imms := a.bits(16, 6), a.bits(10, 6)
+	size := uint32(32)
+	if sf {
+		size = 64
+	}
+	if a.bit(22) != sf || immr >= size || imms >= size {
+		return ""
+	}
+	d, n := reg(sf, rd, false), reg(sf, rn, false)
+	num := func(v uint32) string { return fmt.Sprintf("#%d", v) }
+	switch opc {
+	case 0:
+		switch {
+		case imms == size-1:
+			a.set(d, n, num(immr))
+			return "asr"
+		case immr == 0 && imms == 7:
+			a.set(d, reg(false, rn, false))
+			return "sxtb"
+		case immr == 0 && imms == 15:
+			a.set(d, reg(false, rn, false))
+			return "sxth"
+		case immr == 0 && imms == 31 && sf:
+			a.set(d, reg(false, rn, false))
+			return "sxtw"
+		case imms < immr:
+			a.set(d, n, num(size-immr), num(imms+1))
+			return "sbfiz"
+		}
+		a.set(d, n, num(immr), num(imms-immr+1))
+		return "sbfx"
+	case 1:
+		switch {
+		case imms < immr && rn == 31:
+			a.set(d, num(size-immr), num(imms+1))
+			return "bfc"
+		case imms < immr:
+			a.set(d, n, num(size-immr), num(imms+1))
+			return "bfi"
+		}
+		a.set(d, n, num(immr), num(imms-immr+1))
+		return "bfxil"
+	case 2:
+		switch {
+		case imms != size-1 && imms+1 == immr:
+			a.set(d, n, num(size-1-imms))
+			return "lsl"
+		case imms == size-1:
+			a.set(d, n, num(immr))
+			return "lsr"
+		case immr == 0 && imms == 7 && !sf:
+			a.set(d, n)
+			return "uxtb"
+		case immr == 0 && imms == 15 && !sf:
+			a.set(d, n)
+			return "uxth"
+		case imms < immr:
+			a.set(d, n, num(size-immr), num(imms+1))
+			return "ubfiz"
+		}
+		a.set(d, n, num(immr), num(imms-immr+1))
+		return "ubfx"
+	}
+	return ""
+}
+
+// decodeBitMask expands the N:immr:imms bitmask immediate of the
+// logical instructions.
+func decodeBitMask(n, immr, imms uint32, sf bool) (uint64, bool) {
+	l := 31 - bits.LeadingZeros32(n<<6|^imms&0x3f)
+	if l < 1 || !sf && n == 1 {
+		return 0, false
+	}
+	size := uint32(1) << l
+	levels := size - 1
+	s, r := imms&levels, immr&levels
+	if s == levels {
+		return 0, false
+	}
+	elem := uint64(1)<<(s+1) - 1
+	if r != 0 {
+		elem = (elem>>r | elem<<(size-r)) & (uint64(1)<<size - 1)
+		if size == 64 {
+			elem = bits.RotateLeft64(uint64(1)<<(s+1)-1, -int(r))
+		}
+	}
+	v := elem
+	for sz := size; sz < 64; sz *= 2 {
+		v |= v << sz
+	}
+	if !sf {
+		v &= 0xffffffff
+	}
+	return v, true
+}
+
+// moveWidePreferred tells whether movz or movn can make v, in which
+// case orr is not shown as mov.
+func moveWidePreferred(v uint64, sf bool) bool {
+	width := 64
+	if !sf {
+		width = 32
+		v &= 0xffffffff
+	}
+	for shift := 0; shift < width; shift += 16 {
+		mask := uint64(0xffff) << shift
+		if v&^mask == 0 {
+			return true
+		}
+		inv := ^v
+		if !sf {
+			inv &= 0xffffffff
+		}
+		if inv&^mask == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var arm64Hints = map[uint32]string{
+	0: "nop", 1: "yield", 2: "wfe", 3: "wfi", 4: "sev", 5: "sevl", 7: "xpaclri",
+	8: "pacia1716", 10: "pacib1716", 12: "autia1716", 14: "autib1716",
+	16: "esb", 17: "psb csync", 18: "tsb csync", 20: "csdb",
+	24: "paciaz", 25: "paciasp", 26: "pacibz", 27: "pacibsp",
+	28: "autiaz", 29: "autiasp", 30: "autibz", 31: "autibsp",
+	32: "bti", 34: "bti c", 36: "bti j", 38: "bti jc",
+}
+
+var arm64Barriers = map[uint32]string{
+	15: "sy", 14: "st", 13: "ld", 11: "ish", 10: "ishst", 9: "ishld",
+	7: "nsh", 6: "nshst", 5: "nshld", 3: "osh", 2: "oshst", 1: "oshld",
+}
+
+// arm64SysRegs names system registers by op0:op1:CRn:CRm:op2.
+var arm64SysRegs = map[uint32]string{
+	0xde82: "tpidr_el0", 0xde83: "tpidrro_el0", 0xda20: "fpcr", 0xda21: "fpsr",
+	0xda10: "nzcv", 0xda11: "daif", 0xdf02: "cntvct_el0", 0xdf00: "cntfrq_el0",
+	0xdf01: "cntpct_el0", 0xd801: "ctr_el0", 0xd807: "dczid_el0",
+	0xc000: "midr_el1", 0xc005: "mpidr_el1", 0xc212: "currentel",
+	0xc030: "id_aa64isar0_el1", 0xc031: "id_aa64isar1_el1",
+	0xc020: "id_aa64pfr0_el1", 0xc038: "id_aa64mmfr0_el1",
+	0xc684: "tpidr_el1", 0xc201: "elr_el1", 0xc200: "spsr_el1", 0xc208: "sp_el0",
+	0xc100: "ttbr0_el1", 0xc101: "ttbr1_el1", 0xc080: "sctlr_el1", 0xc600: "vbar_el1",
+	0xc290: "esr_el1", 0xc300: "far_el1",
+}
+
+func (a *arm64Dec) sysReg() string {
+	enc := a.bits(5, 16) | 1<<15
+	if n, ok := arm64SysRegs[enc&0xffff]; ok {
+		return n
+	}
+	return fmt.Sprintf("s%d_%d_c%d_c%d_%d", 2+a.bits(19, 1), a.bits(16, 3), a.bits(12, 4), a.bits(8, 4), a.bits(5, 3))
+}
+
+// branchSys decodes branches, exception generation and system
+// instructions.
+func (a *arm64Dec) branchSys() string {
+	w := a.w
+	rt := a.bits(0, 5)
+	switch {
+	case w&0x7c000000 == 0x14000000:
+		a.set(a.branch(signExtend32(a.bits(0, 26), 26) * 4))
+		if a.bit(31) {
+			return "bl"
+		}
+		return "b"
+	case w&0xff000010 == 0x54000000:
+		a.set(a.branch(signExtend32(a.bits(5, 19), 19) * 4))
+		return "b." + arm64Conds[a.bits(0, 4)]
+	case w&0x7e000000 == 0x34000000:
+		a.set(reg(a.bit(31), rt, false), a.branch(signExtend32(a.bits(5, 19), 19)*4))
+		if a.bit(24) {
+			return "cbnz"
+		}
+		return "cbz"
+	case w&0x7e000000 == 0x36000000:
+		b := a.bits(31, 1)<<5 | a.bits(19, 5)
+		a.set(reg(b >= 32, rt, false), fmt.Sprintf("#%d", b), a.branch(signExtend32(a.bits(5, 14), 14)*4))
+		if a.bit(24) {
+			return "tbnz"
+		}
+		return "tbz"
+	case w&0xff000000 == 0xd4000000:
+		opc, ll := a.bits(21, 3), a.bits(0, 2)
+		if a.bits(2, 3) != 0 {
+			return ""
+		}
+		a.set(imm(uint64(a.bits(5, 16))))
+		switch {
+		case opc == 0 && ll == 1:
+			return "svc"
+		case opc == 0 && ll == 2:
+			return "hvc"
+		case opc == 0 && ll == 3:
+			return "smc"
+		case opc == 1 && ll == 0:
+			return "brk"
+		case opc == 2 && ll == 0:
+			return "hlt"
+		}
+		return ""
+	case w&0xfffff01f == 0xd503201f:
+		h := a.bits(5, 7)
+		if n, ok := arm64Hints[h]; ok {
+			return n
+		}
+		a.set(imm(uint64(h)))
+		return "hint"
+	case w&0xfffff01f == 0xd503301f:
+		crm := a.bits(8, 4)
+		switch a.bits(5, 3) {
+		case 2:
+			if crm != 15 {
+				a.set(fmt.Sprintf("#%d", crm))
+			}
+			return "clrex"
+		case 4, 5:
+			if n, ok := arm64Barriers[crm]; ok {
+				a.set(n)
+			} else {
+				a.set(imm(uint64(crm)))
+			}
+			if a.bits(5, 3) == 4 {
+				if crm == 0 {
+					a.set()
+					return "ssbb"
+				}
+				if crm == 4 {
+					a.set()
+					return "pssbb"
+				}
+				return "dsb"
+			}
+			return "dmb"
+		case 6:
+			if crm != 15 {
+				a.set(imm(uint64(crm)))
+			}
+			return "isb"
+		case 7:
+			return "sb"
+		}
+		return ""
+	case w&0xfff8f01f == 0xd500401f:
+		fields := map[uint32]string{0x05: "spsel", 0x1e: "daifset", 0x1f: "daifclr", 0x03: "uao", 0x04: "pan", 0x1a: "dit", 0x19: "ssbs", 0x1c: "tco"}
+		f := a.bits(16, 3)<<3 | a.bits(5, 3)
+		n, ok := fields[f]
+		if !ok {
+			return ""
+		}
+		a.set(n, imm(uint64(a.bits(8, 4))))
+		return "msr"
+	case w&0xffd00000 == 0xd5100000 || w&0xffd00000 == 0xd5300000:
+		if a.bit(21) {
+			a.set(x(rt), a.sysReg())
+			return "mrs"
+		}
+		a.set(a.sysReg(), x(rt))
+		return "msr"
+	case w&0xfff80000 == 0xd5080000:
+		return a.sys(rt)
+	case w&0xfe000000 == 0xd6000000:
+		return a.branchReg()
+	}
+	return ""
+}
+
+// sys decodes the dc, ic, at and tlbi aliases of sys.
+func (a *arm64Dec) sys(rt uint32) string {
+	op1, crn, crm, op2 := a.bits(16, 3), a.bits(12, 4), a.bits(8, 4), a.bits(5, 3)
+	key := op1<<11 | crn<<7 | crm<<3 | op2
+	ops := map[uint32][2]string{
+		3<<11 | 7<<7 | 4<<3 | 1:  {"dc", "zva"},
+		3<<11 | 7<<7 | 10<<3 | 1: {"dc", "cvac"},
+		3<<11 | 7<<7 | 11<<3 | 1: {"dc", "cvau"},
+		3<<11 | 7<<7 | 12<<3 | 1: {"dc", "cvap"},
+		3<<11 | 7<<7 | 14<<3 | 1: {"dc", "civac"},
+		0<<11 | 7<<7 | 6<<3 | 1:  {"dc", "ivac"},
+		0<<11 | 7<<7 | 6<<3 | 2:  {"dc", "isw"},
+		0<<11 | 7<<7 | 10<<3 | 2: {"dc", "csw"},
+		0<<11 | 7<<7 | 14<<3 | 2: {"dc", "cisw"},
+		3<<11 | 7<<7 | 5<<3 | 1:  {"ic", "ivau"},
+		0<<11 | 7<<7 | 5<<3 | 0:  {"ic", "iallu"},
+		0<<11 | 7<<7 | 1<<3 | 0:  {"ic", "ialluis"},
+	}
+	if o, ok := ops[key]; ok {
+		if rt == 31 {
+			a.set(o[1])
+		} else {
+			a.set(o[1], x(rt))
+		}
+		return o[0]
+	}
+	a.set(fmt.Sprintf("#%d", op1), fmt.Sprintf("C%d", crn), fmt.Sprintf("C%d", crm), fmt.Sprintf("#%d", op2))
+	if rt != 31 {
+		a.args = append(a.args, x(rt))
+	}
+	return "sys"
+}
+
+// branchReg decodes br, blr, ret and their pointer authentication
+// forms.
+func (a *arm64Dec) branchReg() string {
+	opc, op2, op3, rn, op4 := a.bits(21, 4), a.bits(16, 5), a.bits(10, 6), a.bits(5, 5), a.bits(0, 5)
+	if op2 != 31 {
+		return ""
+	}
+	switch {
+	case op3 == 0 && op4 == 0:
+		switch opc {
+		case 0:
+			a.set(x(rn))
+			return "br"
+		case 1:
+			a.set(x(rn))
+			return "blr"
+		case 2:
+			if rn != 30 {
+				a.set(x(rn))
+			}
+			return "ret"
+		case 4:
+			if rn == 31 {
+				return "eret"
+			}
+		case 5:
+			if rn == 31 {
+				return "drps"
+			}
+		}
+	case opc == 2 && rn == 31 && op4 == 31 && (op3 == 2 || op3 == 3):
+		return map[uint32]string{2: "retaa", 3: "retab"}[op3]
+	case (opc == 0 || opc == 1) && op4 == 31 && (op3 == 2 || op3 == 3):
+		a.set(x(rn))
+		return map[uint32]string{0: "br", 1: "blr"}[opc] + map[uint32]string{2: "aaz", 3: "abz"}[op3]
+	case (opc == 8 || opc == 9) && (op3 == 2 || op3 == 3):
+		a.set(x(rn), reg(true, op4, true))
+		return map[uint32]string{8: "br", 9: "blr"}[opc] + map[uint32]string{2: "aa", 3: "ab"}[op3]
+	}
+	return ""
+}
+
+// loadStore decodes the loads and stores.
+func (a *arm64Dec) loadStore() string {
+	w := a.w
+	switch {
+	case w&0x3f000000 == 0x08000000:
+		return a.exclusive()
+	case w&0x3b000000 == 0x18000000:
+		return a.literal()
+	case w&0x3a000000 == 0x28000000:
+		return a.pair()
+	case w&0x3f200c00 == 0x19000000:
+		return a.rcpc()
+	case w&0xff200400 == 0xf8200400:
+		off := signExtend32(a.bits(22, 1)<<9|a.bits(12, 9), 10) * 8
+		mode := 2
+		if a.bit(11) {
+			mode = 3
+		}
+		a.set(x(a.bits(0, 5)), mem(a.bits(5, 5), off, mode))
+		return map[bool]string{false: "ldraa", true: "ldrab"}[a.bit(23)]
+	case w&0x3a000000 == 0x38000000:
+		return a.loadStoreReg()
+	case w&0xbf800000 == 0x0c000000 || w&0xbf800000 == 0x0c800000:
+		return a.structure()
+	case w&0xbf800000 == 0x0d000000 || w&0xbf800000 == 0x0d800000:
+		return a.single()
+	}
+	return ""
+}
+
+// mem formats a base register addressing mode with a signed offset.
+func mem(rn uint32, off int64, mode int) string {
+	base := reg(true, rn, true)
+	switch {
+	case mode == 1:
+		return fmt.Sprintf("[%s], #%d", base, off)
+	case mode == 3:
+		return fmt.Sprintf("[%s, #%d]!", base, off)
+	case off == 0:
+		return "[" + base + "]"
+	}
+	return fmt.Sprintf("[%s, #%d]", base, off)
+}
+
+func (a *arm64Dec) exclusive() string {
+	size, o2, l, o1, o0 := a.bits(30, 2), a.bit(23), a.bit(22), a.bit(21), a.bit(15)
+	rs, rt2, rn, rt := a.bits(16, 5), a.bits(10, 5), a.bits(5, 5), a.bits(0, 5)
+	sf := size == 3
+	suffix := [4]string{"b", "h", "", ""}[size]
+	addr := "[" + reg(true, rn, true) + "]"
+	switch {
+	case o2 && o1:
+		if rt2 != 31 {
+			return ""
+		}
+		op := "cas"
+		if l {
+			op += "a"
+		}
+		if o0 {
+			op += "l"
+		}
+		a.set(reg(sf, rs, false), reg(sf, rt, false), addr)
+		return op + suffix
+	case o2:
+		op := map[bool]string{false: "stllr", true: "stlr"}[o0]
+		if l {
+			op = map[bool]string{false: "ldlar", true: "ldar"}[o0]
+		}
+		a.set(reg(sf, rt, false), addr)
+		return op + suffix
+	case o1 && size >= 2:
+		if l {
+			a.set(reg(sf, rt, false), reg(sf, rt2, false), addr)
+			return map[bool]string{false: "ldxp", true: "ldaxp"}[o0]
+		}
+		a.set(reg(false, rs, false), reg(sf, rt, false), reg(sf, rt2, false), addr)
+		return map[bool]string{false: "stxp", true: "stlxp"}[o0]
+	case o1:
+		if rt2 != 31 {
+			return ""
+		}
+		if rs&1 != 0 || rt&1 != 0 {
+			return ""
+		}
+		op := "casp"
+		if l {
+			op += "a"
+		}
+		if o0 {
+			op += "l"
+		}
+		sf = size == 1
+		a.set(reg(sf, rs, false), reg(sf, rs+1, false), reg(sf, rt, false), reg(sf, rt+1, false), addr)
+		return op
+	}
+	if l {
+		a.set(reg(sf, rt, false), addr)
+		return map[bool]string{false: "ldxr", true: "ldaxr"}[o0] + suffix
+	}
+	a.set(reg(false, rs, false), reg(sf, rt, false), addr)
+	return map[bool]string{false: "stxr", true: "stlxr"}[o0] + suffix
+}
+
+var arm64FPRegs = [5]string{"b", "h", "s", "d", "q"}
+
+func (a *arm64Dec) literal() string {
+	opc, v, rt := a.bits(30, 2), a.bit(26), a.bits(0, 5)
+	target := a.branch(signExtend32(a.bits(5, 19), 19) * 4)
+	switch {
+	case v && opc < 3:
+		a.set(fmt.Sprintf("%s%d", [3]string{"s", "d", "q"}[opc], rt), target)
+		return "ldr"
+	case v:
+		return ""
+	case opc == 0:
+		a.set(reg(false, rt, false), target)
+		return "ldr"
+	case opc == 1:
+		a.set(x(rt), target)
+		return "ldr"
+	case opc == 2:
+		a.set(x(rt), target)
+		return "ldrsw"
+	}
+	a.set(prfop(rt), target)
+	return "prfm"
+}
+
+func prfop(rt uint32) string {
+	t, target, policy := rt>>3, rt>>1&3, rt&1
+	if t > 2 || target > 2 {
+		return fmt.Sprintf("#0x%02x", rt)
+	}
+	return [3]string{"pld", "pli", "pst"}[t] + [3]string{"l1", "l2", "l3"}[target] + [2]string{"keep", "strm"}[policy]
+}
+
+func (a *arm64Dec) pair() string {
+	opc, v, mode, l := a.bits(30, 2), a.bit(26), a.bits(23, 2), a.bit(22)
+	rt2, rn, rt := a.bits(10, 5), a.bits(5, 5), a.bits(0, 5)
+	imm7 := signExtend32(a.bits(15, 7), 7)
+	var r1, r2, op string
+	var scale int64
+	switch {
+	case v && opc < 3:
+		scale = 4 << opc
+		name := [3]string{"s", "d", "q"}[opc]
+		r1, r2 = fmt.Sprintf("%s%d", name, rt), fmt.Sprintf("%s%d", name, rt2)
+	case !v && opc == 0:
+		scale = 4
+		r1, r2 = reg(false, rt, false), reg(false, rt2, false)
+	case !v && opc == 2:
+		scale = 8
+		r1, r2 = x(rt), x(rt2)
+	case !v && opc == 1 && l && mode != 0:
+		scale = 4
+		r1, r2 = x(rt), x(rt2)
+		op = "ldpsw"
+	default:
+		return ""
+	}
+	if op == "" {
+		op = map[bool]string{false: "stp", true: "ldp"}[l]
+		if mode == 0 {
+			op = map[bool]string{false: "stnp", true: "ldnp"}[l]
+		}
+	}
+	m := int(mode)
+	if mode == 0 {
+		m = 2
+	}
+	a.set(r1, r2, mem(rn, imm7*scale, m))
+	return op
+}
+
+// rcpc decodes the unscaled release and acquire forms stlur and
+// ldapur.
+func (a *arm64Dec) rcpc() string {
+	size, opc := a.bits(30, 2), a.bits(22, 2)
+	rn, rt := a.bits(5, 5), a.bits(0, 5)
+	suffix := [4]string{"b", "h", "", ""}[size]
+	addr := mem(rn, signExtend32(a.bits(12, 9), 9), 2)
+	switch {
+	case opc == 0:
+		a.set(reg(size == 3, rt, false), addr)
+		return "stlur" + suffix
+	case opc == 1:
+		a.set(reg(size == 3, rt, false), addr)
+		return "ldapur" + suffix
+	case size == 2 && opc == 2:
+		a.set(x(rt), addr)
+		return "ldapursw"
+	case size < 2:
+		a.set(reg(opc == 2, rt, false), addr)
+		return "ldapurs" + suffix
+	}
+	return ""
+}
+
+// loadStoreReg decodes the single register loads and stores of all
+// addressing modes and the atomic memory operations.
+func (a *arm64Dec) loadStoreReg() string {
+	size, v, opc := a.bits(30, 2), a.bit(26), a.bits(22, 2)
+	rn, rt := a.bits(5, 5), a.bits(0, 5)
+	if !v && !a.bit(24) && a.bit(21) && a.bits(10, 2) == 0 {
+		return a.atomic()
+	}
+
+	// the mnemonic stem, the transfer register and the access size
+	var stem, r string
+	var scale uint
+	if v {
+		scale = uint(size)
+		if opc >= 2 {
+			if size != 0 {
+				return ""
+			}
+			scale = 4
+		}
+		stem = map[bool]string{false: "st", true: "ld"}[opc&1 == 1]
+		r = fmt.Sprintf("%s%d", arm64FPRegs[scale], rt)
+	} else {
+		scale = uint(size)
+		suffix := [4]string{"b", "h", "", ""}[size]
+		switch opc {
+		case 0:
+			stem, r = "st", reg(size == 3, rt, false)
+		case 1:
+			stem, r = "ld", reg(size == 3, rt, false)
+		case 2:
+			switch size {
+			case 3:
+				stem, r = "prfm", prfop(rt)
+			case 2:
+				stem, r = "ld", x(rt)
+				suffix = "sw"
+			default:
+				stem, r = "ld", x(rt)
+				suffix = "s" + suffix
+			}
+		case 3:
+			if size >= 2 {
+				return ""
+			}
+			stem, r = "ld", reg(false, rt, false)
+			suffix = "s" + suffix
+		}
+		if stem != "prfm" {
+			stem += "%s" + suffix
+		}
+	}
+	if v {
+		stem += "%s"
+	}
+	name := func(form string) string {
+		if stem == "prfm" {
+			if form == "r" {
+				return "prfm"
+			}
+			if form == "ur" {
+				return "prfum"
+			}
+			return ""
+		}
+		return fmt.Sprintf(stem, form)
+	}
+
+	switch {
+	case a.bit(24):
+		off := uint64(a.bits(10, 12)) << scale
+		a.pageRef(int(rn), off)
+		a.set(r, mem(rn, int64(off), 2))
+		return name("r")
+	case !a.bit(21):
+		imm9 := signExtend32(a.bits(12, 9), 9)
+		switch a.bits(10, 2) {
+		case 0:
+			a.set(r, mem(rn, imm9, 2))
+			return name("ur")
+		case 1:
+			if stem == "prfm" {
+				return ""
+			}
+			a.set(r, mem(rn, imm9, 1))
+			return name("r")
+		case 2:
+			if v {
+				return ""
+			}
+			a.set(r, mem(rn, imm9, 2))
+			return name("tr")
+		}
+		if stem == "prfm" {
+			return ""
+		}
+		a.set(r, mem(rn, imm9, 3))
+		return name("r")
+	case a.bits(10, 2) == 2:
+		option, s, rm := a.bits(13, 3), a.bit(12), a.bits(16, 5)
+		if option&2 == 0 {
+			return ""
+		}
+		index := reg(option&1 == 1, rm, false)
+		ext := [8]string{2: "uxtw", 3: "lsl", 6: "sxtw", 7: "sxtx"}[option]
+		addr := "[" + reg(true, rn, true) + ", " + index
+		switch {
+		case s:
+			addr += fmt.Sprintf(", %s #%d", ext, scale)
+		case option != 3:
+			addr += ", " + ext
+		}
+		a.set(r, addr+"]")
+		return name("r")
+	}
+	return ""
+}
+
+// atomic decodes the LSE atomic memory operations.
+func (a *arm64Dec) atomic() string {
+	size, acq, rel, o3, opc := a.bits(30, 2), a.bit(23), a.bit(22), a.bit(15), a.bits(12, 3)
+	rs, rn, rt := a.bits(16, 5), a.bits(5, 5), a.bits(0, 5)
+	sf := size == 3
+	var op string
+	switch {
+	case !o3:
+		op = [8]string{"add", "clr", "eor", "set", "smax", "smin", "umax", "umin"}[opc]
+	case opc == 0:
+		op = "swp"
+	case opc == 4 && acq && !rel && rs == 31:
+		a.set(reg(sf, rt, false), "["+reg(true, rn, true)+"]")
+		return "ldapr" + [4]string{"b", "h", "", ""}[size]
+	default:
+		return ""
+	}
+	order := ""
+	if acq {
+		order += "a"
+	}
+	if rel {
+		order += "l"
+	}
+	suffix := order + [4]string{"b", "h", "", ""}[size]
+	addr := "[" + reg(true, rn, true) + "]"
+	if op == "swp" {
+		a.set(reg(sf, rs, false), reg(sf, rt, false), addr)
+		return op + suffix
+	}
+	if rt == 31 && !acq {
+		a.set(reg(sf, rs, false), addr)
+		return "st" + op + suffix
+	}
+	a.set(reg(sf, rs, false), reg(sf, rt, false), addr)
+	return "ld" + op + suffix
+}
+
+// arrangement spells the vector arrangement of size and Q.
+func arrangement(size uint32, q bool) string {
+	n := 64 >> 3 >> size
+	if q {
+		n *= 2
+	}
+	return fmt.Sprintf("%d%s", n, [4]string{"b", "h", "s", "d"}[size])
+}
+
+func vreg(n uint32, arr string) string { return fmt.Sprintf("v%d.%s", n, arr) }
+
+// regList spells a list of count consecutive vector registers.
+func regList(rt uint32, count int, arr string) string {
+	if count > 2 {
+		return fmt.Sprintf("{%s-%s}", vreg(rt, arr), vreg((rt+uint32(count)-1)%32, arr))
+	}
+	var l []string
+	for i := 0; i < count; i++ {
+		l = append(l, vreg((rt+uint32(i))%32, arr))
+	}
+	return "{" + strings.Join(l, ", ") + "}"
+}
+
+// structure decodes ld1 to ld4 and st1 to st4 of multiple structures.
+func (a *arm64Dec) structure() string {
+	q, l, post := a.bit(30), a.bit(22), a.bit(23)
+	rm, opcode, size, rn, rt := a.bits(16, 5), a.bits(12, 4), a.bits(10, 2), a.bits(5, 5), a.bits(0, 5)
+	if !post && rm != 0 || a.bit(21) {
+		return ""
+	}
+	var n, count int
+	switch opcode {
+	case 0:
+		n, count = 4, 4
+	case 2:
+		n, count = 1, 4
+	case 4:
+		n, count = 3, 3
+	case 6:
+		n, count = 1, 3
+	case 7:
+		n, count = 1, 1
+	case 8:
+		n, count = 2, 2
+	case 10:
+		n, count = 1, 2
+	default:
+		return ""
+	}
+	if size == 3 && !q && n > 1 {
+		return ""
+	}
+	arr := arrangement(size, q)
+	addr := "[" + reg(true, rn, true) + "]"
+	if post {
+		if rm == 31 {
+			bytes := 8 * count
+			if q {
+				bytes *= 2
+			}
+			addr += fmt.Sprintf(", #%d", bytes)
+		} else {
+			addr += ", " + x(rm)
+		}
+	}
+	a.set(regList(rt, count, arr), addr)
+	return fmt.Sprintf("%s%d", map[bool]string{false: "st", true: "ld"}[l], n)
+}
+
+// single decodes the loads and stores of one structure to a lane and
+// ld1r to ld4r.
+func (a *arm64Dec) single() string {
+	q, l, post, r := a.bit(30), a.bit(22), a.bit(23), a.bit(21)
+	rm, opcode, s, size, rn, rt := a.bits(16, 5), a.bits(13, 3), a.bit(12), a.bits(10, 2), a.bits(5, 5), a.bits(0, 5)
+	if !post && rm != 0 {
+		return ""
+	}
+	n := 1 + int(opcode&1)*2
+	if r {
+		n++
+	}
+	var list string
+	var esize uint32
+	switch opcode >> 1 {
+	case 3:
+		if !l || s {
+			return ""
+		}
+		list, esize = regList(rt, n, arrangement(size, q)), size
+	default:
+		esize = opcode >> 1
+		index := b2u(q)<<3 | b2u(s)<<2 | size
+		switch {
+		case esize == 1 && size&1 == 0:
+			index >>= 1
+		case esize == 2 && size == 0:
+			index >>= 2
+		case esize == 2 && size == 1 && !s:
+			esize, index = 3, index>>3
+		case esize != 0:
+			return ""
+		}
+		t := [4]string{"b", "h", "s", "d"}[esize]
+		list = regList(rt, n, t) + fmt.Sprintf("[%d]", index)
+	}
+	addr := "[" + reg(true, rn, true) + "]"
+	if post {
+		if rm == 31 {
+			addr += fmt.Sprintf(", #%d", n<<esize)
+		} else {
+			addr += ", " + x(rm)
+		}
+	}
+	a.set(list, addr)
+	if opcode>>1 == 3 {
+		return fmt.Sprintf("ld%dr", n)
+	}
+	return fmt.Sprintf("%s%d", map[bool]string{false: "st", true: "ld"}[l], n)
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// dataReg decodes data processing on registers.
+func (a *arm64Dec) dataReg() string {
+	sf := a.bit(31)
+	rd, rn, rm := a.bits(0, 5), a.bits(5, 5), a.bits(16, 5)
+	d, n, m := reg(sf, rd, false), reg(sf, rn, false), reg(sf, rm, false)
+	shifts := [4]string{"lsl", "lsr", "asr", "ror"}
+	if !a.bit(28) {
+		amount := a.bits(10, 6)
+		shift := a.bits(22, 2)
+		shifted := func(args ...string) []string {
+			if amount != 0 || shift != 0 {
+				args = append(args, fmt.Sprintf("%s #%d", shifts[shift], amount))
+			}
+			return args
+		}
+		switch {
+		case !a.bit(24):
+			opc, neg := a.bits(29, 2), a.bit(21)
+			if !sf && amount >= 32 {
+				return ""
+			}
+			switch {
+			case opc == 1 && !neg && rn == 31 && amount == 0:
+				a.set(d, m)
+				return "mov"
+			case opc == 1 && neg && rn == 31:
+				a.set(shifted(d, m)...)
+				return "mvn"
+			case opc == 3 && !neg && rd == 31:
+				a.set(shifted(n, m)...)
+				return "tst"
+			}
+			a.set(shifted(d, n, m)...)
+			names := [4][2]string{{"and", "bic"}, {"orr", "orn"}, {"eor", "eon"}, {"ands", "bics"}}
+			return names[opc][b2i(neg)]
+		case !a.bit(21):
+			sub, s := a.bit(30), a.bit(29)
+			if shift == 3 || !sf && amount >= 32 {
+				return ""
+			}
+			switch {
+			case s && rd == 31:
+				a.set(shifted(n, m)...)
+				return map[bool]string{false: "cmn", true: "cmp"}[sub]
+			case sub && rn == 31:
+				a.set(shifted(d, m)...)
+				return map[bool]string{false: "neg", true: "negs"}[s]
+			}
+			a.set(shifted(d, n, m)...)
+			return map[bool]string{false: "add", true: "sub"}[sub] + map[bool]string{false: "", true: "s"}[s]
+		}
+		// extended register
+		sub, s := a.bit(30), a.bit(29)
+		option, imm3 := a.bits(13, 3), a.bits(10, 3)
+		if imm3 > 4 || a.bits(22, 2) != 0 {
+			return ""
+		}
+		ext := [8]string{"uxtb", "uxth", "uxtw", "uxtx", "sxtb", "sxth", "sxtw", "sxtx"}[option]
+		isLSL := (rd == 31 && !s || rn == 31) && (sf && option == 3 || !sf && option == 2)
+		index := reg(sf && option&3 == 3, rm, false)
+		var extArg string
+		switch {
+		case isLSL && imm3 == 0:
+		case isLSL:
+			extArg = fmt.Sprintf("lsl #%d", imm3)
+		case imm3 == 0:
+			extArg = ext
+		default:
+			extArg = fmt.Sprintf("%s #%d", ext, imm3)
+		}
+		args := []string{reg(sf, rn, true), index}
+		op := map[bool]string{false: "add", true: "sub"}[sub]
+		if s && rd == 31 {
+			op = map[bool]string{false: "cmn", true: "cmp"}[sub]
+		} else {
+			args = append([]string{reg(sf, rd, !s)}, args...)
+			if s {
+				op += "s"
+			}
+		}
+		if extArg != "" {
+			args = append(args, extArg)
+		}
+		a.set(args...)
+		return op
+	}
+	switch op2 := a.bits(21, 4); {
+	case op2 == 0:
+		if a.bits(10, 6) != 0 {
+			return ""
+		}
+		sub, s := a.bit(30), a.bit(29)
+		if sub && rn == 31 {
+			a.set(d, m)
+			return map[bool]string{false: "ngc", true: "ngcs"}[s]
+		}
+		a.set(d, n, m)
+		return map[bool]string{false: "adc", true: "sbc"}[sub] + map[bool]string{false: "", true: "s"}[s]
+	case op2 == 2:
+		if !a.bit(29) || a.bit(10) || a.bit(4) {
+			return ""
+		}
+		second := m
+		if a.bit(11) {
+			second = imm(uint64(rm))
+		}
+		a.set(n, second, imm(uint64(a.bits(0, 4))), arm64Conds[a.bits(12, 4)])
+		return map[bool]string{false: "ccmn", true: "ccmp"}[a.bit(30)]
+	case op2 == 4:
+		return a.condSelect(sf, d, n, m, rn, rm)
+	case op2 == 6:
+		if a.bit(30) {
+			return a.dataReg1(sf, d, n, rn)
+		}
+		return a.dataReg2(sf, d, n, m)
+	case op2&8 != 0:
+		return a.dataReg3(sf, rd, rn, rm)
+	}
+	return ""
+}
+
+func (a *arm64Dec) condSelect(sf bool, d, n, m string, rn, rm uint32) string {
+	if a.bit(29) || a.bit(11) {
+		return ""
+	}
+	op := a.bits(30, 1)<<1 | a.bits(10, 1)
+	cond := a.bits(12, 4)
+	inv := arm64Conds[cond^1]
+	switch {
+	case op == 1 && rn == 31 && rm == 31 && cond < 14:
+		a.set(d, inv)
+		return "cset"
+	case op == 2 && rn == 31 && rm == 31 && cond < 14:
+		a.set(d, inv)
+		return "csetm"
+	case op == 1 && rn == rm && rn != 31 && cond < 14:
+		a.set(d, n, inv)
+		return "cinc"
+	case op == 2 && rn == rm && rn != 31 && cond < 14:
+		a.set(d, n, inv)
+		return "cinv"
+	case op == 3 && rn == rm && cond < 14:
+		a.set(d, n, inv)
+		return "cneg"
+	}
+	a.set(d, n, m, arm64Conds[cond])
+	return [4]string{"csel", "csinc", "csinv", "csneg"}[op]
+}
+
+func (a *arm64Dec) dataReg1(sf bool, d, n string, rn uint32) string {
+	if a.bit(29) {
+		return ""
+	}
+	opcode := a.bits(10, 6)
+	switch a.bits(16, 5) {
+	case 0:
+		a.set(d, n)
+		switch opcode {
+		case 0:
+			return "rbit"
+		case 1:
+			return "rev16"
+		case 2:
+			if sf {
+				return "rev32"
+			}
+			return "rev"
+		case 3:
+			if sf {
+				return "rev"
+			}
+		case 4:
+			return "clz"
+		case 5:
+			return "cls"
+		}
+	case 1:
+		if !sf {
+			return ""
+		}
+		names := [8]string{"pacia", "pacib", "pacda", "pacdb", "autia", "autib", "autda", "autdb"}
+		switch {
+		case opcode < 8:
+			a.set(d, reg(true, rn, true))
+			return names[opcode]
+		case opcode < 16 && rn == 31:
+			a.set(d)
+			return names[opcode-8] + "z"
+		case opcode == 16 && rn == 31:
+			a.set(d)
+			return "xpaci"
+		case opcode == 17 && rn == 31:
+			a.set(d)
+			return "xpacd"
+		}
+	}
+	return ""
+}
+
+func (a *arm64Dec) dataReg2(sf bool, d, n, m string) string {
+	if a.bit(29) {
+		return ""
+	}
+	opcode := a.bits(10, 6)
+	a.set(d, n, m)
+	switch opcode {
+	case 2:
+		return "udiv"
+	case 3:
+		return "sdiv"
+	case 8, 9, 10, 11:
+		return [4]string{"lsl", "lsr", "asr", "ror"}[opcode-8]
+	case 12:
+		if sf {
+			a.set(d, reg(true, a.bits(5, 5), true), m)
+			return "pacga"
+		}
+	case 16, 17, 18, 20, 21, 22:
+		sz := opcode & 3
+		if sz == 3 != sf {
+			return ""
+		}
+		a.set(d, reg(false, a.bits(5, 5), false), reg(sz == 3, a.bits(16, 5), false))
+		op := "crc32"
+		if opcode&4 != 0 {
+			op = "crc32c"
+		}
+		return op + [4]string{"b", "h", "w", "x"}[sz]
+	case 19, 23:
+		if !sf {
+			return ""
+		}
+		a.set(reg(false, a.bits(0, 5), false), reg(false, a.bits(5, 5), false), x(a.bits(16, 5)))
+		if opcode == 19 {
+			return "crc32x"
+		}
+		return "crc32cx"
+	}
+	return ""
+}
+
+func (a *arm64Dec) dataReg3(sf bool, rd, rn, rm uint32) string {
+	op31, o0, ra := a.bits(21, 3), a.bit(15), a.bits(10, 5)
+	if a.bits(29, 2) != 0 {
+		return ""
+	}
+	d, n, m, acc := reg(sf, rd, false), reg(sf, rn, false), reg(sf, rm, false), reg(sf, ra, false)
+	switch op31 {
+	case 0:
+		if ra == 31 {
+			a.set(d, n, m)
+			return map[bool]string{false: "mul", true: "mneg"}[o0]
+		}
+		a.set(d, n, m, acc)
+		return map[bool]string{false: "madd", true: "msub"}[o0]
+	case 1, 5:
+		if !sf {
+			return ""
+		}
+		u := map[uint32]string{1: "s", 5: "u"}[op31]
+		wn, wm := reg(false, rn, false), reg(false, rm, false)
+		if ra == 31 {
+			a.set(d, wn, wm)
+			return u + map[bool]string{false: "mull", true: "mnegl"}[o0]
+		}
+		a.set(d, wn, wm, acc)
+		return u + map[bool]string{false: "maddl", true: "msubl"}[o0]
+	case 2, 6:
+		if !sf || o0 {
+			return ""
+		}
+		a.set(d, n, m)
+		return map[uint32]string{2: "smulh", 6: "umulh"}[op31]
+	}
+	return ""
+}
@@ -0,0 +1,861 @@
+package disasm
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// simdFP decodes the floating point and Advanced SIMD instructions.
+func (a *arm64Dec) simdFP() string {
+	w := a.w
+	switch {
+	case w&0x7f000000 == 0x1e000000:
+		return a.fpScalar()
+	case w&0xff000000 == 0x1f000000:
+		return a.fp3()
+	case w&0xffff0c00 == 0x4e280800:
+		return a.aes()
+	case w&0xffe08c00 == 0x5e000000:
+		return a.sha3()
+	case w&0xfffe0c00 == 0x5e280800:
+		return a.sha2()
+	case w&0x9f000000 == 0x0e000000:
+		return a.simdVector()
+	case w&0x9f000400 == 0x0f000000:
+		return a.byElement(false)
+	case w&0xdf000400 == 0x5f000000:
+		return a.byElement(true)
+	case w&0xde000000 == 0x5e000000:
+		return a.simdScalar()
+	case w&0x9ff80400 == 0x0f000400:
+		return a.modifiedImm()
+	case w&0x9f800400 == 0x0f000400:
+		return a.shiftImm()
+	}
+	return ""
+}
+
+// fpReg names scalar register n of FP type t.
+func fpReg(t, n uint32) string {
+	return fmt.Sprintf("%s%d", [4]string{"s", "d", "", "h"}[t], n)
+}
+
+// fpImm expands the 8-bit floating point immediate of fmov.
+func fpImm(imm8 uint32) string {
+	exp := int(^imm8>>6&1)<<2 | int(imm8>>4&3) - 3
+	v := math.Ldexp(1+float64(imm8&15)/16, exp)
+	if imm8&0x80 != 0 {
+		v = -v
+	}
+	return fmt.Sprintf("#%.18e", v)
+}
+
+func (a *arm64Dec) fpScalar() string {
+	sf, t := a.bit(31), a.bits(22, 2)
+	rd, rn, rm := a.bits(0, 5), a.bits(5, 5), a.bits(16, 5)
+	if a.bit(29) || t == 2 && a.bits(10, 6) != 0 {
+		return ""
+	}
+	if !a.bit(21) {
+		rmode, opcode, scale := a.bits(19, 2), a.bits(16, 3), a.bits(10, 6)
+		fbits := fmt.Sprintf("#%d", 64-scale)
+		switch {
+		case t == 2 || !sf && scale < 32:
+			return ""
+		case rmode == 0 && (opcode == 2 || opcode == 3):
+			a.set(fpReg(t, rd), reg(sf, rn, false), fbits)
+			return map[uint32]string{2: "scvtf", 3: "ucvtf"}[opcode]
+		case rmode == 3 && opcode < 2:
+			a.set(reg(sf, rd, false), fpReg(t, rn), fbits)
+			return map[uint32]string{0: "fcvtzs", 1: "fcvtzu"}[opcode]
+		}
+		return ""
+	}
+	if a.bit(31) && a.bits(10, 6) != 0 {
+		return ""
+	}
+	switch {
+	case a.bits(10, 6) == 0:
+		rmode, opcode := a.bits(19, 2), a.bits(16, 3)
+		switch {
+		case opcode == 6 && rmode == 1 && t == 2 && sf:
+			a.set(x(rd), fmt.Sprintf("v%d.d[1]", rn))
+			return "fmov"
+		case opcode == 7 && rmode == 1 && t == 2 && sf:
+			a.set(fmt.Sprintf("v%d.d[1]", rd), x(rn))
+			return "fmov"
+		case t == 2:
+			return ""
+		case opcode >= 6 && t != 3 && sf != (t == 1):
+			return ""
+		case opcode == 6 && rmode == 0:
+			a.set(reg(sf, rd, false), fpReg(t, rn))
+			return "fmov"
+		case opcode == 7 && rmode == 0:
+			a.set(fpReg(t, rd), reg(sf, rn, false))
+			return "fmov"
+		case opcode == 2 || opcode == 3:
+			if rmode != 0 {
+				return ""
+			}
+			a.set(fpReg(t, rd), reg(sf, rn, false))
+			return map[uint32]string{2: "scvtf", 3: "ucvtf"}[opcode]
+		case opcode < 2:
+			a.set(reg(sf, rd, false), fpReg(t, rn))
+			return "fcvt" + [4]string{"n", "p", "m", "z"}[rmode] + [2]string{"s", "u"}[opcode]
+		case opcode < 6 && rmode == 0:
+			a.set(reg(sf, rd, false), fpReg(t, rn))
+			return "fcvta" + [2]string{"s", "u"}[opcode-4]
+		}
+		return ""
+	case a.bits(10, 5) == 0x10:
+		opcode := a.bits(15, 6)
+		switch {
+		case opcode < 4:
+			a.set(fpReg(t, rd), fpReg(t, rn))
+			return [4]string{"fmov", "fabs", "fneg", "fsqrt"}[opcode]
+		case opcode&0x3c == 4:
+			to := opcode & 3
+			if to == 2 || to == t {
+				return ""
+			}
+			a.set(fpReg(to, rd), fpReg(t, rn))
+			return "fcvt"
+		case opcode >= 8 && opcode < 16 && opcode != 13:
+			a.set(fpReg(t, rd), fpReg(t, rn))
+			return "frint" + [8]string{"n", "p", "m", "z", "a", "", "x", "i"}[opcode-8]
+		}
+		return ""
+	case a.bits(10, 4) == 8:
+		if a.bits(14, 2) != 0 || a.bits(0, 3) != 0 {
+			return ""
+		}
+		second := fpReg(t, rm)
+		if a.bit(3) {
+			second = "#0.0"
+		}
+		a.set(fpReg(t, rn), second)
+		return map[bool]string{false: "fcmp", true: "fcmpe"}[a.bit(4)]
+	case a.bits(10, 3) == 4:
+		if a.bits(5, 5) != 0 {
+			return ""
+		}
+		a.set(fpReg(t, rd), fpImm(a.bits(13, 8)))
+		return "fmov"
+	case a.bits(10, 2) == 1:
+		a.set(fpReg(t, rn), fpReg(t, rm), imm(uint64(a.bits(0, 4))), arm64Conds[a.bits(12, 4)])
+		return map[bool]string{false: "fccmp", true: "fccmpe"}[a.bit(4)]
+	case a.bits(10, 2) == 2:
+		opcode := a.bits(12, 4)
+		if opcode > 8 {
+			return ""
+		}
+		a.set(fpReg(t, rd), fpReg(t, rn), fpReg(t, rm))
+		return [9]string{"fmul", "fdiv", "fadd", "fsub", "fmax", "fmin", "fmaxnm", "fminnm", "fnmul"}[opcode]
+	case a.bits(10, 2) == 3:
+		a.set(fpReg(t, rd), fpReg(t, rn), fpReg(t, rm), arm64Conds[a.bits(12, 4)])
+		return "fcsel"
+	}
+	return ""
+}
+
+func (a *arm64Dec) fp3() string {
+	t := a.bits(22, 2)
+	if t == 2 {
+		return ""
+	}
+	a.set(fpReg(t, a.bits(0, 5)), fpReg(t, a.bits(5, 5)), fpReg(t, a.bits(16, 5)), fpReg(t, a.bits(10, 5)))
+	return [4]string{"fmadd", "fmsub", "fnmadd", "fnmsub"}[a.bits(21, 1)<<1|a.bits(15, 1)]
+}
+
+func (a *arm64Dec) aes() string {
+	opcode := a.bits(12, 5)
+	if opcode < 4 || opcode > 7 {
+		return ""
+	}
+	a.set(vreg(a.bits(0, 5), "16b"), vreg(a.bits(5, 5), "16b"))
+	return [4]string{"aese", "aesd", "aesmc", "aesimc"}[opcode-4]
+}
+
+func (a *arm64Dec) sha3() string {
+	rd, rn, rm := a.bits(0, 5), a.bits(5, 5), a.bits(16, 5)
+	switch opcode := a.bits(12, 3); opcode {
+	case 0, 1, 2:
+		a.set(fmt.Sprintf("q%d", rd), fmt.Sprintf("s%d", rn), vreg(rm, "4s"))
+		return [3]string{"sha1c", "sha1p", "sha1m"}[opcode]
+	case 3, 6:
+		a.set(vreg(rd, "4s"), vreg(rn, "4s"), vreg(rm, "4s"))
+		return map[uint32]string{3: "sha1su0", 6: "sha256su1"}[opcode]
+	case 4, 5:
+		a.set(fmt.Sprintf("q%d", rd), fmt.Sprintf("q%d", rn), vreg(rm, "4s"))
+		return map[uint32]string{4: "sha256h", 5: "sha256h2"}[opcode]
+	}
+	return ""
+}
+
+func (a *arm64Dec) sha2() string {
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	switch opcode := a.bits(12, 5); opcode {
+	case 0:
+		a.set(fmt.Sprintf("s%d", rd), fmt.Sprintf("s%d", rn))
+		return "sha1h"
+	case 1, 2:
+		a.set(vreg(rd, "4s"), vreg(rn, "4s"))
+		return map[uint32]string{1: "sha1su1", 2: "sha256su0"}[opcode]
+	}
+	return ""
+}
+
+// element decodes the imm5 field of the copy instructions into the
+// element size letter and index.
+func element(imm5 uint32) (string, uint32, bool) {
+	if imm5&0xf == 0 {
+		return "", 0, false
+	}
+	size := bits.TrailingZeros32(imm5)
+	return [4]string{"b", "h", "s", "d"}[size], imm5 >> (size + 1), true
+}
+
+// arm64SameInt names the integer three-same instructions by U and
+// opcode; opcode 3 is the logical group.
+var arm64SameInt = [2][24]string{
+	{"shadd", "sqadd", "srhadd", "", "shsub", "sqsub", "cmgt", "cmge", "sshl", "sqshl", "srshl", "sqrshl",
+		"smax", "smin", "sabd", "saba", "add", "cmtst", "mla", "mul", "smaxp", "sminp", "sqdmulh", "addp"},
+	{"uhadd", "uqadd", "urhadd", "", "uhsub", "uqsub", "cmhi", "cmhs", "ushl", "uqshl", "urshl", "uqrshl",
+		"umax", "umin", "uabd", "uaba", "sub", "cmeq", "mls", "pmul", "umaxp", "uminp", "sqrdmulh", ""},
+}
+
+// arm64SameInt2D marks the integer three-same opcodes that take 2d.
+var arm64SameInt2D = map[uint32]bool{1: true, 5: true, 6: true, 7: true, 8: true, 9: true, 10: true, 11: true, 16: true, 17: true, 23: true}
+
+// arm64SameFloat names the float three-same instructions by U, the
+// top size bit and opcode-24.
+var arm64SameFloat = [2][2][8]string{
+	{{"fmaxnm", "fmla", "fadd", "fmulx", "fcmeq", "", "fmax", "frecps"},
+		{"fminnm", "fmls", "fsub", "", "", "", "fmin", "frsqrts"}},
+	{{"fmaxnmp", "", "faddp", "fmul", "fcmge", "facge", "fmaxp", "fdiv"},
+		{"fminnmp", "", "fabd", "", "fcmgt", "facgt", "fminp", ""}},
+}
+
+// arm64Misc names the integer two-register misc instructions by U and
+// opcode.
+var arm64Misc = [2][21]string{
+	{"rev64", "rev16", "saddlp", "suqadd", "cls", "cnt", "sadalp", "sqabs", "cmgt", "cmeq", "cmlt", "abs",
+		18: "xtn", 20: "sqxtn"},
+	{"rev32", "", "uaddlp", "usqadd", "clz", "", "uadalp", "sqneg", "cmge", "cmle", "", "neg",
+		18: "sqxtun", 19: "shll", 20: "uqxtn"},
+}
+
+// arm64MiscFloat names the float two-register misc instructions by U,
+// the top size bit and opcode-12.
+var arm64MiscFloat = [2][2][20]string{
+	{{12: "frintn", 13: "frintm", 14: "fcvtns", 15: "fcvtms", 16: "fcvtas", 17: "scvtf"},
+		{0: "fcmgt", 1: "fcmeq", 2: "fcmlt", 3: "fabs", 12: "frintp", 13: "frintz", 14: "fcvtps", 15: "fcvtzs", 16: "urecpe", 17: "frecpe"}},
+	{{12: "frinta", 13: "frintx", 14: "fcvtnu", 15: "fcvtmu", 16: "fcvtau", 17: "ucvtf"},
+		{0: "fcmge", 1: "fcmle", 3: "fneg", 13: "frinti", 14: "fcvtpu", 15: "fcvtzu", 16: "ursqrte", 17: "frsqrte", 19: "fsqrt"}},
+}
+
+// simdVector decodes the vector instructions of the 0Q?01110 space.
+func (a *arm64Dec) simdVector() string {
+	q, u := a.bit(30), a.bits(29, 1)
+	size := a.bits(22, 2)
+	rd, rn, rm := a.bits(0, 5), a.bits(5, 5), a.bits(16, 5)
+	if !a.bit(21) {
+		switch {
+		case a.w&0x9f60c400 == 0x0e400400:
+			name := arm64SameFloat[u][a.bits(23, 1)][a.bits(11, 3)]
+			if name == "" {
+				return ""
+			}
+			arr := map[bool]string{false: "4h", true: "8h"}[q]
+			a.set(vreg(rd, arr), vreg(rn, arr), vreg(rm, arr))
+			return name
+		case a.w&0xbfe08400 == 0x0e000400 || a.w&0xffe08400 == 0x6e000400:
+			return a.simdCopy()
+		case a.w&0xbf208c00 == 0x0e000800 && u == 0:
+			op := a.bits(12, 3)
+			names := [8]string{1: "uzp1", 2: "trn1", 3: "zip1", 5: "uzp2", 6: "trn2", 7: "zip2"}
+			if names[op] == "" || size == 3 && !q {
+				return ""
+			}
+			arr := arrangement(size, q)
+			a.set(vreg(rd, arr), vreg(rn, arr), vreg(rm, arr))
+			return names[op]
+		case a.w&0xbfe08c00 == 0x0e000000:
+			arr := map[bool]string{false: "8b", true: "16b"}[q]
+			a.set(vreg(rd, arr), regList(rn, int(a.bits(13, 2))+1, "16b"), vreg(rm, arr))
+			return map[bool]string{false: "tbl", true: "tbx"}[a.bit(12)]
+		case a.w&0xbfe08400 == 0x2e000000:
+			if !q && a.bit(14) {
+				return ""
+			}
+			arr := map[bool]string{false: "8b", true: "16b"}[q]
+			a.set(vreg(rd, arr), vreg(rn, arr), vreg(rm, arr), fmt.Sprintf("#%d", a.bits(11, 4)))
+			return "ext"
+		}
+		return ""
+	}
+	switch {
+	case a.bit(10):
+		opcode := a.bits(11, 5)
+		if opcode >= 24 {
+			name := arm64SameFloat[u][size>>1][opcode-24]
+			if name == "" || size&1 == 1 && !q {
+				return ""
+			}
+			arr := arrangement(2+size&1, q)
+			a.set(vreg(rd, arr), vreg(rn, arr), vreg(rm, arr))
+			return name
+		}
+		if opcode == 3 {
+			arr := map[bool]string{false: "8b", true: "16b"}[q]
+			if u == 0 && size == 2 && rn == rm {
+				a.set(vreg(rd, arr), vreg(rn, arr))
+				return "mov"
+			}
+			a.set(vreg(rd, arr), vreg(rn, arr), vreg(rm, arr))
+			return [2][4]string{{"and", "bic", "orr", "orn"}, {"eor", "bsl", "bit", "bif"}}[u][size]
+		}
+		name := arm64SameInt[u][opcode]
+		if name == "" || size == 3 && (!q || !arm64SameInt2D[opcode]) ||
+			opcode == 22 && (size == 0 || size == 3) || opcode == 19 && u == 1 && size != 0 {
+			return ""
+		}
+		arr := arrangement(size, q)
+		a.set(vreg(rd, arr), vreg(rn, arr), vreg(rm, arr))
+		return name
+	case a.bits(10, 2) == 0:
+		return a.simdDifferent()
+	case a.bits(17, 4) == 0:
+		return a.simdMisc()
+	case a.bits(17, 4) == 8:
+		return a.simdAcross()
+	}
+	return ""
+}
+
+func (a *arm64Dec) simdCopy() string {
+	q, op := a.bit(30), a.bit(29)
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	imm4 := a.bits(11, 4)
+	t, index, ok := element(a.bits(16, 5))
+	if !ok {
+		return ""
+	}
+	size := uint32(map[string]int{"b": 0, "h": 1, "s": 2, "d": 3}[t])
+	elem := func(n, i uint32) string { return fmt.Sprintf("v%d.%s[%d]", n, t, i) }
+	if op {
+		src := imm4 >> size
+		a.set(elem(rd, index), elem(rn, src))
+		return "mov"
+	}
+	switch imm4 {
+	case 0:
+		if size == 3 && !q {
+			return ""
+		}
+		a.set(vreg(rd, arrangement(size, q)), elem(rn, index))
+		return "dup"
+	case 1:
+		if size == 3 && !q {
+			return ""
+		}
+		a.set(vreg(rd, arrangement(size, q)), reg(size == 3, rn, false))
+		return "dup"
+	case 3:
+		if !q {
+			return ""
+		}
+		a.set(elem(rd, index), reg(size == 3, rn, false))
+		return "mov"
+	case 5:
+		if size > 2 || size == 2 && !q {
+			return ""
+		}
+		a.set(reg(q, rd, false), elem(rn, index))
+		return "smov"
+	case 7:
+		if size == 3 != q {
+			return ""
+		}
+		a.set(reg(q, rd, false), elem(rn, index))
+		if size >= 2 {
+			return "mov"
+		}
+		return "umov"
+	}
+	return ""
+}
+
+// long returns the arrangement of elements twice the size.
+func long(size uint32) string {
+	if size == 3 {
+		return "1q"
+	}
+	return arrangement(size+1, true)
+}
+
+func (a *arm64Dec) simdDifferent() string {
+	q, u, size := a.bit(30), a.bits(29, 1), a.bits(22, 2)
+	rd, rn, rm := a.bits(0, 5), a.bits(5, 5), a.bits(16, 5)
+	opcode := a.bits(12, 4)
+	names := [2][15]string{
+		{"saddl", "saddw", "ssubl", "ssubw", "addhn", "sabal", "subhn", "sabdl", "smlal", "sqdmlal", "smlsl", "sqdmlsl", "smull", "sqdmull", "pmull"},
+		{"uaddl", "uaddw", "usubl", "usubw", "raddhn", "uabal", "rsubhn", "uabdl", "umlal", "", "umlsl", "", "umull", "", ""},
+	}
+	if opcode > 14 || names[u][opcode] == "" || size == 3 && opcode != 14 || size == 2 && opcode == 14 || size == 1 && opcode == 14 ||
+		opcode&9 == 9 && opcode < 14 && size == 0 {
+		return ""
+	}
+	name := names[u][opcode]
+	if q {
+		name += "2"
+	}
+	wide, narrow := long(size), arrangement(size, q)
+	switch opcode {
+	case 1, 3:
+		a.set(vreg(rd, wide), vreg(rn, wide), vreg(rm, narrow))
+	case 4, 6:
+		a.set(vreg(rd, narrow), vreg(rn, wide), vreg(rm, wide))
+	default:
+		a.set(vreg(rd, wide), vreg(rn, narrow), vreg(rm, narrow))
+	}
+	return name
+}
+
+func (a *arm64Dec) simdMisc() string {
+	q, u, size := a.bit(30), a.bits(29, 1), a.bits(22, 2)
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	opcode := a.bits(12, 5)
+	arr := arrangement(size, q)
+	switch {
+	case opcode >= 12 && (opcode < 16 || opcode >= 24) && opcode != 22 && opcode != 23:
+		name := ""
+		if opcode-12 < 20 {
+			name = arm64MiscFloat[u][size>>1][opcode-12]
+		}
+		if name == "" || size&1 == 1 && !q {
+			return ""
+		}
+		farr := arrangement(2+size&1, q)
+		a.set(vreg(rd, farr), vreg(rn, farr))
+		if opcode < 16 && name != "fabs" && name != "fneg" {
+			a.args = append(a.args, "#0.0")
+		}
+		return name
+	case opcode == 22 || opcode == 23:
+		if size > 1 || u == 1 && (opcode == 23 || size == 0) {
+			return ""
+		}
+		name := map[uint32]string{22: "fcvtn", 23: "fcvtl"}[opcode]
+		if u == 1 {
+			name = "fcvtxn"
+		}
+		if q {
+			name += "2"
+		}
+		wide := arrangement(2+size, true)
+		narrow := arrangement(1+size, q)
+		if opcode == 23 {
+			a.set(vreg(rd, wide), vreg(rn, narrow))
+		} else {
+			a.set(vreg(rd, narrow), vreg(rn, wide))
+		}
+		return name
+	case opcode == 5 && u == 1:
+		if size > 1 {
+			return ""
+		}
+		barr := map[bool]string{false: "8b", true: "16b"}[q]
+		a.set(vreg(rd, barr), vreg(rn, barr))
+		return [2]string{"not", "rbit"}[size]
+	case opcode > 20:
+		return ""
+	}
+	name := arm64Misc[u][opcode]
+	if name == "" || size == 3 && !q {
+		return ""
+	}
+	switch opcode {
+	case 2, 6:
+		if size == 3 {
+			return ""
+		}
+		a.set(vreg(rd, arrangement(size+1, q)), vreg(rn, arr))
+	case 18, 19, 20:
+		if size == 3 {
+			return ""
+		}
+		if q {
+			name += "2"
+		}
+		if opcode == 19 {
+			a.set(vreg(rd, long(size)), vreg(rn, arr), fmt.Sprintf("#%d", 8<<size))
+		} else {
+			a.set(vreg(rd, arr), vreg(rn, long(size)))
+		}
+	case 8, 9, 10:
+		a.set(vreg(rd, arr), vreg(rn, arr), "#0")
+	case 0, 1, 5:
+		if opcode != 0 && size != 0 || size == 3 || u == 1 && size == 2 {
+			return ""
+		}
+		a.set(vreg(rd, arr), vreg(rn, arr))
+	default:
+		a.set(vreg(rd, arr), vreg(rn, arr))
+	}
+	return name
+}
+
+func (a *arm64Dec) simdAcross() string {
+	q, u, size := a.bit(30), a.bits(29, 1), a.bits(22, 2)
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	opcode := a.bits(12, 5)
+	if opcode == 12 || opcode == 15 {
+		if u == 0 || !q || size&1 != 0 {
+			return ""
+		}
+		a.set(fmt.Sprintf("s%d", rd), vreg(rn, "4s"))
+		names := map[uint32][2]string{12: {"fmaxnmv", "fminnmv"}, 15: {"fmaxv", "fminv"}}
+		return names[opcode][size>>1]
+	}
+	names := map[uint32][2]string{3: {"saddlv", "uaddlv"}, 10: {"smaxv", "umaxv"}, 26: {"sminv", "uminv"}, 27: {"addv", ""}}
+	n, ok := names[opcode]
+	if !ok || n[u] == "" || size == 3 || size == 2 && !q {
+		return ""
+	}
+	dst := size
+	if opcode == 3 {
+		dst++
+	}
+	a.set(fmt.Sprintf("%s%d", arm64FPRegs[dst], rd), vreg(rn, arrangement(size, q)))
+	return n[u]
+}
+
+// simdScalar decodes the scalar forms of the Advanced SIMD instructions
+// that compilers emit.
+func (a *arm64Dec) simdScalar() string {
+	u, size := a.bits(29, 1), a.bits(22, 2)
+	rd, rn, rm := a.bits(0, 5), a.bits(5, 5), a.bits(16, 5)
+	switch {
+	case a.w&0xffe0fc00 == 0x5e000400:
+		t, index, ok := element(a.bits(16, 5))
+		if !ok {
+			return ""
+		}
+		size := map[string]uint32{"b": 0, "h": 1, "s": 2, "d": 3}[t]
+		a.set(fmt.Sprintf("%s%d", arm64FPRegs[size], rd), fmt.Sprintf("v%d.%s[%d]", rn, t, index))
+		return "mov"
+	case a.w&0xdf3e0c00 == 0x5e300800:
+		opcode := a.bits(12, 5)
+		if u == 0 && opcode == 27 && size == 3 {
+			a.set(fmt.Sprintf("d%d", rd), vreg(rn, "2d"))
+			return "addp"
+		}
+		names := map[uint32][2]string{12: {"fmaxnmp", "fminnmp"}, 13: {"faddp", ""}, 15: {"fmaxp", "fminp"}}
+		n, ok := names[opcode]
+		if u == 0 || !ok || n[size>>1] == "" {
+			return ""
+		}
+		if size&1 == 0 {
+			a.set(fmt.Sprintf("s%d", rd), vreg(rn, "2s"))
+		} else {
+			a.set(fmt.Sprintf("d%d", rd), vreg(rn, "2d"))
+		}
+		return n[size>>1]
+	case !a.bit(24) && a.bit(21) && a.bit(10):
+		opcode := a.bits(11, 5)
+		r := func(size, n uint32) string { return fmt.Sprintf("%s%d", arm64FPRegs[size], n) }
+		if opcode >= 24 {
+			name := arm64SameFloat[u][size>>1][opcode-24]
+			switch name {
+			case "fmulx", "fcmeq", "frecps", "frsqrts", "fcmge", "facge", "fabd", "fcmgt", "facgt":
+			default:
+				return ""
+			}
+			a.set(r(2+size&1, rd), r(2+size&1, rn), r(2+size&1, rm))
+			return name
+		}
+		switch {
+		case opcode == 1 || opcode == 5 || opcode == 9 || opcode == 11:
+		case opcode == 22 && (size == 1 || size == 2):
+		case size == 3 && (opcode >= 6 && opcode <= 10 || opcode == 16 || opcode == 17):
+		default:
+			return ""
+		}
+		a.set(r(size, rd), r(size, rn), r(size, rm))
+		return arm64SameInt[u][opcode]
+	case !a.bit(24) && a.bit(21) && a.bits(10, 2) == 2 && a.bits(17, 4) == 0:
+		opcode := a.bits(12, 5)
+		if opcode >= 12 && opcode < 32 && (opcode < 16 || opcode > 25) {
+			name := arm64MiscFloat[u][size>>1][opcode-12]
+			if name == "" || strings.HasPrefix(name, "frint") || name == "fabs" || name == "fneg" || name == "fsqrt" {
+				return ""
+			}
+			r := func(n uint32) string { return fpReg(size&1, n) }
+			a.set(r(rd), r(rn))
+			if opcode < 16 {
+				a.args = append(a.args, "#0.0")
+			}
+			return name
+		}
+		if size != 3 {
+			return ""
+		}
+		switch opcode {
+		case 8, 9, 10:
+			if u == 1 && opcode == 10 {
+				return ""
+			}
+			a.set(fmt.Sprintf("d%d", rd), fmt.Sprintf("d%d", rn), "#0")
+		case 11:
+			a.set(fmt.Sprintf("d%d", rd), fmt.Sprintf("d%d", rn))
+		default:
+			return ""
+		}
+		return arm64Misc[u][opcode]
+	case a.w&0xdf800400 == 0x5f000400 && a.bits(19, 4) != 0:
+		opcode, immh := a.bits(11, 5), a.bits(19, 4)
+		f, ok := arm64ShiftForms[u][opcode]
+		if !ok || opcode == 20 || u == 0 && (opcode == 16 || opcode == 17) {
+			return ""
+		}
+		esize := uint32(bits.Len32(immh) - 1)
+		v := a.bits(16, 7)
+		amount := 16<<esize - v
+		if f.left {
+			amount = v - 8<<esize
+		}
+		r := func(size, n uint32) string { return fmt.Sprintf("%s%d", arm64FPRegs[size], n) }
+		switch {
+		case opcode >= 16:
+			if opcode < 20 {
+				if esize == 3 {
+					return ""
+				}
+				a.set(r(esize, rd), r(esize+1, rn), fmt.Sprintf("#%d", amount))
+				return f.name
+			}
+			if esize < 2 {
+				return ""
+			}
+		case opcode < 12 && esize != 3:
+			return ""
+		}
+		a.set(r(esize, rd), r(esize, rn), fmt.Sprintf("#%d", amount))
+		return f.name
+	}
+	return ""
+}
+
+// arm64ByElement names the by-element instructions by U and opcode;
+// float marks the floating point ones and long the widening ones.
+var arm64ByElement = [2][16]struct {
+	name        string
+	float, long bool
+}{
+	{1: {"fmla", true, false}, 2: {"smlal", false, true}, 3: {"sqdmlal", false, true}, 5: {"fmls", true, false},
+		6: {"smlsl", false, true}, 7: {"sqdmlsl", false, true}, 8: {"mul", false, false}, 9: {"fmul", true, false},
+		10: {"smull", false, true}, 11: {"sqdmull", false, true}, 12: {"sqdmulh", false, false}, 13: {"sqrdmulh", false, false}},
+	{0: {"mla", false, false}, 2: {"umlal", false, true}, 4: {"mls", false, false}, 6: {"umlsl", false, true},
+		9: {"fmulx", true, false}, 10: {"umull", false, true}, 13: {"sqrdmlah", false, false}, 15: {"sqrdmlsh", false, false}},
+}
+
+// byElement decodes the vector and scalar instructions whose last
+// operand is a vector element.
+func (a *arm64Dec) byElement(scalar bool) string {
+	q, u, size := a.bit(30), a.bits(29, 1), a.bits(22, 2)
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	l, m, h := a.bits(21, 1), a.bits(20, 1), a.bits(11, 1)
+	e := arm64ByElement[u][a.bits(12, 4)]
+	if e.name == "" {
+		return ""
+	}
+	esize := size
+	if e.float {
+		switch size {
+		case 0:
+			esize = 1
+		case 1:
+			return ""
+		}
+	}
+	var index, rm uint32
+	switch esize {
+	case 1:
+		index, rm = h<<2|l<<1|m, a.bits(16, 4)
+	case 2:
+		index, rm = h<<1|l, a.bits(16, 5)
+	case 3:
+		if !e.float || l == 1 || !q && !scalar {
+			return ""
+		}
+		index, rm = h, a.bits(16, 5)
+	default:
+		return ""
+	}
+	if !e.float && (esize == 3 || (e.name == "mul" || e.name == "mla" || e.name == "mls") && scalar) {
+		return ""
+	}
+	elem := fmt.Sprintf("v%d.%s[%d]", rm, [4]string{"b", "h", "s", "d"}[esize], index)
+	if scalar {
+		if !e.float && !strings.HasPrefix(e.name, "sq") {
+			return ""
+		}
+		dst := esize
+		if e.long {
+			dst++
+		}
+		a.set(fmt.Sprintf("%s%d", arm64FPRegs[dst], rd), fmt.Sprintf("%s%d", arm64FPRegs[esize], rn), elem)
+		return e.name
+	}
+	name := e.name
+	arr := arrangement(esize, q)
+	if e.long {
+		if q {
+			name += "2"
+		}
+		a.set(vreg(rd, long(esize)), vreg(rn, arr), elem)
+		return name
+	}
+	a.set(vreg(rd, arr), vreg(rn, arr), elem)
+	return name
+}
+
+// modifiedImm decodes movi, mvni, orr, bic and fmov of a vector
+// immediate.
+func (a *arm64Dec) modifiedImm() string {
+	q, op := a.bit(30), a.bit(29)
+	cmode := a.bits(12, 4)
+	rd := a.bits(0, 5)
+	imm8 := a.bits(16, 3)<<5 | a.bits(5, 5)
+	if a.bit(11) {
+		return ""
+	}
+	s4 := map[bool]string{false: "2s", true: "4s"}[q]
+	h8 := map[bool]string{false: "4h", true: "8h"}[q]
+	switch {
+	case cmode&9 == 0:
+		a.set(vreg(rd, s4), imm(uint64(imm8)))
+		if sh := cmode >> 1 & 3; sh != 0 {
+			a.args = append(a.args, fmt.Sprintf("lsl #%d", 8*sh))
+		}
+		return map[bool]string{false: "movi", true: "mvni"}[op]
+	case cmode&9 == 1:
+		a.set(vreg(rd, s4), imm(uint64(imm8)))
+		if sh := cmode >> 1 & 3; sh != 0 {
+			a.args = append(a.args, fmt.Sprintf("lsl #%d", 8*sh))
+		}
+		return map[bool]string{false: "orr", true: "bic"}[op]
+	case cmode&13 == 8:
+		a.set(vreg(rd, h8), imm(uint64(imm8)))
+		if cmode&2 != 0 {
+			a.args = append(a.args, "lsl #8")
+		}
+		return map[bool]string{false: "movi", true: "mvni"}[op]
+	case cmode&13 == 9:
+		a.set(vreg(rd, h8), imm(uint64(imm8)))
+		if cmode&2 != 0 {
+			a.args = append(a.args, "lsl #8")
+		}
+		return map[bool]string{false: "orr", true: "bic"}[op]
+	case cmode&14 == 12:
+		a.set(vreg(rd, s4), imm(uint64(imm8)), fmt.Sprintf("msl #%d", 8<<(cmode&1)))
+		return map[bool]string{false: "movi", true: "mvni"}[op]
+	case cmode == 14 && !op:
+		a.set(vreg(rd, map[bool]string{false: "8b", true: "16b"}[q]), imm(uint64(imm8)))
+		return "movi"
+	case cmode == 14:
+		var v uint64
+		for i := uint(0); i < 8; i++ {
+			if imm8>>i&1 != 0 {
+				v |= 0xff << (8 * i)
+			}
+		}
+		if q {
+			a.set(vreg(rd, "2d"), imm(v))
+		} else {
+			a.set(fmt.Sprintf("d%d", rd), imm(v))
+		}
+		return "movi"
+	case !op:
+		a.set(vreg(rd, s4), fpImm(imm8))
+		return "fmov"
+	case q:
+		a.set(vreg(rd, "2d"), fpImm(imm8))
+		return "fmov"
+	}
+	return ""
+}
+
+// arm64ShiftForm is a shift by immediate; left ones count the shift
+// up from the element size, the others down from twice it.
+type arm64ShiftForm struct {
+	name string
+	left bool
+}
+
+// arm64ShiftForms names the shifts by immediate by U and opcode.
+var arm64ShiftForms = [2]map[uint32]arm64ShiftForm{
+	{0: {"sshr", false}, 2: {"ssra", false}, 4: {"srshr", false}, 6: {"srsra", false}, 10: {"shl", true}, 14: {"sqshl", true},
+		16: {"shrn", false}, 17: {"rshrn", false}, 18: {"sqshrn", false}, 19: {"sqrshrn", false}, 20: {"sshll", true},
+		28: {"scvtf", false}, 31: {"fcvtzs", false}},
+	{0: {"ushr", false}, 2: {"usra", false}, 4: {"urshr", false}, 6: {"ursra", false}, 8: {"sri", false}, 10: {"sli", true},
+		12: {"sqshlu", true}, 14: {"uqshl", true}, 16: {"sqshrun", false}, 17: {"sqrshrun", false}, 18: {"uqshrn", false},
+		19: {"uqrshrn", false}, 20: {"ushll", true}, 28: {"ucvtf", false}, 31: {"fcvtzu", false}},
+}
+
+// shiftImm decodes the vector shifts by immediate.
+func (a *arm64Dec) shiftImm() string {
+	q, u := a.bit(30), a.bits(29, 1)
+	rd, rn := a.bits(0, 5), a.bits(5, 5)
+	immh, opcode := a.bits(19, 4), a.bits(11, 5)
+	size := uint32(bits.Len32(immh) - 1)
+	esize := uint32(8) << size
+	v := a.bits(16, 7)
+	right, left := 2*esize-v, v-esize
+	arr := arrangement(size, q)
+	f, ok := arm64ShiftForms[u][opcode]
+	if !ok {
+		return ""
+	}
+	amount := right
+	if f.left {
+		amount = left
+	}
+	sh := fmt.Sprintf("#%d", amount)
+	switch {
+	case opcode >= 16 && opcode <= 19:
+		if size == 3 {
+			return ""
+		}
+		name := f.name
+		if q {
+			name += "2"
+		}
+		a.set(vreg(rd, arr), vreg(rn, long(size)), fmt.Sprintf("#%d", esize-left))
+		return name
+	case opcode == 20:
+		if size == 3 {
+			return ""
+		}
+		name := f.name
+		if left == 0 {
+			name = map[uint32]string{0: "sxtl", 1: "uxtl"}[u]
+		}
+		if q {
+			name += "2"
+		}
+		a.set(vreg(rd, long(size)), vreg(rn, arr))
+		if left != 0 {
+			a.args = append(a.args, sh)
+		}
+		return name
+	case size == 3 && !q || (opcode == 28 || opcode == 31) && size < 2:
+		return ""
+	}
+	a.set(vreg(rd, arr), vreg(rn, arr), sh)
+	return f.name
+}
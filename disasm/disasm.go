@@ -0,0 +1,111 @@
+// Package disasm decodes machine instructions of x86-64, i386 and
+// AArch64 into text in the Intel syntax of objdump -M intel for x86
+// and the GNU syntax for AArch64, together with the addresses the
+// instructions branch to or refer to, so that callers can name them.
+package disasm
+
+import (
+	"debug/elf"
+	"fmt"
+	"strings"
+)
+
+// An Arch is an instruction set the package decodes.
+type Arch int
+
+// Instruction sets.
+const (
+	I386 Arch = iota
+	X86_64
+	ARM64
+)
+
+func (a Arch) String() string {
+	switch a {
+	case I386:
+		return "i386"
+	case X86_64:
+		return "x86-64"
+	case ARM64:
+		return "aarch64"
+	}
+	return fmt.Sprintf("Arch(%d)", int(a))
+}
+
+// ForMachine returns the instruction set of ELF files for machine.
+func ForMachine(m elf.Machine) (Arch, error) {
+	switch m {
+	case elf.EM_386:
+		return I386, nil
+	case elf.EM_X86_64:
+		return X86_64, nil
+	case elf.EM_AARCH64:
+		return ARM64, nil
+	}
+	return 0, fmt.Errorf("disassembly of %v is not supported", m)
+}
+
+// An Inst is a decoded instruction.
+type Inst struct {
+	// Len is the length of the instruction in bytes; undecodable bytes
+	// make an Inst of Op "(bad)" and Len 1 on x86, and ".inst" on
+	// AArch64
+	Len  int
+	Op   string
+	Args []string
+	// Target is the destination of a direct branch or call
+	Target    uint64
+	HasTarget bool
+	// Ref is the address of a PC-relative data reference, or the one
+	// an adrp and the instruction using its page compute together
+	Ref    uint64
+	HasRef bool
+	// armSep separates the operands with ", " as AArch64 does
+	armSep bool
+}
+
+// String spells i the way objdump does: the mnemonic padded to six
+// columns and then the operands.
+func (i Inst) String() string {
+	if len(i.Args) == 0 {
+		return i.Op
+	}
+	sep := ","
+	if i.armSep {
+		sep = ", "
+	}
+	return fmt.Sprintf("%-6s %s", i.Op, strings.Join(i.Args, sep))
+}
+
+// A Decoder decodes the instructions of one instruction set. It keeps
+// what earlier instructions said that later ones need, such as the
+// pages adrp put in registers, so a Decoder is for one stream of
+// instructions; Reset it at function boundaries.
+type Decoder struct {
+	arch Arch
+	// pages are the adrp pages of x0 to x30
+	pages [31]uint64
+	known uint32
+}
+
+// NewDecoder returns a decoder for arch.
+func NewDecoder(arch Arch) *Decoder {
+	return &Decoder{arch: arch}
+}
+
+// Reset forgets what earlier instructions said.
+func (d *Decoder) Reset() { d.known = 0 }
+
+// Decode decodes the instruction at the start of b, which is at
+// address pc.
+func (d *Decoder) Decode(b []byte, pc uint64) Inst {
+	switch d.arch {
+	case I386:
+		return decodeX86(b, pc, 32)
+	case X86_64:
+		return decodeX86(b, pc, 64)
+	case ARM64:
+		return d.decodeARM64(b, pc)
+	}
+	return Inst{Len: 1, Op: "(bad)"}
+}
@@ -0,0 +1,1490 @@
+package disasm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Flags of x86 table entries.
+const (
+	xD64 = 1 << iota
+	xF64
+	xI64
+	xO64
+	xRep
+	xRepz
+	xBnd
+	xNotrack
+)
+
+var x86FlagNames = map[string]int{
+	"d64": xD64, "f64": xF64, "i64": xI64, "o64": xO64,
+	"rep": xRep, "repz": xRepz, "bnd": xBnd, "notrack": xNotrack,
+}
+
+// An x86Entry is a parsed table entry.
+type x86Entry struct {
+	name  string
+	args  []string
+	flags int
+}
+
+// x86Parsed caches the parsed form of every table entry string; it is
+// filled in init so that decoding does not write to it.
+var x86Parsed = map[string]*x86Entry{}
+
+func init() {
+	add := func(s string) {
+		if s != "" && x86Parsed[s] == nil {
+			x86Parsed[s] = parseX86Entry(s)
+		}
+	}
+	for _, s := range x86OneByte {
+		add(s)
+	}
+	for _, s := range x86TwoByte {
+		add(s)
+	}
+	for _, g := range x86Groups {
+		for _, s := range g {
+			add(s)
+		}
+	}
+	for _, m := range []map[byte][4]string{x86TwoBytePrefixed, x86Map38, x86Map3A, x86VEX38, x86VEX3A} {
+		for _, v := range m {
+			for _, s := range v {
+				add(s)
+			}
+		}
+	}
+	for _, m := range x86EVEX {
+		for _, v := range m {
+			for _, s := range v {
+				add(s)
+			}
+		}
+	}
+	for _, s := range x86FMA4 {
+		add(s)
+	}
+	for _, s := range x86Specials {
+		add(s)
+	}
+}
+
+// x86Specials are entries the decoder picks by hand.
+var x86Specials = []string{
+	"pause", "xchg Zv,rAX", "movsxd Gv,Ed", "xabort Ib", "xbegin Jz f64",
+	"endbr64", "endbr32", "rdsspd/rdsspd/rdsspq Ry", "movhlps Vdq,Hdq,Udq",
+	"movlhps Vdq,Hdq,Udq", "lfence", "mfence", "sfence", "rdfsbase Ry",
+	"rdgsbase Ry", "wrfsbase Ry", "wrgsbase Ry", "clwb Mb", "clflushopt Mb",
+	"rdrand Rv", "rdseed Rv", "rdpid Rq", "prefetch Mb", "prefetchw Mb",
+	"prefetchwt1 Mb", "nop Ev", "jrcxz Jb f64", "jcxz Jb f64",
+}
+
+func parseX86Entry(s string) *x86Entry {
+	f := strings.Fields(s)
+	e := &x86Entry{name: f[0]}
+	if len(f) > 1 && f[1] != "-" {
+		e.args = strings.Split(f[1], ",")
+	}
+	if len(f) > 2 {
+		for _, fl := range strings.Split(f[2], ",") {
+			e.flags |= x86FlagNames[fl]
+		}
+	}
+	return e
+}
+
+func x86Lookup(s string) *x86Entry {
+	if e := x86Parsed[s]; e != nil {
+		return e
+	}
+	return parseX86Entry(s)
+}
+
+var (
+	x86Reg8     = []string{"al", "cl", "dl", "bl", "ah", "ch", "dh", "bh"}
+	x86Reg8Rex  = []string{"al", "cl", "dl", "bl", "spl", "bpl", "sil", "dil", "r8b", "r9b", "r10b", "r11b", "r12b", "r13b", "r14b", "r15b"}
+	x86Reg16    = []string{"ax", "cx", "dx", "bx", "sp", "bp", "si", "di", "r8w", "r9w", "r10w", "r11w", "r12w", "r13w", "r14w", "r15w"}
+	x86Reg32    = []string{"eax", "ecx", "edx", "ebx", "esp", "ebp", "esi", "edi", "r8d", "r9d", "r10d", "r11d", "r12d", "r13d", "r14d", "r15d"}
+	x86Reg64    = []string{"rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi", "r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15"}
+	x86SegRegs  = []string{"es", "cs", "ss", "ds", "fs", "gs", "?", "?"}
+	x86Mem16    = []string{"bx+si", "bx+di", "bp+si", "bp+di", "si", "di", "bp", "bx"}
+	x86PtrNames = map[int]string{
+		1: "BYTE PTR ", 2: "WORD PTR ", 4: "DWORD PTR ", 6: "FWORD PTR ", 8: "QWORD PTR ",
+		10: "TBYTE PTR ", 16: "XMMWORD PTR ", 32: "YMMWORD PTR ", 64: "ZMMWORD PTR ",
+		x86OWord: "OWORD PTR ",
+	}
+)
+
+// x86OWord stands for the size of a 16-byte integer operand, which
+// objdump calls an OWORD rather than an XMMWORD.
+const x86OWord = 17
+
+// errX86Truncated is panicked when an instruction runs past its bytes
+// and recovered in decodeX86.
+type errX86Truncated struct{}
+
+// x86Dec decodes one x86 instruction.
+type x86Dec struct {
+	b    []byte
+	pc   uint64
+	mode int
+	pos  int
+
+	p66, p67, lock, repF3, repF2 bool
+	n66                          int
+	seg                          string
+	rex                          byte
+	rexW, rexR, rexX, rexB       bool
+
+	vex, evex  bool
+	vecLen     int // 0, 1, 2 for 128, 256 and 512 bits
+	vexW       bool
+	vvvv       int
+	pp         int
+	mask       int
+	zeroing    bool
+	bcast      bool
+	evexRp     bool
+	evexVp     bool
+	mandatory  int // the mandatory prefix used: 0 none, 1 66, 2 F3, 3 F2
+	mandUsed66 bool
+
+	hasModRM     bool
+	mod, reg, rm int
+	base, index  int // -1 for none, 16 for rip
+	sibIndex     int
+	vsib         string // the kind of vector register indexing a gather
+	scale        int
+	disp         int64
+	disp8        bool
+	mem16        string
+	opsize, adsz int
+	flags        int
+	opReg        int  // the register in the low opcode bits
+	vprefix      bool // the name takes a "v" for its VEX form
+	crMove       bool // mov to or from control registers is 64-bit
+	segUsed      bool
+	targetArg    int
+	rel          int64
+	hasRel       bool
+	ripRel       bool
+	args         []string
+}
+
+func (d *x86Dec) next() byte {
+	if d.pos >= len(d.b) || d.pos >= 15 {
+		panic(errX86Truncated{})
+	}
+	c := d.b[d.pos]
+	d.pos++
+	return c
+}
+
+func (d *x86Dec) peek() (byte, bool) {
+	if d.pos >= len(d.b) {
+		return 0, false
+	}
+	return d.b[d.pos], true
+}
+
+func (d *x86Dec) imm(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		v |= uint64(d.next()) << (8 * i)
+	}
+	return v
+}
+
+func signExtend(v uint64, bytes int) int64 {
+	shift := uint(64 - 8*bytes)
+	return int64(v<<shift) >> shift
+}
+
+// decodeX86 decodes the instruction at b in 32- or 64-bit mode.
+func decodeX86(b []byte, pc uint64, mode int) (inst Inst) {
+	d := &x86Dec{b: b, pc: pc, mode: mode, targetArg: -1, base: -1, index: -1}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(errX86Truncated); !ok {
+				panic(r)
+			}
+			inst = Inst{Len: 1, Op: "(bad)"}
+		}
+	}()
+	op, ok := d.decode()
+	if !ok {
+		return Inst{Len: 1, Op: "(bad)"}
+	}
+	inst = Inst{Len: d.pos, Op: op, Args: d.args}
+	next := pc + uint64(d.pos)
+	if d.hasRel {
+		t := next + uint64(d.rel)
+		switch {
+		case d.opsize == 16:
+			t &= 0xffff
+		case mode == 32:
+			t &= 0xffffffff
+		}
+		inst.Target, inst.HasTarget = t, true
+		inst.Args[d.targetArg] = fmt.Sprintf("%x", t)
+	}
+	if d.ripRel {
+		r := next + uint64(d.disp)
+		if d.adsz == 32 {
+			r &= 0xffffffff
+		}
+		inst.Ref, inst.HasRef = r, true
+	}
+	return inst
+}
+
+// decode reads the instruction, returning its mnemonic with prefixes
+// and setting d.args, or false for undefined opcodes.
+func (d *x86Dec) decode() (string, bool) {
+	// legacy prefixes and REX
+prefixes:
+	for {
+		c, ok := d.peek()
+		if !ok {
+			return "", false
+		}
+		switch {
+		case c == 0x66:
+			d.p66 = true
+			d.n66++
+		case c == 0x67:
+			d.p67 = true
+		case c == 0xf0:
+			d.lock = true
+		case c == 0xf2:
+			d.repF2, d.repF3 = true, false
+		case c == 0xf3:
+			d.repF3, d.repF2 = true, false
+		case c == 0x26 || c == 0x2e || c == 0x36 || c == 0x3e || c == 0x64 || c == 0x65:
+			d.seg = map[byte]string{0x26: "es", 0x2e: "cs", 0x36: "ss", 0x3e: "ds", 0x64: "fs", 0x65: "gs"}[c]
+		case d.mode == 64 && c >= 0x40 && c <= 0x4f:
+			d.rex = c
+			d.pos++
+			if n, ok := d.peek(); ok && isLegacyPrefix(n) {
+				// a REX prefix is only in effect right before the opcode
+				d.rex = 0
+			}
+			continue
+		default:
+			break prefixes
+		}
+		d.pos++
+		if d.pos >= 15 {
+			return "", false
+		}
+	}
+	d.rexW, d.rexR, d.rexX, d.rexB = d.rex&8 != 0, d.rex&4 != 0, d.rex&2 != 0, d.rex&1 != 0
+	d.adsz = d.mode
+	if d.p67 {
+		d.adsz = d.mode / 2
+	}
+
+	op := d.next()
+	switch {
+	case op == 0x0f:
+		return d.twoByte()
+	case (op == 0xc4 || op == 0xc5 || op == 0x62) && d.vexFollows():
+		return d.vexInst(op)
+	case op >= 0xd8 && op <= 0xdf:
+		return d.x87(op)
+	case op == 0x9b:
+		if s, ok := d.fwait(); ok {
+			return s, true
+		}
+	}
+	s := x86OneByte[op]
+	if s == "" {
+		return "", false
+	}
+	e := x86Lookup(s)
+	switch op {
+	case 0x90:
+		switch {
+		case d.rexB:
+			e = x86Lookup("xchg Zv,rAX")
+		case d.repF3:
+			d.repF3 = false
+			e = x86Lookup("pause")
+		case d.p66:
+			return d.finish(x86Lookup("xchg Zv,rAX"), op)
+		}
+	case 0x63:
+		if d.mode == 64 {
+			e = x86Lookup("movsxd Gv,Ed")
+		}
+	case 0xe3:
+		switch {
+		case d.adsz == 64:
+			e = x86Lookup("jrcxz Jb f64")
+		case d.adsz == 16:
+			e = x86Lookup("jcxz Jb f64")
+		}
+	case 0xc6, 0xc7:
+		if c, ok := d.peek(); ok && c == 0xf8 {
+			d.pos++
+			if op == 0xc6 {
+				return d.finish(x86Lookup("xabort Ib"), op)
+			}
+			return d.finish(x86Lookup("xbegin Jz f64"), op)
+		}
+	}
+	return d.finish(e, op)
+}
+
+func isLegacyPrefix(c byte) bool {
+	switch c {
+	case 0x66, 0x67, 0xf0, 0xf2, 0xf3, 0x26, 0x2e, 0x36, 0x3e, 0x64, 0x65:
+		return true
+	}
+	return false
+}
+
+// vexFollows tells whether C4, C5 or 62 starts a VEX or EVEX prefix
+// rather than les, lds or bound, which 32-bit mode tells apart by the
+// ModRM form of the next byte.
+func (d *x86Dec) vexFollows() bool {
+	if d.mode == 64 {
+		return true
+	}
+	c, ok := d.peek()
+	return ok && c >= 0xc0
+}
+
+// twoByte decodes the 0F, 0F 38 and 0F 3A maps.
+func (d *x86Dec) twoByte() (string, bool) {
+	op := d.next()
+	switch op {
+	case 0x38, 0x3a:
+		op2 := d.next()
+		m := x86Map38
+		if op == 0x3a {
+			m = x86Map3A
+		}
+		v, ok := m[op2]
+		if !ok {
+			return "", false
+		}
+		s := d.pickPrefixed(v)
+		if s == "" {
+			return "", false
+		}
+		name, ok := d.finish(x86Lookup(s), op2)
+		if op == 0x3a && op2 == 0x44 {
+			name = d.pclmulAlias(name)
+		}
+		return name, ok
+	}
+	if v, ok := x86TwoBytePrefixed[op]; ok {
+		s := d.pickPrefixed(v)
+		if s == "" {
+			return "", false
+		}
+		switch {
+		case op == 0x1e && s == "grp1e":
+			return d.grp1e()
+		case (op == 0x12 || op == 0x16) && d.mandatory == 0:
+			if c, ok := d.peek(); ok && c >= 0xc0 {
+				if op == 0x12 {
+					s = "movhlps Vdq,Hdq,Udq"
+				} else {
+					s = "movlhps Vdq,Hdq,Udq"
+				}
+			}
+		case op == 0xc2:
+			name, ok := d.finish(x86Lookup(s), op)
+			return d.ssePredicate(name), ok
+		}
+		return d.finish(x86Lookup(s), op)
+	}
+	s := x86TwoByte[op]
+	if s == "" {
+		return "", false
+	}
+	switch op {
+	case 0x01:
+		return d.grp7()
+	case 0x0d:
+		return d.grpPrefetch()
+	case 0xae:
+		return d.grp15()
+	case 0xc7:
+		return d.grp9()
+	}
+	return d.finish(x86Lookup(s), op)
+}
+
+// pickPrefixed selects the entry of v by the mandatory prefix and
+// notes which prefix it used.
+func (d *x86Dec) pickPrefixed(v [4]string) string {
+	switch {
+	case d.repF2 && v[3] != "":
+		d.mandatory, d.repF2 = 3, false
+		return v[3]
+	case d.repF3 && v[2] != "":
+		d.mandatory, d.repF3 = 2, false
+		return v[2]
+	case d.p66 && v[1] != "":
+		d.mandatory = 1
+		if v[1] != v[0] {
+			// otherwise 66 keeps its meaning of a 16-bit operand size
+			d.mandUsed66 = true
+		}
+		return v[1]
+	}
+	return v[0]
+}
+
+// grp1e decodes F3 0F 1E: endbr64, endbr32 and rdssp, or a hint nop.
+func (d *x86Dec) grp1e() (string, bool) {
+	c, _ := d.peek()
+	switch {
+	case c == 0xfa:
+		d.pos++
+		return "endbr64", true
+	case c == 0xfb:
+		d.pos++
+		return "endbr32", true
+	case c>>6 == 3 && c>>3&7 == 1:
+		return d.finish(x86Lookup("rdsspd/rdsspd/rdsspq Ry"), 0x1e)
+	}
+	return d.finish(x86Lookup("nop Ev"), 0x1e)
+}
+
+func (d *x86Dec) grp7() (string, bool) {
+	c, _ := d.peek()
+	if c >= 0xc0 {
+		d.pos++
+		if s, ok := x86Grp7Reg[c]; ok {
+			return s, true
+		}
+		return "", false
+	}
+	d.modRM()
+	s := x86Groups["grp7"][d.reg]
+	if s == "" {
+		return "", false
+	}
+	return d.operands(x86Lookup(s))
+}
+
+func (d *x86Dec) grpPrefetch() (string, bool) {
+	d.modRM()
+	if d.mod == 3 {
+		return d.operands(x86Lookup("nop Ev"))
+	}
+	switch d.reg {
+	case 0:
+		return d.operands(x86Lookup("prefetch Mb"))
+	case 1:
+		return d.operands(x86Lookup("prefetchw Mb"))
+	case 2:
+		return d.operands(x86Lookup("prefetchwt1 Mb"))
+	}
+	return d.operands(x86Lookup("nop Ev"))
+}
+
+func (d *x86Dec) grp15() (string, bool) {
+	d.modRM()
+	var s string
+	switch {
+	case d.mod == 3 && d.repF3 && d.reg < 4:
+		d.repF3 = false
+		s = []string{"rdfsbase Ry", "rdgsbase Ry", "wrfsbase Ry", "wrgsbase Ry"}[d.reg]
+	case d.mod == 3 && d.reg >= 5:
+		s = []string{"lfence", "mfence", "sfence"}[d.reg-5]
+	case d.mod == 3:
+		return "", false
+	case d.p66 && d.reg == 6:
+		d.mandUsed66 = true
+		s = "clwb Mb"
+	case d.p66 && d.reg == 7:
+		d.mandUsed66 = true
+		s = "clflushopt Mb"
+	default:
+		s = x86Groups["grp15"][d.reg]
+	}
+	return d.operands(x86Lookup(s))
+}
+
+func (d *x86Dec) grp9() (string, bool) {
+	d.modRM()
+	var s string
+	switch {
+	case d.mod == 3 && d.reg == 7 && d.repF3:
+		d.repF3 = false
+		s = "rdpid Rq"
+	case d.mod == 3 && d.reg == 6:
+		s = "rdrand Rv"
+	case d.mod == 3 && d.reg == 7:
+		s = "rdseed Rv"
+	case d.mod == 3:
+		return "", false
+	default:
+		s = x86Groups["grp9"][d.reg]
+	}
+	if s == "" {
+		return "", false
+	}
+	return d.operands(x86Lookup(s))
+}
+
+// needsModRM tells whether the operands of e are encoded in ModRM.
+func needsModRM(e *x86Entry) bool {
+	if strings.HasPrefix(e.name, "grp") {
+		return true
+	}
+	for _, a := range e.args {
+		if x86FixedArgs[a] {
+			continue
+		}
+		switch a[0] {
+		case 'E', 'G', 'M', 'R', 'S', 'C', 'D', 'V', 'W', 'U', 'P', 'Q', 'N', 'K':
+			return true
+		}
+	}
+	return false
+}
+
+// x86FixedArgs are the operands that name a fixed register.
+var x86FixedArgs = map[string]bool{
+	"1": true, "AL": true, "CL": true, "DX": true, "BX": true, "rAX": true, "eAX": true,
+	"ES": true, "CS": true, "SS": true, "DS": true, "FS": true, "GS": true, "XMM0": true,
+}
+
+// finish decodes the ModRM and operands of e, selecting the group
+// member if e is a group.
+func (d *x86Dec) finish(e *x86Entry, op byte) (string, bool) {
+	if e.flags&xI64 != 0 && d.mode == 64 || e.flags&xO64 != 0 && d.mode != 64 {
+		return "", false
+	}
+	if needsModRM(e) {
+		d.modRM()
+	}
+	if strings.HasPrefix(e.name, "grp") {
+		g, ok := x86Groups[e.name]
+		if !ok {
+			return "", false
+		}
+		s := g[d.reg]
+		if s == "" {
+			return "", false
+		}
+		sub := *x86Lookup(s)
+		if sub.args == nil {
+			sub.args = e.args
+		}
+		sub.flags |= e.flags
+		e = &sub
+	}
+	d.opReg = int(op & 7)
+	return d.operands(e)
+}
+
+// modRM reads the ModRM byte and the SIB byte and displacement of a
+// memory operand.
+func (d *x86Dec) modRM() {
+	if d.hasModRM {
+		return
+	}
+	d.hasModRM = true
+	c := d.next()
+	d.mod, d.reg, d.rm = int(c>>6), int(c>>3&7), int(c&7)
+	if d.mod == 3 {
+		return
+	}
+	if d.adsz == 16 {
+		switch {
+		case d.mod == 0 && d.rm == 6:
+			d.disp = signExtend(d.imm(2), 2)
+		case d.mod == 0:
+			d.mem16 = x86Mem16[d.rm]
+		case d.mod == 1:
+			d.mem16 = x86Mem16[d.rm]
+			d.disp, d.disp8 = signExtend(d.imm(1), 1), true
+		default:
+			d.mem16 = x86Mem16[d.rm]
+			d.disp = signExtend(d.imm(2), 2)
+		}
+		return
+	}
+	base := d.rm
+	if d.rm == 4 {
+		sib := d.next()
+		d.scale = 1 << (sib >> 6)
+		d.sibIndex = int(sib>>3&7) | b2i(d.rexX)<<3
+		if d.sibIndex != 4 {
+			d.index = d.sibIndex
+		}
+		base = int(sib & 7)
+		if base == 5 && d.mod == 0 {
+			d.disp = signExtend(d.imm(4), 4)
+			return
+		}
+		d.base = base | b2i(d.rexB)<<3
+	} else if d.rm == 5 && d.mod == 0 {
+		d.disp = signExtend(d.imm(4), 4)
+		if d.mode == 64 {
+			d.base, d.ripRel = 16, true
+		}
+		return
+	} else {
+		d.base = base | b2i(d.rexB)<<3
+	}
+	switch d.mod {
+	case 1:
+		d.disp, d.disp8 = signExtend(d.imm(1), 1), true
+	case 2:
+		d.disp = signExtend(d.imm(4), 4)
+	}
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// operands formats the operands of e and returns its mnemonic with
+// the prefixes that apply to it.
+func (d *x86Dec) operands(e *x86Entry) (string, bool) {
+	if e.flags&xI64 != 0 && d.mode == 64 || e.flags&xO64 != 0 && d.mode != 64 {
+		return "", false
+	}
+	d.flags = e.flags
+	w := d.rexW || (d.vex || d.evex) && d.vexW
+	switch {
+	case d.vex || d.evex:
+		d.opsize = 32
+		if d.vexW {
+			d.opsize = 64
+		}
+	case d.mode == 64 && (d.rexW || e.flags&xF64 != 0):
+		d.opsize = 64
+	case d.p66 && !d.mandUsed66:
+		d.opsize = 16
+	case d.mode == 64 && e.flags&xD64 != 0:
+		d.opsize = 64
+	default:
+		d.opsize = 32
+	}
+	for _, a := range e.args {
+		if a == "Cy" || a == "Dy" {
+			d.crMove = true
+		}
+	}
+	for _, a := range e.args {
+		s, ok := d.arg(a)
+		if !ok {
+			return "", false
+		}
+		if s != "" {
+			d.args = append(d.args, s)
+		}
+	}
+	if d.evex && len(d.args) > 0 && (d.mask != 0 || d.zeroing) {
+		if d.mask != 0 {
+			d.args[0] += fmt.Sprintf("{k%d}", d.mask)
+		}
+		if d.zeroing {
+			d.args[0] += "{z}"
+		}
+	}
+
+	name := e.name
+	if parts := strings.Split(name, "/"); len(parts) == 3 {
+		name = parts[map[int]int{16: 0, 32: 1, 64: 2}[d.opsize]]
+	} else if len(parts) == 2 {
+		name = parts[b2i(w)]
+	}
+	if d.vprefix {
+		name = "v" + name
+	}
+	if name == "mov" && d.mode == 64 && len(e.args) == 2 &&
+		(e.args[1] == "Iv" && d.opsize == 64 || e.args[0][0] == 'O' || e.args[1][0] == 'O') {
+		name = "movabs"
+	}
+
+	var pre strings.Builder
+	unused66 := d.n66 - 1
+	if d.p66 && !d.mandUsed66 && d.opsize != 16 {
+		// REX.W or a fixed 64-bit size overrides it
+		unused66++
+	}
+	for i := 0; i < unused66; i++ {
+		pre.WriteString("data16 ")
+	}
+	if d.seg != "" && !d.segUsed {
+		if d.seg == "ds" && e.flags&xNotrack != 0 {
+			pre.WriteString("notrack ")
+		} else {
+			pre.WriteString(d.seg + " ")
+		}
+	}
+	if d.lock {
+		pre.WriteString("lock ")
+	}
+	switch {
+	case d.repF3 && e.flags&xRep != 0:
+		pre.WriteString("rep ")
+	case d.repF3:
+		pre.WriteString("repz ")
+	case d.repF2 && e.flags&xBnd != 0:
+		pre.WriteString("bnd ")
+	case d.repF2:
+		pre.WriteString("repnz ")
+	}
+	return pre.String() + name, true
+}
+
+// gprSize is the width in bits of a general register operand of size
+// letter sz.
+func (d *x86Dec) gprSize(sz string) int {
+	switch sz {
+	case "b":
+		return 8
+	case "w":
+		return 16
+	case "d":
+		return 32
+	case "q":
+		return 64
+	case "y":
+		if d.mode == 64 && (d.crMove || d.rexW || (d.vex || d.evex) && d.vexW) {
+			return 64
+		}
+		return 32
+	case "z":
+		if d.opsize == 16 {
+			return 16
+		}
+		return 32
+	}
+	return d.opsize
+}
+
+func (d *x86Dec) gpr(n, bits int) string {
+	switch bits {
+	case 8:
+		if d.rex != 0 {
+			return x86Reg8Rex[n&15]
+		}
+		return x86Reg8[n&7]
+	case 16:
+		return x86Reg16[n&15]
+	case 32:
+		return x86Reg32[n&15]
+	}
+	return x86Reg64[n&15]
+}
+
+// bytes is the size in bytes of a memory or vector operand of size
+// letter sz.
+func (d *x86Dec) bytes(sz string) int {
+	vl := 16 << d.vecLen
+	switch sz {
+	case "b":
+		return 1
+	case "w":
+		return 2
+	case "d", "ss":
+		return 4
+	case "q", "sd":
+		return 8
+	case "v", "y", "z":
+		return d.gprSize(sz) / 8
+	case "sy":
+		if d.vexW {
+			return 8
+		}
+		return 4
+	case "dq":
+		return 16
+	case "qq":
+		return 32
+	case "x", "m":
+		return vl
+	case "h":
+		return vl / 2
+	case "k":
+		return vl / 4
+	case "e":
+		return vl / 8
+	case "t":
+		return 10
+	case "o":
+		// cmpxchg16b
+		if d.opsize == 64 {
+			return x86OWord
+		}
+		return 8
+	case "p":
+		switch d.opsize {
+		case 16:
+			return 4
+		case 64:
+			return 10
+		}
+		return 6
+	case "a":
+		if d.opsize == 16 {
+			return 4
+		}
+		return 8
+	}
+	return 0
+}
+
+// vec names vector register n for an operand of sz.
+func (d *x86Dec) vec(n int, sz string) string {
+	switch b := d.bytes(sz); {
+	case b >= 64:
+		return fmt.Sprintf("zmm%d", n)
+	case b >= 32:
+		return fmt.Sprintf("ymm%d", n)
+	}
+	return fmt.Sprintf("xmm%d", n)
+}
+
+// segment returns the segment override to print before a memory
+// operand whose default segment is def, which is only printed for
+// string operands.
+func (d *x86Dec) segment(def string) string {
+	if d.seg != "" && (d.mode == 32 || d.seg == "fs" || d.seg == "gs") {
+		d.segUsed = true
+		return d.seg + ":"
+	}
+	if def != "" {
+		return def + ":"
+	}
+	return ""
+}
+
+// mem formats the ModRM memory operand of size bytes.
+func (d *x86Dec) mem(size int) string {
+	ptr := x86PtrNames[size]
+	disp := d.disp
+	n := size
+	if d.evex && d.bcast {
+		n = 4
+		if d.vexW {
+			n = 8
+		}
+		ptr = strings.Replace(x86PtrNames[n], "PTR", "BCST", 1)
+	}
+	if d.evex && d.disp8 && n > 0 {
+		disp *= int64(n)
+		if d.ripRel {
+			d.disp = disp
+		}
+	}
+	if d.mem16 == "" && d.base < 0 && d.index < 0 {
+		v := uint64(disp)
+		if d.adsz < 64 {
+			v &= 1<<uint(d.adsz) - 1
+		}
+		return fmt.Sprintf("%s%s0x%x", ptr, d.segment("ds"), v)
+	}
+	regs := x86Reg64
+	if d.adsz == 32 {
+		regs = x86Reg32
+	}
+	var b strings.Builder
+	b.WriteString(ptr)
+	b.WriteString(d.segment(""))
+	b.WriteByte('[')
+	sep := ""
+	switch {
+	case d.mem16 != "":
+		b.WriteString(d.mem16)
+		sep = "+"
+	case d.base == 16 && d.adsz == 32:
+		b.WriteString("eip")
+		sep = "+"
+	case d.base == 16:
+		b.WriteString("rip")
+		sep = "+"
+	case d.base >= 0:
+		b.WriteString(regs[d.base])
+		sep = "+"
+	}
+	switch {
+	case d.vsib != "":
+		fmt.Fprintf(&b, "%s%s%d*%d", sep, d.vsib, d.sibIndex, d.scale)
+	case d.index >= 0:
+		fmt.Fprintf(&b, "%s%s*%d", sep, regs[d.index], d.scale)
+	case d.rm == 4 && d.base >= 0 && (d.base&7 != 4 || d.scale != 1):
+		// a SIB byte without index that does not only encode rsp
+		riz := "riz"
+		if d.adsz == 32 {
+			riz = "eiz"
+		}
+		fmt.Fprintf(&b, "%s%s*%d", sep, riz, d.scale)
+	}
+	if d.mod != 0 || d.base < 0 && d.mem16 == "" || d.ripRel {
+		if disp < 0 {
+			fmt.Fprintf(&b, "-0x%x", uint64(-disp))
+		} else {
+			fmt.Fprintf(&b, "+0x%x", uint64(disp))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// immediate reads an immediate of sz, sign-extended by an s suffix or
+// to the operand size for z, and formats it.
+func (d *x86Dec) immediate(sz string) string {
+	var v uint64
+	switch sz {
+	case "b":
+		v = d.imm(1)
+	case "bs":
+		v = uint64(signExtend(d.imm(1), 1))
+	case "w":
+		v = d.imm(2)
+	case "z":
+		if d.opsize == 16 {
+			v = d.imm(2)
+		} else {
+			v = uint64(signExtend(d.imm(4), 4))
+		}
+	case "v":
+		v = d.imm(d.opsize / 8)
+	}
+	if sz == "bs" || sz == "z" {
+		bits := d.opsize
+		if d.opsize == 0 {
+			bits = 32
+		}
+		if bits < 64 {
+			v &= 1<<uint(bits) - 1
+		}
+	}
+	return fmt.Sprintf("0x%x", v)
+}
+
+// arg formats operand a, returning "" for operands the encoding leaves
+// out, such as VEX.vvvv in legacy SSE.
+func (d *x86Dec) arg(a string) (string, bool) {
+	switch a {
+	case "1":
+		return "1", true
+	case "AL", "CL", "DX", "ES", "CS", "SS", "DS", "FS", "GS", "XMM0":
+		return strings.ToLower(a), true
+	case "rAX":
+		return d.gpr(0, d.opsize), true
+	case "eAX":
+		return d.gpr(0, d.gprSize("z")), true
+	case "BX":
+		return "BYTE PTR " + d.segment("ds") + "[" + d.gpr(3, d.adsz) + "]", true
+	case "Rd/Mb", "Rd/Mw", "Rv/Mw":
+		// a register of one size or memory of another
+		if d.mod == 3 {
+			return d.gpr(d.rm|b2i(d.rexB)<<3, d.gprSize(a[1:2])), true
+		}
+		return d.mem(d.bytes(a[4:])), true
+	case "KV":
+		return fmt.Sprintf("k%d", d.reg), true
+	case "KU":
+		return fmt.Sprintf("k%d", d.rm), true
+	case "KH":
+		return fmt.Sprintf("k%d", d.vvvv&7), true
+	case "KWb", "KWw", "KWd", "KWq":
+		if d.mod == 3 {
+			return fmt.Sprintf("k%d", d.rm), true
+		}
+		return d.mem(d.bytes(a[2:])), true
+	case "Lx":
+		r := int(d.imm(1) >> 4)
+		if d.mode != 64 {
+			r &= 7
+		}
+		return d.vec(r, "x"), true
+	case "M":
+		if d.mod == 3 {
+			return "", false
+		}
+		return d.mem(0), true
+	case "Ap":
+		off := d.imm(d.gprSize("z") / 8)
+		sel := d.imm(2)
+		return fmt.Sprintf("0x%x:0x%x", sel, off), true
+	}
+	sz := a[1:]
+	regR := d.reg | b2i(d.rexR)<<3
+	regB := d.rm | b2i(d.rexB)<<3
+	vecR := regR | b2i(d.evexRp)<<4
+	vecB := regB
+	if d.evex {
+		vecB |= b2i(d.rexX) << 4
+	}
+	switch a[0] {
+	case 'E':
+		if d.mod == 3 {
+			return d.gpr(regB, d.gprSize(sz)), true
+		}
+		return d.mem(d.bytes(sz)), true
+	case 'G':
+		return d.gpr(regR, d.gprSize(sz)), true
+	case 'M':
+		if d.mod == 3 {
+			return "", false
+		}
+		return d.mem(d.bytes(sz)), true
+	case 'R':
+		return d.gpr(regB, d.gprSize(sz)), true
+	case 'Z':
+		return d.gpr(d.opReg|b2i(d.rexB)<<3, d.gprSize(sz)), true
+	case 'B':
+		return d.gpr(d.vvvv, d.gprSize(sz)), true
+	case 'I':
+		return d.immediate(sz), true
+	case 'J':
+		if sz == "b" {
+			d.rel = signExtend(d.imm(1), 1)
+		} else if d.opsize == 16 {
+			d.rel = signExtend(d.imm(2), 2)
+		} else {
+			d.rel = signExtend(d.imm(4), 4)
+		}
+		d.hasRel, d.targetArg = true, len(d.args)
+		return "-", true
+	case 'O':
+		v := d.imm(d.adsz / 8)
+		return fmt.Sprintf("%s0x%x", d.segment("ds"), v), true
+	case 'X', 'Y':
+		si := 6
+		seg := "es:"
+		if a[0] == 'X' {
+			seg = d.segment("ds")
+		} else {
+			si = 7
+		}
+		return x86PtrNames[d.bytes(sz)] + seg + "[" + d.gpr(si, d.adsz) + "]", true
+	case 'S':
+		return x86SegRegs[d.reg], true
+	case 'C':
+		return fmt.Sprintf("cr%d", regR), true
+	case 'D':
+		return fmt.Sprintf("dr%d", regR), true
+	case 'V':
+		return d.vec(vecR, sz), true
+	case 'H':
+		if !d.vex && !d.evex || sz == "m" && d.mod != 3 {
+			return "", true
+		}
+		return d.vec(d.vvvv, sz), true
+	case 'W':
+		if d.mod == 3 {
+			return d.vec(vecB, sz), true
+		}
+		return d.mem(d.bytes(sz)), true
+	case 'U':
+		if d.mod != 3 {
+			return "", false
+		}
+		return d.vec(vecB, sz), true
+	case 'P':
+		return fmt.Sprintf("mm%d", d.reg), true
+	case 'Q':
+		if d.mod == 3 {
+			return fmt.Sprintf("mm%d", d.rm), true
+		}
+		return d.mem(d.bytes(sz)), true
+	case 'N':
+		if d.mod != 3 {
+			return "", false
+		}
+		return fmt.Sprintf("mm%d", d.rm), true
+	}
+	return "", false
+}
+
+// vexInst decodes an instruction with a VEX (C4, C5) or EVEX (62)
+// prefix.
+func (d *x86Dec) vexInst(op byte) (string, bool) {
+	if d.rex != 0 || d.p66 || d.repF2 || d.repF3 || d.lock {
+		return "", false
+	}
+	m := 1
+	switch op {
+	case 0xc5:
+		b1 := d.next()
+		d.rexR = b1&0x80 == 0
+		d.vvvv = int(^b1 >> 3 & 15)
+		d.vecLen = int(b1 >> 2 & 1)
+		d.pp = int(b1 & 3)
+		d.vex = true
+	case 0xc4:
+		b1, b2 := d.next(), d.next()
+		d.rexR, d.rexX, d.rexB = b1&0x80 == 0, b1&0x40 == 0, b1&0x20 == 0
+		m = int(b1 & 0x1f)
+		d.vexW = b2&0x80 != 0
+		d.vvvv = int(^b2 >> 3 & 15)
+		d.vecLen = int(b2 >> 2 & 1)
+		d.pp = int(b2 & 3)
+		d.vex = true
+	default:
+		p0, p1, p2 := d.next(), d.next(), d.next()
+		d.rexR, d.rexX, d.rexB, d.evexRp = p0&0x80 == 0, p0&0x40 == 0, p0&0x20 == 0, p0&0x10 == 0
+		m = int(p0 & 7)
+		d.vexW = p1&0x80 != 0
+		d.vvvv = int(^p1>>3&15) | b2i(p2&8 == 0)<<4
+		d.pp = int(p1 & 3)
+		d.zeroing = p2&0x80 != 0
+		d.vecLen = int(p2 >> 5 & 3)
+		d.bcast = p2&0x10 != 0
+		d.mask = int(p2 & 7)
+		d.evex = true
+	}
+	if d.mode != 64 {
+		d.rexR, d.rexX, d.rexB, d.evexRp = false, false, false, false
+		d.vvvv &= 7
+	}
+	d.mandatory = d.pp
+	if d.pp == 1 {
+		d.mandUsed66 = true
+	}
+	c := d.next()
+	if m == 1 && c == 0x77 && d.vex {
+		if d.vecLen == 1 {
+			return "vzeroall", true
+		}
+		return "vzeroupper", true
+	}
+	if d.evex {
+		if v, ok := x86EVEX[m][c]; ok && v[d.pp] != "" {
+			op, ok := d.finish(x86Lookup(v[d.pp]), c)
+			return d.cmpPredicate(op), ok
+		}
+	}
+	if m == 1 && d.vex {
+		if s, ok := x86MaskOps[c]; ok {
+			return d.maskOp(s, c)
+		}
+	}
+	var s string
+	switch m {
+	case 1:
+		if n, ok := d.peek(); ok && c == 0xae && d.pp == 0 && n < 0xc0 && (n>>3&7 == 2 || n>>3&7 == 3) {
+			// vldmxcsr and vstmxcsr
+			d.vprefix = true
+			return d.finish(x86Lookup(x86TwoByte[c]), c)
+		}
+		v, ok := x86TwoBytePrefixed[c]
+		if !ok {
+			return "", false
+		}
+		s = v[d.pp]
+		if (c == 0x12 || c == 0x16) && d.pp == 0 {
+			if n, ok := d.peek(); ok && n >= 0xc0 {
+				s = map[byte]string{0x12: "movhlps Vdq,Hdq,Udq", 0x16: "movlhps Vdq,Hdq,Udq"}[c]
+			}
+		}
+	case 2, 3:
+		vm, lm := x86VEX38, x86Map38
+		if m == 3 {
+			vm, lm = x86VEX3A, x86Map3A
+		}
+		if v, ok := vm[c]; ok && v[d.pp] != "" {
+			return d.finish(x86Lookup(v[d.pp]), c)
+		}
+		if m == 2 && d.vex && d.pp == 1 && c >= 0x90 && c <= 0x93 {
+			return d.gather(c)
+		}
+		if s, ok := x86FMA4[c]; ok && m == 3 && d.vex && d.pp == 1 {
+			op, ok := d.finish(x86Lookup(s), c)
+			if n := len(d.args); ok && d.vexW {
+				// VEX.W swaps the operands from ModRM and the immediate
+				d.args[n-2], d.args[n-1] = d.args[n-1], d.args[n-2]
+			}
+			return op, ok
+		}
+		v, ok := lm[c]
+		if !ok {
+			return "", false
+		}
+		s = v[d.pp]
+		if m == 3 && c == 0x44 && s != "" {
+			d.vprefix = true
+			name, ok := d.finish(x86Lookup(s), c)
+			return d.pclmulAlias(name), ok
+		}
+	default:
+		return "", false
+	}
+	if s == "" || !vexForm(x86Lookup(s)) {
+		return "", false
+	}
+	d.vprefix = true
+	name, ok := d.finish(x86Lookup(s), c)
+	if m == 1 && c == 0xc2 {
+		name = d.ssePredicate(name)
+	}
+	return name, ok
+}
+
+// x86MaskOps are the VEX instructions of map 1 on opmask registers;
+// maskOp completes their names by prefix and VEX.W. KW is an opmask
+// register or memory.
+var x86MaskOps = map[byte]string{
+	0x41: "kand KV,KH,KU", 0x42: "kandn KV,KH,KU", 0x44: "knot KV,KU",
+	0x45: "kor KV,KH,KU", 0x46: "kxnor KV,KH,KU", 0x47: "kxor KV,KH,KU",
+	0x4a: "kadd KV,KH,KU", 0x4b: "kunpck KV,KH,KU",
+	0x90: "kmov KV,KW", 0x91: "kmov KW,KV", 0x92: "kmov KV,Ry", 0x93: "kmov Gy,KU",
+	0x98: "kortest KV,KU", 0x99: "ktest KV,KU",
+}
+
+func (d *x86Dec) maskOp(s string, c byte) (string, bool) {
+	var sz string
+	switch {
+	case c == 0x4b && d.pp == 1 && !d.vexW:
+		sz = "bw"
+	case c == 0x4b && d.pp == 0:
+		sz = map[bool]string{false: "wd", true: "dq"}[d.vexW]
+	case c == 0x4b:
+		return "", false
+	case d.pp == 0:
+		sz = map[bool]string{false: "w", true: "q"}[d.vexW]
+	case d.pp == 1:
+		sz = map[bool]string{false: "b", true: "d"}[d.vexW]
+	case d.pp == 3 && (c == 0x92 || c == 0x93):
+		sz = map[bool]string{false: "d", true: "q"}[d.vexW]
+	default:
+		return "", false
+	}
+	name, args, _ := strings.Cut(s, " ")
+	args = strings.ReplaceAll(args, "KW", "KW"+sz[len(sz)-1:])
+	return d.finish(x86Lookup(name+sz+" "+args), c)
+}
+
+// x86CmpPredicates name the comparisons of vpcmp by immediate.
+var x86CmpPredicates = map[string]string{"0x0": "eq", "0x1": "lt", "0x2": "le", "0x4": "neq", "0x5": "nlt", "0x6": "nle"}
+
+// cmpPredicate folds the immediate of EVEX vpcmp into its name, as in
+// vpcmpnequb.
+func (d *x86Dec) cmpPredicate(op string) string {
+	if !strings.HasPrefix(op, "vpcmp") || len(d.args) != 4 {
+		return op
+	}
+	p, ok := x86CmpPredicates[d.args[3]]
+	if !ok {
+		return op
+	}
+	d.args = d.args[:3]
+	return "vpcmp" + p + op[len("vpcmp"):]
+}
+
+// x86SSEPredicates name the comparisons of cmpps, cmppd, cmpss and
+// cmpsd by immediate; the legacy forms have the first eight.
+var x86SSEPredicates = []string{
+	"eq", "lt", "le", "unord", "neq", "nlt", "nle", "ord",
+	"eq_uq", "nge", "ngt", "false", "neq_oq", "ge", "gt", "true",
+	"eq_os", "lt_oq", "le_oq", "unord_s", "neq_us", "nlt_uq", "nle_uq", "ord_s",
+	"eq_us", "nge_uq", "ngt_uq", "false_os", "neq_os", "ge_oq", "gt_oq", "true_us",
+}
+
+// ssePredicate folds the immediate of cmpps and its kin into the name,
+// as in cmpnlesd.
+func (d *x86Dec) ssePredicate(op string) string {
+	pre, name := "", op
+	if i := strings.LastIndexByte(op, ' '); i >= 0 {
+		pre, name = op[:i+1], op[i+1:]
+	}
+	base := strings.TrimPrefix(name, "v")
+	if len(base) != 5 || !strings.HasPrefix(base, "cmp") || len(d.args) == 0 {
+		return op
+	}
+	var imm int
+	if _, err := fmt.Sscanf(d.args[len(d.args)-1], "0x%x", &imm); err != nil {
+		return op
+	}
+	n := 8
+	if d.vex || d.evex {
+		n = len(x86SSEPredicates)
+	}
+	if imm >= n {
+		return op
+	}
+	d.args = d.args[:len(d.args)-1]
+	return pre + name[:len(name)-2] + x86SSEPredicates[imm] + name[len(name)-2:]
+}
+
+// pclmulAlias names the halves pclmulqdq multiplies, as in
+// pclmulhqlqdq.
+func (d *x86Dec) pclmulAlias(op string) string {
+	halves := map[string]string{"0x0": "lqlq", "0x1": "hqlq", "0x10": "lqhq", "0x11": "hqhq"}
+	if n := len(d.args); n > 0 && halves[d.args[n-1]] != "" {
+		h := halves[d.args[n-1]]
+		d.args = d.args[:n-1]
+		return strings.Replace(op, "pclmulqdq", "pclmul"+h+"dq", 1)
+	}
+	return op
+}
+
+// gather decodes the VEX gathers of 66 0F 38 90 to 93, whose memory
+// operand is indexed by a vector register.
+func (d *x86Dec) gather(c byte) (string, bool) {
+	names := [4][2]string{
+		{"vpgatherdd", "vpgatherdq"}, {"vpgatherqd", "vpgatherqq"},
+		{"vgatherdps", "vgatherdpd"}, {"vgatherqps", "vgatherqpd"},
+	}
+	d.modRM()
+	if d.mod == 3 || d.rm != 4 {
+		return "", false
+	}
+	elem, vl := 4, 16<<d.vecLen
+	if d.vexW {
+		elem = 8
+	}
+	index, dest := vl, vl
+	switch {
+	case c&1 == 0 && d.vexW:
+		// dword indices of qword elements fill half a register
+		index = vl / 2
+	case c&1 == 1 && !d.vexW:
+		dest = vl / 2
+	}
+	name := func(bytes int, n int) string {
+		if bytes == 32 {
+			return fmt.Sprintf("ymm%d", n)
+		}
+		return fmt.Sprintf("xmm%d", n)
+	}
+	d.vsib = name(index, 0)[:3]
+	d.args = []string{
+		name(dest, d.reg|b2i(d.rexR)<<3),
+		d.mem(elem),
+		name(dest, d.vvvv),
+	}
+	return names[c-0x90][b2i(d.vexW)], true
+}
+
+// vexForm tells whether a legacy SSE entry has a VEX form: those with
+// XMM operands and none of MMX.
+func vexForm(e *x86Entry) bool {
+	if strings.HasPrefix(e.name, "grp") {
+		return e.name != "grp12" && e.name != "grp13" && e.name != "grp14"
+	}
+	xmm := false
+	for _, a := range e.args {
+		switch a[0] {
+		case 'P', 'Q', 'N':
+			return false
+		case 'V', 'W', 'U':
+			xmm = true
+		}
+	}
+	return xmm
+}
+
+// x87Mem are the memory forms of D8 to DF by reg, with the size
+// letter of their operand.
+var x87Mem = [8][8]string{
+	{"fadd d", "fmul d", "fcom d", "fcomp d", "fsub d", "fsubr d", "fdiv d", "fdivr d"},
+	{"fld d", "", "fst d", "fstp d", "fldenv", "fldcw w", "fnstenv", "fnstcw w"},
+	{"fiadd d", "fimul d", "ficom d", "ficomp d", "fisub d", "fisubr d", "fidiv d", "fidivr d"},
+	{"fild d", "fisttp d", "fist d", "fistp d", "", "fld t", "", "fstp t"},
+	{"fadd q", "fmul q", "fcom q", "fcomp q", "fsub q", "fsubr q", "fdiv q", "fdivr q"},
+	{"fld q", "fisttp q", "fst q", "fstp q", "frstor", "", "fnsave", "fnstsw w"},
+	{"fiadd w", "fimul w", "ficom w", "ficomp w", "fisub w", "fisubr w", "fidiv w", "fidivr w"},
+	{"fild w", "fisttp w", "fist w", "fistp w", "fbld t", "fild q", "fbstp t", "fistp q"},
+}
+
+// x87Reg are the register forms of D8 to DF by reg; "st,i" takes st
+// and st(i), "i,st" the reverse and "i" st(i) alone. D9 and the
+// single forms of DA, DB, DE and DF are in x87Fixed.
+var x87Reg = [8][8]string{
+	{"fadd st,i", "fmul st,i", "fcom i", "fcomp i", "fsub st,i", "fsubr st,i", "fdiv st,i", "fdivr st,i"},
+	{"fld i", "fxch i"},
+	{"fcmovb st,i", "fcmove st,i", "fcmovbe st,i", "fcmovu st,i"},
+	{"fcmovnb st,i", "fcmovne st,i", "fcmovnbe st,i", "fcmovnu st,i", "", "fucomi st,i", "fcomi st,i"},
+	{"fadd i,st", "fmul i,st", "", "", "fsubr i,st", "fsub i,st", "fdivr i,st", "fdiv i,st"},
+	{"ffree i", "", "fst i", "fstp i", "fucom i", "fucomp i"},
+	{"faddp i,st", "fmulp i,st", "", "", "fsubrp i,st", "fsubp i,st", "fdivrp i,st", "fdivp i,st"},
+	{"ffreep i", "", "", "", "", "fucomip st,i", "fcomip st,i"},
+}
+
+var x87Fixed = map[uint16]string{
+	0xd9d0: "fnop", 0xd9e0: "fchs", 0xd9e1: "fabs", 0xd9e4: "ftst", 0xd9e5: "fxam",
+	0xd9e8: "fld1", 0xd9e9: "fldl2t", 0xd9ea: "fldl2e", 0xd9eb: "fldpi",
+	0xd9ec: "fldlg2", 0xd9ed: "fldln2", 0xd9ee: "fldz",
+	0xd9f0: "f2xm1", 0xd9f1: "fyl2x", 0xd9f2: "fptan", 0xd9f3: "fpatan",
+	0xd9f4: "fxtract", 0xd9f5: "fprem1", 0xd9f6: "fdecstp", 0xd9f7: "fincstp",
+	0xd9f8: "fprem", 0xd9f9: "fyl2xp1", 0xd9fa: "fsqrt", 0xd9fb: "fsincos",
+	0xd9fc: "frndint", 0xd9fd: "fscale", 0xd9fe: "fsin", 0xd9ff: "fcos",
+	0xdae9: "fucompp", 0xdbe2: "fnclex", 0xdbe3: "fninit", 0xded9: "fcompp",
+}
+
+// fwait decodes fwait followed by an x87 control instruction of the
+// non-waiting "fn" form as the waiting form, such as fstcw.
+func (d *x86Dec) fwait() (string, bool) {
+	n, ok := d.peek()
+	if !ok || n < 0xd8 || n > 0xdf {
+		return "", false
+	}
+	save := *d
+	d.pos++
+	s, ok := d.x87(n)
+	if ok && strings.HasPrefix(s, "fn") && s != "fnop" {
+		return "f" + s[2:], true
+	}
+	*d = save
+	return "", false
+}
+
+// x87 decodes the floating point escapes D8 to DF.
+func (d *x86Dec) x87(op byte) (string, bool) {
+	d.modRM()
+	if d.mod != 3 {
+		f := strings.Fields(x87Mem[op-0xd8][d.reg])
+		if len(f) == 0 {
+			return "", false
+		}
+		size := 0
+		if len(f) > 1 {
+			size = d.bytes(f[1])
+		}
+		d.args = []string{d.mem(size)}
+		return d.x87Prefix() + f[0], true
+	}
+	modrm := uint16(op)<<8 | uint16(0xc0|d.reg<<3|d.rm)
+	if s, ok := x87Fixed[modrm]; ok {
+		return d.x87Prefix() + s, true
+	}
+	if modrm == 0xdfe0 {
+		d.args = []string{"ax"}
+		return d.x87Prefix() + "fnstsw", true
+	}
+	f := strings.Fields(x87Reg[op-0xd8][d.reg])
+	if len(f) == 0 {
+		return "", false
+	}
+	sti := fmt.Sprintf("st(%d)", d.rm)
+	if len(f) > 1 {
+		for _, a := range strings.Split(f[1], ",") {
+			if a == "i" {
+				d.args = append(d.args, sti)
+			} else {
+				d.args = append(d.args, a)
+			}
+		}
+	}
+	return d.x87Prefix() + f[0], true
+}
+
+func (d *x86Dec) x87Prefix() string {
+	if d.seg != "" && !d.segUsed {
+		return d.seg + " "
+	}
+	return ""
+}
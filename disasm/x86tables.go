@@ -0,0 +1,527 @@
+package disasm
+
+// The x86 opcode tables spell each entry as "name args flags": the
+// mnemonic, the operands in the notation of the Intel opcode maps and
+// flags, with "-" for no operands. A name with slashes picks by
+// operand size 16/32/64; a name starting with "grp" selects by the reg
+// field of ModRM from x86Groups.
+//
+// Operands: E is a register or memory from ModRM, G a register from
+// its reg field, M memory only, R a register from r/m, I an immediate,
+// J a relative branch target, O a memory offset, X and Y the string
+// operands at rsi and rdi, Z a register from the low opcode bits, S a
+// segment, C and D control and debug registers, V, W and U XMM
+// registers, memory and XMM registers from r/m, H the VEX.vvvv
+// register, L the register in the top bits of an immediate, P, Q and N
+// MMX registers and memory, and K an opmask register. The size letters
+// follow: b byte, w word, d dword, q qword, v by operand size, y dword
+// or qword by REX.W, z word or dword, x by vector length, dq xmmword,
+// ss and sd scalars, t tbyte, p far pointer.
+//
+// Flags: d64 defaults to 64-bit operands in 64-bit mode, f64 forces
+// them, i64 is invalid and o64 only valid in 64-bit mode, rep, repz
+// and bnd name what F3 and F2 prefixes mean.
+
+var x86OneByte = [256]string{
+	0x00: "add Eb,Gb", 0x01: "add Ev,Gv", 0x02: "add Gb,Eb", 0x03: "add Gv,Ev",
+	0x04: "add AL,Ib", 0x05: "add rAX,Iz", 0x06: "push ES i64", 0x07: "pop ES i64",
+	0x08: "or Eb,Gb", 0x09: "or Ev,Gv", 0x0a: "or Gb,Eb", 0x0b: "or Gv,Ev",
+	0x0c: "or AL,Ib", 0x0d: "or rAX,Iz", 0x0e: "push CS i64",
+	0x10: "adc Eb,Gb", 0x11: "adc Ev,Gv", 0x12: "adc Gb,Eb", 0x13: "adc Gv,Ev",
+	0x14: "adc AL,Ib", 0x15: "adc rAX,Iz", 0x16: "push SS i64", 0x17: "pop SS i64",
+	0x18: "sbb Eb,Gb", 0x19: "sbb Ev,Gv", 0x1a: "sbb Gb,Eb", 0x1b: "sbb Gv,Ev",
+	0x1c: "sbb AL,Ib", 0x1d: "sbb rAX,Iz", 0x1e: "push DS i64", 0x1f: "pop DS i64",
+	0x20: "and Eb,Gb", 0x21: "and Ev,Gv", 0x22: "and Gb,Eb", 0x23: "and Gv,Ev",
+	0x24: "and AL,Ib", 0x25: "and rAX,Iz", 0x27: "daa - i64",
+	0x28: "sub Eb,Gb", 0x29: "sub Ev,Gv", 0x2a: "sub Gb,Eb", 0x2b: "sub Gv,Ev",
+	0x2c: "sub AL,Ib", 0x2d: "sub rAX,Iz", 0x2f: "das - i64",
+	0x30: "xor Eb,Gb", 0x31: "xor Ev,Gv", 0x32: "xor Gb,Eb", 0x33: "xor Gv,Ev",
+	0x34: "xor AL,Ib", 0x35: "xor rAX,Iz", 0x37: "aaa - i64",
+	0x38: "cmp Eb,Gb", 0x39: "cmp Ev,Gv", 0x3a: "cmp Gb,Eb", 0x3b: "cmp Gv,Ev",
+	0x3c: "cmp AL,Ib", 0x3d: "cmp rAX,Iz", 0x3f: "aas - i64",
+	0x40: "inc Zv i64", 0x41: "inc Zv i64", 0x42: "inc Zv i64", 0x43: "inc Zv i64",
+	0x44: "inc Zv i64", 0x45: "inc Zv i64", 0x46: "inc Zv i64", 0x47: "inc Zv i64",
+	0x48: "dec Zv i64", 0x49: "dec Zv i64", 0x4a: "dec Zv i64", 0x4b: "dec Zv i64",
+	0x4c: "dec Zv i64", 0x4d: "dec Zv i64", 0x4e: "dec Zv i64", 0x4f: "dec Zv i64",
+	0x50: "push Zv d64", 0x51: "push Zv d64", 0x52: "push Zv d64", 0x53: "push Zv d64",
+	0x54: "push Zv d64", 0x55: "push Zv d64", 0x56: "push Zv d64", 0x57: "push Zv d64",
+	0x58: "pop Zv d64", 0x59: "pop Zv d64", 0x5a: "pop Zv d64", 0x5b: "pop Zv d64",
+	0x5c: "pop Zv d64", 0x5d: "pop Zv d64", 0x5e: "pop Zv d64", 0x5f: "pop Zv d64",
+	0x60: "pushaw/pusha/pusha - i64", 0x61: "popaw/popa/popa - i64",
+	0x62: "bound Gv,Ma i64", 0x63: "arpl Ew,Gw i64",
+	0x68: "push Iz d64", 0x69: "imul Gv,Ev,Iz", 0x6a: "push Ibs d64", 0x6b: "imul Gv,Ev,Ibs",
+	0x6c: "ins Yb,DX rep", 0x6d: "ins Yz,DX rep", 0x6e: "outs DX,Xb rep", 0x6f: "outs DX,Xz rep",
+	0x70: "jo Jb bnd", 0x71: "jno Jb bnd", 0x72: "jb Jb bnd", 0x73: "jae Jb bnd",
+	0x74: "je Jb bnd", 0x75: "jne Jb bnd", 0x76: "jbe Jb bnd", 0x77: "ja Jb bnd",
+	0x78: "js Jb bnd", 0x79: "jns Jb bnd", 0x7a: "jp Jb bnd", 0x7b: "jnp Jb bnd",
+	0x7c: "jl Jb bnd", 0x7d: "jge Jb bnd", 0x7e: "jle Jb bnd", 0x7f: "jg Jb bnd",
+	0x80: "grp1 Eb,Ib", 0x81: "grp1 Ev,Iz", 0x82: "grp1 Eb,Ib i64", 0x83: "grp1 Ev,Ibs",
+	0x84: "test Eb,Gb", 0x85: "test Ev,Gv", 0x86: "xchg Eb,Gb", 0x87: "xchg Ev,Gv",
+	0x88: "mov Eb,Gb", 0x89: "mov Ev,Gv", 0x8a: "mov Gb,Eb", 0x8b: "mov Gv,Ev",
+	0x8c: "mov Rv/Mw,Sw", 0x8d: "lea Gv,M", 0x8e: "mov Sw,Rv/Mw", 0x8f: "grp1a Ev d64",
+	0x90: "nop", 0x91: "xchg Zv,rAX", 0x92: "xchg Zv,rAX", 0x93: "xchg Zv,rAX",
+	0x94: "xchg Zv,rAX", 0x95: "xchg Zv,rAX", 0x96: "xchg Zv,rAX", 0x97: "xchg Zv,rAX",
+	0x98: "cbw/cwde/cdqe", 0x99: "cwd/cdq/cqo", 0x9a: "call Ap i64", 0x9b: "fwait",
+	0x9c: "pushfw/pushf/pushf - d64", 0x9d: "popfw/popf/popf - d64", 0x9e: "sahf", 0x9f: "lahf",
+	0xa0: "mov AL,Ob", 0xa1: "mov rAX,Ov", 0xa2: "mov Ob,AL", 0xa3: "mov Ov,rAX",
+	0xa4: "movs Yb,Xb rep", 0xa5: "movs Yv,Xv rep", 0xa6: "cmps Xb,Yb repz", 0xa7: "cmps Xv,Yv repz",
+	0xa8: "test AL,Ib", 0xa9: "test rAX,Iz", 0xaa: "stos Yb,AL rep", 0xab: "stos Yv,rAX rep",
+	0xac: "lods AL,Xb rep", 0xad: "lods rAX,Xv rep", 0xae: "scas AL,Yb repz", 0xaf: "scas rAX,Yv repz",
+	0xb0: "mov Zb,Ib", 0xb1: "mov Zb,Ib", 0xb2: "mov Zb,Ib", 0xb3: "mov Zb,Ib",
+	0xb4: "mov Zb,Ib", 0xb5: "mov Zb,Ib", 0xb6: "mov Zb,Ib", 0xb7: "mov Zb,Ib",
+	0xb8: "mov Zv,Iv", 0xb9: "mov Zv,Iv", 0xba: "mov Zv,Iv", 0xbb: "mov Zv,Iv",
+	0xbc: "mov Zv,Iv", 0xbd: "mov Zv,Iv", 0xbe: "mov Zv,Iv", 0xbf: "mov Zv,Iv",
+	0xc0: "grp2 Eb,Ib", 0xc1: "grp2 Ev,Ib", 0xc2: "ret Iw f64,bnd", 0xc3: "ret - f64,bnd",
+	0xc4: "les Gz,Mp i64", 0xc5: "lds Gz,Mp i64", 0xc6: "grp11b Eb,Ib", 0xc7: "grp11v Ev,Iz",
+	0xc8: "enter Iw,Ib d64", 0xc9: "leave - d64", 0xca: "retf Iw", 0xcb: "retf",
+	0xcc: "int3", 0xcd: "int Ib", 0xce: "into - i64", 0xcf: "iretw/iret/iretq",
+	0xd0: "grp2 Eb,1", 0xd1: "grp2 Ev,1", 0xd2: "grp2 Eb,CL", 0xd3: "grp2 Ev,CL",
+	0xd4: "aam Ib i64", 0xd5: "aad Ib i64", 0xd7: "xlat BX",
+	0xe0: "loopne Jb f64", 0xe1: "loope Jb f64", 0xe2: "loop Jb f64", 0xe3: "jecxz Jb f64",
+	0xe4: "in AL,Ib", 0xe5: "in eAX,Ib", 0xe6: "out Ib,AL", 0xe7: "out Ib,eAX",
+	0xe8: "call Jz f64,bnd", 0xe9: "jmp Jz f64,bnd", 0xea: "jmp Ap i64", 0xeb: "jmp Jb f64,bnd",
+	0xec: "in AL,DX", 0xed: "in eAX,DX", 0xee: "out DX,AL", 0xef: "out DX,eAX",
+	0xf1: "int1", 0xf4: "hlt", 0xf5: "cmc", 0xf6: "grp3b Eb", 0xf7: "grp3v Ev",
+	0xf8: "clc", 0xf9: "stc", 0xfa: "cli", 0xfb: "sti", 0xfc: "cld", 0xfd: "std",
+	0xfe: "grp4 Eb", 0xff: "grp5 Ev",
+}
+
+// x86Groups are indexed by the reg field of ModRM; an entry without
+// operands takes those of the opcode.
+var x86Groups = map[string][8]string{
+	"grp1":   {"add", "or", "adc", "sbb", "and", "sub", "xor", "cmp"},
+	"grp1a":  {"pop"},
+	"grp2":   {"rol", "ror", "rcl", "rcr", "shl", "shr", "shl", "sar"},
+	"grp3b":  {"test Eb,Ib", "test Eb,Ib", "not", "neg", "mul", "imul", "div", "idiv"},
+	"grp3v":  {"test Ev,Iz", "test Ev,Iz", "not", "neg", "mul", "imul", "div", "idiv"},
+	"grp4":   {"inc", "dec"},
+	"grp5":   {"inc", "dec", "call Ev f64,bnd,notrack", "call Mp", "jmp Ev f64,bnd,notrack", "jmp Mp", "push Ev d64"},
+	"grp11b": {"mov"},
+	"grp11v": {"mov"},
+	"grp6":   {"sldt Ew", "str Ew", "lldt Ew", "ltr Ew", "verr Ew", "verw Ew"},
+	"grp7":   {"sgdt M", "sidt M", "lgdt M", "lidt M", "smsw Ew", "", "lmsw Ew", "invlpg Mb"},
+	"grp8":   {"", "", "", "", "bt", "bts", "btr", "btc"},
+	"grp9":   {"", "cmpxchg8b/cmpxchg8b/cmpxchg16b Mo", "", "xrstors M", "xsavec M", "xsaves M", "vmptrld Mq", "vmptrst Mq"},
+	"grp15":  {"fxsave M", "fxrstor M", "ldmxcsr Md", "stmxcsr Md", "xsave M", "xrstor M", "xsaveopt M", "clflush Mb"},
+	"grp16":  {"prefetchnta Mb", "prefetcht0 Mb", "prefetcht1 Mb", "prefetcht2 Mb", "nop Ev", "nop Ev", "nop Ev", "nop Ev"},
+	"grp12":  {"", "", "psrlw Nq,Ib", "", "psraw Nq,Ib", "", "psllw Nq,Ib"},
+	"grp13":  {"", "", "psrld Nq,Ib", "", "psrad Nq,Ib", "", "pslld Nq,Ib"},
+	"grp14":  {"", "", "psrlq Nq,Ib", "", "", "", "psllq Nq,Ib"},
+	"grp12x": {"", "", "psrlw Hx,Ux,Ib", "", "psraw Hx,Ux,Ib", "", "psllw Hx,Ux,Ib"},
+	"grp13x": {"", "", "psrld Hx,Ux,Ib", "", "psrad Hx,Ux,Ib", "", "pslld Hx,Ux,Ib"},
+	"grp14x": {"", "", "psrlq Hx,Ux,Ib", "psrldq Hx,Ux,Ib", "", "", "psllq Hx,Ux,Ib", "pslldq Hx,Ux,Ib"},
+	"grp17":  {"", "blsr By,Ey", "blsmsk By,Ey", "blsi By,Ey"},
+}
+
+// x86Grp7Reg names the register forms of 0F 01 by ModRM byte.
+var x86Grp7Reg = map[byte]string{
+	0xc1: "vmcall", 0xc2: "vmlaunch", 0xc3: "vmresume", 0xc4: "vmxoff",
+	0xc8: "monitor", 0xc9: "mwait", 0xca: "clac", 0xcb: "stac", 0xcf: "encls",
+	0xd0: "xgetbv", 0xd1: "xsetbv", 0xd4: "vmfunc", 0xd5: "xend", 0xd6: "xtest",
+	0xd7: "enclu", 0xe8: "serialize", 0xee: "rdpkru", 0xef: "wrpkru",
+	0xf8: "swapgs", 0xf9: "rdtscp", 0xfa: "monitorx", 0xfb: "mwaitx",
+	0xfc: "clzero",
+}
+
+// x86TwoByte is the 0F map for opcodes whose meaning does not depend
+// on a 66, F3 or F2 prefix.
+var x86TwoByte = [256]string{
+	0x00: "grp6 Ew", 0x01: "grp7", 0x02: "lar Gv,Ew", 0x03: "lsl Gv,Ew",
+	0x05: "syscall - o64", 0x06: "clts", 0x07: "sysret/sysret/sysretq - o64", 0x08: "invd", 0x09: "wbinvd",
+	0x0b: "ud2", 0x0d: "prefetchw Mb", 0x0e: "femms",
+	0x18: "grp16", 0x19: "nop Ev", 0x1a: "nop Ev", 0x1b: "nop Ev", 0x1c: "nop Ev",
+	0x1d: "nop Ev", 0x1f: "nop Ev",
+	0x20: "mov Ry,Cy", 0x21: "mov Ry,Dy", 0x22: "mov Cy,Ry", 0x23: "mov Dy,Ry",
+	0x30: "wrmsr", 0x31: "rdtsc", 0x32: "rdmsr", 0x33: "rdpmc", 0x34: "sysenter",
+	0x35: "sysexit/sysexit/sysexitq", 0x37: "getsec",
+	0x40: "cmovo Gv,Ev", 0x41: "cmovno Gv,Ev", 0x42: "cmovb Gv,Ev", 0x43: "cmovae Gv,Ev",
+	0x44: "cmove Gv,Ev", 0x45: "cmovne Gv,Ev", 0x46: "cmovbe Gv,Ev", 0x47: "cmova Gv,Ev",
+	0x48: "cmovs Gv,Ev", 0x49: "cmovns Gv,Ev", 0x4a: "cmovp Gv,Ev", 0x4b: "cmovnp Gv,Ev",
+	0x4c: "cmovl Gv,Ev", 0x4d: "cmovge Gv,Ev", 0x4e: "cmovle Gv,Ev", 0x4f: "cmovg Gv,Ev",
+	0x78: "vmread Ey,Gy", 0x79: "vmwrite Gy,Ey",
+	0x80: "jo Jz f64,bnd", 0x81: "jno Jz f64,bnd", 0x82: "jb Jz f64,bnd", 0x83: "jae Jz f64,bnd",
+	0x84: "je Jz f64,bnd", 0x85: "jne Jz f64,bnd", 0x86: "jbe Jz f64,bnd", 0x87: "ja Jz f64,bnd",
+	0x88: "js Jz f64,bnd", 0x89: "jns Jz f64,bnd", 0x8a: "jp Jz f64,bnd", 0x8b: "jnp Jz f64,bnd",
+	0x8c: "jl Jz f64,bnd", 0x8d: "jge Jz f64,bnd", 0x8e: "jle Jz f64,bnd", 0x8f: "jg Jz f64,bnd",
+	0x90: "seto Eb", 0x91: "setno Eb", 0x92: "setb Eb", 0x93: "setae Eb",
+	0x94: "sete Eb", 0x95: "setne Eb", 0x96: "setbe Eb", 0x97: "seta Eb",
+	0x98: "sets Eb", 0x99: "setns Eb", 0x9a: "setp Eb", 0x9b: "setnp Eb",
+	0x9c: "setl Eb", 0x9d: "setge Eb", 0x9e: "setle Eb", 0x9f: "setg Eb",
+	0xa0: "push FS d64", 0xa1: "pop FS d64", 0xa2: "cpuid", 0xa3: "bt Ev,Gv",
+	0xa4: "shld Ev,Gv,Ib", 0xa5: "shld Ev,Gv,CL",
+	0xa8: "push GS d64", 0xa9: "pop GS d64", 0xaa: "rsm", 0xab: "bts Ev,Gv",
+	0xac: "shrd Ev,Gv,Ib", 0xad: "shrd Ev,Gv,CL", 0xae: "grp15", 0xaf: "imul Gv,Ev",
+	0xb0: "cmpxchg Eb,Gb", 0xb1: "cmpxchg Ev,Gv", 0xb2: "lss Gv,Mp", 0xb3: "btr Ev,Gv",
+	0xb4: "lfs Gv,Mp", 0xb5: "lgs Gv,Mp", 0xb6: "movzx Gv,Eb", 0xb7: "movzx Gv,Ew",
+	0xb9: "ud1 Gv,Ev", 0xba: "grp8 Ev,Ib", 0xbb: "btc Ev,Gv",
+	0xbe: "movsx Gv,Eb", 0xbf: "movsx Gv,Ew",
+	0xc0: "xadd Eb,Gb", 0xc1: "xadd Ev,Gv", 0xc3: "movnti My,Gy", 0xc7: "grp9",
+	0xc8: "bswap Zy", 0xc9: "bswap Zy", 0xca: "bswap Zy", 0xcb: "bswap Zy",
+	0xcc: "bswap Zy", 0xcd: "bswap Zy", 0xce: "bswap Zy", 0xcf: "bswap Zy",
+	0xff: "ud0 Gv,Ev",
+}
+
+// x86TwoBytePrefixed is the 0F map for opcodes selected by their
+// mandatory prefix: none, 66, F3 and F2.
+var x86TwoBytePrefixed = map[byte][4]string{
+	0x10: {"movups Vx,Wx", "movupd Vx,Wx", "movss Vx,Hm,Wss", "movsd Vx,Hm,Wsd"},
+	0x11: {"movups Wx,Vx", "movupd Wx,Vx", "movss Wss,Hm,Vx", "movsd Wsd,Hm,Vx"},
+	0x12: {"movlps Vdq,Hdq,Mq", "movlpd Vdq,Hdq,Mq", "movsldup Vx,Wx", "movddup Vx,Wx"},
+	0x13: {"movlps Mq,Vdq", "movlpd Mq,Vdq"},
+	0x14: {"unpcklps Vx,Hx,Wx", "unpcklpd Vx,Hx,Wx"},
+	0x15: {"unpckhps Vx,Hx,Wx", "unpckhpd Vx,Hx,Wx"},
+	0x16: {"movhps Vdq,Hdq,Mq", "movhpd Vdq,Hdq,Mq", "movshdup Vx,Wx"},
+	0x17: {"movhps Mq,Vdq", "movhpd Mq,Vdq"},
+	0x1e: {"nop Ev", "nop Ev", "grp1e", "nop Ev"},
+	0x28: {"movaps Vx,Wx", "movapd Vx,Wx"},
+	0x29: {"movaps Wx,Vx", "movapd Wx,Vx"},
+	0x2a: {"cvtpi2ps Vdq,Qq", "cvtpi2pd Vdq,Qq", "cvtsi2ss Vdq,Hdq,Ey", "cvtsi2sd Vdq,Hdq,Ey"},
+	0x2b: {"movntps Mx,Vx", "movntpd Mx,Vx"},
+	0x2c: {"cvttps2pi Pq,Wq", "cvttpd2pi Pq,Wdq", "cvttss2si Gy,Wss", "cvttsd2si Gy,Wsd"},
+	0x2d: {"cvtps2pi Pq,Wq", "cvtpd2pi Pq,Wdq", "cvtss2si Gy,Wss", "cvtsd2si Gy,Wsd"},
+	0x2e: {"ucomiss Vdq,Wss", "ucomisd Vdq,Wsd"},
+	0x2f: {"comiss Vdq,Wss", "comisd Vdq,Wsd"},
+	0x50: {"movmskps Gd,Ux", "movmskpd Gd,Ux"},
+	0x51: {"sqrtps Vx,Wx", "sqrtpd Vx,Wx", "sqrtss Vdq,Hdq,Wss", "sqrtsd Vdq,Hdq,Wsd"},
+	0x52: {"rsqrtps Vx,Wx", "", "rsqrtss Vdq,Hdq,Wss"},
+	0x53: {"rcpps Vx,Wx", "", "rcpss Vdq,Hdq,Wss"},
+	0x54: {"andps Vx,Hx,Wx", "andpd Vx,Hx,Wx"},
+	0x55: {"andnps Vx,Hx,Wx", "andnpd Vx,Hx,Wx"},
+	0x56: {"orps Vx,Hx,Wx", "orpd Vx,Hx,Wx"},
+	0x57: {"xorps Vx,Hx,Wx", "xorpd Vx,Hx,Wx"},
+	0x58: {"addps Vx,Hx,Wx", "addpd Vx,Hx,Wx", "addss Vdq,Hdq,Wss", "addsd Vdq,Hdq,Wsd"},
+	0x59: {"mulps Vx,Hx,Wx", "mulpd Vx,Hx,Wx", "mulss Vdq,Hdq,Wss", "mulsd Vdq,Hdq,Wsd"},
+	0x5a: {"cvtps2pd Vx,Wh", "cvtpd2ps Vh,Wx", "cvtss2sd Vdq,Hdq,Wss", "cvtsd2ss Vdq,Hdq,Wsd"},
+	0x5b: {"cvtdq2ps Vx,Wx", "cvtps2dq Vx,Wx", "cvttps2dq Vx,Wx"},
+	0x5c: {"subps Vx,Hx,Wx", "subpd Vx,Hx,Wx", "subss Vdq,Hdq,Wss", "subsd Vdq,Hdq,Wsd"},
+	0x5d: {"minps Vx,Hx,Wx", "minpd Vx,Hx,Wx", "minss Vdq,Hdq,Wss", "minsd Vdq,Hdq,Wsd"},
+	0x5e: {"divps Vx,Hx,Wx", "divpd Vx,Hx,Wx", "divss Vdq,Hdq,Wss", "divsd Vdq,Hdq,Wsd"},
+	0x5f: {"maxps Vx,Hx,Wx", "maxpd Vx,Hx,Wx", "maxss Vdq,Hdq,Wss", "maxsd Vdq,Hdq,Wsd"},
+	0x60: {"punpcklbw Pq,Qd", "punpcklbw Vx,Hx,Wx"},
+	0x61: {"punpcklwd Pq,Qd", "punpcklwd Vx,Hx,Wx"},
+	0x62: {"punpckldq Pq,Qd", "punpckldq Vx,Hx,Wx"},
+	0x63: {"packsswb Pq,Qq", "packsswb Vx,Hx,Wx"},
+	0x64: {"pcmpgtb Pq,Qq", "pcmpgtb Vx,Hx,Wx"},
+	0x65: {"pcmpgtw Pq,Qq", "pcmpgtw Vx,Hx,Wx"},
+	0x66: {"pcmpgtd Pq,Qq", "pcmpgtd Vx,Hx,Wx"},
+	0x67: {"packuswb Pq,Qq", "packuswb Vx,Hx,Wx"},
+	0x68: {"punpckhbw Pq,Qq", "punpckhbw Vx,Hx,Wx"},
+	0x69: {"punpckhwd Pq,Qq", "punpckhwd Vx,Hx,Wx"},
+	0x6a: {"punpckhdq Pq,Qq", "punpckhdq Vx,Hx,Wx"},
+	0x6b: {"packssdw Pq,Qq", "packssdw Vx,Hx,Wx"},
+	0x6c: {"", "punpcklqdq Vx,Hx,Wx"},
+	0x6d: {"", "punpckhqdq Vx,Hx,Wx"},
+	0x6e: {"movd/movd/movq Pq,Ey", "movd/movd/movq Vdq,Ey"},
+	0x6f: {"movq Pq,Qq", "movdqa Vx,Wx", "movdqu Vx,Wx"},
+	0x70: {"pshufw Pq,Qq,Ib", "pshufd Vx,Wx,Ib", "pshufhw Vx,Wx,Ib", "pshuflw Vx,Wx,Ib"},
+	0x71: {"grp12", "grp12x"},
+	0x72: {"grp13", "grp13x"},
+	0x73: {"grp14", "grp14x"},
+	0x74: {"pcmpeqb Pq,Qq", "pcmpeqb Vx,Hx,Wx"},
+	0x75: {"pcmpeqw Pq,Qq", "pcmpeqw Vx,Hx,Wx"},
+	0x76: {"pcmpeqd Pq,Qq", "pcmpeqd Vx,Hx,Wx"},
+	0x77: {"emms"},
+	0x7c: {"", "haddpd Vx,Hx,Wx", "", "haddps Vx,Hx,Wx"},
+	0x7d: {"", "hsubpd Vx,Hx,Wx", "", "hsubps Vx,Hx,Wx"},
+	0x7e: {"movd/movd/movq Ey,Pq", "movd/movd/movq Ey,Vdq", "movq Vdq,Wq"},
+	0x7f: {"movq Qq,Pq", "movdqa Wx,Vx", "movdqu Wx,Vx"},
+	0xb8: {"", "", "popcnt Gv,Ev"},
+	0xbc: {"bsf Gv,Ev", "bsf Gv,Ev", "tzcnt Gv,Ev"},
+	0xbd: {"bsr Gv,Ev", "bsr Gv,Ev", "lzcnt Gv,Ev"},
+	0xc2: {"cmpps Vx,Hx,Wx,Ib", "cmppd Vx,Hx,Wx,Ib", "cmpss Vdq,Hdq,Wss,Ib", "cmpsd Vdq,Hdq,Wsd,Ib"},
+	0xc4: {"pinsrw Pq,Rd/Mw,Ib", "pinsrw Vdq,Hdq,Rd/Mw,Ib"},
+	0xc5: {"pextrw Gd,Nq,Ib", "pextrw Gd,Udq,Ib"},
+	0xc6: {"shufps Vx,Hx,Wx,Ib", "shufpd Vx,Hx,Wx,Ib"},
+	0xd0: {"", "addsubpd Vx,Hx,Wx", "", "addsubps Vx,Hx,Wx"},
+	0xd1: {"psrlw Pq,Qq", "psrlw Vx,Hx,Wdq"},
+	0xd2: {"psrld Pq,Qq", "psrld Vx,Hx,Wdq"},
+	0xd3: {"psrlq Pq,Qq", "psrlq Vx,Hx,Wdq"},
+	0xd4: {"paddq Pq,Qq", "paddq Vx,Hx,Wx"},
+	0xd5: {"pmullw Pq,Qq", "pmullw Vx,Hx,Wx"},
+	0xd6: {"", "movq Wq,Vdq", "movq2dq Vdq,Nq", "movdq2q Pq,Udq"},
+	0xd7: {"pmovmskb Gd,Nq", "pmovmskb Gd,Ux"},
+	0xd8: {"psubusb Pq,Qq", "psubusb Vx,Hx,Wx"},
+	0xd9: {"psubusw Pq,Qq", "psubusw Vx,Hx,Wx"},
+	0xda: {"pminub Pq,Qq", "pminub Vx,Hx,Wx"},
+	0xdb: {"pand Pq,Qq", "pand Vx,Hx,Wx"},
+	0xdc: {"paddusb Pq,Qq", "paddusb Vx,Hx,Wx"},
+	0xdd: {"paddusw Pq,Qq", "paddusw Vx,Hx,Wx"},
+	0xde: {"pmaxub Pq,Qq", "pmaxub Vx,Hx,Wx"},
+	0xdf: {"pandn Pq,Qq", "pandn Vx,Hx,Wx"},
+	0xe0: {"pavgb Pq,Qq", "pavgb Vx,Hx,Wx"},
+	0xe1: {"psraw Pq,Qq", "psraw Vx,Hx,Wdq"},
+	0xe2: {"psrad Pq,Qq", "psrad Vx,Hx,Wdq"},
+	0xe3: {"pavgw Pq,Qq", "pavgw Vx,Hx,Wx"},
+	0xe4: {"pmulhuw Pq,Qq", "pmulhuw Vx,Hx,Wx"},
+	0xe5: {"pmulhw Pq,Qq", "pmulhw Vx,Hx,Wx"},
+	0xe6: {"", "cvttpd2dq Vh,Wx", "cvtdq2pd Vx,Wh", "cvtpd2dq Vh,Wx"},
+	0xe7: {"movntq Mq,Pq", "movntdq Mx,Vx"},
+	0xe8: {"psubsb Pq,Qq", "psubsb Vx,Hx,Wx"},
+	0xe9: {"psubsw Pq,Qq", "psubsw Vx,Hx,Wx"},
+	0xea: {"pminsw Pq,Qq", "pminsw Vx,Hx,Wx"},
+	0xeb: {"por Pq,Qq", "por Vx,Hx,Wx"},
+	0xec: {"paddsb Pq,Qq", "paddsb Vx,Hx,Wx"},
+	0xed: {"paddsw Pq,Qq", "paddsw Vx,Hx,Wx"},
+	0xee: {"pmaxsw Pq,Qq", "pmaxsw Vx,Hx,Wx"},
+	0xef: {"pxor Pq,Qq", "pxor Vx,Hx,Wx"},
+	0xf0: {"", "", "", "lddqu Vx,M"},
+	0xf1: {"psllw Pq,Qq", "psllw Vx,Hx,Wdq"},
+	0xf2: {"pslld Pq,Qq", "pslld Vx,Hx,Wdq"},
+	0xf3: {"psllq Pq,Qq", "psllq Vx,Hx,Wdq"},
+	0xf4: {"pmuludq Pq,Qq", "pmuludq Vx,Hx,Wx"},
+	0xf5: {"pmaddwd Pq,Qq", "pmaddwd Vx,Hx,Wx"},
+	0xf6: {"psadbw Pq,Qq", "psadbw Vx,Hx,Wx"},
+	0xf7: {"maskmovq Pq,Nq", "maskmovdqu Vdq,Udq"},
+	0xf8: {"psubb Pq,Qq", "psubb Vx,Hx,Wx"},
+	0xf9: {"psubw Pq,Qq", "psubw Vx,Hx,Wx"},
+	0xfa: {"psubd Pq,Qq", "psubd Vx,Hx,Wx"},
+	0xfb: {"psubq Pq,Qq", "psubq Vx,Hx,Wx"},
+	0xfc: {"paddb Pq,Qq", "paddb Vx,Hx,Wx"},
+	0xfd: {"paddw Pq,Qq", "paddw Vx,Hx,Wx"},
+	0xfe: {"paddd Pq,Qq", "paddd Vx,Hx,Wx"},
+}
+
+// x86Map38 is the 0F 38 map by mandatory prefix; all of it has ModRM
+// and no immediate.
+var x86Map38 = map[byte][4]string{
+	0x00: {"pshufb Pq,Qq", "pshufb Vx,Hx,Wx"},
+	0x01: {"phaddw Pq,Qq", "phaddw Vx,Hx,Wx"},
+	0x02: {"phaddd Pq,Qq", "phaddd Vx,Hx,Wx"},
+	0x03: {"phaddsw Pq,Qq", "phaddsw Vx,Hx,Wx"},
+	0x04: {"pmaddubsw Pq,Qq", "pmaddubsw Vx,Hx,Wx"},
+	0x05: {"phsubw Pq,Qq", "phsubw Vx,Hx,Wx"},
+	0x06: {"phsubd Pq,Qq", "phsubd Vx,Hx,Wx"},
+	0x07: {"phsubsw Pq,Qq", "phsubsw Vx,Hx,Wx"},
+	0x08: {"psignb Pq,Qq", "psignb Vx,Hx,Wx"},
+	0x09: {"psignw Pq,Qq", "psignw Vx,Hx,Wx"},
+	0x0a: {"psignd Pq,Qq", "psignd Vx,Hx,Wx"},
+	0x0b: {"pmulhrsw Pq,Qq", "pmulhrsw Vx,Hx,Wx"},
+	0x10: {"", "pblendvb Vdq,Wdq,XMM0"},
+	0x14: {"", "blendvps Vdq,Wdq,XMM0"},
+	0x15: {"", "blendvpd Vdq,Wdq,XMM0"},
+	0x17: {"", "ptest Vx,Wx"},
+	0x1c: {"pabsb Pq,Qq", "pabsb Vx,Wx"},
+	0x1d: {"pabsw Pq,Qq", "pabsw Vx,Wx"},
+	0x1e: {"pabsd Pq,Qq", "pabsd Vx,Wx"},
+	0x20: {"", "pmovsxbw Vx,Wh"},
+	0x21: {"", "pmovsxbd Vx,Wk"},
+	0x22: {"", "pmovsxbq Vx,We"},
+	0x23: {"", "pmovsxwd Vx,Wh"},
+	0x24: {"", "pmovsxwq Vx,Wk"},
+	0x25: {"", "pmovsxdq Vx,Wh"},
+	0x28: {"", "pmuldq Vx,Hx,Wx"},
+	0x29: {"", "pcmpeqq Vx,Hx,Wx"},
+	0x2a: {"", "movntdqa Vx,Mx"},
+	0x2b: {"", "packusdw Vx,Hx,Wx"},
+	0x30: {"", "pmovzxbw Vx,Wh"},
+	0x31: {"", "pmovzxbd Vx,Wk"},
+	0x32: {"", "pmovzxbq Vx,We"},
+	0x33: {"", "pmovzxwd Vx,Wh"},
+	0x34: {"", "pmovzxwq Vx,Wk"},
+	0x35: {"", "pmovzxdq Vx,Wh"},
+	0x37: {"", "pcmpgtq Vx,Hx,Wx"},
+	0x38: {"", "pminsb Vx,Hx,Wx"},
+	0x39: {"", "pminsd Vx,Hx,Wx"},
+	0x3a: {"", "pminuw Vx,Hx,Wx"},
+	0x3b: {"", "pminud Vx,Hx,Wx"},
+	0x3c: {"", "pmaxsb Vx,Hx,Wx"},
+	0x3d: {"", "pmaxsd Vx,Hx,Wx"},
+	0x3e: {"", "pmaxuw Vx,Hx,Wx"},
+	0x3f: {"", "pmaxud Vx,Hx,Wx"},
+	0x40: {"", "pmulld Vx,Hx,Wx"},
+	0x41: {"", "phminposuw Vdq,Wdq"},
+	0xc8: {"sha1nexte Vdq,Wdq"},
+	0xc9: {"sha1msg1 Vdq,Wdq"},
+	0xca: {"sha1msg2 Vdq,Wdq"},
+	0xcb: {"sha256rnds2 Vdq,Wdq,XMM0"},
+	0xcc: {"sha256msg1 Vdq,Wdq"},
+	0xcd: {"sha256msg2 Vdq,Wdq"},
+	0xdb: {"", "aesimc Vdq,Wdq"},
+	0xdc: {"", "aesenc Vx,Hx,Wx"},
+	0xdd: {"", "aesenclast Vx,Hx,Wx"},
+	0xde: {"", "aesdec Vx,Hx,Wx"},
+	0xdf: {"", "aesdeclast Vx,Hx,Wx"},
+	0xf0: {"movbe Gv,Mv", "movbe Gw,Mw", "", "crc32 Gy,Eb"},
+	0xf1: {"movbe Mv,Gv", "movbe Mw,Gw", "", "crc32 Gy,Ev"},
+	0xf6: {"", "adcx Gy,Ey", "adox Gy,Ey"},
+}
+
+// x86Map3A is the 0F 3A map by mandatory prefix; all of it has ModRM
+// and an 8-bit immediate.
+var x86Map3A = map[byte][4]string{
+	0x08: {"", "roundps Vx,Wx,Ib"},
+	0x09: {"", "roundpd Vx,Wx,Ib"},
+	0x0a: {"", "roundss Vdq,Hdq,Wss,Ib"},
+	0x0b: {"", "roundsd Vdq,Hdq,Wsd,Ib"},
+	0x0c: {"", "blendps Vx,Hx,Wx,Ib"},
+	0x0d: {"", "blendpd Vx,Hx,Wx,Ib"},
+	0x0e: {"", "pblendw Vx,Hx,Wx,Ib"},
+	0x0f: {"palignr Pq,Qq,Ib", "palignr Vx,Hx,Wx,Ib"},
+	0x14: {"", "pextrb Rd/Mb,Vdq,Ib"},
+	0x15: {"", "pextrw Rd/Mw,Vdq,Ib"},
+	0x16: {"", "pextrd/pextrd/pextrq Ey,Vdq,Ib"},
+	0x17: {"", "extractps Ed,Vdq,Ib"},
+	0x20: {"", "pinsrb Vdq,Hdq,Rd/Mb,Ib"},
+	0x21: {"", "insertps Vdq,Hdq,Wd,Ib"},
+	0x22: {"", "pinsrd/pinsrd/pinsrq Vdq,Hdq,Ey,Ib"},
+	0x40: {"", "dpps Vx,Hx,Wx,Ib"},
+	0x41: {"", "dppd Vdq,Hdq,Wdq,Ib"},
+	0x42: {"", "mpsadbw Vx,Hx,Wx,Ib"},
+	0x44: {"", "pclmulqdq Vdq,Hdq,Wdq,Ib"},
+	0x60: {"", "pcmpestrm Vdq,Wdq,Ib"},
+	0x61: {"", "pcmpestri Vdq,Wdq,Ib"},
+	0x62: {"", "pcmpistrm Vdq,Wdq,Ib"},
+	0x63: {"", "pcmpistri Vdq,Wdq,Ib"},
+	0xcc: {"sha1rnds4 Vdq,Wdq,Ib"},
+	0xdf: {"", "aeskeygenassist Vdq,Wdq,Ib"},
+}
+
+// x86VEX38 and x86VEX3A hold the VEX-only instructions of the 0F 38
+// and 0F 3A maps by prefix; their names are complete.
+var x86VEX38 = map[byte][4]string{
+	0x0c: {"", "vpermilps Vx,Hx,Wx"},
+	0x0d: {"", "vpermilpd Vx,Hx,Wx"},
+	0x0e: {"", "vtestps Vx,Wx"},
+	0x0f: {"", "vtestpd Vx,Wx"},
+	0x13: {"", "vcvtph2ps Vx,Wh"},
+	0x16: {"", "vpermps Vqq,Hqq,Wqq"},
+	0x18: {"", "vbroadcastss Vx,Wd"},
+	0x19: {"", "vbroadcastsd Vqq,Wq"},
+	0x1a: {"", "vbroadcastf128 Vqq,Mdq"},
+	0x2c: {"", "vmaskmovps Vx,Hx,Mx"},
+	0x2d: {"", "vmaskmovpd Vx,Hx,Mx"},
+	0x2e: {"", "vmaskmovps Mx,Hx,Vx"},
+	0x2f: {"", "vmaskmovpd Mx,Hx,Vx"},
+	0x36: {"", "vpermd Vqq,Hqq,Wqq"},
+	0x45: {"", "vpsrlvd/vpsrlvd/vpsrlvq Vx,Hx,Wx"},
+	0x46: {"", "vpsravd Vx,Hx,Wx"},
+	0x47: {"", "vpsllvd/vpsllvd/vpsllvq Vx,Hx,Wx"},
+	0x58: {"", "vpbroadcastd Vx,Wd"},
+	0x59: {"", "vpbroadcastq Vx,Wq"},
+	0x5a: {"", "vbroadcasti128 Vqq,Mdq"},
+	0x78: {"", "vpbroadcastb Vx,Wb"},
+	0x79: {"", "vpbroadcastw Vx,Ww"},
+	0x8c: {"", "vpmaskmovd/vpmaskmovd/vpmaskmovq Vx,Hx,Mx"},
+	0x8e: {"", "vpmaskmovd/vpmaskmovd/vpmaskmovq Mx,Hx,Vx"},
+	0x96: {"", "vfmaddsub132ps/vfmaddsub132ps/vfmaddsub132pd Vx,Hx,Wx"},
+	0x97: {"", "vfmsubadd132ps/vfmsubadd132ps/vfmsubadd132pd Vx,Hx,Wx"},
+	0x98: {"", "vfmadd132ps/vfmadd132ps/vfmadd132pd Vx,Hx,Wx"},
+	0x99: {"", "vfmadd132ss/vfmadd132ss/vfmadd132sd Vdq,Hdq,Wsy"},
+	0x9a: {"", "vfmsub132ps/vfmsub132ps/vfmsub132pd Vx,Hx,Wx"},
+	0x9b: {"", "vfmsub132ss/vfmsub132ss/vfmsub132sd Vdq,Hdq,Wsy"},
+	0x9c: {"", "vfnmadd132ps/vfnmadd132ps/vfnmadd132pd Vx,Hx,Wx"},
+	0x9d: {"", "vfnmadd132ss/vfnmadd132ss/vfnmadd132sd Vdq,Hdq,Wsy"},
+	0x9e: {"", "vfnmsub132ps/vfnmsub132ps/vfnmsub132pd Vx,Hx,Wx"},
+	0x9f: {"", "vfnmsub132ss/vfnmsub132ss/vfnmsub132sd Vdq,Hdq,Wsy"},
+	0xa6: {"", "vfmaddsub213ps/vfmaddsub213ps/vfmaddsub213pd Vx,Hx,Wx"},
+	0xa7: {"", "vfmsubadd213ps/vfmsubadd213ps/vfmsubadd213pd Vx,Hx,Wx"},
+	0xa8: {"", "vfmadd213ps/vfmadd213ps/vfmadd213pd Vx,Hx,Wx"},
+	0xa9: {"", "vfmadd213ss/vfmadd213ss/vfmadd213sd Vdq,Hdq,Wsy"},
+	0xaa: {"", "vfmsub213ps/vfmsub213ps/vfmsub213pd Vx,Hx,Wx"},
+	0xab: {"", "vfmsub213ss/vfmsub213ss/vfmsub213sd Vdq,Hdq,Wsy"},
+	0xac: {"", "vfnmadd213ps/vfnmadd213ps/vfnmadd213pd Vx,Hx,Wx"},
+	0xad: {"", "vfnmadd213ss/vfnmadd213ss/vfnmadd213sd Vdq,Hdq,Wsy"},
+	0xae: {"", "vfnmsub213ps/vfnmsub213ps/vfnmsub213pd Vx,Hx,Wx"},
+	0xaf: {"", "vfnmsub213ss/vfnmsub213ss/vfnmsub213sd Vdq,Hdq,Wsy"},
+	0xb6: {"", "vfmaddsub231ps/vfmaddsub231ps/vfmaddsub231pd Vx,Hx,Wx"},
+	0xb7: {"", "vfmsubadd231ps/vfmsubadd231ps/vfmsubadd231pd Vx,Hx,Wx"},
+	0xb8: {"", "vfmadd231ps/vfmadd231ps/vfmadd231pd Vx,Hx,Wx"},
+	0xb9: {"", "vfmadd231ss/vfmadd231ss/vfmadd231sd Vdq,Hdq,Wsy"},
+	0xba: {"", "vfmsub231ps/vfmsub231ps/vfmsub231pd Vx,Hx,Wx"},
+	0xbb: {"", "vfmsub231ss/vfmsub231ss/vfmsub231sd Vdq,Hdq,Wsy"},
+	0xbc: {"", "vfnmadd231ps/vfnmadd231ps/vfnmadd231pd Vx,Hx,Wx"},
+	0xbd: {"", "vfnmadd231ss/vfnmadd231ss/vfnmadd231sd Vdq,Hdq,Wsy"},
+	0xbe: {"", "vfnmsub231ps/vfnmsub231ps/vfnmsub231pd Vx,Hx,Wx"},
+	0xbf: {"", "vfnmsub231ss/vfnmsub231ss/vfnmsub231sd Vdq,Hdq,Wsy"},
+	0xf2: {"andn Gy,By,Ey"},
+	0xf3: {"grp17"},
+	0xf5: {"bzhi Gy,Ey,By", "", "pext Gy,By,Ey", "pdep Gy,By,Ey"},
+	0xf6: {"", "", "", "mulx Gy,By,Ey"},
+	0xf7: {"bextr Gy,Ey,By", "shlx Gy,Ey,By", "sarx Gy,Ey,By", "shrx Gy,Ey,By"},
+}
+
+var x86VEX3A = map[byte][4]string{
+	0x00: {"", "vpermq Vqq,Wqq,Ib"},
+	0x01: {"", "vpermpd Vqq,Wqq,Ib"},
+	0x02: {"", "vpblendd Vx,Hx,Wx,Ib"},
+	0x04: {"", "vpermilps Vx,Wx,Ib"},
+	0x05: {"", "vpermilpd Vx,Wx,Ib"},
+	0x06: {"", "vperm2f128 Vqq,Hqq,Wqq,Ib"},
+	0x18: {"", "vinsertf128 Vqq,Hqq,Wdq,Ib"},
+	0x19: {"", "vextractf128 Wdq,Vqq,Ib"},
+	0x1d: {"", "vcvtps2ph Wh,Vx,Ib"},
+	0x38: {"", "vinserti128 Vqq,Hqq,Wdq,Ib"},
+	0x39: {"", "vextracti128 Wdq,Vqq,Ib"},
+	0x46: {"", "vperm2i128 Vqq,Hqq,Wqq,Ib"},
+	0x4a: {"", "vblendvps Vx,Hx,Wx,Lx"},
+	0x4b: {"", "vblendvpd Vx,Hx,Wx,Lx"},
+	0x4c: {"", "vpblendvb Vx,Hx,Wx,Lx"},
+	0xf0: {"", "", "", "rorx Gy,Ey,Ib"},
+}
+
+// x86FMA4 are the AMD four-operand multiply-adds of VEX 66 0F 3A.
+var x86FMA4 = map[byte]string{
+	0x5c: "vfmaddsubps Vx,Hx,Wx,Lx", 0x5d: "vfmaddsubpd Vx,Hx,Wx,Lx",
+	0x5e: "vfmsubaddps Vx,Hx,Wx,Lx", 0x5f: "vfmsubaddpd Vx,Hx,Wx,Lx",
+	0x68: "vfmaddps Vx,Hx,Wx,Lx", 0x69: "vfmaddpd Vx,Hx,Wx,Lx",
+	0x6a: "vfmaddss Vdq,Hdq,Wss,Lx", 0x6b: "vfmaddsd Vdq,Hdq,Wsd,Lx",
+	0x6c: "vfmsubps Vx,Hx,Wx,Lx", 0x6d: "vfmsubpd Vx,Hx,Wx,Lx",
+	0x6e: "vfmsubss Vdq,Hdq,Wss,Lx", 0x6f: "vfmsubsd Vdq,Hdq,Wsd,Lx",
+	0x78: "vfnmaddps Vx,Hx,Wx,Lx", 0x79: "vfnmaddpd Vx,Hx,Wx,Lx",
+	0x7a: "vfnmaddss Vdq,Hdq,Wss,Lx", 0x7b: "vfnmaddsd Vdq,Hdq,Wsd,Lx",
+	0x7c: "vfnmsubps Vx,Hx,Wx,Lx", 0x7d: "vfnmsubpd Vx,Hx,Wx,Lx",
+	0x7e: "vfnmsubss Vdq,Hdq,Wss,Lx", 0x7f: "vfnmsubsd Vdq,Hdq,Wsd,Lx",
+}
+
+// x86EVEX holds the EVEX instructions that differ from their VEX
+// forms, by map, opcode and prefix; "/" in a name picks by EVEX.W.
+var x86EVEX = map[int]map[byte][4]string{
+	1: {
+		0x6f: {"", "vmovdqa32/vmovdqa64 Vx,Wx", "vmovdqu32/vmovdqu64 Vx,Wx", "vmovdqu8/vmovdqu16 Vx,Wx"},
+		0x7f: {"", "vmovdqa32/vmovdqa64 Wx,Vx", "vmovdqu32/vmovdqu64 Wx,Vx", "vmovdqu8/vmovdqu16 Wx,Vx"},
+		0x64: {"", "vpcmpgtb KV,Hx,Wx"},
+		0x65: {"", "vpcmpgtw KV,Hx,Wx"},
+		0x66: {"", "vpcmpgtd KV,Hx,Wx"},
+		0x74: {"", "vpcmpeqb KV,Hx,Wx"},
+		0x75: {"", "vpcmpeqw KV,Hx,Wx"},
+		0x76: {"", "vpcmpeqd KV,Hx,Wx"},
+		0xdb: {"", "vpandd/vpandq Vx,Hx,Wx"},
+		0xdf: {"", "vpandnd/vpandnq Vx,Hx,Wx"},
+		0xeb: {"", "vpord/vporq Vx,Hx,Wx"},
+		0xef: {"", "vpxord/vpxorq Vx,Hx,Wx"},
+	},
+	2: {
+		0x26: {"", "vptestmb/vptestmw KV,Hx,Wx", "vptestnmb/vptestnmw KV,Hx,Wx"},
+		0x27: {"", "vptestmd/vptestmq KV,Hx,Wx", "vptestnmd/vptestnmq KV,Hx,Wx"},
+		0x29: {"", "vpcmpeqq KV,Hx,Wx", "vpmovb2m/vpmovw2m KV,Ux"},
+		0x37: {"", "vpcmpgtq KV,Hx,Wx"},
+		0x39: {"", "vpminsd/vpminsq Vx,Hx,Wx", "vpmovd2m/vpmovq2m KV,Ux"},
+		0x3b: {"", "vpminud/vpminuq Vx,Hx,Wx"},
+		0x3f: {"", "vpmaxud/vpmaxuq Vx,Hx,Wx"},
+		0x64: {"", "vpblendmd/vpblendmq Vx,Hx,Wx"},
+		0x66: {"", "vpblendmb/vpblendmw Vx,Hx,Wx"},
+		0x7a: {"", "vpbroadcastb Vx,Rd"},
+		0x7b: {"", "vpbroadcastw Vx,Rd"},
+		0x7c: {"", "vpbroadcastd/vpbroadcastq Vx,Ry"},
+		0x28: {"", "vpmuldq Vx,Hx,Wx", "vpmovm2b/vpmovm2w Vx,KU"},
+		0x38: {"", "vpminsb Vx,Hx,Wx", "vpmovm2d/vpmovm2q Vx,KU"},
+		0x8d: {"", "vpermb/vpermw Vx,Hx,Wx"},
+		0x75: {"", "vpermi2b/vpermi2w Vx,Hx,Wx"},
+		0x7d: {"", "vpermt2b/vpermt2w Vx,Hx,Wx"},
+		0x1f: {"", "vpabsq Vx,Wx"},
+	},
+	3: {
+		0x25: {"", "vpternlogd/vpternlogq Vx,Hx,Wx,Ib"},
+		0x3e: {"", "vpcmpub/vpcmpuw KV,Hx,Wx,Ib"},
+		0x3f: {"", "vpcmpb/vpcmpw KV,Hx,Wx,Ib"},
+		0x1e: {"", "vpcmpud/vpcmpuq KV,Hx,Wx,Ib"},
+		0x1f: {"", "vpcmpd/vpcmpq KV,Hx,Wx,Ib"},
+		0x00: {"", "vpermq Vx,Wx,Ib"},
+		0x38: {"", "vinserti32x4/vinserti64x2 Vx,Hx,Wdq,Ib"},
+		0x39: {"", "vextracti32x4/vextracti64x2 Wdq,Vx,Ib"},
+		0x3a: {"", "vinserti32x8/vinserti64x4 Vx,Hx,Wqq,Ib"},
+		0x3b: {"", "vextracti32x8/vextracti64x4 Wqq,Vx,Ib"},
+		0x18: {"", "vinsertf32x4/vinsertf64x2 Vx,Hx,Wdq,Ib"},
+		0x19: {"", "vextractf32x4/vextractf64x2 Wdq,Vx,Ib"},
+	},
+}
@@ -0,0 +1,61 @@
+package file
+
+import (
+	"debug/elf"
+	"errors"
+)
+
+// DynString returns the strings listed for the given tag in the file's
+// dynamic section. The tag must be one whose value is a string table
+// offset: DT_NEEDED, DT_SONAME, DT_RPATH or DT_RUNPATH.
+func (f *File) DynString(tag elf.DynTag) ([]string, error) {
+	switch tag {
+	case elf.DT_NEEDED, elf.DT_SONAME, elf.DT_RPATH, elf.DT_RUNPATH:
+	default:
+		return nil, errors.New("non-string-valued tag")
+	}
+	ds := f.SectionByType(elf.SHT_DYNAMIC)
+	if ds == nil {
+		// not dynamic, so no libraries
+		return nil, nil
+	}
+	d := ds.Data()
+	if int(ds.Link) >= len(f.Sections) {
+		return nil, errors.New("bad dynamic string table link")
+	}
+	str := f.Sections[ds.Link].Data()
+
+	var all []string
+	for len(d) > 0 {
+		var t elf.DynTag
+		var v uint64
+		switch f.Class {
+		case elf.ELFCLASS32:
+			if len(d) < 8 {
+				return all, nil
+			}
+			t = elf.DynTag(f.ByteOrder.Uint32(d[0:4]))
+			v = uint64(f.ByteOrder.Uint32(d[4:8]))
+			d = d[8:]
+		case elf.ELFCLASS64:
+			if len(d) < 16 {
+				return all, nil
+			}
+			t = elf.DynTag(f.ByteOrder.Uint64(d[0:8]))
+			v = f.ByteOrder.Uint64(d[8:16])
+			d = d[16:]
+		default:
+			return all, nil
+		}
+		if t == elf.DT_NULL {
+			break
+		}
+		if t == tag {
+			s, ok := getString(str, int(v))
+			if ok {
+				all = append(all, s)
+			}
+		}
+	}
+	return all, nil
+}
@@ -4,6 +4,7 @@ import (
 	"compress/zlib"
 	"debug/elf"
 	"encoding/binary"
+	"errors"
 	"io"
 	"os"
 )
@@ -163,6 +164,10 @@ func Open(name string) (*File, error) {
 		return nil, err
 	}
 	ff := NewFile(f)
+	if ff == nil {
+		f.Close()
+		return nil, errors.New("not a valid ELF file")
+	}
 
 	ff.closer = f
 	return ff, nil
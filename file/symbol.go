@@ -0,0 +1,211 @@
+package file
+
+import (
+	"debug/elf"
+	"errors"
+)
+
+// ErrNoSymbols is returned by Symbols and DynamicSymbols
+// if there is no such section in the File.
+var ErrNoSymbols = errors.New("no symbol section")
+
+// Section returns a section with the given name, or nil if no such
+// section exists.
+func (f *File) Section(name string) *Section {
+	for _, s := range f.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// SectionByType returns the first section in f with the
+// given type, or nil if there is no such section.
+func (f *File) SectionByType(typ elf.SectionType) *Section {
+	for _, s := range f.Sections {
+		if s.Type == typ {
+			return s
+		}
+	}
+	return nil
+}
+
+// Symbols returns the symbol table for f. The symbols will be listed in the order
+// they appear in f.
+//
+// Like debug/elf, Symbols omits the null symbol at index 0.
+// After retrieving the symbols as symtab, an externally supplied index x
+// corresponds to symtab[x-1], not symtab[x].
+func (f *File) Symbols() ([]Symbol, error) {
+	return f.getSymbols(elf.SHT_SYMTAB)
+}
+
+// DynamicSymbols returns the dynamic symbol table for f. The symbols
+// will be listed in the order they appear in f.
+//
+// Version is set from the GNU symbol versioning sections for symbols
+// with a version, and Library for undefined ones needed from a
+// particular library.
+func (f *File) DynamicSymbols() ([]Symbol, error) {
+	syms, err := f.getSymbols(elf.SHT_DYNSYM)
+	if err != nil {
+		return nil, err
+	}
+	f.gnuVersions(syms)
+	return syms, nil
+}
+
+// gnuVersion is a version defined in .gnu.version_d or needed in
+// .gnu.version_r.
+type gnuVersion struct {
+	Name, Library string
+}
+
+// gnuVersions sets the versions of the dynamic symbols syms from
+// .gnu.version, which holds a version index per symbol.
+func (f *File) gnuVersions(syms []Symbol) {
+	vs := f.SectionByType(elf.SHT_GNU_VERSYM)
+	if vs == nil {
+		return
+	}
+	versym := vs.Data()
+	versions := make(map[uint16]gnuVersion)
+	f.gnuVerdef(versions)
+	f.gnuVerneed(versions)
+	for i := range syms {
+		// versym has an entry for the null symbol omitted from syms
+		off := 2 * (i + 1)
+		if off+2 > len(versym) {
+			break
+		}
+		// the top bit marks a hidden version
+		ndx := f.ByteOrder.Uint16(versym[off:]) & 0x7fff
+		if v, ok := versions[ndx]; ok {
+			syms[i].Version, syms[i].Library = v.Name, v.Library
+		}
+	}
+}
+
+// linkedData returns the contents of the section s links to.
+func (f *File) linkedData(s *Section) []byte {
+	if int(s.Link) >= len(f.Sections) {
+		return nil
+	}
+	return f.Sections[s.Link].Data()
+}
+
+// gnuVerdef adds the versions defined in .gnu.version_d to versions.
+// Indexes 0 and 1 mean local and global symbols, and the definition of
+// index 1 names the file itself, so only later ones are kept.
+func (f *File) gnuVerdef(versions map[uint16]gnuVersion) {
+	s := f.SectionByType(elf.SHT_GNU_VERDEF)
+	if s == nil {
+		return
+	}
+	d, str := s.Data(), f.linkedData(s)
+	for off := 0; off+20 <= len(d); {
+		ndx := f.ByteOrder.Uint16(d[off+4:])
+		cnt := f.ByteOrder.Uint16(d[off+6:])
+		aux := off + int(f.ByteOrder.Uint32(d[off+12:]))
+		if ndx > 1 && cnt > 0 && aux >= 0 && aux+8 <= len(d) {
+			name, _ := getString(str, int(f.ByteOrder.Uint32(d[aux:])))
+			versions[ndx] = gnuVersion{Name: name}
+		}
+		next := int(f.ByteOrder.Uint32(d[off+16:]))
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+}
+
+// gnuVerneed adds the versions needed in .gnu.version_r to versions,
+// with the libraries that provide them.
+func (f *File) gnuVerneed(versions map[uint16]gnuVersion) {
+	s := f.SectionByType(elf.SHT_GNU_VERNEED)
+	if s == nil {
+		return
+	}
+	d, str := s.Data(), f.linkedData(s)
+	for off := 0; off+16 <= len(d); {
+		cnt := int(f.ByteOrder.Uint16(d[off+2:]))
+		lib, _ := getString(str, int(f.ByteOrder.Uint32(d[off+4:])))
+		aux := off + int(f.ByteOrder.Uint32(d[off+8:]))
+		for i := 0; i < cnt && aux >= 0 && aux+16 <= len(d); i++ {
+			ndx := f.ByteOrder.Uint16(d[aux+6:])
+			name, _ := getString(str, int(f.ByteOrder.Uint32(d[aux+8:])))
+			versions[ndx] = gnuVersion{Name: name, Library: lib}
+			next := int(f.ByteOrder.Uint32(d[aux+12:]))
+			if next == 0 {
+				break
+			}
+			aux += next
+		}
+		next := int(f.ByteOrder.Uint32(d[off+12:]))
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+}
+
+func (f *File) getSymbols(typ elf.SectionType) ([]Symbol, error) {
+	symtabSection := f.SectionByType(typ)
+	if symtabSection == nil {
+		return nil, ErrNoSymbols
+	}
+
+	var symSize int
+	switch f.Class {
+	case elf.ELFCLASS32:
+		symSize = elf.Sym32Size
+	case elf.ELFCLASS64:
+		symSize = elf.Sym64Size
+	default:
+		return nil, errors.New("not implemented")
+	}
+
+	data := symtabSection.Data()
+	if len(data) == 0 {
+		return nil, ErrNoSymbols
+	}
+	if len(data)%symSize != 0 {
+		return nil, errors.New("length of symbol section is not a multiple of SymSize")
+	}
+
+	link := symtabSection.Link
+	if link <= 0 || link >= uint32(len(f.Sections)) {
+		return nil, errors.New("section has invalid string table link")
+	}
+	strdata := f.Sections[link].Data()
+
+	// The first entry is all zeros.
+	data = data[symSize:]
+
+	symbols := make([]Symbol, len(data)/symSize)
+	for i := range symbols {
+		var name uint32
+		s := &symbols[i]
+		switch f.Class {
+		case elf.ELFCLASS32:
+			name = f.ByteOrder.Uint32(data[0:4])
+			s.Value = uint64(f.ByteOrder.Uint32(data[4:8]))
+			s.Size = uint64(f.ByteOrder.Uint32(data[8:12]))
+			s.Info = data[12]
+			s.Other = data[13]
+			s.Section = elf.SectionIndex(f.ByteOrder.Uint16(data[14:16]))
+		case elf.ELFCLASS64:
+			name = f.ByteOrder.Uint32(data[0:4])
+			s.Info = data[4]
+			s.Other = data[5]
+			s.Section = elf.SectionIndex(f.ByteOrder.Uint16(data[6:8]))
+			s.Value = f.ByteOrder.Uint64(data[8:16])
+			s.Size = f.ByteOrder.Uint64(data[16:24])
+		}
+		s.Name, _ = getString(strdata, int(name))
+		data = data[symSize:]
+	}
+
+	return symbols, nil
+}
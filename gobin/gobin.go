@@ -0,0 +1,253 @@
+// Package gobin decodes the metadata the Go toolchain leaves in the
+// ELF files it links: the build information of .go.buildinfo, the Go
+// build ID note and the function and line tables of .gopclntab. All of
+// them survive stripping, so they describe stripped binaries too.
+package gobin
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/gosym"
+	"elfreader/file"
+	"encoding/binary"
+	"errors"
+	"runtime/debug"
+)
+
+// ErrNoPCLNTab is returned for files without a Go pclntab.
+var ErrNoPCLNTab = errors.New("no Go pclntab")
+
+const ntGoBuildID = 4
+
+// IsGo tells whether f was linked by the Go toolchain.
+func IsGo(f *file.File) bool {
+	if f.Section(".go.buildinfo") != nil || f.Section(".gopclntab") != nil {
+		return true
+	}
+	return BuildID(f) != ""
+}
+
+// BuildID returns the Go build ID of f, recorded in a "Go" note, or ""
+// if it has none. It is distinct from the GNU build-id.
+func BuildID(f *file.File) string {
+	var data [][]byte
+	if s := f.Section(".note.go.buildid"); s != nil {
+		data = append(data, s.Data())
+	} else {
+		for _, p := range f.Progs {
+			if p.Type != elf.PT_NOTE {
+				continue
+			}
+			b := make([]byte, p.Filesz)
+			if _, err := p.ReadAt(b, 0); err == nil {
+				data = append(data, b)
+			}
+		}
+	}
+	for _, d := range data {
+		for len(d) >= 12 {
+			namesz := f.ByteOrder.Uint32(d[0:4])
+			descsz := f.ByteOrder.Uint32(d[4:8])
+			typ := f.ByteOrder.Uint32(d[8:12])
+			d = d[12:]
+			nlen, dlen := (namesz+3)&^3, (descsz+3)&^3
+			if uint64(nlen)+uint64(descsz) > uint64(len(d)) {
+				break
+			}
+			name := bytes.TrimRight(d[:namesz], "\x00")
+			desc := d[nlen : nlen+descsz]
+			if string(name) == "Go" && typ == ntGoBuildID {
+				return string(desc)
+			}
+			if uint64(nlen)+uint64(dlen) > uint64(len(d)) {
+				break
+			}
+			d = d[nlen+dlen:]
+		}
+	}
+	return ""
+}
+
+// BuildInfo returns the build information of the Go binary f, opened
+// from path: Go version, main module, dependencies and build settings.
+// Files without section headers are searched for the build information
+// header in their writable segments.
+func BuildInfo(f *file.File, path string) (*debug.BuildInfo, error) {
+	bi, err := buildinfo.ReadFile(path)
+	if err == nil || len(f.Sections) > 0 {
+		return bi, err
+	}
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_LOAD || p.Flags&elf.PF_W == 0 {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil {
+			continue
+		}
+		// the header is 16-byte aligned
+		for off := 0; off+32 <= len(data); off += 16 {
+			if bytes.HasPrefix(data[off:], buildInfoMagic) {
+				return parseBuildInfo(data[off:])
+			}
+		}
+	}
+	return nil, err
+}
+
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// parseBuildInfo decodes the build information header of Go 1.18 and
+// later, which holds the version and module strings inline.
+func parseBuildInfo(data []byte) (*debug.BuildInfo, error) {
+	if data[15]&2 == 0 {
+		return nil, errors.New("Go build info of a release before 1.18 needs section headers")
+	}
+	data = data[32:]
+	str := func() string {
+		n, k := binary.Uvarint(data)
+		if k <= 0 || n > uint64(len(data)-k) {
+			data = nil
+			return ""
+		}
+		s := string(data[k : k+int(n)])
+		data = data[k+int(n):]
+		return s
+	}
+	vers, mod := str(), str()
+	if vers == "" {
+		return nil, errors.New("bad Go build info")
+	}
+	// the module information is framed by 16-byte sentinels
+	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
+		mod = mod[16 : len(mod)-16]
+	} else {
+		mod = ""
+	}
+	bi, err := debug.ParseBuildInfo(mod)
+	if err != nil {
+		return nil, err
+	}
+	bi.GoVersion = vers
+	return bi, nil
+}
+
+// pclntab magic numbers, by the release that introduced the format
+var pclnMagics = map[uint32]string{
+	0xfffffffb: "go1.2",
+	0xfffffffa: "go1.16",
+	0xfffffff0: "go1.18",
+	0xfffffff1: "go1.20",
+}
+
+// A PCLNTab is the decoded function and line table of a Go binary.
+type PCLNTab struct {
+	*gosym.Table
+	// Addr is the address of the table
+	Addr uint64
+	// Format is the release that introduced the table format, such as
+	// "go1.20"
+	Format string
+	// TextStart is the address function entries are relative to
+	TextStart uint64
+}
+
+// ReadPCLNTab decodes the pclntab of f, from .gopclntab or, when the
+// section headers are gone, by searching the read-only segments for
+// the table header.
+func ReadPCLNTab(f *file.File) (*PCLNTab, error) {
+	var data []byte
+	var addr uint64
+	if s := f.Section(".gopclntab"); s != nil && s.Type != elf.SHT_NOBITS {
+		data, addr = s.Data(), s.Addr
+	} else {
+		data, addr = findPCLNTab(f)
+	}
+	if len(data) < 16 {
+		return nil, ErrNoPCLNTab
+	}
+	magic := f.ByteOrder.Uint32(data)
+	format, ok := pclnMagics[magic]
+	if !ok {
+		return nil, ErrNoPCLNTab
+	}
+	ptrSize := int(data[7])
+
+	// runtime.text, which 1.18 and later tables count entries from,
+	// starts .text; the header records it too, unrelocated
+	var text uint64
+	if s := f.Section(".text"); s != nil {
+		text = s.Addr
+	} else if magic == 0xfffffff0 || magic == 0xfffffff1 {
+		text = readPtr(f.ByteOrder, data[8+2*ptrSize:], ptrSize)
+	}
+	t, err := gosym.NewTable(nil, gosym.NewLineTable(data, text))
+	if err != nil {
+		return nil, err
+	}
+	return &PCLNTab{Table: t, Addr: addr, Format: format, TextStart: text}, nil
+}
+
+func readPtr(order binary.ByteOrder, b []byte, size int) uint64 {
+	if size == 4 {
+		return uint64(order.Uint32(b))
+	}
+	return order.Uint64(b)
+}
+
+// findPCLNTab searches the non-executable PT_LOAD segments of f for a
+// pclntab header.
+func findPCLNTab(f *file.File) ([]byte, uint64) {
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_LOAD || p.Flags&elf.PF_X != 0 || p.Flags&elf.PF_W != 0 {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil {
+			continue
+		}
+		for off := 0; off+16 <= len(data); off += 4 {
+			magic := f.ByteOrder.Uint32(data[off:])
+			if _, ok := pclnMagics[magic]; !ok {
+				continue
+			}
+			h := data[off:]
+			quantum, ptrSize := h[6], h[7]
+			if h[4] != 0 || h[5] != 0 || quantum != 1 && quantum != 2 && quantum != 4 || ptrSize != 4 && ptrSize != 8 {
+				continue
+			}
+			if nfunc := readPtr(f.ByteOrder, h[8:], int(ptrSize)); nfunc == 0 || nfunc > uint64(len(h)) {
+				continue
+			}
+			return h, p.Vaddr + uint64(off)
+		}
+	}
+	return nil, 0
+}
+
+// A Line is a row of the line table of a function: the instructions
+// from PC up to the PC of the next row come from File:Line.
+type Line struct {
+	PC   uint64
+	File string
+	Line int
+}
+
+// Lines returns the line table of fn.
+func (t *PCLNTab) Lines(fn *gosym.Func) []Line {
+	var lines []Line
+	// the table has no iterator: probe every address, which the
+	// quantum of 1 on most architectures makes exact
+	for pc := fn.Entry; pc < fn.End; pc++ {
+		file, line, f := t.PCToLine(pc)
+		if f == nil || f.Entry != fn.Entry || file == "" {
+			continue
+		}
+		if n := len(lines); n > 0 && lines[n-1].File == file && lines[n-1].Line == line {
+			continue
+		}
+		lines = append(lines, Line{pc, file, line})
+	}
+	return lines
+}
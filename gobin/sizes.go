@@ -0,0 +1,85 @@
+package gobin
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"elfreader/file"
+	"sort"
+	"strings"
+)
+
+// A PackageSize is the share of a binary attributed to a Go package.
+type PackageSize struct {
+	// Package is the import path, or "" for symbols of no package,
+	// such as type descriptors and string data
+	Package string
+	Funcs   int
+	Text    uint64
+	// Data and BSS count the sized data symbols of .symtab, which
+	// stripped binaries lack, in initialized and zero-filled sections
+	Data uint64
+	BSS  uint64
+}
+
+// PackageSizes attributes the functions of t, and the data symbols of
+// f if it has any, to their packages, largest first.
+func PackageSizes(t *PCLNTab, f *file.File) []PackageSize {
+	sizes := make(map[string]*PackageSize)
+	get := func(pkg string) *PackageSize {
+		s, ok := sizes[pkg]
+		if !ok {
+			s = &PackageSize{Package: pkg}
+			sizes[pkg] = s
+		}
+		return s
+	}
+	for i := range t.Funcs {
+		fn := &t.Funcs[i]
+		s := get(fn.PackageName())
+		s.Funcs++
+		s.Text += fn.End - fn.Entry
+	}
+	syms, _ := f.Symbols()
+	for _, sym := range syms {
+		if elf.ST_TYPE(sym.Info) != elf.STT_OBJECT || sym.Size == 0 || sym.Section == elf.SHN_UNDEF || int(sym.Section) >= len(f.Sections) {
+			continue
+		}
+		s := get(symPackage(sym.Name))
+		if f.Sections[sym.Section].Type == elf.SHT_NOBITS {
+			s.BSS += sym.Size
+		} else {
+			s.Data += sym.Size
+		}
+	}
+
+	out := make([]PackageSize, 0, len(sizes))
+	for _, s := range sizes {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if a, b := out[i].Text+out[i].Data, out[j].Text+out[j].Data; a != b {
+			return a > b
+		}
+		return out[i].Package < out[j].Package
+	})
+	return out
+}
+
+// compilerPrefixes start the names of compiler generated symbols, in
+// the spelling of Go 1.20 and later, then in the older one.
+var compilerPrefixes = []string{
+	"go:", "type:",
+	"type.", "go.itab.", "go.string.", "go.func.", "go.shape.", "go.map.", "go.importpath.", "go.buildinfo",
+}
+
+// symPackage returns the package of the .symtab symbol name, or "" for
+// compiler generated symbols.
+func symPackage(name string) string {
+	for _, p := range compilerPrefixes {
+		if strings.HasPrefix(name, p) {
+			return ""
+		}
+	}
+	s := gosym.Sym{Name: name}
+	return s.PackageName()
+}
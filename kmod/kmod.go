@@ -0,0 +1,110 @@
+// Package kmod decodes Linux kernel modules: the key=value pairs of
+// .modinfo, the symbol CRCs of __versions, the name in the struct module
+// of .gnu.linkonce.this_module and the signature appended to signed
+// modules.
+package kmod
+
+import (
+	"bytes"
+	"debug/elf"
+	"elfreader/file"
+	"errors"
+	"strings"
+)
+
+// ErrNotModule is returned for files with neither .modinfo nor
+// .gnu.linkonce.this_module.
+var ErrNotModule = errors.New("not a kernel module")
+
+// A Field is a key=value pair of .modinfo.
+type Field struct {
+	Key, Value string
+}
+
+// A Version is an entry of __versions, the CRC of the prototype of a
+// symbol the module imports, checked by a kernel built with
+// CONFIG_MODVERSIONS.
+type Version struct {
+	CRC  uint64
+	Name string
+}
+
+// A Module is a decoded kernel module.
+type Module struct {
+	// Name is the name of struct module, "" without
+	// .gnu.linkonce.this_module
+	Name     string
+	Info     []Field
+	Versions []Version
+}
+
+// Get returns the values of the .modinfo key, in order.
+func (m *Module) Get(key string) []string {
+	var vals []string
+	for _, f := range m.Info {
+		if f.Key == key {
+			vals = append(vals, f.Value)
+		}
+	}
+	return vals
+}
+
+// Read decodes the module f.
+func Read(f *file.File) (*Module, error) {
+	info := f.Section(".modinfo")
+	this := f.Section(".gnu.linkonce.this_module")
+	if info == nil && this == nil {
+		return nil, ErrNotModule
+	}
+	m := &Module{}
+	if info != nil && info.Type != elf.SHT_NOBITS {
+		for _, s := range bytes.Split(info.Data(), []byte{0}) {
+			if len(s) == 0 {
+				// strings are aligned with NULs
+				continue
+			}
+			k, v := string(s), ""
+			if i := strings.IndexByte(k, '='); i >= 0 {
+				k, v = k[:i], k[i+1:]
+			}
+			m.Info = append(m.Info, Field{k, v})
+		}
+	}
+
+	w := 8
+	if f.Class == elf.ELFCLASS32 {
+		w = 4
+	}
+	// struct modversion_info is a long CRC and the name, 64 bytes in all
+	if s := f.Section("__versions"); s != nil && s.Type != elf.SHT_NOBITS {
+		b := s.Data()
+		for ; len(b) >= 64; b = b[64:] {
+			crc := uint64(f.ByteOrder.Uint32(b))
+			if w == 8 {
+				crc = f.ByteOrder.Uint64(b)
+			}
+			m.Versions = append(m.Versions, Version{crc, cstring(b[w:64])})
+		}
+	}
+
+	// struct module starts with the enum state and a list_head, then
+	// the name
+	if this != nil && this.Type != elf.SHT_NOBITS {
+		b := this.Data()
+		if off := 3 * w; len(b) > off {
+			end := off + 64 - w
+			if end > len(b) {
+				end = len(b)
+			}
+			m.Name = cstring(b[off:end])
+		}
+	}
+	return m, nil
+}
+
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
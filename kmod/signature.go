@@ -0,0 +1,214 @@
+package kmod
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// sigMagic ends the files of signed modules.
+const sigMagic = "~Module signature appended~\n"
+
+// sigInfoLen is the size of struct module_signature.
+const sigInfoLen = 12
+
+// A Signer is a SignerInfo of the PKCS#7 signature of a module.
+type Signer struct {
+	// Issuer and Serial identify the certificate of the signing key,
+	// or SubjectKeyID does
+	Issuer       string
+	Serial       *big.Int
+	SubjectKeyID []byte
+	Hash         string
+	Algorithm    string
+	// Size is the size of the signature, in bytes
+	Size int
+}
+
+// A Signature is the signature appended to a module.
+type Signature struct {
+	// Offset and Size locate the signature data in the file
+	Offset, Size int64
+	// IDType is the kind of signature, PKCS#7 for all current kernels
+	IDType string
+	// Algo and Hash are set in legacy signatures only
+	Algo, Hash string
+	Signers    []Signer
+}
+
+var (
+	idTypes   = []string{"PGP", "X.509", "PKCS#7"}
+	pkeyAlgos = []string{"DSA", "RSA"}
+	hashAlgos = []string{"md4", "md5", "sha1", "rmd160", "sha256", "sha384", "sha512", "sha224", "sm3", "streebog256", "streebog512", "sha3-256", "sha3-384", "sha3-512"}
+)
+
+func name(names []string, v byte) string {
+	if int(v) < len(names) {
+		return names[v]
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+// ReadSignature reads the signature at the end of the module r of size
+// bytes. It returns nil if the module is not signed.
+func ReadSignature(r io.ReaderAt, size int64) (*Signature, error) {
+	tail := int64(len(sigMagic) + sigInfoLen)
+	if size < tail {
+		return nil, nil
+	}
+	b := make([]byte, tail)
+	if _, err := r.ReadAt(b, size-tail); err != nil {
+		return nil, err
+	}
+	if string(b[sigInfoLen:]) != sigMagic {
+		return nil, nil
+	}
+	// struct module_signature: algo, hash, id_type, signer_len,
+	// key_id_len, 3 bytes of padding and the big-endian sig_len
+	sigLen := int64(binary.BigEndian.Uint32(b[8:]))
+	extra := int64(b[3]) + int64(b[4])
+	s := &Signature{Size: sigLen, IDType: name(idTypes, b[2])}
+	s.Offset = size - tail - sigLen
+	if s.Offset-extra < 0 {
+		return nil, fmt.Errorf("signature of %d bytes does not fit in the file", sigLen)
+	}
+	if b[2] != 2 {
+		s.Algo, s.Hash = name(pkeyAlgos, b[0]), name(hashAlgos, b[1])
+		return s, nil
+	}
+	data := make([]byte, sigLen)
+	if _, err := r.ReadAt(data, s.Offset); err != nil {
+		return nil, err
+	}
+	signers, err := pkcs7Signers(data)
+	if err != nil {
+		return s, fmt.Errorf("PKCS#7 signature: %v", err)
+	}
+	s.Signers = signers
+	return s, nil
+}
+
+// The parts of PKCS#7 (RFC 2315) and CMS (RFC 5652) SignedData naming
+// the signers.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version int
+	// IssuerAndSerialNumber, or a [0] SubjectKeyIdentifier
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+var algorithmNames = map[string]string{
+	"1.3.14.3.2.26":           "sha1",
+	"2.16.840.1.101.3.4.2.1":  "sha256",
+	"2.16.840.1.101.3.4.2.2":  "sha384",
+	"2.16.840.1.101.3.4.2.3":  "sha512",
+	"2.16.840.1.101.3.4.2.4":  "sha224",
+	"2.16.840.1.101.3.4.2.8":  "sha3-256",
+	"2.16.840.1.101.3.4.2.9":  "sha3-384",
+	"2.16.840.1.101.3.4.2.10": "sha3-512",
+	"1.2.156.10197.1.401":     "sm3",
+	"1.2.840.113549.1.1.1":    "rsaEncryption",
+	"1.2.840.113549.1.1.5":    "sha1WithRSAEncryption",
+	"1.2.840.113549.1.1.11":   "sha256WithRSAEncryption",
+	"1.2.840.113549.1.1.12":   "sha384WithRSAEncryption",
+	"1.2.840.113549.1.1.13":   "sha512WithRSAEncryption",
+	"1.2.840.113549.1.1.14":   "sha224WithRSAEncryption",
+	"1.2.840.10045.2.1":       "ecPublicKey",
+	"1.2.840.10045.4.3.2":     "ecdsa-with-SHA256",
+	"1.2.840.10045.4.3.3":     "ecdsa-with-SHA384",
+	"1.2.840.10045.4.3.4":     "ecdsa-with-SHA512",
+	"1.3.101.112":             "Ed25519",
+	"1.2.156.10197.1.501":     "SM2-with-SM3",
+}
+
+func algorithmName(oid asn1.ObjectIdentifier) string {
+	if s, ok := algorithmNames[oid.String()]; ok {
+		return s
+	}
+	return oid.String()
+}
+
+// pkcs7Signers returns the signers of the DER PKCS#7 SignedData b.
+func pkcs7Signers(b []byte) ([]Signer, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(b, &ci); err != nil {
+		return nil, err
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("content type %v is not signedData", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+	var signers []Signer
+	for _, si := range sd.SignerInfos {
+		s := Signer{
+			Hash:      algorithmName(si.DigestAlgorithm.Algorithm),
+			Algorithm: algorithmName(si.SignatureAlgorithm.Algorithm),
+			Size:      len(si.Signature),
+		}
+		if si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0 {
+			s.SubjectKeyID = si.SID.Bytes
+		} else {
+			var is issuerAndSerial
+			if _, err := asn1.Unmarshal(si.SID.FullBytes, &is); err != nil {
+				return nil, fmt.Errorf("signer identifier: %v", err)
+			}
+			var rdn pkix.RDNSequence
+			if _, err := asn1.Unmarshal(is.Issuer.FullBytes, &rdn); err != nil {
+				return nil, fmt.Errorf("issuer: %v", err)
+			}
+			var issuer pkix.Name
+			issuer.FillFromRDNSequence(&rdn)
+			s.Issuer, s.Serial = issuer.String(), is.Serial
+		}
+		signers = append(signers, s)
+	}
+	return signers, nil
+}
+
+// SerialString returns the serial number of s in the colon-separated
+// hex of openssl and modinfo.
+func (s Signer) SerialString() string {
+	if s.Serial == nil {
+		return ""
+	}
+	h := hex.EncodeToString(s.Serial.Bytes())
+	var out []byte
+	for i := 0; i < len(h); i += 2 {
+		if i > 0 {
+			out = append(out, ':')
+		}
+		out = append(out, h[i:i+2]...)
+	}
+	return string(out)
+}
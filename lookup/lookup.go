@@ -0,0 +1,248 @@
+// Package lookup maps addresses in an ELF file to the segment, section
+// and symbol containing them and translates between virtual addresses
+// and file offsets.
+//
+// Addresses passed to an Index are run-time addresses: the load base
+// given to New is subtracted from them before they are compared with
+// the link-time addresses recorded in the file. For executables that
+// are not position independent the base is normally 0.
+package lookup
+
+import (
+	"debug/elf"
+	"elfreader/file"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrNotMapped is returned when an address or file offset is not
+// covered by any PT_LOAD segment.
+var ErrNotMapped = errors.New("address not mapped by any segment")
+
+// An Index answers address queries for one ELF file.
+type Index struct {
+	f    *file.File
+	base uint64
+
+	// syms holds the defined code and data symbols sorted by address
+	syms []file.Symbol
+	// maxSize is the largest symbol size, bounding how far back a
+	// containing symbol can start
+	maxSize uint64
+}
+
+// New builds an index over f for a file loaded at base. Symbols are
+// taken from .symtab, or from .dynsym when the file is stripped.
+func New(f *file.File, base uint64) *Index {
+	x := &Index{f: f, base: base}
+	syms, err := f.Symbols()
+	if err != nil || len(syms) == 0 {
+		syms, _ = f.DynamicSymbols()
+	}
+	x.AddSymbols(syms)
+	return x
+}
+
+// AddSymbols adds syms to the index, skipping those it already has.
+// It is used to merge the symbols of a separate debug file, whose
+// section indices are those of the stripped file.
+func (x *Index) AddSymbols(syms []file.Symbol) {
+	type key struct {
+		name  string
+		value uint64
+	}
+	have := make(map[key]bool, len(x.syms))
+	for _, s := range x.syms {
+		have[key{s.Name, s.Value}] = true
+	}
+	for _, s := range syms {
+		if s.Section == elf.SHN_UNDEF || s.Section >= elf.SHN_LORESERVE && s.Section != elf.SHN_ABS {
+			continue
+		}
+		switch elf.ST_TYPE(s.Info) {
+		case elf.STT_FUNC, elf.STT_OBJECT, elf.STT_NOTYPE, elf.STT_GNU_IFUNC:
+		default:
+			continue
+		}
+		if s.Name == "" || have[key{s.Name, s.Value}] {
+			continue
+		}
+		have[key{s.Name, s.Value}] = true
+		x.syms = append(x.syms, s)
+		if s.Size > x.maxSize {
+			x.maxSize = s.Size
+		}
+	}
+	sort.SliceStable(x.syms, func(i, j int) bool {
+		if x.syms[i].Value != x.syms[j].Value {
+			return x.syms[i].Value < x.syms[j].Value
+		}
+		// prefer sized and global symbols among aliases
+		if (x.syms[i].Size == 0) != (x.syms[j].Size == 0) {
+			return x.syms[i].Size != 0
+		}
+		return elf.ST_BIND(x.syms[i].Info) > elf.ST_BIND(x.syms[j].Info)
+	})
+}
+
+// Base returns the load base of the index.
+func (x *Index) Base() uint64 { return x.base }
+
+// A Result describes what contains an address.
+type Result struct {
+	// Addr is the link-time virtual address
+	Addr uint64
+
+	// Prog is the PT_LOAD segment containing Addr, or nil
+	Prog *file.Prog
+	// Offset is the file offset of Addr; valid if HasOffset
+	Offset    uint64
+	HasOffset bool
+
+	// Section is the allocated section containing Addr, or nil
+	Section *file.Section
+
+	// Symbol is the symbol containing Addr or, when Exact is false,
+	// the nearest symbol before it in the same section; nil if none
+	Symbol *file.Symbol
+	Exact  bool
+}
+
+// SymbolOffset returns the distance of the address from the start of
+// its symbol.
+func (r Result) SymbolOffset() uint64 {
+	if r.Symbol == nil {
+		return 0
+	}
+	return r.Addr - r.Symbol.Value
+}
+
+// Lookup describes the run-time address addr.
+func (x *Index) Lookup(addr uint64) Result {
+	r := Result{Addr: addr - x.base}
+	r.Prog = x.loadProg(r.Addr)
+	if off, err := x.vaToOffset(r.Addr); err == nil {
+		r.Offset, r.HasOffset = off, true
+	}
+	r.Section = x.section(r.Addr)
+	r.Symbol, r.Exact = x.symbol(r.Addr)
+	return r
+}
+
+func (x *Index) loadProg(va uint64) *file.Prog {
+	for _, p := range x.f.Progs {
+		if p.Type == elf.PT_LOAD && va >= p.Vaddr && va-p.Vaddr < p.Memsz {
+			return p
+		}
+	}
+	return nil
+}
+
+func (x *Index) section(va uint64) *file.Section {
+	for _, s := range x.f.Sections {
+		if s.Flags&elf.SHF_ALLOC == 0 || s.Flags&elf.SHF_TLS != 0 && s.Type == elf.SHT_NOBITS {
+			continue
+		}
+		if va >= s.Addr && va-s.Addr < s.Size {
+			return s
+		}
+	}
+	return nil
+}
+
+// symbol finds the symbol containing va, falling back to the nearest
+// preceding symbol in the same section.
+func (x *Index) symbol(va uint64) (*file.Symbol, bool) {
+	// first symbol starting after va
+	i := sort.Search(len(x.syms), func(i int) bool { return x.syms[i].Value > va })
+	for j := i - 1; j >= 0; j-- {
+		s := &x.syms[j]
+		if va-s.Value >= x.maxSize {
+			break
+		}
+		if va-s.Value < s.Size {
+			return s, true
+		}
+	}
+	sec := x.section(va)
+	if sec == nil {
+		return nil, false
+	}
+	// symbols of other sections, such as absolute ones, and zero-size
+	// markers like __bss_start may sit between va and the symbol of
+	// the code or data before it
+	for j := i - 1; j >= 0 && x.syms[j].Value >= sec.Addr; j-- {
+		s := &x.syms[j]
+		if int(s.Section) >= len(x.f.Sections) || x.f.Sections[s.Section] != sec {
+			continue
+		}
+		if s.Size == 0 && elf.ST_TYPE(s.Info) == elf.STT_NOTYPE {
+			continue
+		}
+		return s, false
+	}
+	return nil, false
+}
+
+// Symbol returns the symbol named name, if it is in the index.
+func (x *Index) Symbol(name string) (file.Symbol, bool) {
+	for _, s := range x.syms {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return file.Symbol{}, false
+}
+
+// VAToOffset translates the run-time address addr to a file offset.
+func (x *Index) VAToOffset(addr uint64) (uint64, error) {
+	return x.vaToOffset(addr - x.base)
+}
+
+func (x *Index) vaToOffset(va uint64) (uint64, error) {
+	p := x.loadProg(va)
+	if p == nil {
+		return 0, ErrNotMapped
+	}
+	if va-p.Vaddr >= p.Filesz {
+		return 0, fmt.Errorf("address 0x%x is in the zero-filled part of a segment", va)
+	}
+	return p.Off + va - p.Vaddr, nil
+}
+
+// OffsetToVA translates a file offset to a run-time address.
+func (x *Index) OffsetToVA(off uint64) (uint64, error) {
+	for _, p := range x.f.Progs {
+		if p.Type == elf.PT_LOAD && off >= p.Off && off-p.Off < p.Filesz {
+			return p.Vaddr + off - p.Off + x.base, nil
+		}
+	}
+	return 0, ErrNotMapped
+}
+
+// Read returns n bytes at the run-time address addr. Bytes in the
+// zero-filled part of a segment read as zero.
+func (x *Index) Read(addr uint64, n int) ([]byte, error) {
+	va := addr - x.base
+	p := x.loadProg(va)
+	if p == nil {
+		return nil, ErrNotMapped
+	}
+	if uint64(n) > p.Memsz-(va-p.Vaddr) {
+		return nil, fmt.Errorf("%d bytes at 0x%x cross the end of the segment", n, addr)
+	}
+	buf := make([]byte, n)
+	rel := va - p.Vaddr
+	if rel < p.Filesz {
+		m := uint64(n)
+		if rel+m > p.Filesz {
+			m = p.Filesz - rel
+		}
+		if _, err := p.ReadAt(buf[:m], int64(rel)); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"elfreader/debuginfo"
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func addr2lineCmd(args []string) {
+	fs := flag.NewFlagSet("addr2line", flag.ExitOnError)
+	base := fs.String("base", "0", "load base of a PIE or shared object")
+	functions := fs.Bool("f", false, "show function names")
+	inlines := fs.Bool("i", false, "show the inlined call chain")
+	pretty := fs.Bool("p", false, "print each location on one line")
+	demangle := fs.Bool("C", false, "demangle C++ and Rust function names")
+	reverse := fs.Bool("r", false, "map file:line arguments to addresses")
+	debugDir := debugDirFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		usage()
+	}
+	options.Demangle = options.Demangle || *demangle
+
+	loadBase, err := strconv.ParseUint(*base, 0, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	d, err := debuginfo.Load(f)
+	if err == debuginfo.ErrNoDWARF {
+		if df := openDebugFile(f, fs.Arg(0), *debugDir); df != nil {
+			defer df.Close()
+			d, err = debuginfo.Load(df)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	s, err := debuginfo.NewSymbolizer(d)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := options.Addr2lineOptions{Functions: *functions, Inlines: *inlines, Pretty: *pretty}
+	for _, arg := range fs.Args()[1:] {
+		if *reverse {
+			i := strings.LastIndexByte(arg, ':')
+			line, err := strconv.Atoi(arg[i+1:])
+			if i < 0 || err != nil {
+				fmt.Fprintf(os.Stderr, "error: bad location %q, want file:line\n", arg)
+				os.Exit(1)
+			}
+			options.LineAddrsInf(s, arg[:i], line, loadBase)
+			continue
+		}
+		// like addr2line, addresses are hexadecimal with or without 0x
+		pc, err := strconv.ParseUint(strings.TrimPrefix(arg, "0x"), 16, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		options.Addr2lineInf(s, pc-loadBase, opts)
+	}
+}
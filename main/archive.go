@@ -0,0 +1,41 @@
+package main
+
+import (
+	"elfreader/archive"
+	"elfreader/options"
+	"fmt"
+	"os"
+)
+
+// archiveMembers prints the option op for each member of the archive
+// a, opened from name, under a "File: name(member)" line.
+func archiveMembers(a *archive.Archive, op, name string) {
+	for i, m := range a.Members {
+		if i > 0 {
+			fmt.Println()
+		}
+		mname := fmt.Sprintf("%s(%s)", name, m.Name)
+		fmt.Printf("File: %s\n", mname)
+		r, err := m.Open()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			continue
+		}
+		if err := elfOption(op, mname, r); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		r.Close()
+	}
+}
+
+// archiveIndex handles --archive-index, printing which member defines
+// each symbol of the archive name.
+func archiveIndex(name string) {
+	a, err := archive.Open(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	defer a.Close()
+	options.ArchiveIndexInf(a, name)
+}
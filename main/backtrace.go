@@ -0,0 +1,93 @@
+package main
+
+import (
+	"elfreader/core"
+	"elfreader/debuginfo"
+	"elfreader/file"
+	"elfreader/lookup"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func backtraceCmd(args []string) {
+	fs := flag.NewFlagSet("backtrace", flag.ExitOnError)
+	root := fs.String("root", "", "directory the mapped files are looked up under, as a sysroot")
+	demangle := fs.Bool("C", false, "demangle C++ and Rust function names")
+	debugDir := debugDirFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	options.Demangle = options.Demangle || *demangle
+
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	c, err := core.New(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	mods := c.Modules(*root)
+	defer func() {
+		for _, m := range mods {
+			m.Close()
+		}
+	}()
+
+	traces := make([][]core.Frame, len(c.Threads))
+	syms := make(map[*core.Module]*options.ModuleSymbols)
+	seen := make(map[*core.Module]bool)
+	for i, t := range c.Threads {
+		traces[i] = c.Backtrace(t, mods)
+		for _, fr := range traces[i] {
+			m := fr.Module
+			if m == nil || seen[m] {
+				continue
+			}
+			seen[m] = true
+			if m.File == nil {
+				fmt.Fprintf(os.Stderr, "warning: %s: %v\n", m.Path, m.Err)
+				continue
+			}
+			s, closer := moduleSymbols(m, filepath.Join(*root, m.Path), *debugDir)
+			if closer != nil {
+				defer closer.Close()
+			}
+			syms[m] = s
+		}
+	}
+	options.BacktraceInf(c, traces, syms)
+}
+
+// moduleSymbols loads the symbols and line information of m, opened
+// from name, merging those of its separate debug file. The debug file
+// is returned for the caller to close.
+func moduleSymbols(m *core.Module, name, dirs string) (*options.ModuleSymbols, *file.File) {
+	s := &options.ModuleSymbols{Index: lookup.New(m.File, m.Bias)}
+	df := openDebugFile(m.File, name, dirs)
+	if df != nil {
+		if syms, err := df.Symbols(); err == nil {
+			s.Index.AddSymbols(syms)
+		}
+	} else {
+		s.Index.AddSymbols(miniDebugSymbols(m.File, name))
+	}
+	d, err := debuginfo.Load(m.File)
+	if err == debuginfo.ErrNoDWARF && df != nil {
+		d, err = debuginfo.Load(df)
+	}
+	if err == nil {
+		s.Lines, err = debuginfo.NewSymbolizer(d)
+	}
+	if err != nil && err != debuginfo.ErrNoDWARF {
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", name, err)
+	}
+	return s, df
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"debug/elf"
+	"elfreader/bpf"
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func bpfCmd(args []string) {
+	fs := flag.NewFlagSet("bpf", flag.ExitOnError)
+	dump := fs.Bool("btf", false, "dump the BTF types in the raw format of bpftool")
+	lines := fs.Bool("lines", false, "print the func info, line info and CO-RE relocations of .BTF.ext")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		usage()
+	}
+	name, types := fs.Arg(0), fs.Args()[1:]
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	// raw BTF, such as /sys/kernel/btf/vmlinux
+	if spec, err := bpf.ParseBTF(b); err != bpf.ErrNotBTF {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		btfOutput(spec, *dump, types)
+		return
+	}
+
+	f, err := file.Open(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if f.Machine != elf.EM_BPF {
+		// a vmlinux image or any other ELF file with BTF
+		spec, err := bpf.LoadBTF(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		btfOutput(spec, *dump, types)
+		return
+	}
+	o, err := bpf.Open(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	options.BPFInf(o, *lines)
+	if o.BTF != nil && (*dump || len(types) > 0) {
+		fmt.Println()
+		options.BTFInf(o.BTF, types)
+	}
+}
+
+// btfOutput prints the summary of spec, or its types with dump or
+// when some are named.
+func btfOutput(spec *bpf.Spec, dump bool, types []string) {
+	if dump || len(types) > 0 {
+		options.BTFInf(spec, types)
+		return
+	}
+	options.BTFSummaryInf(spec)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"elfreader/core"
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func coreCmd(args []string) {
+	fs := flag.NewFlagSet("core", flag.ExitOnError)
+	fp := fs.Bool("fp", false, "print the floating point and vector registers")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	c, err := core.New(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	options.CoreInf(c, options.CoreOptions{FP: *fp})
+}
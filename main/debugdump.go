@@ -0,0 +1,34 @@
+package main
+
+import (
+	"elfreader/file"
+	"elfreader/options"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// debugDump handles --debug-dump=<kinds>, a comma-separated list of
+// DWARF sections to print, for the ELF file name read from r.
+func debugDump(list, name string, r io.ReaderAt) error {
+	var kinds []string
+	for _, k := range strings.Split(list, ",") {
+		known := false
+		for _, dk := range options.DebugDumpKinds {
+			known = known || k == dk
+		}
+		if !known {
+			fmt.Fprintf(os.Stderr, "error: unknown debug dump kind %q, want one of %s\n", k, strings.Join(options.DebugDumpKinds, ","))
+			os.Exit(1)
+		}
+		kinds = append(kinds, k)
+	}
+
+	f := file.NewFile(r)
+	if f == nil {
+		return fmt.Errorf("%s: not a valid ELF file", name)
+	}
+	options.DebugDumpInf(f, kinds)
+	return nil
+}
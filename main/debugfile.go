@@ -0,0 +1,94 @@
+package main
+
+import (
+	"debug/elf"
+	"elfreader/debuginfod"
+	"elfreader/debuglink"
+	"elfreader/file"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// debugDirFlag registers the -debug-dir flag of the commands that use
+// separate debug files.
+func debugDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("debug-dir", defaultDebugDirs(), "roots of separate debug files, separated by "+string(filepath.ListSeparator))
+}
+
+// defaultDebugDirs returns the default roots of separate debug files in
+// the form of the -debug-dir flag, for the options that have no flags.
+func defaultDebugDirs() string {
+	return strings.Join(debuglink.DefaultRoots, string(filepath.ListSeparator))
+}
+
+// findDebugFile returns the path of the separate debug file of f,
+// opened from name, or "" if there is none. Files not found locally
+// are fetched from the debuginfod servers of DEBUGINFOD_URLS.
+func findDebugFile(f *file.File, name, dirs string) string {
+	p, err := debuglink.Find(f, name, filepath.SplitList(dirs))
+	if err == debuglink.ErrNotFound {
+		c := debuginfod.NewClient()
+		id := debuglink.BuildID(f)
+		if len(c.URLs) == 0 || id == nil {
+			return ""
+		}
+		p, err = c.Debuginfo(id)
+		if err == debuginfod.ErrNotFound {
+			return ""
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", name, err)
+		return ""
+	}
+	return p
+}
+
+// openDebugFile opens the separate debug file of f, opened from name,
+// returning nil if there is none.
+func openDebugFile(f *file.File, name, dirs string) *file.File {
+	p := findDebugFile(f, name, dirs)
+	if p == "" {
+		return nil
+	}
+	d, err := file.Open(p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return nil
+	}
+	return d
+}
+
+// symtabDebugFile returns the separate debug file of f, opened from
+// name, when f is stripped of .symtab and has one. Its symbols apply to
+// f, whose section headers it shares; the caller closes it.
+func symtabDebugFile(f *file.File, name, dirs string) *file.File {
+	if f.SectionByType(elf.SHT_SYMTAB) != nil {
+		return nil
+	}
+	return openDebugFile(f, name, dirs)
+}
+
+// debugFilePath returns the path of the separate debug file of the
+// file name, or "" if there is none.
+func debugFilePath(name, dirs string) string {
+	f, err := file.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return findDebugFile(f, name, dirs)
+}
+
+// miniDebugSymbols returns the function symbols of the MiniDebugInfo
+// of f, opened from name.
+func miniDebugSymbols(f *file.File, name string) []file.Symbol {
+	syms, err := debuglink.MiniDebugSymbols(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", name, err)
+	}
+	return syms
+}
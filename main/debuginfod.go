@@ -0,0 +1,82 @@
+package main
+
+import (
+	"elfreader/debuginfod"
+	"elfreader/debuglink"
+	"elfreader/file"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func debuginfodCmd(args []string) {
+	c := debuginfod.NewClient()
+	fs := flag.NewFlagSet("debuginfod", flag.ExitOnError)
+	urls := fs.String("urls", strings.Join(c.URLs, " "), "space-separated server URLs")
+	cache := fs.String("cache", c.CacheDir, "cache directory")
+	maxAge := fs.Duration("max-age", c.MaxAge, "age after which cached files are fetched again, 0 for never")
+	clean := fs.Bool("clean", false, "remove expired cache entries first")
+	fs.Parse(args)
+	c.URLs = strings.Fields(*urls)
+	c.CacheDir = *cache
+	c.MaxAge = *maxAge
+
+	if *clean {
+		if err := c.Clean(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if fs.NArg() == 0 {
+			return
+		}
+	}
+	kind := fs.Arg(0)
+	if kind == "source" && fs.NArg() != 3 || kind != "source" && fs.NArg() != 2 {
+		usage()
+	}
+	id, err := buildIDArg(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var p string
+	switch kind {
+	case "debuginfo":
+		p, err = c.Debuginfo(id)
+	case "executable":
+		p, err = c.Executable(id)
+	case "source":
+		p, err = c.Source(id, fs.Arg(2))
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %x: %v\n", id, err)
+		os.Exit(1)
+	}
+	fmt.Println(p)
+}
+
+// buildIDArg returns the build-id given in hex, or that of the ELF
+// file named arg.
+func buildIDArg(arg string) ([]byte, error) {
+	if _, err := os.Stat(arg); err != nil {
+		if id, herr := hex.DecodeString(arg); herr == nil && len(id) > 0 {
+			return id, nil
+		}
+		return nil, err
+	}
+	f, err := file.Open(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	id := debuglink.BuildID(f)
+	if id == nil {
+		return nil, fmt.Errorf("%s has no build-id", arg)
+	}
+	return id, nil
+}
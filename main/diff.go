@@ -0,0 +1,43 @@
+package main
+
+import (
+	"debug/elf"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func diffCmd(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	ignoreBuildID := fs.Bool("ignore-build-id", false, "do not compare the GNU build-id note")
+	ignoreSections := fs.String("ignore-section", "", "comma-separated sections whose contents are not compared")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+
+	a := openELF(fs.Arg(0))
+	defer a.Close()
+	b := openELF(fs.Arg(1))
+	defer b.Close()
+
+	opts := options.DiffOptions{IgnoreBuildID: *ignoreBuildID}
+	if *ignoreSections != "" {
+		opts.IgnoreSections = strings.Split(*ignoreSections, ",")
+	}
+	if options.DiffInf(a, b, opts) {
+		os.Exit(1)
+	}
+}
+
+// openELF opens name or exits with an error.
+func openELF(name string) *elf.File {
+	f, err := elf.Open(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	return f
+}
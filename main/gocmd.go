@@ -0,0 +1,38 @@
+package main
+
+import (
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+func goCmd(args []string) {
+	fs := flag.NewFlagSet("go", flag.ExitOnError)
+	funcs := fs.Bool("funcs", false, "list the functions of the pclntab")
+	sizes := fs.Bool("sizes", false, "break the size down by package")
+	lines := fs.String("lines", "", "print the line tables of the functions matching this regular expression")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	o := options.GoOptions{Funcs: *funcs, Sizes: *sizes}
+	if *lines != "" {
+		re, err := regexp.Compile(*lines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		o.Lines = re
+	}
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	options.GoInf(f, fs.Arg(0), o)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"elfreader/file"
+	"elfreader/kmod"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func kmodCmd(args []string) {
+	fs := flag.NewFlagSet("kmod", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	r, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer r.Close()
+	st, err := r.Stat()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	f := file.NewFile(r)
+	if f == nil {
+		fmt.Fprintf(os.Stderr, "error: %s: not a valid ELF file\n", fs.Arg(0))
+		os.Exit(1)
+	}
+	m, err := kmod.Read(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	s, err := kmod.ReadSignature(r, st.Size())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s: %v\n", fs.Arg(0), err)
+	}
+	options.KmodInf(m, s)
+}
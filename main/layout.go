@@ -0,0 +1,54 @@
+package main
+
+import (
+	"elfreader/debuginfo"
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func layoutCmd(args []string) {
+	fs := flag.NewFlagSet("layout", flag.ExitOnError)
+	cacheLine := fs.Int64("cacheline", 64, "cache line size in bytes")
+	padding := fs.Int64("padding", -1, "list all types with more than this many bytes of holes and padding")
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() == 1 && *padding < 0 {
+		usage()
+	}
+
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	d, err := debuginfo.Load(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	layouts, err := debuginfo.Layouts(d, f.ByteOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *padding >= 0 {
+		options.PaddingInf(layouts, *padding)
+	}
+	for i, name := range fs.Args()[1:] {
+		found := debuginfo.FindLayouts(layouts, name)
+		if len(found) == 0 {
+			fmt.Fprintf(os.Stderr, "error: no struct, class or union named %q\n", name)
+			os.Exit(1)
+		}
+		for j, l := range found {
+			if i > 0 || j > 0 || *padding >= 0 {
+				fmt.Println()
+			}
+			options.LayoutInf(l, *cacheLine)
+		}
+	}
+}
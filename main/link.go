@@ -0,0 +1,56 @@
+package main
+
+import (
+	"elfreader/archive"
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func linkCmd(args []string) {
+	fs := flag.NewFlagSet("link", flag.ExitOnError)
+	group := fs.Bool("group", false, "add the objects first, then scan all archives as one group like --start-group")
+	demangle := fs.Bool("C", false, "demangle C++ and Rust symbol names")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		usage()
+	}
+	options.Demangle = options.Demangle || *demangle
+
+	l := archive.NewLinker()
+	var names []string
+	var archives []*archive.Archive
+	for _, name := range fs.Args() {
+		a, err := archive.Open(name)
+		if err == nil {
+			defer a.Close()
+			if *group {
+				names, archives = append(names, name), append(archives, a)
+				continue
+			}
+			err = l.AddArchive(name, a)
+		} else if err == archive.ErrNotArchive {
+			err = addObject(l, name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := l.AddGroup(names, archives); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	options.LinkInf(l)
+}
+
+func addObject(l *archive.Linker, name string) error {
+	f, err := file.Open(name)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	defer f.Close()
+	return l.AddObject(name, f)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"elfreader/file"
+	"elfreader/lookup"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func lookupCmd(args []string) {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	base := fs.String("base", "0", "load base of a PIE or shared object")
+	offset := fs.Bool("offset", false, "arguments are file offsets instead of addresses")
+	n := fs.Int("n", 0, "number of bytes to read at each address")
+	demangle := fs.Bool("C", false, "demangle C++ and Rust symbol names")
+	debugDir := debugDirFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		usage()
+	}
+	options.Demangle = options.Demangle || *demangle
+
+	loadBase, err := strconv.ParseUint(*base, 0, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	x := lookup.New(f, loadBase)
+	if d := openDebugFile(f, fs.Arg(0), *debugDir); d != nil {
+		defer d.Close()
+		if syms, err := d.Symbols(); err == nil {
+			x.AddSymbols(syms)
+		}
+	} else {
+		x.AddSymbols(miniDebugSymbols(f, fs.Arg(0)))
+	}
+	for i, arg := range fs.Args()[1:] {
+		v, err := strconv.ParseUint(arg, 0, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if *offset {
+			if v, err = x.OffsetToVA(v); err != nil {
+				fmt.Fprintf(os.Stderr, "error: offset %s: %v\n", arg, err)
+				os.Exit(1)
+			}
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		options.LookupInf(f, x, v, *n)
+	}
+}
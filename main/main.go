@@ -2,35 +2,144 @@ package main
 
 import (
 	"debug/elf"
+	"elfreader/archive"
+	"elfreader/disasm"
+	"elfreader/file"
 	"elfreader/options"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
 func main() {
+	// subcommand handle
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			diffCmd(os.Args[2:])
+			return
+		case "size":
+			sizeCmd(os.Args[2:])
+			return
+		case "symbols":
+			symbolsCmd(os.Args[2:])
+			return
+		case "lookup":
+			lookupCmd(os.Args[2:])
+			return
+		case "addr2line":
+			addr2lineCmd(os.Args[2:])
+			return
+		case "layout":
+			layoutCmd(os.Args[2:])
+			return
+		case "debuginfod":
+			debuginfodCmd(os.Args[2:])
+			return
+		case "go":
+			goCmd(os.Args[2:])
+			return
+		case "toolchain":
+			toolchainCmd(os.Args[2:])
+			return
+		case "sbom":
+			sbomCmd(os.Args[2:])
+			return
+		case "core":
+			coreCmd(os.Args[2:])
+			return
+		case "backtrace":
+			backtraceCmd(os.Args[2:])
+			return
+		case "link":
+			linkCmd(os.Args[2:])
+			return
+		case "kmod":
+			kmodCmd(os.Args[2:])
+			return
+		case "bpf":
+			bpfCmd(os.Args[2:])
+			return
+		}
+	}
+
+	// modifier handle
+	args := os.Args[1:]
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-C" {
+			options.Demangle = true
+			args = append(args[:i], args[i+1:]...)
+			i--
+		}
+	}
+
 	// format check
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "usage: %s <option> <file>\n", os.Args[0])
-		os.Exit(1)
+	if len(args) != 2 {
+		usage()
 	}
 
 	// option handle
-	op := string(os.Args[1])
+	op := args[0]
+	fName := args[1]
 
-	// open ELF file
-	f, err := elf.Open(os.Args[2])
+	if op == "--archive-index" {
+		archiveIndex(fName)
+		return
+	}
+	a, err := archive.Open(fName)
+	if err == nil {
+		defer a.Close()
+		archiveMembers(a, op, fName)
+		return
+	}
+	if err != archive.ErrNotArchive {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	r, err := os.Open(fName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
-	defer f.Close()
+	defer r.Close()
+	if err := elfOption(op, fName, r); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// elfOption prints the option op of the ELF file name, read from r.
+func elfOption(op, name string, r io.ReaderAt) error {
+	if strings.HasPrefix(op, "--debug-dump=") {
+		return debugDump(strings.TrimPrefix(op, "--debug-dump="), name, r)
+	}
+
+	// open ELF file
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if strings.HasPrefix(op, "--disassemble=") {
+		return disassemble(f, disasmOptions(strings.TrimPrefix(op, "--disassemble=")))
+	}
 
 	// option jump
 	switch op {
 	case "-A":
-		options.AllInf(f, os.Args[2])
+		sf, err := symbolFile(name, r)
+		if err != nil {
+			return err
+		}
+		if d := symtabDebugFile(sf, name, defaultDebugDirs()); d != nil {
+			defer d.Close()
+			sf = d
+		}
+		options.AllInf(f, r, sf)
 	case "-H":
-		options.HeadInf(f, os.Args[2])
+		options.HeadInf(f, r)
 	case "-P":
 		options.ProgramHeadInf(f, true)
 	case "-S":
@@ -39,7 +148,23 @@ func main() {
 		}
 	case "-Sym":
 		{
-			options.SymbolTableInf(f)
+			sf, err := symbolFile(name, r)
+			if err != nil {
+				return err
+			}
+			if d := symtabDebugFile(sf, name, defaultDebugDirs()); d != nil {
+				defer d.Close()
+				sf = d
+			}
+			options.SymbolTableInf(sf)
+		}
+	case "-DynSym":
+		{
+			sf, err := symbolFile(name, r)
+			if err != nil {
+				return err
+			}
+			options.DynamicSymbolInf(sf)
 		}
 
 	//TO DO: Note
@@ -51,6 +176,12 @@ func main() {
 		{
 			options.RelocsInf(f)
 		}
+	case "-Arch":
+		options.ArchInf(f)
+	case "-Plt":
+		options.PltInf(f)
+	case "-D", "--disassemble":
+		return disassemble(f, options.DisasmOptions{})
 
 	//TO DO: Version info
 	/*
@@ -62,4 +193,57 @@ func main() {
 			fmt.Println("Error: no such an option")
 		}
 	}
+	return nil
+}
+
+// symbolFile reads the ELF file name from r for the symbol printers,
+// which use the file package.
+func symbolFile(name string, r io.ReaderAt) (*file.File, error) {
+	f := file.NewFile(r)
+	if f == nil {
+		return nil, fmt.Errorf("%s: not a valid ELF file", name)
+	}
+	return f, nil
+}
+
+// disasmOptions reads the argument of --disassemble=, an address range
+// such as 0x1000-0x1040 or else a symbol name.
+func disasmOptions(arg string) options.DisasmOptions {
+	if lo, hi, err := parseRange(arg); err == nil {
+		return options.DisasmOptions{Start: lo, End: hi}
+	}
+	return options.DisasmOptions{Symbol: arg}
+}
+
+// disassemble prints the executable sections of f selected by o.
+func disassemble(f *elf.File, o options.DisasmOptions) error {
+	a, err := disasm.ForMachine(f.Machine)
+	if err != nil {
+		return err
+	}
+	options.DisassembleInf(f, a, o)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [-C] <option> <file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s --debug-dump=<kind>[,<kind>...] <file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s -D|--disassemble[=<symbol>|<start>-<end>] <file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s --archive-index <archive>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s diff [flags] <file1> <file2>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s size [flags] <file> [<file2>]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s symbols [flags] <file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s lookup [flags] <file> <addr>...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s addr2line [flags] <file> <addr|file:line>...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s layout [flags] <file> [<type>...]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s debuginfod [flags] debuginfo|executable|source <file|build-id> [<path>]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s go [flags] <file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s toolchain [flags] <file>...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s sbom [flags] <file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s core [flags] <core>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s backtrace [flags] <core>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s link [flags] <object|archive>...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s kmod <module.ko>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s bpf [flags] <object|vmlinux|btf> [<type>...]\n", os.Args[0])
+	os.Exit(1)
 }
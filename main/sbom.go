@@ -0,0 +1,28 @@
+package main
+
+import (
+	"elfreader/options"
+	"elfreader/sbom"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func sbomCmd(args []string) {
+	fs := flag.NewFlagSet("sbom", flag.ExitOnError)
+	format := fs.String("format", "cyclonedx", "document format: cyclonedx or spdx")
+	root := fs.String("root", "", "directory the libraries are searched under, as a sysroot")
+	libPath := fs.String("L", os.Getenv("LD_LIBRARY_PATH"), "directories searched for libraries first, separated by "+string(filepath.ListSeparator))
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	b, err := sbom.Build(fs.Arg(0), sbom.Options{Root: *root, LibraryPath: filepath.SplitList(*libPath)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	options.SBOMInf(b, *format)
+}
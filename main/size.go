@@ -0,0 +1,39 @@
+package main
+
+import (
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func sizeCmd(args []string) {
+	fs := flag.NewFlagSet("size", flag.ExitOnError)
+	format := fs.String("format", "berkeley", "summary format: berkeley or sysv")
+	showBloat := fs.Bool("bloat", false, "break symbol sizes down by symbol, section, namespace and compile unit")
+	top := fs.Int("top", 20, "number of entries per breakdown (0 for all)")
+	fs.Parse(args)
+	if *format != "berkeley" && *format != "sysv" {
+		fmt.Fprintf(os.Stderr, "error: unknown format %q\n", *format)
+		os.Exit(1)
+	}
+
+	switch fs.NArg() {
+	case 1:
+		f := openELF(fs.Arg(0))
+		defer f.Close()
+		options.SizeInf(f, fs.Arg(0), *format == "sysv")
+		if *showBloat {
+			fmt.Println()
+			options.BloatInf(f, *top)
+		}
+	case 2:
+		a := openELF(fs.Arg(0))
+		defer a.Close()
+		b := openELF(fs.Arg(1))
+		defer b.Close()
+		options.SizeDiffInf(a, b, fs.Arg(0), fs.Arg(1), *top)
+	default:
+		usage()
+	}
+}
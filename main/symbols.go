@@ -0,0 +1,104 @@
+package main
+
+import (
+	"elfreader/file"
+	"elfreader/options"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func symbolsCmd(args []string) {
+	fs := flag.NewFlagSet("symbols", flag.ExitOnError)
+	dynamic := fs.Bool("dyn", false, "list .dynsym instead of .symtab")
+	demangle := fs.Bool("C", false, "demangle C++ and Rust symbol names")
+	types := fs.String("type", "", "comma-separated symbol types, e.g. FUNC,OBJECT")
+	binds := fs.String("bind", "", "comma-separated bindings, e.g. GLOBAL,WEAK")
+	vis := fs.String("vis", "", "comma-separated visibilities, e.g. DEFAULT,HIDDEN")
+	sections := fs.String("section", "", "comma-separated section names")
+	name := fs.String("name", "", "regular expression the (mangled or demangled) name must match")
+	defined := fs.Bool("defined", false, "only defined symbols")
+	undefined := fs.Bool("undefined", false, "only undefined symbols")
+	addr := fs.String("addr", "", "address range lo-hi (hi exclusive)")
+	sortBy := fs.String("sort", "", "sort by addr, size or name")
+	debugDir := debugDirFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	opts := options.SymbolOptions{
+		Dynamic:      *dynamic,
+		Types:        splitList(*types),
+		Binds:        splitList(*binds),
+		Visibilities: splitList(*vis),
+		Sections:     splitList(*sections),
+		Defined:      *defined,
+		Undefined:    *undefined,
+		SortBy:       *sortBy,
+	}
+	if *name != "" {
+		re, err := regexp.Compile(*name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Name = re
+	}
+	if *addr != "" {
+		lo, hi, err := parseRange(*addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.MinAddr, opts.MaxAddr = lo, hi
+	}
+	options.Demangle = options.Demangle || *demangle
+
+	f, err := file.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	// f may be replaced below, so the deferred call must not bind it now
+	defer func() { f.Close() }()
+	if !*dynamic {
+		// stripped: list the symbols of the separate debug file; without
+		// one, SymbolsInf lists .dynsym with the MiniDebugInfo functions
+		// if there are any
+		if d := symtabDebugFile(f, fs.Arg(0), *debugDir); d != nil {
+			f.Close()
+			f = d
+		}
+	}
+	options.SymbolsInf(f, opts)
+}
+
+// splitList splits a comma-separated flag value, returning nil for "".
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// parseRange parses "lo-hi" with numbers in Go syntax (0x for hex).
+func parseRange(s string) (lo, hi uint64, err error) {
+	l, h, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("bad address range %q", s)
+	}
+	if lo, err = strconv.ParseUint(l, 0, 64); err != nil {
+		return 0, 0, err
+	}
+	if hi, err = strconv.ParseUint(h, 0, 64); err != nil {
+		return 0, 0, err
+	}
+	if hi <= lo {
+		return 0, 0, fmt.Errorf("empty address range %q", s)
+	}
+	return lo, hi, nil
+}
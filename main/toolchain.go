@@ -0,0 +1,51 @@
+package main
+
+import (
+	"elfreader/debuginfo"
+	"elfreader/file"
+	"elfreader/options"
+	"elfreader/toolchain"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func toolchainCmd(args []string) {
+	fs := flag.NewFlagSet("toolchain", flag.ExitOnError)
+	debugDir := debugDirFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		usage()
+	}
+
+	for i, name := range fs.Args() {
+		f, err := file.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		// the producers of stripped files are in their debug files
+		d, err := debuginfo.Load(f)
+		if err == debuginfo.ErrNoDWARF {
+			if df := openDebugFile(f, name, *debugDir); df != nil {
+				d, err = debuginfo.Load(df)
+				df.Close()
+			}
+		}
+		if err != nil && err != debuginfo.ErrNoDWARF {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v\n", name, err)
+		}
+		r, err := toolchain.Analyze(f, name, d)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v\n", name, err)
+		}
+		if fs.NArg() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", name)
+		}
+		options.ToolchainInf(r)
+		f.Close()
+	}
+}
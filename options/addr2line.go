@@ -0,0 +1,77 @@
+package options
+
+import (
+	"elfreader/debuginfo"
+	"fmt"
+)
+
+// Addr2lineOptions selects the output of Addr2lineInf in the manner of
+// the addr2line flags of the same names.
+type Addr2lineOptions struct {
+	Functions bool
+	Inlines   bool
+	Pretty    bool
+}
+
+func frameLocation(fr debuginfo.Frame) string {
+	if fr.File == "" {
+		return "??:0"
+	}
+	return fmt.Sprintf("%s:%d", fr.File, fr.Line)
+}
+
+func frameFunction(fr debuginfo.Frame) string {
+	if fr.Function == "" {
+		return "??"
+	}
+	return symName(fr.Function)
+}
+
+// Addr2lineInf prints the source location of the link-time address pc.
+func Addr2lineInf(s *debuginfo.Symbolizer, pc uint64, o Addr2lineOptions) {
+	frames, err := s.Frames(pc)
+	if err != nil {
+		frames = []debuginfo.Frame{{}}
+	}
+	if !o.Inlines {
+		// without inlines the location is that of the innermost code
+		// and the function is the outermost one, like addr2line
+		fr := frames[0]
+		fr.Function = frames[len(frames)-1].Function
+		frames = []debuginfo.Frame{fr}
+	}
+	for i, fr := range frames {
+		switch {
+		case o.Pretty && i > 0:
+			fmt.Print(" (inlined by) ")
+			fallthrough
+		case o.Pretty:
+			if o.Functions {
+				fmt.Printf("%s at ", frameFunction(fr))
+			}
+			fmt.Println(frameLocation(fr))
+		default:
+			if o.Functions {
+				fmt.Println(frameFunction(fr))
+			}
+			fmt.Println(frameLocation(fr))
+		}
+	}
+}
+
+// LineAddrsInf prints the run-time addresses generated for line of the
+// source file name.
+func LineAddrsInf(s *debuginfo.Symbolizer, name string, line int, base uint64) {
+	addrs, err := s.Addrs(name, line)
+	if err != nil {
+		fmt.Printf("%s:%d: %v\n", name, line, err)
+		return
+	}
+	if len(addrs) == 0 {
+		fmt.Printf("%s:%d: no code\n", name, line)
+		return
+	}
+	for _, a := range addrs {
+		fmt.Printf("0x%x\n", a+base)
+	}
+}
@@ -0,0 +1,147 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/arch"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// shtGNUAttributes is the type of .gnu.attributes.
+const shtGNUAttributes = elf.SectionType(0x6ffffff5)
+
+// attributeSections returns the build attribute sections of f.
+func attributeSections(f *elf.File) []*elf.Section {
+	var out []*elf.Section
+	for _, s := range f.Sections {
+		switch {
+		case s.Type == shtGNUAttributes:
+		case s.Type == arch.SHTAttributes && (f.Machine == elf.EM_ARM || f.Machine == elf.EM_RISCV):
+		default:
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// mipsABIFlags returns the reader of the MIPS ABI flags of f, from
+// PT_MIPS_ABIFLAGS or else .MIPS.abiflags, or nil.
+func mipsABIFlags(f *elf.File) io.ReaderAt {
+	if f.Machine != elf.EM_MIPS && f.Machine != elf.EM_MIPS_RS3_LE {
+		return nil
+	}
+	for _, p := range f.Progs {
+		if p.Type == arch.PT_MIPS_ABIFLAGS {
+			return p
+		}
+	}
+	for _, s := range f.Sections {
+		if s.Type == arch.SHT_MIPS_ABIFLAGS {
+			return s
+		}
+	}
+	return nil
+}
+
+func hasArchInf(f *elf.File) bool {
+	return len(attributeSections(f)) > 0 || mipsABIFlags(f) != nil
+}
+
+// ArchInf prints the build attributes of f in the manner of readelf -A
+// and, for MIPS, the ABI flags.
+func ArchInf(f *elf.File) {
+	secs := attributeSections(f)
+	abi := mipsABIFlags(f)
+	if len(secs) == 0 && abi == nil {
+		fmt.Println("No architecture-specific information in this file.")
+		return
+	}
+	for i, s := range secs {
+		if i > 0 {
+			fmt.Println()
+		}
+		b, err := s.Data()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v\n", s.Name, err)
+			continue
+		}
+		subs, err := arch.ParseAttributes(b, f.ByteOrder)
+		fmt.Printf("Attribute section %s (%d bytes):\n", s.Name, len(b))
+		attributesInf(subs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %s: %v\n", s.Name, err)
+		}
+	}
+
+	if abi == nil {
+		return
+	}
+	if len(secs) > 0 {
+		fmt.Println()
+	}
+	var b [24]byte
+	if _, err := abi.ReadAt(b[:], 0); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: MIPS ABI flags: %v\n", err)
+		return
+	}
+	m, err := arch.ParseMIPSABIFlags(b[:], f.ByteOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return
+	}
+	fmt.Printf("MIPS ABI Flags Version: %d\n", m.Version)
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  ISA:\t%s\n", m.ISA())
+	fmt.Fprintf(w, "  GPR size:\t%s\n", arch.RegSize(m.GPRSize))
+	fmt.Fprintf(w, "  CPR1 size:\t%s\n", arch.RegSize(m.CPR1Size))
+	fmt.Fprintf(w, "  CPR2 size:\t%s\n", arch.RegSize(m.CPR2Size))
+	fmt.Fprintf(w, "  FP ABI:\t%s\n", m.FPABIName())
+	fmt.Fprintf(w, "  ISA Extension:\t%s\n", m.ISAExtName())
+	ases := m.ASENames()
+	if len(ases) == 0 {
+		ases = []string{"None"}
+	}
+	fmt.Fprintf(w, "  ASEs:\t%s\n", strings.Join(ases, ", "))
+	fmt.Fprintf(w, "  FLAGS 1:\t%08x %s\n", m.Flags1, strings.Join(m.Flags1Names(), ", "))
+	fmt.Fprintf(w, "  FLAGS 2:\t%08x\n", m.Flags2)
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func attributesInf(subs []arch.Subsection) {
+	for _, sub := range subs {
+		fmt.Printf("Attribute Section: %s\n", sub.Vendor)
+		for _, g := range sub.Groups {
+			if len(g.Indices) > 0 {
+				idx := make([]string, len(g.Indices))
+				for i, n := range g.Indices {
+					idx[i] = fmt.Sprint(n)
+				}
+				fmt.Printf("%s Attributes: %s\n", g.Scope, strings.Join(idx, " "))
+			} else {
+				fmt.Printf("%s Attributes\n", g.Scope)
+			}
+			// set tabwriter width 8
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, a := range g.Attrs {
+				v := a.Value()
+				if a.IsString {
+					v = fmt.Sprintf("%q", v)
+				}
+				fmt.Fprintf(w, "  %s:\t%s\n", a.Name(), v)
+			}
+			// refresh Write
+			if err := w.Flush(); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
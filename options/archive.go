@@ -0,0 +1,30 @@
+package options
+
+import (
+	"elfreader/archive"
+	"fmt"
+)
+
+// ArchiveIndexInf prints the symbol index of the archive a, opened
+// from name, grouping the symbols by the member defining them.
+func ArchiveIndexInf(a *archive.Archive, name string) {
+	if a.Index == "" {
+		fmt.Printf("%s has no archive index\n", name)
+		return
+	}
+	kind := ""
+	if a.Thin {
+		kind = "thin "
+	}
+	fmt.Printf("Index of %sarchive %s: %d entries in %s, 0x%x bytes\n", kind, name, len(a.Symbols), a.Index, a.IndexSize)
+	for i, s := range a.Symbols {
+		if i == 0 || s.Offset != a.Symbols[i-1].Offset {
+			if s.Member == nil {
+				fmt.Printf("Binary at offset 0x%x: no such member\n", s.Offset)
+			} else {
+				fmt.Printf("Contents of binary %s(%s) at offset 0x%x\n", name, s.Member.Name, s.Offset)
+			}
+		}
+		fmt.Printf("  %s\n", symName(s.Name))
+	}
+}
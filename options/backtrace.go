@@ -0,0 +1,73 @@
+package options
+
+import (
+	"elfreader/core"
+	"elfreader/debuginfo"
+	"elfreader/lookup"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// ModuleSymbols symbolizes the addresses of a module of a core dump.
+type ModuleSymbols struct {
+	Index *lookup.Index
+	// Lines is nil for modules without DWARF
+	Lines *debuginfo.Symbolizer
+}
+
+// BacktraceInf prints the backtraces of the threads of c, traces[i]
+// being that of c.Threads[i]. Inlined calls get a line each, above the
+// function they were inlined into.
+func BacktraceInf(c *core.Core, traces [][]core.Frame, syms map[*core.Module]*ModuleSymbols) {
+	wf := fmt.Sprintf("0x%%0%dx", 2*c.WordSize)
+	for i, t := range c.Threads {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Thread %d, LWP %d, %s:\n", i+1, t.PID, signalInf(t.Signal))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for n, fr := range traces[i] {
+			function, loc, module := "??", "", "??"
+			var inlined []debuginfo.Frame
+			if m := fr.Module; m != nil {
+				module = m.Path
+				if s := syms[m]; s != nil {
+					pc := fr.LookupPC() - m.Bias
+					// a pc past the end of the nearest symbol lies in
+					// code whose symbol was stripped, such as a static
+					// function of a library with only .dynsym: naming
+					// it after its neighbor would be a guess. Symbols
+					// without a size cannot be checked and are kept
+					if r := s.Index.Lookup(fr.LookupPC()); r.Symbol != nil && (r.Exact || r.Symbol.Size == 0) {
+						function = fmt.Sprintf("%s+0x%x", symName(r.Symbol.Name), fr.PC-m.Bias-r.Symbol.Value)
+					}
+					if s.Lines != nil {
+						if frames, err := s.Lines.Frames(pc); err == nil && len(frames) > 0 {
+							inlined = frames[:len(frames)-1]
+							outer := frames[len(frames)-1]
+							if function == "??" && outer.Function != "" {
+								function = frameFunction(outer)
+							}
+							// the call site of the inlined code, if any
+							loc = frameLocation(outer)
+						}
+					}
+				}
+			}
+			if fr.Via == core.ViaFramePointer || fr.Via == core.ViaReturn {
+				module += " [" + fr.Via + "]"
+			}
+			for _, in := range inlined {
+				fmt.Fprintf(w, "  \t\t%s (inlined)\t%s\t\n", frameFunction(in), frameLocation(in))
+			}
+			fmt.Fprintf(w, "  #%d\t"+wf+"\t%s\t%s\t%s\n", n, fr.PC, function, loc, module)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
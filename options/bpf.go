@@ -0,0 +1,293 @@
+package options
+
+import (
+	"elfreader/bpf"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// BPFInf prints the license, programs, maps and relocations of the BPF
+// object o and a summary of its BTF; lines adds the func and line info
+// of .BTF.ext and its CO-RE relocations.
+func BPFInf(o *bpf.Object, lines bool) {
+	if o.License != "" {
+		fmt.Printf("License: %s\n", o.License)
+	}
+	if o.HasVersion {
+		v := o.Version
+		fmt.Printf("Kernel version: %d.%d.%d (0x%x)\n", v>>16, v>>8&0xff, v&0xff, v)
+	}
+
+	fmt.Printf("\nPrograms (%d entries):\n", len(o.Programs))
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Name:\tSection:\tOffset:\tInsns:\tType:\tAttach:\tTarget:")
+	for _, p := range o.Programs {
+		typ, attach := p.ProgType, p.Attach
+		if p.Section == ".text" {
+			typ = "(subprogram)"
+		} else if typ == "" {
+			typ = "(unknown)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t0x%x\t%d\t%s\t%s\t%s\n", p.Name, p.Section, p.Offset, p.Insns(), typ, attach, p.Target)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(o.Maps) > 0 {
+		fmt.Printf("\nMaps (%d entries):\n", len(o.Maps))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Name:\tSection:\tType:\tKey:\tValue:\tMax entries:\tFlags:\tPinning:")
+		for _, m := range o.Maps {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%d\t0x%x\t%d\n", m.Name, m.Section, bpf.MapTypeName(m.Type),
+				mapKey(m.Key, m.KeySize), mapKey(m.Value, m.ValueSize), m.MaxEntries, m.Flags, m.Pinning)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(o.Relocs) > 0 {
+		fmt.Printf("\nRelocations (%d entries):\n", len(o.Relocs))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Section:\tInsn:\tType:\tSymbol:\tTarget:")
+		for _, r := range o.Relocs {
+			fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%s\n", r.Section, r.Offset/8, bpf.RelocName(r.Type), r.Symbol, r.Target)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if o.BTF == nil {
+		fmt.Println("\nNo .BTF section")
+		return
+	}
+	fmt.Println()
+	BTFSummaryInf(o.BTF)
+	if o.Ext == nil {
+		return
+	}
+	fmt.Printf("\n.BTF.ext: %d func info, %d line info, %d CO-RE relocation entries\n",
+		len(o.Ext.Funcs), len(o.Ext.Lines), len(o.Ext.CORE))
+	if !lines {
+		return
+	}
+
+	if len(o.Ext.Funcs) > 0 {
+		fmt.Printf("\nFunc info (%d entries):\n", len(o.Ext.Funcs))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Section:\tInsn:\tType ID:\tFunction:")
+		for _, f := range o.Ext.Funcs {
+			name := o.BTF.TypeName(f.TypeID)
+			if t := o.BTF.Type(f.TypeID); t != nil && t.Kind == bpf.KindFunc {
+				name = funcDecl(o.BTF, t)
+			}
+			fmt.Fprintf(w, "  %s\t%d\t%d\t%s\n", f.Section, f.InsnOff/8, f.TypeID, name)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(o.Ext.Lines) > 0 {
+		fmt.Printf("\nLine info (%d entries):\n", len(o.Ext.Lines))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Section:\tInsn:\tLocation:\tSource:")
+		for _, l := range o.Ext.Lines {
+			fmt.Fprintf(w, "  %s\t%d\t%s:%d:%d\t%s\n", l.Section, l.InsnOff/8, l.File, l.Line, l.Column, l.Text)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(o.Ext.CORE) > 0 {
+		fmt.Printf("\nCO-RE relocations (%d entries):\n", len(o.Ext.CORE))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Section:\tInsn:\tKind:\tType:\tAccess:")
+		for _, c := range o.Ext.CORE {
+			fmt.Fprintf(w, "  %s\t%d\t%s\t%s\t%s\n", c.Section, c.InsnOff/8, bpf.CORERelocKindName(c.Kind),
+				o.BTF.TypeName(c.TypeID), c.Access)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// mapKey spells a map key or value as its type and size.
+func mapKey(typ string, size uint32) string {
+	if typ == "" {
+		return fmt.Sprintf("%d bytes", size)
+	}
+	return fmt.Sprintf("%s (%d bytes)", typ, size)
+}
+
+// funcDecl spells the BTF FUNC t as a C declaration.
+func funcDecl(s *bpf.Spec, t *bpf.Type) string {
+	p := s.Type(t.Type)
+	if p == nil || p.Kind != bpf.KindFuncProto {
+		return s.TypeName(t.ID)
+	}
+	params := ""
+	for i, m := range p.Members {
+		if i > 0 {
+			params += ", "
+		}
+		if m.Type == 0 {
+			params += "..."
+			continue
+		}
+		params += s.TypeName(m.Type)
+		if m.Name != "" {
+			params += " " + m.Name
+		}
+	}
+	if params == "" {
+		params = "void"
+	}
+	return fmt.Sprintf("%s %s(%s)", s.TypeName(p.Type), t.Name, params)
+}
+
+// BTFSummaryInf prints the version and the number of types of each
+// kind of the BTF s.
+func BTFSummaryInf(s *bpf.Spec) {
+	counts := map[bpf.Kind]int{}
+	for _, t := range s.Types[1:] {
+		counts[t.Kind]++
+	}
+	fmt.Printf("BTF version %d, %d types:\n", s.Version, len(s.Types)-1)
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for k := bpf.KindInt; k <= bpf.KindEnum64; k++ {
+		if counts[k] > 0 {
+			fmt.Fprintf(w, "  %s\t%d\n", k, counts[k])
+		}
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+var funcLinkage = []string{"static", "global", "extern"}
+var varLinkage = []string{"static", "global-alloc", "extern"}
+
+func linkage(names []string, l uint32) string {
+	if int(l) < len(names) {
+		return names[l]
+	}
+	return fmt.Sprintf("(%d)", l)
+}
+
+// BTFInf dumps the types of the BTF s in the raw format of bpftool,
+// or only those called one of names if any are given.
+func BTFInf(s *bpf.Spec, names []string) {
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = true
+	}
+	for _, t := range s.Types[1:] {
+		if len(want) > 0 && !want[t.Name] {
+			continue
+		}
+		printBTFType(s, t)
+	}
+}
+
+func printBTFType(s *bpf.Spec, t *bpf.Type) {
+	name := t.Name
+	if name == "" {
+		name = "(anon)"
+	}
+	fmt.Printf("[%d] %s '%s'", t.ID, t.Kind, name)
+	switch t.Kind {
+	case bpf.KindInt:
+		enc := "(none)"
+		switch {
+		case t.Encoding&bpf.IntSigned != 0:
+			enc = "SIGNED"
+		case t.Encoding&bpf.IntChar != 0:
+			enc = "CHAR"
+		case t.Encoding&bpf.IntBool != 0:
+			enc = "BOOL"
+		}
+		fmt.Printf(" size=%d bits_offset=%d nr_bits=%d encoding=%s\n", t.Size, t.BitOffset, t.Bits, enc)
+	case bpf.KindPtr, bpf.KindTypedef, bpf.KindVolatile, bpf.KindConst, bpf.KindRestrict, bpf.KindTypeTag:
+		fmt.Printf(" type_id=%d\n", t.Type)
+	case bpf.KindArray:
+		fmt.Printf(" type_id=%d index_type_id=%d nr_elems=%d\n", t.Elem, t.Index, t.Elems)
+	case bpf.KindStruct, bpf.KindUnion:
+		fmt.Printf(" size=%d vlen=%d\n", t.Size, len(t.Members))
+		for _, m := range t.Members {
+			fmt.Printf("\t'%s' type_id=%d bits_offset=%d", memberName(m.Name), m.Type, m.BitOffset)
+			if m.BitSize != 0 {
+				fmt.Printf(" bitfield_size=%d", m.BitSize)
+			}
+			fmt.Println()
+		}
+	case bpf.KindEnum, bpf.KindEnum64:
+		enc := "UNSIGNED"
+		if t.KindFlag {
+			enc = "SIGNED"
+		}
+		fmt.Printf(" encoding=%s size=%d vlen=%d\n", enc, t.Size, len(t.Enumerators))
+		for _, e := range t.Enumerators {
+			if t.KindFlag {
+				fmt.Printf("\t'%s' val=%d\n", e.Name, e.Value)
+			} else {
+				fmt.Printf("\t'%s' val=%d\n", e.Name, uint64(e.Value))
+			}
+		}
+	case bpf.KindFwd:
+		kind := "struct"
+		if t.KindFlag {
+			kind = "union"
+		}
+		fmt.Printf(" fwd_kind=%s\n", kind)
+	case bpf.KindFunc:
+		fmt.Printf(" type_id=%d linkage=%s\n", t.Type, linkage(funcLinkage, t.Linkage))
+	case bpf.KindFuncProto:
+		fmt.Printf(" ret_type_id=%d vlen=%d\n", t.Type, len(t.Members))
+		for _, m := range t.Members {
+			fmt.Printf("\t'%s' type_id=%d\n", memberName(m.Name), m.Type)
+		}
+	case bpf.KindVar:
+		fmt.Printf(" type_id=%d, linkage=%s\n", t.Type, linkage(varLinkage, t.Linkage))
+	case bpf.KindDatasec:
+		fmt.Printf(" size=%d vlen=%d\n", t.Size, len(t.Vars))
+		for _, v := range t.Vars {
+			fmt.Printf("\ttype_id=%d offset=%d size=%d", v.Type, v.Offset, v.Size)
+			if vt := s.Type(v.Type); vt != nil {
+				fmt.Printf(" (%s '%s')", vt.Kind, vt.Name)
+			}
+			fmt.Println()
+		}
+	case bpf.KindFloat:
+		fmt.Printf(" size=%d\n", t.Size)
+	case bpf.KindDeclTag:
+		fmt.Printf(" type_id=%d component_idx=%d\n", t.Type, t.Component)
+	default:
+		fmt.Println()
+	}
+}
+
+func memberName(n string) string {
+	if n == "" {
+		return "(anon)"
+	}
+	return n
+}
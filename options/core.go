@@ -0,0 +1,172 @@
+package options
+
+import (
+	"elfreader/core"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// CoreOptions selects the optional parts of the core report.
+type CoreOptions struct {
+	// FP prints the floating point and vector registers, besides
+	// their control registers
+	FP bool
+}
+
+// auxv tags printed in decimal; the others are addresses or flags
+var auxvDecimal = map[uint64]bool{
+	2: true, 4: true, 5: true, 6: true, 11: true, 12: true, 13: true,
+	14: true, 17: true, 23: true, 27: true, 28: true, 51: true,
+}
+
+// auxv tags whose value is the address of a string
+var auxvString = map[uint64]bool{15: true, 24: true, 31: true}
+
+func signalInf(s int) string {
+	return fmt.Sprintf("%s (%d)", core.SignalName(s), s)
+}
+
+func seconds(us uint64) string {
+	return fmt.Sprintf("%d.%06ds", us/1000000, us%1000000)
+}
+
+// CoreInf prints the process, threads, auxiliary vector, mapped files
+// and memory map of the core dump c.
+func CoreInf(c *core.Core, o CoreOptions) {
+	wf := fmt.Sprintf("0x%%0%dx", 2*c.WordSize)
+	if p := c.Process; p != nil {
+		fmt.Printf("Command:       %s\n", strings.TrimSpace(p.Args))
+		fmt.Printf("Executable:    %s\n", p.Name)
+		fmt.Printf("Process:       pid %d, ppid %d, pgrp %d, sid %d\n", p.PID, p.PPID, p.PGRP, p.SID)
+		fmt.Printf("User:          uid %d, gid %d\n", p.UID, p.GID)
+		state := fmt.Sprintf("%c (%d)", p.SName, p.State)
+		if p.Zombie {
+			state += ", zombie"
+		}
+		fmt.Printf("State:         %s, nice %d, flags 0x%x\n", state, p.Nice, p.Flags)
+	}
+	for _, t := range c.Threads {
+		if s := t.Siginfo; s != nil {
+			line := fmt.Sprintf("%s, %s", signalInf(s.Signo), core.SigCodeName(s.Signo, s.Code))
+			if s.HasAddr {
+				line += fmt.Sprintf(", fault address 0x%x", s.Addr)
+			}
+			if s.HasSender {
+				line += fmt.Sprintf(", sent by pid %d uid %d", s.PID, s.UID)
+			}
+			if s.Errno != 0 {
+				line += fmt.Sprintf(", errno %d", s.Errno)
+			}
+			fmt.Printf("Signal:        %s\n", line)
+			break
+		}
+	}
+	fmt.Printf("Threads:       %d\n", len(c.Threads))
+
+	for i, t := range c.Threads {
+		fmt.Printf("\nThread %d, LWP %d, %s, user %s, system %s:\n", i+1, t.PID, signalInf(t.Signal), seconds(t.UTime), seconds(t.STime))
+		if t.Pending != 0 || t.Held != 0 {
+			fmt.Printf("  pending signals 0x%x, blocked 0x%x\n", t.Pending, t.Held)
+		}
+		regsInf(t.Regs, wf, 4)
+		if fp := c.FP(t); fp != nil {
+			regsInf(fp.Control, "0x%x", 4)
+			if o.FP {
+				for _, v := range fp.Vectors {
+					fmt.Printf("  %-6s %s\n", v.Name, hex.EncodeToString(reversed(v.Bytes, c)))
+				}
+			}
+		} else if t.FPRegs != nil {
+			fmt.Printf("  NT_PRFPREG: %d bytes\n", len(t.FPRegs))
+		}
+		for _, n := range t.Extra {
+			fmt.Printf("  %s: %d bytes\n", core.NoteName(n.Type), len(n.Desc))
+		}
+	}
+
+	if len(c.Auxv) > 0 {
+		fmt.Println("\nAuxiliary vector:")
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, e := range c.Auxv {
+			val := fmt.Sprintf(wf, e.Val)
+			switch {
+			case auxvDecimal[e.Tag]:
+				val = fmt.Sprint(e.Val)
+			case auxvString[e.Tag]:
+				if s, err := c.ReadString(e.Val, 4096); err == nil {
+					val += fmt.Sprintf(" %q", s)
+				}
+			}
+			fmt.Fprintf(w, "  %s\t%s\n", core.AuxvName(e.Tag), val)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(c.Files) > 0 {
+		fmt.Printf("\nMapped files (page size %d):\n", c.PageSize)
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Start:\tEnd:\tOffset:\tPath:")
+		for _, m := range c.Files {
+			fmt.Fprintf(w, "  "+wf+"\t"+wf+"\t0x%x\t%s\n", m.Start, m.End, m.Offset, m.Path)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Println("\nMemory map:")
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Start:\tEnd:\tPerm:\tDumped:\tOffset:\tPath:")
+	for _, r := range c.MemoryMap() {
+		off := ""
+		if r.Path != "" && !strings.HasPrefix(r.Path, "[") {
+			off = fmt.Sprintf("0x%x", r.Offset)
+		}
+		fmt.Fprintf(w, "  "+wf+"\t"+wf+"\t%s\t0x%x\t%s\t%s\n", r.Start, r.End, r.Perm, r.Dumped, off, r.Path)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// regsInf prints regs in rows of n.
+func regsInf(regs []core.Reg, format string, n int) {
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, r := range regs {
+		sep := "\t"
+		if i%n == n-1 || i == len(regs)-1 {
+			sep = "\n"
+		}
+		fmt.Fprintf(w, "  %s\t"+format+sep, r.Name, r.Value)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// reversed returns the register bytes b most significant first, for
+// printing as a number.
+func reversed(b []byte, c *core.Core) []byte {
+	out := append([]byte(nil), b...)
+	if c.ByteOrder == binary.LittleEndian {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out
+}
@@ -0,0 +1,601 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/cfi"
+	"elfreader/debuginfo"
+	"elfreader/file"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// DebugDumpKinds are the section kinds accepted by DebugDumpInf.
+var DebugDumpKinds = []string{"info", "abbrev", "line", "str", "aranges", "ranges", "rnglists", "loclists", "frame", "frame-interp", "except"}
+
+var unitTypes = map[int]string{
+	debuginfo.UTCompile:      "DW_UT_compile",
+	debuginfo.UTType:         "DW_UT_type",
+	debuginfo.UTPartial:      "DW_UT_partial",
+	debuginfo.UTSkeleton:     "DW_UT_skeleton",
+	debuginfo.UTSplitCompile: "DW_UT_split_compile",
+	debuginfo.UTSplitType:    "DW_UT_split_type",
+}
+
+func dwarfFormat(dwarf64 bool) string {
+	if dwarf64 {
+		return "64-bit"
+	}
+	return "32-bit"
+}
+
+// DebugDumpInf prints the DWARF sections named by kinds, in the order
+// given.
+func DebugDumpInf(f *file.File, kinds []string) {
+	s, err := debuginfo.LoadSections(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// address size of units that do not record it
+	addrSize := 4
+	if f.Class == elf.ELFCLASS64 {
+		addrSize = 8
+	}
+	for _, k := range kinds {
+		switch k {
+		case "info":
+			debugInfoInf(s)
+		case "abbrev":
+			debugAbbrevInf(s)
+		case "line":
+			debugLineInf(s, addrSize)
+		case "str":
+			debugStrInf(s)
+		case "aranges":
+			debugArangesInf(s)
+		case "ranges":
+			debugRangesInf(s, addrSize)
+		case "rnglists":
+			debugRngListsInf(s)
+		case "loclists":
+			debugLocListsInf(s, addrSize)
+		case "frame":
+			debugFrameInf(f, s, addrSize, false)
+		case "frame-interp":
+			debugFrameInf(f, s, addrSize, true)
+		case "except":
+			exceptInf(f, addrSize)
+		}
+	}
+}
+
+func dumpError(err error) {
+	fmt.Printf("  error: %v\n\n", err)
+}
+
+func debugInfoInf(s *debuginfo.Sections) {
+	if s.Data(".debug_info") == nil {
+		return
+	}
+	fmt.Printf("Contents of the .debug_info section:\n\n")
+	units, err := s.Units()
+	for _, u := range units {
+		fmt.Printf("  Compilation Unit @ offset 0x%x:\n", u.Offset)
+		fmt.Printf("   Length:        0x%x (%s)\n", u.Length, dwarfFormat(u.Dwarf64))
+		fmt.Printf("   Version:       %d\n", u.Version)
+		if u.Version >= 5 {
+			fmt.Printf("   Unit Type:     %s (%d)\n", unitTypes[u.Type], u.Type)
+		}
+		fmt.Printf("   Abbrev Offset: 0x%x\n", u.AbbrevOffset)
+		fmt.Printf("   Pointer Size:  %d\n", u.AddrSize)
+		switch u.Type {
+		case debuginfo.UTSkeleton, debuginfo.UTSplitCompile:
+			fmt.Printf("   DWO ID:        0x%016x\n", u.ID)
+		case debuginfo.UTType, debuginfo.UTSplitType:
+			fmt.Printf("   Signature:     0x%016x\n", u.ID)
+			fmt.Printf("   Type Offset:   0x%x\n", u.TypeOffset)
+		}
+
+		dies, derr := s.DIEs(u)
+		var bases debuginfo.Bases
+		if len(dies) > 0 {
+			bases = debuginfo.UnitBases(&dies[0])
+		}
+		for _, d := range dies {
+			if d.Abbrev == nil {
+				fmt.Printf(" <%d><%x>: Abbrev Number: 0\n", d.Depth, d.Offset)
+				continue
+			}
+			fmt.Printf(" <%d><%x>: Abbrev Number: %d (%s)\n", d.Depth, d.Offset, d.Abbrev.Code, debuginfo.TagName(d.Abbrev.Tag))
+			for _, fd := range d.Fields {
+				fmt.Printf("    %-24s %-22s %s\n", debuginfo.AttrName(fd.Attr), fd.Form, s.FormatValue(u, bases, fd))
+			}
+		}
+		if derr != nil {
+			dumpError(derr)
+		}
+		fmt.Println()
+	}
+	if err != nil {
+		dumpError(err)
+	}
+}
+
+func debugAbbrevInf(s *debuginfo.Sections) {
+	if s.Data(".debug_abbrev") == nil {
+		return
+	}
+	fmt.Printf("Contents of the .debug_abbrev section:\n\n")
+	// the tables are found through the units referring to them
+	units, err := s.Units()
+	if err != nil {
+		dumpError(err)
+	}
+	var offs []uint64
+	seen := make(map[uint64]bool)
+	for _, u := range units {
+		if !seen[u.AbbrevOffset] {
+			seen[u.AbbrevOffset] = true
+			offs = append(offs, u.AbbrevOffset)
+		}
+	}
+	sort.Slice(offs, func(i, j int) bool { return offs[i] < offs[j] })
+	for _, off := range offs {
+		fmt.Printf("  Number TAG (0x%x)\n", off)
+		table, err := s.Abbrevs(off)
+		for _, a := range table {
+			children := "no children"
+			if a.Children {
+				children = "has children"
+			}
+			fmt.Printf("   %-6d %s    [%s]\n", a.Code, debuginfo.TagName(a.Tag), children)
+			for _, at := range a.Attrs {
+				fmt.Printf("    %-24s %s", debuginfo.AttrName(at.Attr), at.Form)
+				if at.Implicit() {
+					fmt.Printf(": %d", at.Const)
+				}
+				fmt.Println()
+			}
+		}
+		if err != nil {
+			dumpError(err)
+		}
+	}
+	fmt.Println()
+}
+
+func debugLineInf(s *debuginfo.Sections, addrSize int) {
+	if s.Data(".debug_line") == nil {
+		return
+	}
+	fmt.Printf("Raw dump of debug contents of section .debug_line:\n\n")
+	progs, err := s.LinePrograms(addrSize)
+	for _, p := range progs {
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+		fmt.Fprintf(w, "  Offset:\t0x%x\n", p.Offset)
+		fmt.Fprintf(w, "  Length:\t%d (%s)\n", p.Length, dwarfFormat(p.Dwarf64))
+		fmt.Fprintf(w, "  DWARF Version:\t%d\n", p.Version)
+		if p.Version >= 5 {
+			fmt.Fprintf(w, "  Address size (bytes):\t%d\n", p.AddrSize)
+			fmt.Fprintf(w, "  Segment selector (bytes):\t%d\n", p.SegSelSize)
+		}
+		fmt.Fprintf(w, "  Prologue Length:\t%d\n", p.HeaderLength)
+		fmt.Fprintf(w, "  Minimum Instruction Length:\t%d\n", p.MinInstLength)
+		if p.Version >= 4 {
+			fmt.Fprintf(w, "  Maximum Ops per Instruction:\t%d\n", p.MaxOpsPerInst)
+		}
+		fmt.Fprintf(w, "  Initial value of 'is_stmt':\t%t\n", p.DefaultIsStmt)
+		fmt.Fprintf(w, "  Line Base:\t%d\n", p.LineBase)
+		fmt.Fprintf(w, "  Line Range:\t%d\n", p.LineRange)
+		fmt.Fprintf(w, "  Opcode Base:\t%d\n", p.OpcodeBase)
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("\n Opcodes:\n")
+		for i, n := range p.OpcodeLengths {
+			fmt.Printf("  Opcode %d has %d args\n", i+1, n)
+		}
+
+		fmt.Printf("\n The Directory Table:\n")
+		for i, d := range p.Dirs {
+			if p.Version < 5 && i == 0 {
+				continue
+			}
+			fmt.Printf("  %d\t%s\n", i, d.Name)
+		}
+		fmt.Printf("\n The File Name Table:\n  Entry\tDir\tTime\tSize\tName\n")
+		for i, e := range p.Files {
+			if p.Version < 5 && i == 0 {
+				continue
+			}
+			fmt.Printf("  %d\t%d\t%d\t%d\t%s", i, e.Dir, e.MTime, e.Size, e.Name)
+			if e.MD5 != nil {
+				fmt.Printf("\tMD5 %x", e.MD5)
+			}
+			fmt.Println()
+		}
+
+		fmt.Printf("\n Line Number Statements:\n")
+		for _, op := range p.Ops() {
+			fmt.Printf("  [0x%08x]  %s\n", op.Offset, op.Text)
+		}
+		fmt.Println()
+	}
+	if err != nil {
+		dumpError(err)
+	}
+}
+
+func debugStrInf(s *debuginfo.Sections) {
+	for _, name := range []string{".debug_str", ".debug_line_str"} {
+		data := s.Data(name)
+		if data == nil {
+			continue
+		}
+		fmt.Printf("Contents of the %s section:\n\n", name)
+		for off := 0; off < len(data); {
+			end := off
+			for end < len(data) && data[end] != 0 {
+				end++
+			}
+			fmt.Printf("  0x%08x %s\n", off, data[off:end])
+			off = end + 1
+		}
+		fmt.Println()
+	}
+}
+
+func debugArangesInf(s *debuginfo.Sections) {
+	if s.Data(".debug_aranges") == nil {
+		return
+	}
+	fmt.Printf("Contents of the .debug_aranges section:\n\n")
+	sets, err := s.ARanges()
+	for _, set := range sets {
+		fmt.Printf("  Length:                   %d (%s)\n", set.Length, dwarfFormat(set.Dwarf64))
+		fmt.Printf("  Version:                  %d\n", set.Version)
+		fmt.Printf("  Offset into .debug_info:  0x%x\n", set.InfoOffset)
+		fmt.Printf("  Pointer Size:             %d\n", set.AddrSize)
+		fmt.Printf("  Segment Size:             %d\n\n", set.SegSize)
+		fmt.Printf("    Address            Length\n")
+		for _, r := range set.Ranges {
+			fmt.Printf("    %016x %016x\n", r[0], r[1])
+		}
+		fmt.Println()
+	}
+	if err != nil {
+		dumpError(err)
+	}
+}
+
+func debugRangesInf(s *debuginfo.Sections, addrSize int) {
+	if s.Data(".debug_ranges") == nil {
+		return
+	}
+	fmt.Printf("Contents of the .debug_ranges section:\n\n")
+	fmt.Printf("    Offset   Begin            End\n")
+	entries, err := s.Ranges(addrSize)
+	max := ^uint64(0) >> (64 - 8*uint(addrSize))
+	for _, e := range entries {
+		switch {
+		case e.Begin == 0 && e.End == 0:
+			fmt.Printf("    %08x <End of list>\n", e.Offset)
+		case e.Begin == max:
+			fmt.Printf("    %08x %016x (base address)\n", e.Offset, e.End)
+		default:
+			fmt.Printf("    %08x %016x %016x\n", e.Offset, e.Begin, e.End)
+		}
+	}
+	if err != nil {
+		dumpError(err)
+	}
+	fmt.Println()
+}
+
+func listTableInf(name string, tables []debuginfo.ListTable, err error, s *debuginfo.Sections) {
+	fmt.Printf("Contents of the %s section:\n\n", name)
+	for _, t := range tables {
+		fmt.Printf("  Table at Offset 0x%x:\n", t.Offset)
+		fmt.Printf("   Length:          0x%x (%s)\n", t.Length, dwarfFormat(t.Dwarf64))
+		fmt.Printf("   DWARF version:   %d\n", t.Version)
+		fmt.Printf("   Address size:    %d\n", t.AddrSize)
+		fmt.Printf("   Segment size:    %d\n", t.SegSize)
+		fmt.Printf("   Offset entries:  %d\n", len(t.Offsets))
+		for i, o := range t.Offsets {
+			fmt.Printf("    [%6d] 0x%x\n", i, t.Base+o)
+		}
+		fmt.Println()
+		fmt.Printf("    Offset   Entry\n")
+		for _, e := range t.Entries {
+			fmt.Printf("    %08x %s", e.Offset, e.Name)
+			for _, a := range e.Args {
+				fmt.Printf(" 0x%x", a)
+			}
+			if e.Expr != nil {
+				fmt.Printf(" (%s)", debuginfo.FormatExpr(e.Expr, s.Order, t.AddrSize, t.Dwarf64))
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+	if err != nil {
+		dumpError(err)
+	}
+}
+
+func debugRngListsInf(s *debuginfo.Sections) {
+	if s.Data(".debug_rnglists") == nil {
+		return
+	}
+	tables, err := s.RngLists()
+	listTableInf(".debug_rnglists", tables, err, s)
+}
+
+func debugLocListsInf(s *debuginfo.Sections, addrSize int) {
+	views := s.LocViews()
+	if s.Data(".debug_loclists") != nil {
+		tables, err := s.LocLists(views)
+		listTableInf(".debug_loclists", tables, err, s)
+	}
+	// DWARF 4 location lists
+	if s.Data(".debug_loc") == nil {
+		return
+	}
+	fmt.Printf("Contents of the .debug_loc section:\n\n")
+	fmt.Printf("    Offset   Begin            End              Expression\n")
+	entries, err := s.Locs(addrSize, views)
+	max := ^uint64(0) >> (64 - 8*uint(addrSize))
+	for _, e := range entries {
+		switch {
+		case e.View:
+			fmt.Printf("    %08x view pair %d %d\n", e.Offset, e.Begin, e.End)
+		case e.Begin == 0 && e.End == 0:
+			fmt.Printf("    %08x <End of list>\n", e.Offset)
+		case e.Begin == max:
+			fmt.Printf("    %08x %016x (base address)\n", e.Offset, e.End)
+		default:
+			fmt.Printf("    %08x %016x %016x (%s)\n", e.Offset, e.Begin, e.End, debuginfo.FormatExpr(e.Expr, s.Order, addrSize, false))
+		}
+	}
+	if err != nil {
+		dumpError(err)
+	}
+	fmt.Println()
+}
+
+func debugFrameInf(f *file.File, s *debuginfo.Sections, addrSize int, interp bool) {
+	regName := cfi.RegNames(f.Machine)
+	if data := s.Data(".debug_frame"); data != nil {
+		fmt.Printf("Contents of the .debug_frame section:\n\n")
+		sec, err := cfi.ParseDebugFrame(data, f.ByteOrder, addrSize)
+		frameEntriesInf(sec, regName, interp)
+		if err != nil {
+			dumpError(err)
+		}
+	}
+	ehSec := f.Section(".eh_frame")
+	if ehSec == nil || ehSec.Type == elf.SHT_NOBITS {
+		return
+	}
+	fmt.Printf("Contents of the .eh_frame section:\n\n")
+	eh, err := cfi.ParseEHFrame(ehSec.Data(), f.ByteOrder, addrSize, ehSec.Addr)
+	frameEntriesInf(eh, regName, interp)
+	if err != nil {
+		dumpError(err)
+	}
+	if !interp {
+		ehFrameHdrInf(f, eh, addrSize)
+	}
+}
+
+// ehFrameHdrInf prints the binary search table of .eh_frame_hdr and
+// checks it against the FDEs of eh.
+func ehFrameHdrInf(f *file.File, eh *cfi.Section, addrSize int) {
+	hs := f.Section(".eh_frame_hdr")
+	if hs == nil || hs.Type == elf.SHT_NOBITS {
+		return
+	}
+	fmt.Printf("Contents of the .eh_frame_hdr section:\n\n")
+	h, err := cfi.ParseEHFrameHdr(hs.Data(), f.ByteOrder, addrSize, hs.Addr)
+	if h == nil {
+		dumpError(err)
+		return
+	}
+	fmt.Printf("  Version:                %d\n", h.Version)
+	fmt.Printf("  eh_frame_ptr encoding:  0x%02x (%s)\n", h.EHFramePtrEnc, cfi.EncodingName(h.EHFramePtrEnc))
+	fmt.Printf("  fde_count encoding:     0x%02x (%s)\n", h.FDECountEnc, cfi.EncodingName(h.FDECountEnc))
+	fmt.Printf("  Table encoding:         0x%02x (%s)\n", h.TableEnc, cfi.EncodingName(h.TableEnc))
+	fmt.Printf("  eh_frame_ptr:           0x%x\n", h.EHFramePtr)
+	fmt.Printf("  FDE count:              %d\n", h.Count)
+	fmt.Println()
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "  Location:\tFDE:")
+	for _, e := range h.Table {
+		fmt.Fprintf(w, "  %016x\t%016x\n", e.Loc, e.FDE)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println()
+	if err != nil {
+		dumpError(err)
+		return
+	}
+	problems := h.Check(eh)
+	for _, p := range problems {
+		fmt.Printf("  warning: %s\n", p)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("  Table is sorted and matches the %d FDEs of .eh_frame\n", len(eh.FDEs))
+	}
+	fmt.Println()
+}
+
+// frameEntriesInf prints the CIEs and FDEs of a call frame section in
+// section order, with the raw instructions of each, or, if interp is
+// set, the unwind table they evaluate to.
+func frameEntriesInf(sec *cfi.Section, regName func(uint64) string, interp bool) {
+	type entry struct {
+		off uint64
+		cie *cfi.CIE
+		fde *cfi.FDE
+	}
+	var entries []entry
+	for _, c := range sec.CIEs {
+		entries = append(entries, entry{off: c.Offset, cie: c})
+	}
+	for _, fd := range sec.FDEs {
+		entries = append(entries, entry{off: fd.Offset, fde: fd})
+	}
+	for _, z := range sec.Zero {
+		entries = append(entries, entry{off: z})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].off < entries[j].off })
+
+	for _, e := range entries {
+		switch {
+		case e.cie != nil:
+			c := e.cie
+			fmt.Printf("%08x %016x CIE\n", c.Offset, c.Length)
+			fmt.Printf("  Version:               %d\n", c.Version)
+			fmt.Printf("  Augmentation:          %q\n", c.Augmentation)
+			fmt.Printf("  Code alignment factor: %d\n", c.CodeAlign)
+			fmt.Printf("  Data alignment factor: %d\n", c.DataAlign)
+			fmt.Printf("  Return address column: %d\n", c.RAReg)
+			if c.AugmentationData != nil {
+				fmt.Printf("  Augmentation data:     %s\n", hexBytes(c.AugmentationData))
+				fmt.Printf("  FDE encoding:          %s\n", cfi.EncodingName(c.FDEEncoding))
+				if c.LSDAEncoding != cfi.EncOmit {
+					fmt.Printf("  LSDA encoding:         %s\n", cfi.EncodingName(c.LSDAEncoding))
+				}
+				if c.HasPersonality {
+					fmt.Printf("  Personality:           0x%x (%s)\n", c.Personality, cfi.EncodingName(c.PersonalityEncoding))
+				}
+				if c.SignalFrame {
+					fmt.Printf("  Signal frame\n")
+				}
+			}
+			fmt.Println()
+			if !interp {
+				cfaInstsInf(c, c.Instructions, 0, regName)
+				fmt.Println()
+			}
+		case e.fde != nil:
+			fd := e.fde
+			fmt.Printf("%08x %016x FDE cie=%08x pc=%016x..%016x\n", fd.Offset, fd.Length, fd.CIE.Offset, fd.Begin, fd.Begin+fd.Range)
+			if len(fd.AugmentationData) > 0 {
+				fmt.Printf("  Augmentation data:     %s\n", hexBytes(fd.AugmentationData))
+			}
+			if fd.HasLSDA {
+				fmt.Printf("  LSDA:                  0x%x\n", fd.LSDA)
+			}
+			if interp {
+				unwindTableInf(fd, regName)
+			} else {
+				cfaInstsInf(fd.CIE, fd.Instructions, fd.Begin, regName)
+			}
+			fmt.Println()
+		default:
+			fmt.Printf("%08x ZERO terminator\n", e.off)
+			fmt.Println()
+		}
+	}
+}
+
+func hexBytes(b []byte) string {
+	s := make([]string, len(b))
+	for i, c := range b {
+		s[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(s, " ")
+}
+
+func cfaInstsInf(c *cfi.CIE, b []byte, loc uint64, regName func(uint64) string) {
+	insts, err := c.Decode(b)
+	for _, line := range c.Format(insts, loc, regName) {
+		fmt.Printf("  %s\n", line)
+	}
+	if err != nil {
+		fmt.Printf("  error: %v\n", err)
+	}
+}
+
+// unwindTableInf prints the rows of the unwind table of fd, one column
+// per register that has a rule in any of them, in the manner of
+// readelf --debug-dump=frames-interp.
+func unwindTableInf(fd *cfi.FDE, regName func(uint64) string) {
+	rows, err := cfi.Table(fd)
+	name := func(r uint64) string {
+		if n := regName(r); n != "" {
+			return n
+		}
+		return fmt.Sprintf("r%d", r)
+	}
+	used := make(map[uint64]bool)
+	for _, row := range rows {
+		for r := range row.Regs {
+			used[r] = true
+		}
+	}
+	var regs []uint64
+	for r := range used {
+		regs = append(regs, r)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i] < regs[j] })
+
+	// set tabwriter padding 2
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "   LOC\tCFA")
+	for _, r := range regs {
+		if r == fd.CIE.RAReg {
+			fmt.Fprint(w, "\tra")
+		} else {
+			fmt.Fprintf(w, "\t%s", name(r))
+		}
+	}
+	fmt.Fprintln(w)
+	for _, row := range rows {
+		cfa := "exp"
+		if row.CFA.Expr == nil {
+			cfa = fmt.Sprintf("%s%+d", name(row.CFA.Reg), row.CFA.Offset)
+		}
+		fmt.Fprintf(w, "%016x\t%s", row.Loc, cfa)
+		for _, r := range regs {
+			fmt.Fprintf(w, "\t%s", ruleInf(row.Regs[r], name))
+		}
+		fmt.Fprintln(w)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err != nil {
+		fmt.Printf("  error: %v\n", err)
+	}
+}
+
+func ruleInf(r cfi.Rule, name func(uint64) string) string {
+	switch r.Kind {
+	case cfi.RuleSameValue:
+		return "s"
+	case cfi.RuleOffset:
+		return fmt.Sprintf("c%+d", r.Offset)
+	case cfi.RuleValOffset:
+		return fmt.Sprintf("v%+d", r.Offset)
+	case cfi.RuleRegister:
+		return name(r.Reg)
+	case cfi.RuleExpression:
+		return "exp"
+	case cfi.RuleValExpression:
+		return "vexp"
+	}
+	return "u"
+}
@@ -0,0 +1,412 @@
+package options
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/elf"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// DiffOptions selects items that DiffInf leaves out of the comparison
+// because they are known to differ between otherwise identical builds.
+type DiffOptions struct {
+	// IgnoreBuildID skips the GNU build-id note and the contents of
+	// the section holding it.
+	IgnoreBuildID bool
+	// IgnoreSections lists sections whose contents are not compared.
+	// Their headers are still compared.
+	IgnoreSections []string
+}
+
+// DiffInf compares a and b field by field and prints every difference.
+// It reports whether any difference was found.
+func DiffInf(a, b *elf.File, opts DiffOptions) bool {
+	d := &differ{a: a, b: b, opts: opts}
+	d.header()
+	d.progs()
+	d.sections()
+	d.contents()
+	d.symbols("Symbol Table", (*elf.File).Symbols)
+	d.symbols("Dynamic Symbol Table", (*elf.File).DynamicSymbols)
+	d.dynamic()
+	d.notes()
+
+	if !d.found {
+		fmt.Println("Files are identical")
+	}
+	return d.found
+}
+
+type differ struct {
+	a, b  *elf.File
+	opts  DiffOptions
+	found bool
+
+	// current report block
+	title string
+	w     *tabwriter.Writer
+}
+
+// begin starts a new report block; the title is printed lazily
+// once the block has something to show.
+func (d *differ) begin(title string) {
+	d.title = title
+	// set tabwriter width 8
+	d.w = tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+}
+
+func (d *differ) end() {
+	// refresh Write
+	if err := d.w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (d *differ) report(format string, args ...interface{}) {
+	if d.title != "" {
+		if d.found {
+			fmt.Println()
+		}
+		fmt.Printf("%s:\n", d.title)
+		d.title = ""
+	}
+	d.found = true
+	fmt.Fprintf(d.w, "  "+format+"\n", args...)
+}
+
+func (d *differ) field(what string, va, vb interface{}) {
+	sa, sb := fmt.Sprint(va), fmt.Sprint(vb)
+	if sa != sb {
+		d.report("%s\t%s\t%s", what, sa, sb)
+	}
+}
+
+func (d *differ) ignored(name string) bool {
+	for _, s := range d.opts.IgnoreSections {
+		if s == name {
+			return true
+		}
+	}
+	return d.opts.IgnoreBuildID && name == ".note.gnu.build-id"
+}
+
+func (d *differ) header() {
+	d.begin("ELF Header")
+	ha, hb := d.a.FileHeader, d.b.FileHeader
+	d.field("Class:", ha.Class, hb.Class)
+	d.field("Data:", ha.Data, hb.Data)
+	d.field("Version:", ha.Version, hb.Version)
+	d.field("OSABI:", ha.OSABI, hb.OSABI)
+	d.field("ABIVersion:", ha.ABIVersion, hb.ABIVersion)
+	d.field("Type:", ha.Type, hb.Type)
+	d.field("Machine:", ha.Machine, hb.Machine)
+	d.field("Entry:", fmt.Sprintf("0x%x", ha.Entry), fmt.Sprintf("0x%x", hb.Entry))
+	d.field("Program headers:", len(d.a.Progs), len(d.b.Progs))
+	d.field("Section headers:", len(d.a.Sections), len(d.b.Sections))
+	d.end()
+}
+
+func (d *differ) progs() {
+	d.begin("Program Headers")
+	n := len(d.a.Progs)
+	if len(d.b.Progs) > n {
+		n = len(d.b.Progs)
+	}
+	for i := 0; i < n; i++ {
+		if i >= len(d.a.Progs) {
+			d.report("[%d]\tonly in second\t%v", i, d.b.Progs[i].Type)
+			continue
+		}
+		if i >= len(d.b.Progs) {
+			d.report("[%d]\tonly in first\t%v", i, d.a.Progs[i].Type)
+			continue
+		}
+		pa, pb := d.a.Progs[i].ProgHeader, d.b.Progs[i].ProgHeader
+		d.field(fmt.Sprintf("[%d] Type:", i), pa.Type, pb.Type)
+		d.field(fmt.Sprintf("[%d] Flags:", i), pa.Flags, pb.Flags)
+		d.field(fmt.Sprintf("[%d] Offset:", i), hexv(pa.Off), hexv(pb.Off))
+		d.field(fmt.Sprintf("[%d] vAddr:", i), hexv(pa.Vaddr), hexv(pb.Vaddr))
+		d.field(fmt.Sprintf("[%d] pAddr:", i), hexv(pa.Paddr), hexv(pb.Paddr))
+		d.field(fmt.Sprintf("[%d] fSize:", i), pa.Filesz, pb.Filesz)
+		d.field(fmt.Sprintf("[%d] mSize:", i), pa.Memsz, pb.Memsz)
+		d.field(fmt.Sprintf("[%d] Alignment:", i), pa.Align, pb.Align)
+	}
+	d.end()
+}
+
+// sectionPairs matches the sections of a and b by name. Repeated
+// names are paired in order of appearance.
+func (d *differ) sectionPairs() (pairs [][2]*elf.Section, onlyA, onlyB []*elf.Section) {
+	byName := make(map[string][]*elf.Section)
+	for _, s := range d.b.Sections {
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	for _, s := range d.a.Sections {
+		if l := byName[s.Name]; len(l) > 0 {
+			pairs = append(pairs, [2]*elf.Section{s, l[0]})
+			byName[s.Name] = l[1:]
+		} else {
+			onlyA = append(onlyA, s)
+		}
+	}
+	for _, s := range d.b.Sections {
+		if l := byName[s.Name]; len(l) > 0 && l[0] == s {
+			onlyB = append(onlyB, s)
+			byName[s.Name] = l[1:]
+		}
+	}
+	return pairs, onlyA, onlyB
+}
+
+func sectionLabel(s *elf.Section) string {
+	if s.Name == "" {
+		return "Nil"
+	}
+	return s.Name
+}
+
+func (d *differ) sections() {
+	d.begin("Section Headers")
+	pairs, onlyA, onlyB := d.sectionPairs()
+	for _, s := range onlyA {
+		d.report("%s\tonly in first\t", sectionLabel(s))
+	}
+	for _, s := range onlyB {
+		d.report("%s\tonly in second\t", sectionLabel(s))
+	}
+	for _, p := range pairs {
+		sa, sb := p[0].SectionHeader, p[1].SectionHeader
+		name := sectionLabel(p[0])
+		d.field(name+" Type:", sa.Type, sb.Type)
+		d.field(name+" Flags:", sa.Flags, sb.Flags)
+		d.field(name+" Addr:", hexv(sa.Addr), hexv(sb.Addr))
+		d.field(name+" Offset:", hexv(sa.Offset), hexv(sb.Offset))
+		d.field(name+" Size:", sa.Size, sb.Size)
+		d.field(name+" Link:", d.linkName(d.a, sa.Link), d.linkName(d.b, sb.Link))
+		d.field(name+" Info:", d.infoValue(d.a, p[0]), d.infoValue(d.b, p[1]))
+		d.field(name+" Align:", sa.Addralign, sb.Addralign)
+		d.field(name+" EntSize:", sa.Entsize, sb.Entsize)
+	}
+	d.end()
+}
+
+// linkName resolves a section link to a name so that a shifted
+// section index does not show up as a difference on its own.
+func (d *differ) linkName(f *elf.File, link uint32) string {
+	if link == 0 || int(link) >= len(f.Sections) {
+		return fmt.Sprint(link)
+	}
+	return f.Sections[link].Name
+}
+
+// infoValue spells the sh_info of s: the section a relocation section
+// applies to is named like a link, other values such as the number of
+// local symbols of a symbol table are numbers.
+func (d *differ) infoValue(f *elf.File, s *elf.Section) string {
+	if s.Type == elf.SHT_REL || s.Type == elf.SHT_RELA {
+		return d.linkName(f, s.Info)
+	}
+	return fmt.Sprint(s.Info)
+}
+
+func (d *differ) contents() {
+	d.begin("Section Contents")
+	pairs, _, _ := d.sectionPairs()
+	for _, p := range pairs {
+		sa, sb := p[0], p[1]
+		if d.ignored(sa.Name) || sa.Type == elf.SHT_NOBITS || sb.Type == elf.SHT_NOBITS {
+			continue
+		}
+		off, changed, err := firstDiff(sa.Open(), sb.Open())
+		if err != nil {
+			d.report("%s\tunreadable: %v\t", sectionLabel(sa), err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		ha, hb := sectionHash(sa), sectionHash(sb)
+		d.report("%s\tsha256 %s\tsha256 %s\tfirst difference at 0x%x", sectionLabel(sa), ha, hb, off)
+	}
+	d.end()
+}
+
+// firstDiff returns the offset of the first byte at which ra and rb
+// differ, including the point where the shorter one ends.
+func firstDiff(ra, rb io.Reader) (off uint64, changed bool, err error) {
+	var bufA, bufB [4096]byte
+	for {
+		na, errA := io.ReadFull(ra, bufA[:])
+		nb, errB := io.ReadFull(rb, bufB[:])
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return 0, false, errA
+		}
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return 0, false, errB
+		}
+		n := na
+		if nb < n {
+			n = nb
+		}
+		for i := 0; i < n; i++ {
+			if bufA[i] != bufB[i] {
+				return off + uint64(i), true, nil
+			}
+		}
+		if na != nb {
+			return off + uint64(n), true, nil
+		}
+		if na < len(bufA) {
+			return 0, false, nil
+		}
+		off += uint64(n)
+	}
+}
+
+func sectionHash(s *elf.Section) string {
+	h := sha256.New()
+	if _, err := io.Copy(h, s.Open()); err != nil {
+		return "?"
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+func (d *differ) symbols(title string, read func(*elf.File) ([]elf.Symbol, error)) {
+	d.begin(title)
+	symA, _ := read(d.a)
+	symB, _ := read(d.b)
+
+	// key symbols by name, numbering repeats such as local statics
+	type key struct {
+		name string
+		n    int
+	}
+	index := func(syms []elf.Symbol) (map[key]elf.Symbol, []key) {
+		m := make(map[key]elf.Symbol)
+		seen := make(map[string]int)
+		var order []key
+		for _, s := range syms {
+			if s.Name == "" {
+				continue
+			}
+			name := s.Name + versionSuffix(s)
+			k := key{name, seen[name]}
+			seen[name]++
+			m[k] = s
+			order = append(order, k)
+		}
+		return m, order
+	}
+	ma, orderA := index(symA)
+	mb, orderB := index(symB)
+
+	for _, k := range orderA {
+		sa := ma[k]
+		sb, ok := mb[k]
+		if !ok {
+			d.report("%s\tonly in first\t", k.name)
+			continue
+		}
+		d.field(k.name+" Value:", hexv(sa.Value), hexv(sb.Value))
+		d.field(k.name+" Size:", sa.Size, sb.Size)
+		d.field(k.name+" Type:", elf.ST_TYPE(sa.Info), elf.ST_TYPE(sb.Info))
+		d.field(k.name+" Bind:", elf.ST_BIND(sa.Info), elf.ST_BIND(sb.Info))
+		d.field(k.name+" Vis:", elf.ST_VISIBILITY(sa.Other), elf.ST_VISIBILITY(sb.Other))
+		d.field(k.name+" Ndx:", d.symSection(d.a, sa.Section), d.symSection(d.b, sb.Section))
+	}
+	for _, k := range orderB {
+		if _, ok := ma[k]; !ok {
+			d.report("%s\tonly in second\t", k.name)
+		}
+	}
+	d.end()
+}
+
+func versionSuffix(s elf.Symbol) string {
+	if s.Version == "" {
+		return ""
+	}
+	return "@" + s.Version
+}
+
+// symSection names the section a symbol is defined in, so that symbols
+// are not reported just because section indexes moved.
+func (d *differ) symSection(f *elf.File, ndx elf.SectionIndex) string {
+	if ndx == elf.SHN_UNDEF || ndx >= elf.SHN_LORESERVE || int(ndx) >= len(f.Sections) {
+		return fmt.Sprint(ndx)
+	}
+	return f.Sections[ndx].Name
+}
+
+func (d *differ) dynamic() {
+	d.begin("Dynamic Section")
+	da, errA := dynamicEntries(d.a)
+	db, errB := dynamicEntries(d.b)
+	if errA != nil || errB != nil {
+		d.report("unreadable\t%v\t%v", errA, errB)
+		d.end()
+		return
+	}
+	n := len(da)
+	if len(db) > n {
+		n = len(db)
+	}
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(da):
+			d.report("[%d]\tonly in second\t%v %s", i, db[i].Tag, dynValue(d.b, db[i]))
+		case i >= len(db):
+			d.report("[%d]\tonly in first\t%v %s", i, da[i].Tag, dynValue(d.a, da[i]))
+		default:
+			d.field(fmt.Sprintf("[%d]", i),
+				fmt.Sprintf("%v %s", da[i].Tag, dynValue(d.a, da[i])),
+				fmt.Sprintf("%v %s", db[i].Tag, dynValue(d.b, db[i])))
+		}
+	}
+	d.end()
+}
+
+func (d *differ) notes() {
+	d.begin("Notes")
+	na, nb := d.filterNotes(readNotes(d.a)), d.filterNotes(readNotes(d.b))
+	n := len(na)
+	if len(nb) > n {
+		n = len(nb)
+	}
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(na):
+			d.report("%s %s\tonly in second\t", nb[i].Name, noteTypeName(nb[i]))
+		case i >= len(nb):
+			d.report("%s %s\tonly in first\t", na[i].Name, noteTypeName(na[i]))
+		default:
+			a, b := na[i], nb[i]
+			label := fmt.Sprintf("%s %s", a.Name, noteTypeName(a))
+			d.field(label+" Owner:", a.Name, b.Name)
+			d.field(label+" Type:", noteTypeName(a), noteTypeName(b))
+			if !bytes.Equal(a.Desc, b.Desc) {
+				d.report("%s\t%x\t%x", label+" Desc:", a.Desc, b.Desc)
+			}
+		}
+	}
+	d.end()
+}
+
+func (d *differ) filterNotes(notes []Note) []Note {
+	var out []Note
+	for _, n := range notes {
+		if d.opts.IgnoreBuildID && n.Name == "GNU" && n.Type == ntGNUBuildID {
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func hexv(v uint64) string {
+	return fmt.Sprintf("0x%x", v)
+}
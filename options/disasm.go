@@ -0,0 +1,328 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/disasm"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// DisasmOptions limits what DisassembleInf prints. Zero values
+// disassemble every executable section.
+type DisasmOptions struct {
+	// Symbol limits the listing to the symbols of that name
+	Symbol string
+	// Start and End bound the addresses when End is set; End is
+	// exclusive
+	Start, End uint64
+}
+
+// disasmSym is a symbol that labels code or data.
+type disasmSym struct {
+	Addr, Size uint64
+	Name       string
+	Section    elf.SectionIndex
+	// rank orders symbols at the same address, best first
+	rank int
+}
+
+// disasmSymbols returns the symbols of f worth naming addresses by,
+// from .symtab or else .dynsym, sorted by address and rank.
+func disasmSymbols(f *elf.File) []disasmSym {
+	syms, err := f.Symbols()
+	if err != nil {
+		syms, _ = f.DynamicSymbols()
+	}
+	var out []disasmSym
+	for _, s := range syms {
+		t := elf.ST_TYPE(s.Info)
+		if s.Name == "" || t == elf.STT_SECTION || t == elf.STT_FILE ||
+			s.Section == elf.SHN_UNDEF || s.Section >= elf.SHN_LORESERVE && s.Section != elf.SHN_ABS {
+			continue
+		}
+		// mapping symbols such as $x and $d mark code and data in
+		// AArch64 objects
+		if strings.HasPrefix(s.Name, "$") {
+			continue
+		}
+		rank := 4
+		switch {
+		case t == elf.STT_FUNC && elf.ST_BIND(s.Info) != elf.STB_LOCAL:
+			rank = 0
+		case t == elf.STT_FUNC:
+			rank = 1
+		case t == elf.STT_OBJECT && elf.ST_BIND(s.Info) != elf.STB_LOCAL:
+			rank = 2
+		case elf.ST_BIND(s.Info) != elf.STB_LOCAL:
+			rank = 3
+		}
+		out = append(out, disasmSym{Addr: s.Value, Size: s.Size, Name: symName(s.Name), Section: s.Section, rank: rank})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Addr != out[j].Addr {
+			return out[i].Addr < out[j].Addr
+		}
+		return out[i].rank < out[j].rank
+	})
+	return out
+}
+
+// sectionSyms returns the symbols of section i, or all of syms unless
+// the file is relocatable: symbol values of ET_REL files are offsets
+// into their own section.
+func sectionSyms(f *elf.File, syms []disasmSym, i elf.SectionIndex) []disasmSym {
+	if f.Type != elf.ET_REL {
+		return syms
+	}
+	var out []disasmSym
+	for _, s := range syms {
+		if s.Section == i {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// symAt returns the best symbol at addr, or nil.
+func symAt(syms []disasmSym, addr uint64) *disasmSym {
+	i := sort.Search(len(syms), func(i int) bool { return syms[i].Addr >= addr })
+	if i < len(syms) && syms[i].Addr == addr {
+		return &syms[i]
+	}
+	return nil
+}
+
+// symBefore returns the best of the nearest symbols at or before addr,
+// or nil.
+func symBefore(syms []disasmSym, addr uint64) *disasmSym {
+	i := sort.Search(len(syms), func(i int) bool { return syms[i].Addr > addr })
+	if i == 0 {
+		return nil
+	}
+	// the best ranked of the symbols at that address comes first
+	for i > 1 && syms[i-2].Addr == syms[i-1].Addr {
+		i--
+	}
+	return &syms[i-1]
+}
+
+// symRef spells addr as <name> or <name+0xoff> relative to the
+// nearest symbol at or before it, whatever its size or section as with
+// objdump, or "" if there is none.
+func symRef(syms []disasmSym, addr uint64) string {
+	s := symBefore(syms, addr)
+	if s == nil {
+		return ""
+	}
+	if s.Addr == addr {
+		return "<" + s.Name + ">"
+	}
+	return fmt.Sprintf("<%s+0x%x>", s.Name, addr-s.Addr)
+}
+
+// sectionRelocs returns the relocations applying to section i, sorted
+// by offset, with the symbols they refer to.
+func sectionRelocs(f *elf.File, i elf.SectionIndex) ([]relocEntry, []elf.Symbol) {
+	var out []relocEntry
+	var syms []elf.Symbol
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_REL && s.Type != elf.SHT_RELA || elf.SectionIndex(s.Info) != i {
+			continue
+		}
+		entries, err := relocEntries(f, s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out = append(out, entries...)
+		syms = relocSymbols(f, s)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Offset < out[j].Offset })
+	return out, syms
+}
+
+// relocText spells a relocation as objdump -r does within a listing.
+func relocText(f *elf.File, e relocEntry, syms []elf.Symbol) string {
+	name := ""
+	if int(e.Sym) < len(syms) {
+		s := syms[e.Sym]
+		name = symName(s.Name)
+		if elf.ST_TYPE(s.Info) == elf.STT_SECTION && int(s.Section) < len(f.Sections) {
+			name = f.Sections[s.Section].Name
+		}
+	}
+	switch {
+	case e.Addend > 0:
+		name += fmt.Sprintf("+0x%x", e.Addend)
+	case e.Addend < 0:
+		name += fmt.Sprintf("-0x%x", -e.Addend)
+	}
+	return fmt.Sprintf("%x: %s\t%s", e.Offset, relocTypeName(f, e.Type), name)
+}
+
+// disasmRange is a half-open range of addresses to disassemble.
+type disasmRange struct{ lo, hi uint64 }
+
+// disasmRanges returns the parts of section s, whose symbols are syms,
+// that o selects.
+func disasmRanges(s *elf.Section, syms []disasmSym, o DisasmOptions) []disasmRange {
+	lo, hi := s.Addr, s.Addr+s.Size
+	switch {
+	case o.Symbol != "":
+		var out []disasmRange
+		for i, sym := range syms {
+			if sym.Name != o.Symbol || sym.Addr < lo || sym.Addr >= hi {
+				continue
+			}
+			end := sym.Addr + sym.Size
+			if sym.Size == 0 {
+				// up to the next symbol
+				end = hi
+				for _, n := range syms[i+1:] {
+					if n.Addr > sym.Addr {
+						end = n.Addr
+						break
+					}
+				}
+			}
+			if end > hi {
+				end = hi
+			}
+			out = append(out, disasmRange{sym.Addr, end})
+		}
+		return out
+	case o.End != 0:
+		if o.Start > lo {
+			lo = o.Start
+		}
+		if o.End < hi {
+			hi = o.End
+		}
+		if lo >= hi {
+			return nil
+		}
+	}
+	return []disasmRange{{lo, hi}}
+}
+
+// DisassembleInf prints the instructions of the executable sections of
+// f in the manner of objdump -d, labeling symbols, naming branch
+// targets and data references and, for relocatable files, showing the
+// relocations at each instruction.
+func DisassembleInf(f *elf.File, a disasm.Arch, o DisasmOptions) {
+	all := disasmSymbols(f)
+	var slots map[uint64]string
+	if f.Type != elf.ET_REL {
+		// calls into the PLT are named by the symbols the stubs reach
+		all = append(all, pltSymbols(f)...)
+		sort.SliceStable(all, func(i, j int) bool { return all[i].Addr < all[j].Addr })
+		// and loads from the GOT by those its slots are bound to
+		slots = gotSlotNames(f)
+	}
+	addrWidth := 16
+	if f.Class == elf.ELFCLASS32 {
+		addrWidth = 8
+	}
+	comment := "#"
+	if a == disasm.ARM64 {
+		comment = "//"
+	}
+	for i, s := range f.Sections {
+		if s.Flags&elf.SHF_EXECINSTR == 0 || s.Type == elf.SHT_NOBITS {
+			continue
+		}
+		syms := sectionSyms(f, all, elf.SectionIndex(i))
+		ranges := disasmRanges(s, syms, o)
+		if len(ranges) == 0 {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			log.Fatal(err)
+		}
+		var relocs []relocEntry
+		var relocSyms []elf.Symbol
+		if f.Type == elf.ET_REL {
+			relocs, relocSyms = sectionRelocs(f, elf.SectionIndex(i))
+		}
+
+		fmt.Printf("\nDisassembly of section %s:\n", s.Name)
+		d := disasm.NewDecoder(a)
+		for _, r := range ranges {
+			pc := r.lo
+			if symAt(syms, pc) == nil {
+				// a range is labeled by symbols of its own section only
+				var ref string
+				if sym := symBefore(syms, pc); sym != nil && sym.Section == elf.SectionIndex(i) {
+					ref = symRef(syms, pc)
+				}
+				if ref == "" {
+					ref = fmt.Sprintf("<%s+0x%x>", s.Name, pc-s.Addr)
+					if pc == s.Addr {
+						ref = "<" + s.Name + ">"
+					}
+				}
+				fmt.Printf("\n%0*x %s:\n", addrWidth, pc, ref)
+			}
+			for pc < r.hi {
+				if sym := symAt(syms, pc); sym != nil {
+					fmt.Printf("\n%0*x <%s>:\n", addrWidth, pc, sym.Name)
+					d.Reset()
+				}
+				b := data[pc-s.Addr : r.hi-s.Addr]
+				inst := d.Decode(b, pc)
+				if inst.Len > len(b) || inst.Len == 0 {
+					inst = disasm.Inst{Len: len(b), Op: "(bad)"}
+				}
+				text := inst.String()
+				if inst.HasTarget {
+					if ref := symRef(syms, inst.Target); ref != "" {
+						text += " " + ref
+					}
+				}
+				// pages that adrp loads in a relocatable file are
+				// placeholders until the relocations apply
+				if inst.HasRef && !(a == disasm.ARM64 && f.Type == elf.ET_REL) {
+					text += fmt.Sprintf("        %s %x", comment, inst.Ref)
+					if name, ok := slots[inst.Ref]; ok {
+						text += " <" + name + ">"
+					} else if ref := symRef(syms, inst.Ref); ref != "" {
+						text += " " + ref
+					}
+				}
+				printInst(a, pc, b[:inst.Len], text)
+				for len(relocs) > 0 && relocs[0].Offset < pc+uint64(inst.Len) {
+					if relocs[0].Offset >= pc {
+						fmt.Printf("\t\t\t%s\n", relocText(f, relocs[0], relocSyms))
+					}
+					relocs = relocs[1:]
+				}
+				pc += uint64(inst.Len)
+			}
+		}
+	}
+}
+
+// printInst prints one instruction with its bytes: seven to a line
+// for x86 and as a word for AArch64.
+func printInst(a disasm.Arch, pc uint64, b []byte, text string) {
+	if a == disasm.ARM64 && len(b) == 4 {
+		w := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+		fmt.Printf("%8x:\t%08x \t%s\n", pc, w, text)
+		return
+	}
+	n := len(b)
+	if n > 7 {
+		n = 7
+	}
+	fmt.Printf("%8x:\t%-21s\t%s\n", pc, hexBytes(b[:n])+" ", text)
+	for b = b[n:]; len(b) > 0; b = b[n:] {
+		pc += uint64(n)
+		n = len(b)
+		if n > 7 {
+			n = 7
+		}
+		fmt.Printf("%8x:\t%s \n", pc, hexBytes(b[:n]))
+	}
+}
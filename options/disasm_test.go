@@ -0,0 +1,68 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/disasm"
+	"os"
+	"strings"
+	"testing"
+)
+
+// disassembly returns what DisassembleInf prints for the file name.
+func disassembly(t *testing.T, name string) string {
+	t.Helper()
+	f, err := elf.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	a, err := disasm.ForMachine(f.Machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	DisassembleInf(f, a, DisasmOptions{})
+	os.Stdout = stdout
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestDisassembleGolden compares the disassembly of sample files with
+// the output of objdump. test.x86-64.golden is that of objdump -d -M
+// intel for sample/test without its header, and with the @Base that
+// it appends to unversioned dynamic symbols removed.
+func TestDisassembleGolden(t *testing.T) {
+	for _, tt := range []struct{ file, golden string }{
+		{"../sample/test", "testdata/test.x86-64.golden"},
+		{"testdata/arm64.o", "testdata/arm64.golden"},
+	} {
+		t.Run(tt.golden, func(t *testing.T) {
+			want, err := os.ReadFile(tt.golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(disassembly(t, tt.file), "\n")
+			lines := strings.Split(string(want), "\n")
+			for i, line := range lines {
+				if i >= len(got) {
+					t.Fatalf("%s: output ends before line %d: %q", tt.file, i+1, line)
+				}
+				if got[i] != line {
+					t.Fatalf("%s: line %d:\ngot:  %q\nwant: %q", tt.file, i+1, got[i], line)
+				}
+			}
+			if len(got) > len(lines) {
+				t.Fatalf("%s: extra output from line %d: %q", tt.file, len(lines)+1, got[len(lines)])
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package options
+
+import (
+	"debug/elf"
+	"fmt"
+)
+
+type dynEntry struct {
+	Tag elf.DynTag
+	Val uint64
+}
+
+// dynamicEntries decodes the .dynamic section of f up to DT_NULL.
+// It returns nil if f is not dynamically linked.
+func dynamicEntries(f *elf.File) ([]dynEntry, error) {
+	ds := f.SectionByType(elf.SHT_DYNAMIC)
+	if ds == nil {
+		return nil, nil
+	}
+	d, err := ds.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []dynEntry
+	switch f.Class {
+	case elf.ELFCLASS32:
+		for len(d) >= 8 {
+			e := dynEntry{elf.DynTag(f.ByteOrder.Uint32(d[0:4])), uint64(f.ByteOrder.Uint32(d[4:8]))}
+			d = d[8:]
+			if e.Tag == elf.DT_NULL {
+				break
+			}
+			entries = append(entries, e)
+		}
+	case elf.ELFCLASS64:
+		for len(d) >= 16 {
+			e := dynEntry{elf.DynTag(f.ByteOrder.Uint64(d[0:8])), f.ByteOrder.Uint64(d[8:16])}
+			d = d[16:]
+			if e.Tag == elf.DT_NULL {
+				break
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// dynValue formats the value of a dynamic entry, resolving the tags
+// whose value is an offset into the dynamic string table.
+func dynValue(f *elf.File, e dynEntry) string {
+	switch e.Tag {
+	case elf.DT_NEEDED, elf.DT_SONAME, elf.DT_RPATH, elf.DT_RUNPATH,
+		elf.DT_AUXILIARY, elf.DT_FILTER, elf.DT_CONFIG, elf.DT_DEPAUDIT, elf.DT_AUDIT:
+		if s, ok := dynString(f, e.Val); ok {
+			return s
+		}
+	}
+	return fmt.Sprintf("0x%x", e.Val)
+}
+
+// dynString reads the string at off in the string table linked
+// from the .dynamic section.
+func dynString(f *elf.File, off uint64) (string, bool) {
+	ds := f.SectionByType(elf.SHT_DYNAMIC)
+	if ds == nil || int(ds.Link) >= len(f.Sections) {
+		return "", false
+	}
+	str, err := f.Sections[ds.Link].Data()
+	if err != nil || off >= uint64(len(str)) {
+		return "", false
+	}
+	return cString(str[off:]), true
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
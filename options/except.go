@@ -0,0 +1,223 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/cfi"
+	"elfreader/file"
+	"elfreader/lookup"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// exceptInf prints, for every FDE of .eh_frame with an LSDA, the
+// call-site, action and type tables of its function.
+func exceptInf(f *file.File, addrSize int) {
+	ehSec := f.Section(".eh_frame")
+	if ehSec == nil || ehSec.Type == elf.SHT_NOBITS {
+		return
+	}
+	eh, err := cfi.ParseEHFrame(ehSec.Data(), f.ByteOrder, addrSize, ehSec.Addr)
+	if err != nil {
+		dumpError(err)
+	}
+	var fdes []*cfi.FDE
+	for _, fd := range eh.FDEs {
+		if fd.HasLSDA {
+			fdes = append(fdes, fd)
+		}
+	}
+	if len(fdes) == 0 {
+		return
+	}
+	sort.Slice(fdes, func(i, j int) bool { return fdes[i].Begin < fdes[j].Begin })
+
+	gcc := f.Section(".gcc_except_table")
+	var data []byte
+	if gcc != nil && gcc.Type != elf.SHT_NOBITS {
+		data = gcc.Data()
+	}
+	x := lookup.New(f, 0)
+	fmt.Printf("Contents of the .gcc_except_table section:\n\n")
+	for _, fd := range fdes {
+		fmt.Printf("%08x FDE pc=%016x..%016x", fd.Offset, fd.Begin, fd.Begin+fd.Range)
+		if r := x.Lookup(fd.Begin); r.Symbol != nil && r.Exact {
+			fmt.Printf(" %s", symName(r.Symbol.Name))
+		}
+		fmt.Println()
+		if gcc == nil {
+			dumpError(fmt.Errorf("LSDA 0x%x, but there is no .gcc_except_table", fd.LSDA))
+			continue
+		}
+		l, err := cfi.ParseLSDA(data, f.ByteOrder, addrSize, gcc.Addr, fd.LSDA, fd.Begin)
+		if l != nil {
+			lsdaInf(f, l, x, addrSize)
+		}
+		if err != nil {
+			dumpError(err)
+			continue
+		}
+		fmt.Println()
+	}
+}
+
+func lsdaInf(f *file.File, l *cfi.LSDA, x *lookup.Index, addrSize int) {
+	fmt.Printf("  LSDA:                  0x%x\n", l.Addr)
+	if l.LPStartEnc != cfi.EncOmit {
+		fmt.Printf("  Landing pad base:      0x%x (%s)\n", l.LPStart, cfi.EncodingName(l.LPStartEnc))
+	} else {
+		fmt.Printf("  Landing pad base:      0x%x (function start)\n", l.LPStart)
+	}
+	if l.TTypeEnc != cfi.EncOmit {
+		fmt.Printf("  Type table base:       0x%x (%s)\n", l.TTBase, cfi.EncodingName(l.TTypeEnc))
+	} else {
+		fmt.Printf("  Type table base:       none\n")
+	}
+	fmt.Printf("  Call-site encoding:    %s\n", cfi.EncodingName(l.CallSiteEnc))
+	fmt.Println()
+
+	types := make([]string, len(l.Types))
+	for i, t := range l.Types {
+		types[i] = typeinfoName(f, x, t, l.TTypeEnc&cfi.EncIndirect != 0, addrSize)
+	}
+	actions := make(map[uint64]cfi.Action, len(l.Actions))
+	for _, a := range l.Actions {
+		actions[a.Offset] = a
+	}
+	filterInf := func(filter int64) string {
+		switch {
+		case filter == 0:
+			return "cleanup"
+		case filter > 0:
+			if int(filter) <= len(types) {
+				return "catch " + types[filter-1]
+			}
+			return fmt.Sprintf("catch type %d", filter)
+		}
+		var names []string
+		for _, t := range l.Specs[filter] {
+			if int(t) <= len(types) {
+				names = append(names, types[t-1])
+			} else {
+				names = append(names, fmt.Sprintf("type %d", t))
+			}
+		}
+		return "throw(" + strings.Join(names, ", ") + ")"
+	}
+
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Start:\tEnd:\tLanding pad:\tActions:")
+	for _, c := range l.CallSites {
+		lp := "-"
+		if c.LandingPad != 0 {
+			lp = fmt.Sprintf("%016x", c.LandingPad)
+		}
+		what := "cleanup"
+		switch {
+		case c.LandingPad == 0:
+			what = "none"
+		case c.Action != 0:
+			var chain []string
+			seen := make(map[uint64]bool)
+			for a, ok := actions[c.Action-1]; ok && !seen[a.Offset]; a, ok = actions[a.Next] {
+				seen[a.Offset] = true
+				chain = append(chain, filterInf(a.Filter))
+				if !a.HasNext {
+					break
+				}
+			}
+			what = fmt.Sprintf("0x%x: %s", c.Action-1, strings.Join(chain, "; "))
+		}
+		fmt.Fprintf(w, "  %016x\t%016x\t%s\t%s\n", c.Start, c.Start+c.Len, lp, what)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(l.Actions) > 0 {
+		fmt.Println()
+		fmt.Println("  Action table:")
+		for _, a := range l.Actions {
+			next := "end"
+			if a.HasNext {
+				next = fmt.Sprintf("0x%x", a.Next)
+			}
+			fmt.Printf("    0x%x: filter %d (%s), next %s\n", a.Offset, a.Filter, filterInf(a.Filter), next)
+		}
+	}
+	if len(l.Types) > 0 {
+		fmt.Println()
+		fmt.Println("  Type table:")
+		for i, t := range l.Types {
+			fmt.Printf("    %d: 0x%x %s\n", i+1, t, types[i])
+		}
+	}
+}
+
+// typeinfoName names the typeinfo object of a type table entry. An
+// indirect entry points to a DW.ref slot holding the address of the
+// object.
+func typeinfoName(f *file.File, x *lookup.Index, v uint64, indirect bool, addrSize int) string {
+	if v == 0 {
+		return "..."
+	}
+	if indirect {
+		if r := x.Lookup(v); r.Symbol != nil && r.Exact && strings.HasPrefix(r.Symbol.Name, "DW.ref.") {
+			return symName(strings.TrimPrefix(r.Symbol.Name, "DW.ref."))
+		}
+		b, err := x.Read(v, addrSize)
+		if err != nil {
+			return fmt.Sprintf("*0x%x", v)
+		}
+		p := uint64(f.ByteOrder.Uint32(b))
+		if addrSize == 8 {
+			p = f.ByteOrder.Uint64(b)
+		}
+		if p == 0 {
+			// filled in by a dynamic relocation
+			if name := dynRelocSymbol(f, v); name != "" {
+				return symName(name)
+			}
+			return fmt.Sprintf("*0x%x", v)
+		}
+		v = p
+	}
+	if r := x.Lookup(v); r.Symbol != nil && r.Exact {
+		return symName(r.Symbol.Name)
+	}
+	return fmt.Sprintf("0x%x", v)
+}
+
+// dynRelocSymbol returns the name of the symbol a dynamic relocation
+// stores at addr, or "".
+func dynRelocSymbol(f *file.File, addr uint64) string {
+	var syms []file.Symbol
+	for _, s := range f.Sections {
+		if s.Flags&elf.SHF_ALLOC == 0 || s.Type != elf.SHT_RELA && s.Type != elf.SHT_REL {
+			continue
+		}
+		entries, err := decodeRelocs(f.Class, f.ByteOrder, s.Data(), s.Type == elf.SHT_RELA)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if e.Offset != addr || e.Sym == 0 {
+				continue
+			}
+			if syms == nil {
+				if syms, err = f.DynamicSymbols(); err != nil {
+					return ""
+				}
+			}
+			if int(e.Sym) <= len(syms) {
+				return syms[e.Sym-1].Name
+			}
+		}
+	}
+	return ""
+}
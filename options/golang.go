@@ -0,0 +1,150 @@
+package options
+
+import (
+	"elfreader/file"
+	"elfreader/gobin"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"text/tabwriter"
+)
+
+// GoOptions selects the parts of a Go binary printed by GoInf besides
+// its build information.
+type GoOptions struct {
+	// Funcs lists the functions of the pclntab
+	Funcs bool
+	// Sizes breaks the size of the binary down by package
+	Sizes bool
+	// Lines selects the functions whose line tables are printed
+	Lines *regexp.Regexp
+}
+
+func moduleInf(m *debug.Module) string {
+	s := m.Path
+	if m.Version != "" {
+		s += " " + m.Version
+	}
+	if m.Sum != "" {
+		s += " " + m.Sum
+	}
+	if m.Replace != nil {
+		s += " => " + moduleInf(m.Replace)
+	}
+	return s
+}
+
+// GoInf prints the Go build information, build ID and pclntab summary
+// of the Go binary f, opened from fName, and the parts o asks for.
+func GoInf(f *file.File, fName string, o GoOptions) {
+	if !gobin.IsGo(f) {
+		fmt.Fprintf(os.Stderr, "error: %s: not a Go binary\n", fName)
+		os.Exit(1)
+	}
+	if bi, err := gobin.BuildInfo(f, fName); err != nil {
+		fmt.Printf("Build info:     %v\n", err)
+	} else {
+		fmt.Printf("Go version:     %s\n", bi.GoVersion)
+		fmt.Printf("Path:           %s\n", bi.Path)
+		if bi.Main.Path != "" {
+			fmt.Printf("Main module:    %s\n", moduleInf(&bi.Main))
+		}
+		if len(bi.Deps) > 0 {
+			fmt.Println("Dependencies:")
+			for _, d := range bi.Deps {
+				fmt.Printf("  %s\n", moduleInf(d))
+			}
+		}
+		if len(bi.Settings) > 0 {
+			fmt.Println("Build settings:")
+			for _, s := range bi.Settings {
+				fmt.Printf("  %s=%s\n", s.Key, s.Value)
+			}
+		}
+	}
+	if id := gobin.BuildID(f); id != "" {
+		fmt.Printf("Go build ID:    %s\n", id)
+	}
+
+	t, err := gobin.ReadPCLNTab(f)
+	if err != nil {
+		fmt.Printf("pclntab:        %v\n", err)
+		return
+	}
+	fmt.Printf("pclntab:        0x%x (%s format), %d functions, %d files\n", t.Addr, t.Format, len(t.Funcs), len(t.Files))
+
+	if o.Funcs {
+		fmt.Println()
+		goFuncsInf(t)
+	}
+	if o.Sizes {
+		fmt.Println()
+		goSizesInf(gobin.PackageSizes(t, f))
+	}
+	if o.Lines != nil {
+		for i := range t.Funcs {
+			fn := &t.Funcs[i]
+			if !o.Lines.MatchString(fn.Name) {
+				continue
+			}
+			fmt.Printf("\n%s:\n", fn.Name)
+			for _, l := range t.Lines(fn) {
+				fmt.Printf("  0x%x  %s:%d\n", l.PC, l.File, l.Line)
+			}
+		}
+	}
+}
+
+func goFuncsInf(t *gobin.PCLNTab) {
+	funcs := t.Funcs
+	sort.SliceStable(funcs, func(i, j int) bool { return funcs[i].Entry < funcs[j].Entry })
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	// names come last: the longest would pad every row
+	fmt.Fprintln(w, "Entry:\tSize:\tLocation:\tName:")
+	for i := range funcs {
+		fn := &funcs[i]
+		file, line, _ := t.PCToLine(fn.Entry)
+		fmt.Fprintf(w, "0x%x\t%d\t%s\t%s\n", fn.Entry, fn.End-fn.Entry, goLocation(file, line), fn.Name)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// goLocation spells file:line, or ??:0 like addr2line for functions
+// without line information, such as assembly stubs.
+func goLocation(file string, line int) string {
+	if file == "" || line <= 0 {
+		return "??:0"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+func goSizesInf(sizes []gobin.PackageSize) {
+	var funcs int
+	var text, data, bss uint64
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Package:\tFuncs:\tText:\tData:\tBSS:\tFile size:")
+	for _, s := range sizes {
+		name := s.Package
+		if name == "" {
+			name = "<compiler generated>"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", name, s.Funcs, s.Text, s.Data, s.BSS, s.Text+s.Data)
+		funcs += s.Funcs
+		text += s.Text
+		data += s.Data
+		bss += s.BSS
+	}
+	fmt.Fprintf(w, "Total\t%d\t%d\t%d\t%d\t%d\n", funcs, text, data, bss, text+data)
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
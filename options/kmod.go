@@ -0,0 +1,62 @@
+package options
+
+import (
+	"elfreader/kmod"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// KmodInf prints the module name, the .modinfo fields in the manner
+// of modinfo, the __versions CRCs and the signature s, which is nil for
+// unsigned modules.
+func KmodInf(m *kmod.Module, s *kmod.Signature) {
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if m.Name != "" {
+		fmt.Fprintf(w, "name:\t%s\n", m.Name)
+	}
+	for _, f := range m.Info {
+		fmt.Fprintf(w, "%s:\t%s\n", f.Key, f.Value)
+	}
+	if s == nil {
+		fmt.Fprintln(w, "signature:\tnone")
+	} else {
+		fmt.Fprintf(w, "sig_id:\t%s\n", s.IDType)
+		fmt.Fprintf(w, "sig_size:\t%d bytes at 0x%x\n", s.Size, s.Offset)
+		if s.Algo != "" {
+			fmt.Fprintf(w, "sig_algo:\t%s\n", s.Algo)
+			fmt.Fprintf(w, "sig_hashalgo:\t%s\n", s.Hash)
+		}
+		for _, sg := range s.Signers {
+			if sg.Issuer != "" {
+				fmt.Fprintf(w, "signer:\t%s\n", sg.Issuer)
+				fmt.Fprintf(w, "sig_key:\t%s\n", sg.SerialString())
+			} else {
+				fmt.Fprintf(w, "sig_key:\t%s\n", hex.EncodeToString(sg.SubjectKeyID))
+			}
+			fmt.Fprintf(w, "sig_hashalgo:\t%s\n", sg.Hash)
+			fmt.Fprintf(w, "sig_algo:\t%s, %d bytes\n", sg.Algorithm, sg.Size)
+		}
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(m.Versions) > 0 {
+		fmt.Printf("\nSymbol versions (%d entries):\n", len(m.Versions))
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  CRC:\tSymbol:")
+		for _, v := range m.Versions {
+			fmt.Fprintf(w, "  0x%08x\t%s\n", v.CRC, v.Name)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package options
+
+import (
+	"elfreader/debuginfo"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+func holeInf(bits int64) string {
+	switch {
+	case bits%8 == 0:
+		return plural(bits/8, "byte")
+	case bits < 8:
+		return plural(bits, "bit")
+	}
+	return plural(bits/8, "byte") + " " + plural(bits%8, "bit")
+}
+
+// LayoutInf prints the members of l with their offsets and sizes, the
+// holes between them and the cache line boundaries they fall on, in
+// the manner of pahole.
+func LayoutInf(l *debuginfo.Layout, cacheLine int64) {
+	typeWidth, nameWidth := 0, 0
+	names := make([]string, len(l.Members))
+	for i, m := range l.Members {
+		switch {
+		case m.Base:
+			names[i] = "/* base */"
+		case m.BitSize > 0:
+			names[i] = fmt.Sprintf("%s:%d;", m.Name, m.BitSize)
+		default:
+			names[i] = m.Name + ";"
+		}
+		if len(m.Type) > typeWidth {
+			typeWidth = len(m.Type)
+		}
+		if len(names[i]) > nameWidth {
+			nameWidth = len(names[i])
+		}
+	}
+
+	holes := make(map[int]int64)
+	var sumHoles, bitHoles, sumBitHoles, nHoles int64
+	for _, h := range l.Holes {
+		if h.Member < 0 {
+			continue
+		}
+		holes[h.Member] = h.Bits
+		if h.Bits >= 8 {
+			nHoles++
+			sumHoles += h.Bits / 8
+		}
+		if h.Bits%8 != 0 {
+			bitHoles++
+			sumBitHoles += h.Bits % 8
+		}
+	}
+
+	fmt.Printf("%s %s {\n", l.Kind, l.Name)
+	boundary := cacheLine
+	var sumMembers int64
+	unit := int64(-1)
+	for i, m := range l.Members {
+		if bits, ok := holes[i]; ok {
+			fmt.Printf("\n\t/* XXX %s hole, try to pack */\n", holeInf(bits))
+		}
+		for cacheLine > 0 && m.Offset >= boundary {
+			fmt.Printf("\t/* --- cacheline %d boundary (%d bytes) --- */\n", boundary/cacheLine, boundary)
+			boundary += cacheLine
+		}
+		name := names[i]
+		if m.BitSize > 0 {
+			fmt.Printf("\t%-*s %-*s /* %5d:%2d %4d */\n", typeWidth, m.Type, nameWidth, name, m.Offset, m.BitOffset, m.Size)
+		} else {
+			fmt.Printf("\t%-*s %-*s /* %5d    %4d */\n", typeWidth, m.Type, nameWidth, name, m.Offset, m.Size)
+		}
+		// bitfields sharing a storage unit count it once
+		if m.BitSize == 0 || unit != m.Offset {
+			sumMembers += m.Size
+		}
+		unit = -1
+		if m.BitSize > 0 {
+			unit = m.Offset
+		}
+		for cacheLine > 0 && m.Offset+m.Size > boundary {
+			fmt.Printf("\t/* --- cacheline %d boundary (%d bytes) was %d bytes ago --- */\n", boundary/cacheLine, boundary, m.Offset+m.Size-boundary)
+			boundary += cacheLine
+		}
+	}
+	if len(l.Members) > 0 {
+		fmt.Println()
+	}
+
+	lines := int64(0)
+	if cacheLine > 0 {
+		lines = (l.Size + cacheLine - 1) / cacheLine
+	}
+	fmt.Printf("\t/* size: %d, cachelines: %d, members: %d */\n", l.Size, lines, len(l.Members))
+	if nHoles > 0 {
+		fmt.Printf("\t/* sum members: %d, holes: %d, sum holes: %d */\n", sumMembers, nHoles, sumHoles)
+	} else {
+		fmt.Printf("\t/* sum members: %d */\n", sumMembers)
+	}
+	if bitHoles > 0 {
+		fmt.Printf("\t/* bit holes: %d, sum bit holes: %s */\n", bitHoles, plural(sumBitHoles, "bit"))
+	}
+	if p := l.Padding(); p > 0 {
+		fmt.Printf("\t/* padding: %d */\n", p)
+	}
+	if cacheLine > 0 && l.Size%cacheLine != 0 {
+		fmt.Printf("\t/* last cacheline: %d bytes */\n", l.Size%cacheLine)
+	}
+	fmt.Println("};")
+}
+
+// PaddingInf lists the types whose holes and trailing padding together
+// exceed threshold bytes, most wasteful first.
+func PaddingInf(layouts []*debuginfo.Layout, threshold int64) {
+	var found []*debuginfo.Layout
+	for _, l := range layouts {
+		if l.HoleBytes()+l.Padding() > threshold {
+			found = append(found, l)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		wi := found[i].HoleBytes() + found[i].Padding()
+		wj := found[j].HoleBytes() + found[j].Padding()
+		if wi != wj {
+			return wi > wj
+		}
+		return found[i].Name < found[j].Name
+	})
+
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Type:\tSize:\tHoles:\tPadding:\tWasted:")
+	for _, l := range found {
+		fmt.Fprintf(w, "%s %s\t%d\t%d\t%d\t%d\n", l.Kind, l.Name, l.Size, l.HoleBytes(), l.Padding(), l.HoleBytes()+l.Padding())
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+package options
+
+import (
+	"elfreader/archive"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// LinkInf prints the archive members the simulated link l selected,
+// with the symbol that pulled each in, and the symbols left undefined.
+func LinkInf(l *archive.Linker) {
+	fmt.Printf("Archive members selected: %d\n", len(l.Selected))
+	if len(l.Selected) > 0 {
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Member:\tSymbol:\tReferenced by:")
+		for _, s := range l.Selected {
+			fmt.Fprintf(w, "  %s(%s)\t%s\t%s\n", s.Archive, s.Member.Name, symName(s.Symbol), s.Ref)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	u := l.Unresolved()
+	fmt.Printf("\nUnresolved symbols: %d\n", len(u))
+	if len(u) > 0 {
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Symbol:\tReferenced by:")
+		for _, s := range u {
+			fmt.Fprintf(w, "  %s\t%s\n", symName(s.Name), s.Ref)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
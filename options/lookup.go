@@ -0,0 +1,74 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/file"
+	"elfreader/lookup"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+func progFlags(fl elf.ProgFlag) string {
+	b := []byte("---")
+	if fl&elf.PF_R != 0 {
+		b[0] = 'r'
+	}
+	if fl&elf.PF_W != 0 {
+		b[1] = 'w'
+	}
+	if fl&elf.PF_X != 0 {
+		b[2] = 'x'
+	}
+	return string(b)
+}
+
+// LookupInf prints the segment, section and symbol containing the
+// run-time address addr, followed by n bytes read from it.
+func LookupInf(f *file.File, x *lookup.Index, addr uint64, n int) {
+	r := x.Lookup(addr)
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintf(w, "Address:\t0x%x\n", addr)
+	if x.Base() != 0 {
+		fmt.Fprintf(w, "Link address:\t0x%x\n", r.Addr)
+	}
+	if r.HasOffset {
+		fmt.Fprintf(w, "File offset:\t0x%x\n", r.Offset)
+	}
+	if r.Prog != nil {
+		for i, p := range f.Progs {
+			if p == r.Prog {
+				fmt.Fprintf(w, "Segment:\t%d %s %s 0x%x-0x%x\n", i, p.Type, progFlags(p.Flags), p.Vaddr, p.Vaddr+p.Memsz)
+			}
+		}
+	} else {
+		fmt.Fprintf(w, "Segment:\tnone\n")
+	}
+	if r.Section != nil {
+		fmt.Fprintf(w, "Section:\t%s+0x%x\n", r.Section.Name, r.Addr-r.Section.Addr)
+	} else {
+		fmt.Fprintf(w, "Section:\tnone\n")
+	}
+	switch {
+	case r.Symbol == nil:
+		fmt.Fprintf(w, "Symbol:\tnone\n")
+	case r.Exact:
+		fmt.Fprintf(w, "Symbol:\t%s+0x%x (size %d)\n", symName(r.Symbol.Name), r.SymbolOffset(), r.Symbol.Size)
+	default:
+		fmt.Fprintf(w, "Symbol:\t%s+0x%x (nearest preceding, size %d)\n", symName(r.Symbol.Name), r.SymbolOffset(), r.Symbol.Size)
+	}
+	if n > 0 {
+		b, err := x.Read(addr, n)
+		if err != nil {
+			fmt.Fprintf(w, "Bytes:\t%v\n", err)
+		} else {
+			fmt.Fprintf(w, "Bytes:\t% x\n", b)
+		}
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
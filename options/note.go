@@ -0,0 +1,127 @@
+package options
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+// GNU note types found in the "GNU" owner namespace.
+const (
+	ntGNUABITag   = 1
+	ntGNUHWCap    = 2
+	ntGNUBuildID  = 3
+	ntGNUGoldVer  = 4
+	ntGNUProperty = 5
+)
+
+// readNotes returns every note of f. Notes are taken from the SHT_NOTE
+// sections; files without section headers (such as core dumps) fall back
+// to the PT_NOTE segments.
+func readNotes(f *elf.File) []Note {
+	var notes []Note
+	found := false
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_NOTE {
+			continue
+		}
+		found = true
+		data, err := s.Data()
+		if err != nil {
+			continue
+		}
+		notes = append(notes, parseNotes(data, f.ByteOrder, s.Addralign, s.Name)...)
+	}
+	if found {
+		return notes
+	}
+
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil {
+			continue
+		}
+		notes = append(notes, parseNotes(data, f.ByteOrder, p.Align, "PT_NOTE")...)
+	}
+	return notes
+}
+
+// parseNotes splits the contents of a note section or segment into
+// its entries. Name and descriptor are padded to 8 bytes when the
+// container is 8-byte aligned and to 4 bytes otherwise.
+func parseNotes(data []byte, order binary.ByteOrder, align uint64, where string) []Note {
+	if align != 8 {
+		align = 4
+	}
+	var notes []Note
+	for len(data) >= 12 {
+		namesz := uint64(order.Uint32(data[0:4]))
+		descsz := uint64(order.Uint32(data[4:8]))
+		typ := order.Uint32(data[8:12])
+		data = data[12:]
+
+		if namesz > uint64(len(data)) {
+			break
+		}
+		name := string(bytes.TrimRight(data[:namesz], "\x00"))
+		data = data[minLen(alignUp(namesz, align), len(data)):]
+
+		if descsz > uint64(len(data)) {
+			break
+		}
+		desc := data[:descsz]
+		data = data[minLen(alignUp(descsz, align), len(data)):]
+
+		notes = append(notes, Note{Name: name, Type: typ, Desc: desc, Section: where})
+	}
+	return notes
+}
+
+// buildID returns the GNU build-id of f, or nil if it has none.
+func buildID(f *elf.File) []byte {
+	for _, n := range readNotes(f) {
+		if n.Name == "GNU" && n.Type == ntGNUBuildID {
+			return n.Desc
+		}
+	}
+	return nil
+}
+
+// noteTypeName returns a readable name for the common note types.
+func noteTypeName(n Note) string {
+	switch n.Name {
+	case "GNU":
+		switch n.Type {
+		case ntGNUABITag:
+			return "NT_GNU_ABI_TAG"
+		case ntGNUHWCap:
+			return "NT_GNU_HWCAP"
+		case ntGNUBuildID:
+			return "NT_GNU_BUILD_ID"
+		case ntGNUGoldVer:
+			return "NT_GNU_GOLD_VERSION"
+		case ntGNUProperty:
+			return "NT_GNU_PROPERTY_TYPE_0"
+		}
+	case "Go":
+		if n.Type == 4 {
+			return "NT_GO_BUILDID"
+		}
+	}
+	return fmt.Sprintf("0x%x", n.Type)
+}
+
+func alignUp(v, align uint64) uint64 {
+	return (v + align - 1) &^ (align - 1)
+}
+
+func minLen(v uint64, n int) uint64 {
+	if v > uint64(n) {
+		return uint64(n)
+	}
+	return v
+}
@@ -2,23 +2,39 @@ package options
 
 import (
 	"debug/elf"
+	"elfreader/arch"
+	"elfreader/demangle"
+	"elfreader/file"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"text/tabwriter"
 )
 
+// Demangle makes the printers show C++ and Rust symbol names in their
+// demangled form.
+var Demangle bool
+
+func symName(name string) string {
+	if Demangle {
+		return demangle.Filter(name)
+	}
+	return name
+}
+
 type Note struct {
-	Name string
-	Type uint32
-	Desc []byte
+	Name    string
+	Type    uint32
+	Desc    []byte
+	Section string
 }
 
-func HeadInf(f *elf.File, fName string) {
+func HeadInf(f *elf.File, r io.ReaderAt) {
 	fmt.Println("ELF Header:")
 
 	// get Magic Number
-	r, _ := os.Open(fName)
 	var ident [16]uint8
 	if _, err := r.ReadAt(ident[0:], 0); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
@@ -36,6 +52,16 @@ func HeadInf(f *elf.File, fName string) {
 	fmt.Printf("  Type:		%v\n", f.FileHeader.Type)
 	fmt.Printf("  Machine:	%v\n", f.FileHeader.Machine)
 	fmt.Printf("  Entry:	%d\n", f.FileHeader.Entry)
+	flags, err := arch.ReadFlags(r, f.Class, f.ByteOrder)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if d := arch.DecodeFlags(f.Machine, f.Class, flags); len(d) > 0 {
+		fmt.Printf("  Flags:	0x%x, %s\n", flags, strings.Join(d, ", "))
+	} else {
+		fmt.Printf("  Flags:	0x%x\n", flags)
+	}
 }
 
 func ProgramHeadInf(f *elf.File, all bool) {
@@ -122,31 +148,12 @@ func SectionHeadInf(f *elf.File, all bool) {
 	fmt.Println()
 }
 
-func SymbolTableInf(f *elf.File) {
-	fmt.Println("Symbol Table:")
-	// set tabwriter width 8
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
-	fmt.Fprintln(w, "Name:\tValue:\tSize:\tVersion:\tLib:\tNdx:")
-
-	symtab, err := f.Symbols()
-	if err != nil {
-		log.Fatal(err)
-	}
+func SymbolTableInf(f *file.File) {
+	SymbolsInf(f, SymbolOptions{})
+}
 
-	for _, sym := range symtab {
-		if sym.Name != "" {
-			fmt.Fprintf(w, "%v\t", sym.Name)
-			fmt.Fprintf(w, "0x%x\t", sym.Value)
-			fmt.Fprintf(w, "%v\t", sym.Size)
-			fmt.Fprintf(w, "%v\t", sym.Version)
-			fmt.Fprintf(w, "%v\t", sym.Library)
-			fmt.Fprintf(w, "%v\t\n", sym.Section)
-		}
-	}
-	// refresh Write
-	if err := w.Flush(); err != nil {
-		log.Fatal(err)
-	}
+func DynamicSymbolInf(f *file.File) {
+	SymbolsInf(f, SymbolOptions{Dynamic: true})
 }
 
 //TO DO:
@@ -207,6 +214,40 @@ func RelocsInf(f *elf.File) {
 	if err := w.Flush(); err != nil {
 		log.Fatal(err)
 	}
+
+	for _, section := range f.Sections {
+		if section.Type == elf.SHT_REL || section.Type == elf.SHT_RELA {
+			relocEntryInf(f, section)
+		}
+	}
+}
+
+func relocEntryInf(f *elf.File, section *elf.Section) {
+	entries, err := relocEntries(f, section)
+	if err != nil {
+		log.Fatal(err)
+	}
+	syms := relocSymbols(f, section)
+
+	fmt.Printf("\nRelocation section '%s' contains %d entries:\n", section.Name, len(entries))
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Offset:\tType:\tSymbol:\tAddend:")
+	for _, e := range entries {
+		name := ""
+		if int(e.Sym) < len(syms) {
+			name = symName(syms[e.Sym].Name)
+		}
+		addend := ""
+		if e.HasAddend {
+			addend = fmt.Sprintf("%+d", e.Addend)
+		}
+		fmt.Fprintf(w, "0x%x\t%s\t%s\t%s\n", e.Offset, relocTypeName(f, e.Type), name, addend)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 //TO DO:
@@ -295,14 +336,18 @@ func readCStringAtOffset(data []byte, offset uint64) string {
 }
 */
 
-func AllInf(f *elf.File, fName string) {
-	HeadInf(f, fName)
+func AllInf(f *elf.File, r io.ReaderAt, sf *file.File) {
+	HeadInf(f, r)
 	fmt.Println()
 	ProgramHeadInf(f, false)
 	fmt.Println()
 	SectionHeadInf(f, false)
 	fmt.Println()
-	SymbolTableInf(f)
+	SymbolTableInf(sf)
 	fmt.Println()
 	RelocsInf(f)
+	if hasArchInf(f) {
+		fmt.Println()
+		ArchInf(f)
+	}
 }
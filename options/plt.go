@@ -0,0 +1,414 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/disasm"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// gotReloc is a dynamic relocation with the name and version of its
+// symbol.
+type gotReloc struct {
+	relocEntry
+	Name, Version string
+}
+
+// dynRelocs returns the relocations of the allocated relocation
+// sections of f by offset, and the lazy binding ones (DT_JMPREL) in
+// table order.
+func dynRelocs(f *elf.File) (map[uint64][]gotReloc, []gotReloc) {
+	var jmprel uint64
+	dyn, err := dynamicEntries(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range dyn {
+		if e.Tag == elf.DT_JMPREL {
+			jmprel = e.Val
+		}
+	}
+	byOffset := make(map[uint64][]gotReloc)
+	var lazy []gotReloc
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_REL && s.Type != elf.SHT_RELA || s.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		entries, err := relocEntries(f, s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		syms := relocSymbols(f, s)
+		isLazy := s.Addr == jmprel && jmprel != 0 || jmprel == 0 && strings.HasSuffix(s.Name, ".plt")
+		for _, e := range entries {
+			r := gotReloc{relocEntry: e}
+			if int(e.Sym) < len(syms) {
+				r.Name = symName(syms[e.Sym].Name)
+				r.Version = syms[e.Sym].Version
+			}
+			byOffset[e.Offset] = append(byOffset[e.Offset], r)
+			if isLazy {
+				lazy = append(lazy, r)
+			}
+		}
+	}
+	return byOffset, lazy
+}
+
+// pltGot returns the address of the GOT that PLT stubs index: DT_PLTGOT,
+// or else .got.plt or .got.
+func pltGot(f *elf.File) uint64 {
+	dyn, err := dynamicEntries(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range dyn {
+		if e.Tag == elf.DT_PLTGOT {
+			return e.Val
+		}
+	}
+	for _, name := range []string{".got.plt", ".got"} {
+		if s := f.Section(name); s != nil {
+			return s.Addr
+		}
+	}
+	return 0
+}
+
+// pltStub is one stub of a PLT section.
+type pltStub struct {
+	Addr, Size uint64
+	Section    string
+	Index      elf.SectionIndex
+	// Kind is PLT0 for the header that calls the lazy resolver, jump
+	// for a stub that jumps through its GOT slot, push for an IBT lazy
+	// stub that only pushes its relocation index for PLT0, and
+	// jump+push for a classic lazy stub doing both
+	Kind string
+	// Slot is the GOT slot the stub jumps through, or for push stubs
+	// the one its relocation fills
+	Slot    uint64
+	HasSlot bool
+	Name    string
+}
+
+// pltSections lists the names of the sections holding PLT stubs.
+var pltSections = []string{".plt", ".plt.sec", ".plt.got", ".iplt"}
+
+// pltLayout returns the size of the stubs of PLT section s and of the
+// PLT0 header in front of them.
+func pltLayout(f *elf.File, s *elf.Section) (entry, header uint64) {
+	entry = 16
+	if s.Entsize >= 8 {
+		// i386 linkers record 4 here, which is no stub size
+		entry = s.Entsize
+	}
+	dynamic := f.SectionByType(elf.SHT_DYNAMIC) != nil
+	if s.Entsize == 0 && f.Machine == elf.EM_X86_64 && (s.Name == ".plt.got" || !dynamic) {
+		// jmp *slot(%rip) padded to 8 bytes
+		entry = 8
+	}
+	if s.Name != ".plt" || !dynamic {
+		// statically linked files have no lazy binding and so no PLT0
+		return entry, 0
+	}
+	if f.Machine == elf.EM_AARCH64 {
+		return entry, 32
+	}
+	return entry, entry
+}
+
+// pltStubs returns the stubs of the PLT sections of f with the GOT
+// slots they jump through and the symbols the slots are resolved to.
+func pltStubs(f *elf.File) []pltStub {
+	var a disasm.Arch
+	switch f.Machine {
+	case elf.EM_X86_64, elf.EM_386, elf.EM_AARCH64:
+		a, _ = disasm.ForMachine(f.Machine)
+	default:
+		return nil
+	}
+	relocs, lazy := dynRelocs(f)
+	got := pltGot(f)
+	syms := disasmSymbols(f)
+
+	var stubs []pltStub
+	for i, s := range f.Sections {
+		if !matchName(pltSections, s.Name) || s.Type != elf.SHT_PROGBITS {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			log.Fatal(err)
+		}
+		entry, header := pltLayout(f, s)
+		d := disasm.NewDecoder(a)
+		for off := uint64(0); off < s.Size; {
+			size := entry
+			kind := ""
+			if off == 0 && header > 0 {
+				size, kind = header, "PLT0"
+			}
+			if off+size > uint64(len(data)) {
+				break
+			}
+			st := pltStub{Addr: s.Addr + off, Size: size, Section: s.Name, Index: elf.SectionIndex(i), Kind: kind}
+			jump, push := stubSlot(f, d, data[off:off+size], st.Addr, got)
+			switch {
+			case jump != nil:
+				st.Slot, st.HasSlot = *jump, true
+			case push != nil && int(*push) < len(lazy):
+				st.Slot, st.HasSlot = lazy[*push].Offset, true
+			}
+			if st.Kind == "" {
+				switch {
+				case jump != nil && push != nil:
+					st.Kind = "jump+push"
+				case jump != nil:
+					st.Kind = "jump"
+				case push != nil:
+					st.Kind = "push"
+				}
+			}
+			if st.HasSlot && st.Kind != "PLT0" {
+				st.Name = slotName(relocs[st.Slot], syms)
+			}
+			if st.Kind != "" {
+				stubs = append(stubs, st)
+			}
+			off += size
+		}
+	}
+	return stubs
+}
+
+// stubSlot decodes the PLT stub b at addr and returns the GOT slot it
+// jumps through and the lazy binding index it pushes, if any.
+func stubSlot(f *elf.File, d *disasm.Decoder, b []byte, addr, got uint64) (jump, push *uint64) {
+	d.Reset()
+	for pc := addr; len(b) > 0; {
+		inst := d.Decode(b, pc)
+		if inst.Len <= 0 || inst.Len > len(b) {
+			break
+		}
+		op := inst.Op
+		switch {
+		case f.Machine == elf.EM_386 && strings.HasSuffix(op, "jmp") && jump == nil:
+			// the stubs of PIC code jump through [ebx+disp] with ebx
+			// holding the GOT, the others through an absolute address
+			if v, ok := i386Slot(b[:inst.Len], got); ok {
+				jump = &v
+			}
+		case strings.HasSuffix(op, "jmp") && inst.HasRef && jump == nil,
+			op == "ldr" && inst.HasRef && jump == nil:
+			v := inst.Ref
+			jump = &v
+		case op == "push" && len(inst.Args) == 1 && push == nil:
+			if v, err := strconv.ParseUint(inst.Args[0], 0, 32); err == nil {
+				if f.Machine == elf.EM_386 {
+					// i386 pushes the byte offset into .rel.plt
+					v /= 8
+				}
+				push = &v
+			}
+		}
+		b = b[inst.Len:]
+		pc += uint64(inst.Len)
+	}
+	return jump, push
+}
+
+// i386Slot returns the slot of an i386 indirect jmp instruction b:
+// ff a3 disp32 relative to the GOT or ff 25 addr32.
+func i386Slot(b []byte, got uint64) (uint64, bool) {
+	for len(b) > 0 && (b[0] == 0xf2 || b[0] == 0x3e) {
+		b = b[1:]
+	}
+	if len(b) != 6 || b[0] != 0xff {
+		return 0, false
+	}
+	v := uint64(uint32(b[2]) | uint32(b[3])<<8 | uint32(b[4])<<16 | uint32(b[5])<<24)
+	switch b[1] {
+	case 0xa3:
+		return uint32Add(got, v), true
+	case 0x25:
+		return v, true
+	}
+	return 0, false
+}
+
+func uint32Add(a, b uint64) uint64 { return uint64(uint32(a + b)) }
+
+// slotName names what the relocations rs of a GOT slot resolve it to:
+// the symbol, or for IRELATIVE and RELATIVE relocations the address,
+// by the nearest symbol if there is one.
+func slotName(rs []gotReloc, syms []disasmSym) string {
+	if len(rs) == 0 {
+		return ""
+	}
+	r := rs[0]
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.HasAddend {
+		if ref := symRef(syms, uint64(r.Addend)); ref != "" {
+			return ref
+		}
+		return fmt.Sprintf("0x%x", r.Addend)
+	}
+	return ""
+}
+
+// gotSlotNames returns the names of the symbols that the GOT slots of
+// f are filled with, by slot address, for naming the loads through
+// them; versioned symbols are named name@version as objdump does.
+// Slots holding addresses within f are left to the symbol table.
+func gotSlotNames(f *elf.File) map[uint64]string {
+	relocs, _ := dynRelocs(f)
+	out := make(map[uint64]string)
+	for _, s := range f.Sections {
+		if !strings.HasPrefix(s.Name, ".got") || s.Type != elf.SHT_PROGBITS {
+			continue
+		}
+		for off, rs := range relocs {
+			if off < s.Addr || off >= s.Addr+s.Size || rs[0].Name == "" {
+				continue
+			}
+			out[off] = rs[0].Name
+			if rs[0].Version != "" {
+				out[off] += "@" + rs[0].Version
+			}
+		}
+	}
+	return out
+}
+
+// pltSymbols returns a name@plt symbol for each stub that calls land
+// on, for naming the branch targets of a disassembly.
+func pltSymbols(f *elf.File) []disasmSym {
+	var out []disasmSym
+	for _, st := range pltStubs(f) {
+		if st.Kind == "PLT0" || st.Kind == "push" || st.Name == "" || strings.HasPrefix(st.Name, "<") {
+			continue
+		}
+		out = append(out, disasmSym{Addr: st.Addr, Size: st.Size, Name: st.Name + "@plt", Section: st.Index})
+	}
+	return out
+}
+
+// PltInf prints the stubs of the PLT sections of f with the GOT slots
+// they jump through and the imported symbols they reach, then every
+// GOT slot with its initial value and the relocations that fill it.
+func PltInf(f *elf.File) {
+	stubs := pltStubs(f)
+	if len(stubs) == 0 {
+		fmt.Println("No PLT stubs found.")
+	} else {
+		fmt.Println("PLT stubs:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+		fmt.Fprintln(w, "Address:\tSection:\tKind:\tSlot:\tSymbol:")
+		for _, st := range stubs {
+			slot := "-"
+			if st.HasSlot {
+				slot = fmt.Sprintf("0x%x", st.Slot)
+			}
+			name := st.Name
+			if st.Kind == "PLT0" {
+				name = "(lazy resolver)"
+			}
+			fmt.Fprintf(w, "0x%x\t%s\t%s\t%s\t%s\n", st.Addr, st.Section, st.Kind, slot, name)
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fmt.Println()
+	gotSlotsInf(f, stubs)
+}
+
+// gotSlotsInf prints every slot of the GOT sections of f.
+func gotSlotsInf(f *elf.File, stubs []pltStub) {
+	var gots []*elf.Section
+	for _, s := range f.Sections {
+		if strings.HasPrefix(s.Name, ".got") && s.Type == elf.SHT_PROGBITS {
+			gots = append(gots, s)
+		}
+	}
+	if len(gots) == 0 {
+		fmt.Println("No GOT found.")
+		return
+	}
+	ptr := uint64(8)
+	if f.Class == elf.ELFCLASS32 {
+		ptr = 4
+	}
+	relocs, _ := dynRelocs(f)
+	syms := disasmSymbols(f)
+	got := pltGot(f)
+	var dynamic uint64
+	if s := f.SectionByType(elf.SHT_DYNAMIC); s != nil {
+		dynamic = s.Addr
+	}
+	// the stub, if any, a slot's initial value sends the first call to
+	stubAt := func(v uint64) string {
+		i := sort.Search(len(stubs), func(i int) bool { return stubs[i].Addr+stubs[i].Size > v })
+		for ; i < len(stubs) && stubs[i].Addr <= v; i++ {
+			if stubs[i].Section == ".plt" {
+				return fmt.Sprintf("%s+0x%x", stubs[i].Section, v-f.Section(stubs[i].Section).Addr)
+			}
+		}
+		return ""
+	}
+	sort.SliceStable(stubs, func(i, j int) bool { return stubs[i].Addr < stubs[j].Addr })
+
+	fmt.Println("GOT slots:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Address:\tSection:\tValue:\tRelocation:\tSymbol:\tAddend:")
+	for _, s := range gots {
+		data, err := s.Data()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for off := uint64(0); off+ptr <= uint64(len(data)); off += ptr {
+			addr := s.Addr + off
+			var v uint64
+			if ptr == 4 {
+				v = uint64(f.ByteOrder.Uint32(data[off:]))
+			} else {
+				v = f.ByteOrder.Uint64(data[off:])
+			}
+			value := fmt.Sprintf("0x%x", v)
+			if to := stubAt(v); to != "" {
+				value += " (" + to + ")"
+			}
+			rs := relocs[addr]
+			if len(rs) == 0 {
+				name := ""
+				switch {
+				case dynamic == 0:
+				case v == dynamic:
+					name = "(_DYNAMIC)"
+				case addr == got+ptr || addr == got+2*ptr:
+					name = "(reserved for the dynamic linker)"
+				}
+				fmt.Fprintf(w, "0x%x\t%s\t%s\t-\t%s\t\n", addr, s.Name, value, name)
+				continue
+			}
+			for _, r := range rs {
+				addend := ""
+				if r.HasAddend {
+					addend = fmt.Sprintf("%+d", r.Addend)
+				}
+				fmt.Fprintf(w, "0x%x\t%s\t%s\t%s\t%s\t%s\n", addr, s.Name, value, relocTypeName(f, r.Type), slotName([]gotReloc{r}, syms), addend)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,111 @@
+package options
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+type relocEntry struct {
+	Offset uint64
+	Type   uint32
+	Sym    uint32
+	Addend int64
+	// HasAddend is set for SHT_RELA entries
+	HasAddend bool
+}
+
+// relocEntries decodes the entries of the SHT_REL or SHT_RELA section s.
+func relocEntries(f *elf.File, s *elf.Section) ([]relocEntry, error) {
+	d, err := s.Data()
+	if err != nil {
+		return nil, err
+	}
+	return decodeRelocs(f.Class, f.ByteOrder, d, s.Type == elf.SHT_RELA)
+}
+
+// decodeRelocs decodes the relocation entries in d, with addends if
+// rela is set.
+func decodeRelocs(class elf.Class, order binary.ByteOrder, d []byte, rela bool) ([]relocEntry, error) {
+	var entries []relocEntry
+	switch class {
+	case elf.ELFCLASS32:
+		size := 8
+		if rela {
+			size = 12
+		}
+		for ; len(d) >= size; d = d[size:] {
+			info := order.Uint32(d[4:8])
+			e := relocEntry{Offset: uint64(order.Uint32(d[0:4])), Type: info & 0xff, Sym: info >> 8}
+			if rela {
+				e.Addend, e.HasAddend = int64(int32(order.Uint32(d[8:12]))), true
+			}
+			entries = append(entries, e)
+		}
+	case elf.ELFCLASS64:
+		size := 16
+		if rela {
+			size = 24
+		}
+		for ; len(d) >= size; d = d[size:] {
+			info := order.Uint64(d[8:16])
+			e := relocEntry{Offset: order.Uint64(d[0:8]), Type: uint32(info), Sym: uint32(info >> 32)}
+			if rela {
+				e.Addend, e.HasAddend = int64(order.Uint64(d[16:24])), true
+			}
+			entries = append(entries, e)
+		}
+	default:
+		return nil, fmt.Errorf("unknown ELF class %v", class)
+	}
+	return entries, nil
+}
+
+// relocTypeName returns the name of relocation type t for the machine of f.
+func relocTypeName(f *elf.File, t uint32) string {
+	switch f.Machine {
+	case elf.EM_X86_64:
+		return elf.R_X86_64(t).String()
+	case elf.EM_386:
+		return elf.R_386(t).String()
+	case elf.EM_AARCH64:
+		return elf.R_AARCH64(t).String()
+	case elf.EM_ARM:
+		return elf.R_ARM(t).String()
+	case elf.EM_PPC:
+		return elf.R_PPC(t).String()
+	case elf.EM_PPC64:
+		return elf.R_PPC64(t).String()
+	case elf.EM_RISCV:
+		return elf.R_RISCV(t).String()
+	case elf.EM_MIPS:
+		return elf.R_MIPS(t).String()
+	case elf.EM_S390:
+		return elf.R_390(t).String()
+	case elf.EM_SPARCV9:
+		return elf.R_SPARC(t).String()
+	case elf.EM_LOONGARCH:
+		return elf.R_LARCH(t).String()
+	}
+	return fmt.Sprint(t)
+}
+
+// relocSymbols returns the symbol table the relocation section s refers
+// to, indexed like the table itself (entry 0 is the null symbol).
+func relocSymbols(f *elf.File, s *elf.Section) []elf.Symbol {
+	if int(s.Link) >= len(f.Sections) || s.Link == 0 {
+		return nil
+	}
+	var syms []elf.Symbol
+	var err error
+	switch f.Sections[s.Link].Type {
+	case elf.SHT_DYNSYM:
+		syms, err = f.DynamicSymbols()
+	case elf.SHT_SYMTAB:
+		syms, err = f.Symbols()
+	}
+	if err != nil {
+		return nil
+	}
+	return append([]elf.Symbol{{}}, syms...)
+}
@@ -0,0 +1,28 @@
+package options
+
+import (
+	"elfreader/sbom"
+	"fmt"
+	"log"
+	"os"
+)
+
+// SBOMInf prints the bill of materials b as a "cyclonedx" or "spdx"
+// JSON document.
+func SBOMInf(b *sbom.BOM, format string) {
+	var data []byte
+	var err error
+	switch format {
+	case "cyclonedx":
+		data, err = b.CycloneDX()
+	case "spdx":
+		data, err = b.SPDX()
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown SBOM format %q\n", format)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,353 @@
+package options
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"elfreader/demangle"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// berkeley holds the text/data/bss totals reported by SizeInf.
+type berkeley struct {
+	Text, Data, BSS uint64
+}
+
+func (b berkeley) total() uint64 { return b.Text + b.Data + b.BSS }
+
+// berkeleySizes sums the allocated sections of f: read-only sections
+// count as text, writable ones as data and SHT_NOBITS ones as bss.
+func berkeleySizes(f *elf.File) berkeley {
+	var b berkeley
+	for _, s := range f.Sections {
+		if s.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		switch {
+		case s.Type == elf.SHT_NOBITS:
+			b.BSS += s.Size
+		case s.Flags&elf.SHF_WRITE != 0:
+			b.Data += s.Size
+		default:
+			b.Text += s.Size
+		}
+	}
+	return b
+}
+
+// sysvSection reports whether size -A lists s. BFD turns the symbol
+// and string tables, section groups and the relocations of other
+// sections into its own structures rather than sections, so they are
+// left out, while other unallocated sections such as .comment and the
+// debugging information are listed.
+func sysvSection(s *elf.Section) bool {
+	if s.Flags&elf.SHF_ALLOC != 0 {
+		return s.Type != elf.SHT_NULL
+	}
+	switch s.Type {
+	case elf.SHT_NULL, elf.SHT_SYMTAB, elf.SHT_STRTAB, elf.SHT_SYMTAB_SHNDX, elf.SHT_GROUP:
+		return false
+	case elf.SHT_REL, elf.SHT_RELA:
+		return s.Info == 0
+	}
+	return true
+}
+
+func SizeInf(f *elf.File, fName string, sysv bool) {
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	if sysv {
+		fmt.Printf("%s:\n", fName)
+		fmt.Fprintln(w, "Section:\tSize:\tAddr:")
+		var total uint64
+		for _, s := range f.Sections {
+			if !sysvSection(s) || s.Size == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t0x%x\n", s.Name, s.Size, s.Addr)
+			total += s.Size
+		}
+		fmt.Fprintf(w, "Total\t%d\t\n", total)
+	} else {
+		b := berkeleySizes(f)
+		fmt.Fprintln(w, "text:\tdata:\tbss:\tdec:\thex:\tfilename:")
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%x\t%s\n", b.Text, b.Data, b.BSS, b.total(), b.total(), fName)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// sizedSymbols returns the defined symbols of f that occupy space,
+// falling back to the dynamic symbols for stripped files.
+func sizedSymbols(f *elf.File) []elf.Symbol {
+	syms, err := f.Symbols()
+	if err != nil || len(syms) == 0 {
+		syms, _ = f.DynamicSymbols()
+	}
+	var out []elf.Symbol
+	for _, s := range syms {
+		if s.Size == 0 || s.Section == elf.SHN_UNDEF || s.Section >= elf.SHN_LORESERVE {
+			continue
+		}
+		switch elf.ST_TYPE(s.Info) {
+		case elf.STT_SECTION, elf.STT_FILE:
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+type sizeEntry struct {
+	Name string
+	Size uint64
+}
+
+// sortSizes orders entries by decreasing size, then by name.
+func sortSizes(l []sizeEntry) {
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Size != l[j].Size {
+			return l[i].Size > l[j].Size
+		}
+		return l[i].Name < l[j].Name
+	})
+}
+
+func aggregate(m map[string]uint64) []sizeEntry {
+	l := make([]sizeEntry, 0, len(m))
+	for k, v := range m {
+		l = append(l, sizeEntry{k, v})
+	}
+	sortSizes(l)
+	return l
+}
+
+func symSectionName(f *elf.File, s elf.Symbol) string {
+	if int(s.Section) < len(f.Sections) {
+		return f.Sections[s.Section].Name
+	}
+	return fmt.Sprint(s.Section)
+}
+
+// bloat collects the symbol size breakdowns shown by BloatInf.
+type bloat struct {
+	Symbols   []sizeEntry
+	Sections  map[string]uint64
+	Prefixes  map[string]uint64
+	Units     map[string]uint64
+	HaveUnits bool
+}
+
+func bloatOf(f *elf.File) bloat {
+	b := bloat{
+		Sections: make(map[string]uint64),
+		Prefixes: make(map[string]uint64),
+		Units:    make(map[string]uint64),
+	}
+	units := compileUnits(f)
+	b.HaveUnits = len(units) > 0
+	for _, s := range sizedSymbols(f) {
+		b.Symbols = append(b.Symbols, sizeEntry{s.Name, s.Size})
+		b.Sections[symSectionName(f, s)] += s.Size
+
+		prefix := demangle.Scope(s.Name)
+		if prefix == "" {
+			prefix = "(global)"
+		}
+		b.Prefixes[prefix] += s.Size
+
+		if b.HaveUnits {
+			unit := units.find(s.Value)
+			if unit == "" {
+				unit = "(unknown)"
+			}
+			b.Units[unit] += s.Size
+		}
+	}
+	sortSizes(b.Symbols)
+	return b
+}
+
+func BloatInf(f *elf.File, top int) {
+	b := bloatOf(f)
+
+	fmt.Println("Largest Symbols:")
+	printSizes(b.Symbols, top)
+	fmt.Println()
+	fmt.Println("By Section:")
+	printSizes(aggregate(b.Sections), top)
+	fmt.Println()
+	fmt.Println("By Namespace:")
+	printSizes(aggregate(b.Prefixes), top)
+	if b.HaveUnits {
+		fmt.Println()
+		fmt.Println("By Compile Unit:")
+		printSizes(aggregate(b.Units), top)
+	}
+}
+
+func printSizes(l []sizeEntry, top int) {
+	var total uint64
+	for _, e := range l {
+		total += e.Size
+	}
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Size:\tPercent:\tName:")
+	for i, e := range l {
+		if top > 0 && i >= top {
+			break
+		}
+		fmt.Fprintf(w, "%d\t%.1f%%\t%s\n", e.Size, percent(e.Size, total), e.Name)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func percent(v, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) * 100 / float64(total)
+}
+
+func SizeDiffInf(a, b *elf.File, nameA, nameB string, top int) {
+	ba, bb := berkeleySizes(a), berkeleySizes(b)
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "\ttext:\tdata:\tbss:\tdec:")
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", nameA, ba.Text, ba.Data, ba.BSS, ba.total())
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", nameB, bb.Text, bb.Data, bb.BSS, bb.total())
+	fmt.Fprintf(w, "delta\t%+d\t%+d\t%+d\t%+d\n", delta(ba.Text, bb.Text), delta(ba.Data, bb.Data),
+		delta(ba.BSS, bb.BSS), delta(ba.total(), bb.total()))
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	xa, xb := bloatOf(a), bloatOf(b)
+	fmt.Println()
+	fmt.Println("Symbol Changes:")
+	printDeltas(toMap(xa.Symbols), toMap(xb.Symbols), top)
+	fmt.Println()
+	fmt.Println("Section Changes:")
+	printDeltas(xa.Sections, xb.Sections, top)
+	fmt.Println()
+	fmt.Println("Namespace Changes:")
+	printDeltas(xa.Prefixes, xb.Prefixes, top)
+	if xa.HaveUnits || xb.HaveUnits {
+		fmt.Println()
+		fmt.Println("Compile Unit Changes:")
+		printDeltas(xa.Units, xb.Units, top)
+	}
+}
+
+func delta(a, b uint64) int64 {
+	return int64(b) - int64(a)
+}
+
+func toMap(l []sizeEntry) map[string]uint64 {
+	m := make(map[string]uint64, len(l))
+	for _, e := range l {
+		m[e.Name] += e.Size
+	}
+	return m
+}
+
+// printDeltas lists the entries whose size changed between ma and mb,
+// largest change first.
+func printDeltas(ma, mb map[string]uint64, top int) {
+	type change struct {
+		name   string
+		before uint64
+		after  uint64
+	}
+	var l []change
+	for k, v := range ma {
+		if mb[k] != v {
+			l = append(l, change{k, v, mb[k]})
+		}
+	}
+	for k, v := range mb {
+		if _, ok := ma[k]; !ok {
+			l = append(l, change{k, 0, v})
+		}
+	}
+	abs := func(c change) int64 {
+		d := delta(c.before, c.after)
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if abs(l[i]) != abs(l[j]) {
+			return abs(l[i]) > abs(l[j])
+		}
+		return l[i].name < l[j].name
+	})
+
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Before:\tAfter:\tDelta:\tName:")
+	for i, c := range l {
+		if top > 0 && i >= top {
+			break
+		}
+		fmt.Fprintf(w, "%d\t%d\t%+d\t%s\n", c.before, c.after, delta(c.before, c.after), c.name)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type unitRange struct {
+	Low, High uint64
+	Name      string
+}
+
+type unitRanges []unitRange
+
+// compileUnits returns the address ranges of the DWARF compile units
+// of f, sorted by address. It returns nil if f has no DWARF.
+func compileUnits(f *elf.File) unitRanges {
+	d, err := f.DWARF()
+	if err != nil {
+		return nil
+	}
+	var units unitRanges
+	r := d.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil || e == nil {
+			break
+		}
+		if e.Tag == dwarf.TagCompileUnit || e.Tag == dwarf.TagPartialUnit {
+			name, _ := e.Val(dwarf.AttrName).(string)
+			ranges, _ := d.Ranges(e)
+			for _, rg := range ranges {
+				units = append(units, unitRange{rg[0], rg[1], name})
+			}
+		}
+		r.SkipChildren()
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Low < units[j].Low })
+	return units
+}
+
+// find returns the name of the compile unit containing addr.
+func (u unitRanges) find(addr uint64) string {
+	i := sort.Search(len(u), func(i int) bool { return u[i].Low > addr }) - 1
+	if i >= 0 && addr < u[i].High {
+		return u[i].Name
+	}
+	return ""
+}
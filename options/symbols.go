@@ -0,0 +1,250 @@
+package options
+
+import (
+	"debug/elf"
+	"elfreader/debuglink"
+	"elfreader/file"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// SymbolOptions selects and orders the symbols printed by SymbolsInf.
+// Zero values select everything in table order.
+type SymbolOptions struct {
+	// Dynamic selects .dynsym instead of .symtab
+	Dynamic bool
+
+	// Types, Binds and Visibilities hold names as printed, such as
+	// FUNC, GLOBAL or HIDDEN; matching is case-insensitive
+	Types        []string
+	Binds        []string
+	Visibilities []string
+	// Sections holds section names
+	Sections []string
+	Name     *regexp.Regexp
+
+	Defined   bool
+	Undefined bool
+
+	// MinAddr and MaxAddr bound the symbol value when MaxAddr is set;
+	// MaxAddr is exclusive
+	MinAddr, MaxAddr uint64
+
+	// SortBy is "", "addr", "size" or "name"
+	SortBy string
+}
+
+// numberedSymbol is a symbol with its index in the symbol table, or 0
+// for the MiniDebugInfo functions merged into a .dynsym listing.
+type numberedSymbol struct {
+	file.Symbol
+	Num int
+}
+
+func symTypeName(t elf.SymType) string {
+	switch t {
+	case elf.STT_NOTYPE:
+		return "NOTYPE"
+	case elf.STT_OBJECT:
+		return "OBJECT"
+	case elf.STT_FUNC:
+		return "FUNC"
+	case elf.STT_SECTION:
+		return "SECTION"
+	case elf.STT_FILE:
+		return "FILE"
+	case elf.STT_COMMON:
+		return "COMMON"
+	case elf.STT_TLS:
+		return "TLS"
+	case elf.STT_LOOS:
+		return "GNU_IFUNC"
+	}
+	return fmt.Sprint(uint8(t))
+}
+
+func symBindName(b elf.SymBind) string {
+	switch b {
+	case elf.STB_LOCAL:
+		return "LOCAL"
+	case elf.STB_GLOBAL:
+		return "GLOBAL"
+	case elf.STB_WEAK:
+		return "WEAK"
+	case elf.STB_LOOS:
+		return "UNIQUE"
+	}
+	return fmt.Sprint(uint8(b))
+}
+
+func symVisName(v elf.SymVis) string {
+	switch v {
+	case elf.STV_DEFAULT:
+		return "DEFAULT"
+	case elf.STV_INTERNAL:
+		return "INTERNAL"
+	case elf.STV_HIDDEN:
+		return "HIDDEN"
+	case elf.STV_PROTECTED:
+		return "PROTECTED"
+	}
+	return fmt.Sprint(uint8(v))
+}
+
+// symNdx returns the section index column of s.
+func symNdx(s file.Symbol) string {
+	switch s.Section {
+	case elf.SHN_UNDEF:
+		return "UND"
+	case elf.SHN_ABS:
+		return "ABS"
+	case elf.SHN_COMMON:
+		return "COM"
+	}
+	return fmt.Sprint(uint16(s.Section))
+}
+
+func matchName(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o SymbolOptions) match(f *file.File, s file.Symbol) bool {
+	if !matchName(o.Types, symTypeName(elf.ST_TYPE(s.Info))) ||
+		!matchName(o.Binds, symBindName(elf.ST_BIND(s.Info))) ||
+		!matchName(o.Visibilities, symVisName(elf.ST_VISIBILITY(s.Other))) {
+		return false
+	}
+	if len(o.Sections) > 0 {
+		if s.Section == elf.SHN_UNDEF || int(s.Section) >= len(f.Sections) ||
+			!matchName(o.Sections, f.Sections[s.Section].Name) {
+			return false
+		}
+	}
+	if o.Name != nil && !o.Name.MatchString(s.Name) && !o.Name.MatchString(symName(s.Name)) {
+		return false
+	}
+	if o.Defined && s.Section == elf.SHN_UNDEF {
+		return false
+	}
+	if o.Undefined && s.Section != elf.SHN_UNDEF {
+		return false
+	}
+	if o.MaxAddr != 0 && (s.Value < o.MinAddr || s.Value >= o.MaxAddr) {
+		return false
+	}
+	return true
+}
+
+// miniDebugTable returns the symbols of a file stripped of .symtab but
+// carrying MiniDebugInfo: the dynamic symbols followed by the function
+// symbols of .gnu_debugdata, which holds only those missing from
+// .dynsym. The first n of them are those of .dynsym, in table order.
+// Without MiniDebugInfo it returns file.ErrNoSymbols.
+func miniDebugTable(f *file.File) (syms []file.Symbol, n int, err error) {
+	funcs, err := debuglink.MiniDebugSymbols(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	if len(funcs) == 0 {
+		return nil, 0, file.ErrNoSymbols
+	}
+	dyn, err := f.DynamicSymbols()
+	if err != nil && err != file.ErrNoSymbols {
+		return nil, 0, err
+	}
+	return append(dyn, funcs...), len(dyn), nil
+}
+
+// selectSymbols returns the symbols of f chosen by o, in the order it
+// asks for.
+func selectSymbols(f *file.File, o SymbolOptions) ([]numberedSymbol, error) {
+	var syms []file.Symbol
+	var err error
+	if o.Dynamic {
+		syms, err = f.DynamicSymbols()
+	} else {
+		syms, err = f.Symbols()
+	}
+	// indexed is the number of symbols numbered by their index
+	indexed := len(syms)
+	if !o.Dynamic && err == file.ErrNoSymbols {
+		syms, indexed, err = miniDebugTable(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []numberedSymbol
+	for i, s := range syms {
+		if !o.match(f, s) {
+			continue
+		}
+		// the null symbol at index 0 is not returned by Symbols
+		num := i + 1
+		if i >= indexed {
+			num = 0
+		}
+		out = append(out, numberedSymbol{s, num})
+	}
+
+	switch o.SortBy {
+	case "":
+	case "addr":
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Value < out[j].Value })
+	case "size":
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Size > out[j].Size })
+	case "name":
+		sort.SliceStable(out, func(i, j int) bool { return symName(out[i].Name) < symName(out[j].Name) })
+	default:
+		return nil, fmt.Errorf("unknown sort key %q", o.SortBy)
+	}
+	return out, nil
+}
+
+func SymbolsInf(f *file.File, o SymbolOptions) {
+	syms, err := selectSymbols(f, o)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if o.Dynamic {
+		fmt.Println("Dynamic Symbol Table:")
+	} else {
+		fmt.Println("Symbol Table:")
+	}
+	// set tabwriter width 8
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 8, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(w, "Num:\tValue:\tSize:\tType:\tBind:\tVis:\tNdx:\tName:\tVersion:\tLib:")
+	for _, sym := range syms {
+		if sym.Num == 0 {
+			fmt.Fprint(w, "-\t")
+		} else {
+			fmt.Fprintf(w, "%d\t", sym.Num)
+		}
+		fmt.Fprintf(w, "0x%x\t", sym.Value)
+		fmt.Fprintf(w, "%v\t", sym.Size)
+		fmt.Fprintf(w, "%s\t", symTypeName(elf.ST_TYPE(sym.Info)))
+		fmt.Fprintf(w, "%s\t", symBindName(elf.ST_BIND(sym.Info)))
+		fmt.Fprintf(w, "%s\t", symVisName(elf.ST_VISIBILITY(sym.Other)))
+		fmt.Fprintf(w, "%s\t", symNdx(sym.Symbol))
+		fmt.Fprintf(w, "%v\t", symName(sym.Name))
+		fmt.Fprintf(w, "%v\t", sym.Version)
+		fmt.Fprintf(w, "%v\t\n", sym.Library)
+	}
+	// refresh Write
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
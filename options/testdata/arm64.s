@@ -0,0 +1,96 @@
+// arm64.o is built from this file with
+//
+//	llvm-mc -triple=aarch64 -mattr=+v8.5a,+fullfp16,+crc,+crypto -filetype=obj -o arm64.o arm64.s
+//
+// and arm64.golden checked against llvm-objdump -d, which differs from
+// the GNU syntax printed here only in spelling.
+
+	.text
+	.globl	sum
+	.type	sum, %function
+sum:
+	stp	x29, x30, [sp, #-32]!
+	mov	x29, sp
+	str	x19, [sp, #16]
+	mov	w19, wzr
+	cbz	x1, 2f
+1:	ldr	w8, [x0], #4
+	add	w19, w19, w8
+	subs	x1, x1, #1
+	b.ne	1b
+2:	mov	w0, w19
+	ldr	x19, [sp, #16]
+	ldp	x29, x30, [sp], #32
+	ret
+	.size	sum, .-sum
+
+	.globl	scale
+	.type	scale, %function
+scale:
+	movz	x9, #0x1234, lsl #16
+	movk	x9, #0x5678
+	and	x10, x0, #0xff00
+	orr	w11, w1, w2, lsl #3
+	eor	x12, x3, x4, asr #7
+	madd	x0, x9, x10, x11
+	udiv	w1, w1, w2
+	lsl	x2, x2, #5
+	ubfx	x3, x4, #8, #12
+	csel	x0, x0, x1, lt
+	cset	w5, eq
+	ccmp	x0, #3, #4, ne
+	tbnz	w5, #3, 3f
+	adr	x6, sum
+	ldrb	w7, [x6, x2]
+	ldrsh	x8, [x6, #6]
+	strh	w7, [x6, #-2]!
+	ldur	x9, [x6, #-8]
+3:	clz	x10, x9
+	rev	w11, w11
+	ret
+	.size	scale, .-scale
+
+	.globl	vec
+	.type	vec, %function
+vec:
+	fmov	d0, #1.0
+	fadd	d1, d0, d2
+	fmul	s3, s4, s5
+	fcvtzs	w0, d1
+	scvtf	d2, x3
+	fcmp	d0, #0.0
+	fcsel	d4, d5, d6, gt
+	ld1	{v0.4s, v1.4s}, [x0], #32
+	add	v2.4s, v0.4s, v1.4s
+	mul	v3.8h, v2.8h, v2.h[3]
+	dup	v4.16b, w1
+	movi	v5.2d, #0
+	addv	s6, v2.4s
+	umov	w2, v6.s[0]
+	ins	v7.d[1], x2
+	zip1	v8.16b, v0.16b, v1.16b
+	st1	{v2.4s}, [x1]
+	ret
+	.size	vec, .-vec
+
+	.globl	sys
+	.type	sys, %function
+sys:
+	mrs	x0, tpidr_el0
+	msr	nzcv, x1
+	dmb	ish
+	dsb	sy
+	isb
+	nop
+	yield
+	ldaxr	x2, [x0]
+	stlxr	w3, x1, [x0]
+	cas	x4, x5, [x0]
+	ldadd	w6, w7, [x0]
+	crc32cx	w8, w8, x9
+	svc	#0
+	brk	#0x3e8
+	bl	sum
+	br	x16
+	blr	x17
+	.size	sys, .-sys
@@ -0,0 +1,72 @@
+package options
+
+import (
+	"elfreader/toolchain"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func findingInf(label string, f toolchain.Finding) {
+	name := f.Name
+	if name == "" {
+		name = "unknown"
+	}
+	if f.Version != "" {
+		name += " " + f.Version
+	}
+	fmt.Printf("%-11s%s\n", label, name)
+	for _, e := range f.Evidence {
+		fmt.Printf("  %s\n", e)
+	}
+}
+
+// ToolchainInf prints the toolchain report r: the tools named in the
+// file, the linker and C library with the evidence for them, and the
+// source languages.
+func ToolchainInf(r *toolchain.Report) {
+	fmt.Println("Tools:")
+	if len(r.Tools) == 0 {
+		fmt.Println("  none named")
+	} else {
+		// set tabwriter width 8
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, t := range r.Tools {
+			name := t.Name
+			if t.Version != "" {
+				name += " " + t.Version
+			}
+			text := t.Text
+			if t.Units > 0 {
+				text += " (" + plural(int64(t.Units), "unit") + ")"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", t.Kind, name, t.Source, text)
+		}
+		// refresh Write
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	findingInf("Linker:", r.Linker)
+	findingInf("Libc:", r.Libc)
+
+	fmt.Println("Languages:")
+	if len(r.Languages) == 0 {
+		fmt.Println("  unknown")
+	}
+	for _, l := range r.Languages {
+		s := "  " + l.Name
+		switch {
+		case l.Units > 0 && (len(l.Dialects) != 1 || l.Dialects[0] != l.Name):
+			s += " (" + plural(int64(l.Units), "unit") + ", " + strings.Join(l.Dialects, ", ") + ")"
+		case l.Units > 0:
+			s += " (" + plural(int64(l.Units), "unit") + ")"
+		default:
+			s += " (from " + l.Source + ")"
+		}
+		fmt.Println(s)
+	}
+}
@@ -0,0 +1,196 @@
+package sbom
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// toolName names the generator in the bills.
+const toolName = "go2elf"
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	Ref        string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Hashes     []cdxHash     `json:"hashes,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+type cdxBOM struct {
+	BOMFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string `json:"timestamp"`
+		Tools     struct {
+			Components []cdxComponent `json:"components"`
+		} `json:"tools"`
+		Component cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+func (c *Component) cdx() cdxComponent {
+	x := cdxComponent{Type: c.Type, Ref: c.Ref, Name: c.Name, Version: c.Version, PURL: c.PURL}
+	if c.SHA1 != "" {
+		x.Hashes = []cdxHash{{"SHA-1", c.SHA1}, {"SHA-256", c.SHA256}}
+	}
+	if c.Path != "" {
+		x.Properties = append(x.Properties, cdxProperty{toolName + ":path", c.Path})
+	}
+	x.Properties = append(x.Properties, cdxProperty{toolName + ":source", c.Source})
+	if p := c.Package; p != nil {
+		x.Properties = append(x.Properties, cdxProperty{toolName + ":package:type", p.Type})
+		if p.OS != "" {
+			x.Properties = append(x.Properties, cdxProperty{toolName + ":package:distro", strings.TrimSuffix(p.OS+" "+p.OSVersion, " ")})
+		}
+	}
+	return x
+}
+
+// CycloneDX returns b as a CycloneDX 1.5 JSON document. The libraries
+// a component loads and the components linked into it are both listed
+// as its dependencies.
+func (b *BOM) CycloneDX() ([]byte, error) {
+	var d cdxBOM
+	d.BOMFormat, d.SpecVersion, d.Version = "CycloneDX", "1.5", 1
+	d.SerialNumber = "urn:uuid:" + b.Serial
+	d.Metadata.Timestamp = b.Created.Format(time.RFC3339)
+	d.Metadata.Tools.Components = []cdxComponent{{Type: "application", Name: toolName}}
+	d.Metadata.Component = b.Main.cdx()
+	d.Components = []cdxComponent{}
+	for _, c := range b.Components {
+		d.Components = append(d.Components, c.cdx())
+	}
+	for _, c := range append([]*Component{b.Main}, b.Components...) {
+		d.Dependencies = append(d.Dependencies, cdxDependency{c.Ref, append(append([]string(nil), c.DependsOn...), c.Contains...)})
+	}
+	return json.MarshalIndent(d, "", "  ")
+}
+
+type spdxChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	Category string `json:"referenceCategory"`
+	Type     string `json:"referenceType"`
+	Locator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	ID               string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	Version          string            `json:"versionInfo,omitempty"`
+	FileName         string            `json:"packageFileName,omitempty"`
+	Download         string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Purpose          string            `json:"primaryPackagePurpose,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	Supplier         string            `json:"supplier,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+}
+
+type spdxRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	ID                string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []spdxPackage      `json:"packages"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+// spdxID turns a Ref into an SPDX identifier, which allows letters,
+// digits, dots and dashes only.
+func spdxID(ref string) string {
+	var sb strings.Builder
+	sb.WriteString("SPDXRef-")
+	for _, r := range ref {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
+func (c *Component) spdx() spdxPackage {
+	p := spdxPackage{
+		ID: spdxID(c.Ref), Name: c.Name, Version: c.Version, Download: "NOASSERTION",
+		Purpose: strings.ToUpper(c.Type), Comment: "source: " + c.Source,
+		LicenseConcluded: "NOASSERTION", LicenseDeclared: "NOASSERTION", CopyrightText: "NOASSERTION",
+	}
+	if c.Path != "" {
+		p.FileName = c.Path
+	}
+	if c.SHA1 != "" {
+		p.Checksums = []spdxChecksum{{"SHA1", c.SHA1}, {"SHA256", c.SHA256}}
+	}
+	if c.PURL != "" {
+		p.ExternalRefs = []spdxExternalRef{{"PACKAGE-MANAGER", "purl", c.PURL}}
+	}
+	if c.Package != nil && c.Package.OS != "" {
+		p.Supplier = "Organization: " + c.Package.OS
+	}
+	return p
+}
+
+// SPDX returns b as an SPDX 2.3 JSON document, with DEPENDS_ON
+// relationships to the libraries a component loads and CONTAINS ones
+// to the components linked into it.
+func (b *BOM) SPDX() ([]byte, error) {
+	var d spdxDocument
+	d.SPDXVersion, d.DataLicense, d.ID = "SPDX-2.3", "CC0-1.0", "SPDXRef-DOCUMENT"
+	d.Name = b.Main.Name
+	d.DocumentNamespace = "https://spdx.org/spdxdocs/" + toolName + "-" + b.Serial
+	d.CreationInfo.Created = b.Created.Format(time.RFC3339)
+	d.CreationInfo.Creators = []string{"Tool: " + toolName}
+	d.Relationships = []spdxRelationship{{d.ID, "DESCRIBES", spdxID(b.Main.Ref)}}
+	for _, c := range append([]*Component{b.Main}, b.Components...) {
+		d.Packages = append(d.Packages, c.spdx())
+		for _, r := range c.DependsOn {
+			d.Relationships = append(d.Relationships, spdxRelationship{spdxID(c.Ref), "DEPENDS_ON", spdxID(r)})
+		}
+		for _, r := range c.Contains {
+			d.Relationships = append(d.Relationships, spdxRelationship{spdxID(c.Ref), "CONTAINS", spdxID(r)})
+		}
+	}
+	return json.MarshalIndent(d, "", "  ")
+}
@@ -0,0 +1,169 @@
+package sbom
+
+import (
+	"bufio"
+	"debug/elf"
+	"elfreader/file"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultLibDirs are searched last, after the ld.so.conf directories,
+// like the dynamic loader of glibc does.
+var defaultLibDirs = []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib"}
+
+// resolver finds the libraries of DT_NEEDED entries the way the glibc
+// dynamic loader does, under an optional root directory.
+type resolver struct {
+	root     string
+	libPath  []string
+	confDirs []string
+	// exeRPath is the expanded DT_RPATH of the executable, searched for
+	// all the objects without a DT_RUNPATH
+	exeRPath []string
+}
+
+func newResolver(root string, libPath []string) *resolver {
+	r := &resolver{root: root, libPath: libPath}
+	r.confDirs = r.readConf("/etc/ld.so.conf", 0)
+	return r
+}
+
+// readConf returns the directories listed in the ld.so.conf file name,
+// following include lines.
+func (r *resolver) readConf(name string, depth int) []string {
+	if depth > 8 {
+		return nil
+	}
+	fd, err := os.Open(r.rooted(name))
+	if err != nil {
+		return nil
+	}
+	defer fd.Close()
+	var dirs []string
+	sc := bufio.NewScanner(fd)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "include") {
+			for _, pat := range strings.Fields(line)[1:] {
+				if !filepath.IsAbs(pat) {
+					pat = filepath.Join(filepath.Dir(name), pat)
+				}
+				matches, _ := filepath.Glob(r.rooted(pat))
+				for _, m := range matches {
+					dirs = append(dirs, r.readConf(r.unrooted(m), depth+1)...)
+				}
+			}
+			continue
+		}
+		if line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs
+}
+
+func (r *resolver) rooted(p string) string {
+	if r.root == "" {
+		return p
+	}
+	return filepath.Join(r.root, p)
+}
+
+func (r *resolver) unrooted(p string) string {
+	if r.root == "" {
+		return p
+	}
+	rel, err := filepath.Rel(r.root, p)
+	if err != nil {
+		return p
+	}
+	return "/" + rel
+}
+
+// expand splits a DT_RPATH or DT_RUNPATH of the object at path and
+// substitutes its dynamic string tokens. Directories relative to
+// $ORIGIN are relative to the object itself, the others to the root.
+func (r *resolver) expand(list []string, path string, f *file.File) []string {
+	lib := "lib"
+	if f.Class == elf.ELFCLASS64 {
+		lib = "lib64"
+	}
+	var dirs []string
+	for _, l := range list {
+		for _, d := range strings.Split(l, ":") {
+			if d == "" {
+				continue
+			}
+			for _, tok := range []string{"$LIB", "${LIB}"} {
+				d = strings.ReplaceAll(d, tok, lib)
+			}
+			if strings.Contains(d, "ORIGIN") {
+				for _, tok := range []string{"$ORIGIN", "${ORIGIN}"} {
+					d = strings.ReplaceAll(d, tok, filepath.Dir(path))
+				}
+			} else {
+				d = r.rooted(d)
+			}
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// resolve returns the path of the library name needed by f, opened from
+// path, or "" if it is not found. The directories of libPath are not
+// under the root.
+func (r *resolver) resolve(name string, f *file.File, path string) string {
+	if strings.ContainsRune(name, '/') {
+		// relative names are relative to the working directory, as
+		// for the loader
+		p := name
+		if filepath.IsAbs(p) {
+			p = r.rooted(p)
+		}
+		if r.matches(p, f) {
+			return p
+		}
+		return ""
+	}
+	rpath, _ := f.DynString(elf.DT_RPATH)
+	runpath, _ := f.DynString(elf.DT_RUNPATH)
+
+	var dirs []string
+	if len(runpath) == 0 {
+		dirs = append(dirs, r.expand(rpath, path, f)...)
+		dirs = append(dirs, r.exeRPath...)
+	}
+	dirs = append(dirs, r.libPath...)
+	dirs = append(dirs, r.expand(runpath, path, f)...)
+	for _, d := range r.confDirs {
+		dirs = append(dirs, r.rooted(d))
+	}
+	for _, d := range defaultLibDirs {
+		dirs = append(dirs, r.rooted(d))
+	}
+	for _, d := range dirs {
+		p := filepath.Join(d, name)
+		if r.matches(p, f) {
+			return p
+		}
+	}
+	return ""
+}
+
+// matches tells whether p is an ELF file loadable alongside f: same
+// class and machine.
+func (r *resolver) matches(p string, f *file.File) bool {
+	l, err := file.Open(p)
+	if err != nil {
+		return false
+	}
+	defer l.Close()
+	return l.Class == f.Class && l.Machine == f.Machine
+}
@@ -0,0 +1,273 @@
+// Package sbom builds software bills of materials of ELF files from
+// what the files record about their components: the shared libraries
+// of DT_NEEDED, resolved like the dynamic loader does, the FDO package
+// metadata notes, the module list of Go binaries and the version
+// strings of statically linked libraries. The bill is written as
+// CycloneDX or SPDX JSON.
+package sbom
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"debug/elf"
+	"elfreader/file"
+	"elfreader/gobin"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A Component is a part of the analyzed file.
+type Component struct {
+	// Ref identifies the component within the bill
+	Ref string
+	// Type is "application", "library" or "operating-system"
+	Type    string
+	Name    string
+	Version string
+	PURL    string
+	// Path is the local file of the component, "" for components that
+	// are not files of their own or were not found
+	Path   string
+	SHA1   string
+	SHA256 string
+	// Source tells how the component was found
+	Source string
+	// Package is the package metadata note of the file, or nil
+	Package *Package
+	// DependsOn are the Refs of the libraries the component loads
+	DependsOn []string
+	// Contains are the Refs of the components linked into it
+	Contains []string
+}
+
+// A BOM is the bill of materials of a file.
+type BOM struct {
+	// Main is the analyzed file
+	Main *Component
+	// Components are the others, in the order they were found
+	Components []*Component
+	Created    time.Time
+	// Serial is a random UUID naming this bill
+	Serial string
+
+	refs map[string]bool
+}
+
+// Options controls the search of libraries.
+type Options struct {
+	// Root is the directory the absolute library paths are under,
+	// "" for the root of this system
+	Root string
+	// LibraryPath are searched first, like LD_LIBRARY_PATH
+	LibraryPath []string
+}
+
+// Build returns the bill of materials of the ELF file at path.
+func Build(path string, o Options) (*BOM, error) {
+	f, err := file.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &BOM{Created: time.Now().UTC().Truncate(time.Second), Serial: newUUID()}
+	typ := "application"
+	if f.Type == elf.ET_DYN && !hasInterp(f) {
+		typ = "library"
+	}
+	b.Main = &Component{Ref: b.newRef("file:" + filepath.Base(path)), Type: typ, Name: filepath.Base(path), Path: path, Source: "analyzed file"}
+	if err := b.describe(b.Main, f); err != nil {
+		return nil, err
+	}
+
+	if gobin.IsGo(f) {
+		b.goModules(f, path)
+	}
+
+	r := newResolver(o.Root, o.LibraryPath)
+	rpath, _ := f.DynString(elf.DT_RPATH)
+	r.exeRPath = r.expand(rpath, path, f)
+	byPath := map[string]*Component{path: b.Main}
+	type item struct {
+		c    *Component
+		f    *file.File
+		path string
+	}
+	// breadth first, as the loader loads them
+	queue := []item{{b.Main, f, path}}
+	defer func() {
+		for _, it := range queue {
+			if it.f != f {
+				it.f.Close()
+			}
+		}
+	}()
+	for i := 0; i < len(queue); i++ {
+		it := queue[i]
+		needed, _ := it.f.DynString(elf.DT_NEEDED)
+		for _, name := range needed {
+			p := r.resolve(name, it.f, it.path)
+			key := p
+			if p == "" {
+				key = "missing:" + name
+			}
+			if c, ok := byPath[key]; ok {
+				it.c.DependsOn = appendRef(it.c.DependsOn, c.Ref)
+				continue
+			}
+			c := &Component{Ref: b.newRef("lib:" + name), Type: "library", Name: name, Path: p, Source: "DT_NEEDED"}
+			byPath[key] = c
+			b.Components = append(b.Components, c)
+			it.c.DependsOn = appendRef(it.c.DependsOn, c.Ref)
+			if p == "" {
+				c.Source = "DT_NEEDED, not found"
+				continue
+			}
+			lf, err := file.Open(p)
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, item{c, lf, p})
+			if err := b.describe(c, lf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// describe fills in the hashes of the file of c, its package metadata
+// and the libraries embedded in it.
+func (b *BOM) describe(c *Component, f *file.File) error {
+	var err error
+	if c.SHA1, c.SHA256, err = hashFile(c.Path); err != nil {
+		return err
+	}
+	if c.Type == "library" && c.Version == "" {
+		// libfoo.so.1 is usually a link to libfoo.so.1.2.3
+		if real, err := filepath.EvalSymlinks(c.Path); err == nil && filepath.Base(real) != c.Name {
+			if i := strings.Index(filepath.Base(real), ".so."); i >= 0 {
+				c.Version = filepath.Base(real)[i+4:]
+			}
+		}
+	}
+	if p := PackageNote(f); p != nil {
+		c.Package = p
+		c.Name, c.Version, c.PURL = p.Name, p.Version, p.PURL()
+		c.Source += ", .note.package"
+	}
+	for _, e := range EmbeddedVersions(f) {
+		ref := "embedded:" + e.Name + "@" + e.Version
+		if !b.refs[ref] {
+			b.refs[ref] = true
+			b.Components = append(b.Components, &Component{
+				Ref: ref, Type: "library", Name: e.Name, Version: e.Version,
+				PURL: "pkg:generic/" + e.Name + "@" + e.Version, Source: fmt.Sprintf("version string %q", e.Text),
+			})
+		}
+		c.Contains = appendRef(c.Contains, ref)
+	}
+	return nil
+}
+
+// goModules adds the Go toolchain and the modules of the Go binary f as
+// components contained in the main one.
+func (b *BOM) goModules(f *file.File, path string) {
+	bi, err := gobin.BuildInfo(f, path)
+	if err != nil {
+		return
+	}
+	if bi.Main.Path != "" {
+		b.Main.Name = bi.Main.Path
+		if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			b.Main.Version = bi.Main.Version
+		}
+		b.Main.PURL = goPURL(bi.Main.Path, b.Main.Version)
+	}
+	add := func(c *Component) {
+		c.Ref = b.newRef(c.Ref)
+		b.Components = append(b.Components, c)
+		b.Main.Contains = appendRef(b.Main.Contains, c.Ref)
+	}
+	add(&Component{Ref: "go:stdlib@" + bi.GoVersion, Type: "library", Name: "stdlib", Version: bi.GoVersion,
+		PURL: goPURL("stdlib", bi.GoVersion), Source: "Go build info"})
+	for _, d := range bi.Deps {
+		if d.Replace != nil {
+			d = d.Replace
+		}
+		add(&Component{Ref: "go:" + d.Path + "@" + d.Version, Type: "library", Name: d.Path, Version: d.Version,
+			PURL: goPURL(d.Path, d.Version), Source: "Go build info"})
+	}
+}
+
+func goPURL(path, version string) string {
+	u := "pkg:golang/" + path
+	if version != "" {
+		u += "@" + version
+	}
+	return u
+}
+
+func appendRef(refs []string, ref string) []string {
+	for _, r := range refs {
+		if r == ref {
+			return refs
+		}
+	}
+	return append(refs, ref)
+}
+
+// newRef returns base, or base with a suffix if another component has
+// it already, as two libraries of one name can be found in different
+// directories.
+func (b *BOM) newRef(base string) string {
+	if b.refs == nil {
+		b.refs = make(map[string]bool)
+	}
+	ref := base
+	for n := 2; b.refs[ref]; n++ {
+		ref = fmt.Sprintf("%s#%d", base, n)
+	}
+	b.refs[ref] = true
+	return ref
+}
+
+func hashFile(path string) (string, string, error) {
+	if path == "" {
+		return "", "", nil
+	}
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer fd.Close()
+	h1, h256 := sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(h1, h256), fd); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(h1.Sum(nil)), hex.EncodeToString(h256.Sum(nil)), nil
+}
+
+func hasInterp(f *file.File) bool {
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_INTERP {
+			return true
+		}
+	}
+	return false
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
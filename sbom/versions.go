@@ -0,0 +1,202 @@
+package sbom
+
+import (
+	"bytes"
+	"debug/elf"
+	"elfreader/file"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// A Package is the FDO package metadata note of an ELF file, which
+// distributions embed to tie binaries to the package that built them.
+// See https://systemd.io/ELF_PACKAGE_METADATA/.
+type Package struct {
+	Type         string `json:"type"`
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	OSVersion    string `json:"osVersion"`
+	Debuginfod   string `json:"debugInfoUrl"`
+}
+
+const ntFDOPackagingMetadata = 0xcafe1a7e
+
+// PackageNote returns the FDO package metadata of f, or nil if it has
+// none.
+func PackageNote(f *file.File) *Package {
+	var data [][]byte
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NOTE {
+			data = append(data, s.Data())
+		}
+	}
+	if len(f.Sections) == 0 {
+		for _, p := range f.Progs {
+			if p.Type != elf.PT_NOTE {
+				continue
+			}
+			b := make([]byte, p.Filesz)
+			if _, err := p.ReadAt(b, 0); err == nil {
+				data = append(data, b)
+			}
+		}
+	}
+	for _, d := range data {
+		for len(d) >= 12 {
+			namesz := f.ByteOrder.Uint32(d[0:4])
+			descsz := f.ByteOrder.Uint32(d[4:8])
+			typ := f.ByteOrder.Uint32(d[8:12])
+			d = d[12:]
+			nlen, dlen := uint64(namesz+3)&^3, uint64(descsz+3)&^3
+			if nlen+uint64(descsz) > uint64(len(d)) {
+				break
+			}
+			name := bytes.TrimRight(d[:namesz], "\x00")
+			if string(name) == "FDO" && typ == ntFDOPackagingMetadata {
+				var p Package
+				desc := bytes.TrimRight(d[nlen:nlen+uint64(descsz)], "\x00")
+				if json.Unmarshal(desc, &p) == nil && p.Name != "" {
+					return &p
+				}
+			}
+			if nlen+dlen > uint64(len(d)) {
+				break
+			}
+			d = d[nlen+dlen:]
+		}
+	}
+	return nil
+}
+
+// PURL returns the package URL of p.
+func (p *Package) PURL() string {
+	typ := p.Type
+	switch typ {
+	case "deb", "rpm", "apk", "alpm":
+	default:
+		typ = "generic"
+	}
+	u := "pkg:" + typ + "/"
+	if typ != "generic" && p.OS != "" {
+		u += p.OS + "/"
+	}
+	u += p.Name
+	if p.Version != "" {
+		u += "@" + p.Version
+	}
+	var q []string
+	if p.Architecture != "" {
+		q = append(q, "arch="+p.Architecture)
+	}
+	if p.OS != "" && p.OSVersion != "" {
+		q = append(q, "distro="+p.OS+"-"+p.OSVersion)
+	}
+	for i, s := range q {
+		if i == 0 {
+			u += "?" + s
+		} else {
+			u += "&" + s
+		}
+	}
+	return u
+}
+
+// An Embedded is a library version string found in the read-only data
+// of a file, as left by statically linked libraries.
+type Embedded struct {
+	Name    string
+	Version string
+	// Text is the first line of the string
+	Text string
+}
+
+// versionPatterns recognize the version strings of common libraries;
+// the first submatch is the version.
+var versionPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"glibc", regexp.MustCompile(`^GNU C Library \([^)]*\) (?:stable |development )?release version (\d+\.\d+(?:\.\d+)?)`)},
+	{"openssl", regexp.MustCompile(`^OpenSSL (\d+\.\d+\.\d+[a-z]?) `)},
+	{"zlib", regexp.MustCompile(`^ (?:in|de)flate (\d+\.\d+(?:\.\d+)*) Copyright`)},
+	{"libpng", regexp.MustCompile(`^libpng version (\d+\.\d+\.\d+)`)},
+	{"curl", regexp.MustCompile(`^libcurl/(\d+\.\d+\.\d+)`)},
+	{"expat", regexp.MustCompile(`^expat_(\d+\.\d+\.\d+)$`)},
+	{"sqlite", regexp.MustCompile(`^(3\.\d+\.\d+)$`)},
+	{"busybox", regexp.MustCompile(`^BusyBox v(\d+\.\d+\.\d+)`)},
+	{"lz4", regexp.MustCompile(`^LZ4 (?:command line interface )?v?(\d+\.\d+\.\d+)`)},
+	{"pcre2", regexp.MustCompile(`^PCRE2 (\d+\.\d+)`)},
+}
+
+// EmbeddedVersions returns the library version strings in the
+// read-only data of f, each library once.
+func EmbeddedVersions(f *file.File) []Embedded {
+	var found []Embedded
+	seen := make(map[string]bool)
+	scan := func(data []byte) {
+		sqlite := sqliteSource(data)
+		for _, s := range printable(data, 6) {
+			for _, p := range versionPatterns {
+				if seen[p.name] {
+					continue
+				}
+				if p.name == "sqlite" && !sqlite {
+					continue
+				}
+				if m := p.re.FindStringSubmatch(s); m != nil {
+					seen[p.name] = true
+					if i := strings.IndexByte(s, '\n'); i >= 0 {
+						s = s[:i]
+					}
+					found = append(found, Embedded{p.name, m[1], s})
+				}
+			}
+		}
+	}
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_PROGBITS && s.Flags&elf.SHF_ALLOC != 0 && s.Flags&(elf.SHF_EXECINSTR|elf.SHF_WRITE) == 0 {
+			scan(s.Data())
+		}
+	}
+	if len(f.Sections) == 0 {
+		for _, p := range f.Progs {
+			if p.Type != elf.PT_LOAD || p.Flags&(elf.PF_X|elf.PF_W) != 0 {
+				continue
+			}
+			b := make([]byte, p.Filesz)
+			if _, err := p.ReadAt(b, 0); err == nil {
+				scan(b)
+			}
+		}
+	}
+	return found
+}
+
+// sqliteSource tells whether data holds the source id of SQLite, which
+// makes its bare version string meaningful.
+func sqliteSource(data []byte) bool {
+	return bytes.Contains(data, []byte("sqlite_source_id")) || bytes.Contains(data, []byte("SQLite format 3"))
+}
+
+// printable returns the runs of at least min printable ASCII bytes of
+// data ended by a NUL.
+func printable(data []byte, min int) []string {
+	var out []string
+	start := -1
+	for i, b := range data {
+		if b >= 0x20 && b < 0x7f || b == '\t' || b == '\n' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && b == 0 && i-start >= min {
+			out = append(out, string(data[start:i]))
+		}
+		start = -1
+	}
+	return out
+}
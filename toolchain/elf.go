@@ -0,0 +1,124 @@
+package toolchain
+
+import (
+	"bytes"
+	"debug/elf"
+	"elfreader/file"
+)
+
+const ntGNUGoldVersion = 4
+
+type note struct {
+	name  string
+	typ   uint32
+	desc  []byte
+	where string
+}
+
+// readNotes decodes the notes of the SHT_NOTE sections of f, or of its
+// PT_NOTE segments if it has no section headers.
+func readNotes(f *file.File) []note {
+	var notes []note
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NOTE {
+			notes = append(notes, parseNotes(f, s.Data(), s.Name)...)
+		}
+	}
+	if len(f.Sections) > 0 {
+		return notes
+	}
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		b := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(b, 0); err == nil {
+			notes = append(notes, parseNotes(f, b, "PT_NOTE")...)
+		}
+	}
+	return notes
+}
+
+func parseNotes(f *file.File, d []byte, where string) []note {
+	var notes []note
+	for len(d) >= 12 {
+		namesz := f.ByteOrder.Uint32(d[0:4])
+		descsz := f.ByteOrder.Uint32(d[4:8])
+		typ := f.ByteOrder.Uint32(d[8:12])
+		d = d[12:]
+		nlen, dlen := uint64(namesz+3)&^3, uint64(descsz+3)&^3
+		if nlen+uint64(descsz) > uint64(len(d)) {
+			break
+		}
+		name := bytes.TrimRight(d[:namesz], "\x00")
+		notes = append(notes, note{string(name), typ, d[nlen : nlen+uint64(descsz)], where})
+		if nlen+dlen > uint64(len(d)) {
+			break
+		}
+		d = d[nlen+dlen:]
+	}
+	return notes
+}
+
+// interpreter returns the PT_INTERP path of f, or "".
+func interpreter(f *file.File) string {
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_INTERP {
+			continue
+		}
+		b := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(b, 0); err != nil {
+			return ""
+		}
+		return string(bytes.TrimRight(b, "\x00"))
+	}
+	return ""
+}
+
+// linkedStrings returns the contents of the string table s links to.
+func linkedStrings(f *file.File, s *file.Section) []byte {
+	if int(s.Link) >= len(f.Sections) {
+		return nil
+	}
+	return f.Sections[s.Link].Data()
+}
+
+func cstring(b []byte, off uint64) string {
+	if off >= uint64(len(b)) {
+		return ""
+	}
+	b = b[off:]
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// versionNeeds returns the symbol versions f needs, from
+// .gnu.version_r.
+func versionNeeds(f *file.File) []string {
+	s := f.SectionByType(elf.SHT_GNU_VERNEED)
+	if s == nil {
+		return nil
+	}
+	d, str := s.Data(), linkedStrings(f, s)
+	var names []string
+	for off := uint64(0); off+16 <= uint64(len(d)); {
+		cnt := f.ByteOrder.Uint16(d[off+2:])
+		aux := off + uint64(f.ByteOrder.Uint32(d[off+8:]))
+		for i := 0; i < int(cnt) && aux+16 <= uint64(len(d)); i++ {
+			names = append(names, cstring(str, uint64(f.ByteOrder.Uint32(d[aux+8:]))))
+			next := f.ByteOrder.Uint32(d[aux+12:])
+			if next == 0 {
+				break
+			}
+			aux += uint64(next)
+		}
+		next := f.ByteOrder.Uint32(d[off+12:])
+		if next == 0 {
+			break
+		}
+		off += uint64(next)
+	}
+	return names
+}
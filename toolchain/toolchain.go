@@ -0,0 +1,444 @@
+// Package toolchain identifies the tools that produced an ELF file: the
+// compilers and assemblers named in .comment and DW_AT_producer, the
+// linker, the C library the file was built against and the source
+// languages of its compile units. The linker and C library are guessed
+// from indirect evidence, which the report lists.
+package toolchain
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"elfreader/debuginfo"
+	"elfreader/file"
+	"elfreader/gobin"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Tool is a compiler, assembler or linker named in the file.
+type Tool struct {
+	// Kind is "compiler", "assembler" or "linker"
+	Kind    string
+	Name    string
+	Version string
+	// Source is where the tool is named, such as ".comment" or
+	// "DW_AT_producer"
+	Source string
+	// Text is the string naming the tool
+	Text string
+	// Units counts the compile units of a DW_AT_producer
+	Units int
+}
+
+// A Finding is the linker or C library the evidence points to.
+type Finding struct {
+	// Name is "" if nothing points to one
+	Name     string
+	Version  string
+	Evidence []string
+}
+
+func (f *Finding) add(name, version, evidence string) {
+	if f.Name == "" {
+		f.Name, f.Version = name, version
+	}
+	f.Evidence = append(f.Evidence, evidence)
+}
+
+// A Language is a source language of the file.
+type Language struct {
+	Name string
+	// Dialects are the DW_AT_language values of the units, such as
+	// "C11" for C
+	Dialects []string
+	// Units counts the compile units, 0 if the language is guessed
+	// from symbols and libraries
+	Units  int
+	Source string
+}
+
+// A Report is what is known of the toolchain of a file.
+type Report struct {
+	Tools     []Tool
+	Linker    Finding
+	Libc      Finding
+	Languages []Language
+}
+
+// toolPatterns recognize the tool strings of .comment and
+// DW_AT_producer; the first match wins.
+var toolPatterns = []struct {
+	re         *regexp.Regexp
+	kind, name string
+}{
+	{regexp.MustCompile(`^GCC: \([^)]*\) (\S+)`), "compiler", "GCC"},
+	{regexp.MustCompile(`^GNU AS (\S+)`), "assembler", "GNU as"},
+	{regexp.MustCompile(`^GNU [A-Za-z+]+[0-9]* ([0-9]\S*)`), "compiler", "GCC"},
+	// rustc producers read "clang LLVM (rustc version ...)"
+	{regexp.MustCompile(`rustc version (\S+)`), "compiler", "rustc"},
+	{regexp.MustCompile(`clang version (\S+)`), "compiler", "clang"},
+	{regexp.MustCompile(`^Go cmd/compile ([^\s;]+)`), "compiler", "Go"},
+	{regexp.MustCompile(`^Linker: LLD (\S+)`), "linker", "LLD"},
+	{regexp.MustCompile(`^mold (\S+)`), "linker", "mold"},
+}
+
+func parseTool(text, source string) Tool {
+	for _, p := range toolPatterns {
+		if m := p.re.FindStringSubmatch(text); m != nil {
+			return Tool{Kind: p.kind, Name: p.name, Version: m[1], Source: source, Text: text}
+		}
+	}
+	return Tool{Kind: "unknown", Source: source, Text: text}
+}
+
+// Analyze identifies the toolchain of f, opened from path. d is the
+// DWARF of f or of its separate debug file, or nil.
+func Analyze(f *file.File, path string, d *dwarf.Data) (*Report, error) {
+	r := &Report{}
+	if s := f.Section(".comment"); s != nil && s.Type != elf.SHT_NOBITS {
+		for _, c := range bytes.Split(s.Data(), []byte{0}) {
+			if c := strings.TrimSpace(string(c)); c != "" {
+				r.Tools = append(r.Tools, parseTool(c, ".comment"))
+			}
+		}
+	}
+	if gobin.IsGo(f) {
+		if bi, err := gobin.BuildInfo(f, path); err == nil {
+			r.Tools = append(r.Tools, Tool{Kind: "compiler", Name: "Go", Version: bi.GoVersion, Source: "build info", Text: bi.GoVersion})
+		}
+	}
+	var err error
+	if d != nil {
+		err = r.units(d)
+	}
+
+	notes := readNotes(f)
+	r.findLinker(f, notes)
+	r.findLibc(f, notes)
+	if len(r.Languages) == 0 {
+		r.guessLanguages(f)
+	}
+	return r, err
+}
+
+// units collects the producers and languages of the compile units of d.
+func (r *Report) units(d *dwarf.Data) error {
+	producers := make(map[string]int)
+	langs := make(map[string]*Language)
+	rd := d.Reader()
+	for {
+		e, err := rd.Next()
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag != dwarf.TagCompileUnit {
+			rd.SkipChildren()
+			continue
+		}
+		if p, ok := e.Val(dwarf.AttrProducer).(string); ok && p != "" {
+			producers[p]++
+		}
+		if v, ok := e.Val(dwarf.AttrLanguage).(int64); ok {
+			dialect := strings.TrimPrefix(debuginfo.LangName(uint64(v)), "DW_LANG_")
+			name := langFamily(dialect)
+			l, ok := langs[name]
+			if !ok {
+				l = &Language{Name: name, Source: "DW_AT_language"}
+				langs[name] = l
+			}
+			l.Units++
+			if !contains(l.Dialects, dialect) {
+				l.Dialects = append(l.Dialects, dialect)
+			}
+		}
+		rd.SkipChildren()
+	}
+
+	texts := make([]string, 0, len(producers))
+	for p := range producers {
+		texts = append(texts, p)
+	}
+	sort.Slice(texts, func(i, j int) bool {
+		if a, b := producers[texts[i]], producers[texts[j]]; a != b {
+			return a > b
+		}
+		return texts[i] < texts[j]
+	})
+	for _, p := range texts {
+		t := parseTool(p, "DW_AT_producer")
+		t.Units = producers[p]
+		r.Tools = append(r.Tools, t)
+	}
+	for _, l := range langs {
+		sort.Strings(l.Dialects)
+		r.Languages = append(r.Languages, *l)
+	}
+	sort.Slice(r.Languages, func(i, j int) bool {
+		if a, b := r.Languages[i].Units, r.Languages[j].Units; a != b {
+			return a > b
+		}
+		return r.Languages[i].Name < r.Languages[j].Name
+	})
+	return nil
+}
+
+// langFamily returns the language of a DW_LANG_ name without its
+// prefix, folding the dialects of C, C++, Fortran and others.
+func langFamily(dialect string) string {
+	switch {
+	case dialect == "C" || dialect == "C89" || dialect == "C99" || dialect == "C11" || dialect == "C17":
+		return "C"
+	case strings.HasPrefix(dialect, "C_plus_plus"):
+		return "C++"
+	case strings.HasPrefix(dialect, "ObjC_plus_plus"):
+		return "Objective-C++"
+	case strings.HasPrefix(dialect, "ObjC"):
+		return "Objective-C"
+	case strings.HasPrefix(dialect, "Fortran"):
+		return "Fortran"
+	case strings.HasPrefix(dialect, "Ada"):
+		return "Ada"
+	case strings.HasPrefix(dialect, "Cobol"):
+		return "COBOL"
+	case dialect == "Mips_Assembler":
+		return "assembly"
+	}
+	return dialect
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) hasTool(name string) bool {
+	for _, t := range r.Tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findLinker looks for the marks the linkers leave: LLD and mold name
+// themselves in .comment, gold in a note, and the Go linker leaves no C
+// runtime sections. GNU ld leaves none, so it is assumed when the
+// others are ruled out, with the section order as a hint: GNU ld puts
+// .rodata after .text, LLD and mold before it.
+func (r *Report) findLinker(f *file.File, notes []note) {
+	for _, t := range r.Tools {
+		if t.Kind == "linker" {
+			r.Linker.add(t.Name, t.Version, fmt.Sprintf("%s: %q", t.Source, t.Text))
+		}
+	}
+	for _, n := range notes {
+		if n.name == "GNU" && n.typ == ntGNUGoldVersion {
+			v := strings.TrimRight(string(n.desc), "\x00")
+			r.Linker.add("gold", strings.TrimPrefix(v, "gold "), fmt.Sprintf("%s: %q", n.where, v))
+		}
+	}
+	if gobin.IsGo(f) {
+		if f.Section(".init") == nil && f.Section(".init_array") == nil {
+			r.Linker.add("Go linker", "", "Go binary without C runtime sections")
+		} else {
+			r.Linker.Evidence = append(r.Linker.Evidence, "Go binary linked externally")
+		}
+	}
+
+	text, rodata := f.Section(".text"), f.Section(".rodata")
+	if text != nil && rodata != nil && text.Addr != 0 && rodata.Addr != 0 {
+		if rodata.Addr < text.Addr {
+			r.Linker.add("LLD", "", fmt.Sprintf(".rodata at 0x%x precedes .text at 0x%x", rodata.Addr, text.Addr))
+		} else {
+			r.Linker.add("GNU ld", "", fmt.Sprintf(".rodata at 0x%x follows .text at 0x%x", rodata.Addr, text.Addr))
+		}
+	}
+}
+
+// C library names by dynamic loader.
+var interpLibcs = []struct{ substr, libc string }{
+	{"ld-musl", "musl"},
+	{"/system/bin/linker", "bionic"},
+	{"ld-uClibc", "uClibc"},
+	{"ld-linux", "glibc"},
+	{"ld64.so", "glibc"},
+	{"ld.so.1", "glibc"},
+}
+
+// findLibc looks at the dynamic loader, the libraries and versions
+// imported and, in static binaries, at the symbols the C libraries
+// define.
+func (r *Report) findLibc(f *file.File, notes []note) {
+	if interp := interpreter(f); interp != "" {
+		for _, l := range interpLibcs {
+			if strings.Contains(interp, l.substr) {
+				r.Libc.add(l.libc, "", "PT_INTERP "+interp)
+				break
+			}
+		}
+		if r.Libc.Name == "" {
+			r.Libc.Evidence = append(r.Libc.Evidence, "PT_INTERP "+interp)
+		}
+	}
+
+	needs := versionNeeds(f)
+	libs, _ := f.DynString(elf.DT_NEEDED)
+	for _, lib := range libs {
+		switch {
+		case lib == "libc.so.6":
+			r.Libc.add("glibc", "", "DT_NEEDED "+lib)
+		case strings.HasPrefix(lib, "libc.musl"):
+			r.Libc.add("musl", "", "DT_NEEDED "+lib)
+		case lib == "libc.so":
+			// the name musl and bionic both use
+			r.Libc.add("musl", "", "DT_NEEDED "+lib)
+		}
+	}
+	if newest := newestGLIBC(needs); newest != "" {
+		r.Libc.add("glibc", "", "requires "+newest)
+		if r.Libc.Name == "glibc" {
+			r.Libc.Version = strings.TrimPrefix(newest, "GLIBC_")
+		}
+	}
+
+	if r.Libc.Name == "" {
+		syms, _ := f.Symbols()
+		names := make(map[string]bool, len(syms))
+		for _, s := range syms {
+			if s.Section != elf.SHN_UNDEF {
+				names[s.Name] = true
+			}
+		}
+		for _, m := range staticLibcSymbols {
+			if names[m.sym] {
+				r.Libc.add(m.libc, "", "defines "+m.sym)
+			}
+		}
+		if r.Libc.Name == "" && gobin.IsGo(f) && interpreter(f) == "" {
+			r.Libc.add("none", "", "static Go binary")
+		}
+	}
+
+	for _, n := range notes {
+		switch {
+		case n.name == "Android" && n.typ == 1:
+			r.Libc.Name = "bionic"
+			r.Libc.Evidence = append(r.Libc.Evidence, n.where+": Android ident note")
+		case n.name == "GNU" && n.typ == 1 && r.Libc.Name != "":
+			r.Libc.Evidence = append(r.Libc.Evidence, n.where+": GNU ABI tag")
+		case n.name == "GNU" && n.typ == 1:
+			r.Libc.add("glibc", "", n.where+": GNU ABI tag")
+		}
+	}
+}
+
+// staticLibcSymbols are internal symbols of the C libraries, which
+// static binaries define.
+var staticLibcSymbols = []struct{ sym, libc string }{
+	{"__libc_setup_tls", "glibc"},
+	{"_dl_relocate_static_pie", "glibc"},
+	{"__init_libc", "musl"},
+	{"__libc_start_init", "musl"},
+	{"__libc_init", "bionic"},
+}
+
+// newestGLIBC returns the newest of the GLIBC_ versions in needs.
+func newestGLIBC(needs []string) string {
+	var newest string
+	var nv []int
+	for _, n := range needs {
+		if !strings.HasPrefix(n, "GLIBC_") {
+			continue
+		}
+		v := parseVersion(strings.TrimPrefix(n, "GLIBC_"))
+		if v == nil {
+			continue
+		}
+		if newest == "" || compareVersions(v, nv) > 0 {
+			newest, nv = n, v
+		}
+	}
+	return newest
+}
+
+func parseVersion(s string) []int {
+	var v []int
+	for _, p := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil
+		}
+		v = append(v, n)
+	}
+	return v
+}
+
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// guessLanguages names the languages of a file without DW_AT_language
+// from its runtime libraries and symbol manglings.
+func (r *Report) guessLanguages(f *file.File) {
+	libs, _ := f.DynString(elf.DT_NEEDED)
+	syms, _ := f.Symbols()
+	dyn, _ := f.DynamicSymbols()
+	syms = append(syms, dyn...)
+	has := func(pred func(string) bool) bool {
+		for _, s := range syms {
+			if pred(s.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	hasLib := func(prefix string) bool {
+		for _, l := range libs {
+			if strings.HasPrefix(l, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	add := func(name, source string) {
+		r.Languages = append(r.Languages, Language{Name: name, Source: source})
+	}
+
+	if gobin.IsGo(f) {
+		add("Go", "Go runtime metadata")
+	}
+	rust := r.hasTool("rustc") || has(func(s string) bool {
+		return s == "rust_begin_unwind" || strings.HasPrefix(s, "_R") && len(s) > 2 && s[2] >= 'A' && s[2] <= 'Z'
+	})
+	if rust {
+		add("Rust", "symbols")
+	}
+	switch {
+	case hasLib("libstdc++") || hasLib("libc++"):
+		add("C++", "C++ runtime library")
+	case !rust && has(func(s string) bool { return strings.HasPrefix(s, "_Z") }):
+		add("C++", "mangled symbols")
+	}
+	if hasLib("libgfortran") || has(func(s string) bool { return strings.HasPrefix(s, "_gfortran_") }) {
+		add("Fortran", "Fortran runtime")
+	}
+	if len(r.Languages) == 0 && (r.hasTool("GCC") || r.hasTool("clang")) {
+		add("C", "compiler")
+	}
+}
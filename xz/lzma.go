@@ -0,0 +1,428 @@
+package xz
+
+import "errors"
+
+var errCorrupt = errors.New("xz: corrupt LZMA2 data")
+
+// range decoder
+
+const (
+	probBits  = 11
+	probInit  = 1 << (probBits - 1)
+	moveBits  = 5
+	topValue  = 1 << 24
+	numStates = 12
+)
+
+type prob uint16
+
+type rangeDecoder struct {
+	data  []byte
+	off   int
+	rng   uint32
+	code  uint32
+	short bool
+}
+
+func (rc *rangeDecoder) init(data []byte) error {
+	rc.data, rc.off, rc.short = data, 0, false
+	if len(data) < 5 || data[0] != 0 {
+		return errCorrupt
+	}
+	rc.rng = 0xffffffff
+	rc.code = 0
+	for i := 1; i < 5; i++ {
+		rc.code = rc.code<<8 | uint32(data[i])
+	}
+	rc.off = 5
+	return nil
+}
+
+func (rc *rangeDecoder) next() byte {
+	if rc.off >= len(rc.data) {
+		rc.short = true
+		return 0
+	}
+	b := rc.data[rc.off]
+	rc.off++
+	return b
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < topValue {
+		rc.rng <<= 8
+		rc.code = rc.code<<8 | uint32(rc.next())
+	}
+}
+
+func (rc *rangeDecoder) bit(p *prob) uint32 {
+	bound := (rc.rng >> probBits) * uint32(*p)
+	var b uint32
+	if rc.code < bound {
+		rc.rng = bound
+		*p += (1<<probBits - *p) >> moveBits
+	} else {
+		rc.rng -= bound
+		rc.code -= bound
+		*p -= *p >> moveBits
+		b = 1
+	}
+	rc.normalize()
+	return b
+}
+
+func (rc *rangeDecoder) direct(n uint) uint32 {
+	var v uint32
+	for ; n > 0; n-- {
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		t := 0 - (rc.code >> 31)
+		rc.code += rc.rng & t
+		v = v<<1 + t + 1
+		rc.normalize()
+	}
+	return v
+}
+
+// tree decodes n bits, most significant first, with the probabilities
+// p[1:1<<n].
+func (rc *rangeDecoder) tree(p []prob, n uint) uint32 {
+	m := uint32(1)
+	for i := uint(0); i < n; i++ {
+		m = m<<1 + rc.bit(&p[m])
+	}
+	return m - 1<<n
+}
+
+// reverseTree decodes n bits, least significant first.
+func (rc *rangeDecoder) reverseTree(p []prob, n uint) uint32 {
+	m, v := uint32(1), uint32(0)
+	for i := uint(0); i < n; i++ {
+		b := rc.bit(&p[m])
+		m = m<<1 + b
+		v |= b << i
+	}
+	return v
+}
+
+// LZMA
+
+const (
+	posStatesMax    = 1 << 4
+	endPosModel     = 14
+	fullDistances   = 1 << (endPosModel >> 1)
+	alignBits       = 4
+	lenLowBits      = 3
+	lenMidBits      = 3
+	lenHighBits     = 8
+	lenStates       = 4
+	posSlotBits     = 6
+	minMatch        = 2
+	literalCoderLen = 0x300
+)
+
+type lenDecoder struct {
+	choice  prob
+	choice2 prob
+	low     [posStatesMax][1 << lenLowBits]prob
+	mid     [posStatesMax][1 << lenMidBits]prob
+	high    [1 << lenHighBits]prob
+}
+
+func (l *lenDecoder) reset() {
+	l.choice, l.choice2 = probInit, probInit
+	for i := range l.low {
+		resetProbs(l.low[i][:])
+		resetProbs(l.mid[i][:])
+	}
+	resetProbs(l.high[:])
+}
+
+func (l *lenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.bit(&l.choice) == 0 {
+		return rc.tree(l.low[posState][:], lenLowBits)
+	}
+	if rc.bit(&l.choice2) == 0 {
+		return 1<<lenLowBits + rc.tree(l.mid[posState][:], lenMidBits)
+	}
+	return 1<<lenLowBits + 1<<lenMidBits + rc.tree(l.high[:], lenHighBits)
+}
+
+func resetProbs(p []prob) {
+	for i := range p {
+		p[i] = probInit
+	}
+}
+
+// lzmaState is the state of the LZMA decoder kept across the chunks of
+// an LZMA2 stream.
+type lzmaState struct {
+	lc, lp, pb uint
+	literal    []prob
+	isMatch    [numStates << 4]prob
+	isRep      [numStates]prob
+	isRepG0    [numStates]prob
+	isRepG1    [numStates]prob
+	isRepG2    [numStates]prob
+	isRep0Long [numStates << 4]prob
+	posSlot    [lenStates][1 << posSlotBits]prob
+	posSpecial [1 + fullDistances - endPosModel]prob
+	align      [1 << alignBits]prob
+	matchLen   lenDecoder
+	repLen     lenDecoder
+
+	state                  uint32
+	rep0, rep1, rep2, rep3 uint32
+}
+
+func (s *lzmaState) setProps(props byte) error {
+	if props >= 9*5*5 {
+		return errCorrupt
+	}
+	d := uint(props)
+	s.lc, d = d%9, d/9
+	s.lp, s.pb = d%5, d/5
+	if s.lc+s.lp > 4 {
+		return errCorrupt
+	}
+	return nil
+}
+
+func (s *lzmaState) reset() {
+	n := literalCoderLen << (s.lc + s.lp)
+	if cap(s.literal) >= n {
+		s.literal = s.literal[:n]
+	} else {
+		s.literal = make([]prob, n)
+	}
+	resetProbs(s.literal)
+	resetProbs(s.isMatch[:])
+	resetProbs(s.isRep[:])
+	resetProbs(s.isRepG0[:])
+	resetProbs(s.isRepG1[:])
+	resetProbs(s.isRepG2[:])
+	resetProbs(s.isRep0Long[:])
+	for i := range s.posSlot {
+		resetProbs(s.posSlot[i][:])
+	}
+	resetProbs(s.posSpecial[:])
+	resetProbs(s.align[:])
+	s.matchLen.reset()
+	s.repLen.reset()
+	s.state = 0
+	s.rep0, s.rep1, s.rep2, s.rep3 = 0, 0, 0, 0
+}
+
+// decode decodes the compressed chunk data, appending size bytes to
+// out. dict is the offset in out of the last dictionary reset.
+func (s *lzmaState) decode(out []byte, dict int, data []byte, size int) ([]byte, error) {
+	var rc rangeDecoder
+	if err := rc.init(data); err != nil {
+		return out, err
+	}
+	end := len(out) + size
+	pbMask := uint32(1)<<s.pb - 1
+	lpMask := uint32(1)<<s.lp - 1
+	for len(out) < end {
+		pos := uint32(len(out) - dict)
+		posState := pos & pbMask
+		st := s.state
+
+		if rc.bit(&s.isMatch[st<<4+posState]) == 0 {
+			var prev byte
+			if len(out) > dict {
+				prev = out[len(out)-1]
+			}
+			lit := s.literal[literalCoderLen*((pos&lpMask)<<s.lc+uint32(prev)>>(8-s.lc)):]
+			sym := uint32(1)
+			if st >= 7 {
+				if int(s.rep0) >= len(out)-dict {
+					return out, errCorrupt
+				}
+				match := uint32(out[len(out)-int(s.rep0)-1])
+				for sym < 0x100 {
+					mb := match >> 7 & 1
+					match <<= 1
+					b := rc.bit(&lit[(1+mb)<<8+sym])
+					sym = sym<<1 | b
+					if mb != b {
+						break
+					}
+				}
+			}
+			for sym < 0x100 {
+				sym = sym<<1 | rc.bit(&lit[sym])
+			}
+			out = append(out, byte(sym))
+			switch {
+			case st < 4:
+				s.state = 0
+			case st < 10:
+				s.state = st - 3
+			default:
+				s.state = st - 6
+			}
+			continue
+		}
+
+		var n uint32
+		if rc.bit(&s.isRep[st]) == 0 {
+			s.rep3, s.rep2, s.rep1 = s.rep2, s.rep1, s.rep0
+			n = s.matchLen.decode(&rc, posState)
+			if st < 7 {
+				s.state = 7
+			} else {
+				s.state = 10
+			}
+			s.rep0 = s.distance(&rc, n)
+			if s.rep0 == 0xffffffff {
+				// end marker, not allowed in LZMA2 chunks
+				return out, errCorrupt
+			}
+		} else {
+			if rc.bit(&s.isRepG0[st]) == 0 {
+				if rc.bit(&s.isRep0Long[st<<4+posState]) == 0 {
+					// short rep: one byte at rep0
+					if st < 7 {
+						s.state = 9
+					} else {
+						s.state = 11
+					}
+					if int(s.rep0) >= len(out)-dict {
+						return out, errCorrupt
+					}
+					out = append(out, out[len(out)-int(s.rep0)-1])
+					continue
+				}
+			} else {
+				var d uint32
+				if rc.bit(&s.isRepG1[st]) == 0 {
+					d = s.rep1
+				} else {
+					if rc.bit(&s.isRepG2[st]) == 0 {
+						d = s.rep2
+					} else {
+						d = s.rep3
+						s.rep3 = s.rep2
+					}
+					s.rep2 = s.rep1
+				}
+				s.rep1 = s.rep0
+				s.rep0 = d
+			}
+			n = s.repLen.decode(&rc, posState)
+			if st < 7 {
+				s.state = 8
+			} else {
+				s.state = 11
+			}
+		}
+
+		n += minMatch
+		if int(s.rep0) >= len(out)-dict || len(out)+int(n) > end {
+			return out, errCorrupt
+		}
+		from := len(out) - int(s.rep0) - 1
+		for i := 0; i < int(n); i++ {
+			out = append(out, out[from+i])
+		}
+	}
+	if rc.short {
+		return out, errCorrupt
+	}
+	return out, nil
+}
+
+func (s *lzmaState) distance(rc *rangeDecoder, n uint32) uint32 {
+	ls := n
+	if ls > lenStates-1 {
+		ls = lenStates - 1
+	}
+	slot := rc.tree(s.posSlot[ls][:], posSlotBits)
+	if slot < 4 {
+		return slot
+	}
+	bits := uint(slot>>1 - 1)
+	d := (2 | slot&1) << bits
+	if slot < endPosModel {
+		return d + rc.reverseTree(s.posSpecial[d-slot:], bits)
+	}
+	d += rc.direct(bits-alignBits) << alignBits
+	return d + rc.reverseTree(s.align[:], alignBits)
+}
+
+// decodeLZMA2 decodes an LZMA2 stream, appending to out. It returns the
+// number of bytes of data used.
+func decodeLZMA2(out []byte, data []byte) ([]byte, int, error) {
+	var s lzmaState
+	dict := len(out)
+	off := 0
+	needDict, needProps := true, true
+	for {
+		if off >= len(data) {
+			return out, off, errCorrupt
+		}
+		ctl := data[off]
+		off++
+		if ctl == 0 {
+			return out, off, nil
+		}
+		if ctl == 1 || ctl == 2 {
+			// uncompressed chunk, resetting the dictionary if 1
+			if off+2 > len(data) {
+				return out, off, errCorrupt
+			}
+			n := int(data[off])<<8 | int(data[off+1]) + 1
+			off += 2
+			if ctl == 1 {
+				dict, needDict = len(out), false
+			} else if needDict {
+				return out, off, errCorrupt
+			}
+			if off+n > len(data) {
+				return out, off, errCorrupt
+			}
+			out = append(out, data[off:off+n]...)
+			off += n
+			continue
+		}
+		if ctl < 0x80 {
+			return out, off, errCorrupt
+		}
+		if off+4 > len(data) {
+			return out, off, errCorrupt
+		}
+		size := int(ctl&0x1f)<<16 | int(data[off])<<8 | int(data[off+1]) + 1
+		packed := int(data[off+2])<<8 | int(data[off+3]) + 1
+		off += 4
+		reset := ctl >> 5 & 3
+		if reset == 3 {
+			dict, needDict = len(out), false
+		} else if needDict {
+			return out, off, errCorrupt
+		}
+		if reset >= 2 {
+			if off >= len(data) {
+				return out, off, errCorrupt
+			}
+			if err := s.setProps(data[off]); err != nil {
+				return out, off, err
+			}
+			off++
+			needProps = false
+		} else if needProps {
+			return out, off, errCorrupt
+		}
+		if reset >= 1 {
+			s.reset()
+		}
+		if off+packed > len(data) {
+			return out, off, errCorrupt
+		}
+		var err error
+		if out, err = s.decode(out, dict, data[off:off+packed], size); err != nil {
+			return out, off, err
+		}
+		off += packed
+	}
+}
@@ -0,0 +1,275 @@
+// Package xz decompresses data in the .xz container format, as written
+// by xz(1) and used for the MiniDebugInfo of .gnu_debugdata. Only the
+// LZMA2 filter is supported, with the none, CRC32, CRC64 and SHA-256
+// integrity checks.
+package xz
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+)
+
+var (
+	headerMagic = []byte{0xfd, '7', 'z', 'X', 'Z', 0}
+	footerMagic = []byte{'Y', 'Z'}
+)
+
+// ErrFormat is returned for data that is not in the .xz format.
+var ErrFormat = errors.New("xz: not in xz format")
+
+const (
+	checkNone   = 0x00
+	checkCRC32  = 0x01
+	checkCRC64  = 0x04
+	checkSHA256 = 0x0a
+
+	filterLZMA2 = 0x21
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Decompress returns the decompressed contents of the xz streams in
+// data.
+func Decompress(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, headerMagic) {
+		return nil, ErrFormat
+	}
+	var out []byte
+	for len(data) > 0 {
+		var err error
+		var n int
+		if out, n, err = decodeStream(out, data); err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		// streams are separated by padding in multiples of four zero
+		// bytes
+		for len(data) >= 4 && binary.LittleEndian.Uint32(data) == 0 {
+			data = data[4:]
+		}
+		if len(data) > 0 && !bytes.HasPrefix(data, headerMagic) {
+			return nil, errors.New("xz: trailing garbage after stream")
+		}
+	}
+	return out, nil
+}
+
+// decodeStream decodes the stream at the start of data, appending to
+// out, and returns the size of the stream.
+func decodeStream(out []byte, data []byte) ([]byte, int, error) {
+	if len(data) < 12 {
+		return nil, 0, ErrFormat
+	}
+	flags := data[6:8]
+	if crc32.ChecksumIEEE(flags) != binary.LittleEndian.Uint32(data[8:12]) {
+		return nil, 0, errors.New("xz: stream header checksum mismatch")
+	}
+	if flags[0] != 0 || flags[1]&0xf0 != 0 {
+		return nil, 0, errors.New("xz: unsupported stream flags")
+	}
+	check := flags[1]
+	var checkSize int
+	switch check {
+	case checkNone:
+	case checkCRC32:
+		checkSize = 4
+	case checkCRC64:
+		checkSize = 8
+	case checkSHA256:
+		checkSize = 32
+	default:
+		return nil, 0, fmt.Errorf("xz: unsupported check type %d", check)
+	}
+	off := 12
+
+	type record struct{ unpadded, size uint64 }
+	var records []record
+	for {
+		if off >= len(data) {
+			return nil, 0, errors.New("xz: truncated stream")
+		}
+		if data[off] == 0 {
+			break
+		}
+		start := len(out)
+		blockStart := off
+		var err error
+		var n int
+		if out, n, err = decodeBlock(out, data[off:]); err != nil {
+			return nil, 0, err
+		}
+		off += n
+		// block padding, then the check
+		unpadded := uint64(off - blockStart)
+		for off%4 != 0 {
+			if off >= len(data) || data[off] != 0 {
+				return nil, 0, errors.New("xz: bad block padding")
+			}
+			off++
+		}
+		if off+checkSize > len(data) {
+			return nil, 0, errors.New("xz: truncated stream")
+		}
+		if err := verify(check, out[start:], data[off:off+checkSize]); err != nil {
+			return nil, 0, err
+		}
+		off += checkSize
+		records = append(records, record{unpadded + uint64(checkSize), uint64(len(out) - start)})
+	}
+
+	// index
+	indexStart := off
+	r := &vliReader{data: data, off: off + 1}
+	if r.read() != uint64(len(records)) {
+		return nil, 0, errors.New("xz: index does not match the blocks")
+	}
+	for _, rec := range records {
+		if r.read() != rec.unpadded || r.read() != rec.size {
+			return nil, 0, errors.New("xz: index does not match the blocks")
+		}
+	}
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+	off = r.off
+	for off%4 != 0 {
+		if off >= len(data) || data[off] != 0 {
+			return nil, 0, errors.New("xz: bad index padding")
+		}
+		off++
+	}
+	if off+4 > len(data) || crc32.ChecksumIEEE(data[indexStart:off]) != binary.LittleEndian.Uint32(data[off:]) {
+		return nil, 0, errors.New("xz: index checksum mismatch")
+	}
+	off += 4
+
+	// footer
+	if off+12 > len(data) {
+		return nil, 0, errors.New("xz: truncated stream footer")
+	}
+	footer := data[off : off+12]
+	if !bytes.Equal(footer[10:], footerMagic) || !bytes.Equal(footer[8:10], flags) {
+		return nil, 0, errors.New("xz: bad stream footer")
+	}
+	if crc32.ChecksumIEEE(footer[4:10]) != binary.LittleEndian.Uint32(footer) {
+		return nil, 0, errors.New("xz: stream footer checksum mismatch")
+	}
+	if backward := (uint64(binary.LittleEndian.Uint32(footer[4:])) + 1) * 4; backward != uint64(off-indexStart) {
+		return nil, 0, errors.New("xz: stream footer does not match the index")
+	}
+	return out, off + 12, nil
+}
+
+// decodeBlock decodes the block at the start of data, up to its
+// padding, appending to out. It returns the size of the block header
+// and compressed data.
+func decodeBlock(out []byte, data []byte) ([]byte, int, error) {
+	hsize := (int(data[0]) + 1) * 4
+	if hsize > len(data) {
+		return nil, 0, errors.New("xz: truncated block header")
+	}
+	h := data[:hsize]
+	if crc32.ChecksumIEEE(h[:hsize-4]) != binary.LittleEndian.Uint32(h[hsize-4:]) {
+		return nil, 0, errors.New("xz: block header checksum mismatch")
+	}
+	flags := h[1]
+	if flags&0x3c != 0 {
+		return nil, 0, errors.New("xz: unsupported block flags")
+	}
+	r := &vliReader{data: h[:hsize-4], off: 2}
+	compressed, size := uint64(0), uint64(0)
+	hasCompressed, hasSize := flags&0x40 != 0, flags&0x80 != 0
+	if hasCompressed {
+		compressed = r.read()
+	}
+	if hasSize {
+		size = r.read()
+	}
+	nfilters := int(flags&3) + 1
+	for i := 0; i < nfilters; i++ {
+		id := r.read()
+		props := r.read()
+		if r.err != nil {
+			return nil, 0, r.err
+		}
+		if id != filterLZMA2 || nfilters != 1 {
+			return nil, 0, fmt.Errorf("xz: unsupported filter 0x%x", id)
+		}
+		if props != 1 || r.off >= len(r.data) {
+			return nil, 0, errors.New("xz: bad LZMA2 properties")
+		}
+		// the dictionary size does not matter when decoding to memory
+		r.off++
+	}
+	if r.err != nil {
+		return nil, 0, r.err
+	}
+
+	start := len(out)
+	out, n, err := decodeLZMA2(out, data[hsize:])
+	if err != nil {
+		return nil, 0, err
+	}
+	if hasCompressed && uint64(n) != compressed || hasSize && uint64(len(out)-start) != size {
+		return nil, 0, errors.New("xz: block sizes do not match its header")
+	}
+	return out, hsize + n, nil
+}
+
+func verify(check byte, data, sum []byte) error {
+	var h hash.Hash
+	switch check {
+	case checkNone:
+		return nil
+	case checkCRC32:
+		h = crc32.NewIEEE()
+	case checkCRC64:
+		h = crc64.New(crc64Table)
+	case checkSHA256:
+		h = sha256.New()
+	}
+	h.Write(data)
+	got := h.Sum(nil)
+	if check != checkSHA256 {
+		// the CRCs are stored little endian
+		for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+			got[i], got[j] = got[j], got[i]
+		}
+	}
+	if !bytes.Equal(got, sum) {
+		return errors.New("xz: integrity check failed")
+	}
+	return nil
+}
+
+// vliReader reads the variable-length integers of the xz format. The
+// first error is sticky.
+type vliReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *vliReader) read() uint64 {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if r.off >= len(r.data) {
+			r.err = errors.New("xz: truncated integer")
+			return 0
+		}
+		b := r.data[r.off]
+		r.off++
+		v |= uint64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+	r.err = errors.New("xz: integer too long")
+	return 0
+}